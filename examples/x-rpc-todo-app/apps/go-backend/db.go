@@ -2,7 +2,7 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -67,6 +67,44 @@ func generateUUID() string {
 	return uuid.New().String()
 }
 
+// taskTimes holds a task's date columns as scanned. Due dates are stored as
+// YYYY-MM-DD and timestamps as RFC 3339 text.
+type taskTimes struct {
+	dueDate     sql.NullString
+	createdAt   string
+	completedAt sql.NullString
+}
+
+func (t taskTimes) parse() (dueDate *xrpc.Date, createdAt time.Time, completedAt *time.Time, err error) {
+	if t.dueDate.Valid {
+		date, err := xrpc.ParseDate(t.dueDate.String)
+		if err != nil {
+			return nil, time.Time{}, nil, err
+		}
+		dueDate = &date
+	}
+	if createdAt, err = time.Parse(time.RFC3339, t.createdAt); err != nil {
+		return nil, time.Time{}, nil, err
+	}
+	if t.completedAt.Valid {
+		completed, err := time.Parse(time.RFC3339, t.completedAt.String)
+		if err != nil {
+			return nil, time.Time{}, nil, err
+		}
+		completedAt = &completed
+	}
+	return dueDate, createdAt, completedAt, nil
+}
+
+// formatDate converts an optional date to the text stored in the database
+func formatDate(date *xrpc.Date) *string {
+	if date == nil {
+		return nil
+	}
+	s := date.String()
+	return &s
+}
+
 // =============================================================================
 // TASK OPERATIONS
 // =============================================================================
@@ -74,18 +112,18 @@ func generateUUID() string {
 type TaskSummary struct {
 	Id                    string
 	Title                 string
-	Status                string
-	Priority              string
-	DueDate               *string
-	CreatedAt             string
-	CompletedAt           *string
+	Status                xrpc.TaskStatus
+	Priority              xrpc.Priority
+	DueDate               *xrpc.Date
+	CreatedAt             time.Time
+	CompletedAt           *time.Time
 	SubtaskCount          int
 	SubtaskCompletedCount int
 	EstimatedHours        *float64
 	Position              int
 }
 
-func (db *DB) ListTasks(status, priority *string, limit *int) ([]TaskSummary, int, error) {
+func (db *DB) ListTasks(status *xrpc.TaskStatus, priority *xrpc.Priority, offset, limit int) ([]TaskSummary, int, error) {
 	// Build query with optional filters
 	query := `
 		SELECT
@@ -109,9 +147,8 @@ func (db *DB) ListTasks(status, priority *string, limit *int) ([]TaskSummary, in
 
 	query += " ORDER BY t.position ASC, t.created_at DESC"
 
-	if limit != nil && *limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
-	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
 
 	rows, err := db.conn.Query(query, args...)
 	if err != nil {
@@ -122,14 +159,18 @@ func (db *DB) ListTasks(status, priority *string, limit *int) ([]TaskSummary, in
 	var tasks []TaskSummary
 	for rows.Next() {
 		var t TaskSummary
+		var times taskTimes
 		err := rows.Scan(
-			&t.Id, &t.Title, &t.Status, &t.Priority, &t.DueDate, &t.CreatedAt,
-			&t.CompletedAt, &t.EstimatedHours, &t.Position,
+			&t.Id, &t.Title, &t.Status, &t.Priority, &times.dueDate, &times.createdAt,
+			&times.completedAt, &t.EstimatedHours, &t.Position,
 			&t.SubtaskCount, &t.SubtaskCompletedCount,
 		)
 		if err != nil {
 			return nil, 0, err
 		}
+		if t.DueDate, t.CreatedAt, t.CompletedAt, err = times.parse(); err != nil {
+			return nil, 0, err
+		}
 		tasks = append(tasks, t)
 	}
 
@@ -157,11 +198,11 @@ type FullTask struct {
 	Id             string
 	Title          string
 	Description    *string
-	Status         string
-	Priority       string
-	DueDate        *string
-	CreatedAt      string
-	CompletedAt    *string
+	Status         xrpc.TaskStatus
+	Priority       xrpc.Priority
+	DueDate        *xrpc.Date
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
 	EstimatedHours *float64
 	Position       int
 	Subtasks       []Subtask
@@ -175,17 +216,24 @@ type Subtask struct {
 
 func (db *DB) GetTask(id string) (*FullTask, error) {
 	task := &FullTask{}
+	var times taskTimes
 	err := db.conn.QueryRow(`
 		SELECT id, title, description, status, priority, due_date, created_at,
 		       completed_at, estimated_hours, position
 		FROM tasks WHERE id = ?
 	`, id).Scan(
 		&task.Id, &task.Title, &task.Description, &task.Status, &task.Priority,
-		&task.DueDate, &task.CreatedAt, &task.CompletedAt, &task.EstimatedHours, &task.Position,
+		&times.dueDate, &times.createdAt, &times.completedAt, &task.EstimatedHours, &task.Position,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, xrpc.Errorf(xrpc.CodeNotFound, "task %s not found", id)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if task.DueDate, task.CreatedAt, task.CompletedAt, err = times.parse(); err != nil {
+		return nil, err
+	}
 
 	// Load subtasks
 	subtaskRows, err := db.conn.Query("SELECT id, title, completed FROM subtasks WHERE task_id = ?", id)
@@ -220,7 +268,7 @@ func (db *DB) CreateTask(input xrpc.TaskCreateInput) (*FullTask, error) {
 	_, err = db.conn.Exec(`
 		INSERT INTO tasks (id, title, description, status, priority, due_date, created_at, estimated_hours, position)
 		VALUES (?, ?, ?, 'pending', ?, ?, ?, ?, ?)
-	`, id, input.Title, input.Description, input.Priority, input.DueDate, createdAt, input.EstimatedHours, position)
+	`, id, input.Title, input.Description, input.Priority, formatDate(input.DueDate), createdAt, input.EstimatedHours, position)
 	if err != nil {
 		return nil, err
 	}
@@ -233,37 +281,33 @@ func (db *DB) UpdateTask(input xrpc.TaskUpdateInput) (*FullTask, error) {
 	updates := []string{}
 	args := []interface{}{}
 
-	if input.Title != "" {
+	if input.Title != nil {
 		updates = append(updates, "title = ?")
-		args = append(args, input.Title)
+		args = append(args, *input.Title)
 	}
 	if input.Description != nil {
 		updates = append(updates, "description = ?")
 		args = append(args, *input.Description)
 	}
-	if input.Status != "" {
+	if input.Status != nil {
 		updates = append(updates, "status = ?")
-		args = append(args, input.Status)
+		args = append(args, *input.Status)
 		// Set completed_at when status changes to completed
-		if input.Status == "completed" {
+		if *input.Status == xrpc.TaskStatusCompleted {
 			updates = append(updates, "completed_at = ?")
 			args = append(args, time.Now().UTC().Format(time.RFC3339))
-		} else if input.Status != "completed" {
+		} else {
 			// Clear completed_at if status is no longer completed
 			updates = append(updates, "completed_at = NULL")
 		}
 	}
-	if input.Priority != "" {
+	if input.Priority != nil {
 		updates = append(updates, "priority = ?")
-		args = append(args, input.Priority)
+		args = append(args, *input.Priority)
 	}
 	if input.DueDate != nil {
-		if *input.DueDate == "" {
-			updates = append(updates, "due_date = NULL")
-		} else {
-			updates = append(updates, "due_date = ?")
-			args = append(args, *input.DueDate)
-		}
+		updates = append(updates, "due_date = ?")
+		args = append(args, input.DueDate.String())
 	}
 	if input.EstimatedHours != nil {
 		if *input.EstimatedHours == 0 {
@@ -344,6 +388,9 @@ func (db *DB) ToggleSubtask(taskId, subtaskId string) (*Subtask, error) {
 		"SELECT id, title, completed FROM subtasks WHERE id = ? AND task_id = ?",
 		subtaskId, taskId,
 	).Scan(&st.Id, &st.Title, &completed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, xrpc.Errorf(xrpc.CodeNotFound, "subtask %s not found", subtaskId)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1,22 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"go-backend/xrpc"
 )
 
-var db *DB
+var events = newTaskEvents()
 
 func main() {
-	var err error
-	db, err = NewDB("./tasks.db")
+	db, err := NewDB("./tasks.db")
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer db.Close()
 
+	// Custom validators must be registered before the router is built
+	xrpc.RegisterValidator("workingDay", validateWorkingDay)
+
 	// Create xRPC router with type-safe handlers
 	// Validation is automatically applied before handlers are called
 	router := xrpc.NewRouter().
@@ -26,13 +32,39 @@ func main() {
 		TaskCreate(handleTaskCreate).
 		TaskUpdate(handleTaskUpdate).
 		TaskDelete(handleTaskDelete).
+		TaskWatch(handleTaskWatch).
 		// Subtask endpoints
 		SubtaskAdd(handleSubtaskAdd).
-		SubtaskToggle(handleSubtaskToggle)
+		SubtaskToggle(handleSubtaskToggle).
+		// Share one execution among identical task.list calls fanned out by dashboards
+		InterceptFor("task.list", xrpc.SingleFlight()).
+		// Log every call with its outcome and latency
+		SetLogger(requestLogger{}).
+		// Accept numbers sent as strings, such as the page size of a query string
+		EnableLenientDecoding().
+		// Report not ready while the database is unreachable
+		AddReadinessCheck("db", xrpc.PingCheck(db.conn))
+
+	// Handlers get the database from their context rather than a global, so
+	// tests can provide their own
+	xrpc.Provide[DB](router, func(ctx context.Context) *DB { return db })
 
 	// Wrap with CORS middleware
 	http.Handle("/api", corsMiddleware(router))
 
+	// Serve the endpoints with an http mapping as REST routes, e.g. GET /api/v1/tasks/{id}
+	http.Handle("/api/v1/", corsMiddleware(http.StripPrefix("/api/v1", router.RESTHandler())))
+
+	// Liveness and readiness probes
+	http.Handle("/healthz", router.HealthHandler())
+	http.Handle("/readyz", router.ReadinessHandler())
+
+	// Serve the OpenAPI document for API gateways and documentation portals
+	http.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(router.OpenAPISpec())
+	})
+
 	log.Println("Go backend running on :8080")
 	log.Println("Using generated xRPC router with automatic validation")
 	log.Println("Validation includes: UUID, enum, regex pattern, string length, number range")
@@ -43,99 +75,125 @@ func main() {
 // TASK HANDLERS
 // =============================================================================
 
-func handleTaskList(ctx *xrpc.Context, input xrpc.TaskListInput) (xrpc.TaskListOutput, error) {
-	var status, priority *string
-	var limit *int
-	if input.Status != "" {
-		status = &input.Status
-	}
-	if input.Priority != "" {
-		priority = &input.Priority
-	}
-	if input.Limit > 0 {
-		l := int(input.Limit)
-		limit = &l
-	}
+// taskListCursor is the position task.list cursors encode: the offset of the
+// page's first task.
+type taskListCursor struct {
+	Offset int `json:"offset"`
+}
 
-	tasks, total, err := db.ListTasks(status, priority, limit)
+func handleTaskList(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskListInput) (xrpc.TaskListOutput, error) {
+	var cursor taskListCursor
+	if err := xrpc.DecodeCursor(input.Cursor, &cursor); err != nil {
+		return xrpc.TaskListOutput{}, err
+	}
+	db := xrpc.MustGet[DB](ctx)
+	tasks, total, err := db.ListTasks(input.Status, input.Priority, cursor.Offset, xrpc.PageSize(input.PageSize))
 	if err != nil {
 		return xrpc.TaskListOutput{}, err
 	}
 
 	// Convert to output format using properly typed structs
-	outputTasks := make([]xrpc.TaskListOutputTasksItem, len(tasks))
+	outputTasks := make([]xrpc.TaskSummary, len(tasks))
 	for i, t := range tasks {
-		item := xrpc.TaskListOutputTasksItem{
+		item := xrpc.TaskSummary{
 			Id:                    t.Id,
 			Title:                 t.Title,
 			Status:                t.Status,
 			Priority:              t.Priority,
 			CreatedAt:             t.CreatedAt,
-			SubtaskCount:          float64(t.SubtaskCount),
-			SubtaskCompletedCount: float64(t.SubtaskCompletedCount),
-			Position:              float64(t.Position),
-		}
-		if t.DueDate != nil {
-			item.DueDate = *t.DueDate
-		}
-		if t.CompletedAt != nil {
-			item.CompletedAt = t.CompletedAt
-		}
-		if t.EstimatedHours != nil {
-			item.EstimatedHours = *t.EstimatedHours
+			SubtaskCount:          t.SubtaskCount,
+			SubtaskCompletedCount: t.SubtaskCompletedCount,
+			Position:              t.Position,
+			DueDate:               t.DueDate,
+			CompletedAt:           t.CompletedAt,
+			EstimatedHours:        t.EstimatedHours,
 		}
 		outputTasks[i] = item
 	}
 
-	return xrpc.TaskListOutput{
+	output := xrpc.TaskListOutput{
 		Tasks: outputTasks,
-		Total: float64(total),
-	}, nil
+		Total: total,
+	}
+	if next := cursor.Offset + len(tasks); next < total {
+		if output.NextCursor, err = xrpc.EncodeCursor(taskListCursor{Offset: next}); err != nil {
+			return xrpc.TaskListOutput{}, err
+		}
+	}
+	return output, nil
 }
 
-func handleTaskGet(ctx *xrpc.Context, input xrpc.TaskGetInput) (xrpc.TaskGetOutput, error) {
+func handleTaskGet(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskGetInput) (xrpc.TaskGetOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	task, err := db.GetTask(input.Id)
 	if err != nil {
 		return xrpc.TaskGetOutput{}, err
 	}
 
-	return taskToGetOutput(task), nil
+	return taskToOutput(task), nil
 }
 
-func handleTaskCreate(ctx *xrpc.Context, input xrpc.TaskCreateInput) (xrpc.TaskCreateOutput, error) {
+func handleTaskCreate(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskCreateInput) (xrpc.TaskCreateOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	task, err := db.CreateTask(input)
 	if err != nil {
 		return xrpc.TaskCreateOutput{}, err
 	}
+	events.publish(xrpc.TaskWatchOutputTypeCreated, task.Id)
 
-	return taskToCreateOutput(task), nil
+	return taskToOutput(task), nil
 }
 
-func handleTaskUpdate(ctx *xrpc.Context, input xrpc.TaskUpdateInput) (xrpc.TaskUpdateOutput, error) {
+func handleTaskUpdate(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskUpdateInput) (xrpc.TaskUpdateOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	task, err := db.UpdateTask(input)
 	if err != nil {
 		return xrpc.TaskUpdateOutput{}, err
 	}
+	events.publish(xrpc.TaskWatchOutputTypeUpdated, task.Id)
 
-	return taskToUpdateOutput(task), nil
+	return taskToOutput(task), nil
 }
 
-func handleTaskDelete(ctx *xrpc.Context, input xrpc.TaskDeleteInput) (xrpc.TaskDeleteOutput, error) {
+func handleTaskDelete(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskDeleteInput) (xrpc.TaskDeleteOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	if err := db.DeleteTask(input.Id); err != nil {
 		return xrpc.TaskDeleteOutput{}, err
 	}
+	events.publish(xrpc.TaskWatchOutputTypeDeleted, input.Id)
 	return xrpc.TaskDeleteOutput{Success: true}, nil
 }
 
+func handleTaskWatch(ctx context.Context, info xrpc.RequestInfo, input xrpc.TaskWatchInput, send func(xrpc.TaskWatchOutput) error) error {
+	updates, unsubscribe := events.subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-updates:
+			if input.TaskId != nil && event.TaskId != *input.TaskId {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // =============================================================================
 // SUBTASK HANDLERS
 // =============================================================================
 
-func handleSubtaskAdd(ctx *xrpc.Context, input xrpc.SubtaskAddInput) (xrpc.SubtaskAddOutput, error) {
+func handleSubtaskAdd(ctx context.Context, info xrpc.RequestInfo, input xrpc.SubtaskAddInput) (xrpc.SubtaskAddOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	subtask, err := db.AddSubtask(input.TaskId, input.Title)
 	if err != nil {
 		return xrpc.SubtaskAddOutput{}, err
 	}
+	events.publish(xrpc.TaskWatchOutputTypeUpdated, input.TaskId)
 	return xrpc.SubtaskAddOutput{
 		Id:        subtask.Id,
 		Title:     subtask.Title,
@@ -143,11 +201,13 @@ func handleSubtaskAdd(ctx *xrpc.Context, input xrpc.SubtaskAddInput) (xrpc.Subta
 	}, nil
 }
 
-func handleSubtaskToggle(ctx *xrpc.Context, input xrpc.SubtaskToggleInput) (xrpc.SubtaskToggleOutput, error) {
+func handleSubtaskToggle(ctx context.Context, info xrpc.RequestInfo, input xrpc.SubtaskToggleInput) (xrpc.SubtaskToggleOutput, error) {
+	db := xrpc.MustGet[DB](ctx)
 	subtask, err := db.ToggleSubtask(input.TaskId, input.SubtaskId)
 	if err != nil {
 		return xrpc.SubtaskToggleOutput{}, err
 	}
+	events.publish(xrpc.TaskWatchOutputTypeUpdated, input.TaskId)
 	return xrpc.SubtaskToggleOutput{
 		Id:        subtask.Id,
 		Title:     subtask.Title,
@@ -156,110 +216,71 @@ func handleSubtaskToggle(ctx *xrpc.Context, input xrpc.SubtaskToggleInput) (xrpc
 }
 
 // =============================================================================
-// HELPER FUNCTIONS
+// TASK EVENTS
 // =============================================================================
 
-func taskToGetOutput(task *FullTask) xrpc.TaskGetOutput {
-	output := xrpc.TaskGetOutput{
-		Id:        task.Id,
-		Title:     task.Title,
-		Status:    task.Status,
-		Priority:  task.Priority,
-		CreatedAt: task.CreatedAt,
-		Position:  float64(task.Position),
-	}
-
-	if task.Description != nil {
-		output.Description = *task.Description
-	}
-	if task.DueDate != nil {
-		output.DueDate = *task.DueDate
-	}
-	if task.CompletedAt != nil {
-		output.CompletedAt = task.CompletedAt
-	}
-	if task.EstimatedHours != nil {
-		output.EstimatedHours = *task.EstimatedHours
-	}
-
-	// Convert subtasks using properly typed struct
-	subtasks := make([]xrpc.TaskGetOutputSubtasksItem, len(task.Subtasks))
-	for i, s := range task.Subtasks {
-		subtasks[i] = xrpc.TaskGetOutputSubtasksItem{
-			Id:        s.Id,
-			Title:     s.Title,
-			Completed: s.Completed,
-		}
-	}
-	output.Subtasks = subtasks
+// taskEvents fans task change notifications out to task.watch subscribers.
+type taskEvents struct {
+	mu          sync.Mutex
+	subscribers map[chan xrpc.TaskWatchOutput]struct{}
+}
 
-	return output
+func newTaskEvents() *taskEvents {
+	return &taskEvents{subscribers: make(map[chan xrpc.TaskWatchOutput]struct{})}
 }
 
-func taskToCreateOutput(task *FullTask) xrpc.TaskCreateOutput {
-	output := xrpc.TaskCreateOutput{
-		Id:        task.Id,
-		Title:     task.Title,
-		Status:    task.Status,
-		Priority:  task.Priority,
-		CreatedAt: task.CreatedAt,
-		Position:  float64(task.Position),
-	}
+func (e *taskEvents) subscribe() (<-chan xrpc.TaskWatchOutput, func()) {
+	ch := make(chan xrpc.TaskWatchOutput, 16)
 
-	if task.Description != nil {
-		output.Description = *task.Description
-	}
-	if task.DueDate != nil {
-		output.DueDate = *task.DueDate
-	}
-	if task.CompletedAt != nil {
-		output.CompletedAt = task.CompletedAt
-	}
-	if task.EstimatedHours != nil {
-		output.EstimatedHours = *task.EstimatedHours
+	e.mu.Lock()
+	e.subscribers[ch] = struct{}{}
+	e.mu.Unlock()
+
+	return ch, func() {
+		e.mu.Lock()
+		delete(e.subscribers, ch)
+		e.mu.Unlock()
 	}
+}
 
-	// Convert subtasks using properly typed struct
-	subtasks := make([]xrpc.TaskCreateOutputSubtasksItem, len(task.Subtasks))
-	for i, s := range task.Subtasks {
-		subtasks[i] = xrpc.TaskCreateOutputSubtasksItem{
-			Id:        s.Id,
-			Title:     s.Title,
-			Completed: s.Completed,
+func (e *taskEvents) publish(eventType xrpc.TaskWatchOutputType, taskID string) {
+	event := xrpc.TaskWatchOutput{Type: eventType, TaskId: taskID}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subscribers {
+		// Drop the event for subscribers that are not keeping up rather
+		// than blocking the mutation that produced it
+		select {
+		case ch <- event:
+		default:
 		}
 	}
-	output.Subtasks = subtasks
-
-	return output
 }
 
-func taskToUpdateOutput(task *FullTask) xrpc.TaskUpdateOutput {
-	output := xrpc.TaskUpdateOutput{
-		Id:        task.Id,
-		Title:     task.Title,
-		Status:    task.Status,
-		Priority:  task.Priority,
-		CreatedAt: task.CreatedAt,
-		Position:  float64(task.Position),
-	}
+// =============================================================================
+// HELPER FUNCTIONS
+// =============================================================================
 
-	if task.Description != nil {
-		output.Description = *task.Description
-	}
-	if task.DueDate != nil {
-		output.DueDate = *task.DueDate
-	}
-	if task.CompletedAt != nil {
-		output.CompletedAt = task.CompletedAt
-	}
-	if task.EstimatedHours != nil {
-		output.EstimatedHours = *task.EstimatedHours
+// taskToOutput builds the output shared by task.get, task.create and task.update
+func taskToOutput(task *FullTask) xrpc.Task {
+	output := xrpc.Task{
+		Id:             task.Id,
+		Title:          task.Title,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		CreatedAt:      task.CreatedAt,
+		Position:       task.Position,
+		Description:    task.Description,
+		DueDate:        task.DueDate,
+		CompletedAt:    task.CompletedAt,
+		EstimatedHours: task.EstimatedHours,
 	}
 
 	// Convert subtasks using properly typed struct
-	subtasks := make([]xrpc.TaskUpdateOutputSubtasksItem, len(task.Subtasks))
+	subtasks := make([]xrpc.Subtask, len(task.Subtasks))
 	for i, s := range task.Subtasks {
-		subtasks[i] = xrpc.TaskUpdateOutputSubtasksItem{
+		subtasks[i] = xrpc.Subtask{
 			Id:        s.Id,
 			Title:     s.Title,
 			Completed: s.Completed,
@@ -273,8 +294,9 @@ func taskToUpdateOutput(task *FullTask) xrpc.TaskUpdateOutput {
 func corsMiddleware(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Xrpc-Schema-Hash")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Xrpc-Schema-Mismatch")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -284,3 +306,22 @@ func corsMiddleware(next http.Handler) http.HandlerFunc {
 		next.ServeHTTP(w, r)
 	}
 }
+
+// validateWorkingDay rejects due dates on weekends
+func validateWorkingDay(value interface{}) error {
+	date, ok := value.(xrpc.Date)
+	if !ok {
+		return errors.New("must be a date")
+	}
+	if weekday := date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return errors.New("must be a working day")
+	}
+	return nil
+}
+
+// requestLogger writes one log line per xRPC call
+type requestLogger struct{}
+
+func (requestLogger) LogRequest(ctx context.Context, entry xrpc.LogEntry) {
+	log.Printf("%s %s %d %s", entry.Method, entry.Outcome, entry.Status, entry.Duration)
+}
@@ -0,0 +1,290 @@
+package xrpc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// BearerAuth returns middleware authenticating requests with an "Authorization:
+// Bearer <token>" header with verify. Requests without one, and calls without
+// an HTTP request, continue unauthenticated; a token verify rejects fails the
+// call with UNAUTHORIZED, or with the *Error verify returns.
+func BearerAuth(verify func(ctx context.Context, token string) (Principal, error)) MiddlewareFunc {
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		if info.Request == nil {
+			return NewMiddlewareResult(ctx)
+		}
+		token, ok := bearerToken(info.Request)
+		if !ok {
+			return NewMiddlewareResult(ctx)
+		}
+		principal, err := verify(ctx, token)
+		if err != nil {
+			return NewMiddlewareError(authError(err))
+		}
+		if principal.Scheme == "" {
+			principal.Scheme = "bearer"
+		}
+		return NewMiddlewareResult(WithPrincipal(ctx, principal))
+	}
+}
+
+// JWTKeySet holds the keys JWTAuth verifies tokens with, by key ID (the token's
+// "kid" header): []byte secrets for HS256/384/512, *rsa.PublicKey for
+// RS256/384/512 and *ecdsa.PublicKey for ES256/384/512. Tokens without a key
+// ID are verified with the key stored under "", or the only key.
+type JWTKeySet map[string]interface{}
+
+// JWTAuth returns middleware authenticating requests whose bearer token is a JWT
+// signed with a key in keys and within its "exp" and "nbf" claims. claims maps
+// the verified claims to the principal and may reject them, e.g. for the wrong
+// audience; if it is nil, the principal's ID is the "sub" claim.
+func JWTAuth(keys JWTKeySet, claims func(claims map[string]interface{}) (Principal, error)) MiddlewareFunc {
+	return BearerAuth(func(ctx context.Context, token string) (Principal, error) {
+		verified, err := verifyJWT(token, keys, time.Now())
+		if err != nil {
+			return Principal{}, err
+		}
+		principal := Principal{}
+		if claims != nil {
+			principal, err = claims(verified)
+			if err != nil {
+				return Principal{}, err
+			}
+		}
+		if principal.ID == "" {
+			principal.ID, _ = verified["sub"].(string)
+		}
+		if principal.Claims == nil {
+			principal.Claims = verified
+		}
+		principal.Scheme = "jwt"
+		return principal, nil
+	})
+}
+
+// APIKeyAuth returns middleware authenticating requests with an "X-API-Key" header
+// with lookup, which returns the key's principal. Requests without one, and
+// calls without an HTTP request, continue unauthenticated; a key lookup
+// rejects fails the call with UNAUTHORIZED, or with the *Error lookup returns.
+func APIKeyAuth(lookup func(ctx context.Context, key string) (Principal, error)) MiddlewareFunc {
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		if info.Request == nil {
+			return NewMiddlewareResult(ctx)
+		}
+		key := info.Request.Header.Get("X-API-Key")
+		if key == "" {
+			return NewMiddlewareResult(ctx)
+		}
+		principal, err := lookup(ctx, key)
+		if err != nil {
+			return NewMiddlewareError(authError(err))
+		}
+		if principal.Scheme == "" {
+			principal.Scheme = "api-key"
+		}
+		return NewMiddlewareResult(WithPrincipal(ctx, principal))
+	}
+}
+
+// Authorizer decides whether callers may call the methods declared with
+// permissions. The router consults it after middleware, before decoding input.
+type Authorizer interface {
+	// Authorize reports whether the caller of info.Method holds every one of
+	// permissions. An error fails the call with it instead of PERMISSION_DENIED.
+	Authorize(ctx context.Context, info RequestInfo, permissions []string) (bool, error)
+}
+
+// AuthorizerFunc adapts a function to Authorizer.
+type AuthorizerFunc func(ctx context.Context, info RequestInfo, permissions []string) (bool, error)
+
+// Authorize calls f.
+func (f AuthorizerFunc) Authorize(ctx context.Context, info RequestInfo, permissions []string) (bool, error) {
+	return f(ctx, info, permissions)
+}
+
+// ClaimAuthorizer returns an Authorizer granting the permissions the principal's
+// claim lists, as a JSON array or a space-separated string like OAuth's "scope".
+// Unauthenticated callers hold no permissions.
+func ClaimAuthorizer(claim string) Authorizer {
+	return AuthorizerFunc(func(ctx context.Context, info RequestInfo, permissions []string) (bool, error) {
+		principal, ok := PrincipalFrom(ctx)
+		if !ok {
+			return false, nil
+		}
+		held := map[string]bool{}
+		switch value := principal.Claims[claim].(type) {
+		case string:
+			for _, permission := range strings.Fields(value) {
+				held[permission] = true
+			}
+		case []string:
+			for _, permission := range value {
+				held[permission] = true
+			}
+		case []interface{}:
+			for _, permission := range value {
+				if name, ok := permission.(string); ok {
+					held[name] = true
+				}
+			}
+		}
+		for _, permission := range permissions {
+			if !held[permission] {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header
+func bearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// authError reports a rejected credential as UNAUTHORIZED without its reason,
+// unless the verifier returned an *Error choosing its own code.
+func authError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return NewError(CodeUnauthorized, "Invalid credentials")
+}
+
+// verifyJWT checks a compact JWT's signature and time claims and returns its
+// claims
+func verifyJWT(token string, keys JWTKeySet, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	key, ok := keys[header.Kid]
+	if !ok && header.Kid == "" && len(keys) == 1 {
+		for _, only := range keys {
+			key, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	return claims, nil
+}
+
+// decodeJWTPart decodes a base64url-encoded JSON part of a JWT
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifyJWTSignature checks signature over signed with key for alg; "none" and
+// algorithms that don't match the key's type are rejected
+func verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) error {
+	invalid := errors.New("invalid signature")
+	unsupported := fmt.Errorf("unsupported algorithm %q", alg)
+	if len(alg) != 5 {
+		return unsupported
+	}
+	var id crypto.Hash
+	var newHash func() hash.Hash
+	switch alg[2:] {
+	case "256":
+		id, newHash = crypto.SHA256, sha256.New
+	case "384":
+		id, newHash = crypto.SHA384, sha512.New384
+	case "512":
+		id, newHash = crypto.SHA512, sha512.New
+	default:
+		return unsupported
+	}
+	digest := newHash()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return unsupported
+		}
+		mac := hmac.New(newHash, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return invalid
+		}
+		return nil
+	case "RS":
+		public, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return unsupported
+		}
+		if rsa.VerifyPKCS1v15(public, id, sum, signature) != nil {
+			return invalid
+		}
+		return nil
+	case "ES":
+		public, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return unsupported
+		}
+		size := (public.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(public, sum, r, s) {
+			return invalid
+		}
+		return nil
+	}
+	return unsupported
+}
@@ -0,0 +1,100 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the server while the circuit
+// breaker of the method is open.
+var ErrCircuitOpen = NewError(CodeUnavailable, "Circuit breaker open")
+
+// WithCircuitBreaker opens the circuit breaker of a method after threshold
+// consecutive calls failed on the server side (transport failures, INTERNAL,
+// UNAVAILABLE and DEADLINE_EXCEEDED): its calls then fail with ErrCircuitOpen
+// until cooldown passed, when one call probes the server. The breaker closes
+// again when it succeeds and stays open for another cooldown otherwise.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breakers = &circuitBreakers{threshold: threshold, cooldown: cooldown, states: make(map[string]*circuitState)}
+	}
+}
+
+// circuitBreakers holds the circuit breaker state of every method a Client
+// called; nil when the Client has no circuit breakers.
+type circuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	states    map[string]*circuitState
+}
+
+// circuitState is the circuit breaker state of a method.
+type circuitState struct {
+	failures  int
+	openUntil time.Time
+	// Whether a call is probing the server of an open breaker
+	probing bool
+}
+
+// allow reports whether a call of method may be made: always while its breaker
+// is closed, and for a single probing call once an open one cooled down.
+func (b *circuitBreakers) allow(method string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.states[method]
+	if s == nil || s.failures < b.threshold {
+		return true
+	}
+	if s.probing || time.Now().Before(s.openUntil) {
+		return false
+	}
+	s.probing = true
+	return true
+}
+
+// record counts the outcome of a call of method, opening its breaker after
+// threshold consecutive server failures.
+func (b *circuitBreakers) record(method string, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.states[method]
+	if s == nil {
+		s = &circuitState{}
+		b.states[method] = s
+	}
+	s.probing = false
+	// A call the caller cancelled tells nothing about the server
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if !serverFailure(err) {
+		s.failures = 0
+		return
+	}
+	s.failures++
+	if s.failures >= b.threshold {
+		s.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// serverFailure reports whether err shows the server failing, rather than
+// rejecting the call for reasons of its own.
+func serverFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var callErr *Error
+	if errors.As(err, &callErr) {
+		return callErr.Code == CodeInternal || callErr.Code == CodeUnavailable || callErr.Code == CodeDeadlineExceeded
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package xrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// resultEnvelope is the {"result": ...} body of a result.
+type resultEnvelope struct {
+	Result interface{} `json:"result"`
+}
+
+// errorEnvelope is the {"error": ...} body of an error.
+type errorEnvelope struct {
+	Error *Error `json:"error"`
+}
+
+// maxPooledBuffer is the capacity above which a buffer is dropped rather than
+// pooled, so an occasional large result doesn't keep its memory alive.
+const maxPooledBuffer = 64 << 10
+
+// encodeBuffer is a buffer with the JSON encoder writing to it, reused across
+// calls through encodeBuffers.
+type encodeBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := &encodeBuffer{}
+		buf.encoder = json.NewEncoder(&buf.Buffer)
+		return buf
+	},
+}
+
+// encodeJSON encodes value, followed by a newline, into a pooled buffer. The
+// caller releases the buffer once it has written its bytes, and must not use
+// them afterwards.
+func encodeJSON(value interface{}) (*encodeBuffer, error) {
+	buf := encodeBuffers.Get().(*encodeBuffer)
+	if err := buf.encoder.Encode(value); err != nil {
+		buf.release()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// release returns buf to the pool.
+func (buf *encodeBuffer) release() {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	encodeBuffers.Put(buf)
+}
@@ -0,0 +1,192 @@
+package xrpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a result kept in a CacheStore.
+type CacheEntry struct {
+	// Result is the JSON encoding of the result.
+	Result json.RawMessage `json:"result"`
+	// Expires is when the entry expires; stores drop it then.
+	Expires time.Time `json:"expires"`
+	// Delta is how long the handler took to produce the result. Entries that
+	// take longer to recompute are refreshed earlier.
+	Delta time.Duration `json:"delta"`
+}
+
+// refreshEarly reports whether a call at now should recompute the entry rather
+// than be answered with it: always once it expired, and before that with a
+// probability growing as it nears expiry, scaled by its Delta and beta (XFetch).
+// Calls of a popular query thus refresh it one at a time while the others are
+// still answered from the cache.
+func (e CacheEntry) refreshEarly(now time.Time, beta float64) bool {
+	if beta <= 0 {
+		return !now.Before(e.Expires)
+	}
+	// 1 - rand.Float64() is never 0, whose logarithm is infinite
+	gap := time.Duration(float64(e.Delta) * beta * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(e.Expires)
+}
+
+// CacheStore keeps the results the Cache interceptor caches. A store shared by
+// several servers, such as RedisCacheStore, lets each answer with the results
+// the others computed.
+type CacheStore interface {
+	// Load returns the entry stored under key, or false when there is none or it
+	// expired.
+	Load(ctx context.Context, key string) (CacheEntry, bool, error)
+	// Save stores entry under key until it expires.
+	Save(ctx context.Context, key string, entry CacheEntry) error
+}
+
+// MemoryCacheStore keeps entries in memory, for a single server or tests.
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	// The number of entries after the last sweep of expired ones
+	swept int
+}
+
+// NewMemoryCacheStore returns an empty MemoryCacheStore.
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: make(map[string]CacheEntry)}
+}
+
+// Load returns the entry stored under key, dropping it if it expired.
+func (s *MemoryCacheStore) Load(ctx context.Context, key string) (CacheEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if ok && !time.Now().Before(entry.Expires) {
+		delete(s.entries, key)
+		return CacheEntry{}, false, nil
+	}
+	return entry, ok, nil
+}
+
+// Save stores entry under key. Expired entries are swept whenever the number of
+// entries doubled since the last sweep.
+func (s *MemoryCacheStore) Save(ctx context.Context, key string, entry CacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	if len(s.entries) > 2*s.swept {
+		now := time.Now()
+		for key, entry := range s.entries {
+			if !now.Before(entry.Expires) {
+				delete(s.entries, key)
+			}
+		}
+		s.swept = len(s.entries)
+	}
+	return nil
+}
+
+// CacheOptions configures the Cache interceptor.
+type CacheOptions struct {
+	// TTL is how long results are cached, a minute by default.
+	TTL time.Duration
+	// Beta scales how early entries are refreshed before they expire, 1 by
+	// default; higher values refresh earlier, and a negative one only once they
+	// expired.
+	Beta float64
+	// Shared caches the results of a method for every caller of a tenant. By
+	// default they are cached per user (UserIDFrom); tenants (TenantFrom) never
+	// share results.
+	Shared bool
+	// Prefix starts the keys of the store, "xrpc:cache:" by default; the method
+	// and a hash of the params, tenant and user follow it.
+	Prefix string
+	// ErrorLog receives the errors of the store, which leave calls uncached. By
+	// default they are written to the standard logger.
+	ErrorLog *log.Logger
+}
+
+// Cache returns an interceptor answering queries with the results kept in store
+// for calls with the same params, running the handler only when there is none
+// or it is due for a refresh. Entries are refreshed early with a probability
+// growing as they near expiry, and misses of the same key in this process wait
+// for one handler run, so a popular query expiring does not send every call
+// of every replica to the handler at once. Only queries answered with a single
+// result are cached, and failed calls are not. Register it with InterceptFor
+// for the queries whose results may be served stale for a TTL:
+//
+//	store := NewRedisCacheStore("redis:6379", RedisOptions{})
+//	router.InterceptFor("task.list", Cache(store, CacheOptions{TTL: 30 * time.Second}))
+func Cache(store CacheStore, options CacheOptions) InterceptorFunc {
+	if options.TTL <= 0 {
+		options.TTL = time.Minute
+	}
+	if options.Beta == 0 {
+		options.Beta = 1
+	}
+	if options.Prefix == "" {
+		options.Prefix = "xrpc:cache:"
+	}
+	if options.ErrorLog == nil {
+		options.ErrorLog = log.Default()
+	}
+	var group flightGroup
+	return func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error) {
+		m, ok := methodTable[info.Method]
+		if !ok || m.decodeResult == nil {
+			return next(ctx)
+		}
+		key, err := cacheKey(ctx, options, info.Method, input)
+		if err != nil {
+			return next(ctx)
+		}
+		entry, ok, err := store.Load(ctx, key)
+		if err != nil {
+			options.ErrorLog.Printf("xrpc: loading %s from the cache: %v", info.Method, err)
+		}
+		if ok && !entry.refreshEarly(time.Now(), options.Beta) {
+			if result, err := m.decodeResult(entry.Result); err == nil {
+				return result, nil
+			}
+		}
+		return group.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(result)
+			if err == nil {
+				err = store.Save(ctx, key, CacheEntry{Result: data, Expires: time.Now().Add(options.TTL), Delta: time.Since(start)})
+			}
+			if err != nil {
+				options.ErrorLog.Printf("xrpc: saving %s to the cache: %v", info.Method, err)
+			}
+			return result, nil
+		})
+	}
+}
+
+// cacheKey is the key of the results of calls of method with input: the prefix
+// and method followed by a hash of the params, the tenant and, unless the cache
+// is shared, the user.
+func cacheKey(ctx context.Context, options CacheOptions, method string, input interface{}) (string, error) {
+	params, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	tenant, _ := TenantFrom(ctx)
+	hash.Write([]byte(tenant.ID + "\x00"))
+	if !options.Shared {
+		userID, _ := UserIDFrom(ctx)
+		hash.Write([]byte(userID + "\x00"))
+	}
+	hash.Write(params)
+	return options.Prefix + method + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
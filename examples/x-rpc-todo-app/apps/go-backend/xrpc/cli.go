@@ -0,0 +1,457 @@
+package xrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// CLI is a command line client of the contract. "task create" calls task.create,
+// with a flag per field of its input; --params sets the input as a JSON object
+// the flags are applied on top of:
+//
+//	todocli task create --title "X" --priority high
+//
+// Input is validated before it is sent, and results are printed as JSON or,
+// with --output table, as a table. Run it from a main package:
+//
+//	os.Exit(xrpc.NewCLI("todocli").Run(context.Background(), os.Args[1:]))
+type CLI struct {
+	name string
+	// Options configure the Client of every call; --url and --header apply on
+	// top
+	Options []ClientOption
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// NewCLI returns a CLI called name in its usage, writing to os.Stdout and
+// os.Stderr.
+func NewCLI(name string) *CLI {
+	return &CLI{name: name, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// cliCommand is the command of a method, with the flags of its input fields.
+type cliCommand struct {
+	method string
+	flags  []cliFlag
+}
+
+// cliFlag is the flag setting an input field. kind tells how its values are
+// encoded as JSON: "string" values are quoted, "strings" and "array" flags
+// collect items when repeated (quoted or as JSON literals), "bool" flags may
+// be given without a value and any other value is read as a JSON literal.
+type cliFlag struct {
+	name  string
+	field string
+	kind  string
+	usage string
+}
+
+// cliCommands lists the command of every method.
+var cliCommands = []cliCommand{
+	{
+		method: "subtask.add",
+		flags: []cliFlag{
+			{"task-id", "taskId", "string", "string (required)"},
+			{"title", "title", "string", "string (required)"},
+		},
+	},
+	{
+		method: "subtask.toggle",
+		flags: []cliFlag{
+			{"task-id", "taskId", "string", "string (required)"},
+			{"subtask-id", "subtaskId", "string", "string (required)"},
+		},
+	},
+	{
+		method: "task.create",
+		flags: []cliFlag{
+			{"title", "title", "string", "string (required)"},
+			{"description", "description", "string", "string"},
+			{"priority", "priority", "string", "one of low, medium, high, urgent (default medium)"},
+			{"due-date", "dueDate", "string", "date, such as 2024-01-31"},
+			{"estimated-hours", "estimatedHours", "json", "number"},
+		},
+	},
+	{
+		method: "task.delete",
+		flags: []cliFlag{
+			{"id", "id", "string", "string (required)"},
+		},
+	},
+	{
+		method: "task.get",
+		flags: []cliFlag{
+			{"id", "id", "string", "string (required)"},
+		},
+	},
+	{
+		method: "task.list",
+		flags: []cliFlag{
+			{"status", "status", "string", "one of pending, in_progress, completed, cancelled"},
+			{"priority", "priority", "string", "one of low, medium, high, urgent"},
+			{"cursor", "cursor", "string", "string"},
+			{"page-size", "pageSize", "json", "integer"},
+		},
+	},
+	{
+		method: "task.update",
+		flags: []cliFlag{
+			{"id", "id", "string", "string (required)"},
+			{"title", "title", "string", "string"},
+			{"description", "description", "string", "string"},
+			{"status", "status", "string", "one of pending, in_progress, completed, cancelled"},
+			{"priority", "priority", "string", "one of low, medium, high, urgent"},
+			{"due-date", "dueDate", "string", "date, such as 2024-01-31"},
+			{"estimated-hours", "estimatedHours", "json", "number"},
+		},
+	},
+	{
+		method: "task.watch",
+		flags: []cliFlag{
+			{"task-id", "taskId", "string", "string"},
+		},
+	},
+}
+
+// cliOptions holds the flags every command accepts, before or after it.
+type cliOptions struct {
+	url     string
+	output  string
+	timeout time.Duration
+	headers cliValues
+}
+
+// define defines the flags every command accepts on fs, defaulting to the
+// values parsed so far.
+func (o *cliOptions) define(fs *flag.FlagSet) {
+	fs.StringVar(&o.url, "url", o.url, "URL the server's router is served at; $XRPC_URL by default")
+	fs.StringVar(&o.output, "output", o.output, "output format: json or table")
+	fs.DurationVar(&o.timeout, "timeout", o.timeout, "time limit of the call, such as 5s")
+	fs.Var(&o.headers, "header", "header sent with the call, as \"Name: value\"; repeatable")
+}
+
+// cliValues collects the values of a repeatable flag.
+type cliValues []string
+
+// String returns the values, for flag usage.
+func (v *cliValues) String() string {
+	return strings.Join(*v, ", ")
+}
+
+// Set adds a value.
+func (v *cliValues) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+// cliField collects the values of an input field's flag.
+type cliField struct {
+	cliValues
+	kind string
+}
+
+// IsBoolFlag lets a boolean field be set by its flag alone.
+func (f *cliField) IsBoolFlag() bool {
+	return f.kind == "bool"
+}
+
+// Run runs the command of args, such as "task create --title X", and returns
+// the exit code of the process: 0 on success, 1 when the call failed and 2
+// for invalid usage or input.
+func (cli *CLI) Run(ctx context.Context, args []string) int {
+	options := &cliOptions{url: os.Getenv("XRPC_URL"), output: "json"}
+	global := flag.NewFlagSet(cli.name, flag.ContinueOnError)
+	global.SetOutput(cli.Stderr)
+	global.Usage = func() { cli.usage(global) }
+	options.define(global)
+	if err := global.Parse(args); err != nil {
+		return cliExitCode(err)
+	}
+	command, args := findCLICommand(global.Args())
+	if command == nil {
+		cli.usage(global)
+		return 2
+	}
+
+	name := cli.name + " " + strings.Replace(command.method, ".", " ", -1)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(cli.Stderr)
+	options.define(fs)
+	params := fs.String("params", "", "input as a JSON object, which the field flags are applied on top of")
+	fields := make(map[string]*cliField, len(command.flags))
+	for _, f := range command.flags {
+		fields[f.field] = &cliField{kind: f.kind}
+		fs.Var(fields[f.field], f.name, f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return cliExitCode(err)
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(cli.Stderr, "%s: unexpected argument %q\n", name, fs.Arg(0))
+		return 2
+	}
+	if options.url == "" {
+		fmt.Fprintf(cli.Stderr, "%s: no server URL, set --url or $XRPC_URL\n", name)
+		return 2
+	}
+	if options.output != "json" && options.output != "table" {
+		fmt.Fprintf(cli.Stderr, "%s: unknown output format %q\n", name, options.output)
+		return 2
+	}
+	input, err := command.input(*params, fields)
+	if err != nil {
+		fmt.Fprintf(cli.Stderr, "%s: invalid input: %v\n", name, err)
+		return 2
+	}
+
+	client := NewClient(options.url, cli.Options...)
+	for _, header := range options.headers {
+		key, value, ok := strings.Cut(header, ":")
+		if !ok {
+			fmt.Fprintf(cli.Stderr, "%s: invalid header %q\n", name, header)
+			return 2
+		}
+		client.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+	if methodTable[command.method].kind == "subscription" {
+		err = client.subscribe(ctx, command.method, input, func(data []byte) error {
+			return cli.print(options.output, data)
+		})
+	} else {
+		var result json.RawMessage
+		if err = client.call(ctx, command.method, input, &result); err == nil {
+			err = cli.print(options.output, result)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(cli.Stderr, "%s: %v\n", name, err)
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, fieldErr := range validationErrs {
+				fmt.Fprintf(cli.Stderr, "  %v\n", fieldErr)
+			}
+		}
+		return 1
+	}
+	return 0
+}
+
+// cliExitCode returns the exit code of a failure to parse flags, which the flag
+// package reported: 0 when help was asked for.
+func cliExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+// findCLICommand returns the command args start with, such as "task create"
+// for task.create, and the arguments after it.
+func findCLICommand(args []string) (*cliCommand, []string) {
+	for i := range cliCommands {
+		words := strings.Split(cliCommands[i].method, ".")
+		if len(args) >= len(words) && strings.Join(args[:len(words)], ".") == cliCommands[i].method {
+			return &cliCommands[i], args[len(words):]
+		}
+	}
+	return nil, args
+}
+
+// usage writes the usage of the CLI, listing its commands, to Stderr.
+func (cli *CLI) usage(global *flag.FlagSet) {
+	fmt.Fprintf(cli.Stderr, "Usage: %s [flags] <command> [flags]\n\nCommands:\n", cli.name)
+	w := tabwriter.NewWriter(cli.Stderr, 0, 4, 2, ' ', 0)
+	for _, command := range cliCommands {
+		m := methodTable[command.method]
+		about := m.kind
+		if m.deprecation != "" {
+			about += ", deprecated"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", strings.Replace(command.method, ".", " ", -1), about)
+	}
+	w.Flush()
+	fmt.Fprintf(cli.Stderr, "\nFlags:\n")
+	global.PrintDefaults()
+	fmt.Fprintf(cli.Stderr, "\nRun \"%s <command> --help\" for the flags of a command.\n", cli.name)
+}
+
+// input decodes params, with the values of the field flags set on top, into
+// the method's input and validates it as the router would.
+func (command *cliCommand) input(params string, fields map[string]*cliField) (interface{}, error) {
+	object := map[string]json.RawMessage{}
+	if params != "" {
+		if err := json.Unmarshal([]byte(params), &object); err != nil {
+			return nil, fmt.Errorf("--params: %v", err)
+		}
+	}
+	for field, f := range fields {
+		if len(f.cliValues) > 0 {
+			object[field] = cliValue(f.kind, f.cliValues)
+		}
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	m := methodTable[command.method]
+	// A Router without options decodes JSON as NewRouter's does
+	input, err := m.decode(&Router{}, data)
+	if err != nil {
+		return nil, err
+	}
+	return input, m.validate(input)
+}
+
+// cliValue encodes the values of a field flag of kind as JSON. An array field
+// given a single JSON array takes it as is; single-valued fields take the last
+// value.
+func cliValue(kind string, values []string) json.RawMessage {
+	if kind == "strings" || kind == "array" {
+		if len(values) == 1 && strings.HasPrefix(values[0], "[") && json.Valid([]byte(values[0])) {
+			return json.RawMessage(values[0])
+		}
+		items := make([]json.RawMessage, len(values))
+		for i, value := range values {
+			items[i] = cliLiteral(value, kind == "strings")
+		}
+		data, _ := json.Marshal(items)
+		return data
+	}
+	return cliLiteral(values[len(values)-1], kind == "string")
+}
+
+// cliLiteral encodes a flag value as a JSON string when quoted is set or the
+// value is not valid JSON, and as the JSON literal it spells otherwise.
+func cliLiteral(value string, quoted bool) json.RawMessage {
+	if !quoted && json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// print writes a result or event to Stdout, as indented JSON or as a table.
+func (cli *CLI) print(format string, data []byte) error {
+	if format == "table" {
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cli.Stdout, 0, 4, 2, ' ', 0)
+		writeCLITable(w, value)
+		return w.Flush()
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(cli.Stdout)
+	return err
+}
+
+// writeCLITable writes value as a table: a list of objects with a row per item
+// and a column per field, and an object with a row per field, followed by its
+// first list of objects, such as the items of a page. Other values are
+// written as JSON.
+func writeCLITable(w io.Writer, value interface{}) {
+	if rows, ok := cliRows(value); ok {
+		writeCLIRows(w, rows)
+		return
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(w, cliCell(value))
+		return
+	}
+	var list []map[string]interface{}
+	for _, key := range sortedCLIKeys(object) {
+		if rows, ok := cliRows(object[key]); ok && list == nil {
+			list = rows
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key, cliCell(object[key]))
+	}
+	if list != nil {
+		if len(object) > 1 {
+			fmt.Fprintln(w)
+		}
+		writeCLIRows(w, list)
+	}
+}
+
+// writeCLIRows writes a row per object of rows under a header naming their
+// fields.
+func writeCLIRows(w io.Writer, rows []map[string]interface{}) {
+	seen := map[string]interface{}{}
+	for _, row := range rows {
+		for key := range row {
+			seen[key] = nil
+		}
+	}
+	columns := sortedCLIKeys(seen)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = cliCell(row[column])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
+
+// cliRows returns value as a list of objects, if it is a non-empty one.
+func cliRows(value interface{}) ([]map[string]interface{}, bool) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		if rows[i], ok = item.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return rows, true
+}
+
+// cliCell returns a value as a table cell: strings as they are, nothing for
+// null and JSON for anything else.
+func cliCell(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// sortedCLIKeys returns the keys of object in order.
+func sortedCLIKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
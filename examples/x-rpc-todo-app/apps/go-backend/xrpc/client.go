@@ -0,0 +1,302 @@
+package xrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls a server of the contract over HTTP, with a typed method per
+// endpoint. Errors the server answers with are returned as *Error, wrapping
+// the ValidationErrors of invalid input so errors.As finds them, and failed
+// queries are retried as its RetryPolicy allows.
+type Client struct {
+	url           string
+	httpClient    *http.Client
+	retry         RetryPolicy
+	breakers      *circuitBreakers
+	hedgeDelay    time.Duration
+	hedgedMethods map[string]bool
+	interceptors  []ClientInterceptorFunc
+	// Header is sent with every call, e.g. to authenticate.
+	Header http.Header
+}
+
+// ClientOption configures a Client created with NewClient.
+type ClientOption func(c *Client)
+
+// ClientNextFunc sends req through the rest of a Client's interceptor chain and
+// finally to the server.
+type ClientNextFunc func(ctx context.Context, req *http.Request) (*http.Response, error)
+
+// ClientInterceptorFunc wraps every request a Client sends for a call of
+// method, the mirror of a router's InterceptorFunc: it may add headers to req,
+// such as credentials or trace context, change the context passed to next,
+// and log, time or count the response. Every attempt of a retried or hedged
+// call is intercepted.
+type ClientInterceptorFunc func(ctx context.Context, method string, req *http.Request, next ClientNextFunc) (*http.Response, error)
+
+// NewClient returns a Client POSTing calls to url, where the server's router is
+// served, such as "https://api.example.com/api". Without options it sends
+// requests through a transport with the default TransportOptions and retries
+// with DefaultRetryPolicy.
+func NewClient(url string, options ...ClientOption) *Client {
+	c := &Client{url: url, retry: DefaultRetryPolicy, Header: http.Header{}}
+	c.httpClient = &http.Client{Transport: newTransport(TransportOptions{})}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient sends the Client's requests through httpClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy; RetryPolicy{MaxAttempts: 1} turns
+// retrying off.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithInterceptor adds an interceptor to the Client; the first added is the
+// outermost.
+func WithInterceptor(interceptor ClientInterceptorFunc) ClientOption {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptor)
+	}
+}
+
+// SubtaskAdd calls subtask.add.
+func (c *Client) SubtaskAdd(ctx context.Context, input SubtaskAddInput) (SubtaskAddOutput, error) {
+	var output SubtaskAddOutput
+	err := c.call(ctx, "subtask.add", input, &output)
+	return output, err
+}
+
+// SubtaskToggle calls subtask.toggle.
+func (c *Client) SubtaskToggle(ctx context.Context, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+	var output SubtaskToggleOutput
+	err := c.call(ctx, "subtask.toggle", input, &output)
+	return output, err
+}
+
+// TaskCreate calls task.create.
+func (c *Client) TaskCreate(ctx context.Context, input TaskCreateInput) (TaskCreateOutput, error) {
+	var output TaskCreateOutput
+	err := c.call(ctx, "task.create", input, &output)
+	return output, err
+}
+
+// TaskDelete calls task.delete.
+func (c *Client) TaskDelete(ctx context.Context, input TaskDeleteInput) (TaskDeleteOutput, error) {
+	var output TaskDeleteOutput
+	err := c.call(ctx, "task.delete", input, &output)
+	return output, err
+}
+
+// TaskGet calls task.get.
+func (c *Client) TaskGet(ctx context.Context, input TaskGetInput) (TaskGetOutput, error) {
+	var output TaskGetOutput
+	err := c.call(ctx, "task.get", input, &output)
+	return output, err
+}
+
+// TaskList calls task.list.
+func (c *Client) TaskList(ctx context.Context, input TaskListInput) (TaskListOutput, error) {
+	var output TaskListOutput
+	err := c.call(ctx, "task.list", input, &output)
+	return output, err
+}
+
+// TaskUpdate calls task.update.
+func (c *Client) TaskUpdate(ctx context.Context, input TaskUpdateInput) (TaskUpdateOutput, error) {
+	var output TaskUpdateOutput
+	err := c.call(ctx, "task.update", input, &output)
+	return output, err
+}
+
+// TaskWatch calls task.watch and passes every event the server sends to
+// onEvent, until the subscription ends, ctx is cancelled or onEvent returns
+// an error.
+func (c *Client) TaskWatch(ctx context.Context, input TaskWatchInput, onEvent func(event TaskWatchOutput) error) error {
+	return c.subscribe(ctx, "task.watch", input, func(data []byte) error {
+		var event TaskWatchOutput
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		return onEvent(event)
+	})
+}
+
+// post POSTs the envelope body of a call of method to the server. Reading body
+// from a *bytes.Reader sends it with a Content-Length rather than chunked, and
+// lets the transport resend calls that are safe to repeat when the server closed
+// the keep-alive connection they were sent on. The request passes through the
+// Client's interceptors first. When ctx has a deadline, the time left when the
+// request is sent goes with it in the X-Xrpc-Timeout header, so the server
+// stops working on the call once the caller stops waiting for it and hands
+// what is left of the budget on to the services it calls.
+func (c *Client) post(ctx context.Context, method string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range c.Header {
+		// Copied, as interceptors may add to them
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if m, ok := methodTable[method]; ok && m.kind != "mutation" {
+		// A nil Idempotency-Key marks the request idempotent for the transport
+		// without being sent
+		req.Header["Idempotency-Key"] = nil
+	}
+	next := func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			// Rounded up, so a budget of less than a millisecond is not sent as 0
+			left := time.Until(deadline) + time.Millisecond - 1
+			req.Header.Set("X-Xrpc-Timeout", strconv.FormatInt(int64(left/time.Millisecond), 10))
+		}
+		return c.httpClient.Do(req.WithContext(ctx))
+	}
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return interceptor(ctx, method, req, inner)
+		}
+	}
+	return next(ctx, req)
+}
+
+// call calls method with input and decodes its result into output, retrying
+// the failures the retry policy allows unless the method's circuit breaker
+// opens.
+func (c *Client) call(ctx context.Context, method string, input, output interface{}) error {
+	body, err := callBody(method, input)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if !c.breakers.allow(method) {
+			return ErrCircuitOpen
+		}
+		result, retryAfter, err := c.send(ctx, method, body)
+		c.breakers.record(method, err)
+		if err == nil {
+			return json.Unmarshal(result, output)
+		}
+		wait, ok := c.retry.backoff(ctx, method, err, attempt, retryAfter, time.Since(start))
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes one attempt at a call, returning its result or the wait the
+// server asked for with Retry-After when it failed. Errors are read from the
+// envelope whatever the status, as SetErrorStatus can keep them in-band with
+// 200.
+func (c *Client) attempt(ctx context.Context, method string, body []byte) (json.RawMessage, time.Duration, error) {
+	resp, err := c.post(ctx, method, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := responseError(method, data)
+		// Proxies in front of the server answer without an error envelope
+		if _, ok := err.(*Error); !ok && gatewayStatus(resp.StatusCode) {
+			err = NewError(CodeUnavailable, resp.Status)
+		}
+		return nil, retryAfter(resp.Header.Get("Retry-After")), err
+	}
+	var response struct {
+		Result json.RawMessage  `json:"result"`
+		Error  *json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, 0, fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		return nil, retryAfter(resp.Header.Get("Retry-After")), responseError(method, data)
+	}
+	return response.Result, 0, nil
+}
+
+// subscribe calls method with input and passes the data of every event the
+// server sends to onEvent, stopping at an error event.
+func (c *Client) subscribe(ctx context.Context, method string, input interface{}, onEvent func(data []byte) error) error {
+	body, err := callBody(method, input)
+	if err != nil {
+		return err
+	}
+	resp, err := c.post(ctx, method, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Errors kept in-band with SetErrorStatus come as a 200 JSON envelope
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		data, _ := io.ReadAll(resp.Body)
+		return responseError(method, data)
+	}
+	var event string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			if event == "error" {
+				return responseError(method, data)
+			}
+			if err := onEvent(data); err != nil {
+				return err
+			}
+			event = ""
+		}
+	}
+	return scanner.Err()
+}
+
+// callBody encodes the envelope of a call of method with input.
+func callBody(method string, input interface{}) ([]byte, error) {
+	return json.Marshal(struct {
+		Method string      `json:"method"`
+		Params interface{} `json:"params"`
+	}{method, input})
+}
+
+// gatewayStatus reports whether status is one proxies answer with when the
+// server is unreachable or overloaded.
+func gatewayStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
@@ -0,0 +1,703 @@
+package xrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Codec decodes the JSON params of calls and encodes their results. Routers use
+// encoding/json by default; Router.SetCodec replaces it, such as with a faster
+// implementation. Request envelopes, errors, streams and subscription events
+// are always encoded with encoding/json.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// StdCodec is the Codec of encoding/json.
+type StdCodec struct{}
+
+// Marshal encodes v with json.Marshal.
+func (StdCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data into v with json.Unmarshal.
+func (StdCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// SetCodec sets the Codec decoding params and encoding results, instead of
+// encoding/json. Set it before serving requests.
+func (r *Router) SetCodec(codec Codec) *Router {
+	r.codec = codec
+	return r
+}
+
+// marshal encodes result into a pooled buffer with the router's Codec, in the
+// {"result": ...} envelope unless envelope is false.
+func (r *Router) marshal(result interface{}, envelope bool) (*encodeBuffer, error) {
+	if r.codec == nil {
+		if envelope {
+			return encodeJSON(resultEnvelope{Result: result})
+		}
+		return encodeJSON(result)
+	}
+
+	buf := encodeBuffers.Get().(*encodeBuffer)
+	if envelope {
+		buf.WriteString(`{"result":`)
+	}
+	var data []byte
+	var err error
+	data, err = r.codec.Marshal(result)
+	if err != nil {
+		buf.release()
+		return nil, err
+	}
+	buf.Write(data)
+	if envelope {
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	return buf, nil
+}
+
+// unmarshal decodes the params of a call with the router's Codec.
+func (r *Router) unmarshal(params []byte, input interface{}) error {
+	if r.codec == nil {
+		return json.Unmarshal(params, input)
+	}
+	return r.codec.Unmarshal(params, input)
+}
+
+// paramsError reports an error decoding params: values of the wrong JSON type
+// as ValidationErrors on their field, such as "tasks[1].title: must be a
+// string, got number", or without a path when params themselves are of the
+// wrong type ("must be an object, got array"), and syntax errors with their
+// offset in params. Other errors, such as unknown enum values, keep their
+// message.
+func paramsError(params []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Type != nil {
+		path := pathAt(params, typeErr.Offset)
+		return ValidationErrors{{
+			Field:   fieldOf(path),
+			Path:    path,
+			Message: fmt.Sprintf("must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value),
+		}}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return Errorf(CodeInvalidArgument, "Invalid params: %v at offset %d", err, syntaxErr.Offset)
+	}
+	return Errorf(CodeInvalidArgument, "Invalid params: %v", err)
+}
+
+// pathAt returns the path of the value of data that ends at offset, or of the
+// array or object starting there, reading the tokens of data up to offset.
+func pathAt(data []byte, offset int64) []PathSegment {
+	// A container of the current value, with the key or index of the value
+	type container struct {
+		segment   PathSegment
+		object    bool
+		expectKey bool
+	}
+	var containers []container
+	var path []PathSegment
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.InputOffset() < offset {
+		token, err := decoder.Token()
+		if err != nil {
+			return path
+		}
+		if token == json.Delim('}') || token == json.Delim(']') {
+			containers = containers[:len(containers)-1]
+			continue
+		}
+		if n := len(containers); n > 0 {
+			top := &containers[n-1]
+			if top.expectKey {
+				top.segment.Key, _ = token.(string)
+				top.expectKey = false
+				continue
+			}
+			if top.object {
+				top.expectKey = true
+			} else {
+				top.segment.Index++
+			}
+		}
+		path = path[:0]
+		for _, c := range containers {
+			path = append(path, c.segment)
+		}
+		switch token {
+		case json.Delim('{'):
+			containers = append(containers, container{object: true, expectKey: true})
+		case json.Delim('['):
+			containers = append(containers, container{segment: PathSegment{Index: -1, IsIndex: true}})
+		}
+	}
+	return path
+}
+
+// fieldOf renders path as a field name, such as "tasks[1].title".
+func fieldOf(path []PathSegment) string {
+	var field strings.Builder
+	for _, segment := range path {
+		switch {
+		case segment.IsIndex:
+			fmt.Fprintf(&field, "[%d]", segment.Index)
+		case field.Len() > 0:
+			field.WriteByte('.')
+			field.WriteString(segment.Key)
+		default:
+			field.WriteString(segment.Key)
+		}
+	}
+	return field.String()
+}
+
+// jsonTypeName names the JSON type values of t decode from.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "a base64 string"
+		}
+		return "an array"
+	}
+	return "an object"
+}
+
+// wireCodec is a binary encoding accepted and sent besides JSON. Values are the
+// ones encoding/json decodes to: nil, bool, json.Number or another number,
+// string, []interface{} and map[string]interface{}.
+type wireCodec struct {
+	contentType string
+	decode      func(data []byte) (interface{}, error)
+	encode      func(value interface{}) ([]byte, error)
+}
+
+// wireCodecs maps the media types of the binary encodings to them.
+var wireCodecs = map[string]wireCodec{
+	"application/msgpack":   {"application/msgpack", decodeMsgpack, encodeMsgpack},
+	"application/x-msgpack": {"application/msgpack", decodeMsgpack, encodeMsgpack},
+	"application/cbor":      {"application/cbor", decodeCBOR, encodeCBOR},
+}
+
+// maxDecodeDepth bounds the nesting of decoded arrays and maps.
+const maxDecodeDepth = 1000
+
+// codecFor returns the binary encoding a media type names, if any.
+func codecFor(mediaType string) (wireCodec, bool) {
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return wireCodec{}, false
+	}
+	codec, ok := wireCodecs[mediaType]
+	return codec, ok
+}
+
+// decodeRequest decodes the envelope of a POST request as JSON, or as MessagePack
+// or CBOR when its Content-Type names them.
+func decodeRequest(req *http.Request, request interface{}) error {
+	codec, ok := codecFor(req.Header.Get("Content-Type"))
+	if !ok {
+		return json.NewDecoder(req.Body).Decode(request)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	value, err := codec.decode(data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, request)
+}
+
+// encodeResult transcodes a JSON result body to the first binary encoding the
+// request's Accept header names, returning the body and its Content-Type.
+func encodeResult(req *http.Request, body []byte) ([]byte, string) {
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		codec, ok := codecFor(accepted)
+		if !ok {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			break
+		}
+		encoded, err := codec.encode(value)
+		if err != nil {
+			break
+		}
+		return encoded, codec.contentType
+	}
+	return body, "application/json"
+}
+
+// encodeMsgpack encodes a value as MessagePack, with map keys sorted so equal
+// values encode to equal bytes.
+func encodeMsgpack(value interface{}) ([]byte, error) {
+	return appendMsgpack(nil, value)
+}
+
+func appendMsgpack(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(buf, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(f)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(v)), nil
+	case string:
+		buf = appendMsgpackLength(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(buf, v...), nil
+	case []interface{}:
+		buf = appendMsgpackLength(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			var err error
+			if buf, err = appendMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]interface{}:
+		buf = appendMsgpackLength(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range sortedKeys(v) {
+			var err error
+			buf, _ = appendMsgpack(buf, key)
+			if buf, err = appendMsgpack(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("msgpack: cannot encode %T", value)
+}
+
+// appendMsgpackInt appends i in the smallest MessagePack integer format.
+func appendMsgpackInt(buf []byte, i int64) []byte {
+	switch {
+	case i >= -32 && i <= math.MaxInt8:
+		// Positive and negative fixint
+		return append(buf, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(buf, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(i))
+}
+
+// appendMsgpackLength appends the header of a string, array or map of n items:
+// fix|n below fixLimit, else the 8-bit (if any), 16-bit or 32-bit format.
+func appendMsgpackLength(buf []byte, n int, fix byte, fixLimit int, format8, format16, format32 byte) []byte {
+	switch {
+	case n < fixLimit:
+		return append(buf, fix|byte(n))
+	case format8 != 0 && n <= math.MaxUint8:
+		return append(buf, format8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, format16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(buf, format32), uint32(n))
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodeCBOR encodes a value as CBOR (RFC 8949) with definite lengths and map
+// keys sorted, so equal values encode to equal bytes.
+func encodeCBOR(value interface{}) ([]byte, error) {
+	return appendCBOR(nil, value)
+}
+
+func appendCBOR(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xf6), nil
+	case bool:
+		if v {
+			return append(buf, 0xf5), nil
+		}
+		return append(buf, 0xf4), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			if i < 0 {
+				return appendCBORHead(buf, 1, uint64(-1-i)), nil
+			}
+			return appendCBORHead(buf, 0, uint64(i)), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(f)), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(v)), nil
+	case string:
+		return append(appendCBORHead(buf, 3, uint64(len(v))), v...), nil
+	case []interface{}:
+		buf = appendCBORHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			var err error
+			if buf, err = appendCBOR(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]interface{}:
+		buf = appendCBORHead(buf, 5, uint64(len(v)))
+		for _, key := range sortedKeys(v) {
+			var err error
+			buf, _ = appendCBOR(buf, key)
+			if buf, err = appendCBOR(buf, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("cbor: cannot encode %T", value)
+}
+
+// appendCBORHead appends the initial byte of a major type with its argument n,
+// in the shortest form.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+}
+
+// binaryDecoder reads MessagePack or CBOR values from data, bounding lengths by
+// the bytes left so hostile input cannot make it allocate more than it sent.
+type binaryDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// read consumes the next n bytes.
+func (d *binaryDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	value := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return value, nil
+}
+
+// uint consumes a big-endian unsigned integer of size bytes.
+func (d *binaryDecoder) uint(size int) (uint64, error) {
+	data, err := d.read(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range data {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// array decodes n items with decode. Every item takes at least one byte.
+func (d *binaryDecoder) array(n uint64, decode func() (interface{}, error)) (interface{}, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if d.depth++; d.depth > maxDecodeDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	defer func() { d.depth-- }()
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// object decodes a map of n string keys and values with decode. Every entry
+// takes at least two bytes.
+func (d *binaryDecoder) object(n uint64, decode func() (interface{}, error)) (interface{}, error) {
+	if n > uint64(len(d.data)-d.pos)/2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if d.depth++; d.depth > maxDecodeDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	defer func() { d.depth-- }()
+	object := make(map[string]interface{}, n)
+	for i := uint64(0); i < n; i++ {
+		key, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("map keys must be strings")
+		}
+		value, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		object[name] = value
+	}
+	return object, nil
+}
+
+// decodeAll decodes data as one value with decode, rejecting trailing bytes.
+func decodeAll(d *binaryDecoder, decode func() (interface{}, error)) (interface{}, error) {
+	value, err := decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("trailing data after value")
+	}
+	return value, nil
+}
+
+// decodeMsgpack decodes a MessagePack value. Binary values decode to []byte,
+// which encoding/json writes as base64 like generated []byte fields.
+func decodeMsgpack(data []byte) (interface{}, error) {
+	d := &binaryDecoder{data: data}
+	value, err := decodeAll(d, d.msgpack)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+	return value, nil
+}
+
+func (d *binaryDecoder) msgpack() (interface{}, error) {
+	head, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.msgpackString(uint64(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(uint64(c&0x0f), d.msgpack)
+	case c&0xf0 == 0x80:
+		return d.object(uint64(c&0x0f), d.msgpack)
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := d.uint(size)
+		// Sign-extend the size-byte two's complement value
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	}
+	// The remaining formats are followed by a length of 1, 2 or 4 bytes
+	var size int
+	switch c {
+	case 0xc4, 0xd9:
+		size = 1
+	case 0xc5, 0xda, 0xdc, 0xde:
+		size = 2
+	case 0xc6, 0xdb, 0xdd, 0xdf:
+		size = 4
+	default:
+		return nil, fmt.Errorf("unsupported type 0x%02x", c)
+	}
+	n, err := d.uint(size)
+	if err != nil {
+		return nil, err
+	}
+	switch c {
+	case 0xc4, 0xc5, 0xc6:
+		return d.read(n)
+	case 0xd9, 0xda, 0xdb:
+		return d.msgpackString(n)
+	case 0xdc, 0xdd:
+		return d.array(n, d.msgpack)
+	}
+	return d.object(n, d.msgpack)
+}
+
+func (d *binaryDecoder) msgpackString(n uint64) (interface{}, error) {
+	data, err := d.read(n)
+	return string(data), err
+}
+
+// decodeCBOR decodes a CBOR value. Tags are skipped, keeping their content, and
+// byte strings decode to []byte; indefinite lengths are not supported.
+func decodeCBOR(data []byte) (interface{}, error) {
+	d := &binaryDecoder{data: data}
+	value, err := decodeAll(d, d.cbor)
+	if err != nil {
+		return nil, fmt.Errorf("cbor: %w", err)
+	}
+	return value, nil
+}
+
+func (d *binaryDecoder) cbor() (interface{}, error) {
+	head, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := head[0]>>5, head[0]&0x1f
+	if major == 7 {
+		return d.cborSimple(info)
+	}
+	n := uint64(info)
+	switch {
+	case info >= 24 && info <= 27:
+		if n, err = d.uint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	case info == 31:
+		return nil, errors.New("indefinite lengths are not supported")
+	case info > 27:
+		return nil, fmt.Errorf("invalid additional information %d", info)
+	}
+	switch major {
+	case 0:
+		return n, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return -1 - float64(n), nil
+		}
+		return -1 - int64(n), nil
+	case 2:
+		return d.read(n)
+	case 3:
+		data, err := d.read(n)
+		return string(data), err
+	case 4:
+		return d.array(n, d.cbor)
+	case 5:
+		return d.object(n, d.cbor)
+	}
+	return d.cbor()
+}
+
+// cborSimple decodes the simple values and floats of major type 7.
+func (d *binaryDecoder) cborSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// null and undefined
+		return nil, nil
+	case 25:
+		n, err := d.uint(2)
+		return halfFloat(uint16(n)), err
+	case 26:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 27:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	}
+	return nil, fmt.Errorf("unsupported simple value %d", info)
+}
+
+// halfFloat converts an IEEE 754 half-precision float.
+func halfFloat(h uint16) float64 {
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		value = math.Inf(1)
+		if mantissa != 0 {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if h&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
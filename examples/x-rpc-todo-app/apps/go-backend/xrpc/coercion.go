@@ -0,0 +1,289 @@
+package xrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EnableLenientDecoding makes the router coerce the params clients send as the
+// wrong JSON type where the contract allows it: numeric strings such as
+// "limit": "20" from a form become numbers, and numbers become strings where
+// strings are expected. Values that cannot be coerced fail the call with a
+// validation error on their field instead of the decoder's type error. Set it
+// before serving requests.
+func (r *Router) EnableLenientDecoding() *Router {
+	r.lenientDecoding = true
+	return r
+}
+
+// coerceParams decodes params, coerces their values with coerce and encodes
+// them again. Params that are not an object are left to the decoder to report.
+func coerceParams(params json.RawMessage, coerce func(v map[string]interface{}, path []PathSegment, errs *ValidationErrors)) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.UseNumber()
+	var value map[string]interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return params, nil
+	}
+	var errs ValidationErrors
+	coerce(value, nil, &errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return json.Marshal(value)
+}
+
+// coerceString returns value as a string, formatting numbers, or reports it in
+// errs.
+func coerceString(value interface{}, path []PathSegment, errs *ValidationErrors) interface{} {
+	switch v := value.(type) {
+	case nil, string:
+		return value
+	case json.Number:
+		return v.String()
+	}
+	coercionFailed(errs, path, "must be a string")
+	return value
+}
+
+// coerceNumber returns value as a number, parsing numeric strings, or reports
+// it in errs.
+func coerceNumber(value interface{}, path []PathSegment, errs *ValidationErrors) interface{} {
+	switch v := value.(type) {
+	case nil, json.Number:
+		return value
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		coercionFailed(errs, path, fmt.Sprintf("must be a number, got %q", v))
+		return value
+	}
+	coercionFailed(errs, path, "must be a number")
+	return value
+}
+
+// coerceInteger returns value as an integer, parsing integer strings, or
+// reports it in errs.
+func coerceInteger(value interface{}, path []PathSegment, errs *ValidationErrors) interface{} {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return value
+	case json.Number:
+		text = v.String()
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		coercionFailed(errs, path, "must be an integer")
+		return value
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		coercionFailed(errs, path, "is out of range")
+		return value
+	}
+	if err != nil {
+		coercionFailed(errs, path, fmt.Sprintf("must be an integer, got %q", text))
+		return value
+	}
+	return json.Number(strconv.FormatInt(n, 10))
+}
+
+// coercionFailed reports the value at path in errs.
+func coercionFailed(errs *ValidationErrors, path []PathSegment, message string) {
+	*errs = append(*errs, &ValidationError{
+		Field:   fieldOf(path),
+		Path:    path,
+		Message: message,
+	})
+}
+
+// pathTo returns the path of an element of the value at path, copied so paths
+// of sibling elements do not share their backing array.
+func pathTo(path []PathSegment, segment PathSegment) []PathSegment {
+	return append(append(make([]PathSegment, 0, len(path)+1), path...), segment)
+}
+
+// coerceAssignee coerces the values of a decoded Assignee in place.
+func coerceAssignee(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+	if value, ok := v["name"]; ok {
+		v["name"] = coerceString(value, pathTo(path, PathSegment{Key: "name"}), errs)
+	}
+	if value, ok := v["email"]; ok {
+		v["email"] = coerceString(value, pathTo(path, PathSegment{Key: "email"}), errs)
+	}
+}
+
+// coerceSubtask coerces the values of a decoded Subtask in place.
+func coerceSubtask(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+}
+
+// coerceSubtaskAddInput coerces the values of a decoded SubtaskAddInput in place.
+func coerceSubtaskAddInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["taskId"]; ok {
+		v["taskId"] = coerceString(value, pathTo(path, PathSegment{Key: "taskId"}), errs)
+	}
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+}
+
+// coerceSubtaskToggleInput coerces the values of a decoded SubtaskToggleInput in place.
+func coerceSubtaskToggleInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["taskId"]; ok {
+		v["taskId"] = coerceString(value, pathTo(path, PathSegment{Key: "taskId"}), errs)
+	}
+	if value, ok := v["subtaskId"]; ok {
+		v["subtaskId"] = coerceString(value, pathTo(path, PathSegment{Key: "subtaskId"}), errs)
+	}
+}
+
+// coerceTask coerces the values of a decoded Task in place.
+func coerceTask(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+	if value, ok := v["description"]; ok {
+		v["description"] = coerceString(value, pathTo(path, PathSegment{Key: "description"}), errs)
+	}
+	if value, ok := v["assignee"]; ok {
+		if object, ok := value.(map[string]interface{}); ok {
+			coerceAssignee(object, pathTo(path, PathSegment{Key: "assignee"}), errs)
+		}
+	}
+	if value, ok := v["subtasks"]; ok {
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				if object, ok := item.(map[string]interface{}); ok {
+					coerceSubtask(object, pathTo(pathTo(path, PathSegment{Key: "subtasks"}), PathSegment{Index: i, IsIndex: true}), errs)
+				}
+			}
+		}
+	}
+	if value, ok := v["estimatedHours"]; ok {
+		v["estimatedHours"] = coerceNumber(value, pathTo(path, PathSegment{Key: "estimatedHours"}), errs)
+	}
+	if value, ok := v["position"]; ok {
+		v["position"] = coerceInteger(value, pathTo(path, PathSegment{Key: "position"}), errs)
+	}
+}
+
+// coerceTaskCreateInput coerces the values of a decoded TaskCreateInput in place.
+func coerceTaskCreateInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+	if value, ok := v["description"]; ok {
+		v["description"] = coerceString(value, pathTo(path, PathSegment{Key: "description"}), errs)
+	}
+	if value, ok := v["estimatedHours"]; ok {
+		v["estimatedHours"] = coerceNumber(value, pathTo(path, PathSegment{Key: "estimatedHours"}), errs)
+	}
+}
+
+// coerceTaskDeleteInput coerces the values of a decoded TaskDeleteInput in place.
+func coerceTaskDeleteInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+}
+
+// coerceTaskListInput coerces the values of a decoded TaskListInput in place.
+func coerceTaskListInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["cursor"]; ok {
+		v["cursor"] = coerceString(value, pathTo(path, PathSegment{Key: "cursor"}), errs)
+	}
+	if value, ok := v["pageSize"]; ok {
+		v["pageSize"] = coerceInteger(value, pathTo(path, PathSegment{Key: "pageSize"}), errs)
+	}
+}
+
+// coerceTaskListOutput coerces the values of a decoded TaskListOutput in place.
+func coerceTaskListOutput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["tasks"]; ok {
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				if object, ok := item.(map[string]interface{}); ok {
+					coerceTaskSummary(object, pathTo(pathTo(path, PathSegment{Key: "tasks"}), PathSegment{Index: i, IsIndex: true}), errs)
+				}
+			}
+		}
+	}
+	if value, ok := v["total"]; ok {
+		v["total"] = coerceInteger(value, pathTo(path, PathSegment{Key: "total"}), errs)
+	}
+	if value, ok := v["nextCursor"]; ok {
+		v["nextCursor"] = coerceString(value, pathTo(path, PathSegment{Key: "nextCursor"}), errs)
+	}
+}
+
+// coerceTaskSummary coerces the values of a decoded TaskSummary in place.
+func coerceTaskSummary(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+	if value, ok := v["subtaskCount"]; ok {
+		v["subtaskCount"] = coerceInteger(value, pathTo(path, PathSegment{Key: "subtaskCount"}), errs)
+	}
+	if value, ok := v["subtaskCompletedCount"]; ok {
+		v["subtaskCompletedCount"] = coerceInteger(value, pathTo(path, PathSegment{Key: "subtaskCompletedCount"}), errs)
+	}
+	if value, ok := v["estimatedHours"]; ok {
+		v["estimatedHours"] = coerceNumber(value, pathTo(path, PathSegment{Key: "estimatedHours"}), errs)
+	}
+	if value, ok := v["position"]; ok {
+		v["position"] = coerceInteger(value, pathTo(path, PathSegment{Key: "position"}), errs)
+	}
+}
+
+// coerceTaskUpdateInput coerces the values of a decoded TaskUpdateInput in place.
+func coerceTaskUpdateInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["id"]; ok {
+		v["id"] = coerceString(value, pathTo(path, PathSegment{Key: "id"}), errs)
+	}
+	if value, ok := v["title"]; ok {
+		v["title"] = coerceString(value, pathTo(path, PathSegment{Key: "title"}), errs)
+	}
+	if value, ok := v["description"]; ok {
+		v["description"] = coerceString(value, pathTo(path, PathSegment{Key: "description"}), errs)
+	}
+	if value, ok := v["estimatedHours"]; ok {
+		v["estimatedHours"] = coerceNumber(value, pathTo(path, PathSegment{Key: "estimatedHours"}), errs)
+	}
+}
+
+// coerceTaskWatchInput coerces the values of a decoded TaskWatchInput in place.
+func coerceTaskWatchInput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["taskId"]; ok {
+		v["taskId"] = coerceString(value, pathTo(path, PathSegment{Key: "taskId"}), errs)
+	}
+}
+
+// coerceTaskWatchOutput coerces the values of a decoded TaskWatchOutput in place.
+func coerceTaskWatchOutput(v map[string]interface{}, path []PathSegment, errs *ValidationErrors) {
+	if value, ok := v["taskId"]; ok {
+		v["taskId"] = coerceString(value, pathTo(path, PathSegment{Key: "taskId"}), errs)
+	}
+}
@@ -0,0 +1,338 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// BreakingChange is a change between two versions of the contract that breaks
+// existing clients: a removed method, or an input that no longer accepts what
+// it did or an output that returns what it did not.
+type BreakingChange struct {
+	Method string `json:"method"`
+	// Path locates the change in the method's params or result, such as
+	// "input.status" or "output.tasks[].priority"; empty for the method itself
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// String describes the change, such as `task.list input.status: no longer
+// accepts "cancelled"`.
+func (c BreakingChange) String() string {
+	if c.Path == "" {
+		return c.Method + ": " + c.Message
+	}
+	return c.Method + " " + c.Path + ": " + c.Message
+}
+
+// ContractSchema returns the schema of the contract the package was generated
+// from: every method with the JSON Schemas of its input and output, as
+// xrpc.introspect answers. Publish it with each release to check the next one
+// against it with CheckCompat.
+func ContractSchema() []byte {
+	data, _ := json.MarshalIndent(map[string]interface{}{"methods": methodInfos, "schemaHash": SchemaHash}, "", "  ")
+	return data
+}
+
+// CheckCompat lists the changes from oldSchema to newSchema that break existing
+// clients: removed methods, changed kinds, and inputs that no longer accept
+// what they did (new required fields, narrowed enums, tighter bounds) or
+// outputs that return what they did not (removed or optional fields, widened
+// enums). Both schemas are documents as ContractSchema returns and
+// xrpc.introspect answers, with or without the {"result": ...} envelope. Run it
+// at startup against the previously published schema:
+//
+//	changes, err := CheckCompat(published, ContractSchema())
+//	if err != nil || len(changes) > 0 {
+//	    log.Fatalf("contract breaks clients: %v %v", changes, err)
+//	}
+//
+// Unions and recursive types are compared as a whole.
+func CheckCompat(oldSchema, newSchema []byte) ([]BreakingChange, error) {
+	oldMethods, err := decodeContractSchema(oldSchema)
+	if err != nil {
+		return nil, fmt.Errorf("old schema: %w", err)
+	}
+	newMethods, err := decodeContractSchema(newSchema)
+	if err != nil {
+		return nil, fmt.Errorf("new schema: %w", err)
+	}
+
+	index := make(map[string]MethodInfo, len(newMethods))
+	for _, method := range newMethods {
+		index[method.Name] = method
+	}
+	c := compatChecker{}
+	for _, old := range oldMethods {
+		current, ok := index[old.Name]
+		if !ok {
+			c.report(old.Name, "", "method removed")
+			continue
+		}
+		if current.Kind != old.Kind {
+			c.report(old.Name, "", fmt.Sprintf("changes kind from %s to %s", old.Kind, current.Kind))
+			continue
+		}
+		if err := c.compareRaw(old.Name, "input", old.Input, current.Input, true); err != nil {
+			return nil, err
+		}
+		if err := c.compareRaw(old.Name, "output", old.Output, current.Output, false); err != nil {
+			return nil, err
+		}
+	}
+	return c.changes, nil
+}
+
+// decodeContractSchema decodes the methods of a schema document.
+func decodeContractSchema(data []byte) ([]MethodInfo, error) {
+	var document struct {
+		Methods []MethodInfo `json:"methods"`
+		Result  *struct {
+			Methods []MethodInfo `json:"methods"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Result != nil {
+		return document.Result.Methods, nil
+	}
+	return document.Methods, nil
+}
+
+// compatChecker collects breaking changes while comparing schemas.
+type compatChecker struct {
+	changes []BreakingChange
+}
+
+// report records a breaking change.
+func (c *compatChecker) report(method, path, message string) {
+	c.changes = append(c.changes, BreakingChange{Method: method, Path: path, Message: message})
+}
+
+// compareRaw compares two schemas given as JSON.
+func (c *compatChecker) compareRaw(method, path string, old, current json.RawMessage, input bool) error {
+	var oldSchema, newSchema map[string]interface{}
+	if err := json.Unmarshal(old, &oldSchema); err != nil {
+		return fmt.Errorf("old schema of %s %s: %w", method, path, err)
+	}
+	if err := json.Unmarshal(current, &newSchema); err != nil {
+		return fmt.Errorf("new schema of %s %s: %w", method, path, err)
+	}
+	c.compare(method, path, oldSchema, newSchema, input)
+	return nil
+}
+
+// compare reports the breaking changes from old to current of the schema at
+// path. Inputs break when they stop accepting a value, outputs when they can
+// return a value they could not.
+func (c *compatChecker) compare(method, path string, old, current map[string]interface{}, input bool) {
+	old, oldNullable := nonNullSchema(old)
+	current, newNullable := nonNullSchema(current)
+	if input && oldNullable && !newNullable {
+		c.report(method, path, "no longer accepts null")
+	}
+	if !input && newNullable && !oldNullable {
+		c.report(method, path, "may now be null")
+	}
+
+	if !sameKeyword(old, current, "$ref") || !sameKeyword(old, current, "anyOf") ||
+		!sameKeyword(old, current, "oneOf") || !sameKeyword(old, current, "prefixItems") {
+		c.report(method, path, "changes type")
+		return
+	}
+	if !sameKeyword(old, current, "type") && !widensNumber(old["type"], current["type"], input) {
+		c.report(method, path, fmt.Sprintf("changes type from %v to %v", old["type"], current["type"]))
+		return
+	}
+
+	c.compareEnum(method, path, old, current, input)
+	if input {
+		c.compareBounds(method, path, old, current)
+	}
+
+	switch current["type"] {
+	case "object":
+		c.compareObject(method, path, old, current, input)
+	case "array":
+		oldItems, _ := old["items"].(map[string]interface{})
+		newItems, _ := current["items"].(map[string]interface{})
+		if oldItems != nil && newItems != nil {
+			c.compare(method, path+"[]", oldItems, newItems, input)
+		}
+	}
+}
+
+// compareEnum reports the enum values an input dropped or an output added.
+func (c *compatChecker) compareEnum(method, path string, old, current map[string]interface{}, input bool) {
+	oldValues, oldOK := old["enum"].([]interface{})
+	newValues, newOK := current["enum"].([]interface{})
+	if !oldOK && !newOK {
+		return
+	}
+	if input {
+		if !newOK {
+			return
+		}
+		if !oldOK {
+			c.report(method, path, "now only accepts the values of an enum")
+			return
+		}
+		for _, value := range missingValues(oldValues, newValues) {
+			c.report(method, path, "no longer accepts "+value)
+		}
+		return
+	}
+	if !oldOK {
+		return
+	}
+	if !newOK {
+		c.report(method, path, "may now return values outside its enum")
+		return
+	}
+	for _, value := range missingValues(newValues, oldValues) {
+		c.report(method, path, "may now return "+value)
+	}
+}
+
+// compareBounds reports input constraints that became stricter.
+func (c *compatChecker) compareBounds(method, path string, old, current map[string]interface{}) {
+	for _, keyword := range []string{"minLength", "minimum", "exclusiveMinimum", "minItems", "minProperties"} {
+		newBound, ok := current[keyword].(float64)
+		if !ok {
+			continue
+		}
+		if oldBound, ok := old[keyword].(float64); !ok || newBound > oldBound {
+			c.report(method, path, fmt.Sprintf("raises %s to %v", keyword, newBound))
+		}
+	}
+	for _, keyword := range []string{"maxLength", "maximum", "exclusiveMaximum", "maxItems", "maxProperties"} {
+		newBound, ok := current[keyword].(float64)
+		if !ok {
+			continue
+		}
+		if oldBound, ok := old[keyword].(float64); !ok || newBound < oldBound {
+			c.report(method, path, fmt.Sprintf("lowers %s to %v", keyword, newBound))
+		}
+	}
+	for _, keyword := range []string{"pattern", "format"} {
+		if _, ok := current[keyword]; ok && !sameKeyword(old, current, keyword) {
+			c.report(method, path, fmt.Sprintf("changes %s to %v", keyword, current[keyword]))
+		}
+	}
+}
+
+// compareObject compares the properties of two object schemas.
+func (c *compatChecker) compareObject(method, path string, old, current map[string]interface{}, input bool) {
+	oldProperties, _ := old["properties"].(map[string]interface{})
+	newProperties, _ := current["properties"].(map[string]interface{})
+	oldRequired := requiredFields(old)
+	newRequired := requiredFields(current)
+
+	names := make([]string, 0, len(oldProperties)+len(newProperties))
+	for name := range oldProperties {
+		names = append(names, name)
+	}
+	for name := range newProperties {
+		if _, ok := oldProperties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := path + "." + name
+		oldField, inOld := oldProperties[name].(map[string]interface{})
+		newField, inNew := newProperties[name].(map[string]interface{})
+		if input {
+			switch {
+			case newRequired[name] && !inOld:
+				c.report(method, fieldPath, "adds a required field")
+				continue
+			case newRequired[name] && !oldRequired[name]:
+				c.report(method, fieldPath, "becomes required")
+			}
+		} else {
+			switch {
+			case oldRequired[name] && !inNew:
+				c.report(method, fieldPath, "removes a field")
+				continue
+			case oldRequired[name] && !newRequired[name]:
+				c.report(method, fieldPath, "becomes optional")
+			}
+		}
+		if inOld && inNew {
+			c.compare(method, fieldPath, oldField, newField, input)
+		}
+	}
+
+	// Records describe their values with additionalProperties
+	oldValues, _ := old["additionalProperties"].(map[string]interface{})
+	newValues, _ := current["additionalProperties"].(map[string]interface{})
+	if oldValues != nil && newValues != nil {
+		c.compare(method, path+".*", oldValues, newValues, input)
+	}
+}
+
+// missingValues returns the JSON of the values in from that are not in to.
+func missingValues(from, to []interface{}) []string {
+	present := make(map[string]bool, len(to))
+	for _, value := range to {
+		data, _ := json.Marshal(value)
+		present[string(data)] = true
+	}
+	var missing []string
+	for _, value := range from {
+		data, _ := json.Marshal(value)
+		if !present[string(data)] {
+			missing = append(missing, string(data))
+		}
+	}
+	return missing
+}
+
+// requiredFields returns the set of an object schema's required properties.
+func requiredFields(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	names, _ := schema["required"].([]interface{})
+	for _, name := range names {
+		if name, ok := name.(string); ok {
+			required[name] = true
+		}
+	}
+	return required
+}
+
+// nonNullSchema unwraps the {"anyOf": [schema, {"type": "null"}]} of nullable
+// types, reporting whether schema was nullable.
+func nonNullSchema(schema map[string]interface{}) (map[string]interface{}, bool) {
+	variants, ok := schema["anyOf"].([]interface{})
+	if !ok || len(variants) != 2 {
+		return schema, false
+	}
+	if null, ok := variants[1].(map[string]interface{}); !ok || null["type"] != "null" || len(null) != 1 {
+		return schema, false
+	}
+	inner, ok := variants[0].(map[string]interface{})
+	if !ok {
+		return schema, false
+	}
+	return inner, true
+}
+
+// widensNumber reports whether an integer input became a number, or a number
+// output an integer, which breaks no client.
+func widensNumber(oldType, newType interface{}, input bool) bool {
+	if input {
+		return oldType == "integer" && newType == "number"
+	}
+	return oldType == "number" && newType == "integer"
+}
+
+// sameKeyword reports whether keyword has the same value in both schemas.
+func sameKeyword(old, current map[string]interface{}, keyword string) bool {
+	oldValue, _ := json.Marshal(old[keyword])
+	newValue, _ := json.Marshal(current[keyword])
+	return string(oldValue) == string(newValue)
+}
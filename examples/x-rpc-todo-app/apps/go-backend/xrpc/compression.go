@@ -0,0 +1,113 @@
+package xrpc
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SetCompression compresses query and mutation results of at least minSize bytes
+// with gzip or deflate, whichever the client's Accept-Encoding prefers, so large
+// results need no compressing reverse proxy. Errors and subscription events are
+// sent uncompressed.
+func (r *Router) SetCompression(minSize int) *Router {
+	r.compression = true
+	r.compressionMinSize = minSize
+	return r
+}
+
+// compressor is a pooled gzip or zlib writer, reset onto each response.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressors pools the writers of each supported content coding, since
+// allocating one per response dominates the cost of small results.
+var compressors = map[string]*sync.Pool{
+	"gzip":    {New: func() interface{} { return gzip.NewWriter(nil) }},
+	"deflate": {New: func() interface{} { return zlib.NewWriter(nil) }},
+}
+
+// negotiateEncoding picks the content coding for a response from the request's
+// Accept-Encoding header: "gzip" or "deflate", by quality with gzip preferred
+// on ties, or "" when the client accepts neither.
+func negotiateEncoding(header string) string {
+	quality := map[string]float64{"gzip": -1, "deflate": -1}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		}
+		if _, ok := quality[name]; ok {
+			quality[name] = q
+		}
+	}
+	for name, q := range quality {
+		// Codings the header does not name take the quality of *
+		if q < 0 {
+			quality[name] = wildcard
+		}
+	}
+	if quality["gzip"] > 0 && quality["gzip"] >= quality["deflate"] {
+		return "gzip"
+	}
+	if quality["deflate"] > 0 {
+		return "deflate"
+	}
+	return ""
+}
+
+// writeResult writes the JSON body of a query or mutation result, compressed
+// when SetCompression enabled it and the client accepts it, and transcoded to
+// the binary encoding the request's Accept header names, if any.
+func (r *Router) writeResult(w http.ResponseWriter, req *http.Request, body []byte) {
+	body, contentType := encodeResult(req, body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	if contentType != "application/json" {
+		weakenETag(w)
+	}
+	if !r.compression {
+		w.Write(body)
+		return
+	}
+	// The response differs by Accept-Encoding even when sent uncompressed
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"))
+	if len(body) < r.compressionMinSize || encoding == "" {
+		w.Write(body)
+		return
+	}
+	weakenETag(w)
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Del("Content-Length")
+	pool := compressors[encoding]
+	cw := pool.Get().(compressor)
+	cw.Reset(w)
+	cw.Write(body)
+	cw.Close()
+	pool.Put(cw)
+}
+
+// weakenETag marks the ETag of a result sent transcoded or compressed as weak,
+// since those bytes differ from the JSON body a strong ETag validates.
+func weakenETag(w http.ResponseWriter) {
+	if etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		w.Header().Set("ETag", "W/"+etag)
+	}
+}
@@ -0,0 +1,156 @@
+package xrpc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyLimit caps the calls executing at once. Calls arriving while Max are
+// executing wait up to Wait for one to finish, and then fail with
+// RESOURCE_EXHAUSTED; with no Wait they are shed at once.
+type ConcurrencyLimit struct {
+	Max  int
+	Wait time.Duration
+	// Queue caps the calls waiting for a slot: calls arriving while Queue are
+	// waiting are shed at once. Zero leaves it unbounded.
+	Queue int
+	// RetryAfter is when shed calls are told to try again, in the Retry-After
+	// header and the RetryInfo details of their error; one second by default.
+	RetryAfter time.Duration
+}
+
+// concurrencyPool holds the execution slots of a limit, a buffered channel with
+// an element per executing call, the calls waiting for one, and the method
+// pattern it applies to.
+type concurrencyPool struct {
+	pattern string
+	limit   ConcurrencyLimit
+	slots   chan struct{}
+	queued  atomic.Int64
+}
+
+// newConcurrencyPool returns the pool of limit for methods matching pattern.
+func newConcurrencyPool(pattern string, limit ConcurrencyLimit) *concurrencyPool {
+	if limit.Max < 1 {
+		panic("xrpc: ConcurrencyLimit.Max must be at least 1")
+	}
+	if limit.RetryAfter <= 0 {
+		limit.RetryAfter = time.Second
+	}
+	return &concurrencyPool{pattern: pattern, limit: limit, slots: make(chan struct{}, limit.Max)}
+}
+
+// SetConcurrencyLimit caps the query and mutation calls executing at once across
+// all methods. Subscriptions are long-lived and not counted. Set it before
+// serving requests.
+func (r *Router) SetConcurrencyLimit(limit ConcurrencyLimit) *Router {
+	r.concurrency = newConcurrencyPool("", limit)
+	return r
+}
+
+// SetConcurrencyLimitFor gives methods matching pattern, using the same pattern
+// syntax as UseFor, a pool of their own: their calls share its slots, and
+// queue there before taking a slot of SetConcurrencyLimit, so expensive methods
+// such as "report.*" can't starve cheap ones. When several patterns match a
+// method, the last one set wins.
+func (r *Router) SetConcurrencyLimitFor(pattern string, limit ConcurrencyLimit) *Router {
+	mustValidPattern(pattern)
+	r.concurrencyPools = append(r.concurrencyPools, newConcurrencyPool(pattern, limit))
+	return r
+}
+
+// acquire takes a slot of method's pool, then of the global limit, and returns
+// the function releasing them once the call finishes. It fails with
+// RESOURCE_EXHAUSTED when a slot doesn't free up in time, or ctx's error.
+func (r *Router) acquire(ctx context.Context, method string) (func(), error) {
+	var pools []*concurrencyPool
+	for i := len(r.concurrencyPools) - 1; i >= 0; i-- {
+		pool := r.concurrencyPools[i]
+		if matchMethod(pool.pattern, method) {
+			pools = append(pools, pool)
+			break
+		}
+	}
+	if r.concurrency != nil {
+		pools = append(pools, r.concurrency)
+	}
+
+	acquired := 0
+	release := func() {
+		for _, pool := range pools[:acquired] {
+			<-pool.slots
+		}
+	}
+	for _, pool := range pools {
+		if err := pool.take(ctx); err != nil {
+			release()
+			return nil, err
+		}
+		acquired++
+	}
+	return release, nil
+}
+
+// take takes a slot of p, waiting up to its Wait for one to free up if its queue
+// has room.
+func (p *concurrencyPool) take(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if p.limit.Wait > 0 && p.enqueue() {
+		defer p.queued.Add(-1)
+		timer := time.NewTimer(p.limit.Wait)
+		defer timer.Stop()
+		select {
+		case p.slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return NewError(CodeResourceExhausted, "Too many concurrent calls, try again later").WithDetails(RetryInfo{RetryAfterMs: p.limit.RetryAfter.Milliseconds()})
+}
+
+// enqueue counts a call waiting for a slot of p, unless its queue is full.
+func (p *concurrencyPool) enqueue() bool {
+	if p.queued.Add(1) <= int64(p.limit.Queue) || p.limit.Queue <= 0 {
+		return true
+	}
+	p.queued.Add(-1)
+	return false
+}
+
+// ConcurrencyStats is the load of a concurrency limit: the calls executing and
+// those queued for a slot.
+type ConcurrencyStats struct {
+	// Pattern is the method pattern of a limit set with SetConcurrencyLimitFor,
+	// empty for the one of SetConcurrencyLimit.
+	Pattern   string
+	Max       int
+	Executing int
+	Queued    int
+}
+
+// ConcurrencyStats returns the load of every concurrency limit, the one of
+// SetConcurrencyLimit first, for metrics and health checks to watch how deep
+// their queues get.
+func (r *Router) ConcurrencyStats() []ConcurrencyStats {
+	var stats []ConcurrencyStats
+	pools := r.concurrencyPools
+	if r.concurrency != nil {
+		pools = append([]*concurrencyPool{r.concurrency}, pools...)
+	}
+	for _, pool := range pools {
+		stats = append(stats, ConcurrencyStats{
+			Pattern:   pool.pattern,
+			Max:       pool.limit.Max,
+			Executing: len(pool.slots),
+			Queued:    int(pool.queued.Load()),
+		})
+	}
+	return stats
+}
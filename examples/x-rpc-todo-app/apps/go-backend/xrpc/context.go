@@ -0,0 +1,149 @@
+package xrpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestInfo describes the RPC call currently being served.
+//
+// The ctx passed to handlers is a standard context.Context carrying the call's
+// deadline and cancellation, its RequestInfo, principal and dependencies, so
+// handlers pass it as is to database/sql, net/http and other clients taking one.
+// Code serving calls outside the router, such as a queue consumer, gives a plain
+// context.Context those values with WithRequestInfo, WithPrincipal and
+// WithDependency, and context.WithoutCancel keeps them for work outliving a call.
+type RequestInfo struct {
+	Method         string
+	Request        *http.Request
+	ResponseWriter http.ResponseWriter
+}
+
+// contextKey is unexported so values set by this package cannot collide with other packages.
+type contextKey int
+
+const (
+	requestInfoKey contextKey = iota
+	userIDKey
+	principalKey
+)
+
+// WithRequestInfo returns a copy of ctx carrying info.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey, info)
+}
+
+// RequestInfoFrom returns the RequestInfo stored in ctx by the router, if any.
+func RequestInfoFrom(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey).(RequestInfo)
+	return info, ok
+}
+
+// WithUserID returns a copy of ctx carrying the authenticated user ID.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFrom returns the user ID stored in ctx by WithUserID, if any.
+func UserIDFrom(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey).(string)
+	return userID, ok
+}
+
+// Principal is the caller an authentication middleware accepted, such as
+// BearerAuth, JWTAuth, APIKeyAuth or ClientCertAuth.
+type Principal struct {
+	// ID identifies the caller, such as a user or service account.
+	ID string
+	// Scheme is how the caller authenticated: "bearer", "jwt", "api-key" or "mtls".
+	Scheme string
+	// Claims holds what the credential says about the caller, such as a JWT's claims.
+	Claims map[string]interface{}
+}
+
+// WithPrincipal returns a copy of ctx carrying principal, and its ID as the user ID.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(WithUserID(ctx, principal.ID), principalKey, principal)
+}
+
+// PrincipalFrom returns the principal stored in ctx by WithPrincipal, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(Principal)
+	return principal, ok
+}
+
+// Key identifies a value of type T that middleware, interceptors and handlers
+// carry on a context.Context, such as a tenant or a feature flag. Keys are
+// compared by identity, not by name, so two packages creating keys with the
+// same name get distinct values:
+//
+//	var tenantKey = NewKey[string]("tenant")
+//
+//	ctx = tenantKey.Set(ctx, tenant)
+//	tenant, ok := tenantKey.Get(ctx)
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key, named name in its String.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// Set returns a copy of ctx carrying value under k.
+func (k *Key[T]) Set(ctx context.Context, value T) context.Context {
+	return &keyContext{Context: ctx, key: k, name: k.name, value: value}
+}
+
+// Get returns the value stored in ctx under k, if any.
+func (k *Key[T]) Get(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+// String returns the name of k.
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// keyContext carries the value of a key like context.WithValue, and returns
+// itself under keyContextKey so DataFrom can list the values of all keys.
+type keyContext struct {
+	context.Context
+	key   interface{}
+	name  string
+	value interface{}
+}
+
+// keyContextKey is the key a keyContext returns itself under.
+type keyContextKey struct{}
+
+// Value returns the value of c's key, c itself under keyContextKey, and the
+// values of the parent context otherwise.
+func (c *keyContext) Value(key interface{}) interface{} {
+	switch key {
+	case c.key:
+		return c.value
+	case keyContextKey{}:
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// DataFrom rebuilds the Data map the former Context of middleware and handlers
+// carried: the values set on ctx with Key.Set, by key name. Where keys share a
+// name, the value set last wins.
+//
+// Deprecated: DataFrom is kept for one release to ease porting middleware
+// written against the Data map; get the values with Key.Get instead.
+func DataFrom(ctx context.Context) map[string]interface{} {
+	data := make(map[string]interface{})
+	c, _ := ctx.Value(keyContextKey{}).(*keyContext)
+	for c != nil {
+		if _, ok := data[c.name]; !ok {
+			data[c.name] = c.value
+		}
+		c, _ = c.Context.Value(keyContextKey{}).(*keyContext)
+	}
+	return data
+}
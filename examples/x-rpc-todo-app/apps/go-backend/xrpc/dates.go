@@ -0,0 +1,54 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DateFormat is the layout Date values are encoded and parsed with.
+const DateFormat = "2006-01-02"
+
+// Date is a calendar date without a time of day, sent as YYYY-MM-DD. It
+// embeds the time.Time at midnight UTC on that day.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date t falls on in its location.
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses a YYYY-MM-DD string into a Date
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, must be YYYY-MM-DD", s)
+	}
+	return Date{t}, nil
+}
+
+// String formats d as YYYY-MM-DD
+func (d Date) String() string {
+	return d.Format(DateFormat)
+}
+
+// MarshalJSON encodes d as a YYYY-MM-DD string
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a YYYY-MM-DD string into d
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
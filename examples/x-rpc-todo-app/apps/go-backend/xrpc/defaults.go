@@ -0,0 +1,9 @@
+package xrpc
+
+// ApplyDefaults sets Priority when it is absent.
+func (v *TaskCreateInput) ApplyDefaults() {
+	if v.Priority == nil {
+		value := Priority("medium")
+		v.Priority = &value
+	}
+}
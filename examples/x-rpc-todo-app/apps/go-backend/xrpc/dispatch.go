@@ -0,0 +1,89 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+	"time"
+)
+
+// Dispatcher calls methods independently of the transport that carried them.
+// *Router implements it; ServeHTTP and ServeMessage are both built on it.
+type Dispatcher interface {
+	Dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+}
+
+// Dispatch calls a query or mutation with its JSON params outside of HTTP, such
+// as from a message bus consumer. Auth requirements, permissions, decoding,
+// validation, interceptors, timeouts and the handler run as in ServeHTTP, but
+// HTTP middleware does not, so put the caller in ctx (WithUserID, WithPrincipal)
+// first. The handler's RequestInfo has no Request or ResponseWriter. Calls are
+// reported to the Logger, and subscriptions fail with METHOD_NOT_ALLOWED since
+// they need a streaming transport.
+func (r *Router) Dispatch(ctx context.Context, method string, params json.RawMessage) (result interface{}, err error) {
+	info := RequestInfo{Method: method}
+	ctx = r.withDependencies(WithRequestInfo(ctx, info))
+	ctx = withWarnings(ctx)
+	outcome := OutcomeRejected
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.logf("xrpc: panic dispatching %s: %v\n%s", method, rec, debug.Stack())
+			result, err = nil, NewError(CodeInternal, "Internal server error")
+		}
+		if r.logger != nil {
+			entry := LogEntry{
+				Method:   method,
+				Duration: time.Since(start),
+				Outcome:  outcome,
+			}
+			if err != nil {
+				entry.Code = AsError(err).Code
+			}
+			r.logger.LogRequest(ctx, entry)
+		}
+	}()
+	result, outcome, err = r.dispatch(ctx, info, method, params)
+	return result, err
+}
+
+// ServeMessage answers a request/reply message carrying a {"method", "params"}
+// envelope with the {"result": ...} or {"error": ...} body ServeHTTP would send.
+// ServeNATS and ServeAMQP, generated with the nats and amqp options of the
+// target, serve it from a NATS subject or an AMQP RPC queue; other transports
+// pass it the body of each request and send back the reply.
+func (r *Router) ServeMessage(ctx context.Context, data []byte) []byte {
+	var request struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return r.encodeReply(nil, Errorf(CodeInvalidArgument, "Invalid request: %v", err), nil)
+	}
+	ctx = withWarnings(ctx)
+	result, err := r.Dispatch(ctx, request.Method, request.Params)
+	return r.encodeReply(result, err, WarningsFrom(ctx))
+}
+
+// encodeReply encodes the reply body of a dispatched call, reporting results
+// that fail to encode as errors.
+// Warnings are added next to the result.
+func (r *Router) encodeReply(result interface{}, err error, warnings ValidationErrors) []byte {
+	if err == nil {
+		buf, encodeErr := r.marshal(result, true)
+		if encodeErr == nil {
+			if len(warnings) > 0 {
+				buf.appendWarnings(warnings)
+			}
+			// Copied out of the pooled buffer, without its newline
+			body := append([]byte(nil), buf.Bytes()[:buf.Len()-1]...)
+			buf.release()
+			return body
+		}
+		err = encodeErr
+	}
+	rpcErr := AsError(err)
+	r.logCause(rpcErr)
+	body, _ := json.Marshal(errorEnvelope{Error: rpcErr})
+	return body
+}
@@ -0,0 +1,167 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Priority enum type
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// IsValid checks if the Priority value is valid
+func (e Priority) IsValid() bool {
+	switch e {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// ParsePriority parses a string into a Priority value
+func ParsePriority(s string) (Priority, error) {
+	e := Priority(s)
+	if !e.IsValid() {
+		return "", fmt.Errorf("invalid Priority: %s", s)
+	}
+	return e, nil
+}
+
+// AllPriorityValues returns all valid Priority values
+func AllPriorityValues() []Priority {
+	return []Priority{PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+}
+
+// MarshalJSON encodes e, failing if it is not a valid Priority
+func (e Priority) MarshalJSON() ([]byte, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("invalid Priority %q", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON decodes e, failing if the value is not a valid Priority
+func (e *Priority) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !Priority(s).IsValid() {
+		return fmt.Errorf("invalid Priority %q, must be one of: low, medium, high, urgent", s)
+	}
+	*e = Priority(s)
+	return nil
+}
+
+// TaskStatus enum type
+type TaskStatus string
+
+const (
+	TaskStatusPending    TaskStatus = "pending"
+	TaskStatusInProgress TaskStatus = "in_progress"
+	TaskStatusCompleted  TaskStatus = "completed"
+	TaskStatusCancelled  TaskStatus = "cancelled"
+)
+
+// IsValid checks if the TaskStatus value is valid
+func (e TaskStatus) IsValid() bool {
+	switch e {
+	case TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ParseTaskStatus parses a string into a TaskStatus value
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	e := TaskStatus(s)
+	if !e.IsValid() {
+		return "", fmt.Errorf("invalid TaskStatus: %s", s)
+	}
+	return e, nil
+}
+
+// AllTaskStatusValues returns all valid TaskStatus values
+func AllTaskStatusValues() []TaskStatus {
+	return []TaskStatus{TaskStatusPending, TaskStatusInProgress, TaskStatusCompleted, TaskStatusCancelled}
+}
+
+// MarshalJSON encodes e, failing if it is not a valid TaskStatus
+func (e TaskStatus) MarshalJSON() ([]byte, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("invalid TaskStatus %q", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON decodes e, failing if the value is not a valid TaskStatus
+func (e *TaskStatus) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !TaskStatus(s).IsValid() {
+		return fmt.Errorf("invalid TaskStatus %q, must be one of: pending, in_progress, completed, cancelled", s)
+	}
+	*e = TaskStatus(s)
+	return nil
+}
+
+// TaskWatchOutputType enum type
+type TaskWatchOutputType string
+
+const (
+	TaskWatchOutputTypeCreated TaskWatchOutputType = "created"
+	TaskWatchOutputTypeUpdated TaskWatchOutputType = "updated"
+	TaskWatchOutputTypeDeleted TaskWatchOutputType = "deleted"
+)
+
+// IsValid checks if the TaskWatchOutputType value is valid
+func (e TaskWatchOutputType) IsValid() bool {
+	switch e {
+	case TaskWatchOutputTypeCreated, TaskWatchOutputTypeUpdated, TaskWatchOutputTypeDeleted:
+		return true
+	}
+	return false
+}
+
+// ParseTaskWatchOutputType parses a string into a TaskWatchOutputType value
+func ParseTaskWatchOutputType(s string) (TaskWatchOutputType, error) {
+	e := TaskWatchOutputType(s)
+	if !e.IsValid() {
+		return "", fmt.Errorf("invalid TaskWatchOutputType: %s", s)
+	}
+	return e, nil
+}
+
+// AllTaskWatchOutputTypeValues returns all valid TaskWatchOutputType values
+func AllTaskWatchOutputTypeValues() []TaskWatchOutputType {
+	return []TaskWatchOutputType{TaskWatchOutputTypeCreated, TaskWatchOutputTypeUpdated, TaskWatchOutputTypeDeleted}
+}
+
+// MarshalJSON encodes e, failing if it is not a valid TaskWatchOutputType
+func (e TaskWatchOutputType) MarshalJSON() ([]byte, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("invalid TaskWatchOutputType %q", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+// UnmarshalJSON decodes e, failing if the value is not a valid TaskWatchOutputType
+func (e *TaskWatchOutputType) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if !TaskWatchOutputType(s).IsValid() {
+		return fmt.Errorf("invalid TaskWatchOutputType %q, must be one of: created, updated, deleted", s)
+	}
+	*e = TaskWatchOutputType(s)
+	return nil
+}
@@ -0,0 +1,143 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ErrorCode classifies a failed call so clients can branch on it.
+type ErrorCode string
+
+const (
+	CodeInvalidArgument   ErrorCode = "INVALID_ARGUMENT"
+	CodeUnauthorized      ErrorCode = "UNAUTHORIZED"
+	CodePermissionDenied  ErrorCode = "PERMISSION_DENIED"
+	CodeNotFound          ErrorCode = "NOT_FOUND"
+	CodeMethodNotFound    ErrorCode = "METHOD_NOT_FOUND"
+	CodeMethodNotAllowed  ErrorCode = "METHOD_NOT_ALLOWED"
+	CodeAlreadyExists     ErrorCode = "ALREADY_EXISTS"
+	CodeResourceExhausted ErrorCode = "RESOURCE_EXHAUSTED"
+	CodeUnimplemented     ErrorCode = "UNIMPLEMENTED"
+	CodeUnavailable       ErrorCode = "UNAVAILABLE"
+	CodeDeadlineExceeded  ErrorCode = "DEADLINE_EXCEEDED"
+	CodeInternal          ErrorCode = "INTERNAL"
+)
+
+// HTTPStatus returns the HTTP status code sent with errors of this code.
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeMethodNotFound:
+		return http.StatusNotFound
+	case CodeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case CodeAlreadyExists:
+		return http.StatusConflict
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is returned by handlers and middleware to control the code, message
+// and details sent to the client. Any other error is reported as
+// CodeInternal.
+type Error struct {
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	// The error AsError reported as CodeInternal, kept from the client
+	cause error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the error AsError reported as CodeInternal, or else the details
+// of e when they are an error, such as the ValidationErrors of a
+// CodeInvalidArgument, so errors.Is and errors.As find them.
+func (e *Error) Unwrap() error {
+	if e.cause != nil {
+		return e.cause
+	}
+	if err, ok := e.Details.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// NewError creates an Error with the given code and message.
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf creates an Error with a formatted message.
+func Errorf(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *Error) WithDetails(details interface{}) *Error {
+	return &Error{Code: e.Code, Message: e.Message, Details: details}
+}
+
+// RetryInfo is the Details of errors telling the caller when to try again, such
+// as the RESOURCE_EXHAUSTED of calls a concurrency limit sheds. The router sends
+// it as the Retry-After header too, rounded up to whole seconds.
+type RetryInfo struct {
+	RetryAfterMs int64 `json:"retryAfterMs"`
+}
+
+// AsError converts err into the Error sent to the client. Validation failures
+// become CodeInvalidArgument and expired deadlines CodeDeadlineExceeded; errors
+// not created by this package become CodeInternal with a generic message, so
+// SQL errors, paths and upstream messages do not reach clients; the Error keeps
+// err for Unwrap and the router logs it.
+func AsError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return &Error{Code: CodeInvalidArgument, Message: "Validation failed", Details: validationErrs}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewError(CodeDeadlineExceeded, "Deadline exceeded")
+	}
+	return &Error{Code: CodeInternal, Message: "Internal server error", cause: err}
+}
+
+// writeError writes err as the standard error envelope with the given HTTP status.
+func writeError(w http.ResponseWriter, status int, err *Error) {
+	if rec, ok := w.(errorCodeRecorder); ok {
+		rec.RecordErrorCode(string(err.Code))
+	}
+	if info, ok := err.Details.(RetryInfo); ok {
+		w.Header().Set("Retry-After", strconv.FormatInt((info.RetryAfterMs+999)/1000, 10))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if buf, encodeErr := encodeJSON(errorEnvelope{Error: err}); encodeErr == nil {
+		w.Write(buf.Bytes())
+		buf.release()
+	}
+}
@@ -0,0 +1,125 @@
+package xrpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Names of the events mutations publish.
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+)
+
+// TaskCreatedEvent is the payload of task.created events, the result of
+// task.create.
+type TaskCreatedEvent TaskCreateOutput
+
+// TaskUpdatedEvent is the payload of task.updated events, the result of
+// task.update.
+type TaskUpdatedEvent TaskUpdateOutput
+
+// Event is published through the router's EventPublisher after a call of a
+// mutation declaring an event succeeds. It is encoded as JSON with its payload
+// under data.
+type Event struct {
+	// ID is unique to the event, so consumers can drop events delivered twice.
+	ID string `json:"id"`
+	// Name is the name of the event, such as "task.created".
+	Name string `json:"event"`
+	// Method is the mutation whose call published the event.
+	Method string    `json:"method"`
+	Time   time.Time `json:"time"`
+	// Payload is the result of the call as the event's payload type, such as
+	// TaskCreatedEvent.
+	Payload interface{} `json:"data"`
+}
+
+// newEventID returns a random 128-bit event ID in hex.
+func newEventID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// EventPublisher delivers events to a message bus, queue or webhook. Publish is
+// called with the context of the call once its handler has returned, before
+// its result is sent; publishers that are slow should hand events off to a
+// background worker.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// EventPublisherFunc adapts a function to EventPublisher.
+type EventPublisherFunc func(ctx context.Context, event Event) error
+
+// Publish calls f.
+func (f EventPublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// SetEventPublisher sets the EventPublisher events are published through; until
+// one is set they are dropped. Failing to publish an event does not fail the
+// call, whose mutation is done: the error is written to the error log.
+func (r *Router) SetEventPublisher(publisher EventPublisher) *Router {
+	r.eventPublisher = publisher
+	return r
+}
+
+// publish publishes event, built from the result of a call of info.Method, with
+// an ID and the current time.
+func (r *Router) publish(ctx context.Context, info RequestInfo, event Event) {
+	if r.eventPublisher == nil {
+		return
+	}
+	event.ID = newEventID()
+	event.Method = info.Method
+	event.Time = time.Now().UTC()
+	if err := r.eventPublisher.Publish(ctx, event); err != nil {
+		r.logf("xrpc: publishing %s event of %s: %v", event.Name, info.Method, err)
+	}
+}
+
+// WebhookPublisher returns an EventPublisher POSTing every event as JSON to url
+// with client, http.DefaultClient when nil. With a secret, requests carry the
+// hex HMAC-SHA256 of their body in the X-Webhook-Signature header, as
+// "sha256=<hex>", for the receiver to check they come from this server.
+// Responses other than 2xx are errors.
+func WebhookPublisher(url string, secret []byte, client *http.Client) EventPublisher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return EventPublisherFunc(func(ctx context.Context, event Event) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if len(secret) > 0 {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(body)
+			req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook %s answered %s", url, resp.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,160 @@
+package xrpc
+
+import "encoding/json"
+
+// Example values derived from the contract's schemas as JSON; each satisfies
+// the validation rules of its type.
+const (
+	exampleSubtaskAddInput     = `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example"}`
+	exampleSubtaskAddOutput    = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","completed":true}`
+	exampleSubtaskToggleInput  = `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6","subtaskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`
+	exampleSubtaskToggleOutput = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","completed":true}`
+	exampleTaskCreateInput     = `{"title":"example","description":"example","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`
+	exampleTaskCreateOutput    = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","createdAt":"2099-01-05T09:00:00Z","completedAt":"2099-01-05T09:00:00Z","assignee":{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","name":"example","email":"user@example.com"},"subtasks":[{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","completed":true}],"estimatedHours":1,"position":0}`
+	exampleTaskDeleteInput     = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`
+	exampleTaskDeleteOutput    = `{"success":true}`
+	exampleTaskGetInput        = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`
+	exampleTaskGetOutput       = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","createdAt":"2099-01-05T09:00:00Z","completedAt":"2099-01-05T09:00:00Z","assignee":{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","name":"example","email":"user@example.com"},"subtasks":[{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","completed":true}],"estimatedHours":1,"position":0}`
+	exampleTaskListInput       = `{"status":"pending","priority":"low","cursor":"example","pageSize":1}`
+	exampleTaskListOutput      = `{"tasks":[{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","status":"pending","priority":"low","dueDate":"2099-01-05","createdAt":"2099-01-05T09:00:00Z","completedAt":"2099-01-05T09:00:00Z","subtaskCount":0,"subtaskCompletedCount":0,"estimatedHours":1,"position":0}],"total":0,"nextCursor":"example"}`
+	exampleTaskUpdateInput     = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`
+	exampleTaskUpdateOutput    = `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","createdAt":"2099-01-05T09:00:00Z","completedAt":"2099-01-05T09:00:00Z","assignee":{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","name":"example","email":"user@example.com"},"subtasks":[{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","completed":true}],"estimatedHours":1,"position":0}`
+	exampleTaskWatchInput      = `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`
+	exampleTaskWatchOutput     = `{"type":"created","taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`
+)
+
+// ExampleSubtaskAddInput returns a SubtaskAddInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleSubtaskAddInput() SubtaskAddInput {
+	var value SubtaskAddInput
+	mustDecodeExample(exampleSubtaskAddInput, &value)
+	return value
+}
+
+// ExampleSubtaskAddOutput returns a SubtaskAddOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleSubtaskAddOutput() SubtaskAddOutput {
+	var value SubtaskAddOutput
+	mustDecodeExample(exampleSubtaskAddOutput, &value)
+	return value
+}
+
+// ExampleSubtaskToggleInput returns a SubtaskToggleInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleSubtaskToggleInput() SubtaskToggleInput {
+	var value SubtaskToggleInput
+	mustDecodeExample(exampleSubtaskToggleInput, &value)
+	return value
+}
+
+// ExampleSubtaskToggleOutput returns a SubtaskToggleOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleSubtaskToggleOutput() SubtaskToggleOutput {
+	var value SubtaskToggleOutput
+	mustDecodeExample(exampleSubtaskToggleOutput, &value)
+	return value
+}
+
+// ExampleTaskCreateInput returns a TaskCreateInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskCreateInput() TaskCreateInput {
+	var value TaskCreateInput
+	mustDecodeExample(exampleTaskCreateInput, &value)
+	return value
+}
+
+// ExampleTaskCreateOutput returns a TaskCreateOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskCreateOutput() TaskCreateOutput {
+	var value TaskCreateOutput
+	mustDecodeExample(exampleTaskCreateOutput, &value)
+	return value
+}
+
+// ExampleTaskDeleteInput returns a TaskDeleteInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskDeleteInput() TaskDeleteInput {
+	var value TaskDeleteInput
+	mustDecodeExample(exampleTaskDeleteInput, &value)
+	return value
+}
+
+// ExampleTaskDeleteOutput returns a TaskDeleteOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskDeleteOutput() TaskDeleteOutput {
+	var value TaskDeleteOutput
+	mustDecodeExample(exampleTaskDeleteOutput, &value)
+	return value
+}
+
+// ExampleTaskGetInput returns a TaskGetInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskGetInput() TaskGetInput {
+	var value TaskGetInput
+	mustDecodeExample(exampleTaskGetInput, &value)
+	return value
+}
+
+// ExampleTaskGetOutput returns a TaskGetOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskGetOutput() TaskGetOutput {
+	var value TaskGetOutput
+	mustDecodeExample(exampleTaskGetOutput, &value)
+	return value
+}
+
+// ExampleTaskListInput returns a TaskListInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskListInput() TaskListInput {
+	var value TaskListInput
+	mustDecodeExample(exampleTaskListInput, &value)
+	return value
+}
+
+// ExampleTaskListOutput returns a TaskListOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskListOutput() TaskListOutput {
+	var value TaskListOutput
+	mustDecodeExample(exampleTaskListOutput, &value)
+	return value
+}
+
+// ExampleTaskUpdateInput returns a TaskUpdateInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskUpdateInput() TaskUpdateInput {
+	var value TaskUpdateInput
+	mustDecodeExample(exampleTaskUpdateInput, &value)
+	return value
+}
+
+// ExampleTaskUpdateOutput returns a TaskUpdateOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskUpdateOutput() TaskUpdateOutput {
+	var value TaskUpdateOutput
+	mustDecodeExample(exampleTaskUpdateOutput, &value)
+	return value
+}
+
+// ExampleTaskWatchInput returns a TaskWatchInput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskWatchInput() TaskWatchInput {
+	var value TaskWatchInput
+	mustDecodeExample(exampleTaskWatchInput, &value)
+	return value
+}
+
+// ExampleTaskWatchOutput returns a TaskWatchOutput that passes validation. Every call
+// returns a new value, so callers may change it.
+func ExampleTaskWatchOutput() TaskWatchOutput {
+	var value TaskWatchOutput
+	mustDecodeExample(exampleTaskWatchOutput, &value)
+	return value
+}
+
+// mustDecodeExample decodes an example into value. Examples are generated from
+// the types they decode into, so an error is a generator bug.
+func mustDecodeExample(example string, value interface{}) {
+	if err := json.Unmarshal([]byte(example), value); err != nil {
+		panic("invalid example: " + err.Error())
+	}
+}
@@ -0,0 +1,87 @@
+package xrpc
+
+import "context"
+
+// MethodGate decides whether calls of a method are served, e.g. from a feature
+// flag service. Calls it refuses fail with UNAVAILABLE and the reason it gives.
+type MethodGate interface {
+	Allow(ctx context.Context, info RequestInfo) (allowed bool, reason string)
+}
+
+// MethodGateFunc adapts a function to MethodGate.
+type MethodGateFunc func(ctx context.Context, info RequestInfo) (bool, string)
+
+// Allow calls f.
+func (f MethodGateFunc) Allow(ctx context.Context, info RequestInfo) (bool, string) {
+	return f(ctx, info)
+}
+
+// SetMethodGate sets the MethodGate consulted before every call of a method
+// Disable has not switched off.
+func (r *Router) SetMethodGate(gate MethodGate) *Router {
+	r.methodGate = gate
+	return r
+}
+
+// Disable switches off the methods matching pattern, using the same pattern
+// syntax as UseFor: until Enable is called with the same pattern, their calls
+// fail with UNAVAILABLE. It is safe to call while the router is serving.
+func (r *Router) Disable(pattern string) *Router {
+	mustValidPattern(pattern)
+	r.disabled.Store(pattern, true)
+	return r
+}
+
+// Enable switches back on the methods Disable switched off with pattern.
+func (r *Router) Enable(pattern string) *Router {
+	r.disabled.Delete(pattern)
+	return r
+}
+
+// SetMaintenance switches maintenance mode on or off: while it is on, every
+// call fails with UNAVAILABLE and details {"mode": "maintenance"}. It is safe
+// to call while the router is serving.
+func (r *Router) SetMaintenance(on bool) *Router {
+	r.maintenance.Store(on)
+	return r
+}
+
+// SetReadOnly switches read-only mode on or off: while it is on, mutations fail
+// with UNAVAILABLE and details {"mode": "read-only"}, and queries and
+// subscriptions are served, e.g. while the backing store is migrated. It is
+// safe to call while the router is serving.
+func (r *Router) SetReadOnly(on bool) *Router {
+	r.readOnly.Store(on)
+	return r
+}
+
+// checkGate fails calls of m the mode of the router rules out, and those of
+// methods switched off with Disable or refused by the MethodGate, with
+// UNAVAILABLE.
+func (r *Router) checkGate(ctx context.Context, info RequestInfo, m *methodDescriptor) error {
+	if r.maintenance.Load() {
+		return NewError(CodeUnavailable, "Service is under maintenance").WithDetails(map[string]string{"mode": "maintenance"})
+	}
+	if r.readOnly.Load() && m.kind == "mutation" {
+		return Errorf(CodeUnavailable, "Service is read-only: %s is a mutation", m.name).WithDetails(map[string]string{"mode": "read-only"})
+	}
+	disabled := false
+	r.disabled.Range(func(pattern, _ interface{}) bool {
+		disabled = matchMethod(pattern.(string), info.Method)
+		return !disabled
+	})
+	if disabled {
+		return Errorf(CodeUnavailable, "Method %s is disabled", info.Method)
+	}
+	if r.methodGate == nil {
+		return nil
+	}
+	allowed, reason := r.methodGate.Allow(ctx, info)
+	if allowed {
+		return nil
+	}
+	if reason == "" {
+		return Errorf(CodeUnavailable, "Method %s is disabled", info.Method)
+	}
+	return NewError(CodeUnavailable, reason)
+}
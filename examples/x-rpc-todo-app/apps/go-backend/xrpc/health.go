@@ -0,0 +1,114 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReadinessCheck reports whether a dependency the service needs is available,
+// such as a database or downstream API.
+type ReadinessCheck func(ctx context.Context) error
+
+// readinessCheck is a check registered with AddReadinessCheck.
+type readinessCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// ReadinessTimeout bounds how long ReadinessHandler waits for its checks; a
+// check still running after it is reported as failed.
+var ReadinessTimeout = 5 * time.Second
+
+// AddReadinessCheck registers a check ReadinessHandler runs, reported under
+// name. Register checks before serving requests.
+func (r *Router) AddReadinessCheck(name string, check ReadinessCheck) *Router {
+	r.readinessChecks = append(r.readinessChecks, readinessCheck{name: name, check: check})
+	return r
+}
+
+// PingCheck returns a ReadinessCheck pinging p, such as a *sql.DB.
+func PingCheck(p interface {
+	PingContext(ctx context.Context) error
+}) ReadinessCheck {
+	return p.PingContext
+}
+
+// HealthHandler answers liveness probes, such as GET /healthz: 200 with
+// {"status": "ok"} for as long as the process serves requests. It runs no
+// checks, so a failing dependency does not get the process restarted.
+func (r *Router) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeHealth(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+	})
+}
+
+// ReadinessHandler answers readiness probes, such as GET /readyz, by running the
+// checks registered with AddReadinessCheck concurrently: 200 with {"status":
+// "ok", "checks": {"db": "ok"}} when all pass, and 503 with "unavailable" and
+// the error of each failed check otherwise, so traffic is routed elsewhere
+// until the dependencies recover.
+func (r *Router) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, cancel := context.WithTimeout(req.Context(), ReadinessTimeout)
+		defer cancel()
+
+		results := make([]error, len(r.readinessChecks))
+		var wg sync.WaitGroup
+		for i, entry := range r.readinessChecks {
+			wg.Add(1)
+			go func(i int, check ReadinessCheck) {
+				defer wg.Done()
+				results[i] = runReadinessCheck(ctx, check)
+			}(i, entry.check)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		checks := make(map[string]string, len(results))
+		for i, err := range results {
+			name := r.readinessChecks[i].name
+			if err != nil {
+				status = http.StatusServiceUnavailable
+				checks[name] = err.Error()
+				continue
+			}
+			checks[name] = "ok"
+		}
+		body := map[string]interface{}{"status": "ok", "checks": checks}
+		if status != http.StatusOK {
+			body["status"] = "unavailable"
+		}
+		writeHealth(w, status, body)
+	})
+}
+
+// runReadinessCheck runs check, failing it when it panics or outlasts ctx.
+func runReadinessCheck(ctx context.Context, check ReadinessCheck) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeHealth writes a health or readiness response body.
+func writeHealth(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
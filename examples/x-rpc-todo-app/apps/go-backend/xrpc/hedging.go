@@ -0,0 +1,65 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// WithHedging hedges calls of methods: when an attempt hasn't answered after
+// delay, the Client sends a second one and returns the first successful
+// answer, cancelling the other. Only queries can be hedged, as a hedged call
+// runs twice on the server; WithHedging panics if a method is not one.
+func WithHedging(delay time.Duration, methods ...string) ClientOption {
+	return func(c *Client) {
+		c.hedgeDelay = delay
+		c.hedgedMethods = make(map[string]bool, len(methods))
+		for _, method := range methods {
+			if m, ok := methodTable[method]; !ok || m.kind != "query" {
+				panic("hedged method " + method + " is not a query")
+			}
+			c.hedgedMethods[method] = true
+		}
+	}
+}
+
+// hedgedAnswer is the result of an attempt at a hedged call, or its failure.
+type hedgedAnswer struct {
+	result     json.RawMessage
+	retryAfter time.Duration
+	err        error
+}
+
+// send makes an attempt at a call of method, hedged if the Client hedges it.
+func (c *Client) send(ctx context.Context, method string, body []byte) (json.RawMessage, time.Duration, error) {
+	if !c.hedgedMethods[method] {
+		return c.attempt(ctx, method, body)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// Cancels the attempt that lost
+	defer cancel()
+
+	// Buffered so the attempt that lost doesn't block
+	answers := make(chan hedgedAnswer, 2)
+	attempt := func() {
+		result, retryAfter, err := c.attempt(ctx, method, body)
+		answers <- hedgedAnswer{result, retryAfter, err}
+	}
+	go attempt()
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			pending++
+			go attempt()
+		case answer := <-answers:
+			pending--
+			// A failure is only returned once no other attempt can still succeed
+			if answer.err == nil || pending == 0 {
+				return answer.result, answer.retryAfter, answer.err
+			}
+		}
+	}
+}
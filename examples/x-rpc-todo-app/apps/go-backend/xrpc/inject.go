@@ -0,0 +1,113 @@
+package xrpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// dependencyKey identifies the dependencies of type *T, among the providers of a
+// router and in contexts.
+type dependencyKey[T any] struct{}
+
+// dependenciesKey is the context key of the dependencies of the call.
+type dependenciesKey struct{}
+
+// dependencies are the providers of the router serving a call and the values
+// they returned for it.
+type dependencies struct {
+	providers map[interface{}]func(ctx context.Context) interface{}
+	mu        sync.Mutex
+	values    map[interface{}]*dependency
+}
+
+// dependency is the value of a provider for a call, once it was called.
+type dependency struct {
+	once  sync.Once
+	value interface{}
+}
+
+// get returns the value of the provider of key for the call, calling it with ctx
+// the first time the call asks for it; false if the router has no provider of
+// key.
+func (d *dependencies) get(ctx context.Context, key interface{}) (interface{}, bool) {
+	provider, ok := d.providers[key]
+	if !ok {
+		return nil, false
+	}
+	d.mu.Lock()
+	dep, ok := d.values[key]
+	if !ok {
+		dep = &dependency{}
+		d.values[key] = dep
+	}
+	d.mu.Unlock()
+	// Called outside of the lock, as providers may get other dependencies
+	dep.once.Do(func() { dep.value = provider(ctx) })
+	return dep.value, true
+}
+
+// withDependencies returns a copy of ctx in which the call it serves gets the
+// dependencies provided on r.
+func (r *Router) withDependencies(ctx context.Context) context.Context {
+	if len(r.providers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, dependenciesKey{}, &dependencies{providers: r.providers, values: map[interface{}]*dependency{}})
+}
+
+// Provide registers provider as the source of the *T that handlers, interceptors
+// and middleware of r get with MustGet or Get. It is called with the context of
+// a call at most once per call, the first time the call asks for a *T, so it can
+// return a value shared by every call, such as a connection pool, or one scoped
+// to the call, such as a logger carrying its request ID. Providing a *T again
+// replaces the provider, so tests can swap in fakes. Call it before
+// serving requests:
+//
+//	db, err := NewDB(path)
+//	...
+//	Provide[DB](router, func(ctx context.Context) *DB { return db })
+func Provide[T any](r *Router, provider func(ctx context.Context) *T) *Router {
+	if r.providers == nil {
+		r.providers = map[interface{}]func(ctx context.Context) interface{}{}
+	}
+	r.providers[dependencyKey[T]{}] = func(ctx context.Context) interface{} { return provider(ctx) }
+	return r
+}
+
+// WithDependency returns a copy of ctx in which MustGet and Get return value for
+// *T instead of calling the router's provider, to swap a dependency for one
+// call, such as in tests calling handlers or Dispatch directly, or in middleware
+// binding a dependency to the caller.
+func WithDependency[T any](ctx context.Context, value *T) context.Context {
+	return context.WithValue(ctx, dependencyKey[T]{}, value)
+}
+
+// Get returns the *T of the call ctx belongs to: the one WithDependency put in
+// ctx, or else the one the provider registered with Provide returns; false if
+// there is neither.
+func Get[T any](ctx context.Context) (*T, bool) {
+	if value, ok := ctx.Value(dependencyKey[T]{}).(*T); ok {
+		return value, true
+	}
+	deps, ok := ctx.Value(dependenciesKey{}).(*dependencies)
+	if !ok {
+		return nil, false
+	}
+	value, ok := deps.get(ctx, dependencyKey[T]{})
+	if !ok {
+		return nil, false
+	}
+	return value.(*T), true
+}
+
+// MustGet returns the *T of the call ctx belongs to, like Get. It panics when
+// there is none, a mistake in setting up the server the router answers with
+// INTERNAL.
+func MustGet[T any](ctx context.Context) *T {
+	value, ok := Get[T](ctx)
+	if !ok {
+		panic(fmt.Sprintf("xrpc: no %T provided", value))
+	}
+	return value
+}
@@ -0,0 +1,191 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaHash identifies the contract the router was generated from; clients
+// generated from the same contract carry the same hash. Calls sending another
+// in an X-Xrpc-Schema-Hash header are answered with an X-Xrpc-Schema-Mismatch
+// header holding SchemaHash, warning the client it is stale. Servers called
+// cross-origin have to allow X-Xrpc-Schema-Hash in Access-Control-Allow-Headers
+// and expose X-Xrpc-Schema-Mismatch in Access-Control-Expose-Headers.
+const SchemaHash = "54052c78d158278f"
+
+// MethodInfo describes one method: its name, kind (query, mutation or
+// subscription), the JSON Schemas of its input and output, the version of
+// versioned methods such as task.list@v2, whether it is deprecated, and whether
+// it is an async mutation, answering with an Operation whose result has the
+// output schema.
+type MethodInfo struct {
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	Input      json.RawMessage `json:"input"`
+	Output     json.RawMessage `json:"output"`
+	Version    int             `json:"version,omitempty"`
+	Deprecated *Deprecation    `json:"deprecated,omitempty"`
+	Async      bool            `json:"async,omitempty"`
+}
+
+// Deprecation describes a deprecated method: what to use instead, and the ISO
+// 8601 dates it was deprecated on and will stop being served on, when known.
+type Deprecation struct {
+	Message string `json:"message,omitempty"`
+	Since   string `json:"since,omitempty"`
+	Sunset  string `json:"sunset,omitempty"`
+}
+
+// methodInfos describes every method in the contract.
+var methodInfos = []MethodInfo{
+	{
+		Name:   "subtask.add",
+		Kind:   "mutation",
+		Input:  json.RawMessage(`{"type":"object","properties":{"taskId":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200}},"required":["taskId","title"]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]}`),
+	},
+	{
+		Name:   "subtask.toggle",
+		Kind:   "mutation",
+		Input:  json.RawMessage(`{"type":"object","properties":{"taskId":{"type":"string","format":"uuid"},"subtaskId":{"type":"string","format":"uuid"}},"required":["taskId","subtaskId"]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]}`),
+	},
+	{
+		Name:   "task.create",
+		Kind:   "mutation",
+		Input:  json.RawMessage(`{"type":"object","properties":{"title":{"type":"string","minLength":3,"maxLength":200},"description":{"type":"string","maxLength":2000},"priority":{"type":"string","enum":["low","medium","high","urgent"],"default":"medium"},"dueDate":{"type":"string","format":"date"},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25}},"required":["title"],"allOf":[{"if":{"properties":{"priority":{"const":"urgent"}},"required":["priority"]},"then":{"required":["dueDate"]}}]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	},
+	{
+		Name:   "task.delete",
+		Kind:   "mutation",
+		Input:  json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"}},"required":["id"]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"success":{"type":"boolean"}},"required":["success"]}`),
+	},
+	{
+		Name:   "task.get",
+		Kind:   "query",
+		Input:  json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"}},"required":["id"]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	},
+	{
+		Name:   "task.list",
+		Kind:   "query",
+		Input:  json.RawMessage(`{"type":"object","properties":{"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"cursor":{"type":"string"},"pageSize":{"type":"integer","minimum":1,"maximum":100}}}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"tasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"subtaskCount":{"type":"integer","minimum":0},"subtaskCompletedCount":{"type":"integer","minimum":0},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtaskCount","subtaskCompletedCount","position"]}},"total":{"type":"integer","minimum":0},"nextCursor":{"type":"string"}},"required":["tasks","total"]}`),
+	},
+	{
+		Name:   "task.update",
+		Kind:   "mutation",
+		Input:  json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"anyOf":[{"type":"string","maxLength":2000},{"type":"null"}]},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"anyOf":[{"type":"string","format":"date"},{"type":"null"}]},"estimatedHours":{"anyOf":[{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},{"type":"null"}]}},"required":["id","description","dueDate","estimatedHours"]}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	},
+	{
+		Name:   "task.watch",
+		Kind:   "subscription",
+		Input:  json.RawMessage(`{"type":"object","properties":{"taskId":{"type":"string","format":"uuid"}}}`),
+		Output: json.RawMessage(`{"type":"object","properties":{"type":{"type":"string","enum":["created","updated","deleted"]},"taskId":{"type":"string","format":"uuid"}},"required":["type","taskId"]}`),
+	},
+}
+
+// Introspect returns the methods that have a handler registered, sorted by
+// method name, followed by those of the routers added with Mount.
+func (r *Router) Introspect() []MethodInfo {
+	methods := make([]MethodInfo, 0, len(methodInfos))
+	for _, info := range methodInfos {
+		if methodTable[info.Name].registered(r) {
+			methods = append(methods, info)
+		}
+	}
+	for _, entry := range r.mounts {
+		methods = append(methods, mountedMethods(entry)...)
+	}
+	return methods
+}
+
+// DisableIntrospection turns off the built-in xrpc.introspect method, which then
+// reports CodeMethodNotFound like any unknown method.
+func (r *Router) DisableIntrospection() *Router {
+	r.introspectionDisabled = true
+	return r
+}
+
+// MethodNotFound is the Details of CodeMethodNotFound errors: the method the
+// caller most likely meant, when one is a few edits away from the method it
+// called, and the namespaces of the methods the router serves.
+type MethodNotFound struct {
+	Suggestion string   `json:"suggestion,omitempty"`
+	Namespaces []string `json:"namespaces"`
+}
+
+// methodNotFound returns the error answering a call of an unknown method, with
+// a MethodNotFound suggesting the registered method closest to it by edit
+// distance. Routers with introspection disabled do not reveal their methods.
+func (r *Router) methodNotFound(method string) *Error {
+	err := Errorf(CodeMethodNotFound, "Method not found: %s", method)
+	if r.introspectionDisabled {
+		return err
+	}
+	details := MethodNotFound{Namespaces: []string{}}
+	// Suggestions are at most a third of the method's length plus one edit away
+	best := len(method)/3 + 2
+	seen := map[string]bool{}
+	for _, info := range r.Introspect() {
+		if d := editDistance(method, info.Name); d < best {
+			best = d
+			details.Suggestion = info.Name
+		}
+		namespace := info.Name
+		if i := strings.LastIndex(namespace, "."); i >= 0 {
+			namespace = namespace[:i]
+		}
+		if !seen[namespace] {
+			seen[namespace] = true
+			details.Namespaces = append(details.Namespaces, namespace)
+		}
+	}
+	sort.Strings(details.Namespaces)
+	if details.Suggestion != "" {
+		err.Message += fmt.Sprintf(" (did you mean %s?)", details.Suggestion)
+	}
+	return err.WithDetails(details)
+}
+
+// editDistance returns the Levenshtein distance between a and b: the fewest
+// bytes to insert, delete or replace to turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			replace := prev[j-1]
+			if a[i-1] != b[j-1] {
+				replace++
+			}
+			curr[j] = replace
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// EnableDeprecationHeaders makes calls of deprecated methods answer with a
+// Deprecation header (RFC 9745) carrying the date they were deprecated on, or
+// "true" when the contract gives none, and with a Sunset header (RFC 8594)
+// when it gives the date they stop being served.
+func (r *Router) EnableDeprecationHeaders() *Router {
+	r.deprecationHeaders = true
+	return r
+}
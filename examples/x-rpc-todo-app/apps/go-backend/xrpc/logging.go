@@ -0,0 +1,116 @@
+package xrpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Outcome classifies how a call ended.
+type Outcome string
+
+const (
+	// OutcomeSuccess means the handler returned a result.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeValidationError means the params could not be decoded or failed validation.
+	OutcomeValidationError Outcome = "validation_error"
+	// OutcomeHandlerError means the handler or an interceptor failed or panicked.
+	OutcomeHandlerError Outcome = "handler_error"
+	// OutcomeRejected means the call never reached validation: the request was
+	// malformed, the method unknown or unregistered, or middleware refused it.
+	OutcomeRejected Outcome = "rejected"
+)
+
+// LogEntry describes one completed call.
+type LogEntry struct {
+	Method   string
+	Duration time.Duration
+	Outcome  Outcome
+	// Code is the error code sent to the client; empty on success.
+	Code   ErrorCode
+	Status int
+	// Size is the number of response body bytes written.
+	Size int
+	// Tenant is the ID of the tenant middleware resolved for the call, if any.
+	Tenant string
+}
+
+// Logger receives an entry for every call once its response has been written.
+type Logger interface {
+	LogRequest(ctx context.Context, entry LogEntry)
+}
+
+// SetLogger sets the Logger that receives an entry for every call.
+func (r *Router) SetLogger(logger Logger) *Router {
+	r.logger = logger
+	return r
+}
+
+// MultiLogger returns a Logger that passes every entry to each of loggers, so
+// request logs and metrics can be recorded together.
+func MultiLogger(loggers ...Logger) Logger {
+	return multiLogger(loggers)
+}
+
+type multiLogger []Logger
+
+func (m multiLogger) LogRequest(ctx context.Context, entry LogEntry) {
+	for _, logger := range m {
+		logger.LogRequest(ctx, entry)
+	}
+}
+
+// responseRecorder records the status, size and error code of a response, and
+// the tenant of its call, for the Logger.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+	code   ErrorCode
+	tenant string
+}
+
+// errorCodeRecorder is implemented by the responseRecorder of every generated
+// package, so the codes of errors written by mounted routers are logged too.
+type errorCodeRecorder interface {
+	RecordErrorCode(code string)
+}
+
+// RecordErrorCode records the code of the error response being written.
+func (rec *responseRecorder) RecordErrorCode(code string) {
+	rec.code = ErrorCode(code)
+}
+
+// tenantRecorder is implemented by the responseRecorder of every generated
+// package, so the tenants of calls served by mounted routers are logged too.
+type tenantRecorder interface {
+	RecordTenant(tenant string)
+}
+
+// RecordTenant records the ID of the tenant of the call.
+func (rec *responseRecorder) RecordTenant(tenant string) {
+	rec.tenant = tenant
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(p)
+	rec.size += n
+	return n, err
+}
+
+// Flush lets subscriptions stream through the recorder.
+func (rec *responseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
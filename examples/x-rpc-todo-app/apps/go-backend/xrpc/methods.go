@@ -0,0 +1,455 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// methodDescriptor describes a method: its name, kind (query, mutation or
+// subscription), the auth and tenant it requires, and the functions the router
+// calls it through. Inputs are passed as interface{} holding the method's input
+// type.
+type methodDescriptor struct {
+	name string
+	kind string
+	// Whether a middleware must have authenticated the caller
+	auth bool
+	// Whether a middleware must have resolved the caller's tenant
+	tenant      bool
+	permissions []string
+	// Whether calls run in the background, answered with the Operation running
+	// them
+	async bool
+	// Deprecation and Sunset response headers of a deprecated method; empty
+	// otherwise
+	deprecation string
+	sunset      string
+	// registered reports whether r has a handler for the method
+	registered func(r *Router) bool
+	// replace sets r's handler for the method, reporting whether handler has its
+	// type
+	replace func(r *Router, handler interface{}) bool
+	// coerce coerces the numbers and strings of decoded params with lenient
+	// decoding; nil for inputs without any
+	coerce func(v map[string]interface{}, path []PathSegment, errs *ValidationErrors)
+	// decode decodes params into the method's input
+	decode   func(r *Router, params json.RawMessage) (interface{}, error)
+	validate func(input interface{}) error
+	// warn reports the values of a validated input past their soft limits; nil
+	// for inputs without any
+	warn func(input interface{}) ValidationErrors
+	// invoke calls the handler, collecting streamed items into the output; nil
+	// for subscriptions
+	invoke func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error)
+	// decodeResult decodes a result of the method encoded as JSON, for the Cache
+	// interceptor; nil for methods other than unstreamed queries
+	decodeResult func(data []byte) (interface{}, error)
+	// serve calls the handler of a subscription or streamed query over HTTP,
+	// writing what it produces as it goes, and returns the outcome; nil for
+	// methods answered with a single result
+	serve func(r *Router, ctx context.Context, info RequestInfo, input interface{}, w http.ResponseWriter, req *http.Request) Outcome
+	// event wraps the result of a successful call in the event the method
+	// publishes; nil for methods without one
+	event func(result interface{}) Event
+}
+
+// methodDescriptors describes every method, sorted by method name.
+var methodDescriptors = []*methodDescriptor{
+	{
+		name: "subtask.add",
+		kind: "mutation",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.subtaskAdd) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case SubtaskAddHandler:
+				r.SubtaskAdd(h)
+			case func(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error):
+				r.SubtaskAdd(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceSubtaskAddInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input SubtaskAddInput
+			if err := r.unmarshal(params, &input); err != nil {
+				return input, err
+			}
+			input.Normalize()
+			return input, nil
+		},
+		validate: func(input interface{}) error {
+			return ValidateSubtaskAddInput(input.(SubtaskAddInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.subtaskAdd)(ctx, info, input.(SubtaskAddInput))
+		},
+	},
+	{
+		name: "subtask.toggle",
+		kind: "mutation",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.subtaskToggle) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case SubtaskToggleHandler:
+				r.SubtaskToggle(h)
+			case func(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error):
+				r.SubtaskToggle(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceSubtaskToggleInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input SubtaskToggleInput
+			err := r.unmarshal(params, &input)
+			return input, err
+		},
+		validate: func(input interface{}) error {
+			return ValidateSubtaskToggleInput(input.(SubtaskToggleInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.subtaskToggle)(ctx, info, input.(SubtaskToggleInput))
+		},
+	},
+	{
+		name: "task.create",
+		kind: "mutation",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskCreate) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskCreateHandler:
+				r.TaskCreate(h)
+			case func(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error):
+				r.TaskCreate(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskCreateInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskCreateInput
+			if err := r.unmarshal(params, &input); err != nil {
+				return input, err
+			}
+			input.ApplyDefaults()
+			input.Normalize()
+			return input, nil
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskCreateInput(input.(TaskCreateInput))
+		},
+		warn: func(input interface{}) ValidationErrors {
+			return input.(TaskCreateInput).Warnings()
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.taskCreate)(ctx, info, input.(TaskCreateInput))
+		},
+		event: func(result interface{}) Event {
+			return Event{Name: EventTaskCreated, Payload: TaskCreatedEvent(result.(TaskCreateOutput))}
+		},
+	},
+	{
+		name: "task.delete",
+		kind: "mutation",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskDelete) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskDeleteHandler:
+				r.TaskDelete(h)
+			case func(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error):
+				r.TaskDelete(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskDeleteInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskDeleteInput
+			err := r.unmarshal(params, &input)
+			return input, err
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskDeleteInput(input.(TaskDeleteInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.taskDelete)(ctx, info, input.(TaskDeleteInput))
+		},
+	},
+	{
+		name: "task.get",
+		kind: "query",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskGet) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskGetHandler:
+				r.TaskGet(h)
+			case func(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error):
+				r.TaskGet(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskDeleteInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskGetInput
+			err := r.unmarshal(params, &input)
+			return input, err
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskGetInput(input.(TaskGetInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.taskGet)(ctx, info, input.(TaskGetInput))
+		},
+		decodeResult: func(data []byte) (interface{}, error) {
+			var output TaskGetOutput
+			err := json.Unmarshal(data, &output)
+			return output, err
+		},
+	},
+	{
+		name: "task.list",
+		kind: "query",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskList) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskListHandler:
+				r.TaskList(h)
+			case func(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error):
+				r.TaskList(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskListInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskListInput
+			err := r.unmarshal(params, &input)
+			return input, err
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskListInput(input.(TaskListInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.taskList)(ctx, info, input.(TaskListInput))
+		},
+		decodeResult: func(data []byte) (interface{}, error) {
+			var output TaskListOutput
+			err := json.Unmarshal(data, &output)
+			return output, err
+		},
+	},
+	{
+		name: "task.update",
+		kind: "mutation",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskUpdate) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskUpdateHandler:
+				r.TaskUpdate(h)
+			case func(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error):
+				r.TaskUpdate(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskUpdateInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskUpdateInput
+			if err := r.unmarshal(params, &input); err != nil {
+				return input, err
+			}
+			input.Normalize()
+			return input, nil
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskUpdateInput(input.(TaskUpdateInput))
+		},
+		invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {
+			return loadHandler(r, &r.taskUpdate)(ctx, info, input.(TaskUpdateInput))
+		},
+		event: func(result interface{}) Event {
+			return Event{Name: EventTaskUpdated, Payload: TaskUpdatedEvent(result.(TaskUpdateOutput))}
+		},
+	},
+	{
+		name: "task.watch",
+		kind: "subscription",
+		registered: func(r *Router) bool {
+			return loadHandler(r, &r.taskWatch) != nil
+		},
+		replace: func(r *Router, handler interface{}) bool {
+			switch h := handler.(type) {
+			case TaskWatchHandler:
+				r.TaskWatch(h)
+			case func(ctx context.Context, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error:
+				r.TaskWatch(h)
+			default:
+				return false
+			}
+			return true
+		},
+		coerce: coerceTaskWatchInput,
+		decode: func(r *Router, params json.RawMessage) (interface{}, error) {
+			var input TaskWatchInput
+			err := r.unmarshal(params, &input)
+			return input, err
+		},
+		validate: func(input interface{}) error {
+			return ValidateTaskWatchInput(input.(TaskWatchInput))
+		},
+		serve: func(r *Router, ctx context.Context, info RequestInfo, input interface{}, w http.ResponseWriter, req *http.Request) Outcome {
+			return r.serveSubscription(ctx, w, func(send func(event interface{}) error) error {
+				return loadHandler(r, &r.taskWatch)(ctx, info, input.(TaskWatchInput), func(event TaskWatchOutput) error {
+					return send(event)
+				})
+			})
+		},
+	},
+}
+
+// methodTable indexes methodDescriptors by method name.
+var methodTable = indexMethods(methodDescriptors)
+
+// indexMethods maps descriptors by their method name.
+func indexMethods(descriptors []*methodDescriptor) map[string]*methodDescriptor {
+	table := make(map[string]*methodDescriptor, len(descriptors))
+	for _, m := range descriptors {
+		table[m.name] = m
+	}
+	return table
+}
+
+// getAllowed reports whether method may be called with GET: queries,
+// subscriptions, xrpc.introspect and operation.get can be, mutations must be
+// POSTed.
+func getAllowed(method string) bool {
+	if method == "xrpc.introspect" || method == "operation.get" {
+		return true
+	}
+	m, ok := methodTable[method]
+	return ok && m.kind != "mutation"
+}
+
+// Replace swaps the handler of method while the router serves calls, such as
+// Replace("task.get", h) to canary a new implementation or load one from a
+// plugin. handler is the method's handler type or a func with its signature.
+// Calls already running finish on the handler they started with; later calls
+// get the new one. Replace also registers methods that had no handler yet.
+func (r *Router) Replace(method string, handler interface{}) error {
+	m, ok := methodTable[method]
+	if !ok {
+		return fmt.Errorf("replace %s: unknown method", method)
+	}
+	if handler == nil || !m.replace(r, handler) {
+		return fmt.Errorf("replace %s: %T is not a handler of the method", method, handler)
+	}
+	return nil
+}
+
+// loadHandler reads the handler field of r under its handlers lock, so a call
+// holds on to the handler it loaded while Replace swaps it for another.
+func loadHandler[H any](r *Router, field *H) H {
+	r.handlersMu.RLock()
+	defer r.handlersMu.RUnlock()
+	return *field
+}
+
+// prepare runs the checks that come before a call of m: that it is not switched
+// off, that its handler is registered, its auth and tenant requirements and
+// permissions, and decoding and validating params. It returns the input, or
+// how far the call got and why it failed.
+func (r *Router) prepare(ctx context.Context, info RequestInfo, m *methodDescriptor, params json.RawMessage) (interface{}, Outcome, error) {
+	if err := r.checkGate(ctx, info, m); err != nil {
+		return nil, OutcomeRejected, err
+	}
+	if !m.registered(r) {
+		return nil, OutcomeRejected, NewError(CodeUnimplemented, "Handler not registered")
+	}
+	if m.auth {
+		if _, ok := UserIDFrom(ctx); !ok {
+			return nil, OutcomeRejected, NewError(CodeUnauthorized, "Authentication required")
+		}
+	}
+	if m.tenant {
+		if _, ok := TenantFrom(ctx); !ok {
+			return nil, OutcomeRejected, NewError(CodeInvalidArgument, "Tenant required")
+		}
+	}
+	if len(m.permissions) > 0 {
+		if err := r.authorize(ctx, info, m.permissions); err != nil {
+			return nil, OutcomeRejected, err
+		}
+	}
+
+	if r.lenientDecoding && m.coerce != nil {
+		coerced, err := coerceParams(params, m.coerce)
+		if err != nil {
+			return nil, OutcomeValidationError, err
+		}
+		params = coerced
+	}
+	input, err := m.decode(r, params)
+	if err != nil {
+		return nil, OutcomeValidationError, paramsError(params, err)
+	}
+	if err := m.validate(input); err != nil {
+		return nil, OutcomeValidationError, err
+	}
+	if m.warn != nil {
+		addWarnings(ctx, m.warn(input))
+	}
+	return input, OutcomeSuccess, nil
+}
+
+// serveSubscription streams the events a subscription handler sends to w as
+// Server-Sent Events. Handler errors after the stream started can only be
+// reported in-band, as an error event.
+func (r *Router) serveSubscription(ctx context.Context, w http.ResponseWriter, run func(send func(event interface{}) error) error) Outcome {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		r.writeError(w, NewError(CodeInternal, "Streaming not supported"))
+		return OutcomeHandlerError
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event interface{}) error {
+		return writeEvent(w, flusher, "", event)
+	}
+	if err := run(send); err != nil && ctx.Err() == nil {
+		rpcErr := AsError(err)
+		r.logCause(rpcErr)
+		writeEvent(w, flusher, "error", errorEnvelope{Error: rpcErr})
+		return OutcomeHandlerError
+	}
+	return OutcomeSuccess
+}
@@ -0,0 +1,424 @@
+package xrpc
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// MockServer serves the API from stubs instead of handlers, for Go consumer tests
+// (with httptest.NewServer) and frontend e2e environments that should not run
+// the real backend. Stub a method with its field, e.g. mock.TaskGet.Returns(out, nil).
+type MockServer struct {
+	SubtaskAdd    *SubtaskAddStub
+	SubtaskToggle *SubtaskToggleStub
+	TaskCreate    *TaskCreateStub
+	TaskDelete    *TaskDeleteStub
+	TaskGet       *TaskGetStub
+	TaskList      *TaskListStub
+	TaskUpdate    *TaskUpdateStub
+	TaskWatch     *TaskWatchStub
+	router        *Router
+}
+
+// NewMockServer returns a MockServer whose methods answer with their example output
+// until stubbed.
+func NewMockServer() *MockServer {
+	registerMockValidators()
+	m := &MockServer{
+		SubtaskAdd: (&SubtaskAddStub{}).Func(func(ctx context.Context, input SubtaskAddInput) (SubtaskAddOutput, error) {
+			return ExampleSubtaskAddOutput(), nil
+		}),
+		SubtaskToggle: (&SubtaskToggleStub{}).Func(func(ctx context.Context, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+			return ExampleSubtaskToggleOutput(), nil
+		}),
+		TaskCreate: (&TaskCreateStub{}).Func(func(ctx context.Context, input TaskCreateInput) (TaskCreateOutput, error) {
+			return ExampleTaskCreateOutput(), nil
+		}),
+		TaskDelete: (&TaskDeleteStub{}).Func(func(ctx context.Context, input TaskDeleteInput) (TaskDeleteOutput, error) {
+			return ExampleTaskDeleteOutput(), nil
+		}),
+		TaskGet: (&TaskGetStub{}).Func(func(ctx context.Context, input TaskGetInput) (TaskGetOutput, error) {
+			return ExampleTaskGetOutput(), nil
+		}),
+		TaskList: (&TaskListStub{}).Func(func(ctx context.Context, input TaskListInput) (TaskListOutput, error) {
+			return ExampleTaskListOutput(), nil
+		}),
+		TaskUpdate: (&TaskUpdateStub{}).Func(func(ctx context.Context, input TaskUpdateInput) (TaskUpdateOutput, error) {
+			return ExampleTaskUpdateOutput(), nil
+		}),
+		TaskWatch: (&TaskWatchStub{}).Func(func(ctx context.Context, input TaskWatchInput, send func(TaskWatchOutput) error) error {
+			return send(ExampleTaskWatchOutput())
+		}),
+	}
+	m.router = NewRouter().
+		SubtaskAdd(m.SubtaskAdd.handle).
+		SubtaskToggle(m.SubtaskToggle.handle).
+		TaskCreate(m.TaskCreate.handle).
+		TaskDelete(m.TaskDelete.handle).
+		TaskGet(m.TaskGet.handle).
+		TaskList(m.TaskList.handle).
+		TaskUpdate(m.TaskUpdate.handle).
+		TaskWatch(m.TaskWatch.handle)
+	return m
+}
+
+// Router returns the router serving the stubs, e.g. to add middleware.
+func (m *MockServer) Router() *Router {
+	return m.router
+}
+
+// ServeHTTP serves the API like Router.ServeHTTP.
+func (m *MockServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.router.ServeHTTP(w, req)
+}
+
+// registerMockValidators registers custom validators that accept every value
+// for the ones the process has not registered.
+func registerMockValidators() {
+	for _, name := range customValidators {
+		if _, ok := validators[name]; !ok {
+			RegisterValidator(name, func(interface{}) error { return nil })
+		}
+	}
+}
+
+// SubtaskAddStub stubs subtask.add: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type SubtaskAddStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input SubtaskAddInput) (SubtaskAddOutput, error)
+	calls []SubtaskAddInput
+}
+
+// Returns makes subtask.add answer every call with output and err.
+func (s *SubtaskAddStub) Returns(output SubtaskAddOutput, err error) *SubtaskAddStub {
+	return s.Func(func(context.Context, SubtaskAddInput) (SubtaskAddOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes subtask.add answer calls with fn.
+func (s *SubtaskAddStub) Func(fn func(ctx context.Context, input SubtaskAddInput) (SubtaskAddOutput, error)) *SubtaskAddStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs subtask.add was called with, in order.
+func (s *SubtaskAddStub) Calls() []SubtaskAddInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SubtaskAddInput(nil), s.calls...)
+}
+
+func (s *SubtaskAddStub) handle(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output SubtaskAddOutput
+		return output, NewError(CodeUnimplemented, "subtask.add is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// SubtaskToggleStub stubs subtask.toggle: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type SubtaskToggleStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input SubtaskToggleInput) (SubtaskToggleOutput, error)
+	calls []SubtaskToggleInput
+}
+
+// Returns makes subtask.toggle answer every call with output and err.
+func (s *SubtaskToggleStub) Returns(output SubtaskToggleOutput, err error) *SubtaskToggleStub {
+	return s.Func(func(context.Context, SubtaskToggleInput) (SubtaskToggleOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes subtask.toggle answer calls with fn.
+func (s *SubtaskToggleStub) Func(fn func(ctx context.Context, input SubtaskToggleInput) (SubtaskToggleOutput, error)) *SubtaskToggleStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs subtask.toggle was called with, in order.
+func (s *SubtaskToggleStub) Calls() []SubtaskToggleInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SubtaskToggleInput(nil), s.calls...)
+}
+
+func (s *SubtaskToggleStub) handle(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output SubtaskToggleOutput
+		return output, NewError(CodeUnimplemented, "subtask.toggle is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskCreateStub stubs task.create: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type TaskCreateStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskCreateInput) (TaskCreateOutput, error)
+	calls []TaskCreateInput
+}
+
+// Returns makes task.create answer every call with output and err.
+func (s *TaskCreateStub) Returns(output TaskCreateOutput, err error) *TaskCreateStub {
+	return s.Func(func(context.Context, TaskCreateInput) (TaskCreateOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes task.create answer calls with fn.
+func (s *TaskCreateStub) Func(fn func(ctx context.Context, input TaskCreateInput) (TaskCreateOutput, error)) *TaskCreateStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.create was called with, in order.
+func (s *TaskCreateStub) Calls() []TaskCreateInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskCreateInput(nil), s.calls...)
+}
+
+func (s *TaskCreateStub) handle(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output TaskCreateOutput
+		return output, NewError(CodeUnimplemented, "task.create is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskDeleteStub stubs task.delete: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type TaskDeleteStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskDeleteInput) (TaskDeleteOutput, error)
+	calls []TaskDeleteInput
+}
+
+// Returns makes task.delete answer every call with output and err.
+func (s *TaskDeleteStub) Returns(output TaskDeleteOutput, err error) *TaskDeleteStub {
+	return s.Func(func(context.Context, TaskDeleteInput) (TaskDeleteOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes task.delete answer calls with fn.
+func (s *TaskDeleteStub) Func(fn func(ctx context.Context, input TaskDeleteInput) (TaskDeleteOutput, error)) *TaskDeleteStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.delete was called with, in order.
+func (s *TaskDeleteStub) Calls() []TaskDeleteInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskDeleteInput(nil), s.calls...)
+}
+
+func (s *TaskDeleteStub) handle(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output TaskDeleteOutput
+		return output, NewError(CodeUnimplemented, "task.delete is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskGetStub stubs task.get: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type TaskGetStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskGetInput) (TaskGetOutput, error)
+	calls []TaskGetInput
+}
+
+// Returns makes task.get answer every call with output and err.
+func (s *TaskGetStub) Returns(output TaskGetOutput, err error) *TaskGetStub {
+	return s.Func(func(context.Context, TaskGetInput) (TaskGetOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes task.get answer calls with fn.
+func (s *TaskGetStub) Func(fn func(ctx context.Context, input TaskGetInput) (TaskGetOutput, error)) *TaskGetStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.get was called with, in order.
+func (s *TaskGetStub) Calls() []TaskGetInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskGetInput(nil), s.calls...)
+}
+
+func (s *TaskGetStub) handle(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output TaskGetOutput
+		return output, NewError(CodeUnimplemented, "task.get is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskListStub stubs task.list: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type TaskListStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskListInput) (TaskListOutput, error)
+	calls []TaskListInput
+}
+
+// Returns makes task.list answer every call with output and err.
+func (s *TaskListStub) Returns(output TaskListOutput, err error) *TaskListStub {
+	return s.Func(func(context.Context, TaskListInput) (TaskListOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes task.list answer calls with fn.
+func (s *TaskListStub) Func(fn func(ctx context.Context, input TaskListInput) (TaskListOutput, error)) *TaskListStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.list was called with, in order.
+func (s *TaskListStub) Calls() []TaskListInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskListInput(nil), s.calls...)
+}
+
+func (s *TaskListStub) handle(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output TaskListOutput
+		return output, NewError(CodeUnimplemented, "task.list is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskUpdateStub stubs task.update: it answers with a canned output and error, or a
+// function computing them, and records the inputs it was called with.
+type TaskUpdateStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskUpdateInput) (TaskUpdateOutput, error)
+	calls []TaskUpdateInput
+}
+
+// Returns makes task.update answer every call with output and err.
+func (s *TaskUpdateStub) Returns(output TaskUpdateOutput, err error) *TaskUpdateStub {
+	return s.Func(func(context.Context, TaskUpdateInput) (TaskUpdateOutput, error) {
+		return output, err
+	})
+}
+
+// Func makes task.update answer calls with fn.
+func (s *TaskUpdateStub) Func(fn func(ctx context.Context, input TaskUpdateInput) (TaskUpdateOutput, error)) *TaskUpdateStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.update was called with, in order.
+func (s *TaskUpdateStub) Calls() []TaskUpdateInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskUpdateInput(nil), s.calls...)
+}
+
+func (s *TaskUpdateStub) handle(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		var output TaskUpdateOutput
+		return output, NewError(CodeUnimplemented, "task.update is not stubbed")
+	}
+	return fn(ctx, input)
+}
+
+// TaskWatchStub stubs the task.watch subscription: it sends canned events, or runs a
+// function sending them, and records the inputs it was called with.
+type TaskWatchStub struct {
+	mu    sync.Mutex
+	fn    func(ctx context.Context, input TaskWatchInput, send func(TaskWatchOutput) error) error
+	calls []TaskWatchInput
+}
+
+// Sends makes task.watch send events to every subscriber, then end the stream.
+func (s *TaskWatchStub) Sends(events ...TaskWatchOutput) *TaskWatchStub {
+	return s.Func(func(ctx context.Context, input TaskWatchInput, send func(TaskWatchOutput) error) error {
+		for _, event := range events {
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Func makes task.watch answer calls with fn.
+func (s *TaskWatchStub) Func(fn func(ctx context.Context, input TaskWatchInput, send func(TaskWatchOutput) error) error) *TaskWatchStub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fn = fn
+	return s
+}
+
+// Calls returns the inputs task.watch was called with, in order.
+func (s *TaskWatchStub) Calls() []TaskWatchInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TaskWatchInput(nil), s.calls...)
+}
+
+func (s *TaskWatchStub) handle(ctx context.Context, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, input)
+	fn := s.fn
+	s.mu.Unlock()
+	if fn == nil {
+		return NewError(CodeUnimplemented, "task.watch is not stubbed")
+	}
+	return fn(ctx, input, send)
+}
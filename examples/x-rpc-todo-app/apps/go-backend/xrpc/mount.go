@@ -0,0 +1,157 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Mountable is a router that can be mounted under another with Mount. Every
+// generated Router implements it, so a schema split into contracts generated
+// into separate packages can still be served from one endpoint. Outcomes are
+// passed as strings since each package has its own Outcome type.
+type Mountable interface {
+	// ServeCall answers a call whose envelope the mounting router decoded and
+	// returns its outcome.
+	ServeCall(w http.ResponseWriter, req *http.Request, method string, params json.RawMessage) string
+	// DispatchCall calls a method like Dispatch, without logging it, and returns
+	// its outcome.
+	DispatchCall(ctx context.Context, method string, params json.RawMessage) (interface{}, string, error)
+}
+
+// mountEntry is a router added with Mount and the prefix of the methods it
+// serves, including the trailing dot.
+type mountEntry struct {
+	prefix string
+	router Mountable
+}
+
+// Mount serves the methods of router under prefix: mounted under "billing", its
+// "invoice.get" method is called as "billing.invoice.get". A call first runs
+// this router's middleware, with the prefixed name so UseFor("billing.*")
+// matches it, then the mounted router's middleware, checks, interceptors and
+// handler. This router's CSRF protection and Logger cover mounted calls.
+// Context values pass through, but the keys behind WithUserID and
+// WithPrincipal belong to each package, so a mounted router generated into
+// another package must authenticate callers in its own middleware. Mount
+// panics if prefix is not a method name, overlaps a prefix already mounted,
+// or would hide one of this router's methods.
+func (r *Router) Mount(prefix string, router Mountable) *Router {
+	if prefix == "" || strings.HasPrefix(prefix, ".") || strings.HasSuffix(prefix, ".") || strings.ContainsAny(prefix, "*?[\\") {
+		panic("invalid mount prefix: " + prefix)
+	}
+	prefix += "."
+	for _, entry := range r.mounts {
+		if strings.HasPrefix(prefix, entry.prefix) || strings.HasPrefix(entry.prefix, prefix) {
+			panic("mount prefix " + strings.TrimSuffix(prefix, ".") + " overlaps " + strings.TrimSuffix(entry.prefix, "."))
+		}
+	}
+	for _, m := range methodDescriptors {
+		if strings.HasPrefix(m.name, prefix) {
+			panic("mount prefix " + strings.TrimSuffix(prefix, ".") + " hides method " + m.name)
+		}
+	}
+	r.mounts = append(r.mounts, mountEntry{prefix: prefix, router: router})
+	return r
+}
+
+// mountFor returns the router mounted under the prefix of method and the name
+// that router knows the method by.
+func (r *Router) mountFor(method string) (Mountable, string, bool) {
+	for _, entry := range r.mounts {
+		if strings.HasPrefix(method, entry.prefix) {
+			return entry.router, method[len(entry.prefix):], true
+		}
+	}
+	return nil, "", false
+}
+
+// allowsGET reports whether method may be called with GET. Mounted routers
+// decide for their own methods in ServeCall.
+func (r *Router) allowsGET(method string) bool {
+	if _, _, ok := r.mountFor(method); ok {
+		return true
+	}
+	return getAllowed(method)
+}
+
+// ServeCall answers a call whose {"method", "params"} envelope a mounting router
+// decoded, running this router's middleware, checks and handler as ServeHTTP
+// does. It returns the outcome for the mounting router's Logger.
+func (r *Router) ServeCall(w http.ResponseWriter, req *http.Request, method string, params json.RawMessage) string {
+	if req.Method == http.MethodGet && !r.allowsGET(method) {
+		r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))
+		return string(OutcomeRejected)
+	}
+	return string(r.serveCall(w, req, method, params))
+}
+
+// DispatchCall calls a query or mutation like Dispatch for a mounting router,
+// leaving panic recovery and logging to it, and returns the outcome.
+func (r *Router) DispatchCall(ctx context.Context, method string, params json.RawMessage) (interface{}, string, error) {
+	info := RequestInfo{Method: method}
+	result, outcome, err := r.dispatch(r.withDependencies(WithRequestInfo(ctx, info)), info, method, params)
+	return result, string(outcome), err
+}
+
+// mountedMethods lists the methods of a mounted router, named with their prefix,
+// through its xrpc.introspect method. Its MethodInfo is a type of another
+// package, so the listing is converted through JSON. Routers that disabled
+// introspection list none.
+func mountedMethods(entry mountEntry) []MethodInfo {
+	result, _, err := entry.router.DispatchCall(context.Background(), "xrpc.introspect", nil)
+	if err != nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil
+	}
+	var listing struct {
+		Methods []MethodInfo `json:"methods"`
+	}
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return nil
+	}
+	for i := range listing.Methods {
+		listing.Methods[i].Name = entry.prefix + listing.Methods[i].Name
+	}
+	return listing.Methods
+}
+
+// Handler returns the router as an http.Handler served under prefix, such as
+// "/api/v1". Calls are answered at the prefix, with or without a trailing
+// slash, and the routes of RESTHandler below it, such as /api/v1/tasks/{id}.
+// The prefix is stripped from the URL path before the router sees it, and
+// middleware, such as CORS handling, is wrapped around the handler, the first
+// outermost.
+func (r *Router) Handler(prefix string, middleware ...func(http.Handler) http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	rest := r.RESTHandler()
+	handler := http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "" || req.URL.Path == "/" {
+			r.ServeHTTP(w, req)
+			return
+		}
+		rest.ServeHTTP(w, req)
+	}))
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// MountHTTP registers the Handler of the router for prefix on mux, for the
+// prefix itself and every path below it, so no redirect adds a trailing slash:
+//
+//	v1.MountHTTP(mux, "/api/v1", cors)
+//	v2.MountHTTP(mux, "/api/v2", cors)
+func (r *Router) MountHTTP(mux *http.ServeMux, prefix string, middleware ...func(http.Handler) http.Handler) {
+	handler := r.Handler(prefix, middleware...)
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" {
+		mux.Handle(prefix, handler)
+	}
+	mux.Handle(prefix+"/", handler)
+}
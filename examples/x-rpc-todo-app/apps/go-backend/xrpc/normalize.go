@@ -0,0 +1,26 @@
+package xrpc
+
+import "strings"
+
+// Normalize normalizes Title in place.
+func (v *SubtaskAddInput) Normalize() {
+	v.Title = strings.TrimSpace(v.Title)
+}
+
+// Normalize normalizes Title and Description in place.
+func (v *TaskCreateInput) Normalize() {
+	v.Title = strings.Join(strings.Fields(strings.TrimSpace(v.Title)), " ")
+	if v.Description != nil {
+		*v.Description = strings.TrimSpace(*v.Description)
+	}
+}
+
+// Normalize normalizes Title and Description in place.
+func (v *TaskUpdateInput) Normalize() {
+	if v.Title != nil {
+		*v.Title = strings.TrimSpace(*v.Title)
+	}
+	if v.Description != nil {
+		*v.Description = strings.TrimSpace(*v.Description)
+	}
+}
@@ -0,0 +1,1171 @@
+package xrpc
+
+// openAPISpec is the OpenAPI 3.1 document describing this API.
+const openAPISpec = `{
+  "openapi": "3.1.0",
+  "info": {
+    "title": "xRPC API",
+    "version": "0.0.0"
+  },
+  "paths": {
+    "/api": {
+      "post": {
+        "operationId": "call",
+        "summary": "Call a query or mutation",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "oneOf": [
+                  {
+                    "$ref": "#/components/schemas/SubtaskAddRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/SubtaskToggleRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/TaskCreateRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/TaskDeleteRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/TaskGetRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/TaskListRequest"
+                  },
+                  {
+                    "$ref": "#/components/schemas/TaskUpdateRequest"
+                  }
+                ],
+                "discriminator": {
+                  "propertyName": "method",
+                  "mapping": {
+                    "subtask.add": "#/components/schemas/SubtaskAddRequest",
+                    "subtask.toggle": "#/components/schemas/SubtaskToggleRequest",
+                    "task.create": "#/components/schemas/TaskCreateRequest",
+                    "task.delete": "#/components/schemas/TaskDeleteRequest",
+                    "task.get": "#/components/schemas/TaskGetRequest",
+                    "task.list": "#/components/schemas/TaskListRequest",
+                    "task.update": "#/components/schemas/TaskUpdateRequest"
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The method's result",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "$ref": "#/components/schemas/SubtaskAddResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/SubtaskToggleResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/TaskCreateResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/TaskDeleteResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/TaskGetResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/TaskListResponse"
+                    },
+                    {
+                      "$ref": "#/components/schemas/TaskUpdateResponse"
+                    }
+                  ]
+                }
+              }
+            }
+          },
+          "default": {
+            "description": "The call failed",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      },
+      "get": {
+        "operationId": "subscribe",
+        "summary": "Open a subscription as a Server-Sent Events stream",
+        "parameters": [
+          {
+            "name": "method",
+            "in": "query",
+            "required": true,
+            "schema": {
+              "type": "string",
+              "enum": [
+                "task.watch"
+              ]
+            }
+          },
+          {
+            "name": "params",
+            "in": "query",
+            "description": "JSON-encoded subscription input",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A stream of events; each data line is one JSON-encoded output",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "string"
+                },
+                "x-xrpc-events": {
+                  "oneOf": [
+                    {
+                      "$ref": "#/components/schemas/TaskWatchOutput"
+                    }
+                  ]
+                }
+              }
+            }
+          },
+          "default": {
+            "description": "The call failed",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "SubtaskAddInput": {
+        "type": "object",
+        "properties": {
+          "taskId": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          }
+        },
+        "required": [
+          "taskId",
+          "title"
+        ]
+      },
+      "SubtaskAddOutput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "completed": {
+            "type": "boolean"
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "completed"
+        ]
+      },
+      "SubtaskAddRequest": {
+        "type": "object",
+        "description": "Calls the subtask.add mutation.",
+        "properties": {
+          "method": {
+            "const": "subtask.add"
+          },
+          "params": {
+            "$ref": "#/components/schemas/SubtaskAddInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "mutation"
+      },
+      "SubtaskAddResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/SubtaskAddOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "SubtaskToggleInput": {
+        "type": "object",
+        "properties": {
+          "taskId": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "subtaskId": {
+            "type": "string",
+            "format": "uuid"
+          }
+        },
+        "required": [
+          "taskId",
+          "subtaskId"
+        ]
+      },
+      "SubtaskToggleOutput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "completed": {
+            "type": "boolean"
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "completed"
+        ]
+      },
+      "SubtaskToggleRequest": {
+        "type": "object",
+        "description": "Calls the subtask.toggle mutation.",
+        "properties": {
+          "method": {
+            "const": "subtask.toggle"
+          },
+          "params": {
+            "$ref": "#/components/schemas/SubtaskToggleInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "mutation"
+      },
+      "SubtaskToggleResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/SubtaskToggleOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskCreateInput": {
+        "type": "object",
+        "properties": {
+          "title": {
+            "type": "string",
+            "minLength": 3,
+            "maxLength": 200
+          },
+          "description": {
+            "type": "string",
+            "maxLength": 2000
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ],
+            "default": "medium"
+          },
+          "dueDate": {
+            "type": "string",
+            "format": "date"
+          },
+          "estimatedHours": {
+            "type": "number",
+            "maximum": 100,
+            "exclusiveMinimum": 0,
+            "multipleOf": 0.25
+          }
+        },
+        "required": [
+          "title"
+        ],
+        "allOf": [
+          {
+            "if": {
+              "properties": {
+                "priority": {
+                  "const": "urgent"
+                }
+              },
+              "required": [
+                "priority"
+              ]
+            },
+            "then": {
+              "required": [
+                "dueDate"
+              ]
+            }
+          }
+        ]
+      },
+      "TaskCreateOutput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "description": {
+            "type": "string",
+            "maxLength": 2000
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "in_progress",
+              "completed",
+              "cancelled"
+            ]
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ]
+          },
+          "dueDate": {
+            "type": "string",
+            "format": "date"
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "completedAt": {
+            "anyOf": [
+              {
+                "type": "string",
+                "format": "date-time"
+              },
+              {
+                "type": "null"
+              }
+            ]
+          },
+          "assignee": {
+            "type": "object",
+            "properties": {
+              "id": {
+                "type": "string",
+                "format": "uuid"
+              },
+              "name": {
+                "type": "string",
+                "minLength": 2,
+                "maxLength": 100
+              },
+              "email": {
+                "type": "string",
+                "format": "email"
+              }
+            },
+            "required": [
+              "id",
+              "name",
+              "email"
+            ]
+          },
+          "subtasks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "id": {
+                  "type": "string",
+                  "format": "uuid"
+                },
+                "title": {
+                  "type": "string",
+                  "minLength": 1,
+                  "maxLength": 200
+                },
+                "completed": {
+                  "type": "boolean"
+                }
+              },
+              "required": [
+                "id",
+                "title",
+                "completed"
+              ]
+            },
+            "maxItems": 20
+          },
+          "estimatedHours": {
+            "type": "number",
+            "maximum": 100,
+            "exclusiveMinimum": 0,
+            "multipleOf": 0.25
+          },
+          "position": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "status",
+          "priority",
+          "createdAt",
+          "completedAt",
+          "subtasks",
+          "position"
+        ]
+      },
+      "TaskCreateRequest": {
+        "type": "object",
+        "description": "Calls the task.create mutation.",
+        "properties": {
+          "method": {
+            "const": "task.create"
+          },
+          "params": {
+            "$ref": "#/components/schemas/TaskCreateInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "mutation"
+      },
+      "TaskCreateResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/TaskCreateOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskDeleteInput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          }
+        },
+        "required": [
+          "id"
+        ]
+      },
+      "TaskDeleteOutput": {
+        "type": "object",
+        "properties": {
+          "success": {
+            "type": "boolean"
+          }
+        },
+        "required": [
+          "success"
+        ]
+      },
+      "TaskDeleteRequest": {
+        "type": "object",
+        "description": "Calls the task.delete mutation.",
+        "properties": {
+          "method": {
+            "const": "task.delete"
+          },
+          "params": {
+            "$ref": "#/components/schemas/TaskDeleteInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "mutation"
+      },
+      "TaskDeleteResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/TaskDeleteOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskGetInput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          }
+        },
+        "required": [
+          "id"
+        ]
+      },
+      "TaskGetOutput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "description": {
+            "type": "string",
+            "maxLength": 2000
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "in_progress",
+              "completed",
+              "cancelled"
+            ]
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ]
+          },
+          "dueDate": {
+            "type": "string",
+            "format": "date"
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "completedAt": {
+            "anyOf": [
+              {
+                "type": "string",
+                "format": "date-time"
+              },
+              {
+                "type": "null"
+              }
+            ]
+          },
+          "assignee": {
+            "type": "object",
+            "properties": {
+              "id": {
+                "type": "string",
+                "format": "uuid"
+              },
+              "name": {
+                "type": "string",
+                "minLength": 2,
+                "maxLength": 100
+              },
+              "email": {
+                "type": "string",
+                "format": "email"
+              }
+            },
+            "required": [
+              "id",
+              "name",
+              "email"
+            ]
+          },
+          "subtasks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "id": {
+                  "type": "string",
+                  "format": "uuid"
+                },
+                "title": {
+                  "type": "string",
+                  "minLength": 1,
+                  "maxLength": 200
+                },
+                "completed": {
+                  "type": "boolean"
+                }
+              },
+              "required": [
+                "id",
+                "title",
+                "completed"
+              ]
+            },
+            "maxItems": 20
+          },
+          "estimatedHours": {
+            "type": "number",
+            "maximum": 100,
+            "exclusiveMinimum": 0,
+            "multipleOf": 0.25
+          },
+          "position": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "status",
+          "priority",
+          "createdAt",
+          "completedAt",
+          "subtasks",
+          "position"
+        ]
+      },
+      "TaskGetRequest": {
+        "type": "object",
+        "description": "Calls the task.get query.",
+        "properties": {
+          "method": {
+            "const": "task.get"
+          },
+          "params": {
+            "$ref": "#/components/schemas/TaskGetInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "query"
+      },
+      "TaskGetResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/TaskGetOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskListInput": {
+        "type": "object",
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "in_progress",
+              "completed",
+              "cancelled"
+            ]
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ]
+          },
+          "cursor": {
+            "type": "string"
+          },
+          "pageSize": {
+            "type": "integer",
+            "minimum": 1,
+            "maximum": 100
+          }
+        }
+      },
+      "TaskListOutput": {
+        "type": "object",
+        "properties": {
+          "tasks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "id": {
+                  "type": "string",
+                  "format": "uuid"
+                },
+                "title": {
+                  "type": "string",
+                  "minLength": 1,
+                  "maxLength": 200
+                },
+                "status": {
+                  "type": "string",
+                  "enum": [
+                    "pending",
+                    "in_progress",
+                    "completed",
+                    "cancelled"
+                  ]
+                },
+                "priority": {
+                  "type": "string",
+                  "enum": [
+                    "low",
+                    "medium",
+                    "high",
+                    "urgent"
+                  ]
+                },
+                "dueDate": {
+                  "type": "string",
+                  "format": "date"
+                },
+                "createdAt": {
+                  "type": "string",
+                  "format": "date-time"
+                },
+                "completedAt": {
+                  "anyOf": [
+                    {
+                      "type": "string",
+                      "format": "date-time"
+                    },
+                    {
+                      "type": "null"
+                    }
+                  ]
+                },
+                "subtaskCount": {
+                  "type": "integer",
+                  "minimum": 0
+                },
+                "subtaskCompletedCount": {
+                  "type": "integer",
+                  "minimum": 0
+                },
+                "estimatedHours": {
+                  "type": "number",
+                  "maximum": 100,
+                  "exclusiveMinimum": 0,
+                  "multipleOf": 0.25
+                },
+                "position": {
+                  "type": "integer",
+                  "minimum": 0
+                }
+              },
+              "required": [
+                "id",
+                "title",
+                "status",
+                "priority",
+                "createdAt",
+                "completedAt",
+                "subtaskCount",
+                "subtaskCompletedCount",
+                "position"
+              ]
+            }
+          },
+          "total": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "nextCursor": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "tasks",
+          "total"
+        ]
+      },
+      "TaskListRequest": {
+        "type": "object",
+        "description": "Calls the task.list query.",
+        "properties": {
+          "method": {
+            "const": "task.list"
+          },
+          "params": {
+            "$ref": "#/components/schemas/TaskListInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "query"
+      },
+      "TaskListResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/TaskListOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskUpdateInput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "description": {
+            "anyOf": [
+              {
+                "type": "string",
+                "maxLength": 2000
+              },
+              {
+                "type": "null"
+              }
+            ]
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "in_progress",
+              "completed",
+              "cancelled"
+            ]
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ]
+          },
+          "dueDate": {
+            "anyOf": [
+              {
+                "type": "string",
+                "format": "date"
+              },
+              {
+                "type": "null"
+              }
+            ]
+          },
+          "estimatedHours": {
+            "anyOf": [
+              {
+                "type": "number",
+                "maximum": 100,
+                "exclusiveMinimum": 0,
+                "multipleOf": 0.25
+              },
+              {
+                "type": "null"
+              }
+            ]
+          }
+        },
+        "required": [
+          "id",
+          "description",
+          "dueDate",
+          "estimatedHours"
+        ]
+      },
+      "TaskUpdateOutput": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "format": "uuid"
+          },
+          "title": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+          },
+          "description": {
+            "type": "string",
+            "maxLength": 2000
+          },
+          "status": {
+            "type": "string",
+            "enum": [
+              "pending",
+              "in_progress",
+              "completed",
+              "cancelled"
+            ]
+          },
+          "priority": {
+            "type": "string",
+            "enum": [
+              "low",
+              "medium",
+              "high",
+              "urgent"
+            ]
+          },
+          "dueDate": {
+            "type": "string",
+            "format": "date"
+          },
+          "createdAt": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "completedAt": {
+            "anyOf": [
+              {
+                "type": "string",
+                "format": "date-time"
+              },
+              {
+                "type": "null"
+              }
+            ]
+          },
+          "assignee": {
+            "type": "object",
+            "properties": {
+              "id": {
+                "type": "string",
+                "format": "uuid"
+              },
+              "name": {
+                "type": "string",
+                "minLength": 2,
+                "maxLength": 100
+              },
+              "email": {
+                "type": "string",
+                "format": "email"
+              }
+            },
+            "required": [
+              "id",
+              "name",
+              "email"
+            ]
+          },
+          "subtasks": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "id": {
+                  "type": "string",
+                  "format": "uuid"
+                },
+                "title": {
+                  "type": "string",
+                  "minLength": 1,
+                  "maxLength": 200
+                },
+                "completed": {
+                  "type": "boolean"
+                }
+              },
+              "required": [
+                "id",
+                "title",
+                "completed"
+              ]
+            },
+            "maxItems": 20
+          },
+          "estimatedHours": {
+            "type": "number",
+            "maximum": 100,
+            "exclusiveMinimum": 0,
+            "multipleOf": 0.25
+          },
+          "position": {
+            "type": "integer",
+            "minimum": 0
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "status",
+          "priority",
+          "createdAt",
+          "completedAt",
+          "subtasks",
+          "position"
+        ]
+      },
+      "TaskUpdateRequest": {
+        "type": "object",
+        "description": "Calls the task.update mutation.",
+        "properties": {
+          "method": {
+            "const": "task.update"
+          },
+          "params": {
+            "$ref": "#/components/schemas/TaskUpdateInput"
+          }
+        },
+        "required": [
+          "method",
+          "params"
+        ],
+        "x-xrpc-kind": "mutation"
+      },
+      "TaskUpdateResponse": {
+        "type": "object",
+        "properties": {
+          "result": {
+            "$ref": "#/components/schemas/TaskUpdateOutput"
+          }
+        },
+        "required": [
+          "result"
+        ]
+      },
+      "TaskWatchInput": {
+        "type": "object",
+        "properties": {
+          "taskId": {
+            "type": "string",
+            "format": "uuid"
+          }
+        }
+      },
+      "TaskWatchOutput": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "created",
+              "updated",
+              "deleted"
+            ]
+          },
+          "taskId": {
+            "type": "string",
+            "format": "uuid"
+          }
+        },
+        "required": [
+          "type",
+          "taskId"
+        ]
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "code": {
+            "type": "string",
+            "description": "Machine-readable error code, e.g. INVALID_ARGUMENT or NOT_FOUND."
+          },
+          "message": {
+            "type": "string"
+          },
+          "details": {
+            "description": "Additional error information; validation failures list the failing fields."
+          }
+        },
+        "required": [
+          "code",
+          "message"
+        ]
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "properties": {
+          "error": {
+            "$ref": "#/components/schemas/Error"
+          }
+        },
+        "required": [
+          "error"
+        ]
+      }
+    }
+  }
+}`
+
+// OpenAPISpec returns the OpenAPI 3.1 document (JSON) describing every method,
+// its input and output schemas, and the error envelope.
+func (r *Router) OpenAPISpec() []byte {
+	return []byte(openAPISpec)
+}
@@ -0,0 +1,233 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// OperationStatus is how far an Operation got.
+type OperationStatus string
+
+const (
+	OperationRunning   OperationStatus = "running"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+	OperationCancelled OperationStatus = "cancelled"
+)
+
+// Operation tracks a call of an async mutation, which answers with it at once
+// and runs in the background. Clients poll it with operation.get until it is
+// done, when it holds the mutation's result or error.
+type Operation struct {
+	ID     string          `json:"operationId"`
+	Method string          `json:"method"`
+	Status OperationStatus `json:"status"`
+	// Result is the result of a call that succeeded. Operations the Client
+	// fetches hold it as json.RawMessage.
+	Result interface{} `json:"result,omitempty"`
+	// Error is why a call failed.
+	Error *Error `json:"error,omitempty"`
+	// UserID is the caller that started the operation, the only one the
+	// built-in methods show it to.
+	UserID    string    `json:"userId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Done reports whether the call op tracks has finished.
+func (op Operation) Done() bool {
+	return op.Status != OperationRunning
+}
+
+// OperationStore keeps operations for operation.get to answer from. Save is called
+// when an operation starts and when it is done. A store shared by several
+// servers, such as a database table, lets any of them answer for the operations
+// the others run.
+type OperationStore interface {
+	Save(ctx context.Context, op Operation) error
+	// Load returns the operation with the given ID, or false when there is none.
+	Load(ctx context.Context, id string) (Operation, bool, error)
+}
+
+// MemoryOperationStore keeps operations in memory, dropping those done for
+// longer than its retention. Routers use one with a retention of an hour
+// until SetOperationStore sets another.
+type MemoryOperationStore struct {
+	retention  time.Duration
+	mu         sync.Mutex
+	operations map[string]Operation
+}
+
+// NewMemoryOperationStore returns a MemoryOperationStore keeping operations for
+// retention once they are done.
+func NewMemoryOperationStore(retention time.Duration) *MemoryOperationStore {
+	return &MemoryOperationStore{retention: retention, operations: make(map[string]Operation)}
+}
+
+// Save stores op, replacing the operation with its ID.
+func (s *MemoryOperationStore) Save(ctx context.Context, op Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operations[op.ID] = op
+	if op.Done() {
+		time.AfterFunc(s.retention, func() { s.expire(op) })
+	}
+	return nil
+}
+
+// Load returns the operation with the given ID.
+func (s *MemoryOperationStore) Load(ctx context.Context, id string) (Operation, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.operations[id]
+	return op, ok, nil
+}
+
+// expire drops op unless it was saved again since.
+func (s *MemoryOperationStore) expire(op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.operations[op.ID]; ok && stored.UpdatedAt.Equal(op.UpdatedAt) {
+		delete(s.operations, op.ID)
+	}
+}
+
+// SetOperationStore sets the store the operations of async mutations are kept
+// in, such as one shared by every instance of the server.
+func (r *Router) SetOperationStore(store OperationStore) *Router {
+	r.operationStore = store
+	return r
+}
+
+// startOperation saves the Operation of a call of the async method m and calls
+// its handler in the background. The call keeps the values of ctx but not its
+// deadline or cancellation, which end with the request; the timeouts set with
+// SetTimeout and SetTimeoutFor still apply to it.
+func (r *Router) startOperation(ctx context.Context, info RequestInfo, m *methodDescriptor, input interface{}) (Operation, error) {
+	now := time.Now().UTC()
+	op := Operation{ID: newEventID(), Method: info.Method, Status: OperationRunning, CreatedAt: now, UpdatedAt: now}
+	op.UserID, _ = UserIDFrom(ctx)
+	if err := r.operationStore.Save(ctx, op); err != nil {
+		return Operation{}, err
+	}
+
+	// The response is written before the handler runs
+	info.ResponseWriter = nil
+	ctx, cancel := context.WithCancel(WithRequestInfo(context.WithoutCancel(ctx), info))
+	r.operations.Store(op.ID, cancel)
+	go func() {
+		defer cancel()
+		defer r.operations.Delete(op.ID)
+		result, err := r.runOperation(ctx, info, m, input)
+		r.finishOperation(ctx, info, m, op, result, err)
+	}()
+	return op, nil
+}
+
+// runOperation calls the handler of m through the interceptors, recovering its
+// panics, which no request is left to recover.
+func (r *Router) runOperation(ctx context.Context, info RequestInfo, m *methodDescriptor, input interface{}) (result interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.logf("xrpc: panic serving %s: %v\n%s", info.Method, rec, debug.Stack())
+			result, err = nil, NewError(CodeInternal, "Internal server error")
+		}
+	}()
+	return r.invoke(ctx, info, input, func(ctx context.Context) (interface{}, error) {
+		return m.invoke(r, ctx, info, input)
+	})
+}
+
+// finishOperation saves op with the outcome of its call and publishes the event
+// of a mutation that succeeded. Calls failing once their operation was
+// cancelled end it as cancelled.
+func (r *Router) finishOperation(ctx context.Context, info RequestInfo, m *methodDescriptor, op Operation, result interface{}, err error) {
+	cancelled := errors.Is(ctx.Err(), context.Canceled)
+	ctx = context.WithoutCancel(ctx)
+	op.UpdatedAt = time.Now().UTC()
+	switch {
+	case err == nil:
+		op.Status, op.Result = OperationSucceeded, result
+	case cancelled:
+		op.Status = OperationCancelled
+	default:
+		op.Status, op.Error = OperationFailed, AsError(err)
+		r.logCause(op.Error)
+	}
+	if err := r.operationStore.Save(ctx, op); err != nil {
+		r.logf("xrpc: saving operation %s of %s: %v", op.ID, op.Method, err)
+	}
+	if op.Status == OperationSucceeded && m.event != nil {
+		r.publish(ctx, info, m.event(result))
+	}
+}
+
+// GetOperation returns the operation with the given ID, or a CodeNotFound error.
+func (r *Router) GetOperation(ctx context.Context, id string) (Operation, error) {
+	op, ok, err := r.operationStore.Load(ctx, id)
+	if err != nil {
+		return Operation{}, err
+	}
+	if !ok {
+		return Operation{}, Errorf(CodeNotFound, "Operation not found: %s", id)
+	}
+	return op, nil
+}
+
+// CancelOperation cancels the context of the running operation with the given
+// ID and returns it. Handlers returning an error once their context is
+// cancelled end it as cancelled; those completing anyway end it as they would
+// have. Operations no call of this router runs, such as those of a server that
+// stopped, are marked cancelled at once; a server still running one saves its
+// outcome when done.
+func (r *Router) CancelOperation(ctx context.Context, id string) (Operation, error) {
+	op, err := r.GetOperation(ctx, id)
+	if err != nil || op.Done() {
+		return op, err
+	}
+	if cancel, ok := r.operations.Load(id); ok {
+		cancel.(context.CancelFunc)()
+		return op, nil
+	}
+	op.Status = OperationCancelled
+	op.UpdatedAt = time.Now().UTC()
+	return op, r.operationStore.Save(ctx, op)
+}
+
+// operationParams are the params of operation.get and operation.cancel.
+type operationParams struct {
+	OperationID string `json:"operationId"`
+}
+
+// serveOperation answers the built-in operation.get and operation.cancel methods.
+// Operations started by another caller are answered as not found, as if the
+// ID were wrong.
+func (r *Router) serveOperation(ctx context.Context, method string, params json.RawMessage) (interface{}, Outcome, error) {
+	var p operationParams
+	if len(params) > 0 {
+		if err := r.unmarshal(params, &p); err != nil {
+			return nil, OutcomeValidationError, paramsError(params, err)
+		}
+	}
+	if p.OperationID == "" {
+		return nil, OutcomeValidationError, NewError(CodeInvalidArgument, "operationId is required")
+	}
+	op, err := r.GetOperation(ctx, p.OperationID)
+	if err != nil {
+		return nil, OutcomeHandlerError, err
+	}
+	if userID, _ := UserIDFrom(ctx); op.UserID != userID {
+		return nil, OutcomeHandlerError, Errorf(CodeNotFound, "Operation not found: %s", p.OperationID)
+	}
+	if method == "operation.cancel" {
+		op, err = r.CancelOperation(ctx, p.OperationID)
+	}
+	if err != nil {
+		return nil, OutcomeHandlerError, err
+	}
+	return op, OutcomeSuccess, nil
+}
@@ -0,0 +1,210 @@
+package xrpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// outboxSchema creates the outbox table. Times are Unix nanoseconds, which every
+// database stores and orders the same way.
+const outboxSchema = `CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(32) PRIMARY KEY,
+	event VARCHAR(255) NOT NULL,
+	method VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	payload TEXT NOT NULL,
+	published_at BIGINT
+)`
+
+// OutboxOptions configures an Outbox.
+type OutboxOptions struct {
+	// Table is the outbox table, "xrpc_outbox" by default.
+	Table string
+	// DollarPlaceholders numbers query parameters $1, $2, ... as PostgreSQL
+	// expects, rather than the ? of MySQL and SQLite.
+	DollarPlaceholders bool
+	// BatchSize is the number of events a relay reads at once, 100 by default.
+	BatchSize int
+	// ErrorLog receives the errors of Relay. By default they are written to the
+	// standard logger.
+	ErrorLog *log.Logger
+}
+
+// Outbox keeps events in a table of the database mutations write their changes
+// to until a relay publishes them. Add stores an event in the transaction of
+// the changes, so it is published if and only if they are committed, even when
+// the process stops right after the commit. Events are published at least
+// once: consumers drop the ones delivered twice by ID. Events stored in an
+// outbox are published by its relay, so set no EventPublisher on the router.
+type Outbox struct {
+	db            *sql.DB
+	options       OutboxOptions
+	insert        string
+	pending       string
+	markPublished string
+	prune         string
+}
+
+// NewOutbox returns the Outbox kept in db. Create its table with CreateTable, or
+// with the statement of Schema in a migration.
+func NewOutbox(db *sql.DB, options OutboxOptions) *Outbox {
+	if options.Table == "" {
+		options.Table = "xrpc_outbox"
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 100
+	}
+	o := &Outbox{db: db, options: options}
+	o.insert = o.query("INSERT INTO %s (id, event, method, created_at, payload) VALUES (?, ?, ?, ?, ?)")
+	o.pending = o.query("SELECT id, event, method, created_at, payload FROM %s WHERE published_at IS NULL ORDER BY created_at, id LIMIT ?")
+	o.markPublished = o.query("UPDATE %s SET published_at = ? WHERE id = ?")
+	o.prune = o.query("DELETE FROM %s WHERE published_at IS NOT NULL AND published_at < ?")
+	return o
+}
+
+// query names the outbox table in statement and numbers its placeholders when
+// the database expects $1, $2, ...
+func (o *Outbox) query(statement string) string {
+	statement = fmt.Sprintf(statement, o.options.Table)
+	if !o.options.DollarPlaceholders {
+		return statement
+	}
+	var numbered strings.Builder
+	n := 0
+	for _, c := range statement {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&numbered, "$%d", n)
+			continue
+		}
+		numbered.WriteRune(c)
+	}
+	return numbered.String()
+}
+
+// Schema returns the CREATE TABLE statement of the outbox table.
+func (o *Outbox) Schema() string {
+	return fmt.Sprintf(outboxSchema, o.options.Table)
+}
+
+// CreateTable creates the outbox table if it does not exist.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	_, err := o.db.ExecContext(ctx, o.Schema())
+	return err
+}
+
+// Add stores event in the outbox within tx, the transaction the mutation writes
+// its changes in, such as
+//
+//	outbox.Add(ctx, tx, Event{Name: EventTaskCreated, Payload: TaskCreatedEvent(task)})
+//
+// Its ID and Time are set when empty, and its Method from the RequestInfo of
+// ctx.
+func (o *Outbox) Add(ctx context.Context, tx *sql.Tx, event Event) error {
+	if event.ID == "" {
+		event.ID = newEventID()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.Method == "" {
+		if info, ok := RequestInfoFrom(ctx); ok {
+			event.Method = info.Method
+		}
+	}
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return fmt.Errorf("outbox: encoding %s event: %w", event.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, o.insert, event.ID, event.Name, event.Method, event.Time.UnixNano(), string(payload))
+	return err
+}
+
+// RelayOnce publishes the events not published yet through publisher, oldest
+// first, with their payload as the json.RawMessage it was stored as, and marks
+// them published. It stops at the first event publisher fails on, which the
+// next call retries, and returns how many events it published.
+func (o *Outbox) RelayOnce(ctx context.Context, publisher EventPublisher) (int, error) {
+	published := 0
+	for {
+		events, err := o.readPending(ctx)
+		if err != nil {
+			return published, err
+		}
+		for _, event := range events {
+			if err := publisher.Publish(ctx, event); err != nil {
+				return published, fmt.Errorf("outbox: publishing %s event %s: %w", event.Name, event.ID, err)
+			}
+			if _, err := o.db.ExecContext(ctx, o.markPublished, time.Now().UnixNano(), event.ID); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(events) < o.options.BatchSize {
+			return published, nil
+		}
+	}
+}
+
+// readPending reads a batch of the events not published yet, oldest first.
+func (o *Outbox) readPending(ctx context.Context) ([]Event, error) {
+	rows, err := o.db.QueryContext(ctx, o.pending, o.options.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []Event
+	for rows.Next() {
+		var event Event
+		var created int64
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Name, &event.Method, &created, &payload); err != nil {
+			return nil, err
+		}
+		event.Time = time.Unix(0, created).UTC()
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Relay runs RelayOnce every interval until ctx is done, writing its errors to
+// the error log. Run one relay per outbox table: relays running at the same
+// time publish events twice.
+func (o *Outbox) Relay(ctx context.Context, publisher EventPublisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := o.RelayOnce(ctx, publisher); err != nil && ctx.Err() == nil {
+			o.logf("xrpc: relaying outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Outbox) logf(format string, args ...interface{}) {
+	if o.options.ErrorLog != nil {
+		o.options.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Prune deletes the events published before cutoff and returns how many it
+// deleted, to keep the outbox table small.
+func (o *Outbox) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := o.db.ExecContext(ctx, o.prune, cutoff.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package xrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// DefaultPageSize is the number of items a paginated query returns when called
+// without a pageSize.
+var DefaultPageSize = 20
+
+// PageSize returns the number of items to return for the pageSize a paginated
+// query was called with: size, or DefaultPageSize when it has none. size is
+// validated against the contract's bounds before handlers run.
+func PageSize(size *int) int {
+	if size == nil {
+		return DefaultPageSize
+	}
+	return *size
+}
+
+// EncodeCursor returns the cursor of the page starting at position, such as an
+// offset or the sort key of its first item, to set as nextCursor. The position
+// is JSON-encoded and then base64url-encoded, so clients pass it back without
+// depending on its content.
+func EncodeCursor(position interface{}) (*string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return nil, err
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	return &cursor, nil
+}
+
+// DecodeCursor decodes the cursor a paginated query was called with, made by
+// EncodeCursor, into position. Without one, the first page, position is left
+// unchanged; cursors clients altered or made up fail with INVALID_ARGUMENT.
+func DecodeCursor(cursor *string, position interface{}) error {
+	if cursor == nil || *cursor == "" {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err == nil {
+		err = json.Unmarshal(data, position)
+	}
+	if err != nil {
+		return NewError(CodeInvalidArgument, "Invalid cursor")
+	}
+	return nil
+}
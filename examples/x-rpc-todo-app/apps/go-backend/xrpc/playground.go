@@ -0,0 +1,293 @@
+package xrpc
+
+import (
+	"io"
+	"net/http"
+)
+
+// playgroundPage is the page of Playground. Its script lists the methods of
+// xrpc.introspect and POSTs calls to the URL the page was served from.
+const playgroundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>xRPC Playground</title>
+<style>
+body { margin: 0; font: 14px/1.4 system-ui, sans-serif; display: flex; height: 100vh; color: #222; }
+nav { width: 260px; overflow-y: auto; border-right: 1px solid #ddd; background: #fafafa; }
+nav h1 { font-size: 15px; margin: 12px; }
+nav button { display: flex; justify-content: space-between; width: 100%; padding: 6px 12px; border: 0; background: none; text-align: left; font: inherit; cursor: pointer; }
+nav button:hover, nav button.selected { background: #e8eefc; }
+nav button.deprecated span:first-child { text-decoration: line-through; }
+.kind { font-size: 11px; color: #666; }
+main { flex: 1; overflow-y: auto; padding: 16px 24px; }
+label { display: block; margin: 10px 0 2px; font-weight: 600; }
+label small { font-weight: normal; color: #666; }
+input, select, textarea { width: 100%; max-width: 480px; box-sizing: border-box; font: inherit; padding: 4px; }
+textarea { font-family: monospace; min-height: 60px; }
+.notice { color: #a60; }
+.actions { margin: 16px 0; }
+pre { background: #f4f4f4; padding: 12px; white-space: pre-wrap; word-break: break-word; }
+</style>
+</head>
+<body>
+<nav><h1>xRPC Playground</h1><div id="methods"></div></nav>
+<main id="main"><p>Select a method.</p></main>
+<script>
+"use strict";
+var endpoint = location.pathname;
+
+function el(tag, attrs, children) {
+  var node = document.createElement(tag);
+  Object.keys(attrs || {}).forEach(function (key) {
+    if (attrs[key] !== undefined && attrs[key] !== null) node.setAttribute(key, attrs[key]);
+  });
+  (children || []).forEach(function (child) {
+    node.append(child);
+  });
+  return node;
+}
+
+function parseHeaders(text) {
+  var headers = { "Content-Type": "application/json" };
+  text.split("\n").forEach(function (line) {
+    var i = line.indexOf(":");
+    if (i > 0) headers[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+  });
+  return headers;
+}
+
+function post(method, params, headers, signal) {
+  return fetch(endpoint, {
+    method: "POST",
+    headers: headers || { "Content-Type": "application/json" },
+    body: JSON.stringify({ method: method, params: params }),
+    signal: signal
+  });
+}
+
+// shellQuote quotes s for a POSIX shell, as NewSnippet does.
+function shellQuote(s) {
+  return "'" + s.replace(/'/g, "'\\''") + "'";
+}
+
+// snippet returns the curl and HTTPie commands sending a call, like NewSnippet.
+function snippet(method, params, headers, stream) {
+  var url = location.origin + endpoint;
+  var curl = "curl" + (stream ? " -N " : " ") + shellQuote(url) + " \\\n  -H 'Content-Type: application/json'";
+  var httpie = "http" + (stream ? " --stream" : "") + " POST " + shellQuote(url);
+  Object.keys(headers).sort().forEach(function (key) {
+    if (key === "Content-Type") return;
+    curl += " \\\n  -H " + shellQuote(key + ": " + headers[key]);
+    httpie += " " + shellQuote(key + ":" + headers[key]);
+  });
+  curl += " \\\n  -d " + shellQuote(JSON.stringify({ method: method, params: params }));
+  httpie += " method=" + shellQuote(method) + " params:=" + shellQuote(JSON.stringify(params));
+  return curl + "\n\n" + httpie;
+}
+
+// nonNull returns the schema a nullable schema allows besides null.
+function nonNull(schema) {
+  var variants = schema.anyOf || schema.oneOf;
+  if (variants) {
+    var others = variants.filter(function (v) { return v.type !== "null"; });
+    if (others.length === 1) return others[0];
+  }
+  return schema;
+}
+
+// field returns the control of a property and a function reading its value:
+// left empty, null for a required nullable field and undefined otherwise.
+function field(name, schema, required) {
+  var nullable = nonNull(schema) !== schema || (Array.isArray(schema.type) && schema.type.indexOf("null") >= 0);
+  schema = nonNull(schema);
+  var type = Array.isArray(schema.type) ? schema.type.find(function (t) { return t !== "null"; }) : schema.type;
+  var hint = schema.enum ? "one of " + schema.enum.join(", ") : (schema.format || type || "JSON");
+  var empty = nullable && required ? null : undefined;
+  var attrs = { name: name, required: required && !nullable ? "" : null };
+  var control, read;
+  if (schema.enum) {
+    control = el("select", attrs, [el("option", { value: "" }, [""])]
+      .concat(schema.enum.map(function (v) { return el("option", { value: JSON.stringify(v) }, [String(v)]); })));
+    read = function () { return control.value === "" ? empty : JSON.parse(control.value); };
+  } else if (type === "boolean") {
+    control = el("select", attrs,
+      [el("option", { value: "" }, [""]), el("option", { value: "true" }, ["true"]), el("option", { value: "false" }, ["false"])]);
+    read = function () { return control.value === "" ? empty : control.value === "true"; };
+  } else if (type === "integer" || type === "number") {
+    attrs.type = "number";
+    attrs.min = schema.minimum;
+    attrs.max = schema.maximum;
+    attrs.step = schema.multipleOf || (type === "integer" ? 1 : "any");
+    control = el("input", attrs);
+    read = function () { return control.value === "" ? empty : Number(control.value); };
+  } else if (type === "string") {
+    attrs.type = { email: "email", uri: "url", date: "date" }[schema.format] || "text";
+    attrs.minlength = schema.minLength;
+    attrs.maxlength = schema.maxLength;
+    attrs.pattern = schema.pattern;
+    attrs.placeholder = schema.format === "date-time" ? "2024-01-31T09:00:00Z" : null;
+    control = el("input", attrs);
+    read = function () { return control.value === "" ? empty : control.value; };
+  } else {
+    attrs.placeholder = JSON.stringify(schema);
+    control = el("textarea", attrs);
+    read = function () { return control.value.trim() === "" ? empty : JSON.parse(control.value); };
+  }
+  var label = el("label", {}, [name + (required ? " *" : "") + " ", el("small", {}, [hint])]);
+  var nodes = [label, control];
+  if (schema.description) nodes.push(el("small", {}, [schema.description]));
+  return { nodes: nodes, read: read };
+}
+
+function show(info, button) {
+  document.querySelectorAll("nav button").forEach(function (b) { b.classList.remove("selected"); });
+  button.classList.add("selected");
+  var main = document.getElementById("main");
+  main.replaceChildren(el("h2", {}, [info.name]), el("p", { class: "kind" }, [info.kind]));
+  if (info.deprecated) {
+    main.append(el("p", { class: "notice" }, ["Deprecated. " + (info.deprecated.message || "")]));
+  }
+
+  var input = info.input || {};
+  var required = input.required || [];
+  var fields = [];
+  var form = el("form", { novalidate: "" });
+  Object.keys(input.properties || {}).forEach(function (name) {
+    var f = field(name, input.properties[name], required.indexOf(name) >= 0);
+    f.name = name;
+    fields.push(f);
+    f.nodes.forEach(function (node) { form.append(node); });
+  });
+  var raw = el("textarea", { rows: 8, hidden: "" });
+  var rawToggle = el("input", { type: "checkbox", style: "width: auto" });
+  var headers = el("textarea", { rows: 3, placeholder: "Authorization: Bearer ..." }, [sessionStorage.getItem("xrpc.headers") || ""]);
+  var send = el("button", { type: "submit" }, ["Send"]);
+  var copy = el("button", { type: "button" }, ["Show curl"]);
+  var status = el("p", { class: "kind" });
+  var output = el("pre", {});
+  var running = null;
+
+  function formParams() {
+    var value = {};
+    fields.forEach(function (f) {
+      var v = f.read();
+      if (v !== undefined) value[f.name] = v;
+    });
+    return value;
+  }
+
+  function params() {
+    return rawToggle.checked ? JSON.parse(raw.value || "{}") : formParams();
+  }
+
+  rawToggle.addEventListener("change", function () {
+    if (rawToggle.checked) {
+      try { raw.value = JSON.stringify(formParams(), null, 2); } catch (e) { raw.value = "{}"; }
+    }
+    raw.hidden = !rawToggle.checked;
+  });
+  form.append(el("label", {}, [rawToggle, " Edit params as JSON"]), raw,
+    el("label", {}, ["Headers ", el("small", {}, ["Name: value, one per line"])]), headers,
+    el("div", { class: "actions" }, [send, " ", copy]));
+
+  copy.addEventListener("click", function () {
+    try {
+      output.textContent = snippet(info.name, params(), parseHeaders(headers.value), info.kind === "subscription");
+      status.textContent = "Commands sending this call";
+    } catch (e) {
+      status.textContent = "Invalid JSON: " + e.message;
+    }
+  });
+
+  form.addEventListener("submit", function (event) {
+    event.preventDefault();
+    if (running) {
+      running.abort();
+      return;
+    }
+    if (!rawToggle.checked && !form.reportValidity()) return;
+    var value;
+    try {
+      value = params();
+    } catch (e) {
+      status.textContent = "Invalid JSON: " + e.message;
+      return;
+    }
+    sessionStorage.setItem("xrpc.headers", headers.value);
+    running = new AbortController();
+    send.textContent = "Stop";
+    output.textContent = "";
+    var start = performance.now();
+    post(info.name, value, parseHeaders(headers.value), running.signal).then(function (resp) {
+      status.textContent = resp.status + " " + resp.statusText;
+      var reader = resp.body.getReader();
+      var decoder = new TextDecoder();
+      var text = "";
+      function pump() {
+        return reader.read().then(function (chunk) {
+          if (chunk.done) {
+            try { text = JSON.stringify(JSON.parse(text), null, 2); } catch (e) {}
+            output.textContent = text;
+            return;
+          }
+          text += decoder.decode(chunk.value, { stream: true });
+          output.textContent = text;
+          return pump();
+        });
+      }
+      return pump();
+    }).catch(function (e) {
+      if (e.name !== "AbortError") status.textContent = String(e);
+    }).finally(function () {
+      status.textContent += " in " + Math.round(performance.now() - start) + " ms";
+      running = null;
+      send.textContent = "Send";
+    });
+  });
+  main.append(form, status, output);
+}
+
+post("xrpc.introspect", {}).then(function (resp) { return resp.json(); }).then(function (body) {
+  var list = document.getElementById("methods");
+  if (body.error) {
+    list.append(el("p", { class: "notice" }, [body.error.message]));
+    return;
+  }
+  body.result.methods.forEach(function (info) {
+    var button = el("button", { class: info.deprecated ? "deprecated" : null },
+      [el("span", {}, [info.name]), el("span", { class: "kind" }, [info.kind])]);
+    button.addEventListener("click", function () { show(info, button); });
+    list.append(button);
+  });
+});
+</script>
+</body>
+</html>
+`
+
+// Playground returns a handler serving an interactive page for trying out the
+// router's methods: it lists them with a form per input, built from the
+// constraints of its fields, and shows the response of each call sent. GET
+// requests without a method get the page; the calls it POSTs to the same URL
+// are passed to the router, so mount it behind the API's own authentication:
+//
+//	mux.Handle("/playground", router.Playground())
+//
+// It answers 404 while introspection is disabled.
+func (r *Router) Playground() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.introspectionDisabled {
+			http.NotFound(w, req)
+			return
+		}
+		if (req.Method != http.MethodGet && req.Method != http.MethodHead) || req.URL.Query().Get("method") != "" {
+			r.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'")
+		io.WriteString(w, playgroundPage)
+	})
+}
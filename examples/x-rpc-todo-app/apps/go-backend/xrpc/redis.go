@@ -0,0 +1,282 @@
+package xrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisOptions configures the connections of a RedisCacheStore or a
+// RedisSessionStore.
+type RedisOptions struct {
+	// Username and Password authenticate connections with AUTH when Password is
+	// set; Username is for the ACL users of Redis 6 and later.
+	Username string
+	Password string
+	// DB is the database selected with SELECT, 0 by default.
+	DB int
+	// MaxIdleConns is the number of idle connections kept for reuse, 8 by
+	// default.
+	MaxIdleConns int
+	// DialTimeout bounds connecting, 5 seconds by default.
+	DialTimeout time.Duration
+}
+
+// redisClient sends the commands of a Redis store over pooled connections.
+// Commands are bounded by the deadline of their context, such as the timeout
+// of the call.
+type redisClient struct {
+	addr    string
+	options RedisOptions
+	idle    chan *redisConn
+}
+
+// newRedisClient returns a client of the Redis server at addr. Connections are
+// made when they are first needed.
+func newRedisClient(addr string, options RedisOptions) *redisClient {
+	if options.MaxIdleConns <= 0 {
+		options.MaxIdleConns = 8
+	}
+	if options.DialTimeout <= 0 {
+		options.DialTimeout = 5 * time.Second
+	}
+	return &redisClient{addr: addr, options: options, idle: make(chan *redisConn, options.MaxIdleConns)}
+}
+
+// Close closes the idle connections of the store.
+func (c *redisClient) Close() error {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a command and returns its reply. Idle connections the server closed
+// fail their first command, which is sent again on a new connection; every
+// command of the stores is safe to repeat.
+func (c *redisClient) do(ctx context.Context, args ...string) (interface{}, error) {
+	for {
+		conn, reused, err := c.conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := conn.do(ctx, args...)
+		var replyErr redisError
+		if err == nil || errors.As(err, &replyErr) {
+			c.release(conn)
+			return reply, err
+		}
+		// The connection may be left in the middle of a reply
+		conn.Close()
+		if !reused || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
+
+// conn returns an idle connection, reporting that it was reused, or a new one
+// authenticated and set to the database of the options.
+func (c *redisClient) conn(ctx context.Context) (*redisConn, bool, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, true, nil
+	default:
+	}
+	dialer := net.Dialer{Timeout: c.options.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, false, err
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.options.Password != "" {
+		args := []string{"AUTH", c.options.Password}
+		if c.options.Username != "" {
+			args = []string{"AUTH", c.options.Username, c.options.Password}
+		}
+		if _, err := conn.do(ctx, args...); err != nil {
+			conn.Close()
+			return nil, false, err
+		}
+	}
+	if c.options.DB != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(c.options.DB)); err != nil {
+			conn.Close()
+			return nil, false, err
+		}
+	}
+	return conn, false, nil
+}
+
+// release keeps conn for reuse, or closes it when enough connections are idle.
+func (c *redisClient) release(conn *redisConn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// getJSON decodes the value of key, reporting false if there is none.
+func (c *redisClient) getJSON(ctx context.Context, key string, value interface{}) (bool, error) {
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return false, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return false, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setJSON sets key to the JSON of value with SET, for Redis to expire it after
+// ttl.
+func (c *redisClient) setJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl.Milliseconds() <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, "SET", key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// RedisCacheStore keeps the entries of the Cache interceptor in Redis, shared by
+// every replica of the server and expired by Redis.
+type RedisCacheStore struct {
+	*redisClient
+}
+
+// NewRedisCacheStore returns a RedisCacheStore for the Redis server at addr, such
+// as localhost:6379. Connections are made when they are first needed.
+func NewRedisCacheStore(addr string, options RedisOptions) *RedisCacheStore {
+	return &RedisCacheStore{newRedisClient(addr, options)}
+}
+
+// Load returns the entry stored under key with GET.
+func (s *RedisCacheStore) Load(ctx context.Context, key string) (CacheEntry, bool, error) {
+	var entry CacheEntry
+	ok, err := s.getJSON(ctx, key, &entry)
+	return entry, ok, err
+}
+
+// Save stores entry under key with SET, expiring it in Redis when it expires.
+func (s *RedisCacheStore) Save(ctx context.Context, key string, entry CacheEntry) error {
+	return s.setJSON(ctx, key, entry, time.Until(entry.Expires))
+}
+
+// RedisSessionStore keeps the sessions of the Sessions middleware in Redis under
+// "xrpc:session:" and their ID, shared by every replica of the server and
+// expired by Redis.
+type RedisSessionStore struct {
+	*redisClient
+}
+
+// NewRedisSessionStore returns a RedisSessionStore for the Redis server at addr,
+// such as localhost:6379. Connections are made when they are first needed.
+func NewRedisSessionStore(addr string, options RedisOptions) *RedisSessionStore {
+	return &RedisSessionStore{newRedisClient(addr, options)}
+}
+
+// Load returns the values of the session id with GET.
+func (s *RedisSessionStore) Load(ctx context.Context, id string) (map[string]json.RawMessage, bool, error) {
+	var values map[string]json.RawMessage
+	ok, err := s.getJSON(ctx, "xrpc:session:"+id, &values)
+	return values, ok, err
+}
+
+// Save stores the values of the session id with SET, expiring them after ttl.
+func (s *RedisSessionStore) Save(ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration) error {
+	return s.setJSON(ctx, "xrpc:session:"+id, values, ttl)
+}
+
+// Delete removes the session id with DEL.
+func (s *RedisSessionStore) Delete(ctx context.Context, id string) error {
+	_, err := s.do(ctx, "DEL", "xrpc:session:"+id)
+	return err
+}
+
+// redisConn is a connection to a Redis server.
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply of the server, after which the connection is
+// still usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do sends a command as an array of bulk strings and reads its reply, by the
+// deadline of ctx if it has one.
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	// The zero time of contexts without a deadline clears it
+	deadline, _ := ctx.Deadline()
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply reads a reply: a string for simple strings, an int64 for integers,
+// a []byte for bulk strings, nil for the null bulk string of a missing key, or
+// a redisError.
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	text := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return text, nil
+	case '-':
+		return nil, redisError(text)
+	case ':':
+		return strconv.ParseInt(text, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
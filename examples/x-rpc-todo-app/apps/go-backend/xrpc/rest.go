@@ -0,0 +1,202 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restRoute maps an HTTP method and path to a method of the router. Segments
+// in braces capture a path parameter. kinds tells how the "string" and array
+// fields of the input are bound from query and path parameters; any other
+// field is read as a JSON literal.
+type restRoute struct {
+	method   string
+	segments []string
+	call     string
+	kinds    map[string]string
+}
+
+// restRoutes lists the routes, literal segments before parameters
+var restRoutes = []restRoute{
+	{
+		method:   http.MethodPost,
+		segments: []string{"tasks"},
+		call:     "task.create",
+		kinds:    map[string]string{"title": "string", "description": "string", "priority": "string", "dueDate": "string"},
+	},
+	{
+		method:   http.MethodDelete,
+		segments: []string{"tasks", "{id}"},
+		call:     "task.delete",
+		kinds:    map[string]string{"id": "string"},
+	},
+	{
+		method:   http.MethodGet,
+		segments: []string{"tasks", "{id}"},
+		call:     "task.get",
+		kinds:    map[string]string{"id": "string"},
+	},
+	{
+		method:   http.MethodGet,
+		segments: []string{"tasks"},
+		call:     "task.list",
+		kinds:    map[string]string{"status": "string", "priority": "string", "cursor": "string"},
+	},
+	{
+		method:   http.MethodPatch,
+		segments: []string{"tasks", "{id}"},
+		call:     "task.update",
+		kinds:    map[string]string{"id": "string", "title": "string", "description": "string", "status": "string", "priority": "string", "dueDate": "string"},
+	},
+}
+
+// restCallKey is the context key of the restCall RESTHandler bound a request to.
+type restCallKey struct{}
+
+// restCall is the method and params of a request served by RESTHandler.
+type restCall struct {
+	method string
+	params json.RawMessage
+}
+
+// RESTHandler serves the methods declared with an http mapping as RESTful routes
+// for clients that cannot send RPC envelopes. Path parameters, query parameters
+// and the JSON body of POST, PUT and PATCH requests are bound to the input
+// fields of the same name (path parameters win), and the call goes through the
+// same middleware, validation and handler as the RPC method. Results are written
+// without the {"result": ...} envelope; errors keep their usual body and status.
+// Mount it next to the RPC endpoint:
+//
+//	mux.Handle("/rest/", http.StripPrefix("/rest", router.RESTHandler()))
+func (r *Router) RESTHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		route, pathParams, allowed := matchRESTRoute(req.Method, req.URL)
+		if route == nil && len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))
+			return
+		}
+		if route == nil {
+			r.writeError(w, Errorf(CodeNotFound, "Route not found: %s %s", req.Method, req.URL.Path))
+			return
+		}
+		params, err := bindRESTParams(req, route, pathParams)
+		if err != nil {
+			r.writeError(w, Errorf(CodeInvalidArgument, "Invalid request: %v", err))
+			return
+		}
+		ctx := context.WithValue(req.Context(), restCallKey{}, restCall{method: route.call, params: params})
+		r.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// restCallFrom returns the method and params RESTHandler bound req to, if any.
+func restCallFrom(req *http.Request) (restCall, bool) {
+	call, ok := req.Context().Value(restCallKey{}).(restCall)
+	return call, ok
+}
+
+// marshalResult encodes a result in the {"result": ...} envelope, or bare for
+// requests served by RESTHandler, into a pooled buffer.
+func (r *Router) marshalResult(req *http.Request, result interface{}) (*encodeBuffer, error) {
+	_, ok := restCallFrom(req)
+	return r.marshal(result, !ok)
+}
+
+// matchRESTRoute returns the route matching method and the path of u with its
+// path parameters. When only other methods match the path, it returns them as
+// allowed instead.
+func matchRESTRoute(method string, u *url.URL) (route *restRoute, pathParams map[string]string, allowed []string) {
+	// Split the escaped path so an encoded slash stays inside its segment
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for i := range restRoutes {
+		params, ok := matchRESTPath(restRoutes[i].segments, segments)
+		if !ok {
+			continue
+		}
+		if restRoutes[i].method != method {
+			allowed = append(allowed, restRoutes[i].method)
+			continue
+		}
+		return &restRoutes[i], params, nil
+	}
+	return nil, nil, allowed
+}
+
+// matchRESTPath matches escaped path segments against a route's segments,
+// returning the unescaped path parameters.
+func matchRESTPath(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, part := range pattern {
+		value, err := url.PathUnescape(segments[i])
+		if err != nil {
+			return nil, false
+		}
+		if strings.HasPrefix(part, "{") {
+			if value == "" {
+				return nil, false
+			}
+			params[part[1:len(part)-1]] = value
+			continue
+		}
+		if value != part {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// bindRESTParams builds the params of a REST request from its JSON body, then
+// its query parameters and finally its path parameters.
+func bindRESTParams(req *http.Request, route *restRoute, pathParams map[string]string) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if req.Method != http.MethodGet && req.Method != http.MethodDelete {
+		if err := decodeRequest(req, &fields); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if fields == nil {
+			// The body was null
+			fields = map[string]json.RawMessage{}
+		}
+	}
+	for name, values := range req.URL.Query() {
+		fields[name] = route.bind(name, values)
+	}
+	for name, value := range pathParams {
+		fields[name] = route.bind(name, []string{value})
+	}
+	return json.Marshal(fields)
+}
+
+// bind encodes the query or path parameter values of an input field as JSON.
+// Single-valued fields take the last value.
+func (route *restRoute) bind(name string, values []string) json.RawMessage {
+	kind := route.kinds[name]
+	if kind == "strings" || kind == "array" {
+		items := make([]json.RawMessage, len(values))
+		for i, value := range values {
+			items[i] = restValue(value, kind == "strings")
+		}
+		data, _ := json.Marshal(items)
+		return data
+	}
+	return restValue(values[len(values)-1], kind == "string")
+}
+
+// restValue encodes a parameter value as a JSON string when quoted is set or
+// the value is not valid JSON, and as the JSON literal it spells otherwise.
+func restValue(value string, quoted bool) json.RawMessage {
+	if !quoted && json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
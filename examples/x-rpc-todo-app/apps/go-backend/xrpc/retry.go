@@ -0,0 +1,106 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides which failed calls a Client retries and how long it waits
+// before each retry: InitialBackoff, doubled after every retry up to
+// MaxBackoff and jittered so clients that failed together don't retry
+// together, or the Retry-After the server answered with. A retry that could
+// not start within Budget or the call's deadline is not made.
+type RetryPolicy struct {
+	// MaxAttempts bounds the attempts of a call, the first included; 1 turns
+	// retrying off
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff; 0 leaves it uncapped
+	MaxBackoff time.Duration
+	// Budget bounds the time from the first attempt to the start of the last;
+	// 0 leaves it unbounded
+	Budget time.Duration
+	// Retryable reports whether a call of method that failed with err may be
+	// retried; nil uses DefaultRetryable
+	Retryable func(method string, err error) bool
+}
+
+// DefaultRetryPolicy is the RetryPolicy of Clients created without
+// WithRetryPolicy: up to three attempts of the queries DefaultRetryable
+// allows, 100ms apart and then 200ms, give or take the jitter.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// DefaultRetryable retries queries, which don't change server state, when they
+// failed to reach the server or were answered UNAVAILABLE, DEADLINE_EXCEEDED
+// or RESOURCE_EXHAUSTED. Mutations are never retried, as a failed one may
+// still have been applied.
+func DefaultRetryable(method string, err error) bool {
+	if m, ok := methodTable[method]; !ok || m.kind != "query" {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var callErr *Error
+	if errors.As(err, &callErr) {
+		return callErr.Code == CodeUnavailable || callErr.Code == CodeDeadlineExceeded || callErr.Code == CodeResourceExhausted
+	}
+	// http.Client.Do fails with a *url.Error
+	var transportErr *url.Error
+	return errors.As(err, &transportErr)
+}
+
+// backoff returns how long to wait before retrying a call of method that failed
+// with err on its attempt-th attempt, elapsed after the first one started, or
+// false if it must not be retried.
+func (p RetryPolicy) backoff(ctx context.Context, method string, err error, attempt int, retryAfter, elapsed time.Duration) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	if !retryable(method, err) {
+		return 0, false
+	}
+
+	wait := retryAfter
+	if wait <= 0 {
+		wait = p.InitialBackoff
+		for i := 1; i < attempt && (p.MaxBackoff == 0 || wait < p.MaxBackoff); i++ {
+			wait *= 2
+		}
+		if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+		wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	}
+	if p.Budget > 0 && elapsed+wait > p.Budget {
+		return 0, false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+	return wait, true
+}
+
+// retryAfter parses a Retry-After header, in seconds or an HTTP date; 0 when it
+// is absent or invalid.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
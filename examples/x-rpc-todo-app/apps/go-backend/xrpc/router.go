@@ -1,363 +1,715 @@
 package xrpc
 
 import (
-    "encoding/json"
-    "net/http"
-    "fmt"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// middlewareEntry is a registered middleware and the method pattern it applies
+// to; an empty pattern applies to every method.
+type middlewareEntry struct {
+	pattern    string
+	middleware MiddlewareFunc
+}
+
+// interceptorEntry is a registered interceptor and the method pattern it applies
+// to; an empty pattern applies to every method.
+type interceptorEntry struct {
+	pattern     string
+	interceptor InterceptorFunc
+}
+
+// timeoutEntry is a timeout set with SetTimeoutFor and the method pattern it
+// applies to.
+type timeoutEntry struct {
+	pattern string
+	timeout time.Duration
+}
+
 type Router struct {
-    middleware []MiddlewareFunc
-    taskList TaskListHandler
-    taskGet TaskGetHandler
-    taskCreate TaskCreateHandler
-    taskUpdate TaskUpdateHandler
-    taskDelete TaskDeleteHandler
-    subtaskAdd SubtaskAddHandler
-    subtaskToggle SubtaskToggleHandler
+	middleware            []middlewareEntry
+	interceptors          []interceptorEntry
+	timeout               time.Duration
+	timeouts              []timeoutEntry
+	errorLog              *log.Logger
+	errorStatus           func(err *Error) int
+	logger                Logger
+	authorizer            Authorizer
+	csrf                  *CSRFOptions
+	compression           bool
+	compressionMinSize    int
+	codec                 Codec
+	lenientDecoding       bool
+	concurrency           *concurrencyPool
+	concurrencyPools      []*concurrencyPool
+	readinessChecks       []readinessCheck
+	introspectionDisabled bool
+	deprecationHeaders    bool
+	mounts                []mountEntry
+	methodGate            MethodGate
+	disabled              sync.Map
+	maintenance           atomic.Bool
+	readOnly              atomic.Bool
+	eventPublisher        EventPublisher
+	operationStore        OperationStore
+	operations            sync.Map
+	providers             map[interface{}]func(ctx context.Context) interface{}
+	handlersMu            sync.RWMutex
+	subtaskAdd            SubtaskAddHandler
+	subtaskToggle         SubtaskToggleHandler
+	taskCreate            TaskCreateHandler
+	taskDelete            TaskDeleteHandler
+	taskGet               TaskGetHandler
+	taskList              TaskListHandler
+	taskUpdate            TaskUpdateHandler
+	taskWatch             TaskWatchHandler
 }
+
+// NewRouter panics if a custom validator the contract uses is not registered
+// with RegisterValidator.
 func NewRouter() *Router {
-    return &Router{
-        middleware: make([]MiddlewareFunc, 0),
-    }
+	mustRegisteredValidators()
+	return &Router{
+		middleware:     make([]middlewareEntry, 0),
+		operationStore: NewMemoryOperationStore(time.Hour),
+	}
 }
-func (r *Router) TaskList(handler TaskListHandler) *Router {
-    r.taskList = handler
-    return r
+
+// SubtaskAdd sets the handler of subtask.add.
+func (r *Router) SubtaskAdd(handler SubtaskAddHandler) *Router {
+	r.handlersMu.Lock()
+	r.subtaskAdd = handler
+	r.handlersMu.Unlock()
+	return r
 }
-func (r *Router) TaskGet(handler TaskGetHandler) *Router {
-    r.taskGet = handler
-    return r
+
+// SubtaskToggle sets the handler of subtask.toggle.
+func (r *Router) SubtaskToggle(handler SubtaskToggleHandler) *Router {
+	r.handlersMu.Lock()
+	r.subtaskToggle = handler
+	r.handlersMu.Unlock()
+	return r
 }
+
+// TaskCreate sets the handler of task.create.
 func (r *Router) TaskCreate(handler TaskCreateHandler) *Router {
-    r.taskCreate = handler
-    return r
-}
-func (r *Router) TaskUpdate(handler TaskUpdateHandler) *Router {
-    r.taskUpdate = handler
-    return r
+	r.handlersMu.Lock()
+	r.taskCreate = handler
+	r.handlersMu.Unlock()
+	return r
 }
+
+// TaskDelete sets the handler of task.delete.
 func (r *Router) TaskDelete(handler TaskDeleteHandler) *Router {
-    r.taskDelete = handler
-    return r
+	r.handlersMu.Lock()
+	r.taskDelete = handler
+	r.handlersMu.Unlock()
+	return r
 }
-func (r *Router) SubtaskAdd(handler SubtaskAddHandler) *Router {
-    r.subtaskAdd = handler
-    return r
+
+// TaskGet sets the handler of task.get.
+func (r *Router) TaskGet(handler TaskGetHandler) *Router {
+	r.handlersMu.Lock()
+	r.taskGet = handler
+	r.handlersMu.Unlock()
+	return r
 }
-func (r *Router) SubtaskToggle(handler SubtaskToggleHandler) *Router {
-    r.subtaskToggle = handler
-    return r
+
+// TaskList sets the handler of task.list.
+func (r *Router) TaskList(handler TaskListHandler) *Router {
+	r.handlersMu.Lock()
+	r.taskList = handler
+	r.handlersMu.Unlock()
+	return r
+}
+
+// TaskUpdate sets the handler of task.update.
+func (r *Router) TaskUpdate(handler TaskUpdateHandler) *Router {
+	r.handlersMu.Lock()
+	r.taskUpdate = handler
+	r.handlersMu.Unlock()
+	return r
+}
+
+// TaskWatch sets the handler of task.watch.
+func (r *Router) TaskWatch(handler TaskWatchHandler) *Router {
+	r.handlersMu.Lock()
+	r.taskWatch = handler
+	r.handlersMu.Unlock()
+	return r
 }
+
 func (r *Router) Use(middleware MiddlewareFunc) *Router {
-    r.middleware = append(r.middleware, middleware)
-    return r
+	r.middleware = append(r.middleware, middlewareEntry{middleware: middleware})
+	return r
+}
+
+// UseFor registers middleware that only runs for methods matching pattern, such
+// as "task.*" or "*.create". Patterns use path.Match syntax; an exact method
+// name matches only that method. Middleware runs in registration order,
+// interleaved with middleware added through Use.
+func (r *Router) UseFor(pattern string, middleware MiddlewareFunc) *Router {
+	mustValidPattern(pattern)
+	r.middleware = append(r.middleware, middlewareEntry{pattern: pattern, middleware: middleware})
+	return r
+}
+
+// Intercept registers an interceptor that wraps every query and mutation call.
+// Interceptors run after middleware and validation; the first registered is
+// the outermost. Subscriptions stream events and are not intercepted.
+func (r *Router) Intercept(interceptor InterceptorFunc) *Router {
+	r.interceptors = append(r.interceptors, interceptorEntry{interceptor: interceptor})
+	return r
+}
+
+// InterceptFor registers an interceptor for methods matching pattern, using the
+// same pattern syntax as UseFor.
+func (r *Router) InterceptFor(pattern string, interceptor InterceptorFunc) *Router {
+	mustValidPattern(pattern)
+	r.interceptors = append(r.interceptors, interceptorEntry{pattern: pattern, interceptor: interceptor})
+	return r
+}
+
+// SetTimeout sets how long queries and mutations may run. A call still running
+// when its timeout expires has its context cancelled and fails with
+// DEADLINE_EXCEEDED. Zero, the default, means no timeout. Subscriptions stream
+// until the client disconnects and are not timed out. Calls are also bounded by
+// the deadline their client sends in the X-Xrpc-Timeout header, as the Client
+// of this package does for contexts with a deadline, whichever expires first.
+func (r *Router) SetTimeout(timeout time.Duration) *Router {
+	r.timeout = timeout
+	return r
+}
+
+// SetTimeoutFor sets the timeout of methods matching pattern, using the same
+// pattern syntax as UseFor. It overrides SetTimeout; when several patterns
+// match a method, the last one set wins.
+func (r *Router) SetTimeoutFor(pattern string, timeout time.Duration) *Router {
+	mustValidPattern(pattern)
+	r.timeouts = append(r.timeouts, timeoutEntry{pattern: pattern, timeout: timeout})
+	return r
+}
+
+// SetAuthorizer sets the Authorizer checking the permissions methods declare.
+// Without one, every call of a method that declares permissions is denied.
+func (r *Router) SetAuthorizer(authorizer Authorizer) *Router {
+	r.authorizer = authorizer
+	return r
+}
+
+// authorize checks the caller holds permissions, or fails with PERMISSION_DENIED.
+func (r *Router) authorize(ctx context.Context, info RequestInfo, permissions []string) error {
+	if r.authorizer == nil {
+		return NewError(CodePermissionDenied, "Permission denied")
+	}
+	allowed, err := r.authorizer.Authorize(ctx, info, permissions)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return NewError(CodePermissionDenied, "Permission denied")
+	}
+	return nil
+}
+
+// SetErrorLog sets the logger used to report recovered handler panics and events
+// that failed to publish. By default they are written to the standard logger.
+func (r *Router) SetErrorLog(logger *log.Logger) *Router {
+	r.errorLog = logger
+	return r
+}
+
+func (r *Router) logf(format string, args ...interface{}) {
+	if r.errorLog != nil {
+		r.errorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// SetErrorStatus overrides the HTTP status sent with error responses. By default
+// it is err.Code.HTTPStatus(); return http.StatusOK to keep errors in-band for
+// clients that only read the error envelope.
+func (r *Router) SetErrorStatus(status func(err *Error) int) *Router {
+	r.errorStatus = status
+	return r
+}
+
+// writeError writes err with the status of its code, or the one SetErrorStatus
+// chose, logging the error it keeps from the client.
+func (r *Router) writeError(w http.ResponseWriter, err *Error) {
+	r.logCause(err)
+	status := err.Code.HTTPStatus()
+	if r.errorStatus != nil {
+		status = r.errorStatus(err)
+	}
+	writeError(w, status, err)
+}
+
+// logCause logs the error an INTERNAL error made by AsError stands for, which
+// the client is not told.
+func (r *Router) logCause(err *Error) {
+	if err.cause != nil {
+		r.logf("xrpc: internal error: %v", err.cause)
+	}
+}
+
+// mustValidPattern panics if pattern is not valid path.Match syntax, so typos
+// surface when the router is built rather than as silently skipped methods.
+func mustValidPattern(pattern string) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("invalid method pattern: " + pattern)
+	}
+}
+
+// matchMethod reports whether method matches pattern; an empty pattern matches
+// every method.
+func matchMethod(pattern, method string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, method)
+	return matched
+}
+
+// intercept wraps handler in the interceptors registered for info.Method
+func (r *Router) intercept(info RequestInfo, input interface{}, handler NextFunc) NextFunc {
+	next := handler
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		entry := r.interceptors[i]
+		if !matchMethod(entry.pattern, info.Method) {
+			continue
+		}
+		inner := next
+		next = func(ctx context.Context) (interface{}, error) {
+			return entry.interceptor(ctx, info, input, inner)
+		}
+	}
+	return next
+}
+
+// invoke calls handler through the interceptors registered for info.Method,
+// within its concurrency limits and timeout
+func (r *Router) invoke(ctx context.Context, info RequestInfo, input interface{}, handler NextFunc) (interface{}, error) {
+	release, err := r.acquire(ctx, info.Method)
+	if err != nil {
+		return nil, err
+	}
+	// The slot is held until the handler returns, even past a timeout
+	intercepted := r.intercept(info, input, handler)
+	next := func(ctx context.Context) (interface{}, error) {
+		defer release()
+		return intercepted(ctx)
+	}
+	timeout := r.timeoutFor(info.Method)
+	if timeout <= 0 {
+		return next(ctx)
+	}
+	return r.invokeWithTimeout(ctx, info, timeout, next)
+}
+
+// timeoutFor returns the timeout of method, or zero if it has none
+func (r *Router) timeoutFor(method string) time.Duration {
+	timeout := r.timeout
+	for _, entry := range r.timeouts {
+		if matchMethod(entry.pattern, method) {
+			timeout = entry.timeout
+		}
+	}
+	return timeout
+}
+
+// clientTimeout returns the time left until the deadline the client of req sent
+// in the X-Xrpc-Timeout header, in milliseconds. Values that are not a number
+// of milliseconds up to 2^32-1 are ignored.
+func clientTimeout(req *http.Request) (time.Duration, bool) {
+	value := req.Header.Get("X-Xrpc-Timeout")
+	if value == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// invokeWithTimeout calls next with a context that expires after timeout. If it
+// expires first, the call fails with DEADLINE_EXCEEDED and whatever next
+// returns or writes to info.ResponseWriter afterwards is dropped.
+func (r *Router) invokeWithTimeout(ctx context.Context, info RequestInfo, timeout time.Duration, next NextFunc) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan func() (interface{}, error), 1)
+	go func() {
+		// Panics are recovered here, in the handler's goroutine, so a late one
+		// doesn't crash the server
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				done <- func() (interface{}, error) { panic(rec) }
+				return
+			}
+			r.logf("xrpc: panic serving %s: %v\n%s", info.Method, rec, debug.Stack())
+			done <- func() (interface{}, error) { return nil, NewError(CodeInternal, "Internal server error") }
+		}()
+		result, err := next(ctx)
+		done <- func() (interface{}, error) { return result, err }
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome()
+	case <-ctx.Done():
+		if writer, ok := info.ResponseWriter.(*timeoutWriter); ok {
+			writer.expire()
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, NewError(CodeDeadlineExceeded, "Deadline exceeded")
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// timeoutWriter passes a handler's writes through to the response until its call
+// times out, then drops them, so a slow handler can't write to a response the
+// router already answered.
+type timeoutWriter struct {
+	http.ResponseWriter
+	mu      sync.Mutex
+	expired bool
+}
+
+func (tw *timeoutWriter) expire() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.expired = true
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired {
+		return http.Header{}
+	}
+	return tw.ResponseWriter.Header()
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.expired {
+		tw.ResponseWriter.WriteHeader(status)
+	}
 }
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-    if req.Method != http.MethodPost {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-
-    var request struct {
-        Method string          `json:"method"`
-        Params json.RawMessage `json:"params"`
-    }
-
-    if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
-        http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
-        return
-    }
-
-    ctx := &Context{
-        Request:        req,
-        ResponseWriter: w,
-        Data:           make(map[string]interface{}),
-    }
-
-    // Execute middleware chain
-    for _, middleware := range r.middleware {
-        result := middleware(ctx)
-        if result.Error != nil {
-            http.Error(w, fmt.Sprintf("Middleware error: %v", result.Error), http.StatusInternalServerError)
-            return
-        }
-        if result.Response != nil {
-            // Middleware short-circuited with response
-            return
-        }
-        ctx = result.Context
-    }
-
-    switch request.Method {
-        case "task.list":
-            if r.taskList == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input TaskListInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateTaskListInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.taskList(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "task.get":
-            if r.taskGet == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input TaskGetInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateTaskGetInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.taskGet(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "task.create":
-            if r.taskCreate == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input TaskCreateInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateTaskCreateInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.taskCreate(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "task.update":
-            if r.taskUpdate == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input TaskUpdateInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateTaskUpdateInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.taskUpdate(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "task.delete":
-            if r.taskDelete == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input TaskDeleteInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateTaskDeleteInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.taskDelete(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "subtask.add":
-            if r.subtaskAdd == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input SubtaskAddInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateSubtaskAddInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.subtaskAdd(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        case "subtask.toggle":
-            if r.subtaskToggle == nil {
-                http.Error(w, "Handler not registered", http.StatusNotFound)
-                return
-            }
-
-            var input SubtaskToggleInput
-            if err := json.Unmarshal(request.Params, &input); err != nil {
-                http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)
-                return
-            }
-
-            if err := ValidateSubtaskToggleInput(input); err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                w.WriteHeader(http.StatusBadRequest)
-                if validationErrs, ok := err.(ValidationErrors); ok {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": "Validation failed",
-                        "errors": validationErrs,
-                    })
-                } else {
-                    json.NewEncoder(w).Encode(map[string]interface{}{
-                        "error": err.Error(),
-                    })
-                }
-                return
-            }
-
-            result, err := r.subtaskToggle(ctx, input)
-            if err != nil {
-                w.Header().Set("Content-Type", "application/json")
-                json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})
-                return
-            }
-
-            w.Header().Set("Content-Type", "application/json")
-            json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
-            return
-        default:
-            http.Error(w, "Method not found", http.StatusNotFound)
-            return
-    }
+	var request struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	outcome := OutcomeRejected
+
+	if r.logger != nil {
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		w = rec
+		start := time.Now()
+		defer func() {
+			r.logger.LogRequest(req.Context(), LogEntry{
+				Method:   request.Method,
+				Duration: time.Since(start),
+				Outcome:  outcome,
+				Code:     rec.code,
+				Status:   rec.status,
+				Size:     rec.size,
+				Tenant:   rec.tenant,
+			})
+		}()
+	}
+
+	if err := r.checkCSRF(req); err != nil {
+		r.writeError(w, err)
+		return
+	}
+
+	if call, ok := restCallFrom(req); ok {
+		// RESTHandler already bound the route to a method and params
+		request.Method = call.method
+		request.Params = call.params
+	} else {
+		switch req.Method {
+		case http.MethodPost:
+			if err := decodeRequest(req, &request); err != nil {
+				r.writeError(w, Errorf(CodeInvalidArgument, "Invalid request: %v", err))
+				return
+			}
+		case http.MethodGet:
+			// Queries can be cached by HTTP intermediaries and EventSource clients
+			// can only issue GET requests, so both accept the envelope as query
+			// parameters. Mutations must still be POSTed.
+			query := req.URL.Query()
+			request.Method = query.Get("method")
+			request.Params = decodeQueryParams(query.Get("params"))
+			if !r.allowsGET(request.Method) {
+				r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))
+				return
+			}
+		default:
+			r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))
+			return
+		}
+	}
+
+	outcome = r.serveCall(w, req, request.Method, request.Params)
+}
+
+// serveCall runs the middleware for a call whose envelope ServeHTTP decoded and
+// answers it, or hands it to the router mounted under its prefix. It returns
+// how far the call got, for logging.
+func (r *Router) serveCall(w http.ResponseWriter, req *http.Request, method string, params json.RawMessage) (outcome Outcome) {
+	outcome = OutcomeRejected
+
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		if rec == http.ErrAbortHandler {
+			panic(rec)
+		}
+		r.logf("xrpc: panic serving %s: %v\n%s", method, rec, debug.Stack())
+		r.writeError(w, NewError(CodeInternal, "Internal server error"))
+	}()
+
+	info := RequestInfo{
+		Method:         method,
+		Request:        req,
+		ResponseWriter: w,
+	}
+	if r.timeoutFor(method) > 0 {
+		// A handler still running after its timeout must not write to the
+		// response the router answered with DEADLINE_EXCEEDED
+		info.ResponseWriter = &timeoutWriter{ResponseWriter: w}
+	}
+	ctx := r.withDependencies(WithRequestInfo(req.Context(), info))
+	ctx = withWarnings(ctx)
+	// The client's deadline bounds the middleware too, and the calls the handler
+	// makes to other services with ctx
+	if timeout, ok := clientTimeout(req); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if hash := req.Header.Get("X-Xrpc-Schema-Hash"); hash != "" && hash != SchemaHash {
+		w.Header().Set("X-Xrpc-Schema-Mismatch", SchemaHash)
+	}
+	if r.deprecationHeaders {
+		if m, ok := methodTable[method]; ok && m.deprecation != "" {
+			w.Header().Set("Deprecation", m.deprecation)
+			if m.sunset != "" {
+				w.Header().Set("Sunset", m.sunset)
+			}
+		}
+	}
+
+	// The Logger gets the tenant middleware resolved, even for calls it rejected
+	defer func() {
+		if tenant, ok := TenantFrom(ctx); ok {
+			if rec, ok := w.(tenantRecorder); ok {
+				rec.RecordTenant(tenant.ID)
+			}
+		}
+	}()
+
+	// Execute middleware chain
+	for _, entry := range r.middleware {
+		if !matchMethod(entry.pattern, method) {
+			continue
+		}
+		result := entry.middleware(ctx, info)
+		if result.Error != nil {
+			r.writeError(w, AsError(result.Error))
+			return
+		}
+		if result.Response != nil {
+			// Middleware short-circuited with response
+			return
+		}
+		if result.Context != nil {
+			ctx = result.Context
+		}
+	}
+
+	if sub, name, ok := r.mountFor(method); ok {
+		return Outcome(sub.ServeCall(w, req.WithContext(ctx), name, params))
+	}
+
+	if m, ok := methodTable[method]; ok && m.serve != nil {
+		input, stage, err := r.prepare(ctx, info, m, params)
+		if err != nil {
+			outcome = stage
+			r.writeError(w, AsError(err))
+			return
+		}
+		outcome = m.serve(r, ctx, info, input, w, req)
+		return
+	}
+
+	result, stage, err := r.dispatch(ctx, info, method, params)
+	outcome = stage
+	if err != nil {
+		r.writeError(w, AsError(err))
+		return
+	}
+
+	// Encode before writing so results that fail to encode (such as invalid
+	// enum values) are still reported as errors
+	buf, err := r.marshalResult(req, result)
+	if err != nil {
+		outcome = OutcomeHandlerError
+		r.writeError(w, AsError(err))
+		return
+	}
+	defer buf.release()
+
+	if _, ok := restCallFrom(req); !ok {
+		if warnings := WarningsFrom(ctx); len(warnings) > 0 {
+			buf.appendWarnings(warnings)
+		}
+	}
+
+	if req.Method == http.MethodGet && notModified(w, req, buf.Bytes()) {
+		return
+	}
+	r.writeResult(w, req, buf.Bytes())
+	return
+}
+
+// dispatch checks, decodes and validates the params of a query or mutation and
+// calls its handler through the interceptors, then publishes the event of a
+// mutation that succeeded. Async mutations answer with the Operation running
+// them in the background instead. It returns the handler's result and how far
+// the call got, for logging. Methods under a mount prefix are dispatched by the
+// mounted router.
+func (r *Router) dispatch(ctx context.Context, info RequestInfo, method string, params json.RawMessage) (interface{}, Outcome, error) {
+	m, ok := methodTable[method]
+	if !ok {
+		if method == "xrpc.introspect" && !r.introspectionDisabled {
+			return map[string]interface{}{"methods": r.Introspect(), "schemaHash": SchemaHash}, OutcomeSuccess, nil
+		}
+		if method == "operation.get" || method == "operation.cancel" {
+			return r.serveOperation(ctx, method, params)
+		}
+		if sub, name, ok := r.mountFor(method); ok {
+			result, stage, err := sub.DispatchCall(ctx, name, params)
+			return result, Outcome(stage), err
+		}
+		return nil, OutcomeRejected, r.methodNotFound(method)
+	}
+	if m.invoke == nil {
+		return nil, OutcomeRejected, Errorf(CodeMethodNotAllowed, "Method %s is a subscription and needs a streaming transport", method)
+	}
+
+	input, stage, err := r.prepare(ctx, info, m, params)
+	if err != nil {
+		return nil, stage, err
+	}
+	if m.async {
+		op, err := r.startOperation(ctx, info, m, input)
+		if err != nil {
+			return nil, OutcomeHandlerError, err
+		}
+		return op, OutcomeSuccess, nil
+	}
+
+	result, err := r.invoke(ctx, info, input, func(ctx context.Context) (interface{}, error) {
+		return m.invoke(r, ctx, info, input)
+	})
+	if err != nil {
+		return nil, OutcomeHandlerError, err
+	}
+	if m.event != nil {
+		r.publish(ctx, info, m.event(result))
+	}
+	return result, OutcomeSuccess, nil
+}
+
+// decodeQueryParams reads the params query parameter, which holds the input
+// either as URL-encoded JSON or as base64url-encoded JSON. A missing value
+// is an empty object.
+func decodeQueryParams(value string) json.RawMessage {
+	if value == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err == nil && json.Valid(decoded) {
+		return json.RawMessage(decoded)
+	}
+	// Left as is so decoding reports it as invalid params
+	return json.RawMessage(value)
+}
+
+// notModified sets the ETag of a query result served over GET, a hash of its
+// encoded body, and answers 304 Not Modified when the request's If-None-Match
+// already names it, so polling clients skip unchanged results.
+func notModified(w http.ResponseWriter, req *http.Request, body []byte) bool {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	for _, candidate := range strings.Split(req.Header.Get("If-None-Match"), ",") {
+		// Weak comparison, as RFC 9110 requires for If-None-Match
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// writeEvent writes a single Server-Sent Event and flushes it to the client
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
 }
@@ -0,0 +1,351 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// registerTestValidators registers custom validators that accept every value
+// for the ones the tests' process has not registered.
+func registerTestValidators() {
+	for _, name := range customValidators {
+		if _, ok := validators[name]; !ok {
+			RegisterValidator(name, func(interface{}) error { return nil })
+		}
+	}
+}
+
+// newTestRouter returns a router whose handlers return their method's example
+// result.
+func newTestRouter() *Router {
+	registerTestValidators()
+	return NewRouter().
+		SubtaskAdd(func(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error) {
+			return ExampleSubtaskAddOutput(), nil
+		}).
+		SubtaskToggle(func(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+			return ExampleSubtaskToggleOutput(), nil
+		}).
+		TaskCreate(func(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error) {
+			return ExampleTaskCreateOutput(), nil
+		}).
+		TaskDelete(func(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error) {
+			return ExampleTaskDeleteOutput(), nil
+		}).
+		TaskGet(func(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error) {
+			return ExampleTaskGetOutput(), nil
+		}).
+		TaskList(func(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error) {
+			return ExampleTaskListOutput(), nil
+		}).
+		TaskUpdate(func(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error) {
+			return ExampleTaskUpdateOutput(), nil
+		})
+}
+
+// post returns a POST request carrying body to the API
+func post(body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(body))
+}
+
+// serve sends req to r and returns the response status and decoded JSON body
+func serve(t *testing.T, r *Router, req *http.Request) (int, map[string]json.RawMessage) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v: %s", err, rec.Body.String())
+	}
+	return rec.Code, body
+}
+
+// errorCode returns the code of an error response body
+func errorCode(body map[string]json.RawMessage) ErrorCode {
+	var e struct {
+		Code ErrorCode `json:"code"`
+	}
+	json.Unmarshal(body["error"], &e)
+	return e.Code
+}
+
+// TestRouterEnvelope checks that malformed requests are rejected with the right
+// error code and HTTP status.
+func TestRouterEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+		code ErrorCode
+	}{
+		{"malformed JSON", post(`{"method":`), CodeInvalidArgument},
+		{"unknown method", post(`{"method":"xrpc.unknown","params":{}}`), CodeMethodNotFound},
+		{"missing method", post(`{"params":{}}`), CodeMethodNotFound},
+		{"unsupported HTTP method", httptest.NewRequest(http.MethodPut, "/api", nil), CodeMethodNotAllowed},
+		{"params of the wrong type", post(`{"method":"subtask.add","params":42}`), CodeInvalidArgument},
+		{"mutation over GET", httptest.NewRequest(http.MethodGet, "/api?method=subtask.add", nil), CodeMethodNotAllowed},
+	}
+	r := newTestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, r, tt.req)
+			if code := errorCode(body); code != tt.code {
+				t.Errorf("code = %q, want %q", code, tt.code)
+			}
+			if status != tt.code.HTTPStatus() {
+				t.Errorf("status = %d, want %d", status, tt.code.HTTPStatus())
+			}
+		})
+	}
+}
+
+// TestRouterUnimplemented checks that methods without a handler are reported as
+// unimplemented.
+func TestRouterUnimplemented(t *testing.T) {
+	registerTestValidators()
+	status, body := serve(t, NewRouter(), post(`{"method":"subtask.add","params":{}}`))
+	if code := errorCode(body); code != CodeUnimplemented {
+		t.Errorf("code = %q, want %q", code, CodeUnimplemented)
+	}
+	if status != CodeUnimplemented.HTTPStatus() {
+		t.Errorf("status = %d, want %d", status, CodeUnimplemented.HTTPStatus())
+	}
+}
+
+// TestRouterValidation checks that params missing a required field fail
+// validation and name the field.
+func TestRouterValidation(t *testing.T) {
+	tests := []struct {
+		method string
+		params string
+		field  string
+	}{
+		{"subtask.add", `{"title":"example"}`, "taskId"},
+		{"subtask.toggle", `{"subtaskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`, "taskId"},
+		{"task.create", `{"description":"example","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`, "title"},
+		{"task.delete", `{}`, "id"},
+		{"task.get", `{}`, "id"},
+		{"task.update", `{"title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`, "id"},
+	}
+	r := newTestRouter()
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := post(`{"method":"` + tt.method + `","params":` + tt.params + `}`)
+			status, body := serve(t, r, req)
+			if status != CodeInvalidArgument.HTTPStatus() {
+				t.Errorf("status = %d, want %d", status, CodeInvalidArgument.HTTPStatus())
+			}
+			if !strings.Contains(string(body["error"]), `"field":"`+tt.field+`"`) {
+				t.Errorf("error %s does not name field %s", body["error"], tt.field)
+			}
+		})
+	}
+}
+
+// TestParamsError checks that params of the wrong JSON type are reported on the
+// path of the value, and without a path when params themselves are not an
+// object.
+func TestParamsError(t *testing.T) {
+	var input struct {
+		Tasks []struct {
+			Title string `json:"title"`
+		} `json:"tasks"`
+	}
+	tests := []struct {
+		name    string
+		params  string
+		field   string
+		message string
+	}{
+		{"nested field", `{"tasks":[{"title":"a"},{"title":1}]}`, "tasks[1].title", "must be a string, got number"},
+		{"params", `[]`, "", "must be an object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := paramsError([]byte(tt.params), json.Unmarshal([]byte(tt.params), &input))
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != 1 {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			if errs[0].Field != tt.field || errs[0].Message != tt.message {
+				t.Errorf("error = %q: %q, want %q: %q", errs[0].Field, errs[0].Message, tt.field, tt.message)
+			}
+			if len(errs[0].Path) == 0 && tt.field != "" {
+				t.Errorf("error on %s has no path", tt.field)
+			}
+		})
+	}
+}
+
+// TestRouterRoundTrip calls every method with its example params and checks
+// the result decodes into its output type; queries are also called with GET.
+func TestRouterRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		decode func(data []byte) error
+	}{
+		{
+			name: "subtask.add",
+			req:  post(`{"method":"subtask.add","params":` + exampleSubtaskAddInput + `}`),
+			decode: func(data []byte) error {
+				var output SubtaskAddOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "subtask.toggle",
+			req:  post(`{"method":"subtask.toggle","params":` + exampleSubtaskToggleInput + `}`),
+			decode: func(data []byte) error {
+				var output SubtaskToggleOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "task.create",
+			req:  post(`{"method":"task.create","params":` + exampleTaskCreateInput + `}`),
+			decode: func(data []byte) error {
+				var output TaskCreateOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "task.delete",
+			req:  post(`{"method":"task.delete","params":` + exampleTaskDeleteInput + `}`),
+			decode: func(data []byte) error {
+				var output TaskDeleteOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "task.get",
+			req:  post(`{"method":"task.get","params":` + exampleTaskGetInput + `}`),
+			decode: func(data []byte) error {
+				var output TaskGetOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "GET task.get",
+			req:  httptest.NewRequest(http.MethodGet, "/api?method=task.get&params="+url.QueryEscape(exampleTaskGetInput), nil),
+			decode: func(data []byte) error {
+				var output TaskGetOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "task.list",
+			req:  post(`{"method":"task.list","params":` + exampleTaskListInput + `}`),
+			decode: func(data []byte) error {
+				var output TaskListOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "GET task.list",
+			req:  httptest.NewRequest(http.MethodGet, "/api?method=task.list&params="+url.QueryEscape(exampleTaskListInput), nil),
+			decode: func(data []byte) error {
+				var output TaskListOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+		{
+			name: "task.update",
+			req:  post(`{"method":"task.update","params":` + exampleTaskUpdateInput + `}`),
+			decode: func(data []byte) error {
+				var output TaskUpdateOutput
+				return json.Unmarshal(data, &output)
+			},
+		},
+	}
+	r := newTestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := serve(t, r, tt.req)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d: %s", status, body["error"])
+			}
+			if err := tt.decode(body["result"]); err != nil {
+				t.Errorf("result does not decode: %v", err)
+			}
+		})
+	}
+}
+
+// TestTenantKeys checks that calls of two tenants with the same params get
+// separate cache entries and flights, even when the cache is shared by users.
+func TestTenantKeys(t *testing.T) {
+	params := map[string]string{"id": "1"}
+	keys := map[string]func(ctx context.Context) (string, error){
+		"cacheKey": func(ctx context.Context) (string, error) {
+			return cacheKey(ctx, CacheOptions{Shared: true}, "test.method", params)
+		},
+		"flightKey": func(ctx context.Context) (string, error) {
+			return flightKey(ctx, "test.method", params)
+		},
+	}
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			acme, err := key(WithTenant(context.Background(), Tenant{ID: "acme"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			globex, err := key(WithTenant(context.Background(), Tenant{ID: "globex"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if acme == globex {
+				t.Errorf("tenants share the key %q", acme)
+			}
+		})
+	}
+}
+
+// TestInBandErrors checks that the clients return the error of an envelope the
+// router sends with 200, as SetErrorStatus lets it.
+func TestInBandErrors(t *testing.T) {
+	r := newTestRouter().SetErrorStatus(func(err *Error) int { return http.StatusOK })
+	server := httptest.NewServer(r)
+	defer server.Close()
+	var output json.RawMessage
+	errs := map[string]error{
+		"Client":     NewClient(server.URL).call(context.Background(), "no.such", struct{}{}, &output),
+		"TestClient": NewTestClient(r).call(context.Background(), "no.such", struct{}{}, &output),
+	}
+	for name, err := range errs {
+		if code := AsError(err).Code; code != CodeMethodNotFound {
+			t.Errorf("%s: code = %q, want %q (err: %v)", name, code, CodeMethodNotFound, err)
+		}
+	}
+}
+
+// BenchmarkServeHTTP serves a call of every method with its example params, and
+// one failing with an unknown method, reporting the allocations per call.
+func BenchmarkServeHTTP(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		body string
+	}{
+		{"subtask.add", `{"method":"subtask.add","params":` + exampleSubtaskAddInput + `}`},
+		{"subtask.toggle", `{"method":"subtask.toggle","params":` + exampleSubtaskToggleInput + `}`},
+		{"task.create", `{"method":"task.create","params":` + exampleTaskCreateInput + `}`},
+		{"task.delete", `{"method":"task.delete","params":` + exampleTaskDeleteInput + `}`},
+		{"task.get", `{"method":"task.get","params":` + exampleTaskGetInput + `}`},
+		{"task.list", `{"method":"task.list","params":` + exampleTaskListInput + `}`},
+		{"task.update", `{"method":"task.update","params":` + exampleTaskUpdateInput + `}`},
+		{"unknown method", `{"method":"xrpc.unknown","params":{}}`},
+	}
+	r := newTestRouter()
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.ServeHTTP(httptest.NewRecorder(), post(bm.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package xrpc
+
+import "encoding/json"
+
+// typeSchemas holds the JSON Schema document of every input and output type,
+// keyed by its Go type name.
+var typeSchemas = map[string]json.RawMessage{
+	"SubtaskAddInput":     json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"SubtaskAddInput","type":"object","properties":{"taskId":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200}},"required":["taskId","title"]}`),
+	"SubtaskAddOutput":    json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"SubtaskAddOutput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]}`),
+	"SubtaskToggleInput":  json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"SubtaskToggleInput","type":"object","properties":{"taskId":{"type":"string","format":"uuid"},"subtaskId":{"type":"string","format":"uuid"}},"required":["taskId","subtaskId"]}`),
+	"SubtaskToggleOutput": json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"SubtaskToggleOutput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]}`),
+	"TaskCreateInput":     json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskCreateInput","type":"object","properties":{"title":{"type":"string","minLength":3,"maxLength":200},"description":{"type":"string","maxLength":2000},"priority":{"type":"string","enum":["low","medium","high","urgent"],"default":"medium"},"dueDate":{"type":"string","format":"date"},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25}},"required":["title"],"allOf":[{"if":{"properties":{"priority":{"const":"urgent"}},"required":["priority"]},"then":{"required":["dueDate"]}}]}`),
+	"TaskCreateOutput":    json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskCreateOutput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	"TaskDeleteInput":     json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskDeleteInput","type":"object","properties":{"id":{"type":"string","format":"uuid"}},"required":["id"]}`),
+	"TaskDeleteOutput":    json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskDeleteOutput","type":"object","properties":{"success":{"type":"boolean"}},"required":["success"]}`),
+	"TaskGetInput":        json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskGetInput","type":"object","properties":{"id":{"type":"string","format":"uuid"}},"required":["id"]}`),
+	"TaskGetOutput":       json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskGetOutput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	"TaskListInput":       json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskListInput","type":"object","properties":{"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"cursor":{"type":"string"},"pageSize":{"type":"integer","minimum":1,"maximum":100}}}`),
+	"TaskListOutput":      json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskListOutput","type":"object","properties":{"tasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"subtaskCount":{"type":"integer","minimum":0},"subtaskCompletedCount":{"type":"integer","minimum":0},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtaskCount","subtaskCompletedCount","position"]}},"total":{"type":"integer","minimum":0},"nextCursor":{"type":"string"}},"required":["tasks","total"]}`),
+	"TaskUpdateInput":     json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskUpdateInput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"anyOf":[{"type":"string","maxLength":2000},{"type":"null"}]},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"anyOf":[{"type":"string","format":"date"},{"type":"null"}]},"estimatedHours":{"anyOf":[{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},{"type":"null"}]}},"required":["id","description","dueDate","estimatedHours"]}`),
+	"TaskUpdateOutput":    json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskUpdateOutput","type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"description":{"type":"string","maxLength":2000},"status":{"type":"string","enum":["pending","in_progress","completed","cancelled"]},"priority":{"type":"string","enum":["low","medium","high","urgent"]},"dueDate":{"type":"string","format":"date"},"createdAt":{"type":"string","format":"date-time"},"completedAt":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}]},"assignee":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"name":{"type":"string","minLength":2,"maxLength":100},"email":{"type":"string","format":"email"}},"required":["id","name","email"]},"subtasks":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string","format":"uuid"},"title":{"type":"string","minLength":1,"maxLength":200},"completed":{"type":"boolean"}},"required":["id","title","completed"]},"maxItems":20},"estimatedHours":{"type":"number","maximum":100,"exclusiveMinimum":0,"multipleOf":0.25},"position":{"type":"integer","minimum":0}},"required":["id","title","status","priority","createdAt","completedAt","subtasks","position"]}`),
+	"TaskWatchInput":      json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskWatchInput","type":"object","properties":{"taskId":{"type":"string","format":"uuid"}}}`),
+	"TaskWatchOutput":     json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"TaskWatchOutput","type":"object","properties":{"type":{"type":"string","enum":["created","updated","deleted"]},"taskId":{"type":"string","format":"uuid"}},"required":["type","taskId"]}`),
+}
+
+// methodTypes names the input and output type of every method.
+var methodTypes = map[string][2]string{
+	"subtask.add":    {"SubtaskAddInput", "SubtaskAddOutput"},
+	"subtask.toggle": {"SubtaskToggleInput", "SubtaskToggleOutput"},
+	"task.create":    {"TaskCreateInput", "TaskCreateOutput"},
+	"task.delete":    {"TaskDeleteInput", "TaskDeleteOutput"},
+	"task.get":       {"TaskGetInput", "TaskGetOutput"},
+	"task.list":      {"TaskListInput", "TaskListOutput"},
+	"task.update":    {"TaskUpdateInput", "TaskUpdateOutput"},
+	"task.watch":     {"TaskWatchInput", "TaskWatchOutput"},
+}
+
+// SchemaFor returns the JSON Schema documents of method's input and output,
+// and false if the contract has no such method.
+func SchemaFor(method string) (input, output json.RawMessage, ok bool) {
+	types, ok := methodTypes[method]
+	if !ok {
+		return nil, nil, false
+	}
+	return typeSchemas[types[0]], typeSchemas[types[1]], true
+}
+
+// TypeSchema returns the JSON Schema document of the input or output type name,
+// such as "TaskCreateInput", and false if there is no such type.
+func TypeSchema(name string) (json.RawMessage, bool) {
+	schema, ok := typeSchemas[name]
+	return schema, ok
+}
@@ -0,0 +1,165 @@
+package xrpc
+
+import "context"
+
+// SubtaskService serves the subtask.* methods, each with the signature of
+// its handler. Register an implementation with RegisterSubtaskService.
+type SubtaskService interface {
+	// Add serves subtask.add.
+	Add(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error)
+	// Toggle serves subtask.toggle.
+	Toggle(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error)
+}
+
+// RegisterSubtaskService sets the methods of impl as the handlers of the
+// subtask.* methods of r.
+func RegisterSubtaskService(r *Router, impl SubtaskService) {
+	r.SubtaskAdd(impl.Add)
+	r.SubtaskToggle(impl.Toggle)
+}
+
+// SubtaskHandlerFuncs holds the handlers of the subtask.* methods as functions of
+// their dependencies deps, such as a struct of the database and clients they
+// use, instead of package-level variables.
+type SubtaskHandlerFuncs[D any] struct {
+	Add    func(ctx context.Context, deps D, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error)
+	Toggle func(ctx context.Context, deps D, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error)
+}
+
+// NewSubtaskHandlers returns the SubtaskService calling funcs with deps, for
+// RegisterSubtaskService; tests pass fake dependencies. It panics if a function
+// is missing.
+//
+//	type SubtaskDeps struct{ DB *DB }
+//
+//	handlers := NewSubtaskHandlers(SubtaskDeps{DB: db}, SubtaskHandlerFuncs[SubtaskDeps]{
+//	    Add: addSubtask,
+//	    ...
+//	})
+//	RegisterSubtaskService(router, handlers)
+func NewSubtaskHandlers[D any](deps D, funcs SubtaskHandlerFuncs[D]) SubtaskService {
+	if funcs.Add == nil {
+		panic("xrpc: NewSubtaskHandlers: no Add function for subtask.add")
+	}
+	if funcs.Toggle == nil {
+		panic("xrpc: NewSubtaskHandlers: no Toggle function for subtask.toggle")
+	}
+	return boundSubtaskHandlers[D]{deps: deps, funcs: funcs}
+}
+
+// boundSubtaskHandlers is the SubtaskService of NewSubtaskHandlers.
+type boundSubtaskHandlers[D any] struct {
+	deps  D
+	funcs SubtaskHandlerFuncs[D]
+}
+
+func (h boundSubtaskHandlers[D]) Add(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error) {
+	return h.funcs.Add(ctx, h.deps, info, input)
+}
+
+func (h boundSubtaskHandlers[D]) Toggle(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+	return h.funcs.Toggle(ctx, h.deps, info, input)
+}
+
+// TaskService serves the task.* methods, each with the signature of
+// its handler. Register an implementation with RegisterTaskService.
+type TaskService interface {
+	// Create serves task.create.
+	Create(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error)
+	// Delete serves task.delete.
+	Delete(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error)
+	// Get serves task.get.
+	Get(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error)
+	// List serves task.list.
+	List(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error)
+	// Update serves task.update.
+	Update(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error)
+	// Watch serves task.watch.
+	Watch(ctx context.Context, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error
+}
+
+// RegisterTaskService sets the methods of impl as the handlers of the
+// task.* methods of r.
+func RegisterTaskService(r *Router, impl TaskService) {
+	r.TaskCreate(impl.Create)
+	r.TaskDelete(impl.Delete)
+	r.TaskGet(impl.Get)
+	r.TaskList(impl.List)
+	r.TaskUpdate(impl.Update)
+	r.TaskWatch(impl.Watch)
+}
+
+// TaskHandlerFuncs holds the handlers of the task.* methods as functions of
+// their dependencies deps, such as a struct of the database and clients they
+// use, instead of package-level variables.
+type TaskHandlerFuncs[D any] struct {
+	Create func(ctx context.Context, deps D, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error)
+	Delete func(ctx context.Context, deps D, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error)
+	Get    func(ctx context.Context, deps D, info RequestInfo, input TaskGetInput) (TaskGetOutput, error)
+	List   func(ctx context.Context, deps D, info RequestInfo, input TaskListInput) (TaskListOutput, error)
+	Update func(ctx context.Context, deps D, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error)
+	Watch  func(ctx context.Context, deps D, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error
+}
+
+// NewTaskHandlers returns the TaskService calling funcs with deps, for
+// RegisterTaskService; tests pass fake dependencies. It panics if a function
+// is missing.
+//
+//	type TaskDeps struct{ DB *DB }
+//
+//	handlers := NewTaskHandlers(TaskDeps{DB: db}, TaskHandlerFuncs[TaskDeps]{
+//	    Create: createTask,
+//	    ...
+//	})
+//	RegisterTaskService(router, handlers)
+func NewTaskHandlers[D any](deps D, funcs TaskHandlerFuncs[D]) TaskService {
+	if funcs.Create == nil {
+		panic("xrpc: NewTaskHandlers: no Create function for task.create")
+	}
+	if funcs.Delete == nil {
+		panic("xrpc: NewTaskHandlers: no Delete function for task.delete")
+	}
+	if funcs.Get == nil {
+		panic("xrpc: NewTaskHandlers: no Get function for task.get")
+	}
+	if funcs.List == nil {
+		panic("xrpc: NewTaskHandlers: no List function for task.list")
+	}
+	if funcs.Update == nil {
+		panic("xrpc: NewTaskHandlers: no Update function for task.update")
+	}
+	if funcs.Watch == nil {
+		panic("xrpc: NewTaskHandlers: no Watch function for task.watch")
+	}
+	return boundTaskHandlers[D]{deps: deps, funcs: funcs}
+}
+
+// boundTaskHandlers is the TaskService of NewTaskHandlers.
+type boundTaskHandlers[D any] struct {
+	deps  D
+	funcs TaskHandlerFuncs[D]
+}
+
+func (h boundTaskHandlers[D]) Create(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error) {
+	return h.funcs.Create(ctx, h.deps, info, input)
+}
+
+func (h boundTaskHandlers[D]) Delete(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error) {
+	return h.funcs.Delete(ctx, h.deps, info, input)
+}
+
+func (h boundTaskHandlers[D]) Get(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error) {
+	return h.funcs.Get(ctx, h.deps, info, input)
+}
+
+func (h boundTaskHandlers[D]) List(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error) {
+	return h.funcs.List(ctx, h.deps, info, input)
+}
+
+func (h boundTaskHandlers[D]) Update(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error) {
+	return h.funcs.Update(ctx, h.deps, info, input)
+}
+
+func (h boundTaskHandlers[D]) Watch(ctx context.Context, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error {
+	return h.funcs.Watch(ctx, h.deps, info, input, send)
+}
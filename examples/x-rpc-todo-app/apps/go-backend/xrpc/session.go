@@ -0,0 +1,294 @@
+package xrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the values of sessions by ID for the Sessions middleware,
+// in memory with MemorySessionStore or in Redis with RedisSessionStore.
+type SessionStore interface {
+	// Load returns the values saved for the session id, false if there are none
+	// or they expired.
+	Load(ctx context.Context, id string) (map[string]json.RawMessage, bool, error)
+	// Save stores the values of the session id, to expire after ttl.
+	Save(ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration) error
+	// Delete removes the session id.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemorySessionStore keeps sessions in memory, for a single server or tests.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	// The number of sessions after the last sweep of expired ones
+	swept int
+}
+
+// memorySession is a session of a MemorySessionStore.
+type memorySession struct {
+	values  map[string]json.RawMessage
+	expires time.Time
+}
+
+// NewMemorySessionStore returns an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Load returns a copy of the values of the session id, dropping it if it expired.
+func (s *MemorySessionStore) Load(ctx context.Context, id string) (map[string]json.RawMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if !time.Now().Before(session.expires) {
+		delete(s.sessions, id)
+		return nil, false, nil
+	}
+	return copySessionValues(session.values), true, nil
+}
+
+// Save stores a copy of the values of the session id. Expired sessions are swept
+// whenever the number of sessions doubled since the last sweep.
+func (s *MemorySessionStore) Save(ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sessions[id] = memorySession{values: copySessionValues(values), expires: now.Add(ttl)}
+	if len(s.sessions) > 2*s.swept {
+		for id, session := range s.sessions {
+			if !now.Before(session.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.swept = len(s.sessions)
+	}
+	return nil
+}
+
+// Delete removes the session id.
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// copySessionValues copies values, so sessions of concurrent calls do not share
+// a map.
+func copySessionValues(values map[string]json.RawMessage) map[string]json.RawMessage {
+	copied := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
+
+// SessionOptions configures the Sessions middleware.
+type SessionOptions struct {
+	// CookieName is the name of the session cookie, "xrpc_session" by default.
+	CookieName string
+	// TTL is how long a session lasts after it was last saved, 24 hours by
+	// default.
+	TTL time.Duration
+	// Path and Domain scope the cookie; Path is "/" by default.
+	Path   string
+	Domain string
+	// SameSite restricts sending the cookie with cross-site requests, Lax by
+	// default.
+	SameSite http.SameSite
+	// Insecure sends the cookie over plain HTTP too. Otherwise it is Secure,
+	// which browsers also accept from http://localhost.
+	Insecure bool
+}
+
+// Session is the session of the caller of a call: the one its cookie names, or a
+// new, empty one. Changes are kept until Save. It is safe for concurrent use.
+type Session struct {
+	store   SessionStore
+	options SessionOptions
+	w       http.ResponseWriter
+	mu      sync.Mutex
+	id      string
+	values  map[string]json.RawMessage
+}
+
+// sessionKey is the context key of the session of a call.
+type sessionKey struct{}
+
+// ID returns the ID of the session, empty for a new session until it is saved.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get decodes the value stored under key into target, like json.Unmarshal, and
+// reports false if there is none.
+func (s *Session) Get(key string, target interface{}) (bool, error) {
+	s.mu.Lock()
+	value, ok := s.values[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(value, target)
+}
+
+// Set stores the JSON of value under key.
+func (s *Session) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = data
+	return nil
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Save stores the session, extending its expiry, and sets its cookie, choosing a
+// random ID for a new session. Handlers save before returning, as the cookie
+// cannot be set once a subscription or stream started writing.
+func (s *Session) Save(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(ctx)
+}
+
+// Renew moves the session to a new ID, keeping its values, and saves it. Call it
+// when the caller signs in, so an ID an attacker planted before does not become
+// a signed-in session (session fixation).
+func (s *Session) Renew(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id != "" {
+		if err := s.store.Delete(ctx, s.id); err != nil {
+			return err
+		}
+		s.id = ""
+	}
+	return s.save(ctx)
+}
+
+// Destroy deletes the session and its cookie, such as when the caller signs out.
+// The session is new and empty afterwards.
+func (s *Session) Destroy(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id != "" {
+		if err := s.store.Delete(ctx, s.id); err != nil {
+			return err
+		}
+	}
+	s.id = ""
+	s.values = map[string]json.RawMessage{}
+	s.setCookie("", -1)
+	return nil
+}
+
+// save is Save, with s.mu held.
+func (s *Session) save(ctx context.Context) error {
+	if s.id == "" {
+		id := make([]byte, 32)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		s.id = base64.RawURLEncoding.EncodeToString(id)
+	}
+	if err := s.store.Save(ctx, s.id, s.values, s.options.TTL); err != nil {
+		return err
+	}
+	s.setCookie(s.id, int(s.options.TTL/time.Second))
+	return nil
+}
+
+// setCookie sets the session cookie of the response, deleting it when maxAge is
+// negative. Calls without a response, such as Dispatch, have no cookie.
+func (s *Session) setCookie(value string, maxAge int) {
+	if s.w == nil {
+		return
+	}
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     s.options.CookieName,
+		Value:    value,
+		Path:     s.options.Path,
+		Domain:   s.options.Domain,
+		MaxAge:   maxAge,
+		Secure:   !s.options.Insecure,
+		HttpOnly: true,
+		SameSite: s.options.SameSite,
+	})
+}
+
+// Sessions returns middleware loading the session whose ID the cookie of a call
+// carries from store, or starting a new one, for handlers to get with
+// SessionFrom:
+//
+//	router.Use(Sessions(NewMemorySessionStore(), SessionOptions{}))
+//
+//	session, _ := SessionFrom(ctx)
+//	session.Set("cart", cart)
+//	err := session.Save(ctx)
+//
+// Cookies with IDs the store does not know start a new session with a new ID,
+// so clients cannot choose their session ID.
+func Sessions(store SessionStore, options SessionOptions) MiddlewareFunc {
+	if options.CookieName == "" {
+		options.CookieName = "xrpc_session"
+	}
+	if options.TTL <= 0 {
+		options.TTL = 24 * time.Hour
+	}
+	if options.Path == "" {
+		options.Path = "/"
+	}
+	if options.SameSite == 0 {
+		options.SameSite = http.SameSiteLaxMode
+	}
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		session := &Session{store: store, options: options, w: info.ResponseWriter, values: map[string]json.RawMessage{}}
+		if info.Request != nil {
+			if cookie, err := info.Request.Cookie(options.CookieName); err == nil && validSessionID(cookie.Value) {
+				values, ok, err := store.Load(ctx, cookie.Value)
+				if err != nil {
+					return NewMiddlewareError(err)
+				}
+				if ok && values != nil {
+					session.id = cookie.Value
+					session.values = values
+				}
+			}
+		}
+		return NewMiddlewareResult(context.WithValue(ctx, sessionKey{}, session))
+	}
+}
+
+// validSessionID reports whether id has the form of the IDs Save chooses, so
+// other cookie values are not looked up.
+func validSessionID(id string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil && len(decoded) == 32
+}
+
+// SessionFrom returns the session the Sessions middleware loaded for the call
+// ctx belongs to, if it runs.
+func SessionFrom(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok
+}
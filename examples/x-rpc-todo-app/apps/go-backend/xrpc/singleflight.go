@@ -0,0 +1,135 @@
+package xrpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// singleFlightAllowed reports whether SingleFlight shares calls of method: only
+// queries answered with a single result. Mutations change state and streamed
+// results are written by the call producing them, so their calls always run.
+func singleFlightAllowed(method string) bool {
+	m, ok := methodTable[method]
+	return ok && m.kind == "query" && m.serve == nil
+}
+
+// flightCall is a handler execution shared by identical calls.
+type flightCall struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+	// What fn panicked with, re-raised in one of the waiting calls
+	panicked interface{}
+	// The number of calls waiting for the result, and the cancellation of fn
+	// once none is left
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup shares one execution of a function among the calls with the same
+// key made while it runs. The zero value is ready to use.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do returns the result of fn, or waits for the call with key already running
+// and returns its result instead. fn runs with the values of the ctx of the
+// call starting it but not its cancellation, so the others are not failed by
+// it leaving early; each call stops waiting when its own ctx is done, and fn
+// is cancelled once no call is left waiting for it.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if !ok {
+		if g.calls == nil {
+			g.calls = map[string]*flightCall{}
+		}
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		// Waiting calls get INTERNAL if fn panics before returning
+		call = &flightCall{done: make(chan struct{}), err: NewError(CodeInternal, "Internal server error"), cancel: cancel}
+		g.calls[key] = call
+		go g.run(flightCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		g.mu.Lock()
+		rec := call.panicked
+		call.panicked = nil
+		g.mu.Unlock()
+		if rec != nil {
+			panic(rec)
+		}
+		return call.result, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody waits for the result any more; later calls start afresh
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// run calls fn for call, recovering a panic to re-raise it in a waiting call
+// rather than crash the server from this goroutine.
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		call.panicked = recover()
+		call.cancel()
+		g.mu.Lock()
+		if g.calls[key] == call {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.result, call.err = fn(ctx)
+}
+
+// SingleFlight returns an interceptor sharing one handler execution among
+// identical queries: calls of a method with the same params by the same user
+// (UserIDFrom) of the same tenant (TenantFrom) arriving while one is running wait
+// for it and get its result, so bursts of the same query, such as from dashboards
+// fanning it out, run the handler once. Results are not kept past the call, and a
+// waiting call whose context is done stops waiting. Register it with Intercept, or
+// InterceptFor to limit it to some queries, e.g. InterceptFor("task.list",
+// SingleFlight()). Callers share the result value, so interceptors and handlers
+// must not modify it afterwards.
+func SingleFlight() InterceptorFunc {
+	var group flightGroup
+	return func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error) {
+		if !singleFlightAllowed(info.Method) {
+			return next(ctx)
+		}
+		key, err := flightKey(ctx, info.Method, input)
+		if err != nil {
+			return next(ctx)
+		}
+		return group.do(ctx, key, next)
+	}
+}
+
+// flightKey identifies a call by its method, the tenant and user making it and a
+// hash of its params.
+func flightKey(ctx context.Context, method string, input interface{}) (string, error) {
+	params, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	tenant, _ := TenantFrom(ctx)
+	userID, _ := UserIDFrom(ctx)
+	sum := sha256.Sum256(params)
+	return method + "\x00" + tenant.ID + "\x00" + userID + "\x00" + hex.EncodeToString(sum[:]), nil
+}
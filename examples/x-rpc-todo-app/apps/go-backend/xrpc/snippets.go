@@ -0,0 +1,89 @@
+package xrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Snippet holds commands calling a method of a server of the contract, to paste
+// into a shell.
+type Snippet struct {
+	Method string
+	Curl   string
+	HTTPie string
+}
+
+// snippetInputs holds the example input of every method whose input has one,
+// as JSON, sorted by method name.
+var snippetInputs = [][2]string{
+	{"subtask.add", `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example"}`},
+	{"subtask.toggle", `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6","subtaskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`},
+	{"task.create", `{"title":"example","description":"example","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`},
+	{"task.delete", `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`},
+	{"task.get", `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`},
+	{"task.list", `{"status":"pending","priority":"low","cursor":"example","pageSize":1}`},
+	{"task.update", `{"id":"3fa85f64-5717-4562-b3fc-2c963f66afa6","title":"example","description":"example","status":"pending","priority":"low","dueDate":"2099-01-05","estimatedHours":1}`},
+	{"task.watch", `{"taskId":"3fa85f64-5717-4562-b3fc-2c963f66afa6"}`},
+}
+
+// Snippets returns the snippets calling every method with an example input
+// that passes validation, derived from its schema. url is where the router is
+// served, such as "https://api.example.com/api", and header is sent with every
+// call, e.g. to authenticate. Methods whose input has no example are left out.
+func Snippets(url string, header http.Header) []Snippet {
+	snippets := make([]Snippet, 0, len(snippetInputs))
+	for _, input := range snippetInputs {
+		snippets = append(snippets, NewSnippet(url, input[0], json.RawMessage(input[1]), header))
+	}
+	return snippets
+}
+
+// NewSnippet returns the snippet calling method with params at url, sending
+// header. Subscriptions are called with output buffering off, so events show
+// as they arrive.
+func NewSnippet(url, method string, params json.RawMessage, header http.Header) Snippet {
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	body, _ := json.Marshal(struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{method, params})
+	m, ok := methodTable[method]
+	stream := ok && m.kind == "subscription"
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var curl strings.Builder
+	curl.WriteString("curl")
+	if stream {
+		curl.WriteString(" -N")
+	}
+	curl.WriteString(" " + shellQuote(url) + " \\\n  -H 'Content-Type: application/json'")
+	var httpie strings.Builder
+	httpie.WriteString("http")
+	if stream {
+		httpie.WriteString(" --stream")
+	}
+	httpie.WriteString(" POST " + shellQuote(url))
+	for _, key := range keys {
+		for _, value := range header[key] {
+			curl.WriteString(" \\\n  -H " + shellQuote(key+": "+value))
+			httpie.WriteString(" " + shellQuote(key+":"+value))
+		}
+	}
+	curl.WriteString(" \\\n  -d " + shellQuote(string(body)))
+	httpie.WriteString(" method=" + shellQuote(method) + " params:=" + shellQuote(string(params)))
+	return Snippet{Method: method, Curl: curl.String(), HTTPie: httpie.String()}
+}
+
+// shellQuote quotes s for a POSIX shell, in single quotes that keep everything
+// but a single quote, which is closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,187 @@
+package xrpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tenant is the tenant, such as a customer organization, whose data a call works
+// on.
+type Tenant struct {
+	// ID identifies the tenant.
+	ID string
+}
+
+// tenantKey is the context key of the tenant of a call.
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying tenant.
+func WithTenant(ctx context.Context, tenant Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant stored in ctx by WithTenant, if any.
+func TenantFrom(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(Tenant)
+	return tenant, ok
+}
+
+// TenantResolver returns the ID of the tenant a request names, or "" if it names
+// none.
+type TenantResolver func(ctx context.Context, info RequestInfo) string
+
+// TenantHeader resolves the tenant from the request header name, such as
+// X-Tenant-ID.
+func TenantHeader(name string) TenantResolver {
+	return func(ctx context.Context, info RequestInfo) string {
+		if info.Request == nil {
+			return ""
+		}
+		return strings.TrimSpace(info.Request.Header.Get(name))
+	}
+}
+
+// TenantSubdomain resolves the tenant from the subdomain of domain the request
+// was sent to: "acme" for acme.example.com with domain "example.com". Hosts
+// outside domain or with several labels before it name no tenant.
+func TenantSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(domain)
+	return func(ctx context.Context, info RequestInfo) string {
+		if info.Request == nil {
+			return ""
+		}
+		host := info.Request.Host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+		}
+		host, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || host == "" || strings.Contains(host, ".") {
+			return ""
+		}
+		return host
+	}
+}
+
+// TenantClaim resolves the tenant from the string claim of the Principal an
+// authentication middleware such as JWTAuth accepted, so it must come after
+// that middleware.
+func TenantClaim(claim string) TenantResolver {
+	return func(ctx context.Context, info RequestInfo) string {
+		principal, _ := PrincipalFrom(ctx)
+		tenant, _ := principal.Claims[claim].(string)
+		return tenant
+	}
+}
+
+// ResolveTenant returns middleware setting the tenant of each call, for handlers
+// to get with TenantFrom, to the first tenant resolvers find. Headers and
+// subdomains are chosen by the client, so verify checks that the caller belongs
+// to the tenant, such as against its principal, and that the tenant exists; a
+// *Error it returns is sent as is, other errors as PERMISSION_DENIED. Calls
+// naming no tenant continue without one, which methods declared with
+// tenant: "required" reject:
+//
+//	router.Use(JWTAuth(keys, nil))
+//	router.Use(ResolveTenant(verifyMember,
+//	    TenantClaim("tenant"), TenantHeader("X-Tenant-ID")))
+func ResolveTenant(verify func(ctx context.Context, tenant Tenant) error, resolvers ...TenantResolver) MiddlewareFunc {
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		for _, resolve := range resolvers {
+			tenant := Tenant{ID: resolve(ctx, info)}
+			if tenant.ID == "" {
+				continue
+			}
+			if verify != nil {
+				if err := verify(ctx, tenant); err != nil {
+					return NewMiddlewareError(tenantError(err))
+				}
+			}
+			return NewMiddlewareResult(WithTenant(ctx, tenant))
+		}
+		return NewMiddlewareResult(ctx)
+	}
+}
+
+// tenantError is the error sent for a tenant verify rejected: err if it is an
+// *Error, PERMISSION_DENIED otherwise.
+func tenantError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return NewError(CodePermissionDenied, "Tenant not allowed")
+}
+
+// TenantLimit is the rate the calls of a tenant are limited to: PerSecond calls
+// a second on average, in bursts of up to Burst calls. A zero PerSecond leaves
+// the tenant unlimited.
+type TenantLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// tenantBucket holds the tokens of a tenant, refilled at its rate, and when it
+// is full again.
+type tenantBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// TenantRateLimit returns middleware limiting the calls of each tenant to the
+// TenantLimit that limit returns for it, such as by its plan, with a token
+// bucket per tenant. Calls over it fail with RESOURCE_EXHAUSTED and a
+// Retry-After for when the next one is allowed. It must come after
+// ResolveTenant; calls without a tenant are not limited:
+//
+//	router.Use(TenantRateLimit(func(tenant Tenant) TenantLimit {
+//	    return TenantLimit{PerSecond: 50, Burst: 100}
+//	}))
+func TenantRateLimit(limit func(tenant Tenant) TenantLimit) MiddlewareFunc {
+	var mu sync.Mutex
+	buckets := map[string]*tenantBucket{}
+	// The number of buckets after the last sweep of full ones, which are
+	// the same as new ones
+	swept := 0
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		tenant, ok := TenantFrom(ctx)
+		if !ok {
+			return NewMiddlewareResult(ctx)
+		}
+		rate := limit(tenant)
+		if rate.PerSecond <= 0 {
+			return NewMiddlewareResult(ctx)
+		}
+		burst := math.Max(float64(rate.Burst), 1)
+		now := time.Now()
+		mu.Lock()
+		defer mu.Unlock()
+		bucket, ok := buckets[tenant.ID]
+		if !ok {
+			bucket = &tenantBucket{tokens: burst, updated: now}
+			buckets[tenant.ID] = bucket
+		}
+		bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate.PerSecond)
+		bucket.updated = now
+		if bucket.tokens < 1 {
+			wait := time.Duration((1 - bucket.tokens) / rate.PerSecond * float64(time.Second))
+			return NewMiddlewareError(NewError(CodeResourceExhausted, "Tenant rate limit exceeded").WithDetails(RetryInfo{RetryAfterMs: wait.Milliseconds() + 1}))
+		}
+		bucket.tokens--
+		bucket.full = now.Add(time.Duration((burst - bucket.tokens) / rate.PerSecond * float64(time.Second)))
+		if len(buckets) > 2*swept {
+			for id, bucket := range buckets {
+				if !now.Before(bucket.full) {
+					delete(buckets, id)
+				}
+			}
+			swept = len(buckets)
+		}
+		return NewMiddlewareResult(ctx)
+	}
+}
@@ -0,0 +1,207 @@
+package xrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// TestClient calls a Router in process through httptest, with a typed method per
+// endpoint. Errors the router answers with are returned as *Error, so tests can
+// check their code.
+type TestClient struct {
+	router *Router
+	// Header is sent with every call, e.g. to authenticate.
+	Header http.Header
+}
+
+// NewTestClient returns a TestClient calling router.
+func NewTestClient(router *Router) *TestClient {
+	return &TestClient{router: router, Header: http.Header{}}
+}
+
+// SubtaskAdd calls subtask.add.
+func (c *TestClient) SubtaskAdd(ctx context.Context, input SubtaskAddInput) (SubtaskAddOutput, error) {
+	var output SubtaskAddOutput
+	err := c.call(ctx, "subtask.add", input, &output)
+	return output, err
+}
+
+// SubtaskToggle calls subtask.toggle.
+func (c *TestClient) SubtaskToggle(ctx context.Context, input SubtaskToggleInput) (SubtaskToggleOutput, error) {
+	var output SubtaskToggleOutput
+	err := c.call(ctx, "subtask.toggle", input, &output)
+	return output, err
+}
+
+// TaskCreate calls task.create.
+func (c *TestClient) TaskCreate(ctx context.Context, input TaskCreateInput) (TaskCreateOutput, error) {
+	var output TaskCreateOutput
+	err := c.call(ctx, "task.create", input, &output)
+	return output, err
+}
+
+// TaskDelete calls task.delete.
+func (c *TestClient) TaskDelete(ctx context.Context, input TaskDeleteInput) (TaskDeleteOutput, error) {
+	var output TaskDeleteOutput
+	err := c.call(ctx, "task.delete", input, &output)
+	return output, err
+}
+
+// TaskGet calls task.get.
+func (c *TestClient) TaskGet(ctx context.Context, input TaskGetInput) (TaskGetOutput, error) {
+	var output TaskGetOutput
+	err := c.call(ctx, "task.get", input, &output)
+	return output, err
+}
+
+// TaskList calls task.list.
+func (c *TestClient) TaskList(ctx context.Context, input TaskListInput) (TaskListOutput, error) {
+	var output TaskListOutput
+	err := c.call(ctx, "task.list", input, &output)
+	return output, err
+}
+
+// TaskUpdate calls task.update.
+func (c *TestClient) TaskUpdate(ctx context.Context, input TaskUpdateInput) (TaskUpdateOutput, error) {
+	var output TaskUpdateOutput
+	err := c.call(ctx, "task.update", input, &output)
+	return output, err
+}
+
+// TaskWatch calls task.watch and returns the events the handler sent before
+// it returned; cancel ctx to end subscriptions that stream until cancelled.
+func (c *TestClient) TaskWatch(ctx context.Context, input TaskWatchInput) ([]TaskWatchOutput, error) {
+	var events []TaskWatchOutput
+	err := c.subscribe(ctx, "task.watch", input, func(data []byte) error {
+		var event TaskWatchOutput
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+// do POSTs a call of method with input to the router and returns the response.
+func (c *TestClient) do(ctx context.Context, method string, input interface{}) (*httptest.ResponseRecorder, error) {
+	body, err := json.Marshal(struct {
+		Method string      `json:"method"`
+		Params interface{} `json:"params"`
+	}{method, input})
+	if err != nil {
+		return nil, err
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, req)
+	return rec, nil
+}
+
+// call calls method with input and decodes its result into output.
+func (c *TestClient) call(ctx context.Context, method string, input, output interface{}) error {
+	rec, err := c.do(ctx, method, input)
+	if err != nil {
+		return err
+	}
+	if rec.Code != http.StatusOK {
+		return responseError(method, rec.Body.Bytes())
+	}
+	var response struct {
+		Result json.RawMessage  `json:"result"`
+		Error  *json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		return fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	// Errors kept in-band with SetErrorStatus come with 200
+	if response.Error != nil {
+		return responseError(method, rec.Body.Bytes())
+	}
+	return json.Unmarshal(response.Result, output)
+}
+
+// subscribe calls method with input and passes the data of every event the
+// handler sent to onEvent, stopping at an error event.
+func (c *TestClient) subscribe(ctx context.Context, method string, input interface{}, onEvent func(data []byte) error) error {
+	rec, err := c.do(ctx, method, input)
+	if err != nil {
+		return err
+	}
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream") {
+		return responseError(method, rec.Body.Bytes())
+	}
+	var event string
+	scanner := bufio.NewScanner(rec.Body)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			if event == "error" {
+				return responseError(method, data)
+			}
+			if err := onEvent(data); err != nil {
+				return err
+			}
+			event = ""
+		}
+	}
+	return scanner.Err()
+}
+
+// responseError returns the *Error of an error envelope. The details of a
+// CodeInvalidArgument listing field errors are decoded into ValidationErrors,
+// which errors.As finds through the *Error.
+func responseError(method string, body []byte) error {
+	var response struct {
+		Error *struct {
+			Code    ErrorCode       `json:"code"`
+			Message string          `json:"message"`
+			Details json.RawMessage `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error == nil {
+		return fmt.Errorf("unexpected %s response: %s", method, body)
+	}
+	callErr := &Error{Code: response.Error.Code, Message: response.Error.Message}
+	if len(response.Error.Details) == 0 {
+		return callErr
+	}
+	if callErr.Code == CodeInvalidArgument {
+		var validationErrs ValidationErrors
+		if json.Unmarshal(response.Error.Details, &validationErrs) == nil && fieldErrors(validationErrs) {
+			callErr.Details = validationErrs
+			return callErr
+		}
+	}
+	json.Unmarshal(response.Error.Details, &callErr.Details)
+	return callErr
+}
+
+// fieldErrors reports whether errs decoded from details are field errors,
+// rather than details of another shape.
+func fieldErrors(errs ValidationErrors) bool {
+	if len(errs) == 0 {
+		return false
+	}
+	for _, err := range errs {
+		if err == nil || err.Field == "" || err.Message == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package xrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSOptions configures ListenAndServeTLS. Setting ClientCAFile or ClientCAs
+// turns on mutual TLS: clients must present a certificate signed by one of the
+// CAs, unless ClientCertOptional is set.
+type TLSOptions struct {
+	// CertFile and KeyFile hold the server's PEM certificate chain and key.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the PEM certificates of the CAs client certificates
+	// are verified against.
+	ClientCAFile string
+	// ClientCAs is used instead of ClientCAFile when set.
+	ClientCAs *x509.CertPool
+	// ClientCertOptional accepts connections without a client certificate,
+	// still verifying those that present one; ClientCertAuth leaves their
+	// calls unauthenticated.
+	ClientCertOptional bool
+	// MinVersion is the lowest TLS version accepted, TLS 1.2 if zero.
+	MinVersion uint16
+}
+
+// TLSConfig returns the tls.Config of options, for servers configured by the
+// application rather than by ListenAndServeTLS.
+func (options TLSOptions) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.MinVersion != 0 {
+		config.MinVersion = options.MinVersion
+	}
+	pool := options.ClientCAs
+	if pool == nil && options.ClientCAFile != "" {
+		data, err := os.ReadFile(options.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CAs: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates in %s", options.ClientCAFile)
+		}
+	}
+	if pool == nil {
+		return config, nil
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if options.ClientCertOptional {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// ListenAndServeTLS serves handler, such as a Router, over HTTPS on addr with
+// the TLS configuration of options, like http.ListenAndServeTLS. Handlers
+// read the identity of a verified client certificate with ClientIdentityFrom,
+// or as the Principal of ClientCertAuth:
+//
+//	router.Use(ClientCertAuth(nil))
+//	log.Fatal(ListenAndServeTLS(":8443", router, TLSOptions{
+//	    CertFile:     "server.pem",
+//	    KeyFile:      "server-key.pem",
+//	    ClientCAFile: "services-ca.pem",
+//	}))
+func ListenAndServeTLS(addr string, handler http.Handler, options TLSOptions) error {
+	config, err := options.TLSConfig()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: addr, Handler: handler, TLSConfig: config}
+	return server.ListenAndServeTLS(options.CertFile, options.KeyFile)
+}
+
+// ClientIdentity is the identity of the client certificate verified for a
+// mutual TLS connection.
+type ClientIdentity struct {
+	// CommonName and Organizations are from the certificate's subject.
+	CommonName    string
+	Organizations []string
+	// DNSNames, URIs and EmailAddresses are its subject alternative names; URIs
+	// hold SPIFFE IDs such as spiffe://example.org/ns/prod/sa/billing.
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+	// Certificate is the verified certificate.
+	Certificate *x509.Certificate
+}
+
+// ID identifies the client: its first URI, such as a SPIFFE ID, or else its
+// common name.
+func (c ClientIdentity) ID() string {
+	if len(c.URIs) > 0 {
+		return c.URIs[0]
+	}
+	return c.CommonName
+}
+
+// ClientIdentityFrom returns the identity of the client certificate verified for
+// the connection of the call ctx belongs to, if the client presented one.
+func ClientIdentityFrom(ctx context.Context) (ClientIdentity, bool) {
+	info, ok := RequestInfoFrom(ctx)
+	if !ok {
+		return ClientIdentity{}, false
+	}
+	return clientIdentity(info.Request)
+}
+
+// clientIdentity returns the identity of the leaf of the first verified chain
+// of req; certificates the server did not verify carry no identity.
+func clientIdentity(req *http.Request) (ClientIdentity, bool) {
+	if req == nil || req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return ClientIdentity{}, false
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	identity := ClientIdentity{
+		CommonName:     cert.Subject.CommonName,
+		Organizations:  cert.Subject.Organization,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		Certificate:    cert,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity, true
+}
+
+// ClientCertAuth returns middleware authenticating requests with the client
+// certificate verified by mutual TLS. identify maps its identity to the
+// principal and may reject it, e.g. for a service not allowed to call this one;
+// if it is nil, the principal's ID is the identity's ID. Requests without a
+// verified certificate continue unauthenticated.
+func ClientCertAuth(identify func(ctx context.Context, identity ClientIdentity) (Principal, error)) MiddlewareFunc {
+	return func(ctx context.Context, info RequestInfo) *MiddlewareResult {
+		identity, ok := clientIdentity(info.Request)
+		if !ok {
+			return NewMiddlewareResult(ctx)
+		}
+		principal := Principal{ID: identity.ID()}
+		if identify != nil {
+			var err error
+			principal, err = identify(ctx, identity)
+			if err != nil {
+				return NewMiddlewareError(authError(err))
+			}
+		}
+		if principal.Scheme == "" {
+			principal.Scheme = "mtls"
+		}
+		return NewMiddlewareResult(WithPrincipal(ctx, principal))
+	}
+}
@@ -0,0 +1,82 @@
+package xrpc
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TransportOptions configures the HTTP transport of a Client, set with
+// WithTransport. Zero fields keep the defaults, those of http.DefaultTransport
+// but for MaxIdleConnsPerHost: 100 rather than 2, as a Client makes all its
+// calls to the one server.
+type TransportOptions struct {
+	// MaxIdleConns bounds the idle keep-alive connections across hosts; 100
+	// by default
+	MaxIdleConns int
+	// MaxIdleConnsPerHost bounds the idle keep-alive connections to the
+	// server; 100 by default
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost bounds the connections to the server, in use or idle;
+	// calls beyond it wait. 0 leaves it unbounded
+	MaxConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer; 90s by default
+	IdleConnTimeout time.Duration
+	// TLSConfig configures TLS, e.g. with RootCAs, or Certificates for mTLS
+	TLSConfig *tls.Config
+	// Proxy returns the proxy of a request; http.ProxyFromEnvironment by
+	// default
+	Proxy func(req *http.Request) (*url.URL, error)
+	// DisableHTTP2 keeps to HTTP/1.1. Otherwise HTTP/2 is negotiated with
+	// servers supporting it over TLS, a custom TLSConfig included
+	DisableHTTP2 bool
+}
+
+// WithTransport sends the Client's requests through a transport configured by
+// options.
+func WithTransport(options TransportOptions) ClientOption {
+	return func(c *Client) {
+		c.httpClient = &http.Client{Transport: newTransport(options)}
+	}
+}
+
+// newTransport returns a transport configured by options.
+func newTransport(options TransportOptions) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		ForceAttemptHTTP2:     !options.DisableHTTP2,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
+		MaxConnsPerHost:       options.MaxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSClientConfig:       options.TLSConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if options.MaxIdleConns > 0 {
+		transport.MaxIdleConns = options.MaxIdleConns
+	}
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	}
+	if options.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout
+	}
+	if options.Proxy != nil {
+		transport.Proxy = options.Proxy
+	}
+	if options.DisableHTTP2 {
+		// A non-nil empty map turns HTTP/2 off
+		transport.TLSNextProto = make(map[string]func(authority string, conn *tls.Conn) http.RoundTripper)
+		if options.TLSConfig != nil {
+			// Nor may the TLS handshake offer it, as a TLSConfig shared with a
+			// server may
+			transport.TLSClientConfig = options.TLSConfig.Clone()
+			transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		}
+	}
+	return transport
+}
@@ -1,228 +1,182 @@
 package xrpc
 
-import "net/http"
-
-type Context struct {
-    Request        *http.Request
-    ResponseWriter http.ResponseWriter
-    Data           map[string]interface{}
-}
-
+import (
+	"context"
+	"net/http"
+	"time"
+)
 
 // MiddlewareFunc is a function that processes a request and extends context
-
-type MiddlewareFunc func(ctx *Context) *MiddlewareResult
-
+type MiddlewareFunc func(ctx context.Context, info RequestInfo) *MiddlewareResult
 
 type MiddlewareResult struct {
-    Context  *Context
-    Error    error
-    Response *http.Response
+	Context  context.Context
+	Error    error
+	Response *http.Response
 }
 
-
 // NewMiddlewareResult creates a successful middleware result
-func NewMiddlewareResult(ctx *Context) *MiddlewareResult {
-    return &MiddlewareResult{Context: ctx}
+func NewMiddlewareResult(ctx context.Context) *MiddlewareResult {
+	return &MiddlewareResult{Context: ctx}
 }
 
-
 // NewMiddlewareError creates a middleware result with an error
 func NewMiddlewareError(err error) *MiddlewareResult {
-    return &MiddlewareResult{Error: err}
+	return &MiddlewareResult{Error: err}
 }
 
-
 // NewMiddlewareResponse creates a middleware result that short-circuits with a response
 func NewMiddlewareResponse(resp *http.Response) *MiddlewareResult {
-    return &MiddlewareResult{Response: resp}
+	return &MiddlewareResult{Response: resp}
 }
 
+// NextFunc runs the rest of an interceptor chain and finally the handler
+type NextFunc func(ctx context.Context) (interface{}, error)
 
-type TaskListInput struct {
-    Status string `json:"status,omitempty"`
-    Priority string `json:"priority,omitempty"`
-    Limit float64 `json:"limit,omitempty"`
-}
+// InterceptorFunc wraps a query or mutation call. It receives the validated input
+// and may change the context passed to next, replace the result, translate
+// errors, time the call or recover from panics.
+type InterceptorFunc func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error)
 
-type TaskListOutput struct {
-    Tasks []TaskListOutputTasksItem `json:"tasks"`
-    Total float64 `json:"total"`
+type Assignee struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
 }
 
-type TaskGetInput struct {
-    Id string `json:"id"`
+type Subtask struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
 }
 
-type TaskGetOutput struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Description string `json:"description,omitempty"`
-    Status string `json:"status"`
-    Priority string `json:"priority"`
-    DueDate string `json:"dueDate,omitempty"`
-    CreatedAt string `json:"createdAt"`
-    CompletedAt *string `json:"completedAt"`
-    Assignee TaskGetOutputAssignee `json:"assignee,omitempty"`
-    Subtasks []TaskGetOutputSubtasksItem `json:"subtasks"`
-    EstimatedHours float64 `json:"estimatedHours,omitempty"`
-    Position float64 `json:"position"`
+type SubtaskAddInput struct {
+	TaskId string `json:"taskId"`
+	Title  string `json:"title"`
 }
 
-type TaskCreateInput struct {
-    Title string `json:"title"`
-    Description string `json:"description,omitempty"`
-    Priority string `json:"priority"`
-    DueDate string `json:"dueDate,omitempty"`
-    EstimatedHours float64 `json:"estimatedHours,omitempty"`
-}
-
-type TaskCreateOutput struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Description string `json:"description,omitempty"`
-    Status string `json:"status"`
-    Priority string `json:"priority"`
-    DueDate string `json:"dueDate,omitempty"`
-    CreatedAt string `json:"createdAt"`
-    CompletedAt *string `json:"completedAt"`
-    Assignee TaskCreateOutputAssignee `json:"assignee,omitempty"`
-    Subtasks []TaskCreateOutputSubtasksItem `json:"subtasks"`
-    EstimatedHours float64 `json:"estimatedHours,omitempty"`
-    Position float64 `json:"position"`
-}
+// SubtaskAddOutput has the same shape as Subtask.
+type SubtaskAddOutput = Subtask
 
-type TaskUpdateInput struct {
-    Id string `json:"id"`
-    Title string `json:"title,omitempty"`
-    Description *string `json:"description"`
-    Status string `json:"status,omitempty"`
-    Priority string `json:"priority,omitempty"`
-    DueDate *string `json:"dueDate"`
-    EstimatedHours *float64 `json:"estimatedHours"`
-}
-
-type TaskUpdateOutput struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Description string `json:"description,omitempty"`
-    Status string `json:"status"`
-    Priority string `json:"priority"`
-    DueDate string `json:"dueDate,omitempty"`
-    CreatedAt string `json:"createdAt"`
-    CompletedAt *string `json:"completedAt"`
-    Assignee TaskUpdateOutputAssignee `json:"assignee,omitempty"`
-    Subtasks []TaskUpdateOutputSubtasksItem `json:"subtasks"`
-    EstimatedHours float64 `json:"estimatedHours,omitempty"`
-    Position float64 `json:"position"`
+type SubtaskToggleInput struct {
+	TaskId    string `json:"taskId"`
+	SubtaskId string `json:"subtaskId"`
 }
 
-type TaskDeleteInput struct {
-    Id string `json:"id"`
-}
+// SubtaskToggleOutput has the same shape as Subtask.
+type SubtaskToggleOutput = Subtask
 
-type TaskDeleteOutput struct {
-    Success bool `json:"success"`
+type Task struct {
+	Id             string     `json:"id"`
+	Title          string     `json:"title"`
+	Description    *string    `json:"description,omitempty"`
+	Status         TaskStatus `json:"status"`
+	Priority       Priority   `json:"priority"`
+	DueDate        *Date      `json:"dueDate,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	CompletedAt    *time.Time `json:"completedAt"`
+	Assignee       *Assignee  `json:"assignee,omitempty"`
+	Subtasks       []Subtask  `json:"subtasks"`
+	EstimatedHours *float64   `json:"estimatedHours,omitempty"`
+	Position       int        `json:"position"`
 }
 
-type SubtaskAddInput struct {
-    TaskId string `json:"taskId"`
-    Title string `json:"title"`
+type TaskCreateInput struct {
+	Title          string    `json:"title"`
+	Description    *string   `json:"description,omitempty"`
+	Priority       *Priority `json:"priority,omitempty"`
+	DueDate        *Date     `json:"dueDate,omitempty"`
+	EstimatedHours *float64  `json:"estimatedHours,omitempty"`
 }
 
-type SubtaskAddOutput struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Completed bool `json:"completed"`
-}
+// TaskCreateOutput has the same shape as Task.
+type TaskCreateOutput = Task
 
-type SubtaskToggleInput struct {
-    TaskId string `json:"taskId"`
-    SubtaskId string `json:"subtaskId"`
+type TaskDeleteInput struct {
+	Id string `json:"id"`
 }
 
-type SubtaskToggleOutput struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Completed bool `json:"completed"`
+type TaskDeleteOutput struct {
+	Success bool `json:"success"`
 }
 
-type TaskListOutputTasksItem struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Status string `json:"status"`
-    Priority string `json:"priority"`
-    DueDate string `json:"dueDate,omitempty"`
-    CreatedAt string `json:"createdAt"`
-    CompletedAt *string `json:"completedAt"`
-    SubtaskCount float64 `json:"subtaskCount"`
-    SubtaskCompletedCount float64 `json:"subtaskCompletedCount"`
-    EstimatedHours float64 `json:"estimatedHours,omitempty"`
-    Position float64 `json:"position"`
-}
+// TaskGetInput has the same shape as TaskDeleteInput.
+type TaskGetInput = TaskDeleteInput
+
+// TaskGetOutput has the same shape as Task.
+type TaskGetOutput = Task
 
-type TaskGetOutputAssignee struct {
-    Id string `json:"id"`
-    Name string `json:"name"`
-    Email string `json:"email"`
+type TaskListInput struct {
+	Status   *TaskStatus `json:"status,omitempty"`
+	Priority *Priority   `json:"priority,omitempty"`
+	Cursor   *string     `json:"cursor,omitempty"`
+	PageSize *int        `json:"pageSize,omitempty"`
 }
 
-type TaskGetOutputSubtasksItem struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Completed bool `json:"completed"`
+type TaskListOutput struct {
+	Tasks      []TaskSummary `json:"tasks"`
+	Total      int           `json:"total"`
+	NextCursor *string       `json:"nextCursor,omitempty"`
 }
 
-type TaskCreateOutputAssignee struct {
-    Id string `json:"id"`
-    Name string `json:"name"`
-    Email string `json:"email"`
+type TaskSummary struct {
+	Id                    string     `json:"id"`
+	Title                 string     `json:"title"`
+	Status                TaskStatus `json:"status"`
+	Priority              Priority   `json:"priority"`
+	DueDate               *Date      `json:"dueDate,omitempty"`
+	CreatedAt             time.Time  `json:"createdAt"`
+	CompletedAt           *time.Time `json:"completedAt"`
+	SubtaskCount          int        `json:"subtaskCount"`
+	SubtaskCompletedCount int        `json:"subtaskCompletedCount"`
+	EstimatedHours        *float64   `json:"estimatedHours,omitempty"`
+	Position              int        `json:"position"`
 }
 
-type TaskCreateOutputSubtasksItem struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Completed bool `json:"completed"`
+type TaskUpdateInput struct {
+	Id             string      `json:"id"`
+	Title          *string     `json:"title,omitempty"`
+	Description    *string     `json:"description"`
+	Status         *TaskStatus `json:"status,omitempty"`
+	Priority       *Priority   `json:"priority,omitempty"`
+	DueDate        *Date       `json:"dueDate"`
+	EstimatedHours *float64    `json:"estimatedHours"`
 }
 
-type TaskUpdateOutputAssignee struct {
-    Id string `json:"id"`
-    Name string `json:"name"`
-    Email string `json:"email"`
+// TaskUpdateOutput has the same shape as Task.
+type TaskUpdateOutput = Task
+
+type TaskWatchInput struct {
+	TaskId *string `json:"taskId,omitempty"`
 }
 
-type TaskUpdateOutputSubtasksItem struct {
-    Id string `json:"id"`
-    Title string `json:"title"`
-    Completed bool `json:"completed"`
+type TaskWatchOutput struct {
+	Type   TaskWatchOutputType `json:"type"`
+	TaskId string              `json:"taskId"`
 }
 
 // Typed handler types for each endpoint
 
-// Handler type for task.list
-type TaskListHandler func(ctx *Context, input TaskListInput) (TaskListOutput, error)
-
-
-// Handler type for task.get
-type TaskGetHandler func(ctx *Context, input TaskGetInput) (TaskGetOutput, error)
+// Handler type for subtask.add
+type SubtaskAddHandler func(ctx context.Context, info RequestInfo, input SubtaskAddInput) (SubtaskAddOutput, error)
 
+// Handler type for subtask.toggle
+type SubtaskToggleHandler func(ctx context.Context, info RequestInfo, input SubtaskToggleInput) (SubtaskToggleOutput, error)
 
 // Handler type for task.create
-type TaskCreateHandler func(ctx *Context, input TaskCreateInput) (TaskCreateOutput, error)
-
-
-// Handler type for task.update
-type TaskUpdateHandler func(ctx *Context, input TaskUpdateInput) (TaskUpdateOutput, error)
-
+type TaskCreateHandler func(ctx context.Context, info RequestInfo, input TaskCreateInput) (TaskCreateOutput, error)
 
 // Handler type for task.delete
-type TaskDeleteHandler func(ctx *Context, input TaskDeleteInput) (TaskDeleteOutput, error)
-
+type TaskDeleteHandler func(ctx context.Context, info RequestInfo, input TaskDeleteInput) (TaskDeleteOutput, error)
 
-// Handler type for subtask.add
-type SubtaskAddHandler func(ctx *Context, input SubtaskAddInput) (SubtaskAddOutput, error)
+// Handler type for task.get
+type TaskGetHandler func(ctx context.Context, info RequestInfo, input TaskGetInput) (TaskGetOutput, error)
 
+// Handler type for task.list
+type TaskListHandler func(ctx context.Context, info RequestInfo, input TaskListInput) (TaskListOutput, error)
 
-// Handler type for subtask.toggle
-type SubtaskToggleHandler func(ctx *Context, input SubtaskToggleInput) (SubtaskToggleOutput, error)
+// Handler type for task.update
+type TaskUpdateHandler func(ctx context.Context, info RequestInfo, input TaskUpdateInput) (TaskUpdateOutput, error)
 
+// Handler type for task.watch
+type TaskWatchHandler func(ctx context.Context, info RequestInfo, input TaskWatchInput, send func(TaskWatchOutput) error) error
@@ -1,1379 +1,1030 @@
 package xrpc
 
 import (
-    "fmt"
-    "strings"
-    "regexp"
-    "net/mail"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type ValidationError struct {
-    Field   string `json:"field"`
-    Message string `json:"message"`
+	Field   string        `json:"field"`
+	Path    []PathSegment `json:"path,omitempty"`
+	Message string        `json:"message"`
 }
 
-
-type ValidationErrors []*ValidationError
-
-func (e *ValidationError) Error() string {
-    return fmt.Sprintf("%s: %s", e.Field, e.Message)
+// PathSegment is a step of the path to an invalid value: the Key of an object
+// field or record entry, or the Index of an array item. Paths encode as JSON
+// arrays of keys and indices, such as ["tasks", 1, "title"].
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
 }
 
-func (e ValidationErrors) Error() string {
-    var msgs []string
-    for _, err := range e {
-        msgs = append(msgs, err.Error())
-    }
-    return strings.Join(msgs, "; ")
+func (s PathSegment) MarshalJSON() ([]byte, error) {
+	if s.IsIndex {
+		return []byte(strconv.Itoa(s.Index)), nil
+	}
+	return json.Marshal(s.Key)
 }
 
-func ValidateTaskListInput(input TaskListInput) error {
-    var errs ValidationErrors
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    if input.Limit < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "limit",
-            Message: fmt.Sprintf("must be at least %v", 1),
-        })
-    }
-    if input.Limit > 50 {
-        errs = append(errs, &ValidationError{
-            Field:   "limit",
-            Message: fmt.Sprintf("must be at most %v", 50),
-        })
-    }
-    if float64(input.Limit) != float64(int64(input.Limit)) {
-        errs = append(errs, &ValidationError{
-            Field:   "limit",
-            Message: "must be an integer",
-        })
-    }
-    if input.Limit <= 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "limit",
-            Message: "must be positive",
-        })
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func (s *PathSegment) UnmarshalJSON(data []byte) error {
+	var index int
+	if err := json.Unmarshal(data, &index); err == nil {
+		*s = PathSegment{Index: index, IsIndex: true}
+		return nil
+	}
+	*s = PathSegment{}
+	return json.Unmarshal(data, &s.Key)
 }
 
-func ValidateTaskListOutput(input TaskListOutput) error {
-    var errs ValidationErrors
-    // Validate tasks
-    if input.Tasks == nil {
-        errs = append(errs, &ValidationError{
-            Field:   "tasks",
-            Message: "is required",
-        })
-    }
-    for i, item := range input.Tasks {
-        if err := ValidateTaskListOutputTasksItem(item); err != nil {
-            if nestedErrs, ok := err.(ValidationErrors); ok {
-                for _, nestedErr := range nestedErrs {
-                    errs = append(errs, &ValidationError{
-                        Field:   fmt.Sprintf("tasks[%%d].%%s", i, nestedErr.Field),
-                        Message: nestedErr.Message,
-                    })
-                }
-            }
-        }
-    }
-}
-// Validate total
-if input.Total < 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "total",
-        Message: fmt.Sprintf("must be at least %v", 0),
-    })
-}
-if float64(input.Total) != float64(int64(input.Total)) {
-    errs = append(errs, &ValidationError{
-        Field:   "total",
-        Message: "must be an integer",
-    })
-}
-if len(errs) > 0 {
-    return errs
-}
-return nil
-}
+type ValidationErrors []*ValidationError
 
-func ValidateTaskGetInput(input TaskGetInput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+// JSONPointer renders the path of e as a JSON Pointer (RFC 6901), such as
+// "/tasks/1/title", locating the invalid value in the params.
+func (e *ValidationError) JSONPointer() string {
+	var pointer strings.Builder
+	for _, segment := range e.Path {
+		pointer.WriteString("/")
+		if segment.IsIndex {
+			pointer.WriteString(strconv.Itoa(segment.Index))
+			continue
+		}
+		pointer.WriteString(pointerEscaper.Replace(segment.Key))
+	}
+	return pointer.String()
 }
 
-func ValidateTaskGetOutput(input TaskGetOutput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+// pointerEscaper escapes the keys of JSON Pointers
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    if input.Description != "" {
-        if len(input.Description) > 2000 {
-            errs = append(errs, &ValidationError{
-                Field:   "description",
-                Message: fmt.Sprintf("must be at most %d character(s)", 2000),
-            })
-        }
-    }
-    // Validate status
-    if input.Status == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "is required",
-        })
-    }
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    // Validate priority
-    if input.Priority == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "is required",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    // Validate createdAt
-    if input.CreatedAt == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "createdAt",
-            Message: "is required",
-        })
-    }
-    // Validate completedAt when present
-    if input.CompletedAt != nil {
-    }
-    if err := ValidateTaskGetOutputAssignee(input.Assignee); err != nil {
-        if nestedErrs, ok := err.(ValidationErrors); ok {
-            errs = append(errs, nestedErrs...)
-        } else {
-            errs = append(errs, &ValidationError{
-                Field:   "assignee",
-                Message: err.Error(),
-            })
-        }
-    }
-    // Validate subtasks
-    if input.Subtasks == nil {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: "is required",
-        })
-    }
-    if input.Subtasks != nil && len(input.Subtasks) > 20 {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: fmt.Sprintf("must have at most %d item(s)", 20),
-        })
-    }
-    for i, item := range input.Subtasks {
-        if err := ValidateTaskGetOutputSubtasksItem(item); err != nil {
-            if nestedErrs, ok := err.(ValidationErrors); ok {
-                for _, nestedErr := range nestedErrs {
-                    errs = append(errs, &ValidationError{
-                        Field:   fmt.Sprintf("subtasks[%%d].%%s", i, nestedErr.Field),
-                        Message: nestedErr.Message,
-                    })
-                }
-            }
-        }
-    }
-}
-if input.EstimatedHours > 100 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: fmt.Sprintf("must be at most %v", 100),
-    })
-}
-if input.EstimatedHours <= 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: "must be positive",
-    })
-}
-// Validate position
-if input.Position < 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: fmt.Sprintf("must be at least %v", 0),
-    })
-}
-if float64(input.Position) != float64(int64(input.Position)) {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: "must be an integer",
-    })
-}
-if len(errs) > 0 {
-    return errs
-}
-return nil
+func (e ValidationErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
 }
 
-func ValidateTaskCreateInput(input TaskCreateInput) error {
-    var errs ValidationErrors
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 3 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 3),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    if input.Description != "" {
-        if len(input.Description) > 2000 {
-            errs = append(errs, &ValidationError{
-                Field:   "description",
-                Message: fmt.Sprintf("must be at most %d character(s)", 2000),
-            })
-        }
-    }
-    // Validate priority
-    if input.Priority == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "is required",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    if input.EstimatedHours > 100 {
-        errs = append(errs, &ValidationError{
-            Field:   "estimatedHours",
-            Message: fmt.Sprintf("must be at most %v", 100),
-        })
-    }
-    if input.EstimatedHours <= 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "estimatedHours",
-            Message: "must be positive",
-        })
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
-}
+// Patterns are compiled once at startup rather than on every request
+var (
+	uuidPattern = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+)
 
-func ValidateTaskCreateOutput(input TaskCreateOutput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateAssignee(input Assignee) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    if input.Description != "" {
-        if len(input.Description) > 2000 {
-            errs = append(errs, &ValidationError{
-                Field:   "description",
-                Message: fmt.Sprintf("must be at most %d character(s)", 2000),
-            })
-        }
-    }
-    // Validate status
-    if input.Status == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "is required",
-        })
-    }
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    // Validate priority
-    if input.Priority == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "is required",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    // Validate createdAt
-    if input.CreatedAt == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "createdAt",
-            Message: "is required",
-        })
-    }
-    // Validate completedAt when present
-    if input.CompletedAt != nil {
-    }
-    if err := ValidateTaskCreateOutputAssignee(input.Assignee); err != nil {
-        if nestedErrs, ok := err.(ValidationErrors); ok {
-            errs = append(errs, nestedErrs...)
-        } else {
-            errs = append(errs, &ValidationError{
-                Field:   "assignee",
-                Message: err.Error(),
-            })
-        }
-    }
-    // Validate subtasks
-    if input.Subtasks == nil {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: "is required",
-        })
-    }
-    if input.Subtasks != nil && len(input.Subtasks) > 20 {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: fmt.Sprintf("must have at most %d item(s)", 20),
-        })
-    }
-    for i, item := range input.Subtasks {
-        if err := ValidateTaskCreateOutputSubtasksItem(item); err != nil {
-            if nestedErrs, ok := err.(ValidationErrors); ok {
-                for _, nestedErr := range nestedErrs {
-                    errs = append(errs, &ValidationError{
-                        Field:   fmt.Sprintf("subtasks[%%d].%%s", i, nestedErr.Field),
-                        Message: nestedErr.Message,
-                    })
-                }
-            }
-        }
-    }
-}
-if input.EstimatedHours > 100 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: fmt.Sprintf("must be at most %v", 100),
-    })
-}
-if input.EstimatedHours <= 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: "must be positive",
-    })
-}
-// Validate position
-if input.Position < 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: fmt.Sprintf("must be at least %v", 0),
-    })
-}
-if float64(input.Position) != float64(int64(input.Position)) {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: "must be an integer",
-    })
-}
-if len(errs) > 0 {
-    return errs
-}
-return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate name
+	if input.Name == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "name",
+			Path:    []PathSegment{{Key: "name"}},
+			Message: "is required",
+		})
+	}
+	if input.Name != "" && utf8.RuneCountInString(input.Name) < 2 {
+		errs = append(errs, &ValidationError{
+			Field:   "name",
+			Path:    []PathSegment{{Key: "name"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 2),
+		})
+	}
+	if utf8.RuneCountInString(input.Name) > 100 {
+		errs = append(errs, &ValidationError{
+			Field:   "name",
+			Path:    []PathSegment{{Key: "name"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 100),
+		})
+	}
+	// Validate email
+	if input.Email == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "email",
+			Path:    []PathSegment{{Key: "email"}},
+			Message: "is required",
+		})
+	}
+	if input.Email != "" {
+		if _, err := mail.ParseAddress(input.Email); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:   "email",
+				Path:    []PathSegment{{Key: "email"}},
+				Message: "must be a valid email address",
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskUpdateInput(input TaskUpdateInput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateSubtask(input Subtask) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    if input.Title != "" {
-        if len(input.Title) < 1 {
-            errs = append(errs, &ValidationError{
-                Field:   "title",
-                Message: fmt.Sprintf("must be at least %d character(s)", 1),
-            })
-        }
-        if len(input.Title) > 200 {
-            errs = append(errs, &ValidationError{
-                Field:   "title",
-                Message: fmt.Sprintf("must be at most %d character(s)", 200),
-            })
-        }
-    }
-    // Validate description when present
-    if input.Description != nil {
-        if *input.Description != "" {
-            if len(*input.Description) > 2000 {
-                errs = append(errs, &ValidationError{
-                    Field:   "description",
-                    Message: fmt.Sprintf("must be at most %d character(s)", 2000),
-                })
-            }
-        }
-    }
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    // Validate dueDate when present
-    if input.DueDate != nil {
-    }
-    // Validate estimatedHours when present
-    if input.EstimatedHours != nil {
-        if *input.EstimatedHours > 100 {
-            errs = append(errs, &ValidationError{
-                Field:   "estimatedHours",
-                Message: fmt.Sprintf("must be at most %v", 100),
-            })
-        }
-        if *input.EstimatedHours <= 0 {
-            errs = append(errs, &ValidationError{
-                Field:   "estimatedHours",
-                Message: "must be positive",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate title
+	if input.Title == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is required",
+		})
+	}
+	if input.Title != "" && utf8.RuneCountInString(input.Title) < 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 1),
+		})
+	}
+	if utf8.RuneCountInString(input.Title) > 200 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 200),
+		})
+	}
+	// Validate completed
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskUpdateOutput(input TaskUpdateOutput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateSubtaskAddInput(input SubtaskAddInput) error {
+	var errs ValidationErrors
+	// Validate taskId
+	if input.TaskId == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "taskId",
+			Path:    []PathSegment{{Key: "taskId"}},
+			Message: "is required",
+		})
+	}
+	if input.TaskId != "" {
+		matched := uuidPattern.MatchString(input.TaskId)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    if input.Description != "" {
-        if len(input.Description) > 2000 {
-            errs = append(errs, &ValidationError{
-                Field:   "description",
-                Message: fmt.Sprintf("must be at most %d character(s)", 2000),
-            })
-        }
-    }
-    // Validate status
-    if input.Status == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "is required",
-        })
-    }
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    // Validate priority
-    if input.Priority == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "is required",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    // Validate createdAt
-    if input.CreatedAt == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "createdAt",
-            Message: "is required",
-        })
-    }
-    // Validate completedAt when present
-    if input.CompletedAt != nil {
-    }
-    if err := ValidateTaskUpdateOutputAssignee(input.Assignee); err != nil {
-        if nestedErrs, ok := err.(ValidationErrors); ok {
-            errs = append(errs, nestedErrs...)
-        } else {
-            errs = append(errs, &ValidationError{
-                Field:   "assignee",
-                Message: err.Error(),
-            })
-        }
-    }
-    // Validate subtasks
-    if input.Subtasks == nil {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: "is required",
-        })
-    }
-    if input.Subtasks != nil && len(input.Subtasks) > 20 {
-        errs = append(errs, &ValidationError{
-            Field:   "subtasks",
-            Message: fmt.Sprintf("must have at most %d item(s)", 20),
-        })
-    }
-    for i, item := range input.Subtasks {
-        if err := ValidateTaskUpdateOutputSubtasksItem(item); err != nil {
-            if nestedErrs, ok := err.(ValidationErrors); ok {
-                for _, nestedErr := range nestedErrs {
-                    errs = append(errs, &ValidationError{
-                        Field:   fmt.Sprintf("subtasks[%%d].%%s", i, nestedErr.Field),
-                        Message: nestedErr.Message,
-                    })
-                }
-            }
-        }
-    }
-}
-if input.EstimatedHours > 100 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: fmt.Sprintf("must be at most %v", 100),
-    })
-}
-if input.EstimatedHours <= 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "estimatedHours",
-        Message: "must be positive",
-    })
-}
-// Validate position
-if input.Position < 0 {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: fmt.Sprintf("must be at least %v", 0),
-    })
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "taskId",
+				Path:    []PathSegment{{Key: "taskId"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate title
+	if input.Title == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is required",
+		})
+	}
+	if input.Title != "" && utf8.RuneCountInString(input.Title) < 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 1),
+		})
+	}
+	if utf8.RuneCountInString(input.Title) > 200 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 200),
+		})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
-if float64(input.Position) != float64(int64(input.Position)) {
-    errs = append(errs, &ValidationError{
-        Field:   "position",
-        Message: "must be an integer",
-    })
-}
-if len(errs) > 0 {
-    return errs
-}
-return nil
+
+func ValidateSubtaskAddOutput(input SubtaskAddOutput) error {
+	return ValidateSubtask(input)
 }
 
-func ValidateTaskDeleteInput(input TaskDeleteInput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateSubtaskToggleInput(input SubtaskToggleInput) error {
+	var errs ValidationErrors
+	// Validate taskId
+	if input.TaskId == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "taskId",
+			Path:    []PathSegment{{Key: "taskId"}},
+			Message: "is required",
+		})
+	}
+	if input.TaskId != "" {
+		matched := uuidPattern.MatchString(input.TaskId)
+
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "taskId",
+				Path:    []PathSegment{{Key: "taskId"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate subtaskId
+	if input.SubtaskId == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "subtaskId",
+			Path:    []PathSegment{{Key: "subtaskId"}},
+			Message: "is required",
+		})
+	}
+	if input.SubtaskId != "" {
+		matched := uuidPattern.MatchString(input.SubtaskId)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "subtaskId",
+				Path:    []PathSegment{{Key: "subtaskId"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskDeleteOutput(input TaskDeleteOutput) error {
-    var errs ValidationErrors
-    // Validate success
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateSubtaskToggleOutput(input SubtaskToggleOutput) error {
+	return ValidateSubtask(input)
 }
 
-func ValidateSubtaskAddInput(input SubtaskAddInput) error {
-    var errs ValidationErrors
-    // Validate taskId
-    if input.TaskId == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "taskId",
-            Message: "is required",
-        })
-    }
-    if input.TaskId != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.TaskId)
+func ValidateTask(input Task) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "taskId",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate title
+	if input.Title == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is required",
+		})
+	}
+	if input.Title != "" && utf8.RuneCountInString(input.Title) < 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 1),
+		})
+	}
+	if utf8.RuneCountInString(input.Title) > 200 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 200),
+		})
+	}
+	// Validate description when present
+	if input.Description != nil {
+		if utf8.RuneCountInString(*input.Description) > 2000 {
+			errs = append(errs, &ValidationError{
+				Field:   "description",
+				Path:    []PathSegment{{Key: "description"}},
+				Message: fmt.Sprintf("must be at most %d character(s)", 2000),
+			})
+		}
+	}
+	// Validate status
+	if input.Status == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "status",
+			Path:    []PathSegment{{Key: "status"}},
+			Message: "is required",
+		})
+	}
+	if input.Status != "" && !input.Status.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:   "status",
+			Path:    []PathSegment{{Key: "status"}},
+			Message: "must be one of: pending, in_progress, completed, cancelled",
+		})
+	}
+	// Validate priority
+	if input.Priority == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "priority",
+			Path:    []PathSegment{{Key: "priority"}},
+			Message: "is required",
+		})
+	}
+	if input.Priority != "" && !input.Priority.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:   "priority",
+			Path:    []PathSegment{{Key: "priority"}},
+			Message: "must be one of: low, medium, high, urgent",
+		})
+	}
+	// Validate createdAt
+	if input.CreatedAt.IsZero() {
+		errs = append(errs, &ValidationError{
+			Field:   "createdAt",
+			Path:    []PathSegment{{Key: "createdAt"}},
+			Message: "is required",
+		})
+	}
+	// Validate assignee when present
+	if input.Assignee != nil {
+		if err := ValidateAssignee(*input.Assignee); err != nil {
+			if nestedErrs, ok := err.(ValidationErrors); ok {
+				for _, nestedErr := range nestedErrs {
+					errs = append(errs, &ValidationError{
+						Field:   fmt.Sprintf("assignee.%s", nestedErr.Field),
+						Path:    append([]PathSegment{{Key: "assignee"}}, nestedErr.Path...),
+						Message: nestedErr.Message,
+					})
+				}
+			} else {
+				errs = append(errs, &ValidationError{
+					Field:   "assignee",
+					Path:    []PathSegment{{Key: "assignee"}},
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+	// Validate subtasks
+	if input.Subtasks == nil {
+		errs = append(errs, &ValidationError{
+			Field:   "subtasks",
+			Path:    []PathSegment{{Key: "subtasks"}},
+			Message: "is required",
+		})
+	}
+	if input.Subtasks != nil && len(input.Subtasks) > 20 {
+		errs = append(errs, &ValidationError{
+			Field:   "subtasks",
+			Path:    []PathSegment{{Key: "subtasks"}},
+			Message: fmt.Sprintf("must have at most %d item(s)", 20),
+		})
+	}
+	for i, item := range input.Subtasks {
+		if err := ValidateSubtask(item); err != nil {
+			if nestedErrs, ok := err.(ValidationErrors); ok {
+				for _, nestedErr := range nestedErrs {
+					errs = append(errs, &ValidationError{
+						Field:   fmt.Sprintf("subtasks[%d].%s", i, nestedErr.Field),
+						Path:    append([]PathSegment{{Key: "subtasks"}, {Index: i, IsIndex: true}}, nestedErr.Path...),
+						Message: nestedErr.Message,
+					})
+				}
+			}
+		}
+	}
+	// Validate estimatedHours when present
+	if input.EstimatedHours != nil {
+		if *input.EstimatedHours > 100 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be at most %v", 100),
+			})
+		}
+		if *input.EstimatedHours <= 0 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: "must be positive",
+			})
+		}
+		if !isMultipleOf(float64(*input.EstimatedHours), 0.25) {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be a multiple of %v", 0.25),
+			})
+		}
+	}
+	// Validate position
+	if input.Position < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "position",
+			Path:    []PathSegment{{Key: "position"}},
+			Message: fmt.Sprintf("must be at least %v", 0),
+		})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateSubtaskAddOutput(input SubtaskAddOutput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
-
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate completed
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateTaskCreateInput(input TaskCreateInput) error {
+	var errs ValidationErrors
+	// Validate title
+	if input.Title == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is required",
+		})
+	}
+	if input.Title != "" && utf8.RuneCountInString(input.Title) < 3 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 3),
+		})
+	}
+	if utf8.RuneCountInString(input.Title) > 200 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 200),
+		})
+	}
+	// Validate description when present
+	if input.Description != nil {
+		if utf8.RuneCountInString(*input.Description) > 2000 {
+			errs = append(errs, &ValidationError{
+				Field:   "description",
+				Path:    []PathSegment{{Key: "description"}},
+				Message: fmt.Sprintf("must be at most %d character(s)", 2000),
+			})
+		}
+	}
+	// Validate priority when present
+	if input.Priority != nil {
+		if !(*input.Priority).IsValid() {
+			errs = append(errs, &ValidationError{
+				Field:   "priority",
+				Path:    []PathSegment{{Key: "priority"}},
+				Message: "must be one of: low, medium, high, urgent",
+			})
+		}
+	}
+	// dueDate is required when priority is urgent
+	if input.DueDate == nil && input.Priority != nil && *input.Priority == "urgent" {
+		errs = append(errs, &ValidationError{
+			Field:   "dueDate",
+			Path:    []PathSegment{{Key: "dueDate"}},
+			Message: "is required when priority is urgent",
+		})
+	}
+	// Validate dueDate when present
+	if input.DueDate != nil {
+		if !(*input.DueDate).After(time.Now()) {
+			errs = append(errs, &ValidationError{
+				Field:   "dueDate",
+				Path:    []PathSegment{{Key: "dueDate"}},
+				Message: "must be in the future",
+			})
+		}
+		if err := validators["workingDay"](*input.DueDate); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:   "dueDate",
+				Path:    []PathSegment{{Key: "dueDate"}},
+				Message: err.Error(),
+			})
+		}
+	}
+	// Validate estimatedHours when present
+	if input.EstimatedHours != nil {
+		if *input.EstimatedHours > 100 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be at most %v", 100),
+			})
+		}
+		if *input.EstimatedHours <= 0 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: "must be positive",
+			})
+		}
+		if !isMultipleOf(float64(*input.EstimatedHours), 0.25) {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be a multiple of %v", 0.25),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateSubtaskToggleInput(input SubtaskToggleInput) error {
-    var errs ValidationErrors
-    // Validate taskId
-    if input.TaskId == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "taskId",
-            Message: "is required",
-        })
-    }
-    if input.TaskId != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.TaskId)
+func ValidateTaskCreateOutput(input TaskCreateOutput) error {
+	return ValidateTask(input)
+}
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "taskId",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate subtaskId
-    if input.SubtaskId == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "subtaskId",
-            Message: "is required",
-        })
-    }
-    if input.SubtaskId != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.SubtaskId)
+func ValidateTaskDeleteInput(input TaskDeleteInput) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "subtaskId",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateSubtaskToggleOutput(input SubtaskToggleOutput) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
-
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate completed
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateTaskDeleteOutput(input TaskDeleteOutput) error {
+	var errs ValidationErrors
+	// Validate success
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskListOutputTasksItem(input TaskListOutputTasksItem) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskGetInput(input TaskGetInput) error {
+	return ValidateTaskDeleteInput(input)
+}
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate status
-    if input.Status == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "is required",
-        })
-    }
-    if input.Status != "" && input.Status != "pending" && input.Status != "in_progress" && input.Status != "completed" && input.Status != "cancelled" {
-        errs = append(errs, &ValidationError{
-            Field:   "status",
-            Message: "must be one of: pending, in_progress, completed, cancelled",
-        })
-    }
-    // Validate priority
-    if input.Priority == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "is required",
-        })
-    }
-    if input.Priority != "" && input.Priority != "low" && input.Priority != "medium" && input.Priority != "high" && input.Priority != "urgent" {
-        errs = append(errs, &ValidationError{
-            Field:   "priority",
-            Message: "must be one of: low, medium, high, urgent",
-        })
-    }
-    // Validate createdAt
-    if input.CreatedAt == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "createdAt",
-            Message: "is required",
-        })
-    }
-    // Validate completedAt when present
-    if input.CompletedAt != nil {
-    }
-    // Validate subtaskCount
-    if input.SubtaskCount < 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "subtaskCount",
-            Message: fmt.Sprintf("must be at least %v", 0),
-        })
-    }
-    if float64(input.SubtaskCount) != float64(int64(input.SubtaskCount)) {
-        errs = append(errs, &ValidationError{
-            Field:   "subtaskCount",
-            Message: "must be an integer",
-        })
-    }
-    // Validate subtaskCompletedCount
-    if input.SubtaskCompletedCount < 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "subtaskCompletedCount",
-            Message: fmt.Sprintf("must be at least %v", 0),
-        })
-    }
-    if float64(input.SubtaskCompletedCount) != float64(int64(input.SubtaskCompletedCount)) {
-        errs = append(errs, &ValidationError{
-            Field:   "subtaskCompletedCount",
-            Message: "must be an integer",
-        })
-    }
-    if input.EstimatedHours > 100 {
-        errs = append(errs, &ValidationError{
-            Field:   "estimatedHours",
-            Message: fmt.Sprintf("must be at most %v", 100),
-        })
-    }
-    if input.EstimatedHours <= 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "estimatedHours",
-            Message: "must be positive",
-        })
-    }
-    // Validate position
-    if input.Position < 0 {
-        errs = append(errs, &ValidationError{
-            Field:   "position",
-            Message: fmt.Sprintf("must be at least %v", 0),
-        })
-    }
-    if float64(input.Position) != float64(int64(input.Position)) {
-        errs = append(errs, &ValidationError{
-            Field:   "position",
-            Message: "must be an integer",
-        })
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateTaskGetOutput(input TaskGetOutput) error {
+	return ValidateTask(input)
 }
 
-func ValidateTaskGetOutputAssignee(input TaskGetOutputAssignee) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskListInput(input TaskListInput) error {
+	var errs ValidationErrors
+	// Validate status when present
+	if input.Status != nil {
+		if !(*input.Status).IsValid() {
+			errs = append(errs, &ValidationError{
+				Field:   "status",
+				Path:    []PathSegment{{Key: "status"}},
+				Message: "must be one of: pending, in_progress, completed, cancelled",
+			})
+		}
+	}
+	// Validate priority when present
+	if input.Priority != nil {
+		if !(*input.Priority).IsValid() {
+			errs = append(errs, &ValidationError{
+				Field:   "priority",
+				Path:    []PathSegment{{Key: "priority"}},
+				Message: "must be one of: low, medium, high, urgent",
+			})
+		}
+	}
+	// Validate pageSize when present
+	if input.PageSize != nil {
+		if *input.PageSize < 1 {
+			errs = append(errs, &ValidationError{
+				Field:   "pageSize",
+				Path:    []PathSegment{{Key: "pageSize"}},
+				Message: fmt.Sprintf("must be at least %v", 1),
+			})
+		}
+		if *input.PageSize > 100 {
+			errs = append(errs, &ValidationError{
+				Field:   "pageSize",
+				Path:    []PathSegment{{Key: "pageSize"}},
+				Message: fmt.Sprintf("must be at most %v", 100),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate name
-    if input.Name == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: "is required",
-        })
-    }
-    if input.Name != "" && len(input.Name) < 2 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at least %d character(s)", 2),
-        })
-    }
-    if len(input.Name) > 100 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at most %d character(s)", 100),
-        })
-    }
-    // Validate email
-    if input.Email == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "email",
-            Message: "is required",
-        })
-    }
-    if input.Email != "" {
-        if _, err := mail.ParseAddress(input.Email); err != nil {
-            errs = append(errs, &ValidationError{
-                Field:   "email",
-                Message: "must be a valid email address",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateTaskListOutput(input TaskListOutput) error {
+	var errs ValidationErrors
+	// Validate tasks
+	if input.Tasks == nil {
+		errs = append(errs, &ValidationError{
+			Field:   "tasks",
+			Path:    []PathSegment{{Key: "tasks"}},
+			Message: "is required",
+		})
+	}
+	for i, item := range input.Tasks {
+		if err := ValidateTaskSummary(item); err != nil {
+			if nestedErrs, ok := err.(ValidationErrors); ok {
+				for _, nestedErr := range nestedErrs {
+					errs = append(errs, &ValidationError{
+						Field:   fmt.Sprintf("tasks[%d].%s", i, nestedErr.Field),
+						Path:    append([]PathSegment{{Key: "tasks"}, {Index: i, IsIndex: true}}, nestedErr.Path...),
+						Message: nestedErr.Message,
+					})
+				}
+			}
+		}
+	}
+	// Validate total
+	if input.Total < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "total",
+			Path:    []PathSegment{{Key: "total"}},
+			Message: fmt.Sprintf("must be at least %v", 0),
+		})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskGetOutputSubtasksItem(input TaskGetOutputSubtasksItem) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskSummary(input TaskSummary) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate completed
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate title
+	if input.Title == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is required",
+		})
+	}
+	if input.Title != "" && utf8.RuneCountInString(input.Title) < 1 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at least %d character(s)", 1),
+		})
+	}
+	if utf8.RuneCountInString(input.Title) > 200 {
+		errs = append(errs, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: fmt.Sprintf("must be at most %d character(s)", 200),
+		})
+	}
+	// Validate status
+	if input.Status == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "status",
+			Path:    []PathSegment{{Key: "status"}},
+			Message: "is required",
+		})
+	}
+	if input.Status != "" && !input.Status.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:   "status",
+			Path:    []PathSegment{{Key: "status"}},
+			Message: "must be one of: pending, in_progress, completed, cancelled",
+		})
+	}
+	// Validate priority
+	if input.Priority == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "priority",
+			Path:    []PathSegment{{Key: "priority"}},
+			Message: "is required",
+		})
+	}
+	if input.Priority != "" && !input.Priority.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:   "priority",
+			Path:    []PathSegment{{Key: "priority"}},
+			Message: "must be one of: low, medium, high, urgent",
+		})
+	}
+	// Validate createdAt
+	if input.CreatedAt.IsZero() {
+		errs = append(errs, &ValidationError{
+			Field:   "createdAt",
+			Path:    []PathSegment{{Key: "createdAt"}},
+			Message: "is required",
+		})
+	}
+	// Validate subtaskCount
+	if input.SubtaskCount < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "subtaskCount",
+			Path:    []PathSegment{{Key: "subtaskCount"}},
+			Message: fmt.Sprintf("must be at least %v", 0),
+		})
+	}
+	// Validate subtaskCompletedCount
+	if input.SubtaskCompletedCount < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "subtaskCompletedCount",
+			Path:    []PathSegment{{Key: "subtaskCompletedCount"}},
+			Message: fmt.Sprintf("must be at least %v", 0),
+		})
+	}
+	// Validate estimatedHours when present
+	if input.EstimatedHours != nil {
+		if *input.EstimatedHours > 100 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be at most %v", 100),
+			})
+		}
+		if *input.EstimatedHours <= 0 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: "must be positive",
+			})
+		}
+		if !isMultipleOf(float64(*input.EstimatedHours), 0.25) {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be a multiple of %v", 0.25),
+			})
+		}
+	}
+	// Validate position
+	if input.Position < 0 {
+		errs = append(errs, &ValidationError{
+			Field:   "position",
+			Path:    []PathSegment{{Key: "position"}},
+			Message: fmt.Sprintf("must be at least %v", 0),
+		})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskCreateOutputAssignee(input TaskCreateOutputAssignee) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskUpdateInput(input TaskUpdateInput) error {
+	var errs ValidationErrors
+	// Validate id
+	if input.Id == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "id",
+			Path:    []PathSegment{{Key: "id"}},
+			Message: "is required",
+		})
+	}
+	if input.Id != "" {
+		matched := uuidPattern.MatchString(input.Id)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate name
-    if input.Name == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: "is required",
-        })
-    }
-    if input.Name != "" && len(input.Name) < 2 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at least %d character(s)", 2),
-        })
-    }
-    if len(input.Name) > 100 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at most %d character(s)", 100),
-        })
-    }
-    // Validate email
-    if input.Email == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "email",
-            Message: "is required",
-        })
-    }
-    if input.Email != "" {
-        if _, err := mail.ParseAddress(input.Email); err != nil {
-            errs = append(errs, &ValidationError{
-                Field:   "email",
-                Message: "must be a valid email address",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "id",
+				Path:    []PathSegment{{Key: "id"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	// Validate title when present
+	if input.Title != nil {
+		if utf8.RuneCountInString(*input.Title) < 1 {
+			errs = append(errs, &ValidationError{
+				Field:   "title",
+				Path:    []PathSegment{{Key: "title"}},
+				Message: fmt.Sprintf("must be at least %d character(s)", 1),
+			})
+		}
+		if utf8.RuneCountInString(*input.Title) > 200 {
+			errs = append(errs, &ValidationError{
+				Field:   "title",
+				Path:    []PathSegment{{Key: "title"}},
+				Message: fmt.Sprintf("must be at most %d character(s)", 200),
+			})
+		}
+	}
+	// Validate description when present
+	if input.Description != nil {
+		if utf8.RuneCountInString(*input.Description) > 2000 {
+			errs = append(errs, &ValidationError{
+				Field:   "description",
+				Path:    []PathSegment{{Key: "description"}},
+				Message: fmt.Sprintf("must be at most %d character(s)", 2000),
+			})
+		}
+	}
+	// Validate status when present
+	if input.Status != nil {
+		if !(*input.Status).IsValid() {
+			errs = append(errs, &ValidationError{
+				Field:   "status",
+				Path:    []PathSegment{{Key: "status"}},
+				Message: "must be one of: pending, in_progress, completed, cancelled",
+			})
+		}
+	}
+	// Validate priority when present
+	if input.Priority != nil {
+		if !(*input.Priority).IsValid() {
+			errs = append(errs, &ValidationError{
+				Field:   "priority",
+				Path:    []PathSegment{{Key: "priority"}},
+				Message: "must be one of: low, medium, high, urgent",
+			})
+		}
+	}
+	// Validate dueDate when present
+	if input.DueDate != nil {
+		if !(*input.DueDate).After(time.Now()) {
+			errs = append(errs, &ValidationError{
+				Field:   "dueDate",
+				Path:    []PathSegment{{Key: "dueDate"}},
+				Message: "must be in the future",
+			})
+		}
+		if err := validators["workingDay"](*input.DueDate); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:   "dueDate",
+				Path:    []PathSegment{{Key: "dueDate"}},
+				Message: err.Error(),
+			})
+		}
+	}
+	// Validate estimatedHours when present
+	if input.EstimatedHours != nil {
+		if *input.EstimatedHours > 100 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be at most %v", 100),
+			})
+		}
+		if *input.EstimatedHours <= 0 {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: "must be positive",
+			})
+		}
+		if !isMultipleOf(float64(*input.EstimatedHours), 0.25) {
+			errs = append(errs, &ValidationError{
+				Field:   "estimatedHours",
+				Path:    []PathSegment{{Key: "estimatedHours"}},
+				Message: fmt.Sprintf("must be a multiple of %v", 0.25),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskCreateOutputSubtasksItem(input TaskCreateOutputSubtasksItem) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
-
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate completed
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+func ValidateTaskUpdateOutput(input TaskUpdateOutput) error {
+	return ValidateTask(input)
 }
 
-func ValidateTaskUpdateOutputAssignee(input TaskUpdateOutputAssignee) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskWatchInput(input TaskWatchInput) error {
+	var errs ValidationErrors
+	// Validate taskId when present
+	if input.TaskId != nil {
+		matched := uuidPattern.MatchString(*input.TaskId)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate name
-    if input.Name == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: "is required",
-        })
-    }
-    if input.Name != "" && len(input.Name) < 2 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at least %d character(s)", 2),
-        })
-    }
-    if len(input.Name) > 100 {
-        errs = append(errs, &ValidationError{
-            Field:   "name",
-            Message: fmt.Sprintf("must be at most %d character(s)", 100),
-        })
-    }
-    // Validate email
-    if input.Email == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "email",
-            Message: "is required",
-        })
-    }
-    if input.Email != "" {
-        if _, err := mail.ParseAddress(input.Email); err != nil {
-            errs = append(errs, &ValidationError{
-                Field:   "email",
-                Message: "must be a valid email address",
-            })
-        }
-    }
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "taskId",
+				Path:    []PathSegment{{Key: "taskId"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
-func ValidateTaskUpdateOutputSubtasksItem(input TaskUpdateOutputSubtasksItem) error {
-    var errs ValidationErrors
-    // Validate id
-    if input.Id == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "id",
-            Message: "is required",
-        })
-    }
-    if input.Id != "" {
-        matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", input.Id)
+func ValidateTaskWatchOutput(input TaskWatchOutput) error {
+	var errs ValidationErrors
+	// Validate type
+	if input.Type == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "type",
+			Path:    []PathSegment{{Key: "type"}},
+			Message: "is required",
+		})
+	}
+	if input.Type != "" && !input.Type.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:   "type",
+			Path:    []PathSegment{{Key: "type"}},
+			Message: "must be one of: created, updated, deleted",
+		})
+	}
+	// Validate taskId
+	if input.TaskId == "" {
+		errs = append(errs, &ValidationError{
+			Field:   "taskId",
+			Path:    []PathSegment{{Key: "taskId"}},
+			Message: "is required",
+		})
+	}
+	if input.TaskId != "" {
+		matched := uuidPattern.MatchString(input.TaskId)
 
-        if !matched {
-            errs = append(errs, &ValidationError{
-                Field:   "id",
-                Message: "must be a valid UUID",
-            })
-        }
-    }
-    // Validate title
-    if input.Title == "" {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: "is required",
-        })
-    }
-    if input.Title != "" && len(input.Title) < 1 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at least %d character(s)", 1),
-        })
-    }
-    if len(input.Title) > 200 {
-        errs = append(errs, &ValidationError{
-            Field:   "title",
-            Message: fmt.Sprintf("must be at most %d character(s)", 200),
-        })
-    }
-    // Validate completed
-    if len(errs) > 0 {
-        return errs
-    }
-    return nil
+		if !matched {
+			errs = append(errs, &ValidationError{
+				Field:   "taskId",
+				Path:    []PathSegment{{Key: "taskId"}},
+				Message: "must be a valid UUID",
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
 }
 
+// isMultipleOf reports whether value is a whole number of steps, allowing for
+// the rounding of steps such as 0.1 that floating point cannot represent.
+func isMultipleOf(value, step float64) bool {
+	steps := value / step
+	return math.Abs(steps-math.Round(steps)) <= 1e-9*math.Max(1, math.Abs(steps))
+}
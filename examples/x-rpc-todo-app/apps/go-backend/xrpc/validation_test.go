@@ -0,0 +1,20 @@
+package xrpc
+
+import (
+	"regexp"
+	"testing"
+)
+
+// Each pattern is benchmarked against regexp.MatchString, which compiles the
+// pattern on every call.
+func BenchmarkUUIDPattern(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		uuidPattern.MatchString("3fa85f64-5717-4562-b3fc-2c963f66afa6")
+	}
+}
+
+func BenchmarkUUIDPatternUncompiled(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", "3fa85f64-5717-4562-b3fc-2c963f66afa6")
+	}
+}
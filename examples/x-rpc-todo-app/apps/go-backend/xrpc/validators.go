@@ -0,0 +1,38 @@
+package xrpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidatorFunc checks a field's value against a business rule. The error it
+// returns is reported as the field's validation message.
+type ValidatorFunc func(value interface{}) error
+
+// validators holds the validators registered with RegisterValidator.
+var validators = map[string]ValidatorFunc{}
+
+// customValidators are the validators the contract's fields use.
+var customValidators = []string{
+	"workingDay",
+}
+
+// RegisterValidator registers fn as the custom validator called name. Every
+// validator the contract uses must be registered before NewRouter is called.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validators[name] = fn
+}
+
+// mustRegisteredValidators panics if a validator the contract uses is not
+// registered, so it fails at startup rather than when a request is validated.
+func mustRegisteredValidators() {
+	var missing []string
+	for _, name := range customValidators {
+		if _, ok := validators[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		panic(fmt.Sprintf("custom validators not registered: %s", strings.Join(missing, ", ")))
+	}
+}
@@ -0,0 +1,75 @@
+package xrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"unicode/utf8"
+)
+
+// warningsKey is the context key of the warnings of a call.
+type warningsKey struct{}
+
+// warningCollector gathers the warnings of a call and of the calls dispatched
+// with its context.
+type warningCollector struct {
+	mu       sync.Mutex
+	warnings ValidationErrors
+}
+
+// WarningsFrom returns the warnings of the call ctx belongs to: the values of
+// its input past the soft limits of the contract. They do not reject the
+// call; the router returns them next to the result, in "warnings".
+func WarningsFrom(ctx context.Context) ValidationErrors {
+	c, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append(ValidationErrors(nil), c.warnings...)
+}
+
+// withWarnings returns ctx with a collector for the warnings of a call, unless
+// it has one already.
+func withWarnings(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(warningsKey{}).(*warningCollector); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, warningsKey{}, &warningCollector{})
+}
+
+// addWarnings adds warnings to the collector of ctx, if any.
+func addWarnings(ctx context.Context, warnings ValidationErrors) {
+	c, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok || len(warnings) == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.warnings = append(c.warnings, warnings...)
+	c.mu.Unlock()
+}
+
+// appendWarnings adds warnings to the {"result": ...} envelope in buf, after
+// the result.
+func (buf *encodeBuffer) appendWarnings(warnings ValidationErrors) {
+	data, _ := json.Marshal(warnings)
+	// Written over the closing brace and newline of the envelope
+	buf.Truncate(buf.Len() - 2)
+	buf.WriteString(`,"warnings":`)
+	buf.Write(data)
+	buf.WriteString("}\n")
+}
+
+// Warnings reports the values of Title past their soft limits.
+func (v TaskCreateInput) Warnings() ValidationErrors {
+	var warnings ValidationErrors
+	if utf8.RuneCountInString(v.Title) > 120 {
+		warnings = append(warnings, &ValidationError{
+			Field:   "title",
+			Path:    []PathSegment{{Key: "title"}},
+			Message: "is over 120 characters and will be truncated in the UI",
+		})
+	}
+	return warnings
+}
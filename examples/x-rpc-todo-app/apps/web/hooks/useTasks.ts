@@ -15,8 +15,14 @@ export function useTasks() {
 
   const loadTasks = useCallback(async () => {
     try {
-      const result = await api.task.list({ limit: 50 });
-      setTasks(result.tasks);
+      const all: TaskSummary[] = [];
+      let cursor: string | undefined;
+      do {
+        const page = await api.task.list({ cursor, pageSize: 50 });
+        all.push(...page.tasks);
+        cursor = page.nextCursor;
+      } while (cursor);
+      setTasks(all);
       setError(null);
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Failed to load tasks');
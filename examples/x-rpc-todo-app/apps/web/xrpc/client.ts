@@ -1,4 +1,4 @@
-import { taskListInputSchema, taskListOutputSchema, taskGetInputSchema, taskGetOutputSchema, taskCreateInputSchema, taskCreateOutputSchema, taskUpdateInputSchema, taskUpdateOutputSchema, taskDeleteInputSchema, taskDeleteOutputSchema, subtaskAddInputSchema, subtaskAddOutputSchema, subtaskToggleInputSchema, subtaskToggleOutputSchema, type TaskListInput, type TaskListOutput, type TaskGetInput, type TaskGetOutput, type TaskCreateInput, type TaskCreateOutput, type TaskUpdateInput, type TaskUpdateOutput, type TaskDeleteInput, type TaskDeleteOutput, type SubtaskAddInput, type SubtaskAddOutput, type SubtaskToggleInput, type SubtaskToggleOutput } from './types';
+import { taskListInputSchema, taskListOutputSchema, taskGetInputSchema, taskGetOutputSchema, taskCreateInputSchema, taskCreateOutputSchema, taskUpdateInputSchema, taskUpdateOutputSchema, taskDeleteInputSchema, taskDeleteOutputSchema, taskWatchInputSchema, taskWatchOutputSchema, subtaskAddInputSchema, subtaskAddOutputSchema, subtaskToggleInputSchema, subtaskToggleOutputSchema, type TaskListInput, type TaskListOutput, type TaskGetInput, type TaskGetOutput, type TaskCreateInput, type TaskCreateOutput, type TaskUpdateInput, type TaskUpdateOutput, type TaskDeleteInput, type TaskDeleteOutput, type TaskWatchInput, type TaskWatchOutput, type SubtaskAddInput, type SubtaskAddOutput, type SubtaskToggleInput, type SubtaskToggleOutput } from './types';
 
 import { z } from 'zod';
 
@@ -8,9 +8,58 @@ export interface XRpcClientConfig {
     validateInputs?: boolean;
     validateOutputs?: boolean;
     headers?: Record<string, string>;
+    sendSchemaHash?: boolean;
+    onSchemaMismatch?: (serverHash: string) => void;
 }
 
 
+// Hash of the contract this client was generated from; the server answers
+// calls sending a different one with an X-Xrpc-Schema-Mismatch header
+export const SCHEMA_HASH = '54052c78d158278f';
+
+let schemaMismatchWarned = false;
+
+// Reports a server generated from another contract to onSchemaMismatch, or
+// warns about it once on the console
+export function checkSchemaHash(config: XRpcClientConfig, response: Response): void {
+    const serverHash = response.headers.get('X-Xrpc-Schema-Mismatch');
+    if (!serverHash) {
+        return;
+    }
+    if (config.onSchemaMismatch) {
+        config.onSchemaMismatch(serverHash);
+        return;
+    }
+    if (!schemaMismatchWarned) {
+        schemaMismatchWarned = true;
+        console.warn(`xRPC client generated from schema ${SCHEMA_HASH}, but the server serves ${serverHash}; regenerate the client`);
+    }
+}
+
+
+// Error thrown when an RPC call fails; code mirrors the server's error code
+export class XRpcError extends Error {
+    readonly code: string;
+    readonly details?: unknown;
+    readonly status?: number;
+
+    constructor(code: string, message: string, details?: unknown, status?: number) {
+        super(message);
+        this.name = 'XRpcError';
+        this.code = code;
+        this.details = details;
+        this.status = status;
+    }
+}
+
+// Normalizes an error payload from the server into an XRpcError
+function toXRpcError(payload: any, fallback: string, status?: number): XRpcError {
+    if (typeof payload === 'string') {
+        return new XRpcError('INTERNAL', payload, undefined, status);
+    }
+    return new XRpcError(payload?.code ?? 'INTERNAL', payload?.message || fallback, payload?.details ?? payload?.data, status);
+}
+
 // Base RPC call function
 export async function callRpc<T>(config: XRpcClientConfig, method: string, params: unknown, options?: { inputSchema?: z.ZodType; outputSchema?: z.ZodType; signal?: AbortSignal }) {
     // Validate input if enabled
@@ -24,23 +73,26 @@ export async function callRpc<T>(config: XRpcClientConfig, method: string, param
         method: 'POST',
         headers: {
             'Content-Type': 'application/json',
+            ...(config.sendSchemaHash === false ? {} : { 'X-Xrpc-Schema-Hash': SCHEMA_HASH }),
             ...config.headers,
         },
         body: JSON.stringify({ method, params: validatedParams }),
         signal: options?.signal,
     });
 
+    checkSchemaHash(config, response);
+
     // Handle errors
     if (!response.ok) {
         const error = await response.json().catch(() => ({ error: { message: response.statusText } }));
-        throw new Error(error.error?.message || `RPC call failed: ${response.statusText}`);
+        throw toXRpcError(error.error, `RPC call failed: ${response.statusText}`, response.status);
     }
 
     // Parse response
     const result = await response.json();
     // Handle JSON-RPC response format
     if (result.error) {
-        throw new Error(result.error.message || result.error);
+        throw toXRpcError(result.error, 'RPC call failed', response.status);
     }
     const data = result.result;
 
@@ -52,6 +104,41 @@ export async function callRpc<T>(config: XRpcClientConfig, method: string, param
     return data;
 }
 
+// Base subscription function (Server-Sent Events)
+// EventSource cannot send custom headers, so config.headers is not applied
+export function subscribeRpc<T>(config: XRpcClientConfig, method: string, params: unknown, onData: (data: T) => void, options?: { inputSchema?: z.ZodType; outputSchema?: z.ZodType; onError?: (error: Error) => void }): () => void {
+    // Validate input if enabled
+    let validatedParams = params;
+    if (config.validateInputs !== false && options?.inputSchema) {
+        validatedParams = options.inputSchema.parse(params);
+    }
+
+    const url = new URL(config.baseUrl, globalThis.location?.href);
+    url.searchParams.set('method', method);
+    url.searchParams.set('params', JSON.stringify(validatedParams));
+    const source = new EventSource(url);
+
+    source.onmessage = (event) => {
+        const data = JSON.parse(event.data);
+        if (config.validateOutputs && options?.outputSchema) {
+            onData(options.outputSchema.parse(data));
+        } else {
+            onData(data);
+        }
+    };
+
+    // Handler errors arrive as 'error' events with a JSON payload
+    source.addEventListener('error', (event) => {
+        if (event instanceof MessageEvent) {
+            options?.onError?.(toXRpcError(JSON.parse(event.data).error, 'Subscription failed'));
+        } else {
+            options?.onError?.(new Error('Subscription connection error'));
+        }
+    });
+
+    return () => source.close();
+}
+
 // === Individual Functions (backward compatible) ===
 
 // Type-safe wrapper for task.list
@@ -129,6 +216,22 @@ export async function taskDelete(config: XRpcClientConfig, input: TaskDeleteInpu
 }
 
 
+// Type-safe subscription for task.watch; returns an unsubscribe function
+export function taskWatch(config: XRpcClientConfig, input: TaskWatchInput, onData: (data: TaskWatchOutput) => void, options?: { onError?: (error: Error) => void }) {
+    return subscribeRpc<TaskWatchOutput>(
+        config,
+        'task.watch',
+        input,
+        onData,
+        {
+            inputSchema: taskWatchInputSchema,
+            outputSchema: taskWatchOutputSchema,
+            onError: options?.onError,
+        }
+    );
+}
+
+
 // Type-safe wrapper for subtask.add
 export async function subtaskAdd(config: XRpcClientConfig, input: SubtaskAddInput, options?: { signal?: AbortSignal }) {
     return callRpc<SubtaskAddOutput>(
@@ -173,7 +276,9 @@ export function createClient(config: XRpcClientConfig) {
             update: (input: TaskUpdateInput, options?: { signal?: AbortSignal }) =>
                 taskUpdate(config, input, options),
             delete: (input: TaskDeleteInput, options?: { signal?: AbortSignal }) =>
-                taskDelete(config, input, options)
+                taskDelete(config, input, options),
+            watch: (input: TaskWatchInput, onData: (data: TaskWatchOutput) => void, options?: { onError?: (error: Error) => void }) =>
+                taskWatch(config, input, onData, options)
         },
         subtask: {
             add: (input: SubtaskAddInput, options?: { signal?: AbortSignal }) =>
@@ -28,6 +28,11 @@ export const taskDeleteOutputSchema = router.task.delete.output;
 export type TaskDeleteInput = InferInput<typeof router.task.delete>;
 export type TaskDeleteOutput = InferOutput<typeof router.task.delete>;
 
+export const taskWatchInputSchema = router.task.watch.input;
+export const taskWatchOutputSchema = router.task.watch.output;
+export type TaskWatchInput = InferInput<typeof router.task.watch>;
+export type TaskWatchOutput = InferOutput<typeof router.task.watch>;
+
 export const subtaskAddInputSchema = router.subtask.add.input;
 export const subtaskAddOutputSchema = router.subtask.add.output;
 export type SubtaskAddInput = InferInput<typeof router.subtask.add>;
@@ -1,28 +1,35 @@
 import { z } from 'zod';
-import { createRouter, createEndpoint, query, mutation } from 'xrpckit';
+import { createRouter, createEndpoint, query, mutation, subscription } from 'xrpckit';
 
 // =============================================================================
 // ENUMS
 // =============================================================================
 
-const Priority = z.enum(['low', 'medium', 'high', 'urgent']);
-const TaskStatus = z.enum(['pending', 'in_progress', 'completed', 'cancelled']);
+const Priority = z
+  .enum(['low', 'medium', 'high', 'urgent'])
+  .meta({ id: 'Priority' });
+const TaskStatus = z
+  .enum(['pending', 'in_progress', 'completed', 'cancelled'])
+  .meta({ id: 'TaskStatus' });
 
 // =============================================================================
 // NESTED OBJECT SCHEMAS
 // =============================================================================
 
+// Schemas registered with .meta({ id }) become shared named types: one Go
+// struct and validator used by every endpoint that references them.
+
 const Assignee = z.object({
   id: z.string().uuid(),
   name: z.string().min(2).max(100),
   email: z.string().email(),
-});
+}).meta({ id: 'Assignee' });
 
 const Subtask = z.object({
   id: z.string().uuid(),
   title: z.string().min(1).max(200),
   completed: z.boolean(),
-});
+}).meta({ id: 'Subtask' });
 
 // =============================================================================
 // MAIN TASK SCHEMA
@@ -34,14 +41,14 @@ const Task = z.object({
   description: z.string().max(2000).optional(),
   status: TaskStatus,
   priority: Priority,
-  dueDate: z.string().optional(),
-  createdAt: z.string(),
-  completedAt: z.string().optional().nullable(),
+  dueDate: z.iso.date().optional(),
+  createdAt: z.iso.datetime(),
+  completedAt: z.iso.datetime().optional().nullable(),
   assignee: Assignee.optional(),
   subtasks: z.array(Subtask).max(20),
-  estimatedHours: z.number().positive().max(100).optional(),
+  estimatedHours: z.number().positive().max(100).multipleOf(0.25).optional(),
   position: z.number().int().min(0),
-});
+}).meta({ id: 'Task' });
 
 // Summary version for list views (lighter weight)
 const TaskSummary = z.object({
@@ -49,31 +56,36 @@ const TaskSummary = z.object({
   title: z.string().min(1).max(200),
   status: TaskStatus,
   priority: Priority,
-  dueDate: z.string().optional(),
-  createdAt: z.string(),
-  completedAt: z.string().optional().nullable(),
+  dueDate: z.iso.date().optional(),
+  createdAt: z.iso.datetime(),
+  completedAt: z.iso.datetime().optional().nullable(),
   subtaskCount: z.number().int().min(0),
   subtaskCompletedCount: z.number().int().min(0),
-  estimatedHours: z.number().positive().max(100).optional(),
+  estimatedHours: z.number().positive().max(100).multipleOf(0.25).optional(),
   position: z.number().int().min(0),
-});
+}).meta({ id: 'TaskSummary' });
 
 // =============================================================================
 // TASK ENDPOINTS
 // =============================================================================
 
+// Endpoints with an http mapping are also served as REST routes by the Go
+// backend, e.g. GET /tasks/{id} binds the path parameter to the input's id.
+
 const task = createEndpoint({
-  // List tasks with optional filtering
+  // List tasks with optional filtering, a page at a time: pass the
+  // nextCursor of a page as the cursor of the next
   list: query({
     input: z.object({
       status: TaskStatus.optional(),
       priority: Priority.optional(),
-      limit: z.number().int().min(1).max(50).optional(),
     }),
     output: z.object({
       tasks: z.array(TaskSummary),
       total: z.number().int().min(0),
     }),
+    http: 'GET /tasks',
+    paginated: true,
   }),
 
   // Get a single task with full details
@@ -82,32 +94,74 @@ const task = createEndpoint({
       id: z.string().uuid(),
     }),
     output: Task,
+    http: 'GET /tasks/{id}',
   }),
 
-  // Create a new task
+  // Create a new task. Titles are trimmed with runs of spaces collapsed,
+  // and descriptions trimmed, before they are validated; titles over 120
+  // characters are accepted with a warning. Tasks created without a
+  // priority are of medium priority, and urgent tasks must be due on some
+  // date.
   create: mutation({
     input: z.object({
-      title: z.string().min(3).max(200),
-      description: z.string().max(2000).optional(),
-      priority: Priority,
-      dueDate: z.string().optional(),
-      estimatedHours: z.number().positive().max(100).optional(),
+      title: z
+        .string()
+        .trim()
+        .min(3)
+        .max(200)
+        .meta({
+          normalize: ['collapseSpaces'],
+          warn: {
+            maxLength: 120,
+            message: 'is over 120 characters and will be truncated in the UI',
+          },
+        }),
+      description: z.string().trim().max(2000).optional(),
+      priority: Priority.default('medium'),
+      dueDate: z.iso
+        .date()
+        .meta({
+          future: true,
+          custom: "workingDay",
+          requiredIf: { field: 'priority', equals: 'urgent' },
+        })
+        .optional(),
+      estimatedHours: z
+        .number()
+        .positive()
+        .max(100)
+        .multipleOf(0.25)
+        .optional(),
     }),
     output: Task,
+    http: 'POST /tasks',
+    event: 'task.created',
   }),
 
   // Update an existing task
   update: mutation({
     input: z.object({
       id: z.string().uuid(),
-      title: z.string().min(1).max(200).optional(),
-      description: z.string().max(2000).optional().nullable(),
+      title: z.string().trim().min(1).max(200).optional(),
+      description: z.string().trim().max(2000).optional().nullable(),
       status: TaskStatus.optional(),
       priority: Priority.optional(),
-      dueDate: z.string().optional().nullable(),
-      estimatedHours: z.number().positive().max(100).optional().nullable(),
+      dueDate: z.iso
+        .date()
+        .meta({ future: true, custom: "workingDay" })
+        .optional()
+        .nullable(),
+      estimatedHours: z
+        .number()
+        .positive()
+        .max(100)
+        .multipleOf(0.25)
+        .optional()
+        .nullable(),
     }),
     output: Task,
+    http: 'PATCH /tasks/{id}',
+    event: 'task.updated',
   }),
 
   // Delete a task
@@ -118,6 +172,18 @@ const task = createEndpoint({
     output: z.object({
       success: z.boolean(),
     }),
+    http: 'DELETE /tasks/{id}',
+  }),
+
+  // Stream task changes as they happen (optionally for a single task)
+  watch: subscription({
+    input: z.object({
+      taskId: z.string().uuid().optional(),
+    }),
+    output: z.object({
+      type: z.enum(['created', 'updated', 'deleted']),
+      taskId: z.string().uuid(),
+    }),
   }),
 });
 
@@ -130,7 +196,7 @@ const subtask = createEndpoint({
   add: mutation({
     input: z.object({
       taskId: z.string().uuid(),
-      title: z.string().min(1).max(200),
+      title: z.string().trim().min(1).max(200),
     }),
     output: Subtask,
   }),
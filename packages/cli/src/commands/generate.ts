@@ -8,8 +8,10 @@ import {
 } from "@xrpckit/sdk";
 import {
   type ModuleConfig,
+  type TargetOptions,
   type XrpcConfig,
   extractModules,
+  extractTargetOptions,
   extractTargets,
   isMultiModule,
   loadConfig,
@@ -191,6 +193,7 @@ async function generateMultiModule(
         targetFilter,
         createSpinner,
         options.output,
+        config,
       );
       console.log(formatBoxFooter());
     } catch (error) {
@@ -217,6 +220,7 @@ async function generateModule(
   targetFilter: string | undefined,
   createSpinner?: SpinnerFunction,
   outputOverride?: string,
+  config: XrpcConfig | null = null,
 ): Promise<void> {
   const input = validatePath(moduleConfig.contract);
   if (!existsSync(input)) {
@@ -290,6 +294,7 @@ async function generateModule(
         input,
         createSpinner,
         moduleName,
+        extractTargetOptions(config, target),
       );
       if (targetSpinner && "succeed" in targetSpinner) {
         targetSpinner.succeed(`Generated ${formatTarget(target)} code`);
@@ -416,6 +421,8 @@ async function generateSingleContract(
           targetBaseDir,
           input,
           createSpinner,
+          undefined,
+          extractTargetOptions(config, target),
         );
         if (targetSpinner && "succeed" in targetSpinner) {
           targetSpinner.succeed(`Generated ${formatTarget(target)} code`);
@@ -465,6 +472,7 @@ async function generateForTarget(
     fail: (msg?: string) => void;
   },
   moduleName?: string,
+  targetOptions: TargetOptions = {},
 ): Promise<void> {
   const generator = getGenerator(target);
   if (!generator) {
@@ -474,14 +482,20 @@ async function generateForTarget(
   // Validate output directory path
   const validatedOutputDir = validatePath(outputDir);
 
-  // All targets output to an 'xrpc' subdirectory
+  // All targets output to an 'xrpc' subdirectory, or the directory option's
   // In multi-module mode with non-default module, add module name subdirectory
-  let targetOutputDir: string;
-  if (moduleName && moduleName !== "default") {
-    targetOutputDir = join(validatedOutputDir, "xrpc", moduleName);
-  } else {
-    targetOutputDir = join(validatedOutputDir, "xrpc");
-  }
+  const directory =
+    typeof targetOptions.directory === "string" && targetOptions.directory
+      ? targetOptions.directory
+      : "xrpc";
+  const subdirectory =
+    moduleName && moduleName !== "default"
+      ? join(directory, moduleName)
+      : directory;
+  const targetOutputDir = resolveSafeOutputPath(
+    validatedOutputDir,
+    subdirectory,
+  );
   await mkdir(targetOutputDir, { recursive: true });
 
   const result = generator.generate({
@@ -490,6 +504,7 @@ async function generateForTarget(
     options: {
       contractPath: inputPath, // Pass contract path for client targets
       packageName: "xrpc",
+      ...targetOptions,
     },
   });
 
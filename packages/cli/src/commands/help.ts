@@ -301,7 +301,10 @@ function showValidateHelp(): void {
   );
   console.log(
     formatBoxLine(
-      formatTreeItem("Endpoint definitions (query/mutation types)", false),
+      formatTreeItem(
+        "Endpoint definitions (query/mutation/subscription types)",
+        false,
+      ),
     ),
   );
   console.log(
@@ -162,10 +162,12 @@ async function validateStructure(filePath: string): Promise<string[]> {
       }
       if (
         !endpoint.type ||
-        (endpoint.type !== "query" && endpoint.type !== "mutation")
+        (endpoint.type !== "query" &&
+          endpoint.type !== "mutation" &&
+          endpoint.type !== "subscription")
       ) {
         errors.push(
-          `Endpoint "${endpoint.fullName}" has invalid type: ${endpoint.type}. Must be "query" or "mutation".`,
+          `Endpoint "${endpoint.fullName}" has invalid type: ${endpoint.type}. Must be "query", "mutation" or "subscription".`,
         );
       }
       if (!endpoint.input) {
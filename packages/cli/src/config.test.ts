@@ -3,6 +3,7 @@ import {
   type ModuleConfig,
   type XrpcConfig,
   extractModules,
+  extractTargetOptions,
   extractTargets,
   isMultiModule,
 } from "./config";
@@ -152,4 +153,41 @@ describe("config", () => {
       expect(Object.keys(modules)).toEqual(["users"]);
     });
   });
+
+  describe("extractTargetOptions", () => {
+    test("extracts the options table of a target", () => {
+      const config: XrpcConfig = {
+        contract: "./contract.ts",
+        "go-server": "./backend",
+        options: {
+          "go-server": { packageName: "api", initialisms: true },
+        },
+      };
+
+      expect(extractTargetOptions(config, "go-server")).toEqual({
+        packageName: "api",
+        initialisms: true,
+      });
+      expect(extractTargetOptions(config, "ts-client")).toEqual({});
+      expect(extractTargets(config)).toEqual({ "go-server": "./backend" });
+      expect(isMultiModule(config)).toBe(false);
+    });
+
+    test("is not a module in multi-module config", () => {
+      const config: XrpcConfig = {
+        users: {
+          contract: "./users/contract.ts",
+          "go-server": "./backend/users",
+        },
+        options: {
+          "go-server": { directory: "api" },
+        },
+      };
+
+      expect(Object.keys(extractModules(config))).toEqual(["users"]);
+      expect(extractTargetOptions(config, "go-server")).toEqual({
+        directory: "api",
+      });
+    });
+  });
 });
@@ -32,18 +32,39 @@ export interface ModuleConfig {
  * contract = "packages/orders-api/contract.ts"
  * go-server = "apps/backend/orders"
  * ```
+ *
+ * Either mode can pass options to a target in an [options.<target>] table:
+ * ```toml
+ * [options.go-server]
+ * packageName = "api"
+ * directory = "internal/api"
+ * initialisms = true
+ * ```
  */
 export interface XrpcConfig {
   /** Path to the contract file (local path or remote like "npm:@myorg/api") - single contract mode */
   contract?: string;
+  /** Target options: key = target name, value = options passed to the target */
+  options?: Record<string, TargetOptions>;
   /** Target outputs or module configs: key = target name or module name, value = output path or module config */
-  [key: string]: string | ModuleConfig | undefined;
+  [key: string]:
+    | string
+    | ModuleConfig
+    | Record<string, TargetOptions>
+    | undefined;
 }
 
+/**
+ * Options of a target. `packageName` (default "xrpc") names the generated
+ * package and `directory` (default "xrpc") the subdirectory of the output
+ * path it is written to; the other options are the target's own.
+ */
+export type TargetOptions = Record<string, unknown>;
+
 const CONFIG_FILENAME = "xrpc.toml";
 
-/** Reserved keys that are not target names */
-const RESERVED_KEYS = new Set(["contract"]);
+/** Reserved keys that are not target or module names */
+const RESERVED_KEYS = new Set(["contract", "options"]);
 
 /**
  * Extract target configurations from the flat config structure.
@@ -64,15 +85,30 @@ export function extractTargets(
   return targets;
 }
 
+/**
+ * Extract the options of a target from the config's [options.<target>] table,
+ * empty if it has none.
+ */
+export function extractTargetOptions(
+  config: XrpcConfig | null,
+  target: string,
+): TargetOptions {
+  const options = config?.options?.[target];
+  return typeof options === "object" && options !== null ? options : {};
+}
+
 /**
  * Check if the config is in multi-module mode.
  * Multi-module mode is detected when there's no top-level contract
- * and at least one value is an object (section).
+ * and at least one value is an object (section) other than [options].
  */
 export function isMultiModule(config: XrpcConfig): boolean {
   return (
     !config.contract &&
-    Object.values(config).some((v) => typeof v === "object" && v !== null)
+    Object.entries(config).some(
+      ([key, v]) =>
+        !RESERVED_KEYS.has(key) && typeof v === "object" && v !== null,
+    )
   );
 }
 
@@ -98,7 +134,11 @@ export function extractModules(
   // Multi-module mode: extract all object values as modules
   const modules: Record<string, ModuleConfig> = {};
   for (const [key, value] of Object.entries(config)) {
-    if (typeof value === "object" && value !== null) {
+    if (
+      !RESERVED_KEYS.has(key) &&
+      typeof value === "object" &&
+      value !== null
+    ) {
       const moduleConfig = value as ModuleConfig;
       if (moduleConfig.contract) {
         modules[key] = moduleConfig;
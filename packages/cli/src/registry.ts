@@ -1,11 +1,15 @@
 import type { Target } from "@xrpckit/sdk";
 import { goTarget } from "@xrpckit/target-go-server";
+import { openapiTarget } from "@xrpckit/target-openapi";
+import { protoTarget } from "@xrpckit/target-proto";
 import { swiftClientTarget } from "@xrpckit/target-swift-client";
 import { tsClientTarget } from "@xrpckit/target-ts-client";
 import { tsServerTarget } from "@xrpckit/target-ts-server";
 
 const generators: Record<string, Target> = {
   "go-server": goTarget,
+  openapi: openapiTarget,
+  proto: protoTarget,
   "swift-client": swiftClientTarget,
   "ts-client": tsClientTarget,
   "ts-server": tsServerTarget,
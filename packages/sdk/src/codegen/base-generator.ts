@@ -28,7 +28,7 @@ export abstract class BaseCodeGenerator {
 
   protected toPascalCase(str: string): string {
     return str
-      .split(/[-_]/)
+      .split(/[-_@]/)
       .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
       .join("");
   }
@@ -1,7 +1,11 @@
 export class CodeWriter {
-  private lines: string[] = [];
+  protected lines: string[] = [];
   private indentLevel = 0;
-  private indentString = "    "; // 4 spaces
+  private indentString: string;
+
+  constructor(indentString = "    ") {
+    this.indentString = indentString;
+  }
 
   // Main methods
   writeLine(text: string): this {
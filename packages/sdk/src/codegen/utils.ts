@@ -1,11 +1,12 @@
 /**
  * Converts a string to PascalCase
- * @param str - The string to convert (e.g., "hello-world" or "hello_world")
- * @returns The PascalCase version (e.g., "HelloWorld")
+ * @param str - The string to convert (e.g., "hello-world", "hello_world" or
+ *   the versioned endpoint name "list@v2")
+ * @returns The PascalCase version (e.g., "HelloWorld" or "ListV2")
  */
 export function toPascalCase(str: string): string {
   return str
-    .split(/[-_]/)
+    .split(/[-_@]/)
     .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
     .join("");
 }
@@ -19,8 +19,8 @@ import { ValidationMapperBase } from "./validation-mapper";
 
 describe("framework types", () => {
   describe("TYPE_KINDS", () => {
-    it("should contain all 11 type kinds", () => {
-      expect(TYPE_KINDS).toHaveLength(11);
+    it("should contain all 12 type kinds", () => {
+      expect(TYPE_KINDS).toHaveLength(12);
       expect(TYPE_KINDS).toContain("object");
       expect(TYPE_KINDS).toContain("array");
       expect(TYPE_KINDS).toContain("primitive");
@@ -32,12 +32,13 @@ describe("framework types", () => {
       expect(TYPE_KINDS).toContain("record");
       expect(TYPE_KINDS).toContain("tuple");
       expect(TYPE_KINDS).toContain("date");
+      expect(TYPE_KINDS).toContain("file");
     });
   });
 
   describe("VALIDATION_KINDS", () => {
-    it("should contain all 13 validation kinds", () => {
-      expect(VALIDATION_KINDS).toHaveLength(13);
+    it("should contain all 35 validation kinds", () => {
+      expect(VALIDATION_KINDS).toHaveLength(35);
       // String validations
       expect(VALIDATION_KINDS).toContain("minLength");
       expect(VALIDATION_KINDS).toContain("maxLength");
@@ -45,15 +46,43 @@ describe("framework types", () => {
       expect(VALIDATION_KINDS).toContain("url");
       expect(VALIDATION_KINDS).toContain("uuid");
       expect(VALIDATION_KINDS).toContain("regex");
+      expect(VALIDATION_KINDS).toContain("datetime");
+      expect(VALIDATION_KINDS).toContain("date");
+      expect(VALIDATION_KINDS).toContain("hostname");
+      expect(VALIDATION_KINDS).toContain("ipv4");
+      expect(VALIDATION_KINDS).toContain("ipv6");
+      expect(VALIDATION_KINDS).toContain("duration");
+      expect(VALIDATION_KINDS).toContain("e164");
       // Number validations
       expect(VALIDATION_KINDS).toContain("min");
       expect(VALIDATION_KINDS).toContain("max");
       expect(VALIDATION_KINDS).toContain("int");
       expect(VALIDATION_KINDS).toContain("positive");
       expect(VALIDATION_KINDS).toContain("negative");
+      expect(VALIDATION_KINDS).toContain("exclusiveMin");
+      expect(VALIDATION_KINDS).toContain("exclusiveMax");
+      expect(VALIDATION_KINDS).toContain("multipleOf");
       // Array validations
       expect(VALIDATION_KINDS).toContain("minItems");
       expect(VALIDATION_KINDS).toContain("maxItems");
+      expect(VALIDATION_KINDS).toContain("uniqueItems");
+      // Date validations
+      expect(VALIDATION_KINDS).toContain("future");
+      expect(VALIDATION_KINDS).toContain("past");
+      // Record validations
+      expect(VALIDATION_KINDS).toContain("minEntries");
+      expect(VALIDATION_KINDS).toContain("maxEntries");
+      // File validations
+      expect(VALIDATION_KINDS).toContain("minSize");
+      expect(VALIDATION_KINDS).toContain("maxSize");
+      expect(VALIDATION_KINDS).toContain("mimeTypes");
+      // Conditional validations
+      expect(VALIDATION_KINDS).toContain("requiredIf");
+      expect(VALIDATION_KINDS).toContain("forbiddenIf");
+      // Warnings
+      expect(VALIDATION_KINDS).toContain("warn");
+      // Custom validations
+      expect(VALIDATION_KINDS).toContain("custom");
     });
   });
 
@@ -108,6 +137,30 @@ describe("framework types", () => {
       const validations = getValidationsForType("array");
       expect(validations).toContain("minItems");
       expect(validations).toContain("maxItems");
+      expect(validations).toContain("uniqueItems");
+    });
+
+    it("should return date validations for date type", () => {
+      expect(getValidationsForType("date")).toEqual(["future", "past"]);
+    });
+
+    it("should return record validations for record type", () => {
+      expect(getValidationsForType("record")).toEqual([
+        "minEntries",
+        "maxEntries",
+      ]);
+    });
+
+    it("should return file validations for file type", () => {
+      expect(getValidationsForType("file")).toEqual([
+        "minSize",
+        "maxSize",
+        "mimeTypes",
+      ]);
+    });
+
+    it("should return size validations for bytes", () => {
+      expect(getValidationsForType("bytes")).toEqual(["minSize", "maxSize"]);
     });
 
     it("should return empty array for unknown types", () => {
@@ -248,6 +301,7 @@ describe("TypeMapperBase", () => {
       record: (ctx) => ({ type: "Record" }),
       tuple: (ctx) => ({ type: "tuple" }),
       date: () => ({ type: "Date" }),
+      file: () => ({ type: "File" }),
     };
   }
 
@@ -333,13 +387,35 @@ describe("ValidationMapperBase", () => {
       url: () => ({ validation: "isURL" }),
       uuid: () => ({ validation: "isUUID" }),
       regex: (ctx) => ({ validation: `matches(${ctx.value})` }),
+      datetime: () => ({ validation: "isDateTime" }),
+      date: () => ({ validation: "isDate" }),
+      hostname: () => ({ validation: "isHostname" }),
+      ipv4: () => ({ validation: "isIPv4" }),
+      ipv6: () => ({ validation: "isIPv6" }),
+      duration: () => ({ validation: "isDuration" }),
+      e164: () => ({ validation: "isE164" }),
       min: (ctx) => ({ validation: `>= ${ctx.value}` }),
       max: (ctx) => ({ validation: `<= ${ctx.value}` }),
       int: () => ({ validation: "isInt" }),
       positive: () => ({ validation: "> 0" }),
       negative: () => ({ validation: "< 0" }),
+      exclusiveMin: (ctx) => ({ validation: `> ${ctx.value}` }),
+      exclusiveMax: (ctx) => ({ validation: `< ${ctx.value}` }),
+      multipleOf: (ctx) => ({ validation: `% ${ctx.value}` }),
       minItems: (ctx) => ({ validation: `items >= ${ctx.value}` }),
       maxItems: (ctx) => ({ validation: `items <= ${ctx.value}` }),
+      uniqueItems: () => ({ validation: "isUnique" }),
+      future: () => ({ validation: "isFuture" }),
+      past: () => ({ validation: "isPast" }),
+      minEntries: (ctx) => ({ validation: `entries >= ${ctx.value}` }),
+      maxEntries: (ctx) => ({ validation: `entries <= ${ctx.value}` }),
+      minSize: (ctx) => ({ validation: `size >= ${ctx.value}` }),
+      maxSize: (ctx) => ({ validation: `size <= ${ctx.value}` }),
+      mimeTypes: (ctx) => ({ validation: `type in ${ctx.value}` }),
+      requiredIf: () => ({ validation: "isRequiredIf" }),
+      forbiddenIf: () => ({ validation: "isForbiddenIf" }),
+      warn: () => ({ validation: "isWarned" }),
+      custom: (ctx) => ({ validation: `${ctx.value}()` }),
     };
   }
 
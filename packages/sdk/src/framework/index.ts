@@ -6,6 +6,10 @@ export {
   STRING_VALIDATIONS,
   NUMBER_VALIDATIONS,
   ARRAY_VALIDATIONS,
+  DATE_VALIDATIONS,
+  RECORD_VALIDATIONS,
+  FILE_VALIDATIONS,
+  BYTES_VALIDATIONS,
   // Type guards
   isTypeKind,
   isValidationKind,
@@ -16,6 +16,7 @@ export const TYPE_KINDS = [
   "record",
   "tuple",
   "date",
+  "file",
 ] as const;
 
 /**
@@ -28,22 +29,50 @@ export type TypeKind = (typeof TYPE_KINDS)[number];
  * Target generators must handle all of these to be considered complete.
  */
 export const VALIDATION_KINDS = [
-  // String validations (6)
+  // String validations (13)
   "minLength",
   "maxLength",
   "email",
   "url",
   "uuid",
   "regex",
-  // Number validations (5)
+  "datetime",
+  "date",
+  "hostname",
+  "ipv4",
+  "ipv6",
+  "duration",
+  "e164",
+  // Number validations (8)
   "min",
   "max",
   "int",
   "positive",
   "negative",
-  // Array validations (2)
+  "exclusiveMin",
+  "exclusiveMax",
+  "multipleOf",
+  // Array validations (3)
   "minItems",
   "maxItems",
+  "uniqueItems",
+  // Date validations (2)
+  "future",
+  "past",
+  // Record validations (2)
+  "minEntries",
+  "maxEntries",
+  // File validations (3)
+  "minSize",
+  "maxSize",
+  "mimeTypes",
+  // Conditional validations (2), on optional fields of any type
+  "requiredIf",
+  "forbiddenIf",
+  // Warnings (1), soft limits on strings, numbers and arrays
+  "warn",
+  // Custom validations (1), on fields of any type
+  "custom",
 ] as const;
 
 /**
@@ -61,6 +90,13 @@ export const STRING_VALIDATIONS: ValidationKind[] = [
   "url",
   "uuid",
   "regex",
+  "datetime",
+  "date",
+  "hostname",
+  "ipv4",
+  "ipv6",
+  "duration",
+  "e164",
 ];
 
 /**
@@ -72,12 +108,47 @@ export const NUMBER_VALIDATIONS: ValidationKind[] = [
   "int",
   "positive",
   "negative",
+  "exclusiveMin",
+  "exclusiveMax",
+  "multipleOf",
 ];
 
 /**
  * Validation kinds that apply to array types.
  */
-export const ARRAY_VALIDATIONS: ValidationKind[] = ["minItems", "maxItems"];
+export const ARRAY_VALIDATIONS: ValidationKind[] = [
+  "minItems",
+  "maxItems",
+  "uniqueItems",
+];
+
+/**
+ * Validation kinds that apply to dates and to strings holding a date or
+ * timestamp.
+ */
+export const DATE_VALIDATIONS: ValidationKind[] = ["future", "past"];
+
+/**
+ * Validation kinds that apply to record (map) types.
+ */
+export const RECORD_VALIDATIONS: ValidationKind[] = [
+  "minEntries",
+  "maxEntries",
+];
+
+/**
+ * Validation kinds that apply to uploaded files.
+ */
+export const FILE_VALIDATIONS: ValidationKind[] = [
+  "minSize",
+  "maxSize",
+  "mimeTypes",
+];
+
+/**
+ * Validation kinds that apply to binary (bytes) fields.
+ */
+export const BYTES_VALIDATIONS: ValidationKind[] = ["minSize", "maxSize"];
 
 /**
  * Context provided when mapping a type.
@@ -263,6 +334,14 @@ export function getValidationsForType(baseType: string): ValidationKind[] {
       return NUMBER_VALIDATIONS;
     case "array":
       return ARRAY_VALIDATIONS;
+    case "date":
+      return DATE_VALIDATIONS;
+    case "record":
+      return RECORD_VALIDATIONS;
+    case "file":
+      return FILE_VALIDATIONS;
+    case "bytes":
+      return BYTES_VALIDATIONS;
     default:
       return [];
   }
@@ -9,7 +9,11 @@ import type {
 } from "./types";
 import {
   ARRAY_VALIDATIONS,
+  BYTES_VALIDATIONS,
+  DATE_VALIDATIONS,
+  FILE_VALIDATIONS,
   NUMBER_VALIDATIONS,
+  RECORD_VALIDATIONS,
   STRING_VALIDATIONS,
   VALIDATION_KINDS,
   isValidationKind,
@@ -148,6 +152,14 @@ export abstract class ValidationMapperBase<V> {
         return NUMBER_VALIDATIONS;
       case "array":
         return ARRAY_VALIDATIONS;
+      case "date":
+        return DATE_VALIDATIONS;
+      case "record":
+        return RECORD_VALIDATIONS;
+      case "file":
+        return FILE_VALIDATIONS;
+      case "bytes":
+        return BYTES_VALIDATIONS;
       default:
         return [];
     }
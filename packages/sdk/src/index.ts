@@ -5,9 +5,14 @@ export type {
   Router,
   EndpointGroup,
   Endpoint,
+  Deprecation,
+  HttpMapping,
   TypeDefinition,
   Property,
   ValidationRules,
+  Normalization,
+  FieldCondition,
+  WarningRules,
   TypeReference,
   MiddlewareDefinition,
 } from "./parser";
@@ -16,6 +21,17 @@ export type {
 export { CodeWriter } from "./codegen/code-writer";
 export { toPascalCase, toCamelCase, toSnakeCase } from "./codegen/utils";
 
+// Schema exports
+export {
+  type JsonSchema,
+  type OpenAPIOptions,
+  typeToJsonSchema,
+  buildOpenAPIDocument,
+  typeToExample,
+  ExampleError,
+  schemaHash,
+} from "./schema";
+
 // Framework exports - for building target generators
 export {
   // Constants
@@ -24,6 +40,10 @@ export {
   STRING_VALIDATIONS,
   NUMBER_VALIDATIONS,
   ARRAY_VALIDATIONS,
+  DATE_VALIDATIONS,
+  RECORD_VALIDATIONS,
+  FILE_VALIDATIONS,
+  BYTES_VALIDATIONS,
   // Type guards
   isTypeKind,
   isValidationKind,
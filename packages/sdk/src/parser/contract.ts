@@ -28,10 +28,41 @@ export interface EndpointGroup {
 
 export interface Endpoint {
   name: string;
-  type: "query" | "mutation";
+  type: "query" | "mutation" | "subscription";
   input: TypeReference;
   output: TypeReference;
   fullName: string; // e.g., "greeting.greet"
+  auth?: "required"; // Calls must be authenticated
+  tenant?: "required"; // Calls must carry a tenant
+  permissions?: string[]; // The caller must hold all of these
+  http?: HttpMapping; // Route in the generated REST layer
+  stream?: string; // Output array field whose items are streamed as sent
+  paginated?: boolean; // Takes cursor and pageSize, returns nextCursor
+  version?: number; // From a versioned name such as "list@v2"
+  deprecated?: Deprecation; // Set when the endpoint is deprecated
+  event?: string; // Event a mutation publishes on success, e.g. "task.created"
+  async?: boolean; // Runs in the background, answering with an operation to poll
+  description?: string; // What the endpoint does, for generated docs
+}
+
+/**
+ * Deprecation of an endpoint, declared with query({ ..., deprecated: ... }).
+ * Every field is optional: deprecated: true gives an empty Deprecation.
+ */
+export interface Deprecation {
+  message?: string; // e.g. "Use task.list@v2."
+  since?: string; // ISO 8601 date it was deprecated on
+  sunset?: string; // ISO 8601 date it will stop being served
+}
+
+/**
+ * REST route of an endpoint, declared with query({ ..., http: "GET /tasks/{id}" }).
+ * Path parameters name top-level input fields.
+ */
+export interface HttpMapping {
+  method: "GET" | "POST" | "PUT" | "PATCH" | "DELETE";
+  path: string; // e.g. "/tasks/{id}"
+  pathParams: string[]; // e.g. ["id"]
 }
 
 export interface TypeDefinition {
@@ -47,16 +78,49 @@ export interface TypeDefinition {
     | "literal"
     | "record"
     | "tuple"
-    | "date";
+    | "date"
+    | "file";
   properties?: Property[];
   elementType?: TypeReference;
   baseType?: string;
   unionTypes?: TypeReference[]; // For union types
+  discriminator?: string; // For discriminated unions, the field naming the variant
   enumValues?: (string | number)[]; // For enum types
   literalValue?: string | number | boolean; // For literal types
-  keyType?: TypeReference; // For record types
-  valueType?: TypeReference; // For record types
+  keyType?: TypeReference; // For record types, with the key rules
+  valueType?: TypeReference; // For record types, with the value rules
   tupleElements?: TypeReference[]; // For tuple types
+  description?: string; // Of objects and enums, from .describe()
+}
+
+// A transform of strings: trimming whitespace, changing case, or collapsing
+// runs of whitespace into single spaces (which trims them too)
+export type Normalization =
+  | "trim"
+  | "lowercase"
+  | "uppercase"
+  | "collapseSpaces";
+
+// A condition on another field of the same object, for requiredIf and
+// forbiddenIf: that it equals a value, that it does not, or, with neither,
+// that it is set
+export interface FieldCondition {
+  field: string;
+  equals?: string | number | boolean;
+  notEquals?: string | number | boolean;
+}
+
+// Soft limits of a field, which servers report as warnings next to the
+// result rather than rejecting the call, with the message to report or a
+// default one
+export interface WarningRules {
+  minLength?: number;
+  maxLength?: number;
+  min?: number;
+  max?: number;
+  minItems?: number;
+  maxItems?: number;
+  message?: string;
 }
 
 export interface ValidationRules {
@@ -67,6 +131,20 @@ export interface ValidationRules {
   url?: boolean;
   uuid?: boolean;
   regex?: string;
+  datetime?: boolean; // RFC 3339 timestamp, e.g. z.iso.datetime()
+  date?: boolean; // Calendar date (YYYY-MM-DD), e.g. z.iso.date()
+  hostname?: boolean; // e.g. .meta({ format: "hostname" })
+  ipv4?: boolean; // e.g. z.ipv4()
+  ipv6?: boolean; // e.g. z.ipv6()
+  duration?: boolean; // ISO 8601 duration such as PT1H30M, e.g. z.iso.duration()
+  e164?: boolean; // Phone number such as +14155550100, e.g. z.e164()
+  // What minLength and maxLength count, set with .meta({ lengthUnit }):
+  // runes (code points, the default), UTF-8 bytes or grapheme clusters
+  lengthUnit?: "bytes" | "runes" | "graphemes";
+  // Transforms servers apply to the decoded string, in order, before it is
+  // validated: from .trim(), .toLowerCase() and .toUpperCase(), then
+  // .meta({ normalize: [...] })
+  normalize?: Normalization[];
 
   // Number validations
   min?: number;
@@ -74,10 +152,48 @@ export interface ValidationRules {
   int?: boolean;
   positive?: boolean;
   negative?: boolean;
+  // Bounds the value must be strictly above or below, from .gt() and .lt()
+  exclusiveMin?: number;
+  exclusiveMax?: number;
+  // From .multipleOf(), e.g. 0.25 for quarter hours
+  multipleOf?: number;
 
   // Array validations
   minItems?: number;
   maxItems?: number;
+  // Set with .meta({ uniqueItems: true }): no item equals another
+  uniqueItems?: boolean;
+
+  // Date validations, set with .meta({ future: true }) or .meta({ past: true })
+  future?: boolean;
+  past?: boolean;
+
+  // Record validations, set with .meta({ minEntries, maxEntries })
+  minEntries?: number;
+  maxEntries?: number;
+
+  // File validations, e.g. z.file().max(5_000_000).mime(["image/png"]); the
+  // sizes also bound bytes, set with z.base64().meta({ maxSize: 65_536 })
+  minSize?: number; // In bytes
+  maxSize?: number; // In bytes
+  mimeTypes?: string[];
+
+  // Conditional validations of optional fields, which must be set, or must
+  // not be, when a condition on another field holds: set with
+  // .meta({ requiredIf: { field: "priority", equals: "urgent" } }) or
+  // .meta({ forbiddenIf: { field: "status", notEquals: "completed" } })
+  requiredIf?: FieldCondition;
+  forbiddenIf?: FieldCondition;
+
+  // Soft limits with severity "warning", set with
+  // .meta({ warn: { maxLength: 120, message: "..." } }): values beyond them
+  // are accepted and reported as warnings
+  warn?: WarningRules;
+
+  // Custom validation, set with .meta({ custom: "workingDay" }): the name of
+  // a validator the server registers for business rules the others can't
+  // express
+  custom?: string;
 }
 
 export interface Property {
@@ -85,6 +201,17 @@ export interface Property {
   type: TypeReference;
   required: boolean;
   validation?: ValidationRules;
+  // Struct tags the Go target adds after the json tag, set with
+  // .meta({ goTags: { db: "due_date", validate: "required" } }) so generated
+  // types work with sqlx, validator or mongo as they are
+  goTags?: Record<string, string>;
+  description?: string; // From .describe(), for generated docs
+  // Set with .meta({ sensitive: true }) on emails, tokens and the like:
+  // targets keep the value out of logs and validation error messages
+  sensitive?: boolean;
+  // From .default(): the value servers give the field when it is absent,
+  // which makes it optional. Only strings, numbers and booleans are kept.
+  default?: string | number | boolean;
 }
 
 export interface TypeReference {
@@ -95,9 +222,11 @@ export interface TypeReference {
   properties?: Property[];
   validation?: ValidationRules;
   unionTypes?: TypeReference[]; // For union types
+  discriminator?: string; // For discriminated unions, the field naming the variant
   enumValues?: (string | number)[]; // For enum types
   literalValue?: string | number | boolean; // For literal types
   keyType?: TypeReference; // For record types
   valueType?: TypeReference; // For record types
   tupleElements?: TypeReference[]; // For tuple types
+  description?: string; // Of objects and enums, from .describe()
 }
@@ -4,15 +4,19 @@ import { dirname, join } from "node:path";
 import type {
   EndpointGroup as CoreEndpointGroup,
   EndpointDefinition,
+  Deprecation as DeprecationDefinition,
   RouterDefinition,
 } from "xrpckit";
 import { getRouterMiddleware } from "xrpckit";
 import type {
   ContractDefinition,
+  Deprecation,
   Endpoint,
   EndpointGroup,
+  HttpMapping,
   Router,
   TypeDefinition,
+  TypeReference,
 } from "./contract";
 import { extractTypeInfo, generateTypeName } from "./zod-extractor";
 
@@ -22,9 +26,14 @@ export type {
   Router,
   EndpointGroup,
   Endpoint,
+  Deprecation,
+  HttpMapping,
   TypeDefinition,
   Property,
   ValidationRules,
+  Normalization,
+  FieldCondition,
+  WarningRules,
   TypeReference,
   MiddlewareDefinition,
 } from "./contract";
@@ -174,6 +183,10 @@ function buildContractDefinition(
   const routers: Router[] = [];
   const endpoints: Endpoint[] = [];
   const typeMap = new Map<string, TypeDefinition>();
+  // REST routes by method and path shape, to reject duplicates
+  const httpRoutes = new Map<string, string>();
+  // Endpoints by the event they publish, to reject duplicates
+  const events = new Map<string, string>();
 
   // Validate router structure
   if (!routerDef || typeof routerDef !== "object") {
@@ -212,21 +225,24 @@ function buildContractDefinition(
     for (const [endpointName, endpointDef] of Object.entries(groupDef)) {
       const fullName = `${groupName}.${endpointName}`;
       const epDef = endpointDef as EndpointDefinition;
+      const version = parseVersion(fullName, endpointName);
 
       // Validate endpoint definition
       if (!epDef || typeof epDef !== "object") {
         throw new Error(
-          `Invalid endpoint "${fullName}". Endpoints must be created with query({ ... }) or mutation({ ... }).`,
+          `Invalid endpoint "${fullName}". Endpoints must be created with query({ ... }), mutation({ ... }) or subscription({ ... }).`,
         );
       }
 
       if (
         !epDef.type ||
-        (epDef.type !== "query" && epDef.type !== "mutation")
+        (epDef.type !== "query" &&
+          epDef.type !== "mutation" &&
+          epDef.type !== "subscription")
       ) {
         throw new Error(
           `Invalid endpoint type for "${fullName}". ` +
-            `Type must be "query" or "mutation", got: ${epDef.type}`,
+            `Type must be "query", "mutation" or "subscription", got: ${epDef.type}`,
         );
       }
 
@@ -244,27 +260,35 @@ function buildContractDefinition(
         );
       }
 
+      let endpoint: Endpoint;
       try {
-        // Extract input type from actual Zod schema
+        // Extract input type from actual Zod schema. Named types it uses
+        // are added first so they come before the types that reference them.
         const inputType = extractTypeInfo(epDef.input);
-        const inputTypeName = `${generateTypeName(groupName, endpointName)}Input`;
+        // Versioned names such as "list@v2" give types such as TaskListV2Input
+        const typeName = generateTypeName(
+          groupName,
+          endpointName.replace("@v", "V"),
+        );
+        const inputTypeName = `${typeName}Input`;
+        addNamedTypes(typeMap, inputType);
         addTypeDefinition(typeMap, inputTypeName, inputType);
 
         // Extract output type from actual Zod schema
         const outputType = extractTypeInfo(epDef.output);
-        const outputTypeName = `${generateTypeName(groupName, endpointName)}Output`;
+        const outputTypeName = `${typeName}Output`;
+        addNamedTypes(typeMap, outputType);
         addTypeDefinition(typeMap, outputTypeName, outputType);
 
-        const endpoint: Endpoint = {
+        // Endpoint types keep their per-method names even when the schema is
+        // a named type, so handler signatures don't depend on schema ids
+        endpoint = {
           name: endpointName,
           type: epDef.type,
-          input: { name: inputTypeName, ...inputType },
-          output: { name: outputTypeName, ...outputType },
+          input: { ...inputType, name: inputTypeName },
+          output: { ...outputType, name: outputTypeName },
           fullName,
         };
-
-        endpointGroup.endpoints.push(endpoint);
-        endpoints.push(endpoint);
       } catch (error) {
         if (error instanceof Error) {
           throw new Error(
@@ -273,6 +297,59 @@ function buildContractDefinition(
         }
         throw error;
       }
+
+      if (epDef.auth === "required") {
+        endpoint.auth = "required";
+      }
+      if (epDef.tenant === "required") {
+        endpoint.tenant = "required";
+      }
+      if (epDef.permissions && epDef.permissions.length > 0) {
+        endpoint.permissions = [...epDef.permissions];
+      }
+      if (epDef.http) {
+        endpoint.http = parseHttpMapping(endpoint, epDef.http);
+        const { method, path } = endpoint.http;
+        const route = `${method} ${path.replace(/\{[^}]*\}/g, "{}")}`;
+        const existing = httpRoutes.get(route);
+        if (existing) {
+          throw new Error(
+            `Endpoint "${fullName}" maps to the same route as "${existing}": ${epDef.http}`,
+          );
+        }
+        httpRoutes.set(route, fullName);
+      }
+      if (epDef.stream) {
+        endpoint.stream = parseStreamField(endpoint, epDef.stream);
+      }
+      if (epDef.paginated) {
+        endpoint.paginated = true;
+      }
+      if (version) {
+        endpoint.version = version;
+      }
+      if (epDef.deprecated) {
+        endpoint.deprecated = parseDeprecation(fullName, epDef.deprecated);
+      }
+      if (epDef.event !== undefined) {
+        endpoint.event = parseEvent(endpoint, epDef.event);
+        const existing = events.get(endpoint.event);
+        if (existing) {
+          throw new Error(
+            `Endpoint "${fullName}" publishes the same event as "${existing}": ${endpoint.event}`,
+          );
+        }
+        events.set(endpoint.event, fullName);
+      }
+      if (epDef.async) {
+        endpoint.async = parseAsync(endpoint);
+      }
+      if (epDef.description?.trim()) {
+        endpoint.description = epDef.description.trim();
+      }
+
+      endpointGroup.endpoints.push(endpoint);
+      endpoints.push(endpoint);
     }
 
     if (endpointGroup.endpoints.length === 0) {
@@ -302,6 +379,190 @@ function buildContractDefinition(
   };
 }
 
+const HTTP_METHODS = ["GET", "POST", "PUT", "PATCH", "DELETE"] as const;
+
+/**
+ * Parses an endpoint's REST mapping such as "GET /tasks/{id}", checking that
+ * each path parameter names a top-level input field.
+ */
+function parseHttpMapping(endpoint: Endpoint, mapping: string): HttpMapping {
+  const { fullName, input } = endpoint;
+  if (endpoint.type === "subscription") {
+    throw new Error(
+      `Endpoint "${fullName}" is a subscription; only queries and mutations can declare an http mapping.`,
+    );
+  }
+
+  const match = /^([A-Z]+)\s+(\/\S*)$/.exec(mapping.trim());
+  const method = HTTP_METHODS.find((m) => m === match?.[1]);
+  if (!match || !method) {
+    throw new Error(
+      `Invalid http mapping for "${fullName}": ${mapping}. ` +
+        `Use "<METHOD> /path/{param}" with one of ${HTTP_METHODS.join(", ")}.`,
+    );
+  }
+  if (endpoint.type === "mutation" && method === "GET") {
+    throw new Error(
+      `Invalid http mapping for "${fullName}": ${mapping}. ` +
+        "Mutations change state, so they cannot be mapped to GET.",
+    );
+  }
+
+  const path = match[2];
+  const pathParams: string[] = [];
+  for (const segment of path.split("/").slice(1)) {
+    const param = /^\{([A-Za-z_][A-Za-z0-9_]*)\}$/.exec(segment)?.[1];
+    if (param) {
+      pathParams.push(param);
+    } else if (/[{}]/.test(segment)) {
+      throw new Error(
+        `Invalid http mapping for "${fullName}": ${mapping}. ` +
+          "Path parameters must be whole segments, e.g. /tasks/{id}.",
+      );
+    }
+  }
+
+  const fields = new Set(
+    input.kind === "object"
+      ? (input.properties ?? []).map((prop) => prop.name)
+      : [],
+  );
+  for (const param of pathParams) {
+    if (!fields.has(param)) {
+      throw new Error(
+        `Invalid http mapping for "${fullName}": path parameter "${param}" is not an input field.`,
+      );
+    }
+  }
+
+  return { method, path, pathParams };
+}
+
+/**
+ * Checks the output field an endpoint streams: a required array field of
+ * its object output.
+ */
+function parseStreamField(endpoint: Endpoint, field: string): string {
+  const { fullName, output } = endpoint;
+  if (endpoint.type === "subscription") {
+    throw new Error(
+      `Endpoint "${fullName}" is a subscription; its events are already streamed, so it cannot declare stream.`,
+    );
+  }
+
+  const prop =
+    output.kind === "object"
+      ? output.properties?.find((prop) => prop.name === field)
+      : undefined;
+  if (!prop || !prop.required || prop.type.kind !== "array") {
+    throw new Error(
+      `Invalid stream for "${fullName}": "${field}" is not a required array field of the output. ` +
+        `Use an output such as z.object({ ${field}: z.array(...) }).`,
+    );
+  }
+
+  return field;
+}
+
+/**
+ * Checks the event a mutation publishes: dot-separated lowercase words such
+ * as "task.created".
+ */
+function parseEvent(endpoint: Endpoint, event: string): string {
+  const { fullName } = endpoint;
+  if (endpoint.type !== "mutation") {
+    throw new Error(
+      `Endpoint "${fullName}" is a ${endpoint.type}; only mutations can publish events.`,
+    );
+  }
+  if (endpoint.stream) {
+    throw new Error(
+      `Endpoint "${fullName}" streams its output, so it cannot publish an event.`,
+    );
+  }
+  if (!/^[a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)+$/.test(event)) {
+    throw new Error(
+      `Invalid event for "${fullName}": "${event}". Use dot-separated words such as "task.created".`,
+    );
+  }
+  return event;
+}
+
+/**
+ * Checks that an endpoint declaring async is a mutation answered with a
+ * single result, which the operation holds once it is done.
+ */
+function parseAsync(endpoint: Endpoint): true {
+  const { fullName } = endpoint;
+  if (endpoint.type !== "mutation") {
+    throw new Error(
+      `Endpoint "${fullName}" is a ${endpoint.type}; only mutations can be async.`,
+    );
+  }
+  if (endpoint.stream) {
+    throw new Error(
+      `Endpoint "${fullName}" streams its output, so it cannot be async.`,
+    );
+  }
+  return true;
+}
+
+/**
+ * Returns the version of a versioned endpoint name such as "list@v2", or
+ * undefined for names without one.
+ */
+function parseVersion(
+  fullName: string,
+  endpointName: string,
+): number | undefined {
+  if (!endpointName.includes("@")) {
+    return undefined;
+  }
+  const match = /^[A-Za-z_$][\w$]*@v([1-9][0-9]*)$/.exec(endpointName);
+  if (!match) {
+    throw new Error(
+      `Invalid endpoint name "${fullName}". Versioned endpoints are named like "list@v2".`,
+    );
+  }
+  return Number(match[1]);
+}
+
+/**
+ * Normalizes an endpoint's deprecated option, checking its dates.
+ */
+function parseDeprecation(
+  fullName: string,
+  deprecated: true | string | DeprecationDefinition,
+): Deprecation {
+  if (deprecated === true) {
+    return {};
+  }
+  if (typeof deprecated === "string") {
+    return { message: deprecated };
+  }
+
+  const deprecation: Deprecation = {};
+  if (deprecated.message) {
+    deprecation.message = deprecated.message;
+  }
+  for (const field of ["since", "sunset"] as const) {
+    const date = deprecated[field];
+    if (date === undefined) {
+      continue;
+    }
+    if (
+      !/^\d{4}-\d{2}-\d{2}$/.test(date) ||
+      Number.isNaN(Date.parse(`${date}T00:00:00Z`))
+    ) {
+      throw new Error(
+        `Invalid deprecated.${field} for "${fullName}": ${date}. Use an ISO 8601 date such as "2025-06-30".`,
+      );
+    }
+    deprecation[field] = date;
+  }
+  return deprecation;
+}
+
 function addTypeDefinition(
   typeMap: Map<string, TypeDefinition>,
   name: string,
@@ -318,25 +579,45 @@ function addTypeDefinition(
     elementType: typeRef.elementType,
     baseType: typeRef.baseType,
     unionTypes: typeRef.unionTypes,
+    discriminator: typeRef.discriminator,
     enumValues: typeRef.enumValues,
     literalValue: typeRef.literalValue,
     keyType: typeRef.keyType,
     valueType: typeRef.valueType,
     tupleElements: typeRef.tupleElements,
+    description: typeRef.description,
   };
 
   typeMap.set(name, typeDef);
+}
 
-  // Recursively add nested types
-  if (typeRef.properties) {
-    for (const prop of typeRef.properties) {
-      if (prop.type.name && !typeMap.has(prop.type.name)) {
-        addTypeDefinition(typeMap, prop.type.name, prop.type);
-      }
+/**
+ * Adds every named type reachable from typeRef (including typeRef itself) to
+ * the type map, so a schema shared by several endpoints is defined once.
+ */
+function addNamedTypes(
+  typeMap: Map<string, TypeDefinition>,
+  typeRef: TypeReference,
+): void {
+  const nested = [
+    ...(typeRef.properties ?? []).map((prop) => prop.type),
+    typeof typeRef.baseType === "object" ? typeRef.baseType : undefined,
+    typeRef.elementType,
+    typeRef.valueType,
+    ...(typeRef.unionTypes ?? []),
+    ...(typeRef.tupleElements ?? []),
+  ];
+
+  if (typeRef.name) {
+    if (typeMap.has(typeRef.name)) {
+      return;
     }
+    addTypeDefinition(typeMap, typeRef.name, typeRef);
   }
 
-  if (typeRef.elementType?.name && !typeMap.has(typeRef.elementType.name)) {
-    addTypeDefinition(typeMap, typeRef.elementType.name, typeRef.elementType);
+  for (const child of nested) {
+    if (child) {
+      addNamedTypes(typeMap, child);
+    }
   }
 }
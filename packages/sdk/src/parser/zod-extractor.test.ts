@@ -72,6 +72,20 @@ describe("extractValidationRules", () => {
       expect(rules?.maxLength).toBe(50);
       expect(rules?.email).toBe(true);
     });
+
+    test("extracts network, duration and phone formats without their patterns", () => {
+      expect(extractValidationRules(z.ipv4())).toEqual({ ipv4: true });
+      expect(extractValidationRules(z.ipv6())).toEqual({ ipv6: true });
+      expect(extractValidationRules(z.iso.duration())).toEqual({
+        duration: true,
+      });
+      expect(extractValidationRules(z.e164())).toEqual({ e164: true });
+      const hostname = z.string().max(253).meta({ format: "hostname" });
+      expect(extractValidationRules(hostname)).toEqual({
+        maxLength: 253,
+        hostname: true,
+      });
+    });
   });
 
   describe("Number validations", () => {
@@ -113,10 +127,45 @@ describe("extractValidationRules", () => {
       const rules = extractValidationRules(schema);
 
       expect(rules).toBeDefined();
-      // Note: When .int() is used, Zod v4's toJSONSchema() resets min/max to safe integer bounds
-      // So we can't extract the original min/max values, only the int constraint
+      // Zod's JSON schema has safe integer bounds here, the checks keep these
+      expect(rules?.int).toBe(true);
+      expect(rules?.min).toBe(18);
+      expect(rules?.max).toBe(120);
+    });
+
+    test("extracts positive and negative", () => {
+      expect(extractValidationRules(z.number().positive())?.positive).toBe(
+        true,
+      );
+      expect(extractValidationRules(z.number().negative())?.negative).toBe(
+        true,
+      );
+      expect(extractValidationRules(z.number().nonnegative())?.min).toBe(0);
+    });
+
+    test("extracts exclusive bounds", () => {
+      const rules = extractValidationRules(z.number().gt(1).lt(10));
+
+      expect(rules?.exclusiveMin).toBe(1);
+      expect(rules?.exclusiveMax).toBe(10);
+      expect(rules?.min).toBeUndefined();
+      expect(rules?.max).toBeUndefined();
+    });
+
+    test("extracts multipleOf", () => {
+      const rules = extractValidationRules(z.number().min(0).multipleOf(0.25));
+
+      expect(rules?.multipleOf).toBe(0.25);
+      expect(rules?.min).toBe(0);
+    });
+
+    test("extracts the bounds of integer formats", () => {
+      const rules = extractValidationRules(z.int32());
+
       expect(rules?.int).toBe(true);
-      // min/max may be undefined or set to safe integer bounds when int() is used
+      expect(rules?.min).toBe(-2147483648);
+      expect(rules?.max).toBe(2147483647);
+      expect(extractValidationRules(z.int())?.min).toBeUndefined();
     });
   });
 
@@ -147,6 +196,213 @@ describe("extractValidationRules", () => {
     });
   });
 
+  describe("Date validations", () => {
+    test("extracts datetime and date formats without their patterns", () => {
+      const datetime = extractValidationRules(z.iso.datetime());
+      expect(datetime).toEqual({ datetime: true });
+
+      const date = extractValidationRules(z.iso.date());
+      expect(date).toEqual({ date: true });
+    });
+
+    test("extracts future and past from metadata", () => {
+      const schema = z.iso.date().meta({ future: true }).optional();
+      expect(extractValidationRules(schema)).toEqual({
+        future: true,
+        date: true,
+      });
+
+      expect(extractValidationRules(z.date().meta({ past: true }))).toEqual({
+        past: true,
+      });
+    });
+
+    test("extracts string formats as strings", () => {
+      expect(extractTypeInfo(z.iso.datetime())).toEqual({
+        kind: "primitive",
+        baseType: "string",
+      });
+    });
+  });
+
+  describe("Array validations", () => {
+    test("extracts unique items from metadata", () => {
+      const schema = z
+        .array(z.string())
+        .max(10)
+        .meta({ uniqueItems: true });
+      expect(extractValidationRules(schema)).toEqual({
+        maxItems: 10,
+        uniqueItems: true,
+      });
+    });
+
+    test("attaches item rules to the element type", () => {
+      const schema = z.array(
+        z
+          .string()
+          .min(1)
+          .max(30)
+          .regex(/^[a-z]+$/),
+      );
+
+      const typeInfo = extractTypeInfo(schema);
+
+      expect(typeInfo.elementType?.validation).toEqual({
+        minLength: 1,
+        maxLength: 30,
+        regex: "^[a-z]+$",
+      });
+    });
+  });
+
+  describe("Record validations", () => {
+    test("extracts entry counts from metadata", () => {
+      const schema = z
+        .record(z.string(), z.string())
+        .meta({ minEntries: 1, maxEntries: 20 })
+        .optional();
+      expect(extractValidationRules(schema)).toEqual({
+        minEntries: 1,
+        maxEntries: 20,
+      });
+    });
+
+    test("attaches key and value rules to the record types", () => {
+      const schema = z.record(
+        z.string().regex(/^[a-z]+$/),
+        z.string().max(100),
+      );
+
+      const typeInfo = extractTypeInfo(schema);
+
+      expect(typeInfo.kind).toBe("record");
+      expect(typeInfo.keyType?.validation?.regex).toBe("^[a-z]+$");
+      expect(typeInfo.valueType?.validation).toEqual({ maxLength: 100 });
+    });
+  });
+
+  describe("File validations", () => {
+    test("extracts sizes and media types", () => {
+      const schema = z
+        .file()
+        .min(1)
+        .max(5_000_000)
+        .mime(["image/png", "image/jpeg"])
+        .optional();
+      expect(extractValidationRules(schema)).toEqual({
+        minSize: 1,
+        maxSize: 5_000_000,
+        mimeTypes: ["image/png", "image/jpeg"],
+      });
+    });
+
+    test("extracts files as the file kind", () => {
+      expect(extractTypeInfo(z.file())).toEqual({
+        kind: "file",
+        baseType: "file",
+      });
+      expect(extractValidationRules(z.file())).toBeUndefined();
+    });
+  });
+
+  describe("Bytes", () => {
+    test("extracts base64 strings as bytes", () => {
+      expect(extractTypeInfo(z.base64())).toEqual({
+        kind: "primitive",
+        baseType: "bytes",
+      });
+      expect(extractValidationRules(z.base64())).toBeUndefined();
+    });
+
+    test("extracts sizes from metadata, not string lengths", () => {
+      const schema = z.base64().max(100).meta({ maxSize: 65_536 }).optional();
+      expect(extractValidationRules(schema)).toEqual({ maxSize: 65_536 });
+    });
+  });
+
+  describe("String length units", () => {
+    test("extracts the length unit from metadata", () => {
+      const schema = z.string().max(200).meta({ lengthUnit: "graphemes" });
+      const rules = extractValidationRules(schema);
+
+      expect(rules?.maxLength).toBe(200);
+      expect(rules?.lengthUnit).toBe("graphemes");
+    });
+
+    test("ignores unknown length units", () => {
+      const schema = z.string().max(200).meta({ lengthUnit: "words" });
+      expect(extractValidationRules(schema)?.lengthUnit).toBeUndefined();
+    });
+  });
+
+  describe("String normalizations", () => {
+    test("extracts Zod transforms, then those of metadata, in order", () => {
+      const schema = z
+        .string()
+        .trim()
+        .toLowerCase()
+        .min(3)
+        .meta({ normalize: ["collapseSpaces", "shout"] });
+      expect(extractValidationRules(schema)).toEqual({
+        minLength: 3,
+        normalize: ["trim", "lowercase", "collapseSpaces"],
+      });
+    });
+
+    test("extracts the transforms of array items", () => {
+      const schema = z.array(z.string().toUpperCase());
+      expect(extractTypeInfo(schema).elementType?.validation).toEqual({
+        normalize: ["uppercase"],
+      });
+    });
+  });
+
+  describe("Custom validations", () => {
+    test("extracts the validator name from metadata", () => {
+      const schema = z.iso
+        .date()
+        .meta({ future: true, custom: "workingDay" })
+        .optional();
+      const rules = extractValidationRules(schema);
+
+      expect(rules?.future).toBe(true);
+      expect(rules?.custom).toBe("workingDay");
+    });
+  });
+
+  describe("Warnings", () => {
+    test("extracts soft limits with their message", () => {
+      const schema = z
+        .string()
+        .max(200)
+        .meta({ warn: { maxLength: 120, message: "is truncated in lists" } });
+      const rules = extractValidationRules(schema);
+
+      expect(rules?.maxLength).toBe(200);
+      expect(rules?.warn).toEqual({
+        maxLength: 120,
+        message: "is truncated in lists",
+      });
+      const tags = z.array(z.string()).meta({ warn: { maxItems: 5 } });
+      expect(extractValidationRules(tags)?.warn).toEqual({ maxItems: 5 });
+    });
+
+    test("rejects limits the value cannot have", () => {
+      expect(() =>
+        extractValidationRules(
+          z.number().meta({ warn: { max: 5, maxLength: 5 } }),
+        ),
+      ).toThrow("warn cannot set maxLength");
+      expect(() =>
+        extractValidationRules(z.boolean().meta({ warn: { max: 1 } })),
+      ).toThrow("warn must set soft limits");
+      expect(() =>
+        extractValidationRules(z.string().meta({ warn: { message: "x" } })),
+      ).toThrow("warn must set soft limits");
+    });
+  });
+
   describe("Optional and nullable handling", () => {
     test("extracts rules from optional string", () => {
       const schema = z.string().min(1).max(100).optional();
@@ -227,8 +483,9 @@ describe("extractTypeInfo with validation", () => {
     const ageProp = typeInfo.properties?.[0];
     expect(ageProp?.name).toBe("age");
     expect(ageProp?.validation).toBeDefined();
-    // Note: When .int() is used, min/max may not be extractable via JSON schema
     expect(ageProp?.validation?.int).toBe(true);
+    expect(ageProp?.validation?.min).toBe(18);
+    expect(ageProp?.validation?.max).toBe(120);
   });
 
   test("attaches validation rules to number property without int", () => {
@@ -245,6 +502,158 @@ describe("extractTypeInfo with validation", () => {
     expect(ageProp?.validation?.max).toBe(120);
   });
 
+  test("attaches Go struct tags from metadata", () => {
+    const schema = z.object({
+      dueDate: z
+        .string()
+        .meta({ goTags: { db: "due_date", validate: "omitempty,len=10" } })
+        .optional(),
+      title: z.string(),
+    });
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.properties?.[0]?.goTags).toEqual({
+      db: "due_date",
+      validate: "omitempty,len=10",
+    });
+    expect(typeInfo.properties?.[1]?.goTags).toBeUndefined();
+  });
+
+  test("marks properties declared sensitive in metadata", () => {
+    const schema = z.object({
+      email: z.email().meta({ sensitive: true }),
+      token: z.string().optional().meta({ sensitive: true }),
+      title: z.string(),
+    });
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.properties?.[0]?.sensitive).toBe(true);
+    expect(typeInfo.properties?.[1]?.sensitive).toBe(true);
+    expect(typeInfo.properties?.[2]?.sensitive).toBeUndefined();
+  });
+
+  test("keeps descriptions of objects, enums and properties", () => {
+    const schema = z
+      .object({
+        dueDate: z.string().describe("  When the task is due. ").optional(),
+        status: z.enum(["open", "done"]).describe("Progress of the task."),
+        title: z.string(),
+      })
+      .describe("A task to do.");
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.description).toBe("A task to do.");
+    expect(typeInfo.properties?.[0]?.description).toBe("When the task is due.");
+    expect(typeInfo.properties?.[1]?.type.description).toBe(
+      "Progress of the task.",
+    );
+    expect(typeInfo.properties?.[2]?.description).toBeUndefined();
+  });
+
+  test("makes properties with defaults optional, keeping their rules", () => {
+    const schema = z.object({
+      limit: z.number().int().min(1).default(20),
+      priority: z.enum(["low", "medium"]).default("medium").optional(),
+      tags: z.array(z.string()).default([]),
+    });
+
+    const typeInfo = extractTypeInfo(schema);
+
+    const [limit, priority, tags] = typeInfo.properties ?? [];
+    expect(limit?.required).toBe(false);
+    expect(limit?.type).toEqual({
+      kind: "optional",
+      baseType: { kind: "primitive", baseType: "number" },
+    });
+    expect(limit?.validation).toEqual({ int: true, min: 1 });
+    expect(limit?.default).toBe(20);
+    expect(priority?.required).toBe(false);
+    expect(priority?.default).toBe("medium");
+    // Only strings, numbers and booleans are kept
+    expect(tags?.required).toBe(false);
+    expect(tags?.default).toBeUndefined();
+  });
+
+  test("extracts the conditions of optional fields", () => {
+    const schema = z.object({
+      status: z.enum(["pending", "completed"]),
+      priority: z.enum(["low", "urgent"]).optional(),
+      dueDate: z
+        .string()
+        .optional()
+        .meta({ requiredIf: { field: "priority", equals: "urgent" } }),
+      completedAt: z
+        .string()
+        .nullable()
+        .meta({ forbiddenIf: { field: "status", notEquals: "completed" } }),
+    });
+
+    const [, , dueDate, completedAt] = extractTypeInfo(schema).properties ?? [];
+
+    expect(dueDate?.validation?.requiredIf).toEqual({
+      field: "priority",
+      equals: "urgent",
+    });
+    expect(completedAt?.validation?.forbiddenIf).toEqual({
+      field: "status",
+      notEquals: "completed",
+    });
+  });
+
+  test("rejects conditions its object cannot check", () => {
+    const object = (condition: unknown, other: z.ZodType, optional = true) =>
+      z.object({
+        a: (optional ? z.string().optional() : z.string()).meta({
+          requiredIf: condition,
+        }),
+        b: other,
+      });
+
+    expect(() =>
+      extractTypeInfo(object({ field: "b" }, z.string().optional(), false)),
+    ).toThrow("needs an optional or nullable field");
+    expect(() => extractTypeInfo(object({ field: "c" }, z.string()))).toThrow(
+      'refers to "c", which is not another field of its object',
+    );
+    expect(() =>
+      extractTypeInfo(object({ field: "b", equals: "x" }, z.enum(["y"]))),
+    ).toThrow('compares "b" with "x", which it cannot hold');
+    expect(() => extractTypeInfo(object({ field: "b" }, z.string()))).toThrow(
+      'depends on "b" being set, which it always is',
+    );
+    expect(() =>
+      extractTypeInfo(
+        object({ field: "b", equals: 1, notEquals: 2 }, z.number()),
+      ),
+    ).toThrow("requiredIf must name a field");
+  });
+
+  test("rejects Go struct tags replacing the json tag", () => {
+    const schema = z.object({
+      title: z.string().meta({ goTags: { json: "name" } }),
+    });
+
+    expect(() => extractTypeInfo(schema)).toThrow("the json tag is generated");
+  });
+
+  test("keeps the discriminator of discriminated unions", () => {
+    const schema = z.discriminatedUnion("kind", [
+      z.object({ kind: z.literal("a"), n: z.number() }),
+      z.object({ kind: z.literal("b") }),
+    ]);
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.kind).toBe("union");
+    expect(typeInfo.discriminator).toBe("kind");
+    expect(typeInfo.unionTypes?.length).toBe(2);
+    const plain = extractTypeInfo(z.union([z.string(), z.number()]));
+    expect(plain).not.toHaveProperty("discriminator");
+  });
+
   test("attaches validation rules to optional property", () => {
     const schema = z.object({
       email: z.string().email().optional(),
@@ -295,12 +704,64 @@ describe("extractTypeInfo with validation", () => {
     expect(emailProp?.validation?.email).toBe(true);
 
     const ageProp = typeInfo.properties?.find((p) => p.name === "age");
-    // Note: When .int() is used with min/max, Zod v4 resets bounds in JSON schema
-    // So we can only reliably extract the int constraint
     expect(ageProp?.validation?.int).toBe(true);
+    expect(ageProp?.validation?.min).toBe(18);
 
     const tagsProp = typeInfo.properties?.find((p) => p.name === "tags");
     expect(tagsProp?.type.validation?.minItems).toBe(1);
     expect(tagsProp?.type.validation?.maxItems).toBe(10);
   });
+
+  test("extracts the value type of records", () => {
+    const schema = z.record(z.string(), z.object({ count: z.number() }));
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.kind).toBe("record");
+    expect(typeInfo.valueType?.kind).toBe("object");
+    expect(typeInfo.valueType?.properties?.[0]?.name).toBe("count");
+  });
+
+  test("names objects registered with an id", () => {
+    const Subtask = z
+      .object({ title: z.string() })
+      .meta({ id: "ExtractorSubtask" });
+    const schema = z.object({ subtasks: z.array(Subtask) });
+
+    const typeInfo = extractTypeInfo(schema);
+
+    expect(typeInfo.name).toBeUndefined();
+    expect(typeInfo.properties?.[0]?.type.elementType?.name).toBe(
+      "ExtractorSubtask",
+    );
+  });
+
+  test("refers to recursive schemas by name", () => {
+    const Comment = z
+      .object({
+        body: z.string(),
+        get replies() {
+          return z.array(Comment);
+        },
+      })
+      .meta({ id: "ExtractorComment" });
+
+    const typeInfo = extractTypeInfo(Comment);
+
+    expect(typeInfo.name).toBe("ExtractorComment");
+    expect(typeInfo.properties?.[1]?.type.elementType).toEqual({
+      name: "ExtractorComment",
+      kind: "object",
+    });
+  });
+
+  test("rejects recursive schemas without a name", () => {
+    const Node = z.object({
+      get children(): z.ZodArray<typeof Node> {
+        return z.array(Node);
+      },
+    });
+
+    expect(() => extractTypeInfo(Node)).toThrow(".meta({ id })");
+  });
 });
@@ -4,6 +4,7 @@ import type {
   ZodBoolean,
   ZodDate,
   ZodEnum,
+  ZodFile,
   ZodLiteral,
   ZodNumber,
   ZodObject,
@@ -15,15 +16,25 @@ import type {
   ZodUnion,
 } from "zod";
 import type {
+  FieldCondition,
+  Normalization,
   Property,
   TypeDefinition,
   TypeReference,
   ValidationRules,
+  WarningRules,
 } from "./contract";
 
-// Safe integer bounds for JavaScript (used when Zod .int() is applied)
-const SAFE_INTEGER_MIN = Number.MIN_SAFE_INTEGER;
-const SAFE_INTEGER_MAX = Number.MAX_SAFE_INTEGER;
+// JSON Schema formats that targets check with parsers of their own, each
+// the name of its rule. Hostnames have no Zod schema, so they are set with
+// .meta({ format: "hostname" }).
+const STRING_FORMATS = [
+  "hostname",
+  "ipv4",
+  "ipv6",
+  "duration",
+  "e164",
+] as const;
 
 export function extractValidationRules(
   schema: ZodType,
@@ -31,36 +42,120 @@ export function extractValidationRules(
   const rules: ValidationRules = {};
   let hasRules = false;
 
-  // Unwrap optional/nullable to get to the base schema
+  // Unwrap optional/nullable/default to get to the base schema
   // Handle chained optional/nullable correctly
   let baseSchema: ZodType = schema;
   while (
     baseSchema instanceof z.ZodOptional ||
-    baseSchema instanceof z.ZodNullable
+    baseSchema instanceof z.ZodNullable ||
+    baseSchema instanceof z.ZodDefault
   ) {
-    if (baseSchema instanceof z.ZodOptional) {
-      baseSchema = baseSchema.unwrap() as ZodType;
-    } else if (baseSchema instanceof z.ZodNullable) {
-      baseSchema = baseSchema.unwrap() as ZodType;
+    baseSchema = baseSchema.unwrap() as ZodType;
+  }
+
+  // Zod has no check for dates in the future or past, so contracts mark
+  // them with .meta({ future: true }) or .meta({ past: true })
+  const meta = metadata(schema);
+  if (meta.future === true) {
+    rules.future = true;
+    hasRules = true;
+  }
+  if (meta.past === true) {
+    rules.past = true;
+    hasRules = true;
+  }
+  // Records have no size checks either: .meta({ minEntries, maxEntries })
+  if (typeof meta.minEntries === "number") {
+    rules.minEntries = meta.minEntries;
+    hasRules = true;
+  }
+  if (typeof meta.maxEntries === "number") {
+    rules.maxEntries = meta.maxEntries;
+    hasRules = true;
+  }
+  // Nor for unique items: .meta({ uniqueItems: true })
+  if (baseSchema instanceof z.ZodArray && meta.uniqueItems === true) {
+    rules.uniqueItems = true;
+    hasRules = true;
+  }
+  // Lengths count runes unless the schema asks for bytes or graphemes
+  if (
+    meta.lengthUnit === "bytes" ||
+    meta.lengthUnit === "runes" ||
+    meta.lengthUnit === "graphemes"
+  ) {
+    rules.lengthUnit = meta.lengthUnit;
+    hasRules = true;
+  }
+  // Strings are normalized the way Zod transforms them, then as the schema
+  // asks with .meta({ normalize: ["collapseSpaces"] }), which Zod cannot
+  if (isString(baseSchema)) {
+    const normalize = [
+      ...zodNormalizations(baseSchema),
+      ...(Array.isArray(meta.normalize)
+        ? meta.normalize.filter(isNormalization)
+        : []),
+    ];
+    if (normalize.length > 0) {
+      rules.normalize = normalize;
+      hasRules = true;
+    }
+  }
+  // Bytes have no size checks, the lengths of base64 strings counting
+  // characters: z.base64().meta({ minSize, maxSize })
+  if (baseSchema instanceof z.ZodBase64) {
+    if (typeof meta.minSize === "number") {
+      rules.minSize = meta.minSize;
+      hasRules = true;
+    }
+    if (typeof meta.maxSize === "number") {
+      rules.maxSize = meta.maxSize;
+      hasRules = true;
+    }
+  }
+  // Business rules are checked by validators registered under this name
+  if (typeof meta.custom === "string" && meta.custom) {
+    rules.custom = meta.custom;
+    hasRules = true;
+  }
+  // Whether the field must be set, or must not be, can depend on another
+  // field of its object, which the object checks (see checkConditions)
+  for (const rule of ["requiredIf", "forbiddenIf"] as const) {
+    if (meta[rule] !== undefined) {
+      rules[rule] = fieldCondition(rule, meta[rule]);
+      hasRules = true;
     }
   }
 
+  // Soft limits reported as warnings rather than rejecting the value
+  if (meta.warn !== undefined) {
+    rules.warn = warningRules(baseSchema, meta.warn);
+    hasRules = true;
+  }
+
+  // Files are described as one JSON schema per accepted type, so their
+  // checks are read directly
+  if (baseSchema instanceof z.ZodFile) {
+    return fileValidationRules(baseSchema, rules, hasRules);
+  }
+
   // Use toJSONSchema() for reliable extraction of validation rules
   // This is the most reliable way to get validation constraints in Zod v4
   let jsonSchema: any;
   try {
     jsonSchema = baseSchema.toJSONSchema();
   } catch (e) {
-    // If toJSONSchema fails, fall back to internal structure
-    return undefined;
+    // If toJSONSchema fails (e.g. for z.date()), fall back to internal structure
+    return hasRules ? rules : undefined;
   }
 
   if (!jsonSchema || typeof jsonSchema !== "object") {
-    return undefined;
+    return hasRules ? rules : undefined;
   }
 
-  // Extract string validations
-  if (baseSchema instanceof z.ZodString) {
+  // Extract string validations; bytes are decoded rather than checked as
+  // strings
+  if (isString(baseSchema) && !(baseSchema instanceof z.ZodBase64)) {
     if (typeof jsonSchema.minLength === "number") {
       rules.minLength = jsonSchema.minLength;
       hasRules = true;
@@ -81,57 +176,38 @@ export function extractValidationRules(
       rules.uuid = true;
       hasRules = true;
     }
-    if (jsonSchema.pattern && typeof jsonSchema.pattern === "string") {
-      rules.regex = jsonSchema.pattern;
+    if (jsonSchema.format === "date-time") {
+      rules.datetime = true;
       hasRules = true;
     }
-  } else if (baseSchema instanceof z.ZodNumber) {
-    // Extract number validations from JSON schema
-    // Note: When .int() is used, Zod v4 may reset min/max to safe integer bounds in JSON schema
-    // We'll extract what we can, but custom min/max with .int() may not be fully extractable via JSON schema
-
-    const isInteger = jsonSchema.type === "integer";
-
-    // Only extract min/max if they're not the default safe integer bounds (when int is used)
-    if (typeof jsonSchema.minimum === "number") {
-      if (!isInteger || jsonSchema.minimum !== SAFE_INTEGER_MIN) {
-        rules.min = jsonSchema.minimum;
-        hasRules = true;
-      }
-    }
-
-    if (typeof jsonSchema.maximum === "number") {
-      if (!isInteger || jsonSchema.maximum !== SAFE_INTEGER_MAX) {
-        rules.max = jsonSchema.maximum;
-        hasRules = true;
-      }
-    }
-
-    // Check if it's an integer type
-    if (isInteger) {
-      rules.int = true;
+    if (jsonSchema.format === "date") {
+      rules.date = true;
       hasRules = true;
     }
-
-    // Note: positive/negative might be in exclusiveMinimum/exclusiveMaximum
-    if (
-      jsonSchema.exclusiveMinimum === 0 ||
-      (typeof jsonSchema.minimum === "number" &&
-        jsonSchema.minimum > 0 &&
-        (!isInteger || jsonSchema.minimum !== SAFE_INTEGER_MIN))
-    ) {
-      rules.positive = true;
+    const format = STRING_FORMATS.find((name) => name === jsonSchema.format);
+    if (format) {
+      rules[format] = true;
       hasRules = true;
     }
+    // Dates, timestamps and the formats above also carry the pattern Zod
+    // checks them with, which targets replace with their own parsing
     if (
-      jsonSchema.exclusiveMaximum === 0 ||
-      (typeof jsonSchema.maximum === "number" &&
-        jsonSchema.maximum < 0 &&
-        (!isInteger || jsonSchema.maximum !== SAFE_INTEGER_MAX))
+      jsonSchema.pattern &&
+      typeof jsonSchema.pattern === "string" &&
+      !rules.datetime &&
+      !rules.date &&
+      !format
     ) {
-      rules.negative = true;
+      rules.regex = jsonSchema.pattern;
       hasRules = true;
     }
+  } else if (baseSchema instanceof z.ZodNumber) {
+    return numberValidationRules(
+      baseSchema,
+      jsonSchema.type === "integer",
+      rules,
+      hasRules,
+    );
   } else if (baseSchema instanceof z.ZodArray) {
     // Extract array validations
     if (typeof jsonSchema.minItems === "number") {
@@ -147,7 +223,395 @@ export function extractValidationRules(
   return hasRules ? rules : undefined;
 }
 
+// Size and type checks of z.file(), e.g. z.file().max(1_000_000).mime([...])
+function fileValidationRules(
+  schema: ZodFile,
+  rules: ValidationRules,
+  hasRules: boolean,
+): ValidationRules | undefined {
+  for (const check of (schema as any)._zod.def.checks ?? []) {
+    const def = check._zod.def;
+    if (def.check === "min_size") {
+      rules.minSize = def.minimum;
+      hasRules = true;
+    } else if (def.check === "max_size") {
+      rules.maxSize = def.maximum;
+      hasRules = true;
+    } else if (def.check === "mime_type") {
+      rules.mimeTypes = [...def.mime];
+      hasRules = true;
+    }
+  }
+  return hasRules ? rules : undefined;
+}
+
+// Numbers are checked with the bounds of their checks, as toJSONSchema()
+// replaces the bounds of .min() and .max() with the safe integer ones when
+// .int() follows them. .gt(0) and .lt(0) are positive and negative.
+function numberValidationRules(
+  schema: ZodNumber,
+  isInteger: boolean,
+  rules: ValidationRules,
+  hasRules: boolean,
+): ValidationRules | undefined {
+  if (isInteger) {
+    rules.int = true;
+    hasRules = true;
+  }
+  for (const check of (schema as any)._zod.def.checks ?? []) {
+    const def = check._zod.def;
+    if (def.check === "greater_than" && def.inclusive) {
+      rules.min = Math.max(rules.min ?? def.value, def.value);
+      hasRules = true;
+    } else if (def.check === "greater_than" && def.value === 0) {
+      rules.positive = true;
+      hasRules = true;
+    } else if (def.check === "greater_than") {
+      rules.exclusiveMin = Math.max(rules.exclusiveMin ?? def.value, def.value);
+      hasRules = true;
+    } else if (def.check === "less_than" && def.inclusive) {
+      rules.max = Math.min(rules.max ?? def.value, def.value);
+      hasRules = true;
+    } else if (def.check === "less_than" && def.value === 0) {
+      rules.negative = true;
+      hasRules = true;
+    } else if (def.check === "less_than") {
+      rules.exclusiveMax = Math.min(rules.exclusiveMax ?? def.value, def.value);
+      hasRules = true;
+    } else if (def.check === "multiple_of") {
+      rules.multipleOf = def.value;
+      hasRules = true;
+    }
+  }
+  // Formats narrower than safe integers, such as z.int32(), bound the value
+  // themselves
+  const bag = (schema as any)._zod.bag;
+  if (bag.format !== undefined && bag.format !== "safeint") {
+    if (rules.min === undefined && typeof bag.minimum === "number") {
+      rules.min = bag.minimum;
+      hasRules = true;
+    }
+    if (rules.max === undefined && typeof bag.maximum === "number") {
+      rules.max = bag.maximum;
+      hasRules = true;
+    }
+  }
+  return hasRules ? rules : undefined;
+}
+
+const NORMALIZATIONS: Normalization[] = [
+  "trim",
+  "lowercase",
+  "uppercase",
+  "collapseSpaces",
+];
+
+function isNormalization(value: unknown): value is Normalization {
+  return NORMALIZATIONS.includes(value as Normalization);
+}
+
+// .trim(), .toLowerCase() and .toUpperCase() are overwrite checks, told
+// apart by what they make of a sample; other overwrites are left to Zod
+function zodNormalizations(schema: ZodType): Normalization[] {
+  const outcomes: Record<string, Normalization> = {
+    Ab: "trim",
+    " ab ": "lowercase",
+    " AB ": "uppercase",
+  };
+  const normalizations: Normalization[] = [];
+  for (const check of (schema as any)._zod.def.checks ?? []) {
+    const def = check._zod.def;
+    if (def.check !== "overwrite") continue;
+    let outcome: unknown;
+    try {
+      outcome = def.tx(" Ab ");
+    } catch {
+      continue;
+    }
+    if (typeof outcome === "string" && Object.hasOwn(outcomes, outcome)) {
+      normalizations.push(outcomes[outcome]);
+    }
+  }
+  return normalizations;
+}
+
+// String formats such as z.iso.datetime() and z.email() are not ZodString
+function isString(schema: ZodType): boolean {
+  return schema instanceof z.ZodString || schema instanceof z.ZodStringFormat;
+}
+
+// Keys of Go struct tags: anything but spaces, quotes and colons
+const GO_TAG_KEY = /^[^\s:"`]+$/;
+
+// The condition of .meta({ requiredIf }) or .meta({ forbiddenIf }), which
+// must name a field and compare it with at most one value
+function fieldCondition(rule: string, value: unknown): FieldCondition {
+  const condition = value as Partial<FieldCondition> | null;
+  const isValue = (value: unknown) =>
+    value === undefined ||
+    ["string", "number", "boolean"].includes(typeof value);
+  if (
+    typeof condition !== "object" ||
+    condition === null ||
+    typeof condition.field !== "string" ||
+    !isValue(condition.equals) ||
+    !isValue(condition.notEquals) ||
+    (condition.equals !== undefined && condition.notEquals !== undefined)
+  ) {
+    throw new Error(
+      `${rule} must name a field and compare it with equals or notEquals, e.g. .meta({ ${rule}: { field: "priority", equals: "urgent" } })`,
+    );
+  }
+  return {
+    field: condition.field,
+    ...(condition.equals !== undefined && { equals: condition.equals }),
+    ...(condition.notEquals !== undefined && {
+      notEquals: condition.notEquals,
+    }),
+  };
+}
+
+// The soft limits of .meta({ warn }), which must be limits of the schema's
+// kind of value: lengths of strings, bounds of numbers or counts of arrays
+function warningRules(schema: ZodType, value: unknown): WarningRules {
+  const limits = isString(schema)
+    ? ["minLength", "maxLength"]
+    : schema instanceof z.ZodNumber
+      ? ["min", "max"]
+      : schema instanceof z.ZodArray
+        ? ["minItems", "maxItems"]
+        : [];
+  const warn = value as Record<string, unknown> | null;
+  if (
+    limits.length === 0 ||
+    typeof warn !== "object" ||
+    warn === null ||
+    !limits.some((limit) => warn[limit] !== undefined)
+  ) {
+    throw new Error(
+      "warn must set soft limits of a string, number or array, e.g. .meta({ warn: { maxLength: 120 } })",
+    );
+  }
+  const rules: WarningRules = {};
+  for (const [key, limit] of Object.entries(warn)) {
+    if (key === "message" && typeof limit === "string") {
+      rules.message = limit;
+    } else if (limits.includes(key) && typeof limit === "number") {
+      rules[key as Exclude<keyof WarningRules, "message">] = limit;
+    } else {
+      throw new Error(
+        `warn cannot set ${key} to ${JSON.stringify(limit)}; it takes ${limits.join(", ")} and a message`,
+      );
+    }
+  }
+  return rules;
+}
+
+// Checks the conditions of an object's properties against the fields they
+// refer to: only optional and nullable properties can be required or
+// forbidden, compared fields must hold a value of the compared type, and
+// fields conditioned on being set must be optional or nullable
+function checkConditions(properties: Property[]): void {
+  const canBeUnset = (prop: Property) =>
+    !prop.required || prop.type.kind === "nullable";
+  for (const prop of properties) {
+    for (const rule of ["requiredIf", "forbiddenIf"] as const) {
+      const condition = prop.validation?.[rule];
+      if (!condition) continue;
+      const where = `${rule} of "${prop.name}"`;
+      if (!canBeUnset(prop)) {
+        throw new Error(
+          `${where} needs an optional or nullable field, which can be left out`,
+        );
+      }
+      const other = properties.find(
+        (candidate) =>
+          candidate.name === condition.field && candidate !== prop,
+      );
+      if (!other) {
+        throw new Error(
+          `${where} refers to "${condition.field}", which is not another field of its object`,
+        );
+      }
+      const value = condition.equals ?? condition.notEquals;
+      if (value === undefined) {
+        if (!canBeUnset(other)) {
+          throw new Error(
+            `${where} depends on "${other.name}" being set, which it always is`,
+          );
+        }
+        continue;
+      }
+      if (!isComparable(other, value)) {
+        throw new Error(
+          `${where} compares "${other.name}" with ${JSON.stringify(value)}, which it cannot hold`,
+        );
+      }
+    }
+  }
+}
+
+// Whether a field holds strings, numbers, booleans or enum values that can
+// be compared with value
+function isComparable(
+  prop: Property,
+  value: string | number | boolean,
+): boolean {
+  let type = prop.type;
+  while (
+    (type.kind === "optional" || type.kind === "nullable") &&
+    typeof type.baseType === "object"
+  ) {
+    type = type.baseType;
+  }
+  switch (type.kind) {
+    case "enum":
+      return (type.enumValues ?? []).includes(value as string | number);
+    case "literal":
+      return type.literalValue === value;
+    case "primitive": {
+      // Timestamps and dates are decoded as times
+      const rules = prop.validation;
+      if (rules?.datetime || rules?.date) {
+        return false;
+      }
+      return (
+        (type.baseType === "string" && typeof value === "string") ||
+        (type.baseType === "number" && typeof value === "number") ||
+        (type.baseType === "boolean" && typeof value === "boolean")
+      );
+    }
+    default:
+      return false;
+  }
+}
+
+/**
+ * Extracts the extra Go struct tags of a property, set with
+ * .meta({ goTags: { db: "due_date" } }). The json tag is generated from the
+ * property and cannot be replaced.
+ */
+export function extractGoTags(
+  schema: ZodType,
+): Record<string, string> | undefined {
+  const tags = metadata(schema).goTags;
+  if (tags === undefined) return undefined;
+  if (typeof tags !== "object" || tags === null || Array.isArray(tags)) {
+    throw new Error(
+      'goTags must map tag keys to values, e.g. .meta({ goTags: { db: "due_date" } })',
+    );
+  }
+  const entries = Object.entries(tags);
+  for (const [key, value] of entries) {
+    if (!GO_TAG_KEY.test(key) || key === "json") {
+      throw new Error(
+        `Invalid goTags key "${key}": use a key such as "db" or "validate"; the json tag is generated`,
+      );
+    }
+    if (typeof value !== "string" || value.includes("`")) {
+      throw new Error(
+        `Invalid goTags value for "${key}": use a string without backquotes`,
+      );
+    }
+  }
+  return entries.length > 0 ? (tags as Record<string, string>) : undefined;
+}
+
+// Metadata registered with .meta() on a schema or the optional/nullable/
+// default wrappers around it, outermost wins
+function metadata(schema: ZodType): Record<string, unknown> {
+  const own = z.globalRegistry.get(schema) ?? {};
+  if (
+    schema instanceof z.ZodOptional ||
+    schema instanceof z.ZodNullable ||
+    schema instanceof z.ZodDefault
+  ) {
+    return { ...metadata(schema.unwrap() as ZodType), ...own };
+  }
+  return own;
+}
+
+// The value .default() gives a property when it is absent, found through
+// the optional/nullable wrappers around it; only strings, numbers and
+// booleans, which every target can write as literals
+function defaultValue(
+  schema: ZodType,
+): { default?: string | number | boolean } {
+  if (schema instanceof z.ZodDefault) {
+    const value = (schema as any).def.defaultValue;
+    return ["string", "number", "boolean"].includes(typeof value)
+      ? { default: value }
+      : {};
+  }
+  if (schema instanceof z.ZodOptional || schema instanceof z.ZodNullable) {
+    return defaultValue(schema.unwrap() as ZodType);
+  }
+  return {};
+}
+
+// Attaches the rules of a schema that is not a property, such as a record
+// key or value, to its type
+function withValidation(
+  typeRef: TypeReference,
+  schema: ZodType,
+): TypeReference {
+  if (typeRef.validation) {
+    return typeRef;
+  }
+  const validation = extractValidationRules(schema);
+  return validation ? { ...typeRef, validation } : typeRef;
+}
+
+// Objects, enums and unions registered with .meta({ id }) are shared named
+// types
+function schemaName(schema: ZodType): { name?: string } {
+  const id = z.globalRegistry.get(schema)?.id;
+  return typeof id === "string" ? { name: id } : {};
+}
+
+// The description set with .describe() or .meta({ description }), which
+// targets carry into the documentation of the code they generate
+function schemaDescription(schema: ZodType): { description?: string } {
+  const description = metadata(schema).description;
+  return typeof description === "string" && description.trim()
+    ? { description: description.trim() }
+    : {};
+}
+
+// Schemas whose extraction is in progress, to find schemas that contain
+// themselves
+const extracting = new Set<ZodType>();
+
 export function extractTypeInfo(schema: ZodType): TypeReference {
+  if (extracting.has(schema)) {
+    return recursiveReference(schema);
+  }
+  extracting.add(schema);
+  try {
+    return extractType(schema);
+  } finally {
+    extracting.delete(schema);
+  }
+}
+
+// A schema nested in itself (through a getter or z.lazy()) is referenced by
+// name, so it is extracted once and targets generate a recursive type
+function recursiveReference(schema: ZodType): TypeReference {
+  const { name } = schemaName(schema);
+  if (!(schema instanceof z.ZodObject) || !name) {
+    throw new Error(
+      'Recursive schemas must be objects named with .meta({ id }), e.g. z.object({ ... }).meta({ id: "Comment" })',
+    );
+  }
+  return { name, kind: "object" };
+}
+
+function extractType(schema: ZodType): TypeReference {
+  // Handle lazy schemas, used for recursion
+  if (schema instanceof z.ZodLazy) {
+    return extractTypeInfo(schema.unwrap() as ZodType);
+  }
+
   // Handle optional
   if (schema instanceof z.ZodOptional) {
     const unwrapped = schema.unwrap() as ZodType;
@@ -157,6 +621,14 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
     };
   }
 
+  // Handle defaults: the field may be absent, the server filling it in
+  if (schema instanceof z.ZodDefault) {
+    const baseType = extractTypeInfo(schema.unwrap() as ZodType);
+    return baseType.kind === "optional"
+      ? baseType
+      : { kind: "optional", baseType };
+  }
+
   // Handle nullable
   if (schema instanceof z.ZodNullable) {
     const unwrapped = schema.unwrap() as ZodType;
@@ -173,26 +645,40 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
 
     for (const [key, value] of Object.entries(shape)) {
       const valueType = extractTypeInfo(value as ZodType);
-      const isOptional = value instanceof z.ZodOptional;
+      const isOptional =
+        value instanceof z.ZodOptional || value instanceof z.ZodDefault;
       const validation = extractValidationRules(value as ZodType);
+      const goTags = extractGoTags(value as ZodType);
 
       properties.push({
         name: key,
         type: valueType,
         required: !isOptional,
         validation,
+        ...(goTags && { goTags }),
+        ...defaultValue(value as ZodType),
+        ...schemaDescription(value as ZodType),
+        ...(metadata(value as ZodType).sensitive === true && {
+          sensitive: true,
+        }),
       });
     }
 
+    checkConditions(properties);
+
     return {
+      ...schemaName(schema),
+      ...schemaDescription(schema),
       kind: "object",
       properties,
     };
   }
 
-  // Handle arrays
+  // Handle arrays; items carry their own rules, such as the length of each
+  // tag
   if (schema instanceof z.ZodArray) {
-    const elementType = extractTypeInfo(schema.element as ZodType);
+    const element = schema.element as ZodType;
+    const elementType = withValidation(extractTypeInfo(element), element);
     const validation = extractValidationRules(schema);
 
     return {
@@ -202,8 +688,15 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
     };
   }
 
-  // Handle primitives
-  if (schema instanceof z.ZodString) {
+  // Handle primitives; z.base64() strings are binary content
+  if (schema instanceof z.ZodBase64) {
+    return {
+      kind: "primitive",
+      baseType: "bytes",
+    };
+  }
+
+  if (isString(schema)) {
     return {
       kind: "primitive",
       baseType: "string",
@@ -224,11 +717,14 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
     };
   }
 
-  // Handle unions
+  // Handle unions; z.discriminatedUnion() also records its discriminator
   if (schema instanceof z.ZodUnion) {
     const options = (schema as any).options;
+    const discriminator = (schema as any).def?.discriminator;
     return {
+      ...schemaName(schema),
       kind: "union",
+      ...(typeof discriminator === "string" ? { discriminator } : {}),
       unionTypes: options.map((opt: ZodType) => extractTypeInfo(opt)),
     };
   }
@@ -236,6 +732,8 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
   // Handle enums
   if (schema instanceof z.ZodEnum) {
     return {
+      ...schemaName(schema),
+      ...schemaDescription(schema),
       kind: "enum",
       enumValues: (schema as any).options,
     };
@@ -250,12 +748,21 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
     };
   }
 
-  // Handle records
+  // Handle records; keys are always strings in JSON, but may carry rules
+  // such as a pattern, and values carry their own rules
   if (schema instanceof z.ZodRecord) {
+    const keySchema = (schema as any).keyType ?? (schema as any)._def?.keyType;
+    const valueSchema =
+      (schema as any).valueType ??
+      (schema as any)._def?.valueType ??
+      (schema as any).valueSchema;
     return {
       kind: "record",
-      keyType: { kind: "primitive", baseType: "string" },
-      valueType: extractTypeInfo((schema as any).valueSchema),
+      keyType: keySchema
+        ? withValidation(extractTypeInfo(keySchema), keySchema)
+        : { kind: "primitive", baseType: "string" },
+      valueType: withValidation(extractTypeInfo(valueSchema), valueSchema),
+      validation: extractValidationRules(schema),
     };
   }
 
@@ -281,6 +788,14 @@ export function extractTypeInfo(schema: ZodType): TypeReference {
     };
   }
 
+  // Handle files
+  if (schema instanceof z.ZodFile) {
+    return {
+      kind: "file",
+      baseType: "file",
+    };
+  }
+
   // Fallback for unknown types
   return {
     kind: "primitive",
@@ -0,0 +1,583 @@
+import type {
+  FieldCondition,
+  TypeReference,
+  ValidationRules,
+} from "../parser/contract";
+
+/**
+ * Thrown when no example satisfies a type's constraints, such as a regex no
+ * candidate string matches.
+ */
+export class ExampleError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = "ExampleError";
+  }
+}
+
+/**
+ * Derive an example value from a type reference that satisfies its
+ * validation rules: lengths, formats, patterns, ranges, enums and item
+ * counts. Every property of an object is included, optional ones too, except
+ * references to an enclosing recursive type, which are left out or empty,
+ * and properties a forbiddenIf rule forbids in the example.
+ *
+ * @param typeRef - The type to derive an example of
+ * @param validation - Rules declared on the property holding the type
+ * @returns A JSON-compatible value
+ * @throws ExampleError if no example satisfies the rules
+ *
+ * @example
+ * ```typescript
+ * typeToExample({ kind: "primitive", baseType: "string" }, { minLength: 10 });
+ * // "example..."
+ * ```
+ */
+export function typeToExample(
+  typeRef: TypeReference,
+  validation?: ValidationRules,
+): unknown {
+  const rules = { ...validation, ...typeRef.validation };
+
+  switch (typeRef.kind) {
+    case "optional":
+    case "nullable":
+      return typeof typeRef.baseType === "object"
+        ? typeToExample(typeRef.baseType, rules)
+        : null;
+
+    case "primitive":
+      return primitiveExample(String(typeRef.baseType ?? "unknown"), rules);
+
+    case "date":
+      return timestampExample(rules);
+
+    case "file":
+      return fileExample(rules);
+
+    case "enum":
+      return typeRef.enumValues?.[0] ?? null;
+
+    case "literal":
+      return typeRef.literalValue ?? null;
+
+    case "object": {
+      // A named object without properties refers to an enclosing recursive
+      // type; examples stop there
+      if (typeRef.name && !typeRef.properties) {
+        return undefined;
+      }
+      const example: Record<string, unknown> = {};
+      for (const prop of typeRef.properties ?? []) {
+        const value = typeToExample(prop.type, prop.validation);
+        if (value !== undefined) {
+          example[prop.name] = value;
+        }
+      }
+      for (const prop of typeRef.properties ?? []) {
+        const forbiddenIf = prop.validation?.forbiddenIf;
+        if (forbiddenIf && conditionHolds(example, forbiddenIf)) {
+          delete example[prop.name];
+        }
+      }
+      return example;
+    }
+
+    case "array": {
+      const item = typeRef.elementType
+        ? typeToExample(typeRef.elementType)
+        : null;
+      if (item === undefined) {
+        return [];
+      }
+      const count = itemCount(rules);
+      if (!rules.uniqueItems || !typeRef.elementType) {
+        return repeat(item, count);
+      }
+      const elementType = typeRef.elementType;
+      return Array.from({ length: count }, (_, i) =>
+        i === 0 ? item : itemVariant(elementType, undefined, item, i),
+      );
+    }
+
+    case "record": {
+      const value = typeRef.valueType ? typeToExample(typeRef.valueType) : null;
+      if (value === undefined) {
+        return {};
+      }
+      const key = typeRef.keyType ? typeToExample(typeRef.keyType) : "key";
+      const count = entryCount(rules);
+      const example: Record<string, unknown> = {};
+      for (let i = 0; i < count; i++) {
+        example[i === 0 ? String(key) : keyVariant(String(key), i, typeRef)] =
+          value;
+      }
+      return example;
+    }
+
+    case "tuple":
+      return (typeRef.tupleElements ?? []).map((element) =>
+        typeToExample(element),
+      );
+
+    case "union": {
+      const variants = typeRef.unionTypes ?? [];
+      const variant =
+        variants.find(
+          (member) =>
+            !(member.kind === "literal" && member.literalValue === null),
+        ) ?? variants[0];
+      return variant ? typeToExample(variant) : null;
+    }
+
+    default:
+      return null;
+  }
+}
+
+// Whether the condition of a requiredIf or forbiddenIf rule holds for an
+// example object, absent and null fields differing from every value
+function conditionHolds(
+  example: Record<string, unknown>,
+  condition: FieldCondition,
+): boolean {
+  const value = example[condition.field];
+  const isSet = value !== undefined && value !== null;
+  if (condition.equals !== undefined) {
+    return isSet && value === condition.equals;
+  }
+  if (condition.notEquals !== undefined) {
+    return !isSet || value !== condition.notEquals;
+  }
+  return isSet;
+}
+
+function primitiveExample(baseType: string, rules: ValidationRules): unknown {
+  switch (baseType) {
+    case "string":
+      return stringExample(rules);
+    case "number":
+      return numberExample(rules);
+    case "boolean":
+      return true;
+    case "date":
+      return timestampExample(rules);
+    case "bytes":
+      return btoa("x".repeat(clampCount(rules.minSize, rules.maxSize)));
+    default:
+      return null;
+  }
+}
+
+function stringExample(rules: ValidationRules): string {
+  // Formats win over patterns, the way JSON Schema documents them
+  if (rules.email) {
+    return fitLength("user@example.com", rules, "user", "@example.com");
+  }
+  if (rules.url) {
+    return fitLength("https://example.com/", rules, "https://example.com/", "");
+  }
+  if (rules.uuid) return "3fa85f64-5717-4562-b3fc-2c963f66afa6";
+  if (rules.datetime) return timestampExample(rules);
+  if (rules.date) return timestampExample(rules).slice(0, 10);
+  if (rules.hostname) return "api.example.com";
+  if (rules.ipv4) return "192.0.2.1";
+  if (rules.ipv6) return "2001:db8::1";
+  if (rules.duration) return "PT1H30M";
+  if (rules.e164) return "+14155550100";
+  if (rules.regex) {
+    return patternExample(rules.regex, rules);
+  }
+  return fitLength("example", rules, "example", "");
+}
+
+// Sample the pattern, repeating its quantified parts more often until the
+// sample is long enough
+function patternExample(regex: string, rules: ValidationRules): string {
+  const pattern = new RegExp(regex);
+  const min = rules.minLength ?? 0;
+  const max = rules.maxLength ?? Number.POSITIVE_INFINITY;
+  let node: PatternNode | undefined;
+  try {
+    node = new PatternParser(regex).parse();
+  } catch {
+    node = undefined;
+  }
+  for (let extra = 0; node && extra <= 64; extra++) {
+    const sample = samplePattern(node, extra);
+    if (sample.length > max) break;
+    if (sample.length >= min && pattern.test(sample)) {
+      return sample;
+    }
+  }
+  throw new ExampleError(`No example string matches the pattern ${regex}`);
+}
+
+type PatternNode =
+  | { kind: "text"; text: string }
+  | { kind: "sequence"; items: PatternNode[] }
+  | { kind: "repeat"; node: PatternNode; min: number; max: number };
+
+// Parses the common subset of regular expressions: literals, escapes,
+// character classes, groups, alternations (the first alternative is sampled)
+// and quantifiers
+class PatternParser {
+  private pos = 0;
+
+  constructor(private readonly source: string) {}
+
+  parse(): PatternNode {
+    const node = this.alternation();
+    if (this.pos < this.source.length) {
+      throw new Error(`Unexpected ${this.source[this.pos]}`);
+    }
+    return node;
+  }
+
+  private alternation(): PatternNode {
+    const first = this.sequence();
+    while (this.source[this.pos] === "|") {
+      this.pos++;
+      this.sequence();
+    }
+    return first;
+  }
+
+  private sequence(): PatternNode {
+    const items: PatternNode[] = [];
+    while (this.pos < this.source.length) {
+      const char = this.source[this.pos];
+      if (char === "|" || char === ")") break;
+      items.push(this.quantified(this.atom()));
+    }
+    return { kind: "sequence", items };
+  }
+
+  private atom(): PatternNode {
+    const char = this.source[this.pos++];
+    switch (char) {
+      case "^":
+      case "$":
+        return { kind: "text", text: "" };
+      case ".":
+        return { kind: "text", text: "a" };
+      case "(": {
+        // Non-capturing and named groups sample like any other
+        if (this.source[this.pos] === "?") {
+          const close = this.source[this.pos + 1] === "<" ? ">" : ":";
+          this.pos = this.source.indexOf(close, this.pos) + 1;
+        }
+        const node = this.alternation();
+        if (this.source[this.pos++] !== ")") throw new Error("Unclosed group");
+        return node;
+      }
+      case "[":
+        return { kind: "text", text: this.characterClass() };
+      case "\\":
+        return { kind: "text", text: this.escape() };
+      default:
+        return { kind: "text", text: char };
+    }
+  }
+
+  private escape(): string {
+    const char = this.source[this.pos++];
+    const classes: Record<string, string> = {
+      d: "0",
+      D: "a",
+      w: "a",
+      W: "-",
+      s: " ",
+      S: "a",
+      b: "",
+      B: "",
+      n: "\n",
+      t: "\t",
+    };
+    return classes[char] ?? char;
+  }
+
+  // The first character the class allows, or for a negated class the first
+  // of a few common characters it doesn't exclude
+  private characterClass(): string {
+    const negated = this.source[this.pos] === "^";
+    if (negated) this.pos++;
+    const members: Array<[string, string]> = [];
+    while (this.pos < this.source.length && this.source[this.pos] !== "]") {
+      let from = this.source[this.pos++];
+      if (from === "\\") from = this.escape();
+      let to = from;
+      if (this.source[this.pos] === "-" && this.source[this.pos + 1] !== "]") {
+        this.pos++;
+        to = this.source[this.pos++];
+        if (to === "\\") to = this.escape();
+      }
+      members.push([from, to]);
+    }
+    this.pos++;
+    if (!negated) {
+      return members[0]?.[0] ?? "";
+    }
+    const allowed = ["a", "A", "0", "x", "-", "_", " "].find(
+      (candidate) =>
+        !members.some(([from, to]) => candidate >= from && candidate <= to),
+    );
+    if (allowed === undefined) throw new Error("Empty character class");
+    return allowed;
+  }
+
+  private quantified(node: PatternNode): PatternNode {
+    const char = this.source[this.pos];
+    let min: number;
+    let max: number;
+    if (char === "?" || char === "*" || char === "+") {
+      this.pos++;
+      min = char === "+" ? 1 : 0;
+      max = char === "?" ? 1 : Number.POSITIVE_INFINITY;
+    } else if (char === "{") {
+      const match = /^\{(\d+)(,(\d*))?\}/.exec(this.source.slice(this.pos));
+      if (!match) return node;
+      this.pos += match[0].length;
+      min = Number(match[1]);
+      max = match[2]
+        ? match[3]
+          ? Number(match[3])
+          : Number.POSITIVE_INFINITY
+        : min;
+    } else {
+      return node;
+    }
+    // Lazy quantifiers sample the same
+    if (this.source[this.pos] === "?") this.pos++;
+    return { kind: "repeat", node, min, max };
+  }
+}
+
+function samplePattern(node: PatternNode, extra: number): string {
+  switch (node.kind) {
+    case "text":
+      return node.text;
+    case "sequence":
+      return node.items.map((item) => samplePattern(item, extra)).join("");
+    case "repeat": {
+      const count = Math.min(node.min + extra, node.max);
+      return samplePattern(node.node, extra).repeat(count);
+    }
+  }
+}
+
+// A distinct key for the i-th entry, suffixed with a letter when the key
+// pattern doesn't allow digits
+function keyVariant(key: string, i: number, typeRef: TypeReference): string {
+  const regex = typeRef.keyType?.validation?.regex;
+  const numbered = `${key}${i}`;
+  if (!regex || new RegExp(regex).test(numbered)) {
+    return numbered;
+  }
+  return key + letterSuffix(i);
+}
+
+// A suffix of letters distinct for every i > 0
+function letterSuffix(i: number): string {
+  return "abcdefghijklmnopqrstuvwxyz"[i % 26].repeat(Math.ceil(i / 26));
+}
+
+// The i-th variant of example, the example of typeRef, different from the
+// example and from the other variants, for arrays of unique items. Objects
+// vary their first property that can.
+function itemVariant(
+  typeRef: TypeReference,
+  validation: ValidationRules | undefined,
+  example: unknown,
+  i: number,
+): unknown {
+  const rules = { ...validation, ...typeRef.validation };
+  let variant: unknown;
+  switch (typeRef.kind) {
+    case "optional":
+    case "nullable":
+      if (typeof typeRef.baseType === "object") {
+        return itemVariant(typeRef.baseType, rules, example, i);
+      }
+      break;
+    case "enum":
+      variant = typeRef.enumValues?.[i];
+      break;
+    case "primitive":
+      if (typeRef.baseType === "boolean" && i === 1) {
+        variant = !example;
+      } else if (typeof example === "number") {
+        const step = i * (rules.multipleOf ?? 1);
+        variant = [example + step, example - step]
+          .map((n) => Number(n.toPrecision(12)))
+          .find((n) => inRange(n, rules));
+      } else if (typeof example === "string") {
+        variant = stringVariant(example, rules, i);
+      }
+      break;
+    case "object":
+      for (const prop of typeRef.properties ?? []) {
+        const value = (example as Record<string, unknown>)[prop.name];
+        if (value === undefined) continue;
+        try {
+          return {
+            ...(example as Record<string, unknown>),
+            [prop.name]: itemVariant(prop.type, prop.validation, value, i),
+          };
+        } catch (error) {
+          if (!(error instanceof ExampleError)) throw error;
+        }
+      }
+      break;
+  }
+  if (variant === undefined) {
+    throw new ExampleError(`No example has ${i + 1} unique items`);
+  }
+  return variant;
+}
+
+function inRange(value: number, rules: ValidationRules): boolean {
+  return (
+    (rules.min === undefined || value >= rules.min) &&
+    (rules.max === undefined || value <= rules.max) &&
+    (!rules.positive || value > 0) &&
+    (!rules.negative || value < 0) &&
+    (rules.exclusiveMin === undefined || value > rules.exclusiveMin) &&
+    (rules.exclusiveMax === undefined || value < rules.exclusiveMax)
+  );
+}
+
+// The i-th variant of a string example: numbered or suffixed with letters,
+// replacing its end when it would grow too long, as long as it keeps the
+// length, format and pattern of the rules. Formats such as IP addresses
+// vary a number of their own; timestamps and dates have none.
+function stringVariant(
+  example: string,
+  rules: ValidationRules,
+  i: number,
+): string | undefined {
+  if (rules.datetime || rules.date) {
+    return undefined;
+  }
+  if (rules.email) {
+    return example.replace("@", `${i}@`);
+  }
+  if (rules.uuid) {
+    return example.slice(0, -12) + i.toString(16).padStart(12, "0");
+  }
+  if (rules.hostname) {
+    return `host${i}.${example}`;
+  }
+  if (rules.ipv4) {
+    return i < 254 ? `192.0.2.${i + 1}` : undefined;
+  }
+  if (rules.ipv6) {
+    return `2001:db8::${(i + 1).toString(16)}`;
+  }
+  if (rules.duration) {
+    return `PT${i + 1}H`;
+  }
+  if (rules.e164) {
+    return `+1415555${String(100 + i).padStart(4, "0")}`;
+  }
+  const min = rules.minLength ?? 0;
+  const max = rules.maxLength ?? Number.POSITIVE_INFINITY;
+  const pattern = rules.regex ? new RegExp(rules.regex) : undefined;
+  return [String(i), letterSuffix(i)]
+    .flatMap((suffix) => [
+      example + suffix,
+      example.slice(0, -suffix.length) + suffix,
+    ])
+    .find(
+      (candidate) =>
+        candidate !== example &&
+        candidate.length >= min &&
+        candidate.length <= max &&
+        (!pattern || pattern.test(candidate)),
+    );
+}
+
+// Pad or cut a string to the length rules; padding goes between prefix and
+// suffix so formats stay valid
+function fitLength(
+  value: string,
+  rules: ValidationRules,
+  prefix: string,
+  suffix: string,
+): string {
+  const min = rules.minLength ?? 0;
+  const max = rules.maxLength ?? Number.POSITIVE_INFINITY;
+  if (value.length < min) {
+    return prefix + "x".repeat(min - prefix.length - suffix.length) + suffix;
+  }
+  if (value.length > max) {
+    return value.slice(0, max);
+  }
+  return value;
+}
+
+// The lowest of the values near the lower bound that satisfies the rules,
+// stepping above exclusive bounds by the multiple or by one
+function numberExample(rules: ValidationRules): number {
+  const step = rules.multipleOf ?? 1;
+  let value = rules.min ?? 0;
+  if (rules.positive && value <= 0) value = 1;
+  if (rules.negative && value >= 0) value = -1;
+  if (rules.exclusiveMin !== undefined && value <= rules.exclusiveMin) {
+    value = rules.exclusiveMin + step;
+  }
+  if (rules.exclusiveMax !== undefined && value >= rules.exclusiveMax) {
+    value = rules.exclusiveMax - step;
+  }
+  if (rules.max !== undefined && value > rules.max) value = rules.max;
+  if (rules.multipleOf !== undefined) {
+    const up = roundToMultiple(value, rules.multipleOf, Math.ceil);
+    value = inRange(up, rules)
+      ? up
+      : roundToMultiple(value, rules.multipleOf, Math.floor);
+  }
+  return rules.int ? Math.ceil(value) : value;
+}
+
+// value rounded to a multiple, without the float error of decimal multiples
+// such as 0.1
+function roundToMultiple(
+  value: number,
+  multiple: number,
+  round: (n: number) => number,
+): number {
+  const steps = round(Number((value / multiple).toPrecision(12)));
+  return Number((steps * multiple).toPrecision(12));
+}
+
+// Timestamps far from now satisfy future and past rules until then
+function timestampExample(rules: ValidationRules): string {
+  return rules.past ? "2000-01-03T09:00:00Z" : "2099-01-05T09:00:00Z";
+}
+
+// A file of the first accepted type, as many bytes long as the rules need
+function fileExample(rules: ValidationRules): Record<string, unknown> {
+  const size = clampCount(rules.minSize, rules.maxSize);
+  return {
+    filename: "example",
+    contentType: rules.mimeTypes?.[0] ?? "text/plain",
+    data: btoa("x".repeat(size)),
+  };
+}
+
+// One item, unless the rules ask for more or none
+function itemCount(rules: ValidationRules): number {
+  return clampCount(rules.minItems, rules.maxItems);
+}
+
+function entryCount(rules: ValidationRules): number {
+  return clampCount(rules.minEntries, rules.maxEntries);
+}
+
+function clampCount(min: number | undefined, max: number | undefined): number {
+  return Math.min(Math.max(min ?? 1, 1), max ?? Number.POSITIVE_INFINITY);
+}
+
+function repeat(item: unknown, count: number): unknown[] {
+  return Array.from({ length: count }, () => item);
+}
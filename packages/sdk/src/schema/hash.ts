@@ -0,0 +1,44 @@
+import { createHash } from "node:crypto";
+import type { ContractDefinition } from "../parser";
+import { typeToJsonSchema } from "./json-schema";
+
+/**
+ * Computes a short hash of what a contract puts on the wire: the name, kind
+ * and version of every method and the JSON Schemas of its input and output.
+ * Targets generated from the same contract embed the same hash, so a client
+ * can tell the server it calls was generated from a different contract.
+ * Methods are hashed in name order, so reordering them keeps the hash;
+ * descriptions and other documentation are left out.
+ *
+ * @param contract - The contract to hash
+ * @returns The first 16 hex digits of the SHA-256 of the contract's methods
+ *
+ * @example
+ * ```typescript
+ * schemaHash(contract); // "3f2a9c04b1d7e865"
+ * ```
+ */
+export function schemaHash(contract: ContractDefinition): string {
+  const methods = [...contract.endpoints]
+    .sort((a, b) => (a.fullName < b.fullName ? -1 : 1))
+    .map((endpoint) => ({
+      name: endpoint.fullName,
+      kind: endpoint.type,
+      version: endpoint.version,
+      input: typeToJsonSchema(endpoint.input),
+      output: typeToJsonSchema(endpoint.output),
+    }));
+  return createHash("sha256")
+    .update(JSON.stringify(methods, withoutDescriptions))
+    .digest("hex")
+    .slice(0, 16);
+}
+
+// Leaves descriptions out of the hashed schemas, so documenting a field does
+// not make clients stale; properties named description are schemas, not
+// strings, and are kept
+function withoutDescriptions(key: string, value: unknown): unknown {
+  return key === "description" && typeof value === "string"
+    ? undefined
+    : value;
+}
@@ -0,0 +1,6 @@
+// Schema exports - JSON Schema and OpenAPI documents, example values and
+// schema hashes derived from contracts
+export { ExampleError, typeToExample } from "./example";
+export { schemaHash } from "./hash";
+export { type JsonSchema, typeToJsonSchema } from "./json-schema";
+export { type OpenAPIOptions, buildOpenAPIDocument } from "./openapi";
@@ -0,0 +1,315 @@
+import { toPascalCase } from "../codegen/utils";
+import type { Property, TypeReference, ValidationRules } from "../parser";
+
+/**
+ * A JSON Schema (draft 2020-12) document or subschema.
+ */
+export type JsonSchema = Record<string, unknown>;
+
+/**
+ * Converts a contract type reference into a JSON Schema (draft 2020-12),
+ * the dialect used by OpenAPI 3.1.
+ *
+ * Validation rules are attached to the schema they constrain: string and
+ * number rules to the primitive, item counts to the array, entry counts to
+ * the record. Optional wrappers are transparent; whether a property is
+ * required is expressed by the enclosing object. Recursive types are defined
+ * once under `$defs` and referenced from inside themselves.
+ *
+ * @param typeRef - The type reference to convert
+ * @param validation - Validation rules from the owning property, if any
+ * @returns The JSON Schema for the type
+ *
+ * @example
+ * ```typescript
+ * typeToJsonSchema({ kind: "primitive", baseType: "string" }, { email: true });
+ * // { type: "string", format: "email" }
+ * ```
+ */
+export function typeToJsonSchema(
+  typeRef: TypeReference,
+  validation?: ValidationRules,
+): JsonSchema {
+  const defs: Record<string, JsonSchema> = {};
+  const recursive = new Set<string>();
+  let schema = toSchema(typeRef, validation, { defs, recursive });
+  for (const name of recursive) {
+    // Endpoint types are renamed after their method, so a recursive type
+    // used as an endpoint's input or output is only found at the root
+    if (!(toPascalCase(name) in defs)) {
+      defs[toPascalCase(name)] = schema;
+      schema = defRef(name);
+    }
+  }
+  return Object.keys(defs).length > 0 ? { ...schema, $defs: defs } : schema;
+}
+
+interface SchemaContext {
+  // Recursive types, defined once and referenced as #/$defs/<Name>
+  defs: Record<string, JsonSchema>;
+  // Names of the types referenced from inside themselves
+  recursive: Set<string>;
+}
+
+// The requiredIf and forbiddenIf rules of an object's properties as if/then
+// schemas: when the other field holds the value, or differs from it, or is
+// present, the property is required, or must be absent
+function conditionSchemas(properties: Property[]): JsonSchema[] {
+  const schemas: JsonSchema[] = [];
+  for (const prop of properties) {
+    for (const rule of ["requiredIf", "forbiddenIf"] as const) {
+      const condition = prop.validation?.[rule];
+      if (!condition) continue;
+      const { field, equals, notEquals } = condition;
+      const compared = equals ?? notEquals;
+      const holds: JsonSchema =
+        compared === undefined
+          ? { required: [field] }
+          : {
+              properties: { [field]: { const: compared } },
+              required: [field],
+            };
+      const required = { required: [prop.name] };
+      schemas.push({
+        if: notEquals !== undefined ? { not: holds } : holds,
+        // biome-ignore lint/suspicious/noThenProperty: JSON Schema keyword
+        then: rule === "requiredIf" ? required : { not: required },
+      });
+    }
+  }
+  return schemas;
+}
+
+function toSchema(
+  typeRef: TypeReference,
+  validation: ValidationRules | undefined,
+  ctx: SchemaContext,
+): JsonSchema {
+  const rules = { ...validation, ...typeRef.validation };
+
+  switch (typeRef.kind) {
+    case "optional":
+      return typeof typeRef.baseType === "object"
+        ? toSchema(typeRef.baseType, rules, ctx)
+        : {};
+
+    case "nullable": {
+      const inner =
+        typeof typeRef.baseType === "object"
+          ? toSchema(typeRef.baseType, rules, ctx)
+          : {};
+      return { anyOf: [inner, { type: "null" }] };
+    }
+
+    case "primitive":
+      return primitiveSchema(String(typeRef.baseType ?? "unknown"), rules);
+
+    case "date":
+      return { type: "string", format: "date-time" };
+
+    case "file":
+      return fileSchema(rules);
+
+    case "enum": {
+      const values = typeRef.enumValues ?? [];
+      if (values.every((value) => typeof value === "string")) {
+        return { type: "string", enum: values };
+      }
+      return { enum: values };
+    }
+
+    case "literal":
+      return { const: typeRef.literalValue };
+
+    case "object": {
+      // A named object without properties refers to an enclosing recursive
+      // type
+      if (typeRef.name && !typeRef.properties) {
+        ctx.recursive.add(typeRef.name);
+        return defRef(typeRef.name);
+      }
+      const properties: Record<string, JsonSchema> = {};
+      const required: string[] = [];
+      for (const prop of typeRef.properties ?? []) {
+        const schema = toSchema(prop.type, prop.validation, ctx);
+        properties[prop.name] =
+          prop.default !== undefined
+            ? { ...schema, default: prop.default }
+            : schema;
+        if (prop.required) {
+          required.push(prop.name);
+        }
+      }
+      const schema: JsonSchema = { type: "object", properties };
+      if (required.length > 0) {
+        schema.required = required;
+      }
+      const conditions = conditionSchemas(typeRef.properties ?? []);
+      if (conditions.length > 0) {
+        schema.allOf = conditions;
+      }
+      if (typeRef.name && ctx.recursive.has(typeRef.name)) {
+        ctx.defs[toPascalCase(typeRef.name)] = schema;
+        return defRef(typeRef.name);
+      }
+      return schema;
+    }
+
+    case "array": {
+      const schema: JsonSchema = {
+        type: "array",
+        items: typeRef.elementType
+          ? toSchema(typeRef.elementType, undefined, ctx)
+          : {},
+      };
+      if (rules.minItems !== undefined) schema.minItems = rules.minItems;
+      if (rules.maxItems !== undefined) schema.maxItems = rules.maxItems;
+      if (rules.uniqueItems) schema.uniqueItems = true;
+      return schema;
+    }
+
+    case "union": {
+      const members = (typeRef.unionTypes ?? []).map((member) =>
+        toSchema(member, undefined, ctx),
+      );
+      if (typeRef.discriminator) {
+        return {
+          oneOf: members,
+          discriminator: { propertyName: typeRef.discriminator },
+        };
+      }
+      return { anyOf: members };
+    }
+
+    case "record": {
+      const schema: JsonSchema = {
+        type: "object",
+        additionalProperties: typeRef.valueType
+          ? toSchema(typeRef.valueType, undefined, ctx)
+          : {},
+      };
+      // Keys are strings; only keys with rules or values need a schema
+      const keyType = typeRef.keyType;
+      if (keyType && (keyType.validation || keyType.kind !== "primitive")) {
+        schema.propertyNames = toSchema(keyType, undefined, ctx);
+      }
+      if (rules.minEntries !== undefined) {
+        schema.minProperties = rules.minEntries;
+      }
+      if (rules.maxEntries !== undefined) {
+        schema.maxProperties = rules.maxEntries;
+      }
+      return schema;
+    }
+
+    case "tuple": {
+      const elements = typeRef.tupleElements ?? [];
+      return {
+        type: "array",
+        prefixItems: elements.map((element) =>
+          toSchema(element, undefined, ctx),
+        ),
+        minItems: elements.length,
+        maxItems: elements.length,
+      };
+    }
+
+    default:
+      return {};
+  }
+}
+
+function primitiveSchema(baseType: string, rules: ValidationRules): JsonSchema {
+  switch (baseType) {
+    case "string": {
+      const schema: JsonSchema = { type: "string" };
+      if (rules.minLength !== undefined) schema.minLength = rules.minLength;
+      if (rules.maxLength !== undefined) schema.maxLength = rules.maxLength;
+      if (rules.email) {
+        schema.format = "email";
+      } else if (rules.url) {
+        schema.format = "uri";
+      } else if (rules.uuid) {
+        schema.format = "uuid";
+      } else if (rules.datetime) {
+        schema.format = "date-time";
+      } else if (rules.date) {
+        schema.format = "date";
+      } else if (rules.hostname) {
+        schema.format = "hostname";
+      } else if (rules.ipv4) {
+        schema.format = "ipv4";
+      } else if (rules.ipv6) {
+        schema.format = "ipv6";
+      } else if (rules.duration) {
+        schema.format = "duration";
+      } else if (rules.e164) {
+        schema.format = "e164";
+      } else if (rules.regex) {
+        schema.pattern = rules.regex;
+      }
+      return schema;
+    }
+
+    case "number": {
+      const schema: JsonSchema = { type: rules.int ? "integer" : "number" };
+      if (rules.min !== undefined) schema.minimum = rules.min;
+      if (rules.max !== undefined) schema.maximum = rules.max;
+      if (rules.positive) schema.exclusiveMinimum = 0;
+      if (rules.negative) schema.exclusiveMaximum = 0;
+      if (rules.exclusiveMin !== undefined) {
+        schema.exclusiveMinimum = rules.exclusiveMin;
+      }
+      if (rules.exclusiveMax !== undefined) {
+        schema.exclusiveMaximum = rules.exclusiveMax;
+      }
+      if (rules.multipleOf !== undefined) schema.multipleOf = rules.multipleOf;
+      return schema;
+    }
+
+    case "boolean":
+      return { type: "boolean" };
+
+    case "date":
+      return { type: "string", format: "date-time" };
+
+    case "bytes": {
+      // Standard padded base64, four characters per three bytes
+      const schema: JsonSchema = { type: "string", contentEncoding: "base64" };
+      if (rules.minSize !== undefined) {
+        schema.minLength = Math.ceil(rules.minSize / 3) * 4;
+      }
+      if (rules.maxSize !== undefined) {
+        schema.maxLength = Math.ceil(rules.maxSize / 3) * 4;
+      }
+      return schema;
+    }
+
+    default:
+      return {};
+  }
+}
+
+// Files are sent as multipart file parts, or in JSON bodies as an object
+// holding the base64-encoded content
+function fileSchema(rules: ValidationRules): JsonSchema {
+  const size: JsonSchema = { type: "integer" };
+  if (rules.minSize !== undefined) size.minimum = rules.minSize;
+  if (rules.maxSize !== undefined) size.maximum = rules.maxSize;
+  const contentType: JsonSchema = { type: "string" };
+  if (rules.mimeTypes) contentType.enum = rules.mimeTypes;
+  return {
+    type: "object",
+    properties: {
+      filename: { type: "string" },
+      contentType,
+      size,
+      data: { type: "string", contentEncoding: "base64" },
+    },
+    required: ["data"],
+  };
+}
+
+function defRef(name: string): JsonSchema {
+  return { $ref: `#/$defs/${toPascalCase(name)}` };
+}
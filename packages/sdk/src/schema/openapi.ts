@@ -0,0 +1,238 @@
+import { toPascalCase } from "../codegen/utils";
+import type { ContractDefinition, Endpoint } from "../parser";
+import { type JsonSchema, typeToJsonSchema } from "./json-schema";
+
+export type OpenAPIOptions = {
+  /** Document title (default: "xRPC API") */
+  title?: string;
+  /** API version reported in the document (default: "0.0.0") */
+  version?: string;
+  /** Path the router is mounted on (default: "/api") */
+  path?: string;
+};
+
+/**
+ * Builds an OpenAPI 3.1 document describing every method in a contract.
+ *
+ * xRPC serves all methods from a single path, so the document has one POST
+ * operation whose request body is a `oneOf` of per-method envelopes
+ * discriminated by `method`. Subscriptions are described as a GET operation
+ * on the same path returning `text/event-stream`. Input and output schemas
+ * (including validation constraints) are emitted under `components.schemas`
+ * using the same names as the generated types.
+ *
+ * @param contract - The parsed contract
+ * @param options - Document metadata and mount path
+ * @returns The OpenAPI document as a plain JSON-serializable object
+ */
+export function buildOpenAPIDocument(
+  contract: ContractDefinition,
+  options: OpenAPIOptions = {},
+): Record<string, unknown> {
+  const schemas: Record<string, JsonSchema> = {};
+  const requestRefs: JsonSchema[] = [];
+  const responseRefs: JsonSchema[] = [];
+  const eventRefs: JsonSchema[] = [];
+  const mapping: Record<string, string> = {};
+
+  for (const endpoint of contract.endpoints) {
+    const methodName = toMethodName(endpoint.fullName);
+    const inputName = schemaName(endpoint, "Input");
+    const outputName = schemaName(endpoint, "Output");
+
+    schemas[inputName] = hoistDefs(typeToJsonSchema(endpoint.input), schemas);
+    schemas[outputName] = hoistDefs(
+      typeToJsonSchema(endpoint.output),
+      schemas,
+    );
+
+    if (endpoint.type === "subscription") {
+      eventRefs.push(ref(outputName));
+      continue;
+    }
+
+    let description = `Calls the ${endpoint.fullName} ${endpoint.type}.`;
+    if (endpoint.deprecated?.message) {
+      description += ` Deprecated: ${endpoint.deprecated.message}`;
+    }
+    const request: JsonSchema = {
+      type: "object",
+      description,
+      properties: {
+        method: { const: endpoint.fullName },
+        params: ref(inputName),
+      },
+      required: ["method", "params"],
+      "x-xrpc-kind": endpoint.type,
+    };
+    if (endpoint.deprecated) {
+      request.deprecated = true;
+    }
+    schemas[`${methodName}Request`] = request;
+    schemas[`${methodName}Response`] = {
+      type: "object",
+      properties: { result: ref(outputName) },
+      required: ["result"],
+    };
+
+    requestRefs.push(ref(`${methodName}Request`));
+    responseRefs.push(ref(`${methodName}Response`));
+    mapping[endpoint.fullName] = `#/components/schemas/${methodName}Request`;
+  }
+
+  schemas.Error = {
+    type: "object",
+    properties: {
+      code: {
+        type: "string",
+        description:
+          "Machine-readable error code, e.g. INVALID_ARGUMENT or NOT_FOUND.",
+      },
+      message: { type: "string" },
+      details: {
+        description:
+          "Additional error information; validation failures list the failing fields.",
+      },
+    },
+    required: ["code", "message"],
+  };
+  schemas.ErrorResponse = {
+    type: "object",
+    properties: { error: ref("Error") },
+    required: ["error"],
+  };
+
+  const errorResponse = {
+    description: "The call failed",
+    content: { "application/json": { schema: ref("ErrorResponse") } },
+  };
+
+  const pathItem: Record<string, unknown> = {};
+  if (requestRefs.length > 0) {
+    pathItem.post = {
+      operationId: "call",
+      summary: "Call a query or mutation",
+      requestBody: {
+        required: true,
+        content: {
+          "application/json": {
+            schema: {
+              oneOf: requestRefs,
+              discriminator: { propertyName: "method", mapping },
+            },
+          },
+        },
+      },
+      responses: {
+        "200": {
+          description: "The method's result",
+          content: {
+            "application/json": { schema: { oneOf: responseRefs } },
+          },
+        },
+        default: errorResponse,
+      },
+    };
+  }
+
+  const subscriptions = contract.endpoints.filter(
+    (endpoint) => endpoint.type === "subscription",
+  );
+  if (subscriptions.length > 0) {
+    pathItem.get = {
+      operationId: "subscribe",
+      summary: "Open a subscription as a Server-Sent Events stream",
+      parameters: [
+        {
+          name: "method",
+          in: "query",
+          required: true,
+          schema: {
+            type: "string",
+            enum: subscriptions.map((endpoint) => endpoint.fullName),
+          },
+        },
+        {
+          name: "params",
+          in: "query",
+          description: "JSON-encoded subscription input",
+          schema: { type: "string" },
+        },
+      ],
+      responses: {
+        "200": {
+          description:
+            "A stream of events; each data line is one JSON-encoded output",
+          content: {
+            "text/event-stream": {
+              schema: { type: "string" },
+              "x-xrpc-events": { oneOf: eventRefs },
+            },
+          },
+        },
+        default: errorResponse,
+      },
+    };
+  }
+
+  return {
+    openapi: "3.1.0",
+    info: {
+      title: options.title ?? "xRPC API",
+      version: options.version ?? "0.0.0",
+    },
+    paths: { [options.path ?? "/api"]: pathItem },
+    components: { schemas },
+  };
+}
+
+// Converts "task.get" to "TaskGet"
+function toMethodName(fullName: string): string {
+  return fullName
+    .split(".")
+    .map((part) => toPascalCase(part))
+    .join("");
+}
+
+function schemaName(endpoint: Endpoint, suffix: "Input" | "Output"): string {
+  const typeRef = suffix === "Input" ? endpoint.input : endpoint.output;
+  return typeRef.name
+    ? toPascalCase(typeRef.name)
+    : `${toMethodName(endpoint.fullName)}${suffix}`;
+}
+
+function ref(name: string): JsonSchema {
+  return { $ref: `#/components/schemas/${name}` };
+}
+
+// Moves the recursive types a schema defines under $defs to
+// components.schemas, where every method's schemas can refer to them
+function hoistDefs(
+  schema: JsonSchema,
+  schemas: Record<string, JsonSchema>,
+): JsonSchema {
+  const { $defs, ...rest } = schema;
+  for (const [name, def] of Object.entries(
+    ($defs ?? {}) as Record<string, JsonSchema>,
+  )) {
+    schemas[name] = rewriteRefs(def) as JsonSchema;
+  }
+  return rewriteRefs(rest) as JsonSchema;
+}
+
+function rewriteRefs(value: unknown): unknown {
+  if (Array.isArray(value)) {
+    return value.map(rewriteRefs);
+  }
+  if (!value || typeof value !== "object") {
+    return value;
+  }
+  return Object.fromEntries(
+    Object.entries(value).map(([key, child]) => [
+      key,
+      key === "$ref" && typeof child === "string"
+        ? child.replace("#/$defs/", "#/components/schemas/")
+        : rewriteRefs(child),
+    ]),
+  );
+}
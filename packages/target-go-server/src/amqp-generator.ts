@@ -0,0 +1,164 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates amqp.go: ServeAMQP, which consumes the RPC requests of an AMQP
+ * queue and publishes the replies of Router.ServeMessage to their ReplyTo
+ * queue. Only emitted with the `amqp: true` option, since it depends on the
+ * RabbitMQ client module.
+ */
+export class GoAMQPGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateAMQP(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "sync",
+      "github.com/rabbitmq/amqp091-go",
+    );
+
+    w.comment(
+      "AMQPChannel is the part of an AMQP channel ServeAMQP uses. *amqp091.Channel",
+    )
+      .comment("implements it.")
+      .type(
+        "AMQPChannel interface",
+        "{\n\tConsumeWithContext(ctx context.Context, queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp091.Table) (<-chan amqp091.Delivery, error)\n\tPublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error\n}",
+      );
+
+    w.comment("AMQPOptions configures ServeAMQP.")
+      .struct("AMQPOptions", (b) => {
+        b.comment(
+          "Context returns the context of a call from its delivery, such as with the",
+        )
+          .comment(
+            "caller taken from its headers (WithUserID). The context of ServeAMQP by",
+          )
+          .comment("default.")
+          .l(
+            "Context func(ctx context.Context, d amqp091.Delivery) context.Context",
+          )
+          .comment(
+            "MaxConcurrent is the number of deliveries answered at once, 1 by default,",
+          )
+          .comment(
+            "which answers them in order. Set the prefetch count of the channel (Qos)",
+          )
+          .comment("to at least as many.")
+          .l("MaxConcurrent int");
+      });
+
+    w.comment(
+      "ServeAMQP consumes the RPC requests of queue and answers them with",
+    )
+      .comment(
+        'ServeMessage: requests carry a {"method", "params"} envelope, and the body',
+      )
+      .comment(
+        "ServeHTTP would send is published to their ReplyTo queue with their",
+      )
+      .comment(
+        "CorrelationId. Deliveries are acknowledged once answered. It returns when",
+      )
+      .comment(
+        "ctx is done, once the calls it started are answered, or when the channel is",
+      )
+      .comment("closed.")
+      .n()
+      .func(
+        "ServeAMQP(ctx context.Context, ch AMQPChannel, queue string, router *Router, options AMQPOptions) error",
+        (b) => {
+          b.decl(
+            "deliveries, err",
+            'ch.ConsumeWithContext(ctx, queue, "", false, false, false, false, nil)',
+          )
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .decl("limit", "options.MaxConcurrent")
+            .if("limit < 1", (b) => {
+              b.l("limit = 1");
+            })
+            .comment(
+              "Calls started before ctx is done still run to their reply",
+            )
+            .decl("callCtx", "context.WithoutCancel(ctx)")
+            .decl("slots", "make(chan struct{}, limit)")
+            .l("var wg sync.WaitGroup")
+            .l("defer wg.Wait()")
+            .l("for {")
+            .i()
+            .l("select {")
+            .l("case <-ctx.Done():")
+            .i()
+            .return("ctx.Err()")
+            .u()
+            .l("case d, ok := <-deliveries:")
+            .i()
+            .if("!ok", (b) => {
+              b.return("amqp091.ErrClosed");
+            })
+            .l("slots <- struct{}{}")
+            .l("wg.Add(1)")
+            .l("go func() {")
+            .i()
+            .l("defer func() {")
+            .i()
+            .l("<-slots")
+            .l("wg.Done()")
+            .u()
+            .l("}()")
+            .l("serveAMQPDelivery(callCtx, ch, d, router, options)")
+            .u()
+            .l("}()")
+            .u()
+            .l("}")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment("serveAMQPDelivery answers a delivery and acknowledges it.")
+      .n()
+      .func(
+        "serveAMQPDelivery(ctx context.Context, ch AMQPChannel, d amqp091.Delivery, router *Router, options AMQPOptions)",
+        (b) => {
+          b.decl("callCtx", "ctx")
+            .if("options.Context != nil", (b) => {
+              b.l("callCtx = options.Context(ctx, d)");
+            })
+            .decl("reply", "router.ServeMessage(callCtx, d.Body)")
+            .if('d.ReplyTo != ""', (b) => {
+              b.l(
+                'err := ch.PublishWithContext(ctx, "", d.ReplyTo, false, false, amqp091.Publishing{',
+              )
+                .i()
+                .l('ContentType:   "application/json",')
+                .l("CorrelationId: d.CorrelationId,")
+                .l("Body:          reply,")
+                .u()
+                .l("})")
+                .ifErr((b) => {
+                  b.l(
+                    'router.logf("xrpc: replying to %s: %v", d.ReplyTo, err)',
+                  );
+                });
+            })
+            .if("err := d.Ack(false); err != nil", (b) => {
+              b.l(
+                'router.logf("xrpc: acknowledging delivery %d: %v", d.DeliveryTag, err)',
+              );
+            });
+        },
+      );
+
+    return w.toString();
+  }
+}
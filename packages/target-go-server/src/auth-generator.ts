@@ -0,0 +1,471 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates auth.go: authentication middleware presets that verify a request's
+ * credentials and store the caller as a Principal in its context. Requests
+ * without credentials pass through unauthenticated, so public methods keep
+ * working; methods declared with auth: "required" reject them in the router.
+ */
+export class GoAuthGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateAuth(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "crypto",
+      "crypto/ecdsa",
+      "crypto/hmac",
+      "crypto/rsa",
+      "crypto/sha256",
+      "crypto/sha512",
+      "encoding/base64",
+      "encoding/json",
+      "errors",
+      "fmt",
+      "hash",
+      "math/big",
+      "net/http",
+      "strings",
+      "time",
+    );
+
+    this.generateBearerAuth(w);
+    this.generateJWTAuth(w);
+    this.generateAPIKeyAuth(w);
+    this.generateAuthorizer(w);
+    this.generateHelpers(w);
+    this.generateJWTVerification(w);
+
+    return w.toString();
+  }
+
+  private generateBearerAuth(w: GoBuilder): void {
+    w.comment(
+      'BearerAuth returns middleware authenticating requests with an "Authorization:',
+    )
+      .comment(
+        'Bearer <token>" header with verify. Requests without one, and calls without',
+      )
+      .comment(
+        "an HTTP request, continue unauthenticated; a token verify rejects fails the",
+      )
+      .comment("call with UNAUTHORIZED, or with the *Error verify returns.")
+      .n()
+      .func(
+        "BearerAuth(verify func(ctx context.Context, token string) (Principal, error)) MiddlewareFunc",
+        (b) => {
+          b.l(
+            "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+          )
+            .i()
+            .if("info.Request == nil", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .l("token, ok := bearerToken(info.Request)")
+            .if("!ok", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .l("principal, err := verify(ctx, token)")
+            .ifErr((b) => {
+              b.return("NewMiddlewareError(authError(err))");
+            })
+            .if('principal.Scheme == ""', (b) => {
+              b.l('principal.Scheme = "bearer"');
+            })
+            .return("NewMiddlewareResult(WithPrincipal(ctx, principal))")
+            .u()
+            .l("}");
+        },
+      );
+  }
+
+  private generateJWTAuth(w: GoBuilder): void {
+    w.comment(
+      "JWTKeySet holds the keys JWTAuth verifies tokens with, by key ID (the token's",
+    )
+      .comment(
+        '"kid" header): []byte secrets for HS256/384/512, *rsa.PublicKey for',
+      )
+      .comment(
+        "RS256/384/512 and *ecdsa.PublicKey for ES256/384/512. Tokens without a key",
+      )
+      .comment('ID are verified with the key stored under "", or the only key.')
+      .type("JWTKeySet", "map[string]interface{}");
+
+    w.comment(
+      "JWTAuth returns middleware authenticating requests whose bearer token is a JWT",
+    )
+      .comment(
+        'signed with a key in keys and within its "exp" and "nbf" claims. claims maps',
+      )
+      .comment(
+        "the verified claims to the principal and may reject them, e.g. for the wrong",
+      )
+      .comment(
+        'audience; if it is nil, the principal\'s ID is the "sub" claim.',
+      )
+      .n()
+      .func(
+        "JWTAuth(keys JWTKeySet, claims func(claims map[string]interface{}) (Principal, error)) MiddlewareFunc",
+        (b) => {
+          b.l(
+            "return BearerAuth(func(ctx context.Context, token string) (Principal, error) {",
+          )
+            .i()
+            .l("verified, err := verifyJWT(token, keys, time.Now())")
+            .ifErr((b) => {
+              b.return("Principal{}, err");
+            })
+            .decl("principal", "Principal{}")
+            .if("claims != nil", (b) => {
+              b.l("principal, err = claims(verified)")
+                .ifErr((b) => {
+                  b.return("Principal{}, err");
+                });
+            })
+            .if('principal.ID == ""', (b) => {
+              b.l('principal.ID, _ = verified["sub"].(string)');
+            })
+            .if("principal.Claims == nil", (b) => {
+              b.l("principal.Claims = verified");
+            })
+            .l('principal.Scheme = "jwt"')
+            .return("principal, nil")
+            .u()
+            .l("})");
+        },
+      );
+  }
+
+  private generateAPIKeyAuth(w: GoBuilder): void {
+    w.comment(
+      'APIKeyAuth returns middleware authenticating requests with an "X-API-Key" header',
+    )
+      .comment(
+        "with lookup, which returns the key's principal. Requests without one, and",
+      )
+      .comment(
+        "calls without an HTTP request, continue unauthenticated; a key lookup",
+      )
+      .comment(
+        "rejects fails the call with UNAUTHORIZED, or with the *Error lookup returns.",
+      )
+      .n()
+      .func(
+        "APIKeyAuth(lookup func(ctx context.Context, key string) (Principal, error)) MiddlewareFunc",
+        (b) => {
+          b.l(
+            "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+          )
+            .i()
+            .if("info.Request == nil", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .decl("key", 'info.Request.Header.Get("X-API-Key")')
+            .if('key == ""', (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .l("principal, err := lookup(ctx, key)")
+            .ifErr((b) => {
+              b.return("NewMiddlewareError(authError(err))");
+            })
+            .if('principal.Scheme == ""', (b) => {
+              b.l('principal.Scheme = "api-key"');
+            })
+            .return("NewMiddlewareResult(WithPrincipal(ctx, principal))")
+            .u()
+            .l("}");
+        },
+      );
+  }
+
+  private generateAuthorizer(w: GoBuilder): void {
+    w.comment(
+      "Authorizer decides whether callers may call the methods declared with",
+    )
+      .comment(
+        "permissions. The router consults it after middleware, before decoding input.",
+      )
+      .type(
+        "Authorizer interface",
+        "{\n\t// Authorize reports whether the caller of info.Method holds every one of\n\t// permissions. An error fails the call with it instead of PERMISSION_DENIED.\n\tAuthorize(ctx context.Context, info RequestInfo, permissions []string) (bool, error)\n}",
+      );
+
+    w.comment("AuthorizerFunc adapts a function to Authorizer.")
+      .type(
+        "AuthorizerFunc",
+        "func(ctx context.Context, info RequestInfo, permissions []string) (bool, error)",
+      );
+
+    w.comment("Authorize calls f.")
+      .n()
+      .method(
+        "f AuthorizerFunc",
+        "Authorize",
+        "ctx context.Context, info RequestInfo, permissions []string",
+        "(bool, error)",
+        (b) => {
+          b.return("f(ctx, info, permissions)");
+        },
+      );
+
+    w.comment(
+      "ClaimAuthorizer returns an Authorizer granting the permissions the principal's",
+    )
+      .comment(
+        'claim lists, as a JSON array or a space-separated string like OAuth\'s "scope".',
+      )
+      .comment("Unauthenticated callers hold no permissions.")
+      .n()
+      .func("ClaimAuthorizer(claim string) Authorizer", (b) => {
+        b.l(
+          "return AuthorizerFunc(func(ctx context.Context, info RequestInfo, permissions []string) (bool, error) {",
+        )
+          .i()
+          .l("principal, ok := PrincipalFrom(ctx)")
+          .if("!ok", (b) => {
+            b.return("false, nil");
+          })
+          .decl("held", "map[string]bool{}")
+          .l("switch value := principal.Claims[claim].(type) {")
+          .l("case string:")
+          .i()
+          .l("for _, permission := range strings.Fields(value) {")
+          .i()
+          .l("held[permission] = true")
+          .u()
+          .l("}")
+          .u()
+          .l("case []string:")
+          .i()
+          .l("for _, permission := range value {")
+          .i()
+          .l("held[permission] = true")
+          .u()
+          .l("}")
+          .u()
+          .l("case []interface{}:")
+          .i()
+          .l("for _, permission := range value {")
+          .i()
+          .if("name, ok := permission.(string); ok", (b) => {
+            b.l("held[name] = true");
+          })
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .l("for _, permission := range permissions {")
+          .i()
+          .if("!held[permission]", (b) => {
+            b.return("false, nil");
+          })
+          .u()
+          .l("}")
+          .return("true, nil")
+          .u()
+          .l("})");
+      });
+  }
+
+  private generateHelpers(w: GoBuilder): void {
+    w.comment(
+      'bearerToken returns the token of an "Authorization: Bearer <token>" header',
+    )
+      .n()
+      .func("bearerToken(req *http.Request) (string, bool)", (b) => {
+        b.decl("header", 'req.Header.Get("Authorization")')
+          .if(
+            'len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ")',
+            (b) => {
+              b.return('"", false');
+            },
+          )
+          .decl("token", "strings.TrimSpace(header[7:])")
+          .return('token, token != ""');
+      });
+
+    w.comment(
+      "authError reports a rejected credential as UNAUTHORIZED without its reason,",
+    )
+      .comment("unless the verifier returned an *Error choosing its own code.")
+      .n()
+      .func("authError(err error) *Error", (b) => {
+        b.var("e", "*Error")
+          .if("errors.As(err, &e)", (b) => {
+            b.return("e");
+          })
+          .return('NewError(CodeUnauthorized, "Invalid credentials")');
+      });
+  }
+
+  private generateJWTVerification(w: GoBuilder): void {
+    w.comment(
+      "verifyJWT checks a compact JWT's signature and time claims and returns its",
+    )
+      .comment("claims")
+      .n()
+      .func(
+        "verifyJWT(token string, keys JWTKeySet, now time.Time) (map[string]interface{}, error)",
+        (b) => {
+          b.decl("parts", 'strings.Split(token, ".")')
+            .if("len(parts) != 3", (b) => {
+              b.return('nil, errors.New("malformed token")');
+            })
+            .var(
+              "header",
+              'struct {\n\t\tAlg string `json:"alg"`\n\t\tKid string `json:"kid"`\n\t}',
+            )
+            .if("err := decodeJWTPart(parts[0], &header); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .l("key, ok := keys[header.Kid]")
+            .if('!ok && header.Kid == "" && len(keys) == 1', (b) => {
+              b.l("for _, only := range keys {")
+                .i()
+                .l("key, ok = only, true")
+                .u()
+                .l("}");
+            })
+            .if("!ok", (b) => {
+              b.return('nil, fmt.Errorf("unknown key %q", header.Kid)');
+            })
+            .l(
+              "signature, err := base64.RawURLEncoding.DecodeString(parts[2])",
+            )
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .if(
+              'err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil',
+              (b) => {
+                b.return("nil, err");
+              },
+            )
+            .n()
+            .var("claims", "map[string]interface{}")
+            .if("err := decodeJWTPart(parts[1], &claims); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .if(
+              'exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0))',
+              (b) => {
+                b.return('nil, errors.New("token expired")');
+              },
+            )
+            .if(
+              'nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0))',
+              (b) => {
+                b.return('nil, errors.New("token not valid yet")');
+              },
+            )
+            .return("claims, nil");
+        },
+      );
+
+    w.comment("decodeJWTPart decodes a base64url-encoded JSON part of a JWT")
+      .n()
+      .func("decodeJWTPart(part string, v interface{}) error", (b) => {
+        b.l("data, err := base64.RawURLEncoding.DecodeString(part)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .return("json.Unmarshal(data, v)");
+      });
+
+    w.comment(
+      'verifyJWTSignature checks signature over signed with key for alg; "none" and',
+    )
+      .comment("algorithms that don't match the key's type are rejected")
+      .n()
+      .func(
+        "verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) error",
+        (b) => {
+          b.decl("invalid", 'errors.New("invalid signature")')
+            .decl("unsupported", 'fmt.Errorf("unsupported algorithm %q", alg)')
+            .if("len(alg) != 5", (b) => {
+              b.return("unsupported");
+            })
+            .var("id", "crypto.Hash")
+            .var("newHash", "func() hash.Hash")
+            .l("switch alg[2:] {")
+            .l('case "256":')
+            .i()
+            .l("id, newHash = crypto.SHA256, sha256.New")
+            .u()
+            .l('case "384":')
+            .i()
+            .l("id, newHash = crypto.SHA384, sha512.New384")
+            .u()
+            .l('case "512":')
+            .i()
+            .l("id, newHash = crypto.SHA512, sha512.New")
+            .u()
+            .l("default:")
+            .i()
+            .return("unsupported")
+            .u()
+            .l("}")
+            .decl("digest", "newHash()")
+            .l("digest.Write([]byte(signed))")
+            .decl("sum", "digest.Sum(nil)")
+            .n()
+            .l("switch alg[:2] {")
+            .l('case "HS":')
+            .i()
+            .l("secret, ok := key.([]byte)")
+            .if("!ok", (b) => {
+              b.return("unsupported");
+            })
+            .decl("mac", "hmac.New(newHash, secret)")
+            .l("mac.Write([]byte(signed))")
+            .if("!hmac.Equal(signature, mac.Sum(nil))", (b) => {
+              b.return("invalid");
+            })
+            .return("nil")
+            .u()
+            .l('case "RS":')
+            .i()
+            .l("public, ok := key.(*rsa.PublicKey)")
+            .if("!ok", (b) => {
+              b.return("unsupported");
+            })
+            .if(
+              "rsa.VerifyPKCS1v15(public, id, sum, signature) != nil",
+              (b) => {
+                b.return("invalid");
+              },
+            )
+            .return("nil")
+            .u()
+            .l('case "ES":')
+            .i()
+            .l("public, ok := key.(*ecdsa.PublicKey)")
+            .if("!ok", (b) => {
+              b.return("unsupported");
+            })
+            .decl("size", "(public.Curve.Params().BitSize + 7) / 8")
+            .if("len(signature) != 2*size", (b) => {
+              b.return("invalid");
+            })
+            .decl("r", "new(big.Int).SetBytes(signature[:size])")
+            .decl("s", "new(big.Int).SetBytes(signature[size:])")
+            .if("!ecdsa.Verify(public, sum, r, s)", (b) => {
+              b.return("invalid");
+            })
+            .return("nil")
+            .u()
+            .l("}")
+            .return("unsupported");
+        },
+      );
+  }
+}
@@ -0,0 +1,158 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates breaker.go: the per-method circuit breakers of a Client, opened
+ * after a number of consecutive server failures so calls fail fast instead
+ * of piling onto a struggling server, and probed again after a cooldown.
+ */
+export class GoBreakerGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateBreaker(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "errors", "sync", "time");
+
+    w.comment(
+      "ErrCircuitOpen is returned without calling the server while the circuit",
+    )
+      .comment("breaker of the method is open.")
+      .l(
+        'var ErrCircuitOpen = NewError(CodeUnavailable, "Circuit breaker open")',
+      )
+      .n();
+
+    w.comment(
+      "WithCircuitBreaker opens the circuit breaker of a method after threshold",
+    )
+      .comment(
+        "consecutive calls failed on the server side (transport failures, INTERNAL,",
+      )
+      .comment(
+        "UNAVAILABLE and DEADLINE_EXCEEDED): its calls then fail with ErrCircuitOpen",
+      )
+      .comment(
+        "until cooldown passed, when one call probes the server. The breaker closes",
+      )
+      .comment(
+        "again when it succeeds and stays open for another cooldown otherwise.",
+      )
+      .n()
+      .func(
+        "WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption",
+        (b) => {
+          b.l("return func(c *Client) {")
+            .i()
+            .l(
+              "c.breakers = &circuitBreakers{threshold: threshold, cooldown: cooldown, states: make(map[string]*circuitState)}",
+            )
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "circuitBreakers holds the circuit breaker state of every method a Client",
+    )
+      .comment("called; nil when the Client has no circuit breakers.")
+      .struct("circuitBreakers", (b) => {
+        b.l("threshold int")
+          .l("cooldown time.Duration")
+          .l("mu sync.Mutex")
+          .l("states map[string]*circuitState");
+      });
+
+    w.comment("circuitState is the circuit breaker state of a method.")
+      .struct("circuitState", (b) => {
+        b.l("failures int")
+          .l("openUntil time.Time")
+          .comment("Whether a call is probing the server of an open breaker")
+          .l("probing bool");
+      });
+
+    w.comment(
+      "allow reports whether a call of method may be made: always while its breaker",
+    )
+      .comment(
+        "is closed, and for a single probing call once an open one cooled down.",
+      )
+      .n()
+      .method("b *circuitBreakers", "allow", "method string", "bool", (b) => {
+        b.if("b == nil", (b) => {
+          b.return("true");
+        })
+          .l("b.mu.Lock()")
+          .l("defer b.mu.Unlock()")
+          .decl("s", "b.states[method]")
+          .if("s == nil || s.failures < b.threshold", (b) => {
+            b.return("true");
+          })
+          .if("s.probing || time.Now().Before(s.openUntil)", (b) => {
+            b.return("false");
+          })
+          .l("s.probing = true")
+          .return("true");
+      });
+
+    w.comment(
+      "record counts the outcome of a call of method, opening its breaker after",
+    )
+      .comment("threshold consecutive server failures.")
+      .n()
+      .method(
+        "b *circuitBreakers",
+        "record",
+        "method string, err error",
+        "",
+        (b) => {
+          b.if("b == nil", (b) => {
+            b.return();
+          })
+            .l("b.mu.Lock()")
+            .l("defer b.mu.Unlock()")
+            .decl("s", "b.states[method]")
+            .if("s == nil", (b) => {
+              b.l("s = &circuitState{}").l("b.states[method] = s");
+            })
+            .l("s.probing = false")
+            .comment("A call the caller cancelled tells nothing about the server")
+            .if("errors.Is(err, context.Canceled)", (b) => {
+              b.return();
+            })
+            .if("!serverFailure(err)", (b) => {
+              b.l("s.failures = 0").return();
+            })
+            .l("s.failures++")
+            .if("s.failures >= b.threshold", (b) => {
+              b.l("s.openUntil = time.Now().Add(b.cooldown)");
+            });
+        },
+      );
+
+    w.comment(
+      "serverFailure reports whether err shows the server failing, rather than",
+    )
+      .comment("rejecting the call for reasons of its own.")
+      .n()
+      .func("serverFailure(err error) bool", (b) => {
+        b.if("err == nil", (b) => {
+          b.return("false");
+        })
+          .var("callErr", "*Error")
+          .if("errors.As(err, &callErr)", (b) => {
+            b.return(
+              "callErr.Code == CodeInternal || callErr.Code == CodeUnavailable || callErr.Code == CodeDeadlineExceeded",
+            );
+          })
+          .return("true");
+      });
+
+    return w.toString();
+  }
+}
@@ -0,0 +1,97 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates buffers.go: the result and error envelopes of responses as
+ * declared structs, and the pool of buffers and JSON encoders responses are
+ * encoded into, so serving a call allocates no envelope map or encoder.
+ */
+export class GoBufferGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateBuffers(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("bytes", "encoding/json", "sync");
+
+    this.generateEnvelopes(w);
+    this.generatePool(w);
+
+    return w.toString();
+  }
+
+  private generateEnvelopes(w: GoBuilder): void {
+    w.comment('resultEnvelope is the {"result": ...} body of a result.')
+      .struct("resultEnvelope", (b) => {
+        b.l('Result interface{} `json:"result"`');
+      });
+
+    w.comment('errorEnvelope is the {"error": ...} body of an error.')
+      .struct("errorEnvelope", (b) => {
+        b.l('Error *Error `json:"error"`');
+      });
+  }
+
+  private generatePool(w: GoBuilder): void {
+    w.comment(
+      "maxPooledBuffer is the capacity above which a buffer is dropped rather than",
+    )
+      .comment(
+        "pooled, so an occasional large result doesn't keep its memory alive.",
+      )
+      .l("const maxPooledBuffer = 64 << 10")
+      .n();
+
+    w.comment(
+      "encodeBuffer is a buffer with the JSON encoder writing to it, reused across",
+    )
+      .comment("calls through encodeBuffers.")
+      .struct("encodeBuffer", (b) => {
+        b.l("bytes.Buffer").l("encoder *json.Encoder");
+      });
+
+    w.l("var encodeBuffers = sync.Pool{")
+      .i()
+      .l("New: func() interface{} {")
+      .i()
+      .decl("buf", "&encodeBuffer{}")
+      .l("buf.encoder = json.NewEncoder(&buf.Buffer)")
+      .return("buf")
+      .u()
+      .l("},")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment(
+      "encodeJSON encodes value, followed by a newline, into a pooled buffer. The",
+    )
+      .comment(
+        "caller releases the buffer once it has written its bytes, and must not use",
+      )
+      .comment("them afterwards.")
+      .n()
+      .func("encodeJSON(value interface{}) (*encodeBuffer, error)", (b) => {
+        b.decl("buf", "encodeBuffers.Get().(*encodeBuffer)")
+          .if("err := buf.encoder.Encode(value); err != nil", (b) => {
+            b.l("buf.release()").return("nil, err");
+          })
+          .return("buf, nil");
+      });
+
+    w.comment("release returns buf to the pool.")
+      .n()
+      .method("buf *encodeBuffer", "release", "", "", (b) => {
+        b.if("buf.Cap() > maxPooledBuffer", (b) => {
+          b.return();
+        })
+          .l("buf.Reset()")
+          .l("encodeBuffers.Put(buf)");
+      });
+  }
+}
@@ -0,0 +1,342 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates cache.go: the opt-in Cache interceptor keeping the results of
+ * queries in a pluggable CacheStore, in memory or in Redis (redis.go) so the
+ * replicas of a server share them. Entries are refreshed early with a
+ * probability growing as they near expiry (XFetch), so one call recomputes a
+ * popular result while the others are still answered from the cache instead
+ * of all of them missing at once, and misses of the same key in a process
+ * share one handler run.
+ */
+export class GoCacheGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateCache(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "crypto/sha256",
+      "encoding/hex",
+      "encoding/json",
+      "log",
+      "math",
+      "math/rand",
+      "sync",
+      "time",
+    );
+
+    this.generateStore(w);
+    this.generateMemoryStore(w);
+    this.generateInterceptor(w);
+
+    return w.toString();
+  }
+
+  private generateStore(w: GoBuilder): void {
+    w.comment("CacheEntry is a result kept in a CacheStore.").struct(
+      "CacheEntry",
+      (b) => {
+        b.comment("Result is the JSON encoding of the result.")
+          .l('Result json.RawMessage `json:"result"`')
+          .comment("Expires is when the entry expires; stores drop it then.")
+          .l('Expires time.Time `json:"expires"`')
+          .comment(
+            "Delta is how long the handler took to produce the result. Entries that",
+          )
+          .comment("take longer to recompute are refreshed earlier.")
+          .l('Delta time.Duration `json:"delta"`');
+      },
+    );
+
+    w.comment(
+      "refreshEarly reports whether a call at now should recompute the entry rather",
+    )
+      .comment(
+        "than be answered with it: always once it expired, and before that with a",
+      )
+      .comment(
+        "probability growing as it nears expiry, scaled by its Delta and beta (XFetch).",
+      )
+      .comment(
+        "Calls of a popular query thus refresh it one at a time while the others are",
+      )
+      .comment("still answered from the cache.")
+      .n()
+      .method(
+        "e CacheEntry",
+        "refreshEarly",
+        "now time.Time, beta float64",
+        "bool",
+        (b) => {
+          b.if("beta <= 0", (b) => {
+            b.return("!now.Before(e.Expires)");
+          })
+            .comment("1 - rand.Float64() is never 0, whose logarithm is infinite")
+            .decl(
+              "gap",
+              "time.Duration(float64(e.Delta) * beta * -math.Log(1-rand.Float64()))",
+            )
+            .return("!now.Add(gap).Before(e.Expires)");
+        },
+      );
+
+    w.comment(
+      "CacheStore keeps the results the Cache interceptor caches. A store shared by",
+    )
+      .comment(
+        "several servers, such as RedisCacheStore, lets each answer with the results",
+      )
+      .comment("the others computed.")
+      .l("type CacheStore interface {")
+      .i()
+      .comment(
+        "Load returns the entry stored under key, or false when there is none or it",
+      )
+      .comment("expired.")
+      .l("Load(ctx context.Context, key string) (CacheEntry, bool, error)")
+      .comment("Save stores entry under key until it expires.")
+      .l("Save(ctx context.Context, key string, entry CacheEntry) error")
+      .u()
+      .l("}")
+      .n();
+  }
+
+  private generateMemoryStore(w: GoBuilder): void {
+    w.comment(
+      "MemoryCacheStore keeps entries in memory, for a single server or tests.",
+    ).struct("MemoryCacheStore", (b) => {
+      b.l("mu      sync.Mutex")
+        .l("entries map[string]CacheEntry")
+        .comment("The number of entries after the last sweep of expired ones")
+        .l("swept int");
+    });
+
+    w.comment("NewMemoryCacheStore returns an empty MemoryCacheStore.")
+      .n()
+      .func("NewMemoryCacheStore() *MemoryCacheStore", (b) => {
+        b.return(
+          "&MemoryCacheStore{entries: make(map[string]CacheEntry)}",
+        );
+      });
+
+    w.comment("Load returns the entry stored under key, dropping it if it expired.")
+      .n()
+      .method(
+        "s *MemoryCacheStore",
+        "Load",
+        "ctx context.Context, key string",
+        "(CacheEntry, bool, error)",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .decl("entry, ok", "s.entries[key]")
+            .if("ok && !time.Now().Before(entry.Expires)", (b) => {
+              b.l("delete(s.entries, key)").return("CacheEntry{}, false, nil");
+            })
+            .return("entry, ok, nil");
+        },
+      );
+
+    w.comment(
+      "Save stores entry under key. Expired entries are swept whenever the number of",
+    )
+      .comment("entries doubled since the last sweep.")
+      .n()
+      .method(
+        "s *MemoryCacheStore",
+        "Save",
+        "ctx context.Context, key string, entry CacheEntry",
+        "error",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .l("s.entries[key] = entry")
+            .if("len(s.entries) > 2*s.swept", (b) => {
+              b.decl("now", "time.Now()")
+                .l("for key, entry := range s.entries {")
+                .i()
+                .if("!now.Before(entry.Expires)", (b) => {
+                  b.l("delete(s.entries, key)");
+                })
+                .u()
+                .l("}")
+                .l("s.swept = len(s.entries)");
+            })
+            .return("nil");
+        },
+      );
+  }
+
+  private generateInterceptor(w: GoBuilder): void {
+    w.comment("CacheOptions configures the Cache interceptor.").struct(
+      "CacheOptions",
+      (b) => {
+        b.comment("TTL is how long results are cached, a minute by default.")
+          .l("TTL time.Duration")
+          .comment(
+            "Beta scales how early entries are refreshed before they expire, 1 by",
+          )
+          .comment(
+            "default; higher values refresh earlier, and a negative one only once they",
+          )
+          .comment("expired.")
+          .l("Beta float64")
+          .comment(
+            "Shared caches the results of a method for every caller of a tenant. By",
+          )
+          .comment(
+            "default they are cached per user (UserIDFrom); tenants (TenantFrom) never",
+          )
+          .comment("share results.")
+          .l("Shared bool")
+          .comment(
+            'Prefix starts the keys of the store, "xrpc:cache:" by default; the method',
+          )
+          .comment("and a hash of the params, tenant and user follow it.")
+          .l("Prefix string")
+          .comment(
+            "ErrorLog receives the errors of the store, which leave calls uncached. By",
+          )
+          .comment("default they are written to the standard logger.")
+          .l("ErrorLog *log.Logger");
+      },
+    );
+
+    w.comment(
+      "Cache returns an interceptor answering queries with the results kept in store",
+    )
+      .comment(
+        "for calls with the same params, running the handler only when there is none",
+      )
+      .comment(
+        "or it is due for a refresh. Entries are refreshed early with a probability",
+      )
+      .comment(
+        "growing as they near expiry, and misses of the same key in this process wait",
+      )
+      .comment(
+        "for one handler run, so a popular query expiring does not send every call",
+      )
+      .comment(
+        "of every replica to the handler at once. Only queries answered with a single",
+      )
+      .comment(
+        "result are cached, and failed calls are not. Register it with InterceptFor",
+      )
+      .comment("for the queries whose results may be served stale for a TTL:")
+      .comment("")
+      .comment(
+        '    store := NewRedisCacheStore("redis:6379", RedisOptions{})',
+      )
+      .comment(
+        '    router.InterceptFor("task.list", Cache(store, CacheOptions{TTL: 30 * time.Second}))',
+      )
+      .n()
+      .func(
+        "Cache(store CacheStore, options CacheOptions) InterceptorFunc",
+        (b) => {
+          b.if("options.TTL <= 0", (b) => {
+            b.l("options.TTL = time.Minute");
+          })
+            .if("options.Beta == 0", (b) => {
+              b.l("options.Beta = 1");
+            })
+            .if('options.Prefix == ""', (b) => {
+              b.l('options.Prefix = "xrpc:cache:"');
+            })
+            .if("options.ErrorLog == nil", (b) => {
+              b.l("options.ErrorLog = log.Default()");
+            })
+            .var("group", "flightGroup")
+            .l(
+              "return func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error) {",
+            )
+            .i()
+            .decl("m, ok", "methodTable[info.Method]")
+            .if("!ok || m.decodeResult == nil", (b) => {
+              b.return("next(ctx)");
+            })
+            .decl("key, err", "cacheKey(ctx, options, info.Method, input)")
+            .ifErr((b) => {
+              b.return("next(ctx)");
+            })
+            .decl("entry, ok, err", "store.Load(ctx, key)")
+            .ifErr((b) => {
+              b.l(
+                'options.ErrorLog.Printf("xrpc: loading %s from the cache: %v", info.Method, err)',
+              );
+            })
+            .if("ok && !entry.refreshEarly(time.Now(), options.Beta)", (b) => {
+              b.if(
+                "result, err := m.decodeResult(entry.Result); err == nil",
+                (b) => {
+                  b.return("result, nil");
+                },
+              );
+            })
+            .l(
+              "return group.do(ctx, key, func(ctx context.Context) (interface{}, error) {",
+            )
+            .i()
+            .decl("start", "time.Now()")
+            .decl("result, err", "next(ctx)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .decl("data, err", "json.Marshal(result)")
+            .if("err == nil", (b) => {
+              b.l(
+                "err = store.Save(ctx, key, CacheEntry{Result: data, Expires: time.Now().Add(options.TTL), Delta: time.Since(start)})",
+              );
+            })
+            .ifErr((b) => {
+              b.l(
+                'options.ErrorLog.Printf("xrpc: saving %s to the cache: %v", info.Method, err)',
+              );
+            })
+            .return("result, nil")
+            .u()
+            .l("})")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "cacheKey is the key of the results of calls of method with input: the prefix",
+    )
+      .comment(
+        "and method followed by a hash of the params, the tenant and, unless the cache",
+      )
+      .comment("is shared, the user.")
+      .n()
+      .func(
+        "cacheKey(ctx context.Context, options CacheOptions, method string, input interface{}) (string, error)",
+        (b) => {
+          b.decl("params, err", "json.Marshal(input)")
+            .ifErr((b) => {
+              b.return('"", err');
+            })
+            .decl("hash", "sha256.New()")
+            .decl("tenant, _", "TenantFrom(ctx)")
+            .l('hash.Write([]byte(tenant.ID + "\\x00"))')
+            .if("!options.Shared", (b) => {
+              b.decl("userID, _", "UserIDFrom(ctx)")
+                .l('hash.Write([]byte(userID + "\\x00"))');
+            })
+            .l("hash.Write(params)")
+            .return(
+              'options.Prefix + method + ":" + hex.EncodeToString(hash.Sum(nil)), nil',
+            );
+        },
+      );
+  }
+}
@@ -0,0 +1,697 @@
+import type { ContractDefinition, Property } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { paramKind, unwrap } from "./rest-generator";
+
+/** Flags every command accepts, which input field flags must not shadow. */
+const GLOBAL_FLAGS = ["url", "output", "timeout", "header", "params"];
+
+/** Turns a field name such as "dueDate" into the flag name "due-date". */
+function toFlagName(name: string): string {
+  const flag = name
+    .replace(/([a-z0-9])([A-Z])/g, "$1-$2")
+    .replace(/_/g, "-")
+    .toLowerCase();
+  return GLOBAL_FLAGS.includes(flag) ? `input-${flag}` : flag;
+}
+
+/**
+ * How the values of an input field's flag are encoded as JSON: the query
+ * parameter kinds of the REST layer, plus "bool" for flags that may be given
+ * without a value and "json" for flags read as a JSON literal.
+ */
+function flagKind(property: Property): string {
+  const kind = paramKind(property.type);
+  if (kind) {
+    return kind;
+  }
+  const type = unwrap(property.type);
+  return type.kind === "primitive" && type.baseType === "boolean"
+    ? "bool"
+    : "json";
+}
+
+/** Describes the values an input field's flag takes, for its usage. */
+function flagUsage(property: Property): string {
+  const type = unwrap(property.type);
+  let usage: string;
+  if (type.kind === "enum") {
+    usage = `one of ${(type.enumValues ?? []).join(", ")}`;
+  } else if (type.kind === "array") {
+    const item = type.elementType ? unwrap(type.elementType) : undefined;
+    const itemType = item?.kind === "primitive" ? item.baseType : "JSON";
+    usage = `${itemType} item, repeatable`;
+  } else if (property.validation?.int) {
+    usage = "integer";
+  } else if (property.validation?.date) {
+    usage = "date, such as 2024-01-31";
+  } else if (type.kind === "date" || property.validation?.datetime) {
+    usage = "RFC 3339 date-time";
+  } else if (type.kind === "primitive") {
+    usage = String(type.baseType);
+  } else {
+    usage = `JSON ${type.kind}`;
+  }
+  const optional =
+    property.type.kind === "optional" || property.type.kind === "nullable";
+  if (property.default !== undefined) {
+    return `${usage} (default ${property.default})`;
+  }
+  return property.required && !optional ? `${usage} (required)` : usage;
+}
+
+/**
+ * Generates cli.go: a command line client of the contract with a command per
+ * method and a flag per input field, for ops debugging and smoke tests. Input
+ * is validated before it is sent and results are printed as JSON or a table.
+ */
+export class GoCLIGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateCLI(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const hasSubscriptions = contract.endpoints.some(
+      (endpoint) => endpoint.type === "subscription",
+    );
+
+    w.package(this.packageName).import(
+      "bytes",
+      "context",
+      "encoding/json",
+      "errors",
+      "flag",
+      "fmt",
+      "io",
+      "os",
+      "sort",
+      "strings",
+      "text/tabwriter",
+      "time",
+    );
+
+    w.comment(
+      'CLI is a command line client of the contract. "task create" calls task.create,',
+    )
+      .comment(
+        "with a flag per field of its input; --params sets the input as a JSON object",
+      )
+      .comment("the flags are applied on top of:")
+      .comment("")
+      .comment('    todocli task create --title "X" --priority high')
+      .comment("")
+      .comment(
+        "Input is validated before it is sent, and results are printed as JSON or,",
+      )
+      .comment("with --output table, as a table. Run it from a main package:")
+      .comment("")
+      .comment(
+        `    os.Exit(${this.packageName}.NewCLI("todocli").Run(context.Background(), os.Args[1:]))`,
+      )
+      .struct("CLI", (b) => {
+        b.l("name string")
+          .comment(
+            "Options configure the Client of every call; --url and --header apply on",
+          )
+          .comment("top")
+          .l("Options []ClientOption")
+          .l("Stdout io.Writer")
+          .l("Stderr io.Writer");
+      });
+
+    w.comment(
+      "NewCLI returns a CLI called name in its usage, writing to os.Stdout and",
+    )
+      .comment("os.Stderr.")
+      .n()
+      .func("NewCLI(name string) *CLI", (b) => {
+        b.return("&CLI{name: name, Stdout: os.Stdout, Stderr: os.Stderr}");
+      });
+
+    this.generateCommands(w, contract);
+    this.generateOptions(w);
+    this.generateRun(w, hasSubscriptions);
+    this.generateInput(w);
+    this.generateOutput(w);
+
+    return w.toString();
+  }
+
+  private generateCommands(w: GoBuilder, contract: ContractDefinition): void {
+    w.comment(
+      "cliCommand is the command of a method, with the flags of its input fields.",
+    ).struct("cliCommand", (b) => {
+      b.l("method string").l("flags  []cliFlag");
+    });
+
+    w.comment(
+      "cliFlag is the flag setting an input field. kind tells how its values are",
+    )
+      .comment(
+        'encoded as JSON: "string" values are quoted, "strings" and "array" flags',
+      )
+      .comment(
+        'collect items when repeated (quoted or as JSON literals), "bool" flags may',
+      )
+      .comment(
+        "be given without a value and any other value is read as a JSON literal.",
+      )
+      .struct("cliFlag", (b) => {
+        b.l("name  string")
+          .l("field string")
+          .l("kind  string")
+          .l("usage string");
+      });
+
+    w.comment("cliCommands lists the command of every method.")
+      .l("var cliCommands = []cliCommand{")
+      .i();
+    for (const endpoint of contract.endpoints) {
+      const properties = unwrap(endpoint.input).properties ?? [];
+      w.l("{")
+        .i()
+        .l(`method: ${JSON.stringify(endpoint.fullName)},`)
+        .l("flags: []cliFlag{")
+        .i();
+      for (const property of properties) {
+        const fields = [
+          JSON.stringify(toFlagName(property.name)),
+          JSON.stringify(property.name),
+          JSON.stringify(flagKind(property)),
+          JSON.stringify(flagUsage(property)),
+        ];
+        w.l(`{${fields.join(", ")}},`);
+      }
+      w.u().l("},").u().l("},");
+    }
+    w.u().l("}").n();
+  }
+
+  private generateOptions(w: GoBuilder): void {
+    w.comment(
+      "cliOptions holds the flags every command accepts, before or after it.",
+    ).struct("cliOptions", (b) => {
+      b.l("url     string")
+        .l("output  string")
+        .l("timeout time.Duration")
+        .l("headers cliValues");
+    });
+
+    w.comment(
+      "define defines the flags every command accepts on fs, defaulting to the",
+    )
+      .comment("values parsed so far.")
+      .n()
+      .method("o *cliOptions", "define", "fs *flag.FlagSet", "", (b) => {
+        b.l(
+          'fs.StringVar(&o.url, "url", o.url, "URL the server\'s router is served at; $XRPC_URL by default")',
+        )
+          .l(
+            'fs.StringVar(&o.output, "output", o.output, "output format: json or table")',
+          )
+          .l(
+            'fs.DurationVar(&o.timeout, "timeout", o.timeout, "time limit of the call, such as 5s")',
+          )
+          .l(
+            'fs.Var(&o.headers, "header", "header sent with the call, as \\"Name: value\\"; repeatable")',
+          );
+      });
+
+    w.comment("cliValues collects the values of a repeatable flag.").type(
+      "cliValues",
+      "[]string",
+    );
+
+    w.comment("String returns the values, for flag usage.")
+      .n()
+      .method("v *cliValues", "String", "", "string", (b) => {
+        b.return('strings.Join(*v, ", ")');
+      });
+
+    w.comment("Set adds a value.")
+      .n()
+      .method("v *cliValues", "Set", "value string", "error", (b) => {
+        b.l("*v = append(*v, value)").return("nil");
+      });
+
+    w.comment("cliField collects the values of an input field's flag.").struct(
+      "cliField",
+      (b) => {
+        b.l("cliValues").l("kind string");
+      },
+    );
+
+    w.comment("IsBoolFlag lets a boolean field be set by its flag alone.")
+      .n()
+      .method("f *cliField", "IsBoolFlag", "", "bool", (b) => {
+        b.return('f.kind == "bool"');
+      });
+  }
+
+  private generateRun(w: GoBuilder, hasSubscriptions: boolean): void {
+    w.comment(
+      'Run runs the command of args, such as "task create --title X", and returns',
+    )
+      .comment(
+        "the exit code of the process: 0 on success, 1 when the call failed and 2",
+      )
+      .comment("for invalid usage or input.")
+      .n()
+      .method(
+        "cli *CLI",
+        "Run",
+        "ctx context.Context, args []string",
+        "int",
+        (b) => {
+          b.decl(
+            "options",
+            '&cliOptions{url: os.Getenv("XRPC_URL"), output: "json"}',
+          )
+            .decl("global", "flag.NewFlagSet(cli.name, flag.ContinueOnError)")
+            .l("global.SetOutput(cli.Stderr)")
+            .l("global.Usage = func() { cli.usage(global) }")
+            .l("options.define(global)")
+            .if("err := global.Parse(args); err != nil", (b) => {
+              b.return("cliExitCode(err)");
+            })
+            .decl("command, args", "findCLICommand(global.Args())")
+            .if("command == nil", (b) => {
+              b.l("cli.usage(global)").return("2");
+            })
+            .n()
+            .decl(
+              "name",
+              'cli.name + " " + strings.Replace(command.method, ".", " ", -1)',
+            )
+            .decl("fs", "flag.NewFlagSet(name, flag.ContinueOnError)")
+            .l("fs.SetOutput(cli.Stderr)")
+            .l("options.define(fs)")
+            .decl(
+              "params",
+              'fs.String("params", "", "input as a JSON object, which the field flags are applied on top of")',
+            )
+            .decl("fields", "make(map[string]*cliField, len(command.flags))")
+            .l("for _, f := range command.flags {")
+            .i()
+            .l("fields[f.field] = &cliField{kind: f.kind}")
+            .l("fs.Var(fields[f.field], f.name, f.usage)")
+            .u()
+            .l("}")
+            .if("err := fs.Parse(args); err != nil", (b) => {
+              b.return("cliExitCode(err)");
+            })
+            .if("fs.NArg() > 0", (b) => {
+              b.l(
+                'fmt.Fprintf(cli.Stderr, "%s: unexpected argument %q\\n", name, fs.Arg(0))',
+              ).return("2");
+            })
+            .if('options.url == ""', (b) => {
+              b.l(
+                'fmt.Fprintf(cli.Stderr, "%s: no server URL, set --url or $XRPC_URL\\n", name)',
+              ).return("2");
+            })
+            .if(
+              'options.output != "json" && options.output != "table"',
+              (b) => {
+                b.l(
+                  'fmt.Fprintf(cli.Stderr, "%s: unknown output format %q\\n", name, options.output)',
+                ).return("2");
+              },
+            )
+            .decl("input, err", "command.input(*params, fields)")
+            .ifErr((b) => {
+              b.l(
+                'fmt.Fprintf(cli.Stderr, "%s: invalid input: %v\\n", name, err)',
+              ).return("2");
+            })
+            .n()
+            .decl("client", "NewClient(options.url, cli.Options...)")
+            .l("for _, header := range options.headers {")
+            .i()
+            .decl("key, value, ok", 'strings.Cut(header, ":")')
+            .if("!ok", (b) => {
+              b.l(
+                'fmt.Fprintf(cli.Stderr, "%s: invalid header %q\\n", name, header)',
+              ).return("2");
+            })
+            .l(
+              "client.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))",
+            )
+            .u()
+            .l("}")
+            .if("options.timeout > 0", (b) => {
+              b.l("var cancel context.CancelFunc")
+                .l("ctx, cancel = context.WithTimeout(ctx, options.timeout)")
+                .l("defer cancel()");
+            });
+          if (hasSubscriptions) {
+            b.l('if methodTable[command.method].kind == "subscription" {')
+              .i()
+              .l(
+                "err = client.subscribe(ctx, command.method, input, func(data []byte) error {",
+              )
+              .i()
+              .return("cli.print(options.output, data)")
+              .u()
+              .l("})")
+              .u()
+              .l("} else {")
+              .i();
+          }
+          b.var("result", "json.RawMessage")
+            .if(
+              "err = client.call(ctx, command.method, input, &result); err == nil",
+              (b) => {
+                b.l("err = cli.print(options.output, result)");
+              },
+            );
+          if (hasSubscriptions) {
+            b.u().l("}");
+          }
+          b.ifErr((b) => {
+            b.l('fmt.Fprintf(cli.Stderr, "%s: %v\\n", name, err)')
+              .var("validationErrs", "ValidationErrors")
+              .if("errors.As(err, &validationErrs)", (b) => {
+                b.l("for _, fieldErr := range validationErrs {")
+                  .i()
+                  .l('fmt.Fprintf(cli.Stderr, "  %v\\n", fieldErr)')
+                  .u()
+                  .l("}");
+              })
+              .return("1");
+          }).return("0");
+        },
+      );
+
+    w.comment(
+      "cliExitCode returns the exit code of a failure to parse flags, which the flag",
+    )
+      .comment("package reported: 0 when help was asked for.")
+      .n()
+      .func("cliExitCode(err error) int", (b) => {
+        b.if("errors.Is(err, flag.ErrHelp)", (b) => {
+          b.return("0");
+        }).return("2");
+      });
+
+    w.comment(
+      'findCLICommand returns the command args start with, such as "task create"',
+    )
+      .comment("for task.create, and the arguments after it.")
+      .n()
+      .func(
+        "findCLICommand(args []string) (*cliCommand, []string)",
+        (b) => {
+          b.l("for i := range cliCommands {")
+            .i()
+            .decl("words", 'strings.Split(cliCommands[i].method, ".")')
+            .if(
+              'len(args) >= len(words) && strings.Join(args[:len(words)], ".") == cliCommands[i].method',
+              (b) => {
+                b.return("&cliCommands[i], args[len(words):]");
+              },
+            )
+            .u()
+            .l("}")
+            .return("nil, args");
+        },
+      );
+
+    w.comment(
+      "usage writes the usage of the CLI, listing its commands, to Stderr.",
+    )
+      .n()
+      .method("cli *CLI", "usage", "global *flag.FlagSet", "", (b) => {
+        b.l(
+          'fmt.Fprintf(cli.Stderr, "Usage: %s [flags] <command> [flags]\\n\\nCommands:\\n", cli.name)',
+        )
+          .decl("w", "tabwriter.NewWriter(cli.Stderr, 0, 4, 2, ' ', 0)")
+          .l("for _, command := range cliCommands {")
+          .i()
+          .decl("m", "methodTable[command.method]")
+          .decl("about", "m.kind")
+          .if('m.deprecation != ""', (b) => {
+            b.l('about += ", deprecated"');
+          })
+          .l(
+            'fmt.Fprintf(w, "  %s\\t%s\\n", strings.Replace(command.method, ".", " ", -1), about)',
+          )
+          .u()
+          .l("}")
+          .l("w.Flush()")
+          .l('fmt.Fprintf(cli.Stderr, "\\nFlags:\\n")')
+          .l("global.PrintDefaults()")
+          .l(
+            'fmt.Fprintf(cli.Stderr, "\\nRun \\"%s <command> --help\\" for the flags of a command.\\n", cli.name)',
+          );
+      });
+  }
+
+  private generateInput(w: GoBuilder): void {
+    w.comment(
+      "input decodes params, with the values of the field flags set on top, into",
+    )
+      .comment("the method's input and validates it as the router would.")
+      .n()
+      .method(
+        "command *cliCommand",
+        "input",
+        "params string, fields map[string]*cliField",
+        "(interface{}, error)",
+        (b) => {
+          b.decl("object", "map[string]json.RawMessage{}")
+            .if('params != ""', (b) => {
+              b.if(
+                "err := json.Unmarshal([]byte(params), &object); err != nil",
+                (b) => {
+                  b.return('nil, fmt.Errorf("--params: %v", err)');
+                },
+              );
+            })
+            .l("for field, f := range fields {")
+            .i()
+            .if("len(f.cliValues) > 0", (b) => {
+              b.l("object[field] = cliValue(f.kind, f.cliValues)");
+            })
+            .u()
+            .l("}")
+            .decl("data, err", "json.Marshal(object)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .decl("m", "methodTable[command.method]")
+            .comment(
+              "A Router without options decodes JSON as NewRouter's does",
+            )
+            .decl("input, err", "m.decode(&Router{}, data)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .return("input, m.validate(input)");
+        },
+      );
+
+    w.comment(
+      "cliValue encodes the values of a field flag of kind as JSON. An array field",
+    )
+      .comment(
+        "given a single JSON array takes it as is; single-valued fields take the last",
+      )
+      .comment("value.")
+      .n()
+      .func("cliValue(kind string, values []string) json.RawMessage", (b) => {
+        b.if('kind == "strings" || kind == "array"', (b) => {
+          b.if(
+            'len(values) == 1 && strings.HasPrefix(values[0], "[") && json.Valid([]byte(values[0]))',
+            (b) => {
+              b.return("json.RawMessage(values[0])");
+            },
+          )
+            .decl("items", "make([]json.RawMessage, len(values))")
+            .l("for i, value := range values {")
+            .i()
+            .l('items[i] = cliLiteral(value, kind == "strings")')
+            .u()
+            .l("}")
+            .decl("data, _", "json.Marshal(items)")
+            .return("data");
+        }).return('cliLiteral(values[len(values)-1], kind == "string")');
+      });
+
+    w.comment(
+      "cliLiteral encodes a flag value as a JSON string when quoted is set or the",
+    )
+      .comment(
+        "value is not valid JSON, and as the JSON literal it spells otherwise.",
+      )
+      .n()
+      .func("cliLiteral(value string, quoted bool) json.RawMessage", (b) => {
+        b.if("!quoted && json.Valid([]byte(value))", (b) => {
+          b.return("json.RawMessage(value)");
+        })
+          .decl("data, _", "json.Marshal(value)")
+          .return("data");
+      });
+  }
+
+  private generateOutput(w: GoBuilder): void {
+    w.comment(
+      "print writes a result or event to Stdout, as indented JSON or as a table.",
+    )
+      .n()
+      .method(
+        "cli *CLI",
+        "print",
+        "format string, data []byte",
+        "error",
+        (b) => {
+          b.if('format == "table"', (b) => {
+            b.var("value", "interface{}")
+              .decl("decoder", "json.NewDecoder(bytes.NewReader(data))")
+              .l("decoder.UseNumber()")
+              .if("err := decoder.Decode(&value); err != nil", (b) => {
+                b.return("err");
+              })
+              .decl("w", "tabwriter.NewWriter(cli.Stdout, 0, 4, 2, ' ', 0)")
+              .l("writeCLITable(w, value)")
+              .return("w.Flush()");
+          })
+            .var("out", "bytes.Buffer")
+            .if('err := json.Indent(&out, data, "", "  "); err != nil', (b) => {
+              b.return("err");
+            })
+            .l("out.WriteByte('\\n')")
+            .decl("_, err", "out.WriteTo(cli.Stdout)")
+            .return("err");
+        },
+      );
+
+    w.comment(
+      "writeCLITable writes value as a table: a list of objects with a row per item",
+    )
+      .comment(
+        "and a column per field, and an object with a row per field, followed by its",
+      )
+      .comment(
+        "first list of objects, such as the items of a page. Other values are",
+      )
+      .comment("written as JSON.")
+      .n()
+      .func("writeCLITable(w io.Writer, value interface{})", (b) => {
+        b.if("rows, ok := cliRows(value); ok", (b) => {
+          b.l("writeCLIRows(w, rows)").return();
+        })
+          .decl("object, ok", "value.(map[string]interface{})")
+          .if("!ok", (b) => {
+            b.l("fmt.Fprintln(w, cliCell(value))").return();
+          })
+          .var("list", "[]map[string]interface{}")
+          .l("for _, key := range sortedCLIKeys(object) {")
+          .i()
+          .if("rows, ok := cliRows(object[key]); ok && list == nil", (b) => {
+            b.l("list = rows").l("continue");
+          })
+          .l('fmt.Fprintf(w, "%s\\t%s\\n", key, cliCell(object[key]))')
+          .u()
+          .l("}")
+          .if("list != nil", (b) => {
+            b.if("len(object) > 1", (b) => {
+              b.l("fmt.Fprintln(w)");
+            }).l("writeCLIRows(w, list)");
+          });
+      });
+
+    w.comment(
+      "writeCLIRows writes a row per object of rows under a header naming their",
+    )
+      .comment("fields.")
+      .n()
+      .func("writeCLIRows(w io.Writer, rows []map[string]interface{})", (b) => {
+        b.decl("seen", "map[string]interface{}{}")
+          .l("for _, row := range rows {")
+          .i()
+          .l("for key := range row {")
+          .i()
+          .l("seen[key] = nil")
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .decl("columns", "sortedCLIKeys(seen)")
+          .l(
+            'fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\\t")))',
+          )
+          .l("for _, row := range rows {")
+          .i()
+          .decl("cells", "make([]string, len(columns))")
+          .l("for i, column := range columns {")
+          .i()
+          .l("cells[i] = cliCell(row[column])")
+          .u()
+          .l("}")
+          .l('fmt.Fprintln(w, strings.Join(cells, "\\t"))')
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "cliRows returns value as a list of objects, if it is a non-empty one.",
+    )
+      .n()
+      .func(
+        "cliRows(value interface{}) ([]map[string]interface{}, bool)",
+        (b) => {
+          b.decl("items, ok", "value.([]interface{})")
+            .if("!ok || len(items) == 0", (b) => {
+              b.return("nil, false");
+            })
+            .decl("rows", "make([]map[string]interface{}, len(items))")
+            .l("for i, item := range items {")
+            .i()
+            .if("rows[i], ok = item.(map[string]interface{}); !ok", (b) => {
+              b.return("nil, false");
+            })
+            .u()
+            .l("}")
+            .return("rows, true");
+        },
+      );
+
+    w.comment(
+      "cliCell returns a value as a table cell: strings as they are, nothing for",
+    )
+      .comment("null and JSON for anything else.")
+      .n()
+      .func("cliCell(value interface{}) string", (b) => {
+        b.l("switch value := value.(type) {")
+          .l("case nil:")
+          .i()
+          .return('""')
+          .u()
+          .l("case string:")
+          .i()
+          .return("value")
+          .u()
+          .l("}")
+          .decl("data, _", "json.Marshal(value)")
+          .return("string(data)");
+      });
+
+    w.comment("sortedCLIKeys returns the keys of object in order.")
+      .n()
+      .func("sortedCLIKeys(object map[string]interface{}) []string", (b) => {
+        b.decl("keys", "make([]string, 0, len(object))")
+          .l("for key := range object {")
+          .i()
+          .l("keys = append(keys, key)")
+          .u()
+          .l("}")
+          .l("sort.Strings(keys)")
+          .return("keys");
+      });
+  }
+}
@@ -0,0 +1,589 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import {
+  OPERATION_CANCEL_METHOD,
+  OPERATION_GET_METHOD,
+  toMethodName,
+} from "./server-generator";
+
+/**
+ * Generates client.go: a Client calling a server of the contract over HTTP,
+ * with a typed method per endpoint and the ClientOptions configuring it, so
+ * Go services calling one another share the contract's types instead of
+ * hand-writing requests.
+ */
+export class GoClientGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateClient(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const hasSubscriptions = contract.endpoints.some(
+      (endpoint) => endpoint.type === "subscription",
+    );
+
+    const imports = [
+      "bytes",
+      "context",
+      "encoding/json",
+      "fmt",
+      "io",
+      "net/http",
+      "strconv",
+      "time",
+    ];
+    if (hasSubscriptions) {
+      imports.unshift("bufio");
+      imports.splice(imports.indexOf("time"), 0, "strings");
+    }
+    w.package(this.packageName).import(...imports);
+
+    w.comment(
+      "Client calls a server of the contract over HTTP, with a typed method per",
+    )
+      .comment(
+        "endpoint. Errors the server answers with are returned as *Error, wrapping",
+      )
+      .comment(
+        "the ValidationErrors of invalid input so errors.As finds them, and failed",
+      )
+      .comment("queries are retried as its RetryPolicy allows.")
+      .struct("Client", (b) => {
+        b.l("url string")
+          .l("httpClient *http.Client")
+          .l("retry RetryPolicy")
+          .l("breakers *circuitBreakers")
+          .l("hedgeDelay time.Duration")
+          .l("hedgedMethods map[string]bool")
+          .l("interceptors []ClientInterceptorFunc")
+          .comment("Header is sent with every call, e.g. to authenticate.")
+          .l("Header http.Header");
+      });
+
+    w.comment("ClientOption configures a Client created with NewClient.")
+      .type("ClientOption", "func(c *Client)");
+
+    w.comment(
+      "ClientNextFunc sends req through the rest of a Client's interceptor chain and",
+    )
+      .comment("finally to the server.")
+      .type(
+        "ClientNextFunc",
+        "func(ctx context.Context, req *http.Request) (*http.Response, error)",
+      );
+
+    w.comment(
+      "ClientInterceptorFunc wraps every request a Client sends for a call of",
+    )
+      .comment(
+        "method, the mirror of a router's InterceptorFunc: it may add headers to req,",
+      )
+      .comment(
+        "such as credentials or trace context, change the context passed to next,",
+      )
+      .comment(
+        "and log, time or count the response. Every attempt of a retried or hedged",
+      )
+      .comment("call is intercepted.")
+      .type(
+        "ClientInterceptorFunc",
+        "func(ctx context.Context, method string, req *http.Request, next ClientNextFunc) (*http.Response, error)",
+      );
+
+    w.comment(
+      "NewClient returns a Client POSTing calls to url, where the server's router is",
+    )
+      .comment(
+        'served, such as "https://api.example.com/api". Without options it sends',
+      )
+      .comment(
+        "requests through a transport with the default TransportOptions and retries",
+      )
+      .comment("with DefaultRetryPolicy.")
+      .n()
+      .func("NewClient(url string, options ...ClientOption) *Client", (b) => {
+        b.decl(
+          "c",
+          "&Client{url: url, retry: DefaultRetryPolicy, Header: http.Header{}}",
+        )
+          .l(
+            "c.httpClient = &http.Client{Transport: newTransport(TransportOptions{})}",
+          )
+          .l("for _, option := range options {")
+          .i()
+          .l("option(c)")
+          .u()
+          .l("}")
+          .return("c");
+      });
+
+    w.comment("WithHTTPClient sends the Client's requests through httpClient.")
+      .n()
+      .func("WithHTTPClient(httpClient *http.Client) ClientOption", (b) => {
+        b.l("return func(c *Client) {")
+          .i()
+          .l("c.httpClient = httpClient")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "WithRetryPolicy replaces DefaultRetryPolicy; RetryPolicy{MaxAttempts: 1} turns",
+    )
+      .comment("retrying off.")
+      .n()
+      .func("WithRetryPolicy(policy RetryPolicy) ClientOption", (b) => {
+        b.l("return func(c *Client) {")
+          .i()
+          .l("c.retry = policy")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "WithInterceptor adds an interceptor to the Client; the first added is the",
+    )
+      .comment("outermost.")
+      .n()
+      .func(
+        "WithInterceptor(interceptor ClientInterceptorFunc) ClientOption",
+        (b) => {
+          b.l("return func(c *Client) {")
+            .i()
+            .l("c.interceptors = append(c.interceptors, interceptor)")
+            .u()
+            .l("}");
+        },
+      );
+
+    for (const endpoint of contract.endpoints) {
+      const name = toMethodName(endpoint.fullName);
+      const inputType = toPascalCase(endpoint.input.name!);
+      const outputType = toPascalCase(endpoint.output.name!);
+      const method = JSON.stringify(endpoint.fullName);
+
+      if (endpoint.type === "subscription") {
+        w.comment(
+          `${name} calls ${endpoint.fullName} and passes every event the server sends to`,
+        )
+          .comment(
+            "onEvent, until the subscription ends, ctx is cancelled or onEvent returns",
+          )
+          .comment("an error.")
+          .n()
+          .method(
+            "c *Client",
+            name,
+            `ctx context.Context, input ${inputType}, onEvent func(event ${outputType}) error`,
+            "error",
+            (b) => {
+              b.l(
+                `return c.subscribe(ctx, ${method}, input, func(data []byte) error {`,
+              )
+                .i()
+                .var("event", outputType)
+                .if("err := json.Unmarshal(data, &event); err != nil", (b) => {
+                  b.return("err");
+                })
+                .return("onEvent(event)")
+                .u()
+                .l("})");
+            },
+          );
+        continue;
+      }
+
+      if (endpoint.async) {
+        w.comment(
+          `${name} calls ${endpoint.fullName}, which answers with the Operation running it;`,
+        )
+          .comment("poll it with GetOperation until it is done.")
+          .n()
+          .method(
+            "c *Client",
+            name,
+            `ctx context.Context, input ${inputType}`,
+            "(Operation, error)",
+            (b) => {
+              b.return(`c.callOperation(ctx, ${method}, input)`);
+            },
+          );
+        continue;
+      }
+
+      w.comment(`${name} calls ${endpoint.fullName}.`)
+        .n()
+        .method(
+          "c *Client",
+          name,
+          `ctx context.Context, input ${inputType}`,
+          `(${outputType}, error)`,
+          (b) => {
+            b.var("output", outputType)
+              .l(`err := c.call(ctx, ${method}, input, &output)`)
+              .return("output, err");
+          },
+        );
+    }
+
+    if (contract.endpoints.some((endpoint) => endpoint.async)) {
+      this.generateOperations(w);
+    }
+    this.generateTransport(w, hasSubscriptions);
+
+    return w.toString();
+  }
+
+  private generateOperations(w: GoBuilder): void {
+    w.comment(
+      `GetOperation calls ${OPERATION_GET_METHOD}, returning the operation of an async mutation`,
+    )
+      .comment("with the given ID.")
+      .n()
+      .method(
+        "c *Client",
+        "GetOperation",
+        "ctx context.Context, id string",
+        "(Operation, error)",
+        (b) => {
+          b.return(
+            `c.callOperation(ctx, "${OPERATION_GET_METHOD}", operationParams{OperationID: id})`,
+          );
+        },
+      );
+
+    w.comment(
+      `CancelOperation calls ${OPERATION_CANCEL_METHOD}, asking the server to cancel the`,
+    )
+      .comment("operation with the given ID.")
+      .n()
+      .method(
+        "c *Client",
+        "CancelOperation",
+        "ctx context.Context, id string",
+        "(Operation, error)",
+        (b) => {
+          b.return(
+            `c.callOperation(ctx, "${OPERATION_CANCEL_METHOD}", operationParams{OperationID: id})`,
+          );
+        },
+      );
+
+    w.comment(
+      "callOperation calls method and decodes the Operation it answers with, keeping",
+    )
+      .comment("its result as json.RawMessage.")
+      .n()
+      .method(
+        "c *Client",
+        "callOperation",
+        "ctx context.Context, method string, input interface{}",
+        "(Operation, error)",
+        (b) => {
+          b.l("var output struct {")
+            .i()
+            .l("Operation")
+            .l('Result json.RawMessage `json:"result,omitempty"`')
+            .u()
+            .l("}")
+            .if(
+              "err := c.call(ctx, method, input, &output); err != nil",
+              (b) => {
+                b.return("Operation{}, err");
+              },
+            )
+            .decl("op", "output.Operation")
+            .if("len(output.Result) > 0", (b) => {
+              b.l("op.Result = output.Result");
+            })
+            .return("op, nil");
+        },
+      );
+  }
+
+  private generateTransport(w: GoBuilder, hasSubscriptions: boolean): void {
+    w.comment(
+      "post POSTs the envelope body of a call of method to the server. Reading body",
+    )
+      .comment(
+        "from a *bytes.Reader sends it with a Content-Length rather than chunked, and",
+      )
+      .comment(
+        "lets the transport resend calls that are safe to repeat when the server closed",
+      )
+      .comment(
+        "the keep-alive connection they were sent on. The request passes through the",
+      )
+      .comment(
+        "Client's interceptors first. When ctx has a deadline, the time left when the",
+      )
+      .comment(
+        "request is sent goes with it in the X-Xrpc-Timeout header, so the server",
+      )
+      .comment(
+        "stops working on the call once the caller stops waiting for it and hands",
+      )
+      .comment("what is left of the budget on to the services it calls.")
+      .n()
+      .method(
+        "c *Client",
+        "post",
+        "ctx context.Context, method string, body []byte",
+        "(*http.Response, error)",
+        (b) => {
+          b.l(
+            "req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))",
+          )
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l('req.Header.Set("Content-Type", "application/json")')
+            .l("for key, values := range c.Header {")
+            .i()
+            .comment("Copied, as interceptors may add to them")
+            .l("req.Header[key] = append([]string(nil), values...)")
+            .u()
+            .l("}")
+            .if(
+              'm, ok := methodTable[method]; ok && m.kind != "mutation"',
+              (b) => {
+                b.comment(
+                  "A nil Idempotency-Key marks the request idempotent for the transport",
+                )
+                  .comment("without being sent")
+                  .l('req.Header["Idempotency-Key"] = nil');
+              },
+            )
+            .decl(
+              "next",
+              "func(ctx context.Context, req *http.Request) (*http.Response, error) {",
+            )
+            .i()
+            .if("deadline, ok := ctx.Deadline(); ok", (b) => {
+              b.comment(
+                "Rounded up, so a budget of less than a millisecond is not sent as 0",
+              )
+                .decl("left", "time.Until(deadline) + time.Millisecond - 1")
+                .l(
+                  'req.Header.Set("X-Xrpc-Timeout", strconv.FormatInt(int64(left/time.Millisecond), 10))',
+                );
+            })
+            .l("return c.httpClient.Do(req.WithContext(ctx))")
+            .u()
+            .l("}")
+            .l("for i := len(c.interceptors) - 1; i >= 0; i-- {")
+            .i()
+            .l("interceptor, inner := c.interceptors[i], next")
+            .l(
+              "next = func(ctx context.Context, req *http.Request) (*http.Response, error) {",
+            )
+            .i()
+            .l("return interceptor(ctx, method, req, inner)")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .return("next(ctx, req)");
+        },
+      );
+
+    w.comment(
+      "call calls method with input and decodes its result into output, retrying",
+    )
+      .comment(
+        "the failures the retry policy allows unless the method's circuit breaker",
+      )
+      .comment("opens.")
+      .n()
+      .method(
+        "c *Client",
+        "call",
+        "ctx context.Context, method string, input, output interface{}",
+        "error",
+        (b) => {
+          b.decl("body, err", "callBody(method, input)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .decl("start", "time.Now()")
+            .l("for attempt := 1; ; attempt++ {")
+            .i()
+            .if("!c.breakers.allow(method)", (b) => {
+              b.return("ErrCircuitOpen");
+            })
+            .decl("result, retryAfter, err", "c.send(ctx, method, body)")
+            .l("c.breakers.record(method, err)")
+            .if("err == nil", (b) => {
+              b.return("json.Unmarshal(result, output)");
+            })
+            .l(
+              "wait, ok := c.retry.backoff(ctx, method, err, attempt, retryAfter, time.Since(start))",
+            )
+            .if("!ok", (b) => {
+              b.return("err");
+            })
+            .decl("timer", "time.NewTimer(wait)")
+            .l("select {")
+            .l("case <-ctx.Done():")
+            .i()
+            .l("timer.Stop()")
+            .return("err")
+            .u()
+            .l("case <-timer.C:")
+            .l("}")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "attempt makes one attempt at a call, returning its result or the wait the",
+    )
+      .comment(
+        "server asked for with Retry-After when it failed. Errors are read from the",
+      )
+      .comment(
+        "envelope whatever the status, as SetErrorStatus can keep them in-band with",
+      )
+      .comment("200.")
+      .n()
+      .method(
+        "c *Client",
+        "attempt",
+        "ctx context.Context, method string, body []byte",
+        "(json.RawMessage, time.Duration, error)",
+        (b) => {
+          b.decl("resp, err", "c.post(ctx, method, body)")
+            .ifErr((b) => {
+              b.return("nil, 0, err");
+            })
+            .l("defer resp.Body.Close()")
+            .decl("data, err", "io.ReadAll(resp.Body)")
+            .ifErr((b) => {
+              b.return("nil, 0, err");
+            })
+            .if("resp.StatusCode != http.StatusOK", (b) => {
+              b.decl("err", "responseError(method, data)")
+                .comment(
+                  "Proxies in front of the server answer without an error envelope",
+                )
+                .if(
+                  "_, ok := err.(*Error); !ok && gatewayStatus(resp.StatusCode)",
+                  (b) => {
+                    b.l("err = NewError(CodeUnavailable, resp.Status)");
+                  },
+                )
+                .return('nil, retryAfter(resp.Header.Get("Retry-After")), err');
+            })
+            .var(
+              "response",
+              'struct {\n\t\tResult json.RawMessage  `json:"result"`\n\t\tError  *json.RawMessage `json:"error"`\n\t}',
+            )
+            .if("err := json.Unmarshal(data, &response); err != nil", (b) => {
+              b.return(
+                'nil, 0, fmt.Errorf("decoding %s response: %w", method, err)',
+              );
+            })
+            .if("response.Error != nil", (b) => {
+              b.return(
+                'nil, retryAfter(resp.Header.Get("Retry-After")), responseError(method, data)',
+              );
+            })
+            .return("response.Result, 0, nil");
+        },
+      );
+
+    if (hasSubscriptions) {
+      w.comment(
+        "subscribe calls method with input and passes the data of every event the",
+      )
+        .comment("server sends to onEvent, stopping at an error event.")
+        .n()
+        .method(
+          "c *Client",
+          "subscribe",
+          "ctx context.Context, method string, input interface{}, onEvent func(data []byte) error",
+          "error",
+          (b) => {
+            b.decl("body, err", "callBody(method, input)")
+              .ifErr((b) => {
+                b.return("err");
+              })
+              .decl("resp, err", "c.post(ctx, method, body)")
+              .ifErr((b) => {
+                b.return("err");
+              })
+              .l("defer resp.Body.Close()")
+              .comment(
+                "Errors kept in-band with SetErrorStatus come as a 200 JSON envelope",
+              )
+              .if(
+                'resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")',
+                (b) => {
+                  b.decl("data, _", "io.ReadAll(resp.Body)").return(
+                    "responseError(method, data)",
+                  );
+                },
+              )
+              .var("event", "string")
+              .decl("scanner", "bufio.NewScanner(resp.Body)")
+              .l("scanner.Buffer(nil, 16<<20)")
+              .l("for scanner.Scan() {")
+              .i()
+              .decl("line", "scanner.Text()")
+              .l("switch {")
+              .l('case strings.HasPrefix(line, "event: "):')
+              .i()
+              .l('event = strings.TrimPrefix(line, "event: ")')
+              .u()
+              .l('case strings.HasPrefix(line, "data: "):')
+              .i()
+              .decl("data", '[]byte(strings.TrimPrefix(line, "data: "))')
+              .if('event == "error"', (b) => {
+                b.return("responseError(method, data)");
+              })
+              .if("err := onEvent(data); err != nil", (b) => {
+                b.return("err");
+              })
+              .l('event = ""')
+              .u()
+              .l("}")
+              .u()
+              .l("}")
+              .return("scanner.Err()");
+          },
+        );
+    }
+
+    w.comment("callBody encodes the envelope of a call of method with input.")
+      .n()
+      .func(
+        "callBody(method string, input interface{}) ([]byte, error)",
+        (b) => {
+          b.l("return json.Marshal(struct {")
+            .i()
+            .l('Method string      `json:"method"`')
+            .l('Params interface{} `json:"params"`')
+            .u()
+            .l("}{method, input})");
+        },
+      );
+
+    w.comment(
+      "gatewayStatus reports whether status is one proxies answer with when the",
+    )
+      .comment("server is unreachable or overloaded.")
+      .n()
+      .func("gatewayStatus(status int) bool", (b) => {
+        b.return(
+          "status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout",
+        );
+      });
+  }
+}
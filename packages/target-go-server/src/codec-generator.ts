@@ -0,0 +1,1182 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates codec.go: the Codec interface params are decoded and results
+ * encoded with, encoding/json unless Router.SetCodec plugs in another
+ * implementation, and MessagePack and CBOR wire encodings negotiated with the
+ * Content-Type of POST requests and the Accept header of responses. Payloads
+ * are transcoded to and from JSON, so they decode into the generated types and
+ * are validated exactly like JSON ones; the codecs are written against the
+ * standard library only. Errors and subscription events are always JSON.
+ */
+export class GoCodecGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate codec.go.
+   * @param staticJSON - Whether json.go was generated, so results encode through
+   * the appendJSON methods of the types
+   */
+  generateCodec(staticJSON = false): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "bytes",
+      "encoding/binary",
+      "encoding/json",
+      "errors",
+      "fmt",
+      "io",
+      "math",
+      "mime",
+      "net/http",
+      "reflect",
+      "sort",
+      "strings",
+    );
+
+    this.generateJSONCodec(w, staticJSON);
+    this.generateParamsErrors(w);
+    this.generateRegistry(w);
+    this.generateNegotiation(w);
+    this.generateMsgpackEncoder(w);
+    this.generateCBOREncoder(w);
+    this.generateDecoder(w);
+    this.generateMsgpackDecoder(w);
+    this.generateCBORDecoder(w);
+
+    return w.toString();
+  }
+
+  private generateJSONCodec(w: GoBuilder, staticJSON: boolean): void {
+    w.comment(
+      "Codec decodes the JSON params of calls and encodes their results. Routers use",
+    )
+      .comment(
+        "encoding/json by default; Router.SetCodec replaces it, such as with a faster",
+      )
+      .comment(
+        "implementation. Request envelopes, errors, streams and subscription events",
+      )
+      .comment("are always encoded with encoding/json.")
+      .type(
+        "Codec interface",
+        "{\n\tMarshal(v interface{}) ([]byte, error)\n\tUnmarshal(data []byte, v interface{}) error\n}",
+      );
+
+    w.comment("StdCodec is the Codec of encoding/json.")
+      .type("StdCodec", "struct{}");
+
+    w.comment("Marshal encodes v with json.Marshal.")
+      .n()
+      .method("StdCodec", "Marshal", "v interface{}", "([]byte, error)", (b) => {
+        b.return("json.Marshal(v)");
+      });
+
+    w.comment("Unmarshal decodes data into v with json.Unmarshal.")
+      .n()
+      .method(
+        "StdCodec",
+        "Unmarshal",
+        "data []byte, v interface{}",
+        "error",
+        (b) => {
+          b.return("json.Unmarshal(data, v)");
+        },
+      );
+
+    w.comment(
+      "SetCodec sets the Codec decoding params and encoding results, instead of",
+    )
+      .comment("encoding/json. Set it before serving requests.")
+      .n()
+      .method("r *Router", "SetCodec", "codec Codec", "*Router", (b) => {
+        b.l("r.codec = codec").return("r");
+      });
+
+    if (staticJSON) {
+      w.comment(
+        "jsonAppender is implemented by the types json.go encodes without reflection.",
+      ).type(
+        "jsonAppender interface",
+        "{\n\tappendJSON(dst []byte) ([]byte, error)\n}",
+      );
+    }
+
+    w.comment(
+      "marshal encodes result into a pooled buffer with the router's Codec, in the",
+    )
+      .comment('{"result": ...} envelope unless envelope is false.')
+      .n()
+      .method(
+        "r *Router",
+        "marshal",
+        "result interface{}, envelope bool",
+        "(*encodeBuffer, error)",
+        (b) => {
+          if (staticJSON) {
+            b.decl("appender, static", "result.(jsonAppender)").if(
+              "r.codec == nil && !static",
+              (b) => {
+                b.if("envelope", (b) => {
+                  b.return("encodeJSON(resultEnvelope{Result: result})");
+                }).return("encodeJSON(result)");
+              },
+            );
+          } else {
+            b.if("r.codec == nil", (b) => {
+              b.if("envelope", (b) => {
+                b.return("encodeJSON(resultEnvelope{Result: result})");
+              }).return("encodeJSON(result)");
+            });
+          }
+          b.n()
+            .decl("buf", "encodeBuffers.Get().(*encodeBuffer)")
+            .if("envelope", (b) => {
+              b.l('buf.WriteString(`{"result":`)');
+            })
+            .var("data", "[]byte")
+            .var("err", "error");
+          if (staticJSON) {
+            b.l("switch {")
+              .l("case r.codec != nil:")
+              .i()
+              .l("data, err = r.codec.Marshal(result)")
+              .u()
+              .l("default:")
+              .i()
+              .comment("Appended in place, so the Write below copies nothing")
+              .l("data, err = appender.appendJSON(buf.AvailableBuffer())")
+              .u()
+              .l("}");
+          } else {
+            b.l("data, err = r.codec.Marshal(result)");
+          }
+          b.ifErr((b) => {
+            b.l("buf.release()").return("nil, err");
+          })
+            .l("buf.Write(data)")
+            .if("envelope", (b) => {
+              b.l("buf.WriteByte('}')");
+            })
+            .l("buf.WriteByte('\\n')")
+            .return("buf, nil");
+        },
+      );
+
+    w.comment("unmarshal decodes the params of a call with the router's Codec.")
+      .n()
+      .method(
+        "r *Router",
+        "unmarshal",
+        "params []byte, input interface{}",
+        "error",
+        (b) => {
+          b.if("r.codec == nil", (b) => {
+            if (staticJSON) {
+              b.comment("json.go's decoders need no validation pass first")
+                .if("decoder, ok := input.(json.Unmarshaler); ok", (b) => {
+                  b.return("decoder.UnmarshalJSON(params)");
+                });
+            }
+            b.return("json.Unmarshal(params, input)");
+          }).return("r.codec.Unmarshal(params, input)");
+        },
+      );
+  }
+
+  private generateParamsErrors(w: GoBuilder): void {
+    w.comment(
+      "paramsError reports an error decoding params: values of the wrong JSON type",
+    )
+      .comment(
+        'as ValidationErrors on their field, such as "tasks[1].title: must be a',
+      )
+      .comment(
+        'string, got number", or without a path when params themselves are of the',
+      )
+      .comment(
+        'wrong type ("must be an object, got array"), and syntax errors with their',
+      )
+      .comment(
+        "offset in params. Other errors, such as unknown enum values, keep their",
+      )
+      .comment("message.")
+      .n()
+      .func("paramsError(params []byte, err error) error", (b) => {
+        b.var("typeErr", "*json.UnmarshalTypeError")
+          .if("errors.As(err, &typeErr) && typeErr.Type != nil", (b) => {
+            b.decl("path", "pathAt(params, typeErr.Offset)")
+              .return("ValidationErrors{{")
+              .i()
+              .l("Field:   fieldOf(path),")
+              .l("Path:    path,")
+              .l(
+                'Message: fmt.Sprintf("must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value),',
+              )
+              .u()
+              .l("}}");
+          })
+          .var("syntaxErr", "*json.SyntaxError")
+          .if("errors.As(err, &syntaxErr)", (b) => {
+            b.return(
+              'Errorf(CodeInvalidArgument, "Invalid params: %v at offset %d", err, syntaxErr.Offset)',
+            );
+          })
+          .return('Errorf(CodeInvalidArgument, "Invalid params: %v", err)');
+      })
+      .n();
+
+    w.comment(
+      "pathAt returns the path of the value of data that ends at offset, or of the",
+    )
+      .comment(
+        "array or object starting there, reading the tokens of data up to offset.",
+      )
+      .n()
+      .func("pathAt(data []byte, offset int64) []PathSegment", (b) => {
+        b.comment(
+          "A container of the current value, with the key or index of the value",
+        )
+          .l("type container struct {")
+          .i()
+          .l("segment   PathSegment")
+          .l("object    bool")
+          .l("expectKey bool")
+          .u()
+          .l("}")
+          .var("containers", "[]container")
+          .var("path", "[]PathSegment")
+          .decl("decoder", "json.NewDecoder(bytes.NewReader(data))")
+          .l("for decoder.InputOffset() < offset {")
+          .i()
+          .decl("token, err", "decoder.Token()")
+          .ifErr((b) => {
+            b.return("path");
+          })
+          .if("token == json.Delim('}') || token == json.Delim(']')", (b) => {
+            b.l("containers = containers[:len(containers)-1]").l("continue");
+          })
+          .if("n := len(containers); n > 0", (b) => {
+            b.decl("top", "&containers[n-1]")
+              .if("top.expectKey", (b) => {
+                b.l("top.segment.Key, _ = token.(string)")
+                  .l("top.expectKey = false")
+                  .l("continue");
+              })
+              .l("if top.object {")
+              .i()
+              .l("top.expectKey = true")
+              .u()
+              .l("} else {")
+              .i()
+              .l("top.segment.Index++")
+              .u()
+              .l("}");
+          })
+          .l("path = path[:0]")
+          .l("for _, c := range containers {")
+          .i()
+          .l("path = append(path, c.segment)")
+          .u()
+          .l("}")
+          .l("switch token {")
+          .l("case json.Delim('{'):")
+          .i()
+          .l(
+            "containers = append(containers, container{object: true, expectKey: true})",
+          )
+          .u()
+          .l("case json.Delim('['):")
+          .i()
+          .l(
+            "containers = append(containers, container{segment: PathSegment{Index: -1, IsIndex: true}})",
+          )
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .return("path");
+      })
+      .n();
+
+    w.comment('fieldOf renders path as a field name, such as "tasks[1].title".')
+      .n()
+      .func("fieldOf(path []PathSegment) string", (b) => {
+        b.var("field", "strings.Builder")
+          .l("for _, segment := range path {")
+          .i()
+          .l("switch {")
+          .l("case segment.IsIndex:")
+          .i()
+          .l('fmt.Fprintf(&field, "[%d]", segment.Index)')
+          .u()
+          .l("case field.Len() > 0:")
+          .i()
+          .l("field.WriteByte('.')")
+          .l("field.WriteString(segment.Key)")
+          .u()
+          .l("default:")
+          .i()
+          .l("field.WriteString(segment.Key)")
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .return("field.String()");
+      })
+      .n();
+
+    w.comment("jsonTypeName names the JSON type values of t decode from.")
+      .n()
+      .func("jsonTypeName(t reflect.Type) string", (b) => {
+        b.l("for t.Kind() == reflect.Ptr {")
+          .i()
+          .l("t = t.Elem()")
+          .u()
+          .l("}")
+          .l("switch t.Kind() {")
+          .l("case reflect.String:")
+          .i()
+          .return('"a string"')
+          .u()
+          .l("case reflect.Bool:")
+          .i()
+          .return('"a boolean"')
+          .u()
+          .l(
+            "case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,",
+          )
+          .i()
+          .l(
+            "reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:",
+          )
+          .return('"an integer"')
+          .u()
+          .l("case reflect.Float32, reflect.Float64:")
+          .i()
+          .return('"a number"')
+          .u()
+          .l("case reflect.Slice, reflect.Array:")
+          .i()
+          .if("t.Elem().Kind() == reflect.Uint8", (b) => {
+            b.return('"a base64 string"');
+          })
+          .return('"an array"')
+          .u()
+          .l("}")
+          .return('"an object"');
+      })
+      .n();
+  }
+
+  private generateRegistry(w: GoBuilder): void {
+    w.comment(
+      "wireCodec is a binary encoding accepted and sent besides JSON. Values are the",
+    )
+      .comment(
+        "ones encoding/json decodes to: nil, bool, json.Number or another number,",
+      )
+      .comment("string, []interface{} and map[string]interface{}.")
+      .struct("wireCodec", (b) => {
+        b.l("contentType string")
+          .l("decode      func(data []byte) (interface{}, error)")
+          .l("encode      func(value interface{}) ([]byte, error)");
+      });
+
+    w.comment(
+      "wireCodecs maps the media types of the binary encodings to them.",
+    )
+      .l("var wireCodecs = map[string]wireCodec{")
+      .i()
+      .l(
+        '"application/msgpack":   {"application/msgpack", decodeMsgpack, encodeMsgpack},',
+      )
+      .l(
+        '"application/x-msgpack": {"application/msgpack", decodeMsgpack, encodeMsgpack},',
+      )
+      .l(
+        '"application/cbor":      {"application/cbor", decodeCBOR, encodeCBOR},',
+      )
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("maxDecodeDepth bounds the nesting of decoded arrays and maps.")
+      .l("const maxDecodeDepth = 1000")
+      .n();
+  }
+
+  private generateNegotiation(w: GoBuilder): void {
+    w.comment(
+      "codecFor returns the binary encoding a media type names, if any.",
+    )
+      .n()
+      .func("codecFor(mediaType string) (wireCodec, bool)", (b) => {
+        b.l("mediaType, _, err := mime.ParseMediaType(mediaType)")
+          .ifErr((b) => {
+            b.return("wireCodec{}, false");
+          })
+          .l("codec, ok := wireCodecs[mediaType]")
+          .return("codec, ok");
+      });
+
+    w.comment(
+      "decodeRequest decodes the envelope of a POST request as JSON, or as MessagePack",
+    )
+      .comment("or CBOR when its Content-Type names them.")
+      .n()
+      .func(
+        "decodeRequest(req *http.Request, request interface{}) error",
+        (b) => {
+          b.l('codec, ok := codecFor(req.Header.Get("Content-Type"))')
+            .if("!ok", (b) => {
+              b.return("json.NewDecoder(req.Body).Decode(request)");
+            })
+            .l("data, err := io.ReadAll(req.Body)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("value, err := codec.decode(data)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("body, err := json.Marshal(value)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .return("json.Unmarshal(body, request)");
+        },
+      );
+
+    w.comment(
+      "encodeResult transcodes a JSON result body to the first binary encoding the",
+    )
+      .comment(
+        "request's Accept header names, returning the body and its Content-Type.",
+      )
+      .n()
+      .func(
+        "encodeResult(req *http.Request, body []byte) ([]byte, string)",
+        (b) => {
+          b.l(
+            'for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {',
+          )
+            .i()
+            .l("codec, ok := codecFor(accepted)")
+            .if("!ok", (b) => {
+              b.l("continue");
+            })
+            .decl("decoder", "json.NewDecoder(bytes.NewReader(body))")
+            .l("decoder.UseNumber()")
+            .var("value", "interface{}")
+            .if("err := decoder.Decode(&value); err != nil", (b) => {
+              b.l("break");
+            })
+            .l("encoded, err := codec.encode(value)")
+            .ifErr((b) => {
+              b.l("break");
+            })
+            .return("encoded, codec.contentType")
+            .u()
+            .l("}")
+            .return('body, "application/json"');
+        },
+      );
+  }
+
+  private generateMsgpackEncoder(w: GoBuilder): void {
+    w.comment(
+      "encodeMsgpack encodes a value as MessagePack, with map keys sorted so equal",
+    )
+      .comment("values encode to equal bytes.")
+      .n()
+      .func("encodeMsgpack(value interface{}) ([]byte, error)", (b) => {
+        b.return("appendMsgpack(nil, value)");
+      });
+
+    w.func(
+      "appendMsgpack(buf []byte, value interface{}) ([]byte, error)",
+      (b) => {
+        b.l("switch v := value.(type) {")
+          .l("case nil:")
+          .i()
+          .return("append(buf, 0xc0), nil")
+          .u()
+          .l("case bool:")
+          .i()
+          .if("v", (b) => {
+            b.return("append(buf, 0xc3), nil");
+          })
+          .return("append(buf, 0xc2), nil")
+          .u()
+          .l("case json.Number:")
+          .i()
+          .if("i, err := v.Int64(); err == nil", (b) => {
+            b.return("appendMsgpackInt(buf, i), nil");
+          })
+          .l("f, err := v.Float64()")
+          .ifErr((b) => {
+            b.return("nil, err");
+          })
+          .return(
+            "binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(f)), nil",
+          )
+          .u()
+          .l("case float64:")
+          .i()
+          .return(
+            "binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(v)), nil",
+          )
+          .u()
+          .l("case string:")
+          .i()
+          .l(
+            "buf = appendMsgpackLength(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)",
+          )
+          .return("append(buf, v...), nil")
+          .u()
+          .l("case []interface{}:")
+          .i()
+          .l("buf = appendMsgpackLength(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)")
+          .l("for _, item := range v {")
+          .i()
+          .var("err", "error")
+          .if("buf, err = appendMsgpack(buf, item); err != nil", (b) => {
+            b.return("nil, err");
+          })
+          .u()
+          .l("}")
+          .return("buf, nil")
+          .u()
+          .l("case map[string]interface{}:")
+          .i()
+          .l("buf = appendMsgpackLength(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)")
+          .l("for _, key := range sortedKeys(v) {")
+          .i()
+          .var("err", "error")
+          .l("buf, _ = appendMsgpack(buf, key)")
+          .if("buf, err = appendMsgpack(buf, v[key]); err != nil", (b) => {
+            b.return("nil, err");
+          })
+          .u()
+          .l("}")
+          .return("buf, nil")
+          .u()
+          .l("}")
+          .return('nil, fmt.Errorf("msgpack: cannot encode %T", value)');
+      },
+    );
+
+    w.comment(
+      "appendMsgpackInt appends i in the smallest MessagePack integer format.",
+    )
+      .n()
+      .func("appendMsgpackInt(buf []byte, i int64) []byte", (b) => {
+        b.l("switch {")
+          .l("case i >= -32 && i <= math.MaxInt8:")
+          .i()
+          .comment("Positive and negative fixint")
+          .return("append(buf, byte(i))")
+          .u()
+          .l("case i >= math.MinInt8 && i <= math.MaxInt8:")
+          .i()
+          .return("append(buf, 0xd0, byte(i))")
+          .u()
+          .l("case i >= math.MinInt16 && i <= math.MaxInt16:")
+          .i()
+          .return("binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(i))")
+          .u()
+          .l("case i >= math.MinInt32 && i <= math.MaxInt32:")
+          .i()
+          .return("binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(i))")
+          .u()
+          .l("}")
+          .return(
+            "binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(i))",
+          );
+      });
+
+    w.comment(
+      "appendMsgpackLength appends the header of a string, array or map of n items:",
+    )
+      .comment(
+        "fix|n below fixLimit, else the 8-bit (if any), 16-bit or 32-bit format.",
+      )
+      .n()
+      .func(
+        "appendMsgpackLength(buf []byte, n int, fix byte, fixLimit int, format8, format16, format32 byte) []byte",
+        (b) => {
+          b.l("switch {")
+            .l("case n < fixLimit:")
+            .i()
+            .return("append(buf, fix|byte(n))")
+            .u()
+            .l("case format8 != 0 && n <= math.MaxUint8:")
+            .i()
+            .return("append(buf, format8, byte(n))")
+            .u()
+            .l("case n <= math.MaxUint16:")
+            .i()
+            .return(
+              "binary.BigEndian.AppendUint16(append(buf, format16), uint16(n))",
+            )
+            .u()
+            .l("}")
+            .return(
+              "binary.BigEndian.AppendUint32(append(buf, format32), uint32(n))",
+            );
+        },
+      );
+
+    w.comment("sortedKeys returns the keys of m in ascending order.")
+      .n()
+      .func("sortedKeys(m map[string]interface{}) []string", (b) => {
+        b.decl("keys", "make([]string, 0, len(m))")
+          .l("for key := range m {")
+          .i()
+          .l("keys = append(keys, key)")
+          .u()
+          .l("}")
+          .l("sort.Strings(keys)")
+          .return("keys");
+      });
+  }
+
+  private generateCBOREncoder(w: GoBuilder): void {
+    w.comment(
+      "encodeCBOR encodes a value as CBOR (RFC 8949) with definite lengths and map",
+    )
+      .comment("keys sorted, so equal values encode to equal bytes.")
+      .n()
+      .func("encodeCBOR(value interface{}) ([]byte, error)", (b) => {
+        b.return("appendCBOR(nil, value)");
+      });
+
+    w.func("appendCBOR(buf []byte, value interface{}) ([]byte, error)", (b) => {
+      b.l("switch v := value.(type) {")
+        .l("case nil:")
+        .i()
+        .return("append(buf, 0xf6), nil")
+        .u()
+        .l("case bool:")
+        .i()
+        .if("v", (b) => {
+          b.return("append(buf, 0xf5), nil");
+        })
+        .return("append(buf, 0xf4), nil")
+        .u()
+        .l("case json.Number:")
+        .i()
+        .if("i, err := v.Int64(); err == nil", (b) => {
+          b.if("i < 0", (b) => {
+            b.return("appendCBORHead(buf, 1, uint64(-1-i)), nil");
+          }).return("appendCBORHead(buf, 0, uint64(i)), nil");
+        })
+        .l("f, err := v.Float64()")
+        .ifErr((b) => {
+          b.return("nil, err");
+        })
+        .return(
+          "binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(f)), nil",
+        )
+        .u()
+        .l("case float64:")
+        .i()
+        .return(
+          "binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(v)), nil",
+        )
+        .u()
+        .l("case string:")
+        .i()
+        .return("append(appendCBORHead(buf, 3, uint64(len(v))), v...), nil")
+        .u()
+        .l("case []interface{}:")
+        .i()
+        .l("buf = appendCBORHead(buf, 4, uint64(len(v)))")
+        .l("for _, item := range v {")
+        .i()
+        .var("err", "error")
+        .if("buf, err = appendCBOR(buf, item); err != nil", (b) => {
+          b.return("nil, err");
+        })
+        .u()
+        .l("}")
+        .return("buf, nil")
+        .u()
+        .l("case map[string]interface{}:")
+        .i()
+        .l("buf = appendCBORHead(buf, 5, uint64(len(v)))")
+        .l("for _, key := range sortedKeys(v) {")
+        .i()
+        .var("err", "error")
+        .l("buf, _ = appendCBOR(buf, key)")
+        .if("buf, err = appendCBOR(buf, v[key]); err != nil", (b) => {
+          b.return("nil, err");
+        })
+        .u()
+        .l("}")
+        .return("buf, nil")
+        .u()
+        .l("}")
+        .return('nil, fmt.Errorf("cbor: cannot encode %T", value)');
+    });
+
+    w.comment(
+      "appendCBORHead appends the initial byte of a major type with its argument n,",
+    )
+      .comment("in the shortest form.")
+      .n()
+      .func("appendCBORHead(buf []byte, major byte, n uint64) []byte", (b) => {
+        b.l("major <<= 5")
+          .l("switch {")
+          .l("case n < 24:")
+          .i()
+          .return("append(buf, major|byte(n))")
+          .u()
+          .l("case n <= math.MaxUint8:")
+          .i()
+          .return("append(buf, major|24, byte(n))")
+          .u()
+          .l("case n <= math.MaxUint16:")
+          .i()
+          .return(
+            "binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))",
+          )
+          .u()
+          .l("case n <= math.MaxUint32:")
+          .i()
+          .return(
+            "binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))",
+          )
+          .u()
+          .l("}")
+          .return("binary.BigEndian.AppendUint64(append(buf, major|27), n)");
+      });
+  }
+
+  private generateDecoder(w: GoBuilder): void {
+    w.comment(
+      "binaryDecoder reads MessagePack or CBOR values from data, bounding lengths by",
+    )
+      .comment(
+        "the bytes left so hostile input cannot make it allocate more than it sent.",
+      )
+      .struct("binaryDecoder", (b) => {
+        b.l("data  []byte").l("pos   int").l("depth int");
+      });
+
+    w.comment("read consumes the next n bytes.")
+      .n()
+      .method("d *binaryDecoder", "read", "n uint64", "([]byte, error)", (b) => {
+        b.if("n > uint64(len(d.data)-d.pos)", (b) => {
+          b.return("nil, io.ErrUnexpectedEOF");
+        })
+          .decl("value", "d.data[d.pos : d.pos+int(n)]")
+          .l("d.pos += int(n)")
+          .return("value, nil");
+      });
+
+    w.comment("uint consumes a big-endian unsigned integer of size bytes.")
+      .n()
+      .method("d *binaryDecoder", "uint", "size int", "(uint64, error)", (b) => {
+        b.l("data, err := d.read(uint64(size))")
+          .ifErr((b) => {
+            b.return("0, err");
+          })
+          .var("n", "uint64")
+          .l("for _, c := range data {")
+          .i()
+          .l("n = n<<8 | uint64(c)")
+          .u()
+          .l("}")
+          .return("n, nil");
+      });
+
+    w.comment(
+      "array decodes n items with decode. Every item takes at least one byte.",
+    )
+      .n()
+      .method(
+        "d *binaryDecoder",
+        "array",
+        "n uint64, decode func() (interface{}, error)",
+        "(interface{}, error)",
+        (b) => {
+          b.if("n > uint64(len(d.data)-d.pos)", (b) => {
+            b.return("nil, io.ErrUnexpectedEOF");
+          })
+            .if("d.depth++; d.depth > maxDecodeDepth", (b) => {
+              b.return('nil, errors.New("nesting too deep")');
+            })
+            .l("defer func() { d.depth-- }()")
+            .decl("items", "make([]interface{}, n)")
+            .l("for i := range items {")
+            .i()
+            .l("item, err := decode()")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("items[i] = item")
+            .u()
+            .l("}")
+            .return("items, nil");
+        },
+      );
+
+    w.comment(
+      "object decodes a map of n string keys and values with decode. Every entry",
+    )
+      .comment("takes at least two bytes.")
+      .n()
+      .method(
+        "d *binaryDecoder",
+        "object",
+        "n uint64, decode func() (interface{}, error)",
+        "(interface{}, error)",
+        (b) => {
+          b.if("n > uint64(len(d.data)-d.pos)/2", (b) => {
+            b.return("nil, io.ErrUnexpectedEOF");
+          })
+            .if("d.depth++; d.depth > maxDecodeDepth", (b) => {
+              b.return('nil, errors.New("nesting too deep")');
+            })
+            .l("defer func() { d.depth-- }()")
+            .decl("object", "make(map[string]interface{}, n)")
+            .l("for i := uint64(0); i < n; i++ {")
+            .i()
+            .l("key, err := decode()")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("name, ok := key.(string)")
+            .if("!ok", (b) => {
+              b.return('nil, errors.New("map keys must be strings")');
+            })
+            .l("value, err := decode()")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("object[name] = value")
+            .u()
+            .l("}")
+            .return("object, nil");
+        },
+      );
+
+    w.comment(
+      "decodeAll decodes data as one value with decode, rejecting trailing bytes.",
+    )
+      .n()
+      .func(
+        "decodeAll(d *binaryDecoder, decode func() (interface{}, error)) (interface{}, error)",
+        (b) => {
+          b.l("value, err := decode()")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .if("d.pos != len(d.data)", (b) => {
+              b.return('nil, errors.New("trailing data after value")');
+            })
+            .return("value, nil");
+        },
+      );
+  }
+
+  private generateMsgpackDecoder(w: GoBuilder): void {
+    w.comment(
+      "decodeMsgpack decodes a MessagePack value. Binary values decode to []byte,",
+    )
+      .comment(
+        "which encoding/json writes as base64 like generated []byte fields.",
+      )
+      .n()
+      .func("decodeMsgpack(data []byte) (interface{}, error)", (b) => {
+        b.decl("d", "&binaryDecoder{data: data}")
+          .l("value, err := decodeAll(d, d.msgpack)")
+          .ifErr((b) => {
+            b.return('nil, fmt.Errorf("msgpack: %w", err)');
+          })
+          .return("value, nil");
+      });
+
+    w.method(
+      "d *binaryDecoder",
+      "msgpack",
+      "",
+      "(interface{}, error)",
+      (b) => {
+        b.l("head, err := d.read(1)")
+          .ifErr((b) => {
+            b.return("nil, err");
+          })
+          .decl("c", "head[0]")
+          .l("switch {")
+          .l("case c <= 0x7f:")
+          .i()
+          .return("int64(c), nil")
+          .u()
+          .l("case c >= 0xe0:")
+          .i()
+          .return("int64(int8(c)), nil")
+          .u()
+          .l("case c&0xe0 == 0xa0:")
+          .i()
+          .return("d.msgpackString(uint64(c & 0x1f))")
+          .u()
+          .l("case c&0xf0 == 0x90:")
+          .i()
+          .return("d.array(uint64(c&0x0f), d.msgpack)")
+          .u()
+          .l("case c&0xf0 == 0x80:")
+          .i()
+          .return("d.object(uint64(c&0x0f), d.msgpack)")
+          .u()
+          .l("}")
+          .l("switch c {")
+          .l("case 0xc0:")
+          .i()
+          .return("nil, nil")
+          .u()
+          .l("case 0xc2:")
+          .i()
+          .return("false, nil")
+          .u()
+          .l("case 0xc3:")
+          .i()
+          .return("true, nil")
+          .u()
+          .l("case 0xca:")
+          .i()
+          .l("n, err := d.uint(4)")
+          .return("float64(math.Float32frombits(uint32(n))), err")
+          .u()
+          .l("case 0xcb:")
+          .i()
+          .l("n, err := d.uint(8)")
+          .return("math.Float64frombits(n), err")
+          .u()
+          .l("case 0xcc, 0xcd, 0xce, 0xcf:")
+          .i()
+          .return("d.uint(1 << (c - 0xcc))")
+          .u()
+          .l("case 0xd0, 0xd1, 0xd2, 0xd3:")
+          .i()
+          .decl("size", "1 << (c - 0xd0)")
+          .l("n, err := d.uint(size)")
+          .comment("Sign-extend the size-byte two's complement value")
+          .decl("shift", "64 - 8*size")
+          .return("int64(n<<shift) >> shift, err")
+          .u()
+          .l("}")
+          .comment(
+            "The remaining formats are followed by a length of 1, 2 or 4 bytes",
+          )
+          .var("size", "int")
+          .l("switch c {")
+          .l("case 0xc4, 0xd9:")
+          .i()
+          .l("size = 1")
+          .u()
+          .l("case 0xc5, 0xda, 0xdc, 0xde:")
+          .i()
+          .l("size = 2")
+          .u()
+          .l("case 0xc6, 0xdb, 0xdd, 0xdf:")
+          .i()
+          .l("size = 4")
+          .u()
+          .l("default:")
+          .i()
+          .return('nil, fmt.Errorf("unsupported type 0x%02x", c)')
+          .u()
+          .l("}")
+          .l("n, err := d.uint(size)")
+          .ifErr((b) => {
+            b.return("nil, err");
+          })
+          .l("switch c {")
+          .l("case 0xc4, 0xc5, 0xc6:")
+          .i()
+          .return("d.read(n)")
+          .u()
+          .l("case 0xd9, 0xda, 0xdb:")
+          .i()
+          .return("d.msgpackString(n)")
+          .u()
+          .l("case 0xdc, 0xdd:")
+          .i()
+          .return("d.array(n, d.msgpack)")
+          .u()
+          .l("}")
+          .return("d.object(n, d.msgpack)");
+      },
+    );
+
+    w.method(
+      "d *binaryDecoder",
+      "msgpackString",
+      "n uint64",
+      "(interface{}, error)",
+      (b) => {
+        b.l("data, err := d.read(n)").return("string(data), err");
+      },
+    );
+  }
+
+  private generateCBORDecoder(w: GoBuilder): void {
+    w.comment(
+      "decodeCBOR decodes a CBOR value. Tags are skipped, keeping their content, and",
+    )
+      .comment(
+        "byte strings decode to []byte; indefinite lengths are not supported.",
+      )
+      .n()
+      .func("decodeCBOR(data []byte) (interface{}, error)", (b) => {
+        b.decl("d", "&binaryDecoder{data: data}")
+          .l("value, err := decodeAll(d, d.cbor)")
+          .ifErr((b) => {
+            b.return('nil, fmt.Errorf("cbor: %w", err)');
+          })
+          .return("value, nil");
+      });
+
+    w.method("d *binaryDecoder", "cbor", "", "(interface{}, error)", (b) => {
+      b.l("head, err := d.read(1)")
+        .ifErr((b) => {
+          b.return("nil, err");
+        })
+        .decl("major, info", "head[0]>>5, head[0]&0x1f")
+        .if("major == 7", (b) => {
+          b.return("d.cborSimple(info)");
+        })
+        .decl("n", "uint64(info)")
+        .l("switch {")
+        .l("case info >= 24 && info <= 27:")
+        .i()
+        .if("n, err = d.uint(1 << (info - 24)); err != nil", (b) => {
+          b.return("nil, err");
+        })
+        .u()
+        .l("case info == 31:")
+        .i()
+        .return('nil, errors.New("indefinite lengths are not supported")')
+        .u()
+        .l("case info > 27:")
+        .i()
+        .return('nil, fmt.Errorf("invalid additional information %d", info)')
+        .u()
+        .l("}")
+        .l("switch major {")
+        .l("case 0:")
+        .i()
+        .return("n, nil")
+        .u()
+        .l("case 1:")
+        .i()
+        .if("n > math.MaxInt64", (b) => {
+          b.return("-1 - float64(n), nil");
+        })
+        .return("-1 - int64(n), nil")
+        .u()
+        .l("case 2:")
+        .i()
+        .return("d.read(n)")
+        .u()
+        .l("case 3:")
+        .i()
+        .l("data, err := d.read(n)")
+        .return("string(data), err")
+        .u()
+        .l("case 4:")
+        .i()
+        .return("d.array(n, d.cbor)")
+        .u()
+        .l("case 5:")
+        .i()
+        .return("d.object(n, d.cbor)")
+        .u()
+        .l("}")
+        .return("d.cbor()");
+    });
+
+    w.comment(
+      "cborSimple decodes the simple values and floats of major type 7.",
+    )
+      .n()
+      .method(
+        "d *binaryDecoder",
+        "cborSimple",
+        "info byte",
+        "(interface{}, error)",
+        (b) => {
+          b.l("switch info {")
+            .l("case 20:")
+            .i()
+            .return("false, nil")
+            .u()
+            .l("case 21:")
+            .i()
+            .return("true, nil")
+            .u()
+            .l("case 22, 23:")
+            .i()
+            .comment("null and undefined")
+            .return("nil, nil")
+            .u()
+            .l("case 25:")
+            .i()
+            .l("n, err := d.uint(2)")
+            .return("halfFloat(uint16(n)), err")
+            .u()
+            .l("case 26:")
+            .i()
+            .l("n, err := d.uint(4)")
+            .return("float64(math.Float32frombits(uint32(n))), err")
+            .u()
+            .l("case 27:")
+            .i()
+            .l("n, err := d.uint(8)")
+            .return("math.Float64frombits(n), err")
+            .u()
+            .l("}")
+            .return('nil, fmt.Errorf("unsupported simple value %d", info)');
+        },
+      );
+
+    w.comment("halfFloat converts an IEEE 754 half-precision float.")
+      .n()
+      .func("halfFloat(h uint16) float64", (b) => {
+        b.decl("exponent", "int(h>>10) & 0x1f")
+          .decl("mantissa", "float64(h & 0x3ff)")
+          .var("value", "float64")
+          .l("switch exponent {")
+          .l("case 0:")
+          .i()
+          .l("value = math.Ldexp(mantissa, -24)")
+          .u()
+          .l("case 31:")
+          .i()
+          .l("value = math.Inf(1)")
+          .if("mantissa != 0", (b) => {
+            b.l("value = math.NaN()");
+          })
+          .u()
+          .l("default:")
+          .i()
+          .l("value = math.Ldexp(mantissa+1024, exponent-25)")
+          .u()
+          .l("}")
+          .if("h&0x8000 != 0", (b) => {
+            b.return("-value");
+          })
+          .return("value");
+      });
+  }
+}
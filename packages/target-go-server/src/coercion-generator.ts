@@ -0,0 +1,391 @@
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+
+/**
+ * Generates coercion.go: Router.EnableLenientDecoding and a coerce function
+ * for every struct holding numbers or strings, directly or in a nested
+ * struct. With lenient decoding the router decodes params generically, coerces
+ * the values clients send as the other type where the contract allows it
+ * ("limit": "20" for a number, "zip": 12345 for a string) and reports the
+ * values that cannot be coerced as validation errors on their field, before
+ * decoding the params into the method's input.
+ */
+export class GoCoercionGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private coercible: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate coercion.go.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateCoercion(structs: GoStruct[], aliases: Map<string, string>): string {
+    this.aliases = aliases;
+    // Structs with numbers or strings, then those nesting them, until no more
+    // are found
+    this.coercible = new Set();
+    let found = true;
+    while (found) {
+      found = false;
+      for (const struct of structs) {
+        if (this.coercible.has(struct.name)) continue;
+        if (struct.fields.some((field) => this.isCoercible(field.type))) {
+          this.coercible.add(struct.name);
+          found = true;
+        }
+      }
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import(
+      "bytes",
+      "encoding/json",
+      "errors",
+      "fmt",
+      "math",
+      "strconv",
+      "strings",
+    );
+    this.generateLenientDecoding(w);
+    this.generateScalarCoercions(w);
+    for (const struct of structs) {
+      if (this.coercible.has(struct.name)) {
+        this.generateCoerceFunction(w, struct);
+      }
+    }
+    return w.toString();
+  }
+
+  /**
+   * The coerce function of every type with one, aliases included, once
+   * generateCoercion has run.
+   */
+  getCoerceFunctions(): Map<string, string> {
+    const functions = new Map<string, string>();
+    for (const struct of this.coercible) {
+      functions.set(struct, `coerce${struct}`);
+    }
+    for (const [alias, target] of this.aliases) {
+      if (this.coercible.has(target)) {
+        functions.set(alias, `coerce${target}`);
+      }
+    }
+    return functions;
+  }
+
+  private generateLenientDecoding(w: GoBuilder): void {
+    w.comment(
+      "EnableLenientDecoding makes the router coerce the params clients send as the",
+    )
+      .comment(
+        "wrong JSON type where the contract allows it: numeric strings such as",
+      )
+      .comment(
+        '"limit": "20" from a form become numbers, and numbers become strings where',
+      )
+      .comment(
+        "strings are expected. Values that cannot be coerced fail the call with a",
+      )
+      .comment(
+        "validation error on their field instead of the decoder's type error. Set it",
+      )
+      .comment("before serving requests.")
+      .n()
+      .method("r *Router", "EnableLenientDecoding", "", "*Router", (b) => {
+        b.l("r.lenientDecoding = true").return("r");
+      })
+      .n();
+
+    w.comment(
+      "coerceParams decodes params, coerces their values with coerce and encodes",
+    )
+      .comment(
+        "them again. Params that are not an object are left to the decoder to report.",
+      )
+      .n()
+      .func(
+        "coerceParams(params json.RawMessage, coerce func(v map[string]interface{}, path []PathSegment, errs *ValidationErrors)) (json.RawMessage, error)",
+        (b) => {
+          b.decl("decoder", "json.NewDecoder(bytes.NewReader(params))")
+            .l("decoder.UseNumber()")
+            .var("value", "map[string]interface{}")
+            .if("err := decoder.Decode(&value); err != nil", (b) => {
+              b.return("params, nil");
+            })
+            .var("errs", "ValidationErrors")
+            .l("coerce(value, nil, &errs)")
+            .if("len(errs) > 0", (b) => {
+              b.return("nil, errs");
+            })
+            .return("json.Marshal(value)");
+        },
+      )
+      .n();
+  }
+
+  private generateScalarCoercions(w: GoBuilder): void {
+    w.comment(
+      "coerceString returns value as a string, formatting numbers, or reports it in",
+    )
+      .comment("errs.")
+      .n()
+      .func(
+        "coerceString(value interface{}, path []PathSegment, errs *ValidationErrors) interface{}",
+        (b) => {
+          b.l("switch v := value.(type) {")
+            .l("case nil, string:")
+            .i()
+            .return("value")
+            .u()
+            .l("case json.Number:")
+            .i()
+            .return("v.String()")
+            .u()
+            .l("}")
+            .l('coercionFailed(errs, path, "must be a string")')
+            .return("value");
+        },
+      )
+      .n();
+
+    w.comment(
+      "coerceNumber returns value as a number, parsing numeric strings, or reports",
+    )
+      .comment("it in errs.")
+      .n()
+      .func(
+        "coerceNumber(value interface{}, path []PathSegment, errs *ValidationErrors) interface{}",
+        (b) => {
+          b.l("switch v := value.(type) {")
+            .l("case nil, json.Number:")
+            .i()
+            .return("value")
+            .u()
+            .l("case string:")
+            .i()
+            .decl("f, err", "strconv.ParseFloat(strings.TrimSpace(v), 64)")
+            .if("err == nil && !math.IsInf(f, 0) && !math.IsNaN(f)", (b) => {
+              b.return("json.Number(strconv.FormatFloat(f, 'g', -1, 64))");
+            })
+            .l(
+              'coercionFailed(errs, path, fmt.Sprintf("must be a number, got %q", v))',
+            )
+            .return("value")
+            .u()
+            .l("}")
+            .l('coercionFailed(errs, path, "must be a number")')
+            .return("value");
+        },
+      )
+      .n();
+
+    w.comment(
+      "coerceInteger returns value as an integer, parsing integer strings, or",
+    )
+      .comment("reports it in errs.")
+      .n()
+      .func(
+        "coerceInteger(value interface{}, path []PathSegment, errs *ValidationErrors) interface{}",
+        (b) => {
+          b.var("text", "string")
+            .l("switch v := value.(type) {")
+            .l("case nil:")
+            .i()
+            .return("value")
+            .u()
+            .l("case json.Number:")
+            .i()
+            .l("text = v.String()")
+            .u()
+            .l("case string:")
+            .i()
+            .l("text = strings.TrimSpace(v)")
+            .u()
+            .l("default:")
+            .i()
+            .l('coercionFailed(errs, path, "must be an integer")')
+            .return("value")
+            .u()
+            .l("}")
+            .decl("n, err", "strconv.ParseInt(text, 10, 64)")
+            .if("errors.Is(err, strconv.ErrRange)", (b) => {
+              b.l('coercionFailed(errs, path, "is out of range")').return(
+                "value",
+              );
+            })
+            .ifErr((b) => {
+              b.l(
+                'coercionFailed(errs, path, fmt.Sprintf("must be an integer, got %q", text))',
+              ).return("value");
+            })
+            .return("json.Number(strconv.FormatInt(n, 10))");
+        },
+      )
+      .n();
+
+    w.comment("coercionFailed reports the value at path in errs.")
+      .n()
+      .func(
+        "coercionFailed(errs *ValidationErrors, path []PathSegment, message string)",
+        (b) => {
+          b.l("*errs = append(*errs, &ValidationError{")
+            .i()
+            .l("Field:   fieldOf(path),")
+            .l("Path:    path,")
+            .l("Message: message,")
+            .u()
+            .l("})");
+        },
+      )
+      .n();
+
+    w.comment(
+      "pathTo returns the path of an element of the value at path, copied so paths",
+    )
+      .comment("of sibling elements do not share their backing array.")
+      .n()
+      .func(
+        "pathTo(path []PathSegment, segment PathSegment) []PathSegment",
+        (b) => {
+          b.return(
+            "append(append(make([]PathSegment, 0, len(path)+1), path...), segment)",
+          );
+        },
+      );
+  }
+
+  private generateCoerceFunction(w: GoBuilder, struct: GoStruct): void {
+    w.comment(
+      `coerce${struct.name} coerces the values of a decoded ${struct.name} in place.`,
+    )
+      .n()
+      .func(
+        `coerce${struct.name}(v map[string]interface{}, path []PathSegment, errs *ValidationErrors)`,
+        (b) => {
+          for (const field of struct.fields) {
+            if (!this.isCoercible(field.type)) continue;
+            b.if(`value, ok := v["${field.jsonName}"]; ok`, (b) => {
+              this.coerceValue(
+                b,
+                field.type,
+                "value",
+                (coerced) => `v["${field.jsonName}"] = ${coerced}`,
+                `pathTo(path, PathSegment{Key: "${field.jsonName}"})`,
+                0,
+              );
+            });
+          }
+        },
+      )
+      .n();
+  }
+
+  // Coerces the decoded value expr holds to goType, storing scalars back
+  // with store
+  private coerceValue(
+    b: GoBuilder,
+    goType: string,
+    expr: string,
+    store: (coerced: string) => string,
+    path: string,
+    depth: number,
+  ): void {
+    const scalar = scalarCoercion(goType);
+    if (scalar) {
+      b.l(store(`${scalar}(${expr}, ${path}, errs)`));
+      return;
+    }
+    const struct = this.aliases.get(goType) ?? goType;
+    if (this.coercible.has(struct)) {
+      b.if(`object, ok := ${expr}.(map[string]interface{}); ok`, (b) => {
+        b.l(`coerce${struct}(object, ${path}, errs)`);
+      });
+      return;
+    }
+    const suffix = depth === 0 ? "" : `${depth}`;
+    if (goType.startsWith("*")) {
+      // Null is left to the decoder
+      this.coerceValue(b, goType.slice(1), expr, store, path, depth);
+    } else if (goType.startsWith("[]")) {
+      const items = `items${suffix}`;
+      const index = `i${suffix}`;
+      const item = `item${suffix}`;
+      b.if(`${items}, ok := ${expr}.([]interface{}); ok`, (b) => {
+        b.l(`for ${index}, ${item} := range ${items} {`).i();
+        this.coerceValue(
+          b,
+          goType.slice(2),
+          item,
+          (coerced) => `${items}[${index}] = ${coerced}`,
+          `pathTo(${path}, PathSegment{Index: ${index}, IsIndex: true})`,
+          depth + 1,
+        );
+        b.u().l("}");
+      });
+    } else if (goType.startsWith("map[")) {
+      const values = `values${suffix}`;
+      const key = `k${suffix}`;
+      const entry = `entry${suffix}`;
+      b.if(`${values}, ok := ${expr}.(map[string]interface{}); ok`, (b) => {
+        b.l(`for ${key}, ${entry} := range ${values} {`).i();
+        this.coerceValue(
+          b,
+          goType.slice(goType.indexOf("]") + 1),
+          entry,
+          (coerced) => `${values}[${key}] = ${coerced}`,
+          `pathTo(${path}, PathSegment{Key: ${key}})`,
+          depth + 1,
+        );
+        b.u().l("}");
+      });
+    }
+  }
+
+  // Whether a field of goType holds numbers or strings to coerce
+  private isCoercible(goType: string): boolean {
+    if (scalarCoercion(goType)) {
+      return true;
+    }
+    if (this.coercible.has(this.aliases.get(goType) ?? goType)) {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.isCoercible(elem);
+  }
+}
+
+// The function coercing decoded values to a number or string type, if goType
+// is one; enums, timestamps and dates are decoded by their own types
+function scalarCoercion(goType: string): string | undefined {
+  if (goType === "string") {
+    return "coerceString";
+  }
+  if (/^u?int(8|16|32|64)?$/.test(goType)) {
+    return "coerceInteger";
+  }
+  if (goType === "float64" || goType === "float32") {
+    return "coerceNumber";
+  }
+  return undefined;
+}
+
+// The element type of pointer, slice and map types; byte slices are strings
+function elemType(goType: string): string | undefined {
+  if (goType.startsWith("*")) {
+    return goType.slice(1);
+  }
+  if (goType.startsWith("[]") && goType !== "[]byte") {
+    return goType.slice(2);
+  }
+  if (goType.startsWith("map[")) {
+    return goType.slice(goType.indexOf("]") + 1);
+  }
+  return undefined;
+}
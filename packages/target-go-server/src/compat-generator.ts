@@ -0,0 +1,588 @@
+import { GoBuilder } from "./go-builder";
+import { INTROSPECT_METHOD } from "./server-generator";
+
+/**
+ * Generates compat.go: ContractSchema, the schema document of the contract,
+ * and CheckCompat, which lists the changes between two schema documents that
+ * break existing clients, so a service can check at startup that it still
+ * serves the previously published contract.
+ */
+export class GoCompatGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateCompat(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("encoding/json", "fmt", "sort");
+
+    w.comment(
+      "BreakingChange is a change between two versions of the contract that breaks",
+    )
+      .comment(
+        "existing clients: a removed method, or an input that no longer accepts what",
+      )
+      .comment("it did or an output that returns what it did not.")
+      .struct("BreakingChange", (b) => {
+        b.l('Method  string `json:"method"`')
+          .comment(
+            'Path locates the change in the method\'s params or result, such as',
+          )
+          .comment(
+            '"input.status" or "output.tasks[].priority"; empty for the method itself',
+          )
+          .l('Path    string `json:"path,omitempty"`')
+          .l('Message string `json:"message"`');
+      });
+
+    w.comment(
+      'String describes the change, such as `task.list input.status: no longer',
+    )
+      .comment('accepts "cancelled"`.')
+      .n()
+      .method("c BreakingChange", "String", "", "string", (b) => {
+        b.if('c.Path == ""', (b) => {
+          b.return('c.Method + ": " + c.Message');
+        }).return('c.Method + " " + c.Path + ": " + c.Message');
+      });
+
+    w.comment(
+      "ContractSchema returns the schema of the contract the package was generated",
+    )
+      .comment(
+        "from: every method with the JSON Schemas of its input and output, as",
+      )
+      .comment(
+        `${INTROSPECT_METHOD} answers. Publish it with each release to check the next one`,
+      )
+      .comment("against it with CheckCompat.")
+      .n()
+      .func("ContractSchema() []byte", (b) => {
+        b.l(
+          'data, _ := json.MarshalIndent(map[string]interface{}{"methods": methodInfos, "schemaHash": SchemaHash}, "", "  ")',
+        ).return("data");
+      });
+
+    this.generateCheckCompat(w);
+    this.generateChecker(w);
+    this.generateHelpers(w);
+
+    return w.toString();
+  }
+
+  private generateCheckCompat(w: GoBuilder): void {
+    w.comment(
+      "CheckCompat lists the changes from oldSchema to newSchema that break existing",
+    )
+      .comment(
+        "clients: removed methods, changed kinds, and inputs that no longer accept",
+      )
+      .comment(
+        "what they did (new required fields, narrowed enums, tighter bounds) or",
+      )
+      .comment(
+        "outputs that return what they did not (removed or optional fields, widened",
+      )
+      .comment(
+        "enums). Both schemas are documents as ContractSchema returns and",
+      )
+      .comment(
+        `${INTROSPECT_METHOD} answers, with or without the {"result": ...} envelope. Run it`,
+      )
+      .comment("at startup against the previously published schema:")
+      .comment("")
+      .comment("    changes, err := CheckCompat(published, ContractSchema())")
+      .comment("    if err != nil || len(changes) > 0 {")
+      .comment(
+        '        log.Fatalf("contract breaks clients: %v %v", changes, err)',
+      )
+      .comment("    }")
+      .comment("")
+      .comment("Unions and recursive types are compared as a whole.")
+      .n()
+      .func(
+        "CheckCompat(oldSchema, newSchema []byte) ([]BreakingChange, error)",
+        (b) => {
+          b.decl("oldMethods, err", "decodeContractSchema(oldSchema)")
+            .ifErr((b) => {
+              b.return('nil, fmt.Errorf("old schema: %w", err)');
+            })
+            .decl("newMethods, err", "decodeContractSchema(newSchema)")
+            .ifErr((b) => {
+              b.return('nil, fmt.Errorf("new schema: %w", err)');
+            })
+            .n()
+            .decl("index", "make(map[string]MethodInfo, len(newMethods))")
+            .l("for _, method := range newMethods {")
+            .i()
+            .l("index[method.Name] = method")
+            .u()
+            .l("}")
+            .decl("c", "compatChecker{}")
+            .l("for _, old := range oldMethods {")
+            .i()
+            .decl("current, ok", "index[old.Name]")
+            .if("!ok", (b) => {
+              b.l('c.report(old.Name, "", "method removed")').l("continue");
+            })
+            .if("current.Kind != old.Kind", (b) => {
+              b.l(
+                'c.report(old.Name, "", fmt.Sprintf("changes kind from %s to %s", old.Kind, current.Kind))',
+              ).l("continue");
+            })
+            .if(
+              'err := c.compareRaw(old.Name, "input", old.Input, current.Input, true); err != nil',
+              (b) => {
+                b.return("nil, err");
+              },
+            )
+            .if(
+              'err := c.compareRaw(old.Name, "output", old.Output, current.Output, false); err != nil',
+              (b) => {
+                b.return("nil, err");
+              },
+            )
+            .u()
+            .l("}")
+            .return("c.changes, nil");
+        },
+      );
+
+    w.comment("decodeContractSchema decodes the methods of a schema document.")
+      .n()
+      .func("decodeContractSchema(data []byte) ([]MethodInfo, error)", (b) => {
+        b.var("document", "struct {")
+          .i()
+          .l('Methods []MethodInfo `json:"methods"`')
+          .l("Result  *struct {")
+          .i()
+          .l('Methods []MethodInfo `json:"methods"`')
+          .u()
+          .l('} `json:"result"`')
+          .u()
+          .l("}")
+          .if("err := json.Unmarshal(data, &document); err != nil", (b) => {
+            b.return("nil, err");
+          })
+          .if("document.Result != nil", (b) => {
+            b.return("document.Result.Methods, nil");
+          })
+          .return("document.Methods, nil");
+      });
+  }
+
+  private generateChecker(w: GoBuilder): void {
+    w.comment("compatChecker collects breaking changes while comparing schemas.")
+      .struct("compatChecker", (b) => {
+        b.l("changes []BreakingChange");
+      });
+
+    w.comment("report records a breaking change.")
+      .n()
+      .method(
+        "c *compatChecker",
+        "report",
+        "method, path, message string",
+        "",
+        (b) => {
+          b.l(
+            "c.changes = append(c.changes, BreakingChange{Method: method, Path: path, Message: message})",
+          );
+        },
+      );
+
+    w.comment("compareRaw compares two schemas given as JSON.")
+      .n()
+      .method(
+        "c *compatChecker",
+        "compareRaw",
+        "method, path string, old, current json.RawMessage, input bool",
+        "error",
+        (b) => {
+          b.var("oldSchema, newSchema", "map[string]interface{}")
+            .if("err := json.Unmarshal(old, &oldSchema); err != nil", (b) => {
+              b.return(
+                'fmt.Errorf("old schema of %s %s: %w", method, path, err)',
+              );
+            })
+            .if(
+              "err := json.Unmarshal(current, &newSchema); err != nil",
+              (b) => {
+                b.return(
+                  'fmt.Errorf("new schema of %s %s: %w", method, path, err)',
+                );
+              },
+            )
+            .l("c.compare(method, path, oldSchema, newSchema, input)")
+            .return("nil");
+        },
+      );
+
+    w.comment(
+      "compare reports the breaking changes from old to current of the schema at",
+    )
+      .comment(
+        "path. Inputs break when they stop accepting a value, outputs when they can",
+      )
+      .comment("return a value they could not.")
+      .n()
+      .method(
+        "c *compatChecker",
+        "compare",
+        "method, path string, old, current map[string]interface{}, input bool",
+        "",
+        (b) => {
+          b.l("old, oldNullable := nonNullSchema(old)")
+            .l("current, newNullable := nonNullSchema(current)")
+            .if("input && oldNullable && !newNullable", (b) => {
+              b.l('c.report(method, path, "no longer accepts null")');
+            })
+            .if("!input && newNullable && !oldNullable", (b) => {
+              b.l('c.report(method, path, "may now be null")');
+            })
+            .n()
+            .l(
+              'if !sameKeyword(old, current, "$ref") || !sameKeyword(old, current, "anyOf") ||',
+            )
+            .i()
+            .l(
+              '!sameKeyword(old, current, "oneOf") || !sameKeyword(old, current, "prefixItems") {',
+            )
+            .l('c.report(method, path, "changes type")')
+            .return()
+            .u()
+            .l("}")
+            .if(
+              '!sameKeyword(old, current, "type") && !widensNumber(old["type"], current["type"], input)',
+              (b) => {
+                b.l(
+                  'c.report(method, path, fmt.Sprintf("changes type from %v to %v", old["type"], current["type"]))',
+                ).return();
+              },
+            )
+            .n()
+            .l("c.compareEnum(method, path, old, current, input)")
+            .if("input", (b) => {
+              b.l("c.compareBounds(method, path, old, current)");
+            })
+            .n()
+            .l('switch current["type"] {')
+            .l('case "object":')
+            .i()
+            .l("c.compareObject(method, path, old, current, input)")
+            .u()
+            .l('case "array":')
+            .i()
+            .l('oldItems, _ := old["items"].(map[string]interface{})')
+            .l('newItems, _ := current["items"].(map[string]interface{})')
+            .if("oldItems != nil && newItems != nil", (b) => {
+              b.l('c.compare(method, path+"[]", oldItems, newItems, input)');
+            })
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "compareEnum reports the enum values an input dropped or an output added.",
+    )
+      .n()
+      .method(
+        "c *compatChecker",
+        "compareEnum",
+        "method, path string, old, current map[string]interface{}, input bool",
+        "",
+        (b) => {
+          b.l('oldValues, oldOK := old["enum"].([]interface{})')
+            .l('newValues, newOK := current["enum"].([]interface{})')
+            .if("!oldOK && !newOK", (b) => {
+              b.return();
+            })
+            .if("input", (b) => {
+              b.if("!newOK", (b) => {
+                b.return();
+              })
+                .if("!oldOK", (b) => {
+                  b.l(
+                    'c.report(method, path, "now only accepts the values of an enum")',
+                  ).return();
+                })
+                .l(
+                  "for _, value := range missingValues(oldValues, newValues) {",
+                )
+                .i()
+                .l('c.report(method, path, "no longer accepts "+value)')
+                .u()
+                .l("}")
+                .return();
+            })
+            .if("!oldOK", (b) => {
+              b.return();
+            })
+            .if("!newOK", (b) => {
+              b.l(
+                'c.report(method, path, "may now return values outside its enum")',
+              ).return();
+            })
+            .l("for _, value := range missingValues(newValues, oldValues) {")
+            .i()
+            .l('c.report(method, path, "may now return "+value)')
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment("compareBounds reports input constraints that became stricter.")
+      .n()
+      .method(
+          "c *compatChecker",
+          "compareBounds",
+          "method, path string, old, current map[string]interface{}",
+          "",
+          (b) => {
+            b.l(
+              'for _, keyword := range []string{"minLength", "minimum", "exclusiveMinimum", "minItems", "minProperties"} {',
+            )
+              .i()
+              .l("newBound, ok := current[keyword].(float64)")
+              .if("!ok", (b) => {
+                b.l("continue");
+              })
+              .if(
+                "oldBound, ok := old[keyword].(float64); !ok || newBound > oldBound",
+                (b) => {
+                  b.l(
+                    'c.report(method, path, fmt.Sprintf("raises %s to %v", keyword, newBound))',
+                  );
+                },
+              )
+              .u()
+              .l("}")
+              .l(
+                'for _, keyword := range []string{"maxLength", "maximum", "exclusiveMaximum", "maxItems", "maxProperties"} {',
+              )
+              .i()
+              .l("newBound, ok := current[keyword].(float64)")
+              .if("!ok", (b) => {
+                b.l("continue");
+              })
+              .if(
+                "oldBound, ok := old[keyword].(float64); !ok || newBound < oldBound",
+                (b) => {
+                  b.l(
+                    'c.report(method, path, fmt.Sprintf("lowers %s to %v", keyword, newBound))',
+                  );
+                },
+              )
+              .u()
+              .l("}")
+              .l('for _, keyword := range []string{"pattern", "format"} {')
+              .i()
+              .if(
+                "_, ok := current[keyword]; ok && !sameKeyword(old, current, keyword)",
+                (b) => {
+                  b.l(
+                    'c.report(method, path, fmt.Sprintf("changes %s to %v", keyword, current[keyword]))',
+                  );
+                },
+              )
+              .u()
+              .l("}");
+          },
+        );
+
+      w.comment("compareObject compares the properties of two object schemas.")
+        .n()
+        .method(
+        "c *compatChecker",
+        "compareObject",
+        "method, path string, old, current map[string]interface{}, input bool",
+        "",
+        (b) => {
+          b.l('oldProperties, _ := old["properties"].(map[string]interface{})')
+            .l(
+              'newProperties, _ := current["properties"].(map[string]interface{})',
+            )
+            .decl("oldRequired", "requiredFields(old)")
+            .decl("newRequired", "requiredFields(current)")
+            .n()
+            .decl(
+              "names",
+              "make([]string, 0, len(oldProperties)+len(newProperties))",
+            )
+            .l("for name := range oldProperties {")
+            .i()
+            .l("names = append(names, name)")
+            .u()
+            .l("}")
+            .l("for name := range newProperties {")
+            .i()
+            .if("_, ok := oldProperties[name]; !ok", (b) => {
+              b.l("names = append(names, name)");
+            })
+            .u()
+            .l("}")
+            .l("sort.Strings(names)")
+            .n()
+            .l("for _, name := range names {")
+            .i()
+            .decl("fieldPath", 'path + "." + name')
+            .l(
+              "oldField, inOld := oldProperties[name].(map[string]interface{})",
+            )
+            .l(
+              "newField, inNew := newProperties[name].(map[string]interface{})",
+            )
+            .l("if input {")
+            .i()
+            .l("switch {")
+            .l("case newRequired[name] && !inOld:")
+            .i()
+            .l('c.report(method, fieldPath, "adds a required field")')
+            .l("continue")
+            .u()
+            .l("case newRequired[name] && !oldRequired[name]:")
+            .i()
+            .l('c.report(method, fieldPath, "becomes required")')
+            .u()
+            .l("}")
+            .u()
+            .l("} else {")
+            .i()
+            .l("switch {")
+            .l("case oldRequired[name] && !inNew:")
+            .i()
+            .l('c.report(method, fieldPath, "removes a field")')
+            .l("continue")
+            .u()
+            .l("case oldRequired[name] && !newRequired[name]:")
+            .i()
+            .l('c.report(method, fieldPath, "becomes optional")')
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .if("inOld && inNew", (b) => {
+              b.l("c.compare(method, fieldPath, oldField, newField, input)");
+            })
+            .u()
+            .l("}")
+            .n()
+            .comment("Records describe their values with additionalProperties")
+            .l(
+              'oldValues, _ := old["additionalProperties"].(map[string]interface{})',
+            )
+            .l(
+              'newValues, _ := current["additionalProperties"].(map[string]interface{})',
+            )
+            .if("oldValues != nil && newValues != nil", (b) => {
+              b.l('c.compare(method, path+".*", oldValues, newValues, input)');
+            });
+        },
+      );
+  }
+
+  private generateHelpers(w: GoBuilder): void {
+    w.comment(
+      "missingValues returns the JSON of the values in from that are not in to.",
+    )
+      .n()
+      .func("missingValues(from, to []interface{}) []string", (b) => {
+        b.decl("present", "make(map[string]bool, len(to))")
+          .l("for _, value := range to {")
+          .i()
+          .l("data, _ := json.Marshal(value)")
+          .l("present[string(data)] = true")
+          .u()
+          .l("}")
+          .var("missing", "[]string")
+          .l("for _, value := range from {")
+          .i()
+          .l("data, _ := json.Marshal(value)")
+          .if("!present[string(data)]", (b) => {
+            b.l("missing = append(missing, string(data))");
+          })
+          .u()
+          .l("}")
+          .return("missing");
+      });
+
+    w.comment(
+      "requiredFields returns the set of an object schema's required properties.",
+    )
+      .n()
+      .func(
+        "requiredFields(schema map[string]interface{}) map[string]bool",
+        (b) => {
+          b.decl("required", "make(map[string]bool)")
+            .l('names, _ := schema["required"].([]interface{})')
+            .l("for _, name := range names {")
+            .i()
+            .if("name, ok := name.(string); ok", (b) => {
+              b.l("required[name] = true");
+            })
+            .u()
+            .l("}")
+            .return("required");
+        },
+      );
+
+    w.comment(
+      'nonNullSchema unwraps the {"anyOf": [schema, {"type": "null"}]} of nullable',
+    )
+      .comment("types, reporting whether schema was nullable.")
+      .n()
+      .func(
+        "nonNullSchema(schema map[string]interface{}) (map[string]interface{}, bool)",
+        (b) => {
+          b.l('variants, ok := schema["anyOf"].([]interface{})')
+            .if("!ok || len(variants) != 2", (b) => {
+              b.return("schema, false");
+            })
+            .if(
+              'null, ok := variants[1].(map[string]interface{}); !ok || null["type"] != "null" || len(null) != 1',
+              (b) => {
+                b.return("schema, false");
+              },
+            )
+            .l("inner, ok := variants[0].(map[string]interface{})")
+            .if("!ok", (b) => {
+              b.return("schema, false");
+            })
+            .return("inner, true");
+        },
+      );
+
+    w.comment(
+      "widensNumber reports whether an integer input became a number, or a number",
+    )
+      .comment("output an integer, which breaks no client.")
+      .n()
+      .func(
+        "widensNumber(oldType, newType interface{}, input bool) bool",
+        (b) => {
+          b.if("input", (b) => {
+            b.return('oldType == "integer" && newType == "number"');
+          }).return('oldType == "number" && newType == "integer"');
+        },
+      );
+
+    w.comment(
+      "sameKeyword reports whether keyword has the same value in both schemas.",
+    )
+      .n()
+      .func(
+        "sameKeyword(old, current map[string]interface{}, keyword string) bool",
+        (b) => {
+          b.l("oldValue, _ := json.Marshal(old[keyword])")
+            .l("newValue, _ := json.Marshal(current[keyword])")
+            .return("string(oldValue) == string(newValue)");
+        },
+      );
+  }
+}
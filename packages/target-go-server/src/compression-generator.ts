@@ -0,0 +1,206 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates compression.go: opt-in response compression enabled with
+ * Router.SetCompression. Query and mutation results of at least the minimum
+ * size are compressed with gzip or deflate, as negotiated with the client's
+ * Accept-Encoding, using pooled writers. Brotli is not offered, since the
+ * standard library has no encoder for it.
+ */
+export class GoCompressionGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateCompression(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "compress/gzip",
+      "compress/zlib",
+      "io",
+      "net/http",
+      "strconv",
+      "strings",
+      "sync",
+    );
+
+    this.generateSetCompression(w);
+    this.generateWriters(w);
+    this.generateNegotiation(w);
+    this.generateWriteResult(w);
+
+    return w.toString();
+  }
+
+  private generateSetCompression(w: GoBuilder): void {
+    w.comment(
+      "SetCompression compresses query and mutation results of at least minSize bytes",
+    )
+      .comment(
+        "with gzip or deflate, whichever the client's Accept-Encoding prefers, so large",
+      )
+      .comment(
+        "results need no compressing reverse proxy. Errors and subscription events are",
+      )
+      .comment("sent uncompressed.")
+      .n()
+      .method("r *Router", "SetCompression", "minSize int", "*Router", (b) => {
+        b.l("r.compression = true")
+          .l("r.compressionMinSize = minSize")
+          .return("r");
+      });
+  }
+
+  private generateWriters(w: GoBuilder): void {
+    w.comment(
+      "compressor is a pooled gzip or zlib writer, reset onto each response.",
+    ).type(
+      "compressor interface",
+      "{\n\tio.WriteCloser\n\tReset(w io.Writer)\n}",
+    );
+
+    w.comment(
+      "compressors pools the writers of each supported content coding, since",
+    )
+      .comment(
+        "allocating one per response dominates the cost of small results.",
+      )
+      .l("var compressors = map[string]*sync.Pool{")
+      .i()
+      .l('"gzip": {New: func() interface{} { return gzip.NewWriter(nil) }},')
+      .l('"deflate": {New: func() interface{} { return zlib.NewWriter(nil) }},')
+      .u()
+      .l("}")
+      .n();
+  }
+
+  private generateNegotiation(w: GoBuilder): void {
+    w.comment(
+      "negotiateEncoding picks the content coding for a response from the request's",
+    )
+      .comment(
+        'Accept-Encoding header: "gzip" or "deflate", by quality with gzip preferred',
+      )
+      .comment('on ties, or "" when the client accepts neither.')
+      .n()
+      .func("negotiateEncoding(header string) string", (b) => {
+        b.decl("quality", 'map[string]float64{"gzip": -1, "deflate": -1}')
+          .decl("wildcard", "-1.0")
+          .l('for _, part := range strings.Split(header, ",") {')
+          .i()
+          .l('name, params, _ := strings.Cut(part, ";")')
+          .l("name = strings.ToLower(strings.TrimSpace(name))")
+          .decl("q", "1.0")
+          .l('for _, param := range strings.Split(params, ";") {')
+          .i()
+          .if(
+            'value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok',
+            (b) => {
+              b.if(
+                "parsed, err := strconv.ParseFloat(value, 64); err == nil",
+                (b) => {
+                  b.l("q = parsed");
+                },
+              );
+            },
+          )
+          .u()
+          .l("}")
+          .if('name == "*"', (b) => {
+            b.l("wildcard = q");
+          })
+          .if("_, ok := quality[name]; ok", (b) => {
+            b.l("quality[name] = q");
+          })
+          .u()
+          .l("}")
+          .l("for name, q := range quality {")
+          .i()
+          .comment("Codings the header does not name take the quality of *")
+          .if("q < 0", (b) => {
+            b.l("quality[name] = wildcard");
+          })
+          .u()
+          .l("}")
+          .if(
+            'quality["gzip"] > 0 && quality["gzip"] >= quality["deflate"]',
+            (b) => {
+              b.return('"gzip"');
+            },
+          )
+          .if('quality["deflate"] > 0', (b) => {
+            b.return('"deflate"');
+          })
+          .return('""');
+      });
+  }
+
+  private generateWriteResult(w: GoBuilder): void {
+    w.comment(
+      "writeResult writes the JSON body of a query or mutation result, compressed",
+    )
+      .comment(
+        "when SetCompression enabled it and the client accepts it, and transcoded to",
+      )
+      .comment("the binary encoding the request's Accept header names, if any.")
+      .n()
+      .method(
+        "r *Router",
+        "writeResult",
+        "w http.ResponseWriter, req *http.Request, body []byte",
+        "",
+        (b) => {
+          b.l("body, contentType := encodeResult(req, body)")
+            .l('w.Header().Set("Content-Type", contentType)')
+            .l('w.Header().Add("Vary", "Accept")')
+            .if('contentType != "application/json"', (b) => {
+              b.l("weakenETag(w)");
+            })
+            .if("!r.compression", (b) => {
+              b.l("w.Write(body)").return();
+            })
+            .comment(
+              "The response differs by Accept-Encoding even when sent uncompressed",
+            )
+            .l('w.Header().Add("Vary", "Accept-Encoding")')
+            .decl(
+              "encoding",
+              'negotiateEncoding(req.Header.Get("Accept-Encoding"))',
+            )
+            .if('len(body) < r.compressionMinSize || encoding == ""', (b) => {
+              b.l("w.Write(body)").return();
+            })
+            .l("weakenETag(w)")
+            .l('w.Header().Set("Content-Encoding", encoding)')
+            .l('w.Header().Del("Content-Length")')
+            .decl("pool", "compressors[encoding]")
+            .decl("cw", "pool.Get().(compressor)")
+            .l("cw.Reset(w)")
+            .l("cw.Write(body)")
+            .l("cw.Close()")
+            .l("pool.Put(cw)");
+        },
+      );
+
+    w.comment(
+      "weakenETag marks the ETag of a result sent transcoded or compressed as weak,",
+    )
+      .comment(
+        "since those bytes differ from the JSON body a strong ETag validates.",
+      )
+      .n()
+      .func("weakenETag(w http.ResponseWriter)", (b) => {
+        b.if(
+          'etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/")',
+          (b) => {
+            b.l('w.Header().Set("ETag", "W/"+etag)');
+          },
+        );
+      });
+  }
+}
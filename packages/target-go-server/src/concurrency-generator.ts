@@ -0,0 +1,299 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates concurrency.go: opt-in caps on the number of query and mutation
+ * calls executing at once, globally with Router.SetConcurrencyLimit and per
+ * method pool with Router.SetConcurrencyLimitFor. Saturated calls queue for
+ * a slot up to the limit's Wait, as long as its queue has room, then fail with
+ * RESOURCE_EXHAUSTED telling clients when to retry, rather than piling up
+ * until connections time out. Router.ConcurrencyStats reports the depth of
+ * the queues.
+ */
+export class GoConcurrencyGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateConcurrency(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "sync/atomic", "time");
+
+    this.generateLimits(w);
+    this.generateAcquire(w);
+    this.generateStats(w);
+
+    return w.toString();
+  }
+
+  private generateLimits(w: GoBuilder): void {
+    w.comment(
+      "ConcurrencyLimit caps the calls executing at once. Calls arriving while Max are",
+    )
+      .comment(
+        "executing wait up to Wait for one to finish, and then fail with",
+      )
+      .comment("RESOURCE_EXHAUSTED; with no Wait they are shed at once.")
+      .struct("ConcurrencyLimit", (b) => {
+        b.l("Max  int")
+          .l("Wait time.Duration")
+          .comment(
+            "Queue caps the calls waiting for a slot: calls arriving while Queue are",
+          )
+          .comment("waiting are shed at once. Zero leaves it unbounded.")
+          .l("Queue int")
+          .comment(
+            "RetryAfter is when shed calls are told to try again, in the Retry-After",
+          )
+          .comment(
+            "header and the RetryInfo details of their error; one second by default.",
+          )
+          .l("RetryAfter time.Duration");
+      });
+
+    w.comment(
+      "concurrencyPool holds the execution slots of a limit, a buffered channel with",
+    )
+      .comment(
+        "an element per executing call, the calls waiting for one, and the method",
+      )
+      .comment("pattern it applies to.")
+      .struct("concurrencyPool", (b) => {
+        b.l("pattern string")
+          .l("limit   ConcurrencyLimit")
+          .l("slots   chan struct{}")
+          .l("queued  atomic.Int64");
+      });
+
+    w.comment(
+      "newConcurrencyPool returns the pool of limit for methods matching pattern.",
+    )
+      .n()
+      .func(
+      "newConcurrencyPool(pattern string, limit ConcurrencyLimit) *concurrencyPool",
+      (b) => {
+        b.if("limit.Max < 1", (b) => {
+          b.l('panic("xrpc: ConcurrencyLimit.Max must be at least 1")');
+        })
+          .if("limit.RetryAfter <= 0", (b) => {
+            b.l("limit.RetryAfter = time.Second");
+          })
+          .return(
+            "&concurrencyPool{pattern: pattern, limit: limit, slots: make(chan struct{}, limit.Max)}",
+          );
+      },
+    );
+
+    w.comment(
+      "SetConcurrencyLimit caps the query and mutation calls executing at once across",
+    )
+      .comment(
+        "all methods. Subscriptions are long-lived and not counted. Set it before",
+      )
+      .comment("serving requests.")
+      .n()
+      .method(
+        "r *Router",
+        "SetConcurrencyLimit",
+        "limit ConcurrencyLimit",
+        "*Router",
+        (b) => {
+          b.l('r.concurrency = newConcurrencyPool("", limit)').return("r");
+        },
+      );
+
+    w.comment(
+      "SetConcurrencyLimitFor gives methods matching pattern, using the same pattern",
+    )
+      .comment(
+        "syntax as UseFor, a pool of their own: their calls share its slots, and",
+      )
+      .comment(
+        "queue there before taking a slot of SetConcurrencyLimit, so expensive methods",
+      )
+      .comment(
+        'such as "report.*" can\'t starve cheap ones. When several patterns match a',
+      )
+      .comment("method, the last one set wins.")
+      .n()
+      .method(
+        "r *Router",
+        "SetConcurrencyLimitFor",
+        "pattern string, limit ConcurrencyLimit",
+        "*Router",
+        (b) => {
+          b.l("mustValidPattern(pattern)")
+            .l(
+              "r.concurrencyPools = append(r.concurrencyPools, newConcurrencyPool(pattern, limit))",
+            )
+            .return("r");
+        },
+      );
+  }
+
+  private generateAcquire(w: GoBuilder): void {
+    w.comment(
+      "acquire takes a slot of method's pool, then of the global limit, and returns",
+    )
+      .comment(
+        "the function releasing them once the call finishes. It fails with",
+      )
+      .comment(
+        "RESOURCE_EXHAUSTED when a slot doesn't free up in time, or ctx's error.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "acquire",
+        "ctx context.Context, method string",
+        "(func(), error)",
+        (b) => {
+          b.var("pools", "[]*concurrencyPool")
+            .l("for i := len(r.concurrencyPools) - 1; i >= 0; i-- {")
+            .i()
+            .decl("pool", "r.concurrencyPools[i]")
+            .if("matchMethod(pool.pattern, method)", (b) => {
+              b.l("pools = append(pools, pool)").l("break");
+            })
+            .u()
+            .l("}")
+            .if("r.concurrency != nil", (b) => {
+              b.l("pools = append(pools, r.concurrency)");
+            })
+            .n()
+            .decl("acquired", "0")
+            .decl("release", "func() {")
+            .i()
+            .l("for _, pool := range pools[:acquired] {")
+            .i()
+            .l("<-pool.slots")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .l("for _, pool := range pools {")
+            .i()
+            .if("err := pool.take(ctx); err != nil", (b) => {
+              b.l("release()").return("nil, err");
+            })
+            .l("acquired++")
+            .u()
+            .l("}")
+            .return("release, nil");
+        },
+      );
+
+    w.comment(
+      "take takes a slot of p, waiting up to its Wait for one to free up if its queue",
+    )
+      .comment("has room.")
+      .n()
+      .method(
+        "p *concurrencyPool",
+        "take",
+        "ctx context.Context",
+        "error",
+        (b) => {
+          b.l("select {")
+            .l("case p.slots <- struct{}{}:")
+            .i()
+            .return("nil")
+            .u()
+            .l("default:")
+            .l("}")
+            .if("p.limit.Wait > 0 && p.enqueue()", (b) => {
+              b.l("defer p.queued.Add(-1)")
+                .decl("timer", "time.NewTimer(p.limit.Wait)")
+                .l("defer timer.Stop()")
+                .l("select {")
+                .l("case p.slots <- struct{}{}:")
+                .i()
+                .return("nil")
+                .u()
+                .l("case <-ctx.Done():")
+                .i()
+                .return("ctx.Err()")
+                .u()
+                .l("case <-timer.C:")
+                .l("}");
+            })
+            .return(
+              'NewError(CodeResourceExhausted, "Too many concurrent calls, try again later").WithDetails(RetryInfo{RetryAfterMs: p.limit.RetryAfter.Milliseconds()})',
+            );
+        },
+      );
+
+    w.comment(
+      "enqueue counts a call waiting for a slot of p, unless its queue is full.",
+    )
+      .n()
+      .method("p *concurrencyPool", "enqueue", "", "bool", (b) => {
+        b.if(
+          "p.queued.Add(1) <= int64(p.limit.Queue) || p.limit.Queue <= 0",
+          (b) => {
+            b.return("true");
+          },
+        )
+          .l("p.queued.Add(-1)")
+          .return("false");
+      });
+  }
+
+  private generateStats(w: GoBuilder): void {
+    w.comment(
+      "ConcurrencyStats is the load of a concurrency limit: the calls executing and",
+    )
+      .comment("those queued for a slot.")
+      .struct("ConcurrencyStats", (b) => {
+        b.comment(
+          "Pattern is the method pattern of a limit set with SetConcurrencyLimitFor,",
+        )
+          .comment("empty for the one of SetConcurrencyLimit.")
+          .l("Pattern string")
+          .l("Max int")
+          .l("Executing int")
+          .l("Queued int");
+      });
+
+    w.comment(
+      "ConcurrencyStats returns the load of every concurrency limit, the one of",
+    )
+      .comment(
+        "SetConcurrencyLimit first, for metrics and health checks to watch how deep",
+      )
+      .comment("their queues get.")
+      .n()
+      .method(
+        "r *Router",
+        "ConcurrencyStats",
+        "",
+        "[]ConcurrencyStats",
+        (b) => {
+          b.var("stats", "[]ConcurrencyStats")
+            .decl("pools", "r.concurrencyPools")
+            .if("r.concurrency != nil", (b) => {
+              b.l(
+                "pools = append([]*concurrencyPool{r.concurrency}, pools...)",
+              );
+            })
+            .l("for _, pool := range pools {")
+            .i()
+            .l("stats = append(stats, ConcurrencyStats{")
+            .i()
+            .l("Pattern:   pool.pattern,")
+            .l("Max:       pool.limit.Max,")
+            .l("Executing: len(pool.slots),")
+            .l("Queued:    int(pool.queued.Load()),")
+            .u()
+            .l("})")
+            .u()
+            .l("}")
+            .return("stats");
+        },
+      );
+  }
+}
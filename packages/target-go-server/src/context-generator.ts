@@ -0,0 +1,304 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates context.go: the RequestInfo passed to handlers and typed
+ * accessors for values carried on a standard context.Context. Handlers get
+ * the context.Context of their call itself, with its deadline and values, so
+ * there is no RPC context to convert to or from one. Key[T] carries values of
+ * the application's own types, such as ones middleware passes to handlers,
+ * and the deprecated DataFrom rebuilds the Data map of the former Context
+ * from them for one release.
+ */
+export class GoContextGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateContext(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "net/http");
+
+    this.generateRequestInfo(w);
+    this.generateContextKeys(w);
+    this.generateRequestInfoAccessors(w);
+    this.generateUserIDAccessors(w);
+    this.generatePrincipalAccessors(w);
+    this.generateKeys(w);
+
+    return w.toString();
+  }
+
+  private generateRequestInfo(w: GoBuilder): void {
+    w.comment("RequestInfo describes the RPC call currently being served.")
+      .comment("")
+      .comment(
+        "The ctx passed to handlers is a standard context.Context carrying the call's",
+      )
+      .comment(
+        "deadline and cancellation, its RequestInfo, principal and dependencies, so",
+      )
+      .comment(
+        "handlers pass it as is to database/sql, net/http and other clients taking one.",
+      )
+      .comment(
+        "Code serving calls outside the router, such as a queue consumer, gives a plain",
+      )
+      .comment(
+        "context.Context those values with WithRequestInfo, WithPrincipal and",
+      )
+      .comment(
+        "WithDependency, and context.WithoutCancel keeps them for work outliving a call.",
+      )
+      .struct("RequestInfo", (b) => {
+        b.l("Method         string")
+          .l("Request        *http.Request")
+          .l("ResponseWriter http.ResponseWriter");
+      })
+      .n();
+  }
+
+  private generateContextKeys(w: GoBuilder): void {
+    w.comment(
+      "contextKey is unexported so values set by this package cannot collide with other packages.",
+    )
+      .type("contextKey", "int")
+      .n();
+
+    w.l("const (")
+      .i()
+      .l("requestInfoKey contextKey = iota")
+      .l("userIDKey")
+      .l("principalKey")
+      .u()
+      .l(")")
+      .n();
+  }
+
+  private generateRequestInfoAccessors(w: GoBuilder): void {
+    w.comment("WithRequestInfo returns a copy of ctx carrying info.")
+      .n()
+      .func(
+        "WithRequestInfo(ctx context.Context, info RequestInfo) context.Context",
+        (b) => {
+          b.return("context.WithValue(ctx, requestInfoKey, info)");
+        },
+      )
+      .n();
+
+    w.comment(
+      "RequestInfoFrom returns the RequestInfo stored in ctx by the router, if any.",
+    )
+      .n()
+      .func(
+        "RequestInfoFrom(ctx context.Context) (RequestInfo, bool)",
+        (b) => {
+          b.decl("info, ok", "ctx.Value(requestInfoKey).(RequestInfo)").return(
+            "info, ok",
+          );
+        },
+      )
+      .n();
+  }
+
+  private generateUserIDAccessors(w: GoBuilder): void {
+    w.comment(
+      "WithUserID returns a copy of ctx carrying the authenticated user ID.",
+    )
+      .n()
+      .func(
+        "WithUserID(ctx context.Context, userID string) context.Context",
+        (b) => {
+          b.return("context.WithValue(ctx, userIDKey, userID)");
+        },
+      )
+      .n();
+
+    w.comment(
+      "UserIDFrom returns the user ID stored in ctx by WithUserID, if any.",
+    )
+      .n()
+      .func("UserIDFrom(ctx context.Context) (string, bool)", (b) => {
+        b.decl("userID, ok", "ctx.Value(userIDKey).(string)").return(
+          "userID, ok",
+        );
+      })
+      .n();
+  }
+
+  private generatePrincipalAccessors(w: GoBuilder): void {
+    w.comment(
+      "Principal is the caller an authentication middleware accepted, such as",
+    )
+      .comment("BearerAuth, JWTAuth, APIKeyAuth or ClientCertAuth.")
+      .struct("Principal", (b) => {
+        b.comment("ID identifies the caller, such as a user or service account.")
+          .l("ID string")
+          .comment(
+            'Scheme is how the caller authenticated: "bearer", "jwt", "api-key" or "mtls".',
+          )
+          .l("Scheme string")
+          .comment(
+            "Claims holds what the credential says about the caller, such as a JWT's claims.",
+          )
+          .l("Claims map[string]interface{}");
+      })
+      .n();
+
+    w.comment(
+      "WithPrincipal returns a copy of ctx carrying principal, and its ID as the user ID.",
+    )
+      .n()
+      .func(
+        "WithPrincipal(ctx context.Context, principal Principal) context.Context",
+        (b) => {
+          b.return(
+            "context.WithValue(WithUserID(ctx, principal.ID), principalKey, principal)",
+          );
+        },
+      )
+      .n();
+
+    w.comment(
+      "PrincipalFrom returns the principal stored in ctx by WithPrincipal, if any.",
+    )
+      .n()
+      .func("PrincipalFrom(ctx context.Context) (Principal, bool)", (b) => {
+        b.decl("principal, ok", "ctx.Value(principalKey).(Principal)").return(
+          "principal, ok",
+        );
+      })
+      .n();
+  }
+
+  private generateKeys(w: GoBuilder): void {
+    w.comment(
+      "Key identifies a value of type T that middleware, interceptors and handlers",
+    )
+      .comment(
+        "carry on a context.Context, such as a tenant or a feature flag. Keys are",
+      )
+      .comment(
+        "compared by identity, not by name, so two packages creating keys with the",
+      )
+      .comment("same name get distinct values:")
+      .comment("")
+      .comment('    var tenantKey = NewKey[string]("tenant")')
+      .comment("")
+      .comment("    ctx = tenantKey.Set(ctx, tenant)")
+      .comment("    tenant, ok := tenantKey.Get(ctx)")
+      .struct("Key[T any]", (b) => {
+        b.l("name string");
+      });
+
+    w.comment("NewKey returns a new key, named name in its String.")
+      .n()
+      .func("NewKey[T any](name string) *Key[T]", (b) => {
+        b.return("&Key[T]{name: name}");
+      })
+      .n();
+
+    w.comment("Set returns a copy of ctx carrying value under k.")
+      .n()
+      .method(
+        "k *Key[T]",
+        "Set",
+        "ctx context.Context, value T",
+        "context.Context",
+        (b) => {
+          b.return(
+            "&keyContext{Context: ctx, key: k, name: k.name, value: value}",
+          );
+        },
+      )
+      .n();
+
+    w.comment("Get returns the value stored in ctx under k, if any.")
+      .n()
+      .method("k *Key[T]", "Get", "ctx context.Context", "(T, bool)", (b) => {
+        b.decl("value, ok", "ctx.Value(k).(T)").return("value, ok");
+      })
+      .n();
+
+    w.comment("String returns the name of k.")
+      .n()
+      .method("k *Key[T]", "String", "", "string", (b) => {
+        b.return("k.name");
+      })
+      .n();
+
+    w.comment(
+      "keyContext carries the value of a key like context.WithValue, and returns",
+    )
+      .comment(
+        "itself under keyContextKey so DataFrom can list the values of all keys.",
+      )
+      .struct("keyContext", (b) => {
+        b.l("context.Context")
+          .l("key   interface{}")
+          .l("name  string")
+          .l("value interface{}");
+      });
+
+    w.comment("keyContextKey is the key a keyContext returns itself under.")
+      .type("keyContextKey", "struct{}");
+
+    w.comment(
+      "Value returns the value of c's key, c itself under keyContextKey, and the",
+    )
+      .comment("values of the parent context otherwise.")
+      .n()
+      .method(
+        "c *keyContext",
+        "Value",
+        "key interface{}",
+        "interface{}",
+        (b) => {
+          b.l("switch key {")
+            .l("case c.key:")
+            .i()
+            .return("c.value")
+            .u()
+            .l("case keyContextKey{}:")
+            .i()
+            .return("c")
+            .u()
+            .l("}")
+            .return("c.Context.Value(key)");
+        },
+      );
+
+    w.comment(
+      "DataFrom rebuilds the Data map the former Context of middleware and handlers",
+    )
+      .comment(
+        "carried: the values set on ctx with Key.Set, by key name. Where keys share a",
+      )
+      .comment("name, the value set last wins.")
+      .comment("")
+      .comment(
+        "Deprecated: DataFrom is kept for one release to ease porting middleware",
+      )
+      .comment(
+        "written against the Data map; get the values with Key.Get instead.",
+      )
+      .n()
+      .func("DataFrom(ctx context.Context) map[string]interface{}", (b) => {
+        b.decl("data", "make(map[string]interface{})")
+          .decl("c, _", "ctx.Value(keyContextKey{}).(*keyContext)")
+          .l("for c != nil {")
+          .i()
+          .if("_, ok := data[c.name]; !ok", (b) => {
+            b.l("data[c.name] = c.value");
+          })
+          .l("c, _ = c.Context.Value(keyContextKey{}).(*keyContext)")
+          .u()
+          .l("}")
+          .return("data");
+      });
+  }
+}
@@ -0,0 +1,127 @@
+import type {
+  ContractDefinition,
+  Property,
+  TypeReference,
+} from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import type { CollectedType } from "./type-collector";
+
+/**
+ * Generates dates.go: the Date type fields validated as calendar dates
+ * (z.iso.date()) are generated with, encoded as "YYYY-MM-DD" instead of the
+ * RFC 3339 timestamps time.Time uses.
+ */
+export class GoDateGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate dates.go, or null when no field in the contract is a date.
+   * @param contract - The contract definition
+   * @param collectedTypes - Nested types from GoTypeCollector
+   */
+  generateDates(
+    contract: ContractDefinition,
+    collectedTypes: CollectedType[] = [],
+  ): string | null {
+    const usesDate =
+      contract.types.some((type) => hasDateProperty(type.properties)) ||
+      collectedTypes.some((collected) => hasDateField(collected.typeRef));
+    if (!usesDate) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("encoding/json", "fmt", "time");
+
+    w.comment(
+      "DateFormat is the layout Date values are encoded and parsed with.",
+    )
+      .l('const DateFormat = "2006-01-02"')
+      .n();
+
+    w.comment(
+      "Date is a calendar date without a time of day, sent as YYYY-MM-DD. It",
+    )
+      .comment("embeds the time.Time at midnight UTC on that day.")
+      .struct("Date", (b) => {
+        b.l("time.Time");
+      });
+
+    w.comment("NewDate returns the date t falls on in its location.")
+      .n()
+      .func("NewDate(t time.Time) Date", (b) => {
+        b.return(
+          "Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}",
+        );
+      });
+
+    w.comment("ParseDate parses a YYYY-MM-DD string into a Date")
+      .n()
+      .func("ParseDate(s string) (Date, error)", (b) => {
+        b.l("t, err := time.Parse(DateFormat, s)")
+          .ifErr((b) => {
+            b.return(
+              'Date{}, fmt.Errorf("invalid date %q, must be YYYY-MM-DD", s)',
+            );
+          })
+          .return("Date{t}, nil");
+      });
+
+    w.comment("String formats d as YYYY-MM-DD")
+      .n()
+      .method("d Date", "String", "", "string", (b) => {
+        b.return("d.Format(DateFormat)");
+      });
+
+    w.comment("MarshalJSON encodes d as a YYYY-MM-DD string")
+      .n()
+      .method("d Date", "MarshalJSON", "", "([]byte, error)", (b) => {
+        b.return("json.Marshal(d.String())");
+      });
+
+    w.comment("UnmarshalJSON decodes a YYYY-MM-DD string into d")
+      .n()
+      .method("d *Date", "UnmarshalJSON", "data []byte", "error", (b) => {
+        b.var("s", "string")
+          .if("err := json.Unmarshal(data, &s); err != nil", (b) => {
+            b.return("err");
+          })
+          .l("parsed, err := ParseDate(s)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .l("*d = parsed")
+          .return("nil");
+      });
+
+    return w.toString();
+  }
+}
+
+function hasDateProperty(properties: Property[] | undefined): boolean {
+  return (properties ?? []).some(
+    (prop) => !!prop.validation?.date || hasDateField(prop.type),
+  );
+}
+
+function hasDateField(typeRef: TypeReference): boolean {
+  if (hasDateProperty(typeRef.properties)) {
+    return true;
+  }
+  if (typeof typeRef.baseType === "object") {
+    return hasDateField(typeRef.baseType);
+  }
+  const children = [
+    typeRef.elementType,
+    typeRef.valueType,
+    ...(typeRef.unionTypes ?? []),
+    ...(typeRef.tupleElements ?? []),
+  ];
+  return children.some((child) => !!child && hasDateField(child));
+}
@@ -0,0 +1,207 @@
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+
+/**
+ * Generates defaults.go for contracts with fields declared with .default():
+ * an ApplyDefaults method on every struct holding such a field, directly or
+ * in a nested struct, setting the fields that are absent. These fields are
+ * optional pointers, so a field the client left out (nil) is told apart
+ * from one it set to its zero value. The router applies the defaults of
+ * decoded inputs before normalizing and validating them, so handlers get
+ * the values the contract promises.
+ */
+export class GoDefaultsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private defaulted: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate defaults.go, or undefined when no field has a default.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateDefaults(
+    structs: GoStruct[],
+    aliases: Map<string, string>,
+  ): string | undefined {
+    this.aliases = aliases;
+    // Structs with defaults, then those nesting them, until no more are
+    // found
+    this.defaulted = new Set();
+    let found = true;
+    while (found) {
+      found = false;
+      for (const struct of structs) {
+        if (this.defaulted.has(struct.name)) continue;
+        if (
+          struct.fields.some(
+            (field) =>
+              field.default !== undefined || this.holdsDefaults(field.type),
+          )
+        ) {
+          this.defaulted.add(struct.name);
+          found = true;
+        }
+      }
+    }
+    if (this.defaulted.size === 0) {
+      return undefined;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName);
+    for (const struct of structs) {
+      if (this.defaulted.has(struct.name)) {
+        this.generateApplyDefaults(w, struct);
+      }
+    }
+    return w.toString();
+  }
+
+  /**
+   * The types with an ApplyDefaults method, aliases included, once
+   * generateDefaults has run.
+   */
+  getDefaulted(): Set<string> {
+    const types = new Set(this.defaulted);
+    for (const [alias, target] of this.aliases) {
+      if (this.defaulted.has(target)) {
+        types.add(alias);
+      }
+    }
+    return types;
+  }
+
+  private generateApplyDefaults(w: GoBuilder, struct: GoStruct): void {
+    const defaults = struct.fields.filter(
+      (field) => field.default !== undefined,
+    );
+    const nested = struct.fields.filter(
+      (field) => field.default === undefined && this.holdsDefaults(field.type),
+    );
+    const names = defaults.map((field) => field.name);
+    const parts: string[] = [];
+    if (names.length > 0) {
+      const absent = names.length === 1 ? "it is absent" : "they are absent";
+      parts.push(`sets ${joinNames(names)} when ${absent}`);
+    }
+    if (nested.length > 0) {
+      parts.push("applies the defaults of its nested values");
+    }
+    w.doc(`ApplyDefaults ${parts.join(", and ")}.`);
+    w.n().method(`v *${struct.name}`, "ApplyDefaults", "", "", (b) => {
+      for (const field of defaults) {
+        const expr = `v.${field.name}`;
+        b.if(`${expr} == nil`, (b) => {
+          b.decl("value", goLiteral(field.default!, field.type.slice(1))).l(
+            `${expr} = &value`,
+          );
+        });
+      }
+      for (const field of nested) {
+        this.applyNested(b, field.type, `v.${field.name}`, 0);
+      }
+    });
+  }
+
+  // Applies the defaults of the structs expr holds
+  private applyNested(
+    b: GoBuilder,
+    goType: string,
+    expr: string,
+    depth: number,
+  ): void {
+    if (this.isDefaulted(goType)) {
+      b.l(`${expr}.ApplyDefaults()`);
+      return;
+    }
+    const suffix = depth === 0 ? "" : `${depth}`;
+    const elem = elemType(goType);
+    if (elem === undefined) return;
+    if (goType.startsWith("*")) {
+      b.if(`${expr} != nil`, (b) => {
+        // Pointer receivers take the pointer itself
+        const target = this.isDefaulted(elem) ? expr : `*${expr}`;
+        this.applyNested(b, elem, target, depth + 1);
+      });
+    } else if (goType.startsWith("[]")) {
+      const index = `i${suffix}`;
+      b.l(`for ${index} := range ${expr} {`).i();
+      this.applyNested(
+        b,
+        elem,
+        `${parenthesize(expr)}[${index}]`,
+        depth + 1,
+      );
+      b.u().l("}");
+    } else {
+      // Map values are copies, applied and stored back
+      const key = `k${suffix}`;
+      const value = `e${suffix}`;
+      b.l(`for ${key}, ${value} := range ${expr} {`).i();
+      this.applyNested(b, elem, value, depth + 1);
+      b.l(`${parenthesize(expr)}[${key}] = ${value}`).u().l("}");
+    }
+  }
+
+  // Whether a field of goType holds structs with defaults
+  private holdsDefaults(goType: string): boolean {
+    if (this.isDefaulted(goType)) {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.holdsDefaults(elem);
+  }
+
+  private isDefaulted(goType: string): boolean {
+    return this.defaulted.has(this.aliases.get(goType) ?? goType);
+  }
+}
+
+// The Go literal of a default held by a goType, converted unless it is the
+// literal's own type: 20, 0.5, "medium" or Priority("medium")
+function goLiteral(value: string | number | boolean, goType: string): string {
+  const literal = JSON.stringify(value);
+  const literalType =
+    typeof value === "string"
+      ? "string"
+      : typeof value === "boolean"
+        ? "bool"
+        : Number.isInteger(value)
+          ? "int"
+          : "float64";
+  return goType === literalType ? literal : `${goType}(${literal})`;
+}
+
+// Dereferenced pointers are indexed within parentheses
+function parenthesize(expr: string): string {
+  return expr.startsWith("*") ? `(${expr})` : expr;
+}
+
+// The element type of pointer, slice and map types
+function elemType(goType: string): string | undefined {
+  if (goType.startsWith("*")) {
+    return goType.slice(1);
+  }
+  if (goType.startsWith("[]")) {
+    return goType.slice(2);
+  }
+  if (goType.startsWith("map[")) {
+    return goType.slice(goType.indexOf("]") + 1);
+  }
+  return undefined;
+}
+
+// Limit, or Limit and Priority, or Limit, Priority and Sort
+function joinNames(names: string[]): string {
+  if (names.length === 1) {
+    return names[0];
+  }
+  return `${names.slice(0, -1).join(", ")} and ${names[names.length - 1]}`;
+}
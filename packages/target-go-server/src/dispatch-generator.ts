@@ -0,0 +1,202 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates dispatch.go: the transport-agnostic entry points of the router.
+ * Router.Dispatch calls a method from its name and JSON params, so the same
+ * handlers can serve message bus consumers, and Router.ServeMessage answers
+ * a request/reply message carrying an RPC envelope. nats.go and amqp.go,
+ * generated with the `nats` and `amqp` options, serve it from a NATS subject
+ * or an AMQP RPC queue.
+ */
+export class GoDispatchGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate dispatch.go.
+   * @param hasWarnings - Whether warnings.go was generated, so replies carry the warnings of their call
+   */
+  generateDispatch(hasWarnings = false): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "runtime/debug",
+      "time",
+    );
+
+    this.generateDispatcher(w, hasWarnings);
+    this.generateServeMessage(w, hasWarnings);
+
+    return w.toString();
+  }
+
+  private generateDispatcher(w: GoBuilder, hasWarnings: boolean): void {
+    w.comment(
+      "Dispatcher calls methods independently of the transport that carried them.",
+    )
+      .comment(
+        "*Router implements it; ServeHTTP and ServeMessage are both built on it.",
+      )
+      .type(
+        "Dispatcher interface",
+        "{\n\tDispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, error)\n}",
+      );
+
+    w.comment(
+      "Dispatch calls a query or mutation with its JSON params outside of HTTP, such",
+    )
+      .comment(
+        "as from a message bus consumer. Auth requirements, permissions, decoding,",
+      )
+      .comment(
+        "validation, interceptors, timeouts and the handler run as in ServeHTTP, but",
+      )
+      .comment(
+        "HTTP middleware does not, so put the caller in ctx (WithUserID, WithPrincipal)",
+      )
+      .comment(
+        "first. The handler's RequestInfo has no Request or ResponseWriter. Calls are",
+      )
+      .comment(
+        "reported to the Logger, and subscriptions fail with METHOD_NOT_ALLOWED since",
+      )
+      .comment("they need a streaming transport.")
+      .n()
+      .method(
+        "r *Router",
+        "Dispatch",
+        "ctx context.Context, method string, params json.RawMessage",
+        "(result interface{}, err error)",
+        (b) => {
+          b.decl("info", "RequestInfo{Method: method}").l(
+            "ctx = r.withDependencies(WithRequestInfo(ctx, info))",
+          );
+          if (hasWarnings) {
+            b.l("ctx = withWarnings(ctx)");
+          }
+          b.decl("outcome", "OutcomeRejected")
+            .decl("start", "time.Now()")
+            .l("defer func() {")
+            .i()
+            .if("rec := recover(); rec != nil", (b) => {
+              b.l(
+                'r.logf("xrpc: panic dispatching %s: %v\\n%s", method, rec, debug.Stack())',
+              ).l(
+                'result, err = nil, NewError(CodeInternal, "Internal server error")',
+              );
+            })
+            .if("r.logger != nil", (b) => {
+              b.decl("entry", "LogEntry{")
+                .i()
+                .l("Method:   method,")
+                .l("Duration: time.Since(start),")
+                .l("Outcome:  outcome,")
+                .u()
+                .l("}")
+                .ifErr((b) => {
+                  b.l("entry.Code = AsError(err).Code");
+                })
+                .l("r.logger.LogRequest(ctx, entry)");
+            })
+            .u()
+            .l("}()")
+            .l("result, outcome, err = r.dispatch(ctx, info, method, params)")
+            .return("result, err");
+        },
+      );
+  }
+
+  private generateServeMessage(w: GoBuilder, hasWarnings: boolean): void {
+    w.comment(
+      'ServeMessage answers a request/reply message carrying a {"method", "params"}',
+    )
+      .comment(
+        'envelope with the {"result": ...} or {"error": ...} body ServeHTTP would send.',
+      )
+      .comment(
+        "ServeNATS and ServeAMQP, generated with the nats and amqp options of the",
+      )
+      .comment(
+        "target, serve it from a NATS subject or an AMQP RPC queue; other transports",
+      )
+      .comment("pass it the body of each request and send back the reply.")
+      .n()
+      .method(
+        "r *Router",
+        "ServeMessage",
+        "ctx context.Context, data []byte",
+        "[]byte",
+        (b) => {
+          b.var("request", "struct {")
+            .i()
+            .l('Method string          `json:"method"`')
+            .l('Params json.RawMessage `json:"params"`')
+            .u()
+            .l("}")
+            .if("err := json.Unmarshal(data, &request); err != nil", (b) => {
+              b.return(
+                `r.encodeReply(nil, Errorf(CodeInvalidArgument, "Invalid request: %v", err)${hasWarnings ? ", nil" : ""})`,
+              );
+            });
+          if (hasWarnings) {
+            // Dispatch adds the warnings of the call to this collector
+            b.l("ctx = withWarnings(ctx)");
+          }
+          b.decl(
+            "result, err",
+            "r.Dispatch(ctx, request.Method, request.Params)",
+          ).return(
+            hasWarnings
+              ? "r.encodeReply(result, err, WarningsFrom(ctx))"
+              : "r.encodeReply(result, err)",
+          );
+        },
+      );
+
+    w.comment(
+      "encodeReply encodes the reply body of a dispatched call, reporting results",
+    ).comment("that fail to encode as errors.");
+    if (hasWarnings) {
+      w.comment("Warnings are added next to the result.");
+    }
+    w.n().method(
+      "r *Router",
+      "encodeReply",
+      hasWarnings
+        ? "result interface{}, err error, warnings ValidationErrors"
+        : "result interface{}, err error",
+      "[]byte",
+      (b) => {
+        b.if("err == nil", (b) => {
+          b.decl("buf, encodeErr", "r.marshal(result, true)")
+            .if("encodeErr == nil", (b) => {
+              if (hasWarnings) {
+                b.if("len(warnings) > 0", (b) => {
+                  b.l("buf.appendWarnings(warnings)");
+                });
+              }
+              b.comment("Copied out of the pooled buffer, without its newline")
+                .decl(
+                  "body",
+                  "append([]byte(nil), buf.Bytes()[:buf.Len()-1]...)",
+                )
+                .l("buf.release()")
+                .return("body");
+            })
+            .l("err = encodeErr");
+        })
+          .decl("rpcErr", "AsError(err)")
+          .l("r.logCause(rpcErr)")
+          .decl("body, _", "json.Marshal(errorEnvelope{Error: rpcErr})")
+          .return("body");
+      },
+    );
+  }
+}
@@ -0,0 +1,154 @@
+import type { ContractDefinition, TypeReference } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { goEnumConstNames } from "./patterns";
+import type { CollectedType } from "./type-collector";
+import { isStringEnum } from "./type-mapper";
+
+/**
+ * Generates enums.go: a typed string for every string enum in the contract
+ * with a constant per value, plus JSON methods that reject any other value so
+ * invalid enums fail when params are decoded and when results are encoded.
+ */
+export class GoEnumGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate enums.go, or null when the contract has no string enums.
+   * @param contract - The contract definition
+   * @param collectedTypes - Nested types from GoTypeCollector, including inline enums it named
+   */
+  generateEnums(
+    contract: ContractDefinition,
+    collectedTypes: CollectedType[] = [],
+  ): string | null {
+    const enums = new Map<string, string[]>();
+    const descriptions = new Map<string, string>();
+    const addEnum = (name: string, typeRef: Partial<TypeReference>) => {
+      const goName = toPascalCase(name);
+      if (isStringEnum(typeRef as TypeReference) && !enums.has(goName)) {
+        enums.set(goName, typeRef.enumValues as string[]);
+        if (typeRef.description) {
+          descriptions.set(goName, typeRef.description);
+        }
+      }
+    };
+    for (const type of contract.types) {
+      addEnum(type.name, type);
+    }
+    for (const collected of collectedTypes) {
+      addEnum(collected.name, collected.typeRef);
+    }
+    if (enums.size === 0) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("encoding/json", "fmt");
+
+    // Enums with the same values are aliases of the first, the same way
+    // GoTypeGenerator shares structs of the same shape
+    const byValues = new Map<string, string>();
+    for (const [name, values] of enums) {
+      const key = JSON.stringify(values);
+      const existing = byValues.get(key);
+      if (existing) {
+        w.comment(`${name} has the same values as ${existing}.`).type(
+          name,
+          `= ${existing}`,
+        );
+        continue;
+      }
+      byValues.set(key, name);
+      this.generateEnum(w, name, values, descriptions.get(name));
+    }
+
+    return w.toString();
+  }
+
+  private generateEnum(
+    w: GoBuilder,
+    name: string,
+    values: string[],
+    description?: string,
+  ): void {
+    const constNames = goEnumConstNames(name, values);
+    const width = Math.max(...constNames.map((constName) => constName.length));
+    const allowed = values.join(", ").replace(/%/g, "%%");
+    const message = JSON.stringify(
+      `invalid ${name} %q, must be one of: ${allowed}`,
+    );
+
+    w.comment(`${name} enum type`);
+    if (description) {
+      w.l("//").doc(description);
+    }
+    w.type(name, "string");
+
+    w.l("const (").i();
+    values.forEach((value, index) => {
+      w.l(
+        `${constNames[index].padEnd(width)} ${name} = ${JSON.stringify(value)}`,
+      );
+    });
+    w.u().l(")").n();
+
+    w.comment(`IsValid checks if the ${name} value is valid`)
+      .n()
+      .method(`e ${name}`, "IsValid", "", "bool", (b) => {
+        b.l("switch e {")
+          .l(`case ${constNames.join(", ")}:`)
+          .i()
+          .return("true")
+          .u()
+          .l("}")
+          .return("false");
+      });
+
+    w.comment(`Parse${name} parses a string into a ${name} value`)
+      .n()
+      .func(`Parse${name}(s string) (${name}, error)`, (b) => {
+        b.decl("e", `${name}(s)`)
+          .if("!e.IsValid()", (b) => {
+            b.return(`"", fmt.Errorf("invalid ${name}: %s", s)`);
+          })
+          .return("e, nil");
+      });
+
+    w.comment(`All${name}Values returns all valid ${name} values`)
+      .n()
+      .func(`All${name}Values() []${name}`, (b) => {
+        b.return(`[]${name}{${constNames.join(", ")}}`);
+      });
+
+    w.comment(`MarshalJSON encodes e, failing if it is not a valid ${name}`)
+      .n()
+      .method(`e ${name}`, "MarshalJSON", "", "([]byte, error)", (b) => {
+        b.if("!e.IsValid()", (b) => {
+          b.return(`nil, fmt.Errorf("invalid ${name} %q", string(e))`);
+        }).return("json.Marshal(string(e))");
+      });
+
+    w.comment(
+      `UnmarshalJSON decodes e, failing if the value is not a valid ${name}`,
+    )
+      .n()
+      .method(`e *${name}`, "UnmarshalJSON", "data []byte", "error", (b) => {
+        b.var("s", "string")
+          .if("err := json.Unmarshal(data, &s); err != nil", (b) => {
+            b.return("err");
+          })
+          .if(`!${name}(s).IsValid()`, (b) => {
+            b.return(`fmt.Errorf(${message}, s)`);
+          })
+          .l(`*e = ${name}(s)`)
+          .return("nil");
+      });
+  }
+}
@@ -0,0 +1,264 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Error codes emitted into errors.go, in declaration order, with the HTTP
+ * status each one maps to.
+ */
+const ERROR_CODES: Array<{ name: string; code: string; status: string }> = [
+  {
+    name: "CodeInvalidArgument",
+    code: "INVALID_ARGUMENT",
+    status: "http.StatusBadRequest",
+  },
+  {
+    name: "CodeUnauthorized",
+    code: "UNAUTHORIZED",
+    status: "http.StatusUnauthorized",
+  },
+  {
+    name: "CodePermissionDenied",
+    code: "PERMISSION_DENIED",
+    status: "http.StatusForbidden",
+  },
+  { name: "CodeNotFound", code: "NOT_FOUND", status: "http.StatusNotFound" },
+  {
+    name: "CodeMethodNotFound",
+    code: "METHOD_NOT_FOUND",
+    status: "http.StatusNotFound",
+  },
+  {
+    name: "CodeMethodNotAllowed",
+    code: "METHOD_NOT_ALLOWED",
+    status: "http.StatusMethodNotAllowed",
+  },
+  {
+    name: "CodeAlreadyExists",
+    code: "ALREADY_EXISTS",
+    status: "http.StatusConflict",
+  },
+  {
+    name: "CodeResourceExhausted",
+    code: "RESOURCE_EXHAUSTED",
+    status: "http.StatusTooManyRequests",
+  },
+  {
+    name: "CodeUnimplemented",
+    code: "UNIMPLEMENTED",
+    status: "http.StatusNotImplemented",
+  },
+  {
+    name: "CodeUnavailable",
+    code: "UNAVAILABLE",
+    status: "http.StatusServiceUnavailable",
+  },
+  {
+    name: "CodeDeadlineExceeded",
+    code: "DEADLINE_EXCEEDED",
+    status: "http.StatusGatewayTimeout",
+  },
+  {
+    name: "CodeInternal",
+    code: "INTERNAL",
+    status: "http.StatusInternalServerError",
+  },
+];
+
+/**
+ * Generates errors.go: the Error type handlers return to control the code,
+ * message and details sent to clients, and the error envelope writer used by
+ * the router.
+ */
+export class GoErrorsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateErrors(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "errors",
+      "fmt",
+      "net/http",
+      "strconv",
+    );
+
+    this.generateErrorCodes(w);
+    this.generateErrorType(w);
+    this.generateConstructors(w);
+    this.generateAsError(w);
+    this.generateWriteError(w);
+
+    return w.toString();
+  }
+
+  private generateErrorCodes(w: GoBuilder): void {
+    w.comment(
+      "ErrorCode classifies a failed call so clients can branch on it.",
+    ).type("ErrorCode", "string");
+
+    const width = Math.max(...ERROR_CODES.map((entry) => entry.name.length));
+    w.l("const (").i();
+    for (const entry of ERROR_CODES) {
+      w.l(`${entry.name.padEnd(width)} ErrorCode = "${entry.code}"`);
+    }
+    w.u().l(")").n();
+
+    w.comment(
+      "HTTPStatus returns the HTTP status code sent with errors of this code.",
+    )
+      .n()
+      .method("c ErrorCode", "HTTPStatus", "", "int", (b) => {
+        b.l("switch c {");
+        for (const entry of ERROR_CODES) {
+          if (entry.name === "CodeInternal") continue;
+          b.l(`case ${entry.name}:`).i().return(entry.status).u();
+        }
+        b.l("default:")
+          .i()
+          .return("http.StatusInternalServerError")
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateErrorType(w: GoBuilder): void {
+    w.comment(
+      "Error is returned by handlers and middleware to control the code, message",
+    )
+      .comment("and details sent to the client. Any other error is reported as")
+      .comment("CodeInternal.")
+      .struct("Error", (b) => {
+        b.l('Code    ErrorCode   `json:"code"`')
+          .l('Message string      `json:"message"`')
+          .l('Details interface{} `json:"details,omitempty"`')
+          .comment("The error AsError reported as CodeInternal, kept from the client")
+          .l("cause error");
+      })
+      .n();
+
+    w.method("e *Error", "Error", "", "string", (b) => {
+      b.return('fmt.Sprintf("%s: %s", e.Code, e.Message)');
+    });
+
+    w.comment(
+      "Unwrap returns the error AsError reported as CodeInternal, or else the details",
+    )
+      .comment(
+        "of e when they are an error, such as the ValidationErrors of a",
+      )
+      .comment("CodeInvalidArgument, so errors.Is and errors.As find them.")
+      .n()
+      .method("e *Error", "Unwrap", "", "error", (b) => {
+        b.if("e.cause != nil", (b) => {
+          b.return("e.cause");
+        }).if("err, ok := e.Details.(error); ok", (b) => {
+          b.return("err");
+        }).return("nil");
+      });
+  }
+
+  private generateConstructors(w: GoBuilder): void {
+    w.comment("NewError creates an Error with the given code and message.")
+      .n()
+      .func("NewError(code ErrorCode, message string) *Error", (b) => {
+        b.return("&Error{Code: code, Message: message}");
+      });
+
+    w.comment("Errorf creates an Error with a formatted message.")
+      .n()
+      .func(
+        "Errorf(code ErrorCode, format string, args ...interface{}) *Error",
+        (b) => {
+          b.return("&Error{Code: code, Message: fmt.Sprintf(format, args...)}");
+        },
+      );
+
+    w.comment("WithDetails returns a copy of e carrying details.")
+      .n()
+      .method("e *Error", "WithDetails", "details interface{}", "*Error", (b) => {
+        b.return(
+          "&Error{Code: e.Code, Message: e.Message, Details: details}",
+        );
+      });
+
+    w.comment(
+      "RetryInfo is the Details of errors telling the caller when to try again, such",
+    )
+      .comment(
+        "as the RESOURCE_EXHAUSTED of calls a concurrency limit sheds. The router sends",
+      )
+      .comment("it as the Retry-After header too, rounded up to whole seconds.")
+      .struct("RetryInfo", (b) => {
+        b.l('RetryAfterMs int64 `json:"retryAfterMs"`');
+      });
+  }
+
+  private generateAsError(w: GoBuilder): void {
+    w.comment(
+      "AsError converts err into the Error sent to the client. Validation failures",
+    )
+      .comment(
+        "become CodeInvalidArgument and expired deadlines CodeDeadlineExceeded; errors",
+      )
+      .comment(
+        "not created by this package become CodeInternal with a generic message, so",
+      )
+      .comment(
+        "SQL errors, paths and upstream messages do not reach clients; the Error keeps",
+      )
+      .comment("err for Unwrap and the router logs it.")
+      .n()
+      .func("AsError(err error) *Error", (b) => {
+        b.var("rpcErr", "*Error")
+          .if("errors.As(err, &rpcErr)", (b) => {
+            b.return("rpcErr");
+          })
+          .var("validationErrs", "ValidationErrors")
+          .if("errors.As(err, &validationErrs)", (b) => {
+            b.return(
+              '&Error{Code: CodeInvalidArgument, Message: "Validation failed", Details: validationErrs}',
+            );
+          })
+          .if("errors.Is(err, context.DeadlineExceeded)", (b) => {
+            b.return('NewError(CodeDeadlineExceeded, "Deadline exceeded")');
+          })
+          .return(
+            '&Error{Code: CodeInternal, Message: "Internal server error", cause: err}',
+          );
+      });
+  }
+
+  private generateWriteError(w: GoBuilder): void {
+    w.comment(
+      "writeError writes err as the standard error envelope with the given HTTP status.",
+    )
+      .n()
+      .func(
+        "writeError(w http.ResponseWriter, status int, err *Error)",
+        (b) => {
+          b.if("rec, ok := w.(errorCodeRecorder); ok", (b) => {
+            b.l("rec.RecordErrorCode(string(err.Code))");
+          })
+            .if("info, ok := err.Details.(RetryInfo); ok", (b) => {
+              b.l(
+                'w.Header().Set("Retry-After", strconv.FormatInt((info.RetryAfterMs+999)/1000, 10))',
+              );
+            })
+            .l('w.Header().Set("Content-Type", "application/json")')
+            .l("w.WriteHeader(status)")
+            .if(
+              "buf, encodeErr := encodeJSON(errorEnvelope{Error: err}); encodeErr == nil",
+              (b) => {
+                b.l("w.Write(buf.Bytes())").l("buf.release()");
+              },
+            );
+        },
+      );
+  }
+}
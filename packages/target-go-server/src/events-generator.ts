@@ -0,0 +1,249 @@
+import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { toMethodName } from "./server-generator";
+
+/**
+ * Generates events.go: the Event mutations declared with an event option
+ * publish after they succeed, with a typed payload per event derived from the
+ * mutation's output (TaskCreatedEvent for "task.created"), the EventPublisher
+ * interface a message bus or queue plugs in through, and WebhookPublisher
+ * POSTing events to a URL, so downstream services react to changes without
+ * polling.
+ */
+export class GoEventsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateEvents(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const publishing = contract.endpoints.filter((endpoint) => endpoint.event);
+
+    w.package(this.packageName).import(
+      "bytes",
+      "context",
+      "crypto/hmac",
+      "crypto/rand",
+      "crypto/sha256",
+      "encoding/hex",
+      "encoding/json",
+      "fmt",
+      "net/http",
+      "time",
+    );
+
+    if (publishing.length > 0) {
+      w.comment("Names of the events mutations publish.").l("const (").i();
+      for (const endpoint of publishing) {
+        w.l(`${eventConstName(endpoint)} = "${endpoint.event}"`);
+      }
+      w.u().l(")").n();
+
+      for (const endpoint of publishing) {
+        w.comment(
+          `${eventPayloadType(endpoint)} is the payload of ${endpoint.event} events, the result of`,
+        )
+          .comment(`${endpoint.fullName}.`)
+          .type(
+            eventPayloadType(endpoint),
+            toPascalCase(endpoint.output.name!),
+          );
+      }
+    }
+
+    this.generateEvent(w);
+    this.generatePublisher(w);
+    this.generateWebhook(w);
+
+    return w.toString();
+  }
+
+  private generateEvent(w: GoBuilder): void {
+    w.comment(
+      "Event is published through the router's EventPublisher after a call of a",
+    )
+      .comment(
+        "mutation declaring an event succeeds. It is encoded as JSON with its payload",
+      )
+      .comment("under data.")
+      .struct("Event", (b) => {
+        b.comment(
+          "ID is unique to the event, so consumers can drop events delivered twice.",
+        )
+          .l('ID string `json:"id"`')
+          .comment('Name is the name of the event, such as "task.created".')
+          .l('Name string `json:"event"`')
+          .comment("Method is the mutation whose call published the event.")
+          .l('Method string `json:"method"`')
+          .l('Time time.Time `json:"time"`')
+          .comment(
+            "Payload is the result of the call as the event's payload type, such as",
+          )
+          .comment("TaskCreatedEvent.")
+          .l('Payload interface{} `json:"data"`');
+      });
+
+    w.comment("newEventID returns a random 128-bit event ID in hex.")
+      .n()
+      .func("newEventID() string", (b) => {
+        b.var("id", "[16]byte")
+          .l("rand.Read(id[:])")
+          .return("hex.EncodeToString(id[:])");
+      });
+  }
+
+  private generatePublisher(w: GoBuilder): void {
+    w.comment(
+      "EventPublisher delivers events to a message bus, queue or webhook. Publish is",
+    )
+      .comment(
+        "called with the context of the call once its handler has returned, before",
+      )
+      .comment(
+        "its result is sent; publishers that are slow should hand events off to a",
+      )
+      .comment("background worker.")
+      .l("type EventPublisher interface {")
+      .i()
+      .l("Publish(ctx context.Context, event Event) error")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("EventPublisherFunc adapts a function to EventPublisher.").type(
+      "EventPublisherFunc",
+      "func(ctx context.Context, event Event) error",
+    );
+
+    w.comment("Publish calls f.")
+      .n()
+      .method(
+        "f EventPublisherFunc",
+        "Publish",
+        "ctx context.Context, event Event",
+        "error",
+        (b) => {
+          b.return("f(ctx, event)");
+        },
+      );
+
+    w.comment(
+      "SetEventPublisher sets the EventPublisher events are published through; until",
+    )
+      .comment(
+        "one is set they are dropped. Failing to publish an event does not fail the",
+      )
+      .comment(
+        "call, whose mutation is done: the error is written to the error log.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "SetEventPublisher",
+        "publisher EventPublisher",
+        "*Router",
+        (b) => {
+          b.l("r.eventPublisher = publisher").return("r");
+        },
+      );
+
+    w.comment(
+      "publish publishes event, built from the result of a call of info.Method, with",
+    )
+      .comment("an ID and the current time.")
+      .n()
+      .method(
+        "r *Router",
+        "publish",
+        "ctx context.Context, info RequestInfo, event Event",
+        "",
+        (b) => {
+          b.if("r.eventPublisher == nil", (b) => {
+            b.return();
+          })
+            .l("event.ID = newEventID()")
+            .l("event.Method = info.Method")
+            .l("event.Time = time.Now().UTC()")
+            .if("err := r.eventPublisher.Publish(ctx, event); err != nil", (b) => {
+              b.l(
+                'r.logf("xrpc: publishing %s event of %s: %v", event.Name, info.Method, err)',
+              );
+            });
+        },
+      );
+  }
+
+  private generateWebhook(w: GoBuilder): void {
+    w.comment(
+      "WebhookPublisher returns an EventPublisher POSTing every event as JSON to url",
+    )
+      .comment(
+        "with client, http.DefaultClient when nil. With a secret, requests carry the",
+      )
+      .comment(
+        "hex HMAC-SHA256 of their body in the X-Webhook-Signature header, as",
+      )
+      .comment(
+        '"sha256=<hex>", for the receiver to check they come from this server.',
+      )
+      .comment("Responses other than 2xx are errors.")
+      .n()
+      .func(
+        "WebhookPublisher(url string, secret []byte, client *http.Client) EventPublisher",
+        (b) => {
+          b.if("client == nil", (b) => {
+            b.l("client = http.DefaultClient");
+          })
+            .l(
+              "return EventPublisherFunc(func(ctx context.Context, event Event) error {",
+            )
+            .i()
+            .decl("body, err", "json.Marshal(event)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .decl(
+              "req, err",
+              "http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))",
+            )
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l('req.Header.Set("Content-Type", "application/json")')
+            .if("len(secret) > 0", (b) => {
+              b.decl("mac", "hmac.New(sha256.New, secret)")
+                .l("mac.Write(body)")
+                .l(
+                  'req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))',
+                );
+            })
+            .decl("resp, err", "client.Do(req)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("resp.Body.Close()")
+            .if("resp.StatusCode < 200 || resp.StatusCode > 299", (b) => {
+              b.return('fmt.Errorf("webhook %s answered %s", url, resp.Status)');
+            })
+            .return("nil")
+            .u()
+            .l("})");
+        },
+      );
+  }
+}
+
+// EventTaskCreated for "task.created"
+export function eventConstName(endpoint: Endpoint): string {
+  return `Event${toMethodName(endpoint.event!)}`;
+}
+
+// TaskCreatedEvent for "task.created"
+export function eventPayloadType(endpoint: Endpoint): string {
+  return `${toMethodName(endpoint.event!)}Event`;
+}
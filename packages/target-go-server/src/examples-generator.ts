@@ -0,0 +1,101 @@
+import {
+  type ContractDefinition,
+  ExampleError,
+  typeToExample,
+} from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { toPascalCase } from "./naming";
+
+/**
+ * Generates examples.go: an ExampleX() constructor for every input and output
+ * type returning a value that passes validation, derived from the type's
+ * schema, for handler tests and local seed data.
+ */
+export class GoExamplesGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate examples.go, or null when no type has a derivable example.
+   * @param contract - The contract definition
+   */
+  generateExamples(contract: ContractDefinition): string | null {
+    const examples = collectExamples(contract);
+    if (examples.size === 0) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("encoding/json");
+
+    w.comment(
+      "Example values derived from the contract's schemas as JSON; each satisfies",
+    )
+      .comment("the validation rules of its type.")
+      .l("const (")
+      .i();
+    for (const [typeName, example] of examples) {
+      w.l(`example${typeName} = ${goStringLiteral(example)}`);
+    }
+    w.u().l(")").n();
+
+    for (const typeName of examples.keys()) {
+      w.comment(
+        `Example${typeName} returns a ${typeName} that passes validation. Every call`,
+      )
+        .comment("returns a new value, so callers may change it.")
+        .n()
+        .func(`Example${typeName}() ${typeName}`, (b) => {
+          b.var("value", typeName)
+            .l(`mustDecodeExample(example${typeName}, &value)`)
+            .return("value");
+        });
+    }
+
+    w.comment(
+      "mustDecodeExample decodes an example into value. Examples are generated from",
+    )
+      .comment("the types they decode into, so an error is a generator bug.")
+      .n()
+      .func("mustDecodeExample(example string, value interface{})", (b) => {
+        b.if(
+          "err := json.Unmarshal([]byte(example), value); err != nil",
+          (b) => {
+            b.l('panic("invalid example: " + err.Error())');
+          },
+        );
+      });
+
+    return w.toString();
+  }
+}
+
+/**
+ * The example JSON of every input and output type, keyed by Go type name.
+ * Types whose constraints no example satisfies, such as a regex the sampler
+ * can't match, are left out.
+ */
+export function collectExamples(
+  contract: ContractDefinition,
+): Map<string, string> {
+  const examples = new Map<string, string>();
+  const skipped = new Set<string>();
+  for (const endpoint of contract.endpoints) {
+    for (const typeRef of [endpoint.input, endpoint.output]) {
+      const name = toPascalCase(typeRef.name!);
+      if (examples.has(name) || skipped.has(name)) continue;
+      try {
+        examples.set(name, JSON.stringify(typeToExample(typeRef)));
+      } catch (error) {
+        if (!(error instanceof ExampleError)) throw error;
+        skipped.add(name);
+      }
+    }
+  }
+  return examples;
+}
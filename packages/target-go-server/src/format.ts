@@ -0,0 +1,46 @@
+import { spawnSync } from "node:child_process";
+import type { Diagnostic, GeneratedFile } from "@xrpckit/sdk";
+
+/**
+ * Runs the Go files through gofmt, which aligns what GoBuilder only indents,
+ * so generated files are what `gofmt -w` would leave and regenerating changes
+ * nothing else. Without gofmt on the PATH the files are returned as generated
+ * with a warning; a file gofmt cannot parse is kept as generated with a
+ * warning quoting gofmt, to be reported as a generator bug.
+ */
+export function formatGoFiles(
+  files: GeneratedFile[],
+  diagnostics: Diagnostic[],
+): GeneratedFile[] {
+  const formatted: GeneratedFile[] = [];
+  for (const file of files) {
+    if (!file.path.endsWith(".go")) {
+      formatted.push(file);
+      continue;
+    }
+    const result = spawnSync("gofmt", [], {
+      input: file.content,
+      encoding: "utf8",
+      maxBuffer: 64 * 1024 * 1024,
+    });
+    if (result.error) {
+      diagnostics.push({
+        severity: "warning",
+        message: "gofmt was not found, so the Go files are not formatted.",
+        hint: "Install Go, or set format = false to skip formatting.",
+      });
+      return files;
+    }
+    if (result.status !== 0) {
+      diagnostics.push({
+        severity: "warning",
+        message: `gofmt cannot parse ${file.path}: ${result.stderr.trim()}`,
+        path: file.path,
+      });
+      formatted.push(file);
+      continue;
+    }
+    formatted.push({ ...file, content: result.stdout });
+  }
+  return formatted;
+}
@@ -0,0 +1,171 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates gates.go: Router.Disable and Enable switching methods off and on
+ * while serving, the MethodGate interface a feature flag service plugs in
+ * through, and the maintenance and read-only modes of the router. They are
+ * checked before a call's params are decoded, and calls they stop fail with
+ * UNAVAILABLE, so operators can turn off a failing endpoint during an incident
+ * or writes during a migration without redeploying.
+ */
+export class GoGateGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateGates(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context");
+
+    w.comment(
+      "MethodGate decides whether calls of a method are served, e.g. from a feature",
+    )
+      .comment(
+        "flag service. Calls it refuses fail with UNAVAILABLE and the reason it gives.",
+      )
+      .l("type MethodGate interface {")
+      .i()
+      .l(
+        "Allow(ctx context.Context, info RequestInfo) (allowed bool, reason string)",
+      )
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("MethodGateFunc adapts a function to MethodGate.").type(
+      "MethodGateFunc",
+      "func(ctx context.Context, info RequestInfo) (bool, string)",
+    );
+
+    w.comment("Allow calls f.")
+      .n()
+      .method(
+        "f MethodGateFunc",
+        "Allow",
+        "ctx context.Context, info RequestInfo",
+        "(bool, string)",
+        (b) => {
+          b.return("f(ctx, info)");
+        },
+      );
+
+    w.comment(
+      "SetMethodGate sets the MethodGate consulted before every call of a method",
+    )
+      .comment("Disable has not switched off.")
+      .n()
+      .method("r *Router", "SetMethodGate", "gate MethodGate", "*Router", (b) => {
+        b.l("r.methodGate = gate").return("r");
+      });
+
+    w.comment(
+      "Disable switches off the methods matching pattern, using the same pattern",
+    )
+      .comment(
+        "syntax as UseFor: until Enable is called with the same pattern, their calls",
+      )
+      .comment(
+        "fail with UNAVAILABLE. It is safe to call while the router is serving.",
+      )
+      .n()
+      .method("r *Router", "Disable", "pattern string", "*Router", (b) => {
+        b.l("mustValidPattern(pattern)")
+          .l("r.disabled.Store(pattern, true)")
+          .return("r");
+      });
+
+    w.comment(
+      "Enable switches back on the methods Disable switched off with pattern.",
+    )
+      .n()
+      .method("r *Router", "Enable", "pattern string", "*Router", (b) => {
+        b.l("r.disabled.Delete(pattern)").return("r");
+      });
+
+    w.comment(
+      "SetMaintenance switches maintenance mode on or off: while it is on, every",
+    )
+      .comment(
+        "call fails with UNAVAILABLE and details {\"mode\": \"maintenance\"}. It is safe",
+      )
+      .comment("to call while the router is serving.")
+      .n()
+      .method("r *Router", "SetMaintenance", "on bool", "*Router", (b) => {
+        b.l("r.maintenance.Store(on)").return("r");
+      });
+
+    w.comment(
+      "SetReadOnly switches read-only mode on or off: while it is on, mutations fail",
+    )
+      .comment(
+        "with UNAVAILABLE and details {\"mode\": \"read-only\"}, and queries and",
+      )
+      .comment(
+        "subscriptions are served, e.g. while the backing store is migrated. It is",
+      )
+      .comment("safe to call while the router is serving.")
+      .n()
+      .method("r *Router", "SetReadOnly", "on bool", "*Router", (b) => {
+        b.l("r.readOnly.Store(on)").return("r");
+      });
+
+    w.comment(
+      "checkGate fails calls of m the mode of the router rules out, and those of",
+    )
+      .comment(
+        "methods switched off with Disable or refused by the MethodGate, with",
+      )
+      .comment("UNAVAILABLE.")
+      .n()
+      .method(
+        "r *Router",
+        "checkGate",
+        "ctx context.Context, info RequestInfo, m *methodDescriptor",
+        "error",
+        (b) => {
+          b.if("r.maintenance.Load()", (b) => {
+            b.return(
+              'NewError(CodeUnavailable, "Service is under maintenance").WithDetails(map[string]string{"mode": "maintenance"})',
+            );
+          })
+            .if('r.readOnly.Load() && m.kind == "mutation"', (b) => {
+              b.return(
+                'Errorf(CodeUnavailable, "Service is read-only: %s is a mutation", m.name).WithDetails(map[string]string{"mode": "read-only"})',
+              );
+            })
+            .decl("disabled", "false")
+            .l("r.disabled.Range(func(pattern, _ interface{}) bool {")
+            .i()
+            .l("disabled = matchMethod(pattern.(string), info.Method)")
+            .return("!disabled")
+            .u()
+            .l("})")
+            .if("disabled", (b) => {
+              b.return(
+                'Errorf(CodeUnavailable, "Method %s is disabled", info.Method)',
+              );
+            })
+            .if("r.methodGate == nil", (b) => {
+              b.return("nil");
+            })
+            .decl("allowed, reason", "r.methodGate.Allow(ctx, info)")
+            .if("allowed", (b) => {
+              b.return("nil");
+            })
+            .if('reason == ""', (b) => {
+              b.return(
+                'Errorf(CodeUnavailable, "Method %s is disabled", info.Method)',
+              );
+            })
+            .return("NewError(CodeUnavailable, reason)");
+        },
+      );
+
+    return w.toString();
+  }
+}
@@ -0,0 +1,2971 @@
+import { describe, expect, it } from "bun:test";
+import {
+  type ContractDefinition,
+  schemaHash,
+  type TypeReference,
+  type ValidationRules,
+} from "@xrpckit/sdk";
+import { goTarget } from "./generator";
+
+function createContract(): ContractDefinition {
+  const greetInput: TypeReference = {
+    kind: "object",
+    name: "GreetingGreetInput",
+    properties: [
+      {
+        name: "name",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+        validation: { minLength: 1, maxLength: 100 },
+      },
+    ],
+  };
+
+  const greetOutput: TypeReference = {
+    kind: "object",
+    name: "GreetingGreetOutput",
+    properties: [
+      {
+        name: "message",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+      },
+    ],
+  };
+
+  return {
+    routers: [],
+    types: [
+      {
+        name: "GreetingGreetInput",
+        kind: "object",
+        properties: greetInput.properties,
+      },
+      {
+        name: "GreetingGreetOutput",
+        kind: "object",
+        properties: greetOutput.properties,
+      },
+    ],
+    endpoints: [
+      {
+        name: "greet",
+        type: "query",
+        input: greetInput,
+        output: greetOutput,
+        fullName: "greeting.greet",
+      },
+    ],
+  };
+}
+
+function generateFiles(contract: ContractDefinition): Map<string, string> {
+  const output = goTarget.generate({
+    contract,
+    outputDir: "out",
+    options: { packageName: "server", format: false },
+  });
+  return new Map(output.files.map((file) => [file.path, file.content]));
+}
+
+describe("go-server target", () => {
+  it("passes context.Context and RequestInfo to handlers", () => {
+    const files = generateFiles(createContract());
+
+    const contextGo = files.get("context.go") ?? "";
+    expect(contextGo).toContain("type RequestInfo struct");
+    expect(contextGo).toContain(
+      "func WithUserID(ctx context.Context, userID string) context.Context",
+    );
+    expect(contextGo).toContain(
+      "func UserIDFrom(ctx context.Context) (string, bool)",
+    );
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).not.toContain("map[string]interface{}");
+    expect(typesGo).toContain(
+      "type GreetingGreetHandler func(ctx context.Context, info RequestInfo, input GreetingGreetInput) (GreetingGreetOutput, error)",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "ctx := r.withDependencies(WithRequestInfo(req.Context(), info))",
+    );
+    expect(files.get("methods.go")).toContain(
+      "return loadHandler(r, &r.greetingGreet)(ctx, info, input.(GreetingGreetInput))",
+    );
+  });
+
+  it("dispatches calls through a table of method descriptors", () => {
+    const files = generateFiles(createContract());
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain("type methodDescriptor struct {");
+    expect(methodsGo).toContain('name: "greeting.greet",');
+    expect(methodsGo).toContain(
+      "return ValidateGreetingGreetInput(input.(GreetingGreetInput))",
+    );
+    expect(methodsGo).toContain(
+      "var methodTable = indexMethods(methodDescriptors)",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("m, ok := methodTable[method]");
+    expect(routerGo).not.toContain('case "greeting.greet":');
+  });
+
+  it("mounts routers of other contracts under a method prefix", () => {
+    const files = generateFiles(createContract());
+
+    const mountGo = files.get("mount.go") ?? "";
+    expect(mountGo).toContain("type Mountable interface {");
+    expect(mountGo).toContain(
+      "func (r *Router) Mount(prefix string, router Mountable) *Router {",
+    );
+    expect(mountGo).toContain(
+      "func (r *Router) ServeCall(w http.ResponseWriter, req *http.Request, method string, params json.RawMessage) string {",
+    );
+    expect(mountGo).toContain(
+      'panic("mount prefix " + strings.TrimSuffix(prefix, ".") + " hides method " + m.name)',
+    );
+
+    // Mounted calls run this router's middleware before being handed over
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "return Outcome(sub.ServeCall(w, req.WithContext(ctx), name, params))",
+    );
+    expect(routerGo).toContain("sub.DispatchCall(ctx, name, params)");
+    expect(files.get("introspect.go")).toContain(
+      "methods = append(methods, mountedMethods(entry)...)",
+    );
+  });
+
+  it("serves routers under a URL path prefix", () => {
+    const contract = createContract();
+    const mountGo = generateFiles(contract).get("mount.go") ?? "";
+    expect(mountGo).toContain(
+      "func (r *Router) Handler(prefix string, middleware ...func(http.Handler) http.Handler) http.Handler {",
+    );
+    expect(mountGo).toContain(
+      "func (r *Router) MountHTTP(mux *http.ServeMux, prefix string, middleware ...func(http.Handler) http.Handler) {",
+    );
+    expect(mountGo).toContain('mux.Handle(prefix+"/", handler)');
+    expect(mountGo).toContain(
+      'r.writeError(w, Errorf(CodeNotFound, "Route not found: %s %s", req.Method, req.URL.Path))',
+    );
+    expect(mountGo).not.toContain("RESTHandler()");
+
+    // REST routes are served below the prefix
+    contract.endpoints[0].http = {
+      method: "POST",
+      path: "/greetings",
+      pathParams: [],
+    };
+    const restMountGo = generateFiles(contract).get("mount.go") ?? "";
+    expect(restMountGo).toContain("rest := r.RESTHandler()");
+    expect(restMountGo).toContain("rest.ServeHTTP(w, req)");
+  });
+
+  it("serves over mutual TLS with client certificate identities", () => {
+    const tlsGo = generateFiles(createContract()).get("tls.go") ?? "";
+    expect(tlsGo).toContain(
+      "func ListenAndServeTLS(addr string, handler http.Handler, options TLSOptions) error {",
+    );
+    expect(tlsGo).toContain("config.ClientAuth = tls.RequireAndVerifyClientCert");
+    expect(tlsGo).toContain("config.ClientAuth = tls.VerifyClientCertIfGiven");
+    expect(tlsGo).toContain(
+      "func ClientIdentityFrom(ctx context.Context) (ClientIdentity, bool) {",
+    );
+    expect(tlsGo).toContain('principal.Scheme = "mtls"');
+  });
+
+  it("injects typed dependencies into calls", () => {
+    const files = generateFiles(createContract());
+
+    const injectGo = files.get("inject.go") ?? "";
+    expect(injectGo).toContain(
+      "func Provide[T any](r *Router, provider func(ctx context.Context) *T) *Router {",
+    );
+    expect(injectGo).toContain("func MustGet[T any](ctx context.Context) *T {");
+    expect(injectGo).toContain(
+      "func WithDependency[T any](ctx context.Context, value *T) context.Context {",
+    );
+    expect(injectGo).toContain(
+      "dep.once.Do(func() { dep.value = provider(ctx) })",
+    );
+    expect(files.get("router.go")).toContain(
+      "ctx := r.withDependencies(WithRequestInfo(req.Context(), info))",
+    );
+    expect(files.get("dispatch.go")).toContain(
+      "ctx = r.withDependencies(WithRequestInfo(ctx, info))",
+    );
+  });
+
+  it("replaces handlers while serving behind a read-write lock", () => {
+    const files = generateFiles(createContract());
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain(
+      "func (r *Router) Replace(method string, handler interface{}) error {",
+    );
+    expect(methodsGo).toContain("case GreetingGreetHandler:");
+    expect(methodsGo).toContain(
+      "case func(ctx context.Context, info RequestInfo, input GreetingGreetInput) (GreetingGreetOutput, error):",
+    );
+    expect(methodsGo).toContain(
+      "return loadHandler(r, &r.greetingGreet) != nil",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("handlersMu sync.RWMutex");
+    expect(routerGo).toContain(
+      "r.handlersMu.Lock()\n\tr.greetingGreet = handler\n\tr.handlersMu.Unlock()",
+    );
+  });
+
+  it("streams subscription endpoints over Server-Sent Events", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "watch",
+      type: "subscription",
+      fullName: "greeting.watch",
+    });
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain(
+      "type GreetingWatchHandler func(ctx context.Context, info RequestInfo, input GreetingGreetInput, send func(GreetingGreetOutput) error) error",
+    );
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain('kind: "subscription",');
+    expect(methodsGo).toContain(
+      'w.Header().Set("Content-Type", "text/event-stream")',
+    );
+    expect(methodsGo).toContain(
+      "return loadHandler(r, &r.greetingWatch)(ctx, info, input.(GreetingGreetInput), func(event GreetingGreetOutput) error {",
+    );
+    expect(files.get("router.go")).toContain("func writeEvent(");
+  });
+
+  it("reports handler failures through the error envelope", () => {
+    const files = generateFiles(createContract());
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain('CodeNotFound          ErrorCode = "NOT_FOUND"');
+    expect(errorsGo).toContain("case CodeNotFound:\n\t\treturn http.StatusNotFound");
+    expect(errorsGo).toContain("func AsError(err error) *Error");
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).not.toContain("http.Error(");
+    expect(routerGo).not.toContain('"fmt"');
+    expect(routerGo).toContain("writeError(w, AsError(err))");
+  });
+
+  it("keeps the messages of foreign errors from clients", () => {
+    const files = generateFiles(createContract());
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain(
+      'return &Error{Code: CodeInternal, Message: "Internal server error", cause: err}',
+    );
+    expect(errorsGo).not.toContain("Message: err.Error()");
+    expect(files.get("router.go")).toContain(
+      'if err.cause != nil {\n\t\tr.logf("xrpc: internal error: %v", err.cause)',
+    );
+  });
+
+  it("compiles validation patterns once at package level", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties![0].validation = { regex: "^[a-z]+$" };
+    const files = generateFiles(contract);
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain(
+      'namePattern = regexp.MustCompile("^[a-z]+$")',
+    );
+    expect(validationGo).toContain("namePattern.MatchString(input.Name)");
+    expect(validationGo).not.toContain("regexp.MatchString");
+
+    const validationTest = files.get("validation_test.go") ?? "";
+    expect(validationTest).toContain("func BenchmarkNamePattern(b *testing.B)");
+  });
+
+  it("omits validation_test.go without pattern validations", () => {
+    const files = generateFiles(createContract());
+    expect(files.has("validation_test.go")).toBe(false);
+  });
+
+  it("registers handlers through typed setters without type assertions", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) GreetingGreet(handler GreetingGreetHandler) *Router",
+    );
+    expect(routerGo).toContain("greetingGreet GreetingGreetHandler");
+    expect(routerGo).not.toContain("input.(");
+
+    const typesGo = files.get("types.go") ?? "";
+    const handlerLines = typesGo
+      .split("\n")
+      .filter((line) => line.includes("Handler func("));
+    expect(handlerLines.length).toBe(1);
+    expect(handlerLines[0]).not.toContain("interface{}");
+  });
+
+  it("embeds the OpenAPI document behind Router.OpenAPISpec", () => {
+    const files = generateFiles(createContract());
+
+    const openapiGo = files.get("openapi.go") ?? "";
+    expect(openapiGo).toContain("func (r *Router) OpenAPISpec() []byte");
+    expect(openapiGo).toContain('"openapi": "3.1.0"');
+    expect(openapiGo).toContain('"$ref": "#/components/schemas/GreetingGreetRequest"');
+  });
+
+  it("answers xrpc.introspect with the registered methods", () => {
+    const files = generateFiles(createContract());
+
+    const introspectGo = files.get("introspect.go") ?? "";
+    expect(introspectGo).toContain("func (r *Router) Introspect() []MethodInfo");
+    expect(introspectGo).toContain("if methodTable[info.Name].registered(r) {");
+    expect(introspectGo).toContain(
+      'Input:  json.RawMessage(`{"type":"object","properties":{"name":{"type":"string","minLength":1,"maxLength":100}},"required":["name"]}`),',
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      'if method == "xrpc.introspect" && !r.introspectionDisabled {',
+    );
+  });
+
+  it("serves versioned methods and reports their deprecation", () => {
+    const contract = createContract();
+    contract.endpoints[0].deprecated = {
+      message: "Use greeting.greet@v2.",
+      since: "2025-06-30",
+      sunset: "2026-01-01",
+    };
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "greet@v2",
+      fullName: "greeting.greet@v2",
+      version: 2,
+      deprecated: undefined,
+    });
+    const files = generateFiles(contract);
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) GreetingGreetV2(handler GreetingGreetV2Handler) *Router {",
+    );
+    expect(routerGo).toContain('w.Header().Set("Deprecation", m.deprecation)');
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain('name: "greeting.greet@v2",');
+    expect(methodsGo).toContain('deprecation: "@1751241600",');
+    expect(methodsGo).toContain('sunset: "Thu, 01 Jan 2026 00:00:00 GMT",');
+
+    const introspectGo = files.get("introspect.go") ?? "";
+    expect(introspectGo).toContain("Version: 2,");
+    expect(introspectGo).toContain(
+      'Deprecated: &Deprecation{Message: `Use greeting.greet@v2.`, Since: "2025-06-30", Sunset: "2026-01-01"},',
+    );
+    expect(introspectGo).toContain(
+      "func (r *Router) EnableDeprecationHeaders() *Router {",
+    );
+  });
+
+  it("checks contract schemas for breaking changes", () => {
+    const files = generateFiles(createContract());
+
+    const compatGo = files.get("compat.go") ?? "";
+    expect(compatGo).toContain("func ContractSchema() []byte {");
+    expect(compatGo).toContain(
+      "func CheckCompat(oldSchema, newSchema []byte) ([]BreakingChange, error) {",
+    );
+    expect(compatGo).toContain('c.report(old.Name, "", "method removed")');
+    expect(compatGo).toContain(
+      'c.report(method, fieldPath, "adds a required field")',
+    );
+  });
+
+  it("exports a JSON Schema document for every input and output type", () => {
+    const files = generateFiles(createContract());
+
+    const schemasGo = files.get("schemas.go") ?? "";
+    expect(schemasGo).toContain(
+      '"GreetingGreetInput": json.RawMessage(`{"$schema":"https://json-schema.org/draft/2020-12/schema","title":"GreetingGreetInput","type":"object","properties":{"name":{"type":"string","minLength":1,"maxLength":100}},"required":["name"]}`),',
+    );
+    expect(schemasGo).toContain(
+      '"greeting.greet": {"GreetingGreetInput", "GreetingGreetOutput"},',
+    );
+    expect(schemasGo).toContain(
+      "func SchemaFor(method string) (input, output json.RawMessage, ok bool) {",
+    );
+    expect(schemasGo).toContain(
+      "func TypeSchema(name string) (json.RawMessage, bool) {",
+    );
+  });
+
+  it("constructs example values of every input and output type", () => {
+    const files = generateFiles(createContract());
+
+    const examplesGo = files.get("examples.go") ?? "";
+    expect(examplesGo).toContain(
+      "exampleGreetingGreetInput = `{\"name\":\"example\"}`",
+    );
+    expect(examplesGo).toContain(
+      "func ExampleGreetingGreetInput() GreetingGreetInput {",
+    );
+    expect(examplesGo).toContain(
+      "mustDecodeExample(exampleGreetingGreetOutput, &value)",
+    );
+  });
+
+  it("calls the router in process through a typed test client", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "watch",
+      type: "subscription",
+      fullName: "greeting.watch",
+    });
+    const files = generateFiles(contract);
+
+    const testClientGo = files.get("testclient.go") ?? "";
+    expect(testClientGo).toContain(
+      "func NewTestClient(router *Router) *TestClient {",
+    );
+    expect(testClientGo).toContain(
+      "func (c *TestClient) GreetingGreet(ctx context.Context, input GreetingGreetInput) (GreetingGreetOutput, error) {",
+    );
+    expect(testClientGo).toContain(
+      'err := c.call(ctx, "greeting.greet", input, &output)',
+    );
+    expect(testClientGo).toContain(
+      "func (c *TestClient) GreetingWatch(ctx context.Context, input GreetingGreetInput) ([]GreetingGreetOutput, error) {",
+    );
+    expect(testClientGo).toContain('case strings.HasPrefix(line, "data: "):');
+  });
+
+  it("calls servers over HTTP through a client retrying failed queries", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "watch",
+      type: "subscription",
+      fullName: "greeting.watch",
+    });
+    const files = generateFiles(contract);
+
+    const clientGo = files.get("client.go") ?? "";
+    expect(clientGo).toContain(
+      "func NewClient(url string, options ...ClientOption) *Client {",
+    );
+    expect(clientGo).toContain(
+      "func (c *Client) GreetingGreet(ctx context.Context, input GreetingGreetInput) (GreetingGreetOutput, error) {",
+    );
+    expect(clientGo).toContain(
+      "func (c *Client) GreetingWatch(ctx context.Context, input GreetingGreetInput, onEvent func(event GreetingGreetOutput) error) error {",
+    );
+    expect(clientGo).toContain(
+      "wait, ok := c.retry.backoff(ctx, method, err, attempt, retryAfter, time.Since(start))",
+    );
+    // Errors kept in-band with SetErrorStatus come with 200
+    expect(clientGo).toContain(
+      'if response.Error != nil {\n\t\treturn nil, retryAfter(resp.Header.Get("Retry-After")), responseError(method, data)',
+    );
+    expect(files.get("testclient.go")).toContain(
+      "if response.Error != nil {\n\t\treturn responseError(method, rec.Body.Bytes())",
+    );
+    expect(files.get("router_test.go")).toContain(
+      "func TestInBandErrors(t *testing.T) {",
+    );
+
+    const retryGo = files.get("retry.go") ?? "";
+    expect(retryGo).toContain(
+      'if m, ok := methodTable[method]; !ok || m.kind != "query" {',
+    );
+    expect(retryGo).toContain("func retryAfter(value string) time.Duration {");
+  });
+
+  it("breaks circuits and hedges queries in the client", () => {
+    const files = generateFiles(createContract());
+
+    const clientGo = files.get("client.go") ?? "";
+    expect(clientGo).toContain(
+      "if !c.breakers.allow(method) {\n\t\t\treturn ErrCircuitOpen\n\t\t}",
+    );
+    expect(clientGo).toContain(
+      "result, retryAfter, err := c.send(ctx, method, body)",
+    );
+
+    const breakerGo = files.get("breaker.go") ?? "";
+    expect(breakerGo).toContain(
+      "func WithCircuitBreaker(threshold int, cooldown time.Duration) ClientOption {",
+    );
+    expect(breakerGo).toContain("s.openUntil = time.Now().Add(b.cooldown)");
+
+    const hedgingGo = files.get("hedging.go") ?? "";
+    expect(hedgingGo).toContain(
+      "func WithHedging(delay time.Duration, methods ...string) ClientOption {",
+    );
+    expect(hedgingGo).toContain("if answer.err == nil || pending == 0 {");
+  });
+
+  it("pools the client's connections through a tunable transport", () => {
+    const files = generateFiles(createContract());
+
+    const clientGo = files.get("client.go") ?? "";
+    expect(clientGo).toContain(
+      "c.httpClient = &http.Client{Transport: newTransport(TransportOptions{})}",
+    );
+    expect(clientGo).toContain('req.Header["Idempotency-Key"] = nil');
+
+    const transportGo = files.get("transport.go") ?? "";
+    expect(transportGo).toContain(
+      "func WithTransport(options TransportOptions) ClientOption {",
+    );
+    expect(transportGo).toContain("ForceAttemptHTTP2: !options.DisableHTTP2,");
+    expect(transportGo).toContain(
+      'transport.TLSClientConfig.NextProtos = []string{"http/1.1"}',
+    );
+  });
+
+  it("decodes validation errors from the error envelope in the clients", () => {
+    const files = generateFiles(createContract());
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain("func (e *Error) Unwrap() error {");
+
+    const testClientGo = files.get("testclient.go") ?? "";
+    expect(testClientGo).toContain(
+      "if json.Unmarshal(response.Error.Details, &validationErrs) == nil && fieldErrors(validationErrs) {",
+    );
+    expect(testClientGo).toContain("callErr.Details = validationErrs");
+  });
+
+  it("runs the client's requests through its interceptors", () => {
+    const files = generateFiles(createContract());
+
+    const clientGo = files.get("client.go") ?? "";
+    expect(clientGo).toContain(
+      "type ClientInterceptorFunc func(ctx context.Context, method string, req *http.Request, next ClientNextFunc) (*http.Response, error)",
+    );
+    expect(clientGo).toContain(
+      "func WithInterceptor(interceptor ClientInterceptorFunc) ClientOption {",
+    );
+    expect(clientGo).toContain("return interceptor(ctx, method, req, inner)");
+  });
+
+  it("propagates the client's deadline to the server", () => {
+    const files = generateFiles(createContract());
+
+    const clientGo = files.get("client.go") ?? "";
+    expect(clientGo).toContain("if deadline, ok := ctx.Deadline(); ok {");
+    expect(clientGo).toContain(
+      'req.Header.Set("X-Xrpc-Timeout", strconv.FormatInt(int64(left/time.Millisecond), 10))',
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func clientTimeout(req *http.Request) (time.Duration, bool) {",
+    );
+    expect(routerGo).toContain("if timeout, ok := clientTimeout(req); ok {");
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain("if errors.Is(err, context.DeadlineExceeded) {");
+  });
+
+  it("generates a CLI with a flag per input field", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties!.push(
+      {
+        name: "dryRun",
+        required: false,
+        type: { kind: "primitive", baseType: "boolean" },
+      },
+      {
+        name: "tags",
+        required: false,
+        type: {
+          kind: "array",
+          elementType: { kind: "primitive", baseType: "string" },
+        },
+      },
+      {
+        name: "url",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+      },
+    );
+    const files = generateFiles(contract);
+
+    const cliGo = files.get("cli.go") ?? "";
+    expect(cliGo).toContain('method: "greeting.greet",');
+    expect(cliGo).toContain('{"name", "name", "string", "string (required)"},');
+    expect(cliGo).toContain('{"dry-run", "dryRun", "bool", "boolean"},');
+    expect(cliGo).toContain(
+      '{"tags", "tags", "strings", "string item, repeatable"},',
+    );
+    expect(cliGo).toContain(
+      '{"input-url", "url", "string", "string (required)"},',
+    );
+    expect(cliGo).toContain("return input, m.validate(input)");
+    expect(cliGo).not.toContain("client.subscribe(");
+  });
+
+  it("serves a playground page listing methods from introspection", () => {
+    const files = generateFiles(createContract());
+
+    const playgroundGo = files.get("playground.go") ?? "";
+    expect(playgroundGo).toContain("func (r *Router) Playground() http.Handler {");
+    expect(playgroundGo).toContain('post("xrpc.introspect", {})');
+    expect(playgroundGo).toContain("r.ServeHTTP(w, req)");
+    expect(playgroundGo).not.toContain("innerHTML");
+  });
+
+  it("generates curl and HTTPie snippets with example inputs", () => {
+    const files = generateFiles(createContract());
+
+    const snippetsGo = files.get("snippets.go") ?? "";
+    expect(snippetsGo).toContain('{"greeting.greet", `{"name":"example"}`},');
+    expect(snippetsGo).toContain(
+      "func Snippets(url string, header http.Header) []Snippet {",
+    );
+    expect(snippetsGo).toContain('curl.WriteString(" -N")');
+    expect(files.get("playground.go")).toContain("Show curl");
+  });
+
+  it("adds the struct tags declared with goTags after the json tag", () => {
+    const contract = createContract();
+    const name = contract.endpoints[0].input.properties![0];
+    name.goTags = { db: "name", validate: "required,max=100" };
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const files = generateFiles(contract);
+
+    expect(files.get("types.go")).toContain(
+      'Name string `json:"name" db:"name" validate:"required,max=100"`',
+    );
+  });
+
+  it("redacts sensitive fields in logs and validation errors", () => {
+    expect(generateFiles(createContract()).has("redact.go")).toBe(false);
+
+    const contract = createContract();
+    const name = contract.endpoints[0].input.properties![0];
+    name.sensitive = true;
+    name.validation = { ...name.validation, custom: "knownName" };
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const files = generateFiles(contract);
+
+    const redactGo = files.get("redact.go") ?? "";
+    expect(redactGo).toContain(
+      "func (v GreetingGreetInput) Redacted() GreetingGreetInput {",
+    );
+    expect(redactGo).toContain(
+      '\tif v.Name != "" {\n\t\tv.Name = RedactedText\n\t}',
+    );
+    expect(redactGo).toContain("\tcase *GreetingGreetInput:");
+    expect(redactGo).not.toContain("GreetingGreetOutput");
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain('Message: "is invalid",');
+    expect(validationGo).not.toContain("Message: err.Error(),");
+  });
+
+  it("applies the defaults of absent input fields", () => {
+    expect(generateFiles(createContract()).has("defaults.go")).toBe(false);
+
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    const optional = (baseType: TypeReference): TypeReference => ({
+      kind: "optional",
+      baseType,
+    });
+    input.properties?.push(
+      {
+        name: "limit",
+        required: false,
+        type: optional({ kind: "primitive", baseType: "number" }),
+        validation: { int: true, min: 1 },
+        default: 20,
+      },
+      {
+        name: "ratio",
+        required: false,
+        type: optional({ kind: "primitive", baseType: "number" }),
+        default: 1,
+      },
+      {
+        name: "sort",
+        required: false,
+        type: optional({ kind: "enum", name: "Sort", enumValues: ["asc"] }),
+        default: "asc",
+      },
+      {
+        // Times are not written as literals
+        name: "since",
+        required: false,
+        type: optional({ kind: "primitive", baseType: "string" }),
+        validation: { datetime: true },
+        default: "2024-01-01T00:00:00Z",
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const defaultsGo = files.get("defaults.go") ?? "";
+    expect(defaultsGo).toContain(
+      "// ApplyDefaults sets Limit, Ratio and Sort when they are absent.",
+    );
+    expect(defaultsGo).toContain(
+      "if v.Limit == nil {\n\t\tvalue := 20\n\t\tv.Limit = &value\n\t}",
+    );
+    expect(defaultsGo).toContain("value := float64(1)");
+    expect(defaultsGo).toContain('value := Sort("asc")');
+    expect(defaultsGo).not.toContain("Since");
+    expect(files.get("methods.go")).toContain(
+      "return input, err\n\t\t\t}\n\t\t\tinput.ApplyDefaults()\n\t\t\treturn input, nil",
+    );
+  });
+
+  it("checks conditional requiredness naming both fields", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "status",
+        required: true,
+        type: { kind: "enum", enumValues: ["pending", "completed"] },
+      },
+      {
+        name: "notify",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "boolean" },
+        },
+      },
+      {
+        name: "email",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "string" },
+        },
+        validation: { requiredIf: { field: "notify", equals: true } },
+      },
+      {
+        name: "completedAt",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "string" },
+        },
+        validation: {
+          forbiddenIf: { field: "status", notEquals: "completed" },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const validationGo = generateFiles(contract).get("validation.go") ?? "";
+
+    expect(validationGo).toContain(
+      "// email is required when notify is true\n\tif input.Email == nil && input.Notify != nil && *input.Notify {",
+    );
+    expect(validationGo).toContain(
+      'if input.CompletedAt != nil && input.Status != "completed" {',
+    );
+    expect(validationGo).toContain(
+      'Field:   "completedAt",\n\t\t\tPath:    []PathSegment{{Key: "completedAt"}},\n\t\t\tMessage: "must not be set unless status is completed",',
+    );
+    // The conditions are not checks of the value itself
+    expect(validationGo).not.toContain("Validate email when present");
+  });
+
+  it("dereferences set pointers to validate their values", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    const number = (validation: ValidationRules): TypeReference => ({
+      kind: "primitive",
+      baseType: "number",
+      validation,
+    });
+    input.properties?.push(
+      {
+        name: "limit",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "nullable", baseType: number({ min: 1 }) },
+        },
+        validation: { min: 1 },
+      },
+      {
+        name: "scores",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: {
+            kind: "array",
+            elementType: { kind: "nullable", baseType: number({ min: 1 }) },
+          },
+        },
+      },
+      {
+        name: "dueDates",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: {
+            kind: "array",
+            elementType: {
+              kind: "nullable",
+              baseType: {
+                kind: "primitive",
+                baseType: "string",
+                validation: { date: true, future: true },
+              },
+            },
+          },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const validationGo = generateFiles(contract).get("validation.go") ?? "";
+
+    expect(validationGo).toContain(
+      "// Validate limit when present\n\tif input.Limit != nil {\n\t\tif *input.Limit < 1 {",
+    );
+    expect(validationGo).toContain(
+      "for i, item := range input.Scores {\n\t\tif item == nil { continue }\n\t\tif *item < 1 {",
+    );
+    // Dates of items are strings, so no time is compared and imported
+    expect(validationGo).not.toContain('"time"');
+  });
+
+  it("reports the paths of errors in nested values", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    const contact: TypeReference = {
+      kind: "object",
+      name: "Contact",
+      properties: [
+        {
+          name: "email",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { email: true },
+        },
+      ],
+    };
+    input.properties?.push(
+      { name: "owner", required: true, type: contact },
+      {
+        name: "teams",
+        required: true,
+        type: {
+          kind: "array",
+          elementType: {
+            kind: "record",
+            keyType: { kind: "primitive", baseType: "string" },
+            valueType: contact,
+          },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    contract.types.push({ ...contact, name: "Contact" });
+    const validationGo = generateFiles(contract).get("validation.go") ?? "";
+
+    expect(validationGo).toContain(
+      'Path    []PathSegment `json:"path,omitempty"`',
+    );
+    expect(validationGo).toContain(
+      "func (e *ValidationError) JSONPointer() string {",
+    );
+    expect(validationGo).toContain('Path:    []PathSegment{{Key: "email"}},');
+    // Errors of nested objects are prefixed with the field holding them
+    expect(validationGo).toContain(
+      'Field:   fmt.Sprintf("owner.%s", nestedErr.Field),\n\t\t\t\t\tPath:    append([]PathSegment{{Key: "owner"}}, nestedErr.Path...),',
+    );
+    expect(validationGo).not.toContain("errs = append(errs, nestedErrs...)");
+    expect(validationGo).toContain(
+      'Path:    append([]PathSegment{{Key: "teams"}, {Index: i, IsIndex: true}, {Key: key1}}, nestedErr.Path...),',
+    );
+  });
+
+  it("normalizes decoded inputs before validating them", () => {
+    expect(generateFiles(createContract()).has("normalize.go")).toBe(false);
+
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    const contact: TypeReference = {
+      kind: "object",
+      name: "Contact",
+      properties: [
+        {
+          name: "email",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { email: true, normalize: ["trim", "lowercase"] },
+        },
+      ],
+    };
+    input.properties![0].validation = {
+      ...input.properties![0].validation,
+      normalize: ["collapseSpaces"],
+    };
+    input.properties?.push(
+      {
+        name: "tags",
+        required: true,
+        type: {
+          kind: "array",
+          elementType: {
+            kind: "primitive",
+            baseType: "string",
+            validation: { normalize: ["uppercase"] },
+          },
+        },
+      },
+      {
+        name: "contacts",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: {
+            kind: "record",
+            keyType: { kind: "primitive", baseType: "string" },
+            valueType: contact,
+          },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    contract.types.push({ ...contact, name: "Contact" });
+    const files = generateFiles(contract);
+
+    const normalizeGo = files.get("normalize.go") ?? "";
+    expect(normalizeGo).toContain(
+      "// Normalize normalizes Name, Tags and the strings of its nested values in\n// place.",
+    );
+    expect(normalizeGo).toContain("func (v *GreetingGreetInput) Normalize() {");
+    expect(normalizeGo).toContain(
+      'v.Name = strings.Join(strings.Fields(v.Name), " ")',
+    );
+    expect(normalizeGo).toContain(
+      "for i := range v.Tags {\n\t\tv.Tags[i] = strings.ToUpper(v.Tags[i])\n\t}",
+    );
+    expect(normalizeGo).toContain(
+      "for k, e := range v.Contacts {\n\t\te.Normalize()\n\t\tv.Contacts[k] = e\n\t}",
+    );
+    expect(normalizeGo).toContain(
+      "v.Email = strings.ToLower(strings.TrimSpace(v.Email))",
+    );
+    expect(normalizeGo).not.toContain("GreetingGreetOutput");
+    expect(files.get("methods.go")).toContain(
+      "if err := r.unmarshal(params, &input); err != nil {\n\t\t\t\treturn input, err\n\t\t\t}\n\t\t\tinput.Normalize()\n\t\t\treturn input, nil",
+    );
+    // Items with nothing but normalizations have nothing to validate
+    expect(files.get("validation.go")).not.toContain("range input.Tags");
+  });
+
+  it("reports soft limits as warnings next to results", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    const contact: TypeReference = {
+      kind: "object",
+      name: "Contact",
+      properties: [
+        {
+          name: "note",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { warn: { maxLength: 80 } },
+        },
+      ],
+    };
+    input.properties?.push(
+      {
+        name: "title",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+        validation: {
+          maxLength: 200,
+          warn: { maxLength: 120, message: "will be truncated in the UI" },
+        },
+      },
+      {
+        name: "contacts",
+        required: true,
+        type: { kind: "array", elementType: contact },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    contract.types.push({ ...contact, name: "Contact" });
+    const files = generateFiles(contract);
+
+    const warningsGo = files.get("warnings.go") ?? "";
+    expect(warningsGo).toContain(
+      "func (v GreetingGreetInput) Warnings() ValidationErrors {",
+    );
+    expect(warningsGo).toContain(
+      'if utf8.RuneCountInString(v.Title) > 120 {\n\t\twarnings = append(warnings, &ValidationError{\n\t\t\tField:   "title",\n\t\t\tPath:    []PathSegment{{Key: "title"}},\n\t\t\tMessage: "will be truncated in the UI",',
+    );
+    expect(warningsGo).toContain(
+      'Message: "should be at most 80 character(s)",',
+    );
+    expect(warningsGo).toContain(
+      'nestWarnings(e.Warnings(), fmt.Sprintf("contacts[%d]", i), PathSegment{Key: "contacts"}, PathSegment{Index: i, IsIndex: true})',
+    );
+    // Soft limits do not reject values
+    expect(files.get("validation.go")).not.toContain("120");
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain("return input.(GreetingGreetInput).Warnings()");
+    expect(methodsGo).toContain(
+      "if m.warn != nil {\n\t\taddWarnings(ctx, m.warn(input))\n\t}",
+    );
+    expect(files.get("router.go")).toContain(
+      "if warnings := WarningsFrom(ctx); len(warnings) > 0 {\n\t\tbuf.appendWarnings(warnings)\n\t}",
+    );
+    expect(files.get("dispatch.go")).toContain(
+      "return r.encodeReply(result, err, WarningsFrom(ctx))",
+    );
+
+    const plain = generateFiles(createContract());
+    expect(plain.has("warnings.go")).toBe(false);
+    expect(plain.get("methods.go")).not.toContain("warn");
+  });
+
+  it("coerces numbers and strings of params with lenient decoding", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "limit",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "number" },
+        },
+        validation: { int: true },
+      },
+      {
+        name: "scores",
+        required: true,
+        type: {
+          kind: "array",
+          elementType: { kind: "primitive", baseType: "number" },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const coercionGo = files.get("coercion.go") ?? "";
+    expect(coercionGo).toContain(
+      "func (r *Router) EnableLenientDecoding() *Router {",
+    );
+    expect(coercionGo).toContain(
+      'v["name"] = coerceString(value, pathTo(path, PathSegment{Key: "name"}), errs)',
+    );
+    expect(coercionGo).toContain(
+      'v["limit"] = coerceInteger(value, pathTo(path, PathSegment{Key: "limit"}), errs)',
+    );
+    expect(coercionGo).toContain(
+      'items[i] = coerceNumber(item, pathTo(pathTo(path, PathSegment{Key: "scores"}), PathSegment{Index: i, IsIndex: true}), errs)',
+    );
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain("coerce: coerceGreetingGreetInput,");
+    expect(methodsGo).toContain(
+      "if r.lenientDecoding && m.coerce != nil {\n\t\tcoerced, err := coerceParams(params, m.coerce)",
+    );
+  });
+
+  it("reports params that fail to decode with their field and type", () => {
+    const files = generateFiles(createContract());
+
+    const codecGo = files.get("codec.go") ?? "";
+    expect(codecGo).toContain(
+      "func paramsError(params []byte, err error) error {",
+    );
+    expect(codecGo).toContain(
+      'Message: fmt.Sprintf("must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value),',
+    );
+    expect(codecGo).toContain(
+      "func pathAt(data []byte, offset int64) []PathSegment {",
+    );
+    // Params of the wrong type themselves are reported without a path
+    expect(codecGo).toContain(
+      "path := pathAt(params, typeErr.Offset)\n\t\treturn ValidationErrors{{",
+    );
+    expect(files.get("router_test.go")).toContain(
+      '{"params", `[]`, "", "must be an object, got array"},',
+    );
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain(
+      "return nil, OutcomeValidationError, paramsError(params, err)",
+    );
+    expect(methodsGo).not.toContain("Invalid params");
+  });
+
+  it("embeds the schema hash clients compare theirs with", () => {
+    const contract = createContract();
+    const files = generateFiles(contract);
+
+    const introspectGo = files.get("introspect.go") ?? "";
+    expect(introspectGo).toContain(
+      `const SchemaHash = "${schemaHash(contract)}"`,
+    );
+    expect(schemaHash(contract)).toMatch(/^[0-9a-f]{16}$/);
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      'if hash := req.Header.Get("X-Xrpc-Schema-Hash"); hash != "" && hash != SchemaHash {\n\t\tw.Header().Set("X-Xrpc-Schema-Mismatch", SchemaHash)',
+    );
+    expect(routerGo).toContain(
+      '"methods": r.Introspect(), "schemaHash": SchemaHash}',
+    );
+
+    // Descriptions are documentation, not part of the schema
+    contract.endpoints[0].input.properties![0].description = "Who to greet.";
+    expect(schemaHash(contract)).toBe(
+      introspectGo.match(/SchemaHash = "(\w+)"/)?.[1] ?? "",
+    );
+    contract.endpoints[0].input.properties?.push({
+      name: "nickname",
+      required: true,
+      type: { kind: "primitive", baseType: "string" },
+    });
+    expect(schemaHash(contract)).not.toBe(
+      introspectGo.match(/SchemaHash = "(\w+)"/)?.[1] ?? "",
+    );
+  });
+
+  it("suggests the closest method to callers of an unknown one", () => {
+    const files = generateFiles(createContract());
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain(
+      'CodeMethodNotFound    ErrorCode = "METHOD_NOT_FOUND"',
+    );
+    expect(errorsGo).toContain(
+      "case CodeMethodNotFound:\n\t\treturn http.StatusNotFound",
+    );
+
+    const introspectGo = files.get("introspect.go") ?? "";
+    expect(introspectGo).toContain(
+      "func (r *Router) methodNotFound(method string) *Error {",
+    );
+    expect(introspectGo).toContain(
+      "if d := editDistance(method, info.Name); d < best {",
+    );
+    expect(introspectGo).toContain("func editDistance(a, b string) int {");
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "return nil, OutcomeRejected, r.methodNotFound(method)",
+    );
+  });
+
+  it("turns schema descriptions into doc comments", () => {
+    const contract = createContract();
+    const endpoint = contract.endpoints[0];
+    endpoint.description = "Greets the caller by name.";
+    endpoint.input.properties![0].description = "Name of the caller.";
+    contract.types[0].description = "holds who to greet.";
+    contract.types[0].properties = endpoint.input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain(
+      "// GreetingGreetInput holds who to greet.\ntype GreetingGreetInput struct {",
+    );
+    expect(typesGo).toContain(
+      "\t// Name of the caller.\n\tName string `json:\"name\"`",
+    );
+    expect(typesGo).toContain(
+      "// Handler type for greeting.greet\n//\n// Greets the caller by name.\n",
+    );
+    expect(files.get("router.go")).toContain(
+      "// GreetingGreet sets the handler of greeting.greet.\n//\n// Greets the caller by name.\n",
+    );
+  });
+
+  it("carries typed values on contexts under Key[T]", () => {
+    const contextGo = generateFiles(createContract()).get("context.go") ?? "";
+    expect(contextGo).toContain("type Key[T any] struct {\n\tname string\n}");
+    expect(contextGo).toContain(
+      "func NewKey[T any](name string) *Key[T] {\n\treturn &Key[T]{name: name}\n}",
+    );
+    expect(contextGo).toContain(
+      "func (k *Key[T]) Set(ctx context.Context, value T) context.Context {\n\treturn &keyContext{Context: ctx, key: k, name: k.name, value: value}\n}",
+    );
+    expect(contextGo).toContain(
+      "func (k *Key[T]) Get(ctx context.Context) (T, bool) {\n\tvalue, ok := ctx.Value(k).(T)\n\treturn value, ok\n}",
+    );
+    // The Data map of the former Context, rebuilt from the keys set
+    expect(contextGo).toContain(
+      "// Deprecated: DataFrom is kept for one release to ease porting middleware",
+    );
+    expect(contextGo).toContain(
+      "func DataFrom(ctx context.Context) map[string]interface{} {",
+    );
+    expect(contextGo).toContain(
+      "c, _ = c.Context.Value(keyContextKey{}).(*keyContext)",
+    );
+  });
+
+  it("generates a service interface per namespace to register at once", () => {
+    const files = generateFiles(createContract());
+
+    const servicesGo = files.get("services.go") ?? "";
+    expect(servicesGo).toContain("type GreetingService interface {");
+    expect(servicesGo).toContain(
+      "\tGreet(ctx context.Context, info RequestInfo, input GreetingGreetInput) (GreetingGreetOutput, error)",
+    );
+    expect(servicesGo).toContain(
+      "func RegisterGreetingService(r *Router, impl GreetingService) {\n\tr.GreetingGreet(impl.Greet)\n}",
+    );
+  });
+
+  it("generates handler constructors bound to a dependency struct", () => {
+    const files = generateFiles(createContract());
+
+    const servicesGo = files.get("services.go") ?? "";
+    expect(servicesGo).toContain(
+      "type GreetingHandlerFuncs[D any] struct {\n\tGreet func(ctx context.Context, deps D, info RequestInfo, input GreetingGreetInput) (GreetingGreetOutput, error)\n}",
+    );
+    expect(servicesGo).toContain(
+      "func NewGreetingHandlers[D any](deps D, funcs GreetingHandlerFuncs[D]) GreetingService {",
+    );
+    expect(servicesGo).toContain(
+      'panic("xrpc: NewGreetingHandlers: no Greet function for greeting.greet")',
+    );
+    expect(servicesGo).toContain(
+      "func (h boundGreetingHandlers[D]) Greet(ctx context.Context, info RequestInfo, input GreetingGreetInput) (GreetingGreetOutput, error) {\n\treturn h.funcs.Greet(ctx, h.deps, info, input)\n}",
+    );
+
+    const contract = createContract();
+    contract.endpoints[0].output.properties?.push({
+      name: "lines",
+      required: true,
+      type: {
+        kind: "array",
+        elementType: { kind: "primitive", baseType: "string" },
+      },
+    });
+    contract.endpoints[0].stream = "lines";
+    expect(generateFiles(contract).get("services.go")).toContain(
+      "return h.funcs.Greet(ctx, h.deps, info, input, stream)",
+    );
+  });
+
+  it("switches methods off with Disable and a MethodGate", () => {
+    const files = generateFiles(createContract());
+
+    const gatesGo = files.get("gates.go") ?? "";
+    expect(gatesGo).toContain("type MethodGate interface {");
+    expect(gatesGo).toContain(
+      "func (r *Router) Disable(pattern string) *Router {",
+    );
+    expect(gatesGo).toContain("return NewError(CodeUnavailable, reason)");
+    expect(files.get("methods.go")).toContain(
+      "if err := r.checkGate(ctx, info, m); err != nil {\n\t\treturn nil, OutcomeRejected, err\n\t}\n\tif !m.registered(r) {",
+    );
+  });
+
+  it("rejects calls in maintenance mode and mutations in read-only mode", () => {
+    const files = generateFiles(createContract());
+
+    const gatesGo = files.get("gates.go") ?? "";
+    expect(gatesGo).toContain(
+      "func (r *Router) SetMaintenance(on bool) *Router {",
+    );
+    expect(gatesGo).toContain(
+      "func (r *Router) SetReadOnly(on bool) *Router {",
+    );
+    expect(gatesGo).toContain('if r.readOnly.Load() && m.kind == "mutation" {');
+    expect(files.get("router.go")).toContain("maintenance atomic.Bool");
+  });
+
+  it("publishes the events of mutations after they succeed", () => {
+    const contract = createContract();
+    contract.endpoints[0].type = "mutation";
+    contract.endpoints[0].event = "greeting.sent";
+    const files = generateFiles(contract);
+
+    const eventsGo = files.get("events.go") ?? "";
+    expect(eventsGo).toContain('EventGreetingSent = "greeting.sent"');
+    expect(eventsGo).toContain("type GreetingSentEvent GreetingGreetOutput");
+    expect(eventsGo).toContain(
+      "func WebhookPublisher(url string, secret []byte, client *http.Client) EventPublisher {",
+    );
+    expect(files.get("methods.go")).toContain(
+      "return Event{Name: EventGreetingSent, Payload: GreetingSentEvent(result.(GreetingGreetOutput))}",
+    );
+    expect(files.get("router.go")).toContain(
+      "if m.event != nil {\n\t\tr.publish(ctx, info, m.event(result))\n\t}",
+    );
+  });
+
+  it("stores events in a transactional outbox and relays them", () => {
+    const files = generateFiles(createContract());
+
+    const outboxGo = files.get("outbox.go") ?? "";
+    expect(outboxGo).toContain(
+      "func NewOutbox(db *sql.DB, options OutboxOptions) *Outbox {",
+    );
+    expect(outboxGo).toContain(
+      "func (o *Outbox) Add(ctx context.Context, tx *sql.Tx, event Event) error {",
+    );
+    expect(outboxGo).toContain(
+      "func (o *Outbox) Relay(ctx context.Context, publisher EventPublisher, interval time.Duration) {",
+    );
+  });
+
+  it("runs async mutations in the background as operations", () => {
+    const contract = createContract();
+    contract.endpoints[0].type = "mutation";
+    contract.endpoints[0].async = true;
+    const files = generateFiles(contract);
+
+    expect(files.get("methods.go")).toContain("async: true,");
+    expect(files.get("router.go")).toContain(
+      "if m.async {\n\t\top, err := r.startOperation(ctx, info, m, input)",
+    );
+    expect(files.get("router.go")).toContain(
+      'if method == "operation.get" || method == "operation.cancel" {',
+    );
+    expect(files.get("operations.go")).toContain(
+      "func (r *Router) SetOperationStore(store OperationStore) *Router {",
+    );
+    expect(files.get("client.go")).toContain(
+      "func (c *Client) GreetingGreet(ctx context.Context, input GreetingGreetInput) (Operation, error) {",
+    );
+    expect(files.get("client.go")).toContain(
+      "func (c *Client) GetOperation(ctx context.Context, id string) (Operation, error) {",
+    );
+    expect(files.get("router_test.go")).toContain("var output Operation");
+  });
+
+  it("reserves the operation methods when a mutation is async", () => {
+    const contract = createContract();
+    contract.endpoints[0].type = "mutation";
+    contract.endpoints[0].async = true;
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "get",
+      fullName: "operation.get",
+      async: undefined,
+    });
+
+    const output = goTarget.generate({ contract, outputDir: "out" });
+    expect(output.files).toEqual([]);
+    expect(
+      output.diagnostics?.some(
+        (issue) =>
+          issue.severity === "error" &&
+          issue.message.includes('"operation.get" is reserved'),
+      ),
+    ).toBe(true);
+  });
+
+  it("serves the API from programmable stubs with MockServer", () => {
+    const files = generateFiles(createContract());
+
+    const mockGo = files.get("mock.go") ?? "";
+    expect(mockGo).toContain("GreetingGreet *GreetingGreetStub");
+    expect(mockGo).toContain("return ExampleGreetingGreetOutput(), nil");
+    expect(mockGo).toContain("GreetingGreet(m.GreetingGreet.handle)");
+    expect(mockGo).toContain(
+      "func (s *GreetingGreetStub) Returns(output GreetingGreetOutput, err error) *GreetingGreetStub {",
+    );
+    expect(mockGo).toContain(
+      'return output, NewError(CodeUnimplemented, "greeting.greet is not stubbed")',
+    );
+  });
+
+  it("tests the router over HTTP with examples derived from the schemas", () => {
+    const files = generateFiles(createContract());
+
+    const routerTestGo = files.get("router_test.go") ?? "";
+    expect(routerTestGo).toContain("return ExampleGreetingGreetOutput(), nil");
+    expect(routerTestGo).toContain("func TestRouterEnvelope(t *testing.T) {");
+    expect(routerTestGo).toContain("func TestRouterValidation(t *testing.T) {");
+    expect(routerTestGo).toContain('{"greeting.greet", `{}`, "name"},');
+    expect(routerTestGo).toContain("func TestRouterRoundTrip(t *testing.T) {");
+    expect(routerTestGo).toContain(
+      'req:    post(`{"method":"greeting.greet","params":` + exampleGreetingGreetInput + `}`),',
+    );
+  });
+
+  it("rejects contracts that define the reserved introspection method", () => {
+    const contract = createContract();
+    contract.endpoints[0].fullName = "xrpc.introspect";
+
+    const output = goTarget.generate({ contract, outputDir: "out" });
+    expect(output.files).toEqual([]);
+    expect(
+      output.diagnostics?.some((issue) => issue.severity === "error"),
+    ).toBe(true);
+  });
+
+  it("times out query and mutation calls with DEADLINE_EXCEEDED", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) SetTimeoutFor(pattern string, timeout time.Duration) *Router {",
+    );
+    expect(routerGo).toContain(
+      "return r.invokeWithTimeout(ctx, info, timeout, next)",
+    );
+    expect(routerGo).toContain(
+      'return nil, NewError(CodeDeadlineExceeded, "Deadline exceeded")',
+    );
+    expect(routerGo).toContain(
+      "info.ResponseWriter = &timeoutWriter{ResponseWriter: w}",
+    );
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain(
+      "case CodeDeadlineExceeded:\n\t\treturn http.StatusGatewayTimeout",
+    );
+  });
+
+  it("rejects unauthenticated calls of methods that require auth", () => {
+    const contract = createContract();
+    contract.endpoints[0].auth = "required";
+    const files = generateFiles(contract);
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain("auth: true,");
+    expect(methodsGo).toContain("if _, ok := UserIDFrom(ctx); !ok {");
+    expect(methodsGo).toContain(
+      'return nil, OutcomeRejected, NewError(CodeUnauthorized, "Authentication required")',
+    );
+
+    const authGo = files.get("auth.go") ?? "";
+    expect(authGo).toContain(
+      "func JWTAuth(keys JWTKeySet, claims func(claims map[string]interface{}) (Principal, error)) MiddlewareFunc {",
+    );
+    expect(authGo).toContain(
+      "func APIKeyAuth(lookup func(ctx context.Context, key string) (Principal, error)) MiddlewareFunc {",
+    );
+    // In-process calls have no request to read credentials from, so they
+    // continue unauthenticated like requests without any
+    expect(authGo).toContain(
+      'if info.Request == nil {\n\t\t\treturn NewMiddlewareResult(ctx)\n\t\t}\n\t\tkey := info.Request.Header.Get("X-API-Key")',
+    );
+
+    const routerTestGo = files.get("router_test.go") ?? "";
+    expect(routerTestGo).toContain(
+      'return NewMiddlewareResult(WithUserID(ctx, "test-user"))',
+    );
+  });
+
+  it("leaves methods without auth requirements open", () => {
+    const files = generateFiles(createContract());
+
+    expect(files.get("methods.go")).not.toContain("auth: true,");
+  });
+
+  it("rejects calls without a tenant to methods that require one", () => {
+    const contract = createContract();
+    contract.endpoints[0].tenant = "required";
+    const files = generateFiles(contract);
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain("tenant: true,");
+    expect(methodsGo).toContain(
+      'if _, ok := TenantFrom(ctx); !ok {\n\t\t\treturn nil, OutcomeRejected, NewError(CodeInvalidArgument, "Tenant required")',
+    );
+    expect(generateFiles(createContract()).get("methods.go")).not.toContain(
+      "tenant: true,",
+    );
+
+    const tenantGo = files.get("tenant.go") ?? "";
+    expect(tenantGo).toContain(
+      "func ResolveTenant(verify func(ctx context.Context, tenant Tenant) error, resolvers ...TenantResolver) MiddlewareFunc {",
+    );
+    expect(tenantGo).toContain(
+      "func TenantFrom(ctx context.Context) (Tenant, bool) {",
+    );
+    expect(tenantGo).toContain(
+      "func TenantRateLimit(limit func(tenant Tenant) TenantLimit) MiddlewareFunc {",
+    );
+
+    expect(files.get("router.go")).toContain(
+      "if rec, ok := w.(tenantRecorder); ok {\n\t\t\t\trec.RecordTenant(tenant.ID)",
+    );
+    expect(files.get("router_test.go")).toContain(
+      'return NewMiddlewareResult(WithTenant(ctx, Tenant{ID: "test-tenant"}))',
+    );
+  });
+
+  it("checks declared permissions with the Authorizer", () => {
+    const contract = createContract();
+    contract.endpoints[0].permissions = ["greeting:read", "greeting:write"];
+    const files = generateFiles(contract);
+
+    expect(files.get("methods.go")).toContain(
+      "permissions: []string{`greeting:read`, `greeting:write`},",
+    );
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) SetAuthorizer(authorizer Authorizer) *Router",
+    );
+
+    const authGo = files.get("auth.go") ?? "";
+    expect(authGo).toContain("type Authorizer interface {");
+    expect(authGo).toContain("func ClaimAuthorizer(claim string) Authorizer {");
+
+    const routerTestGo = files.get("router_test.go") ?? "";
+    expect(routerTestGo).toContain(
+      "SetAuthorizer(AuthorizerFunc(func(context.Context, RequestInfo, []string) (bool, error) {",
+    );
+  });
+
+  it("checks CSRF tokens of POST requests once SetCSRF enables it", () => {
+    const files = generateFiles(createContract());
+
+    const csrfGo = files.get("csrf.go") ?? "";
+    expect(csrfGo).toContain(
+      "func (r *Router) SetCSRF(options CSRFOptions) *Router {",
+    );
+    expect(csrfGo).toContain(
+      "func (r *Router) IssueCSRFCookie(w http.ResponseWriter, req *http.Request) (string, error) {",
+    );
+    expect(csrfGo).toContain(
+      "subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) != 1",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("if err := r.checkCSRF(req); err != nil {");
+  });
+
+  it("dispatches calls independently of the transport", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) dispatch(ctx context.Context, info RequestInfo, method string, params json.RawMessage) (interface{}, Outcome, error) {",
+    );
+    expect(routerGo).toContain(
+      "result, stage, err := r.dispatch(ctx, info, method, params)",
+    );
+
+    const dispatchGo = files.get("dispatch.go") ?? "";
+    expect(dispatchGo).toContain("type Dispatcher interface {");
+    expect(dispatchGo).toContain(
+      "func (r *Router) Dispatch(ctx context.Context, method string, params json.RawMessage) (result interface{}, err error) {",
+    );
+    expect(dispatchGo).toContain(
+      "func (r *Router) ServeMessage(ctx context.Context, data []byte) []byte {",
+    );
+  });
+
+  it("serves liveness and readiness probes", () => {
+    const files = generateFiles(createContract());
+
+    const healthGo = files.get("health.go") ?? "";
+    expect(healthGo).toContain(
+      "func (r *Router) AddReadinessCheck(name string, check ReadinessCheck) *Router {",
+    );
+    expect(healthGo).toContain(
+      "func (r *Router) HealthHandler() http.Handler {",
+    );
+    expect(healthGo).toContain(
+      "func (r *Router) ReadinessHandler() http.Handler {",
+    );
+    expect(healthGo).toContain(
+      "func PingCheck(p interface{ PingContext(ctx context.Context) error }) ReadinessCheck {",
+    );
+  });
+
+  it("scopes middleware to method patterns with UseFor", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) UseFor(pattern string, middleware MiddlewareFunc) *Router",
+    );
+    expect(routerGo).toContain("if !matchMethod(entry.pattern, method) {");
+  });
+
+  it("wraps handler calls in the interceptor chain", () => {
+    const files = generateFiles(createContract());
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain(
+      "type InterceptorFunc func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error)",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) InterceptFor(pattern string, interceptor InterceptorFunc) *Router",
+    );
+    expect(routerGo).toContain(
+      "result, err := r.invoke(ctx, info, input, func(ctx context.Context) (interface{}, error) {",
+    );
+    expect(routerGo).toContain("return m.invoke(r, ctx, info, input)");
+  });
+
+  it("recovers handler panics as INTERNAL errors", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("rec := recover()");
+    expect(routerGo).toContain("debug.Stack()");
+    expect(routerGo).toContain(
+      'writeError(w, NewError(CodeInternal, "Internal server error"))',
+    );
+    expect(routerGo).toContain(
+      "func (r *Router) SetErrorLog(logger *log.Logger) *Router",
+    );
+  });
+
+  it("reports every call to the configured Logger", () => {
+    const files = generateFiles(createContract());
+
+    const loggingGo = files.get("logging.go") ?? "";
+    expect(loggingGo).toContain("type Logger interface {");
+    expect(loggingGo).toContain('OutcomeValidationError Outcome = "validation_error"');
+    expect(loggingGo).toContain(
+      "func (r *Router) SetLogger(logger Logger) *Router",
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("r.logger.LogRequest(req.Context(), LogEntry{");
+    expect(files.get("methods.go")).toContain(
+      "return nil, OutcomeValidationError, err",
+    );
+    expect(routerGo).toContain("return nil, OutcomeHandlerError, err");
+    expect(routerGo).toContain("return result, OutcomeSuccess, nil");
+    expect(routerGo).toContain("outcome = stage");
+  });
+
+  it("emits Prometheus metrics only when requested", () => {
+    expect(generateFiles(createContract()).has("metrics.go")).toBe(false);
+
+    const output = goTarget.generate({
+      contract: createContract(),
+      outputDir: "out",
+      options: { packageName: "server", metrics: "prometheus" },
+    });
+    const metricsGo =
+      output.files.find((file) => file.path === "metrics.go")?.content ?? "";
+    expect(metricsGo).toContain(
+      '"github.com/prometheus/client_golang/prometheus"',
+    );
+    expect(metricsGo).toContain(
+      "func NewMetrics(reg prometheus.Registerer) *Metrics",
+    );
+    expect(metricsGo).toContain("if _, ok := methodTable[method]; !ok {");
+    expect(metricsGo).toContain(
+      "func (m *Metrics) LogRequest(ctx context.Context, entry LogEntry)",
+    );
+    expect(metricsGo).toContain(
+      "func NewConcurrencyCollector(r *Router) prometheus.Collector {",
+    );
+    expect(metricsGo).toContain(
+      "m.tenants.WithLabelValues(entry.Tenant, string(entry.Outcome)).Inc()",
+    );
+  });
+
+  it("emits the gRPC bridge only when requested", () => {
+    expect(generateFiles(createContract()).has("grpc.go")).toBe(false);
+
+    const output = goTarget.generate({
+      contract: createContract(),
+      outputDir: "out",
+      options: { packageName: "server", grpc: true, grpcPackage: "greeter.v1" },
+    });
+    const grpcGo =
+      output.files.find((file) => file.path === "grpc.go")?.content ?? "";
+    expect(grpcGo).toContain('"google.golang.org/grpc"');
+    expect(grpcGo).toContain(
+      "func RegisterGRPC(server grpc.ServiceRegistrar, router *Router) error {",
+    );
+    expect(grpcGo).toContain("func IsGRPCRequest(req *http.Request) bool {");
+    expect(grpcGo).toContain('{name: "Greet", method: "greeting.greet"},');
+    expect(grpcGo).toContain('Package: proto.String("greeter.v1"),');
+    expect(grpcGo).toContain(
+      'grpcMethodDescriptor("Greet", ".greeter.v1.GreetingGreetInput", ".greeter.v1.GreetingGreetOutput", false),',
+    );
+  });
+
+  it("emits the NATS and AMQP adapters only when requested", () => {
+    const files = generateFiles(createContract());
+    expect(files.has("nats.go")).toBe(false);
+    expect(files.has("amqp.go")).toBe(false);
+
+    const output = goTarget.generate({
+      contract: createContract(),
+      outputDir: "out",
+      options: { packageName: "server", nats: true, amqp: true },
+    });
+    const natsGo =
+      output.files.find((file) => file.path === "nats.go")?.content ?? "";
+    expect(natsGo).toContain('"github.com/nats-io/nats.go"');
+    expect(natsGo).toContain(
+      "func ServeNATS(nc *nats.Conn, subject string, router *Router, options NATSOptions) (*nats.Subscription, error) {",
+    );
+    expect(natsGo).toContain(
+      "return nc.QueueSubscribe(subject, options.Queue, handler)",
+    );
+    expect(natsGo).toContain("reply := router.ServeMessage(ctx, msg.Data)");
+    expect(natsGo).toContain("if err := msg.Respond(reply); err != nil {");
+
+    const amqpGo =
+      output.files.find((file) => file.path === "amqp.go")?.content ?? "";
+    expect(amqpGo).toContain('"github.com/rabbitmq/amqp091-go"');
+    expect(amqpGo).toContain("type AMQPChannel interface {");
+    expect(amqpGo).toContain(
+      "func ServeAMQP(ctx context.Context, ch AMQPChannel, queue string, router *Router, options AMQPOptions) error {",
+    );
+    expect(amqpGo).toContain("callCtx := context.WithoutCancel(ctx)");
+    expect(amqpGo).toContain("CorrelationId: d.CorrelationId,");
+    expect(amqpGo).toContain("if err := d.Ack(false); err != nil {");
+  });
+
+  it("accepts GET for queries but not mutations", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "rename",
+      type: "mutation",
+      fullName: "greeting.rename",
+    });
+    const files = generateFiles(contract);
+    const routerGo = files.get("router.go") ?? "";
+
+    expect(routerGo).toContain("case http.MethodGet:");
+    expect(routerGo).toContain("if !r.allowsGET(request.Method) {");
+    expect(files.get("methods.go")).toContain(
+      'return ok && m.kind != "mutation"',
+    );
+    expect(routerGo).toContain(
+      'request.Params = decodeQueryParams(query.Get("params"))',
+    );
+    expect(routerGo).toContain(
+      "func decodeQueryParams(value string) json.RawMessage",
+    );
+  });
+
+  it("answers conditional GET queries with ETags and 304s", () => {
+    const contract = createContract();
+    const routerGo = generateFiles(contract).get("router.go") ?? "";
+
+    expect(routerGo).toContain(
+      "if req.Method == http.MethodGet && notModified(w, req, buf.Bytes()) {",
+    );
+    expect(routerGo).toContain('w.Header().Set("ETag", etag)');
+    expect(routerGo).toContain("w.WriteHeader(http.StatusNotModified)");
+
+    contract.endpoints[0].type = "mutation";
+    const mutationsOnly = generateFiles(contract).get("router.go") ?? "";
+    expect(mutationsOnly).not.toContain("notModified");
+    expect(mutationsOnly).not.toContain('"crypto/sha256"');
+  });
+
+  it("compresses results once SetCompression enables it", () => {
+    const files = generateFiles(createContract());
+
+    const compressionGo = files.get("compression.go") ?? "";
+    expect(compressionGo).toContain(
+      "func (r *Router) SetCompression(minSize int) *Router {",
+    );
+    expect(compressionGo).toContain(
+      "func negotiateEncoding(header string) string {",
+    );
+    expect(compressionGo).toContain(
+      '"gzip": {New: func() interface{} { return gzip.NewWriter(nil) }},',
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("r.writeResult(w, req, buf.Bytes())");
+  });
+
+  it("negotiates MessagePack and CBOR encodings", () => {
+    const files = generateFiles(createContract());
+
+    const codecGo = files.get("codec.go") ?? "";
+    expect(codecGo).toContain(
+      '"application/msgpack":   {"application/msgpack", decodeMsgpack, encodeMsgpack},',
+    );
+    expect(codecGo).toContain(
+      '"application/cbor":      {"application/cbor", decodeCBOR, encodeCBOR},',
+    );
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "if err := decodeRequest(req, &request); err != nil {",
+    );
+
+    const compressionGo = files.get("compression.go") ?? "";
+    expect(compressionGo).toContain(
+      "body, contentType := encodeResult(req, body)",
+    );
+  });
+
+  it("serves endpoints with an http mapping through RESTHandler", () => {
+    const contract = createContract();
+    contract.endpoints[0].http = {
+      method: "GET",
+      path: "/greetings/{name}",
+      pathParams: ["name"],
+    };
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "search",
+      fullName: "greeting.search",
+      http: { method: "GET", path: "/greetings/search", pathParams: [] },
+    });
+    const files = generateFiles(contract);
+
+    const restGo = files.get("rest.go") ?? "";
+    expect(restGo).toContain("func (r *Router) RESTHandler() http.Handler {");
+    expect(restGo).toContain(
+      'segments: []string{"greetings", "search"},\n\t\tcall:     "greeting.search",',
+    );
+    expect(restGo.indexOf('"greeting.search"')).toBeLessThan(
+      restGo.indexOf('"greeting.greet"'),
+    );
+    expect(restGo).toContain('kinds:    map[string]string{"name": "string"},');
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("if call, ok := restCallFrom(req); ok {");
+    expect(routerGo).toContain("buf, err := r.marshalResult(req, result)");
+
+    expect(generateFiles(createContract()).has("rest.go")).toBe(false);
+  });
+
+  it("maps error codes to HTTP statuses through SetErrorStatus", () => {
+    const files = generateFiles(createContract());
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain(
+      "func (r *Router) SetErrorStatus(status func(err *Error) int) *Router",
+    );
+    expect(routerGo).toContain("status := err.Code.HTTPStatus()");
+    expect(routerGo).toContain("status = r.errorStatus(err)");
+
+    const errorsGo = files.get("errors.go") ?? "";
+    expect(errorsGo).toContain(
+      "func writeError(w http.ResponseWriter, status int, err *Error) {",
+    );
+  });
+
+  it("generates int fields for integer-validated numbers", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push({
+      name: "limit",
+      required: false,
+      type: {
+        kind: "optional",
+        baseType: { kind: "primitive", baseType: "number" },
+      },
+      validation: { int: true, min: 0.5, max: 10 },
+    });
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Limit *int `json:"limit,omitempty"`');
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("*input.Limit < 1");
+    expect(validationGo).not.toContain("must be an integer");
+  });
+
+  it("generates optional fields as pointers validated only when set", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "nickname",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "string" },
+        },
+        validation: { minLength: 2 },
+      },
+      {
+        name: "note",
+        required: false,
+        type: {
+          kind: "nullable",
+          baseType: {
+            kind: "optional",
+            baseType: { kind: "primitive", baseType: "string" },
+          },
+        },
+      },
+      {
+        name: "tags",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: {
+            kind: "array",
+            elementType: { kind: "primitive", baseType: "string" },
+          },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Nickname *string `json:"nickname,omitempty"`');
+    expect(typesGo).toContain("Note *string");
+    expect(typesGo).toContain("Tags []string");
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.Nickname != nil {");
+    expect(validationGo).toContain(
+      "if utf8.RuneCountInString(*input.Nickname) < 2 {",
+    );
+    expect(validationGo).not.toContain("input.Note != nil");
+  });
+
+  it("validates objects nested in arrays and records", () => {
+    const contract = createContract();
+    const point: TypeReference = {
+      kind: "object",
+      properties: [
+        {
+          name: "x",
+          required: true,
+          type: { kind: "primitive", baseType: "number" },
+          validation: { min: 0 },
+        },
+      ],
+    };
+    const output = contract.endpoints[0].output;
+    output.properties?.push(
+      {
+        name: "grid",
+        required: true,
+        type: {
+          kind: "array",
+          elementType: { kind: "array", elementType: point },
+        },
+      },
+      {
+        name: "byName",
+        required: true,
+        type: {
+          kind: "record",
+          keyType: { kind: "primitive", baseType: "string" },
+          valueType: structuredClone(point),
+        },
+      },
+    );
+    contract.types[1].properties = output.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain("Grid [][]GreetingGreetOutputGridItem");
+    expect(typesGo).toContain(
+      "ByName map[string]GreetingGreetOutputByNameValue",
+    );
+    expect(typesGo).not.toContain("map[string]interface{}");
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("for j, item1 := range item {");
+    expect(validationGo).toContain(
+      'fmt.Sprintf("grid[%d][%d].%s", i, j, nestedErr.Field)',
+    );
+    expect(validationGo).toContain("for key, item := range input.ByName {");
+    expect(validationGo).toContain(
+      'fmt.Sprintf("byName.%s.%s", key, nestedErr.Field)',
+    );
+  });
+
+  it("validates unique items and the rules of each item", () => {
+    const contract = createContract();
+    const point: TypeReference = {
+      kind: "object",
+      name: "Point",
+      properties: [
+        {
+          name: "x",
+          required: true,
+          type: { kind: "primitive", baseType: "number" },
+        },
+      ],
+    };
+    contract.endpoints[0].input.properties?.push(
+      {
+        name: "tags",
+        required: true,
+        type: {
+          kind: "array",
+          elementType: {
+            kind: "primitive",
+            baseType: "string",
+            validation: { minLength: 1, maxLength: 30, regex: "^[a-z]+$" },
+          },
+        },
+        validation: { uniqueItems: true },
+      },
+      {
+        name: "points",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "array", elementType: point },
+        },
+        validation: { uniqueItems: true },
+      },
+    );
+    contract.types.push({ ...point, name: "Point" });
+    const files = generateFiles(contract);
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain(
+      "if dup, first := duplicateItem(len(input.Tags), func(at int) interface{} { return input.Tags[at] }); dup >= 0 {",
+    );
+    expect(validationGo).toContain(
+      'Message: fmt.Sprintf("must have unique items: item %d equals item %d", dup, first),',
+    );
+    expect(validationGo).toContain(
+      "func(at int) interface{} { return jsonKey(input.Points[at]) }",
+    );
+    expect(validationGo).toContain(
+      "func duplicateItem(n int, key func(i int) interface{}) (int, int) {",
+    );
+    expect(validationGo).toContain('"encoding/json"');
+    expect(validationGo).toContain("for i, item := range input.Tags {");
+    expect(validationGo).toContain("if utf8.RuneCountInString(item) > 30 {");
+    expect(validationGo).toContain('Field:   fmt.Sprintf("tags[%d]", i),');
+
+    expect(files.get("schemas.go")).toContain(
+      '"tags":{"type":"array","items":{"type":"string","minLength":1,"maxLength":30,"pattern":"^[a-z]+$"},"uniqueItems":true}',
+    );
+  });
+
+  it("validates exclusive bounds and multiples", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties?.push(
+      {
+        name: "hours",
+        required: true,
+        type: { kind: "primitive", baseType: "number" },
+        validation: { exclusiveMin: 0.5, exclusiveMax: 100, multipleOf: 0.25 },
+      },
+      {
+        name: "seats",
+        required: true,
+        type: { kind: "primitive", baseType: "number" },
+        validation: { int: true, exclusiveMin: 1.5, multipleOf: 2 },
+      },
+    );
+    const files = generateFiles(contract);
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.Hours <= 0.5 {");
+    expect(validationGo).toContain(
+      'Message: fmt.Sprintf("must be greater than %v", 0.5),',
+    );
+    expect(validationGo).toContain("if input.Hours >= 100 {");
+    expect(validationGo).toContain(
+      'Message: fmt.Sprintf("must be less than %v", 100),',
+    );
+    expect(validationGo).toContain(
+      "if !isMultipleOf(float64(input.Hours), 0.25) {",
+    );
+    expect(validationGo).toContain(
+      "func isMultipleOf(value, step float64) bool {",
+    );
+    expect(validationGo).toContain('"math"');
+    // Integers compare with whole bounds and multiples with %
+    expect(validationGo).toContain("if input.Seats <= 1 {");
+    expect(validationGo).toContain("if input.Seats%2 != 0 {");
+
+    expect(files.get("schemas.go")).toContain(
+      '"hours":{"type":"number","exclusiveMinimum":0.5,"exclusiveMaximum":100,"multipleOf":0.25}',
+    );
+  });
+
+  it("parses string formats rather than matching patterns", () => {
+    const contract = createContract();
+    const string = (validation: ValidationRules): TypeReference => ({
+      kind: "primitive",
+      baseType: "string",
+      validation,
+    });
+    contract.endpoints[0].input.properties?.push(
+      { name: "host", required: true, type: string({ hostname: true }) },
+      { name: "ip", required: true, type: string({ ipv4: true }) },
+      { name: "ip6", required: true, type: string({ ipv6: true }) },
+      { name: "timeout", required: true, type: string({ duration: true }) },
+      { name: "phone", required: true, type: string({ e164: true }) },
+      {
+        name: "routes",
+        required: true,
+        type: {
+          kind: "record",
+          keyType: string({ ipv4: true }),
+          valueType: { kind: "primitive", baseType: "string" },
+        },
+      },
+    );
+    const files = generateFiles(contract);
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain('"net/netip"');
+    expect(validationGo).toContain("if !isHostname(input.Host) {");
+    expect(validationGo).toContain(
+      "if addr, err := netip.ParseAddr(input.Ip); err != nil || !addr.Is4() {",
+    );
+    expect(validationGo).toContain('Message: "must be a valid IPv4 address",');
+    expect(validationGo).toContain(
+      'if addr, err := netip.ParseAddr(input.Ip6); err != nil || !addr.Is6() || addr.Zone() != "" {',
+    );
+    expect(validationGo).toContain("if !isDuration(input.Timeout) {");
+    expect(validationGo).toContain(
+      'e164Pattern = regexp.MustCompile("^[+][0-9]{7,15}$")',
+    );
+    expect(validationGo).toContain("if !e164Pattern.MatchString(input.Phone) {");
+    expect(validationGo).toContain(
+      "if addr, err := netip.ParseAddr(key); err != nil || !addr.Is4() {",
+    );
+    expect(validationGo).toContain('Message: "key must be a valid IPv4 address",');
+    expect(validationGo).toContain("func isHostname(s string) bool {");
+    expect(validationGo).toContain("func isDuration(s string) bool {");
+    expect(validationGo).toContain("func durationUnits(s, units string) bool {");
+
+    expect(files.get("schemas.go")).toContain(
+      '"timeout":{"type":"string","format":"duration"}',
+    );
+  });
+
+  it("aliases structs with the same shape", () => {
+    const contract = createContract();
+    const endpoint = contract.endpoints[0];
+    const input = endpoint.input;
+    contract.endpoints.push({
+      name: "echo",
+      type: "query",
+      input: structuredClone({ ...input, name: "GreetingEchoInput" }),
+      output: structuredClone({ ...input, name: "GreetingEchoOutput" }),
+      fullName: "greeting.echo",
+    });
+    contract.types.push(
+      {
+        name: "GreetingEchoInput",
+        kind: "object",
+        properties: structuredClone(input.properties),
+      },
+      {
+        name: "GreetingEchoOutput",
+        kind: "object",
+        properties: structuredClone(input.properties),
+      },
+    );
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    // Types are generated in name order, so the first of them is the struct
+    expect(typesGo).toContain("type GreetingEchoInput struct {");
+    expect(typesGo).toContain("type GreetingEchoOutput = GreetingEchoInput");
+    expect(typesGo).toContain("type GreetingGreetInput = GreetingEchoInput");
+    expect(typesGo).toContain("type GreetingGreetOutput struct {");
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain(
+      "func ValidateGreetingGreetInput(input GreetingGreetInput) error {\n\treturn ValidateGreetingEchoInput(input)\n}",
+    );
+  });
+
+  it("generates typed string constants for enums", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "tone",
+        required: true,
+        type: { kind: "enum", enumValues: ["warm", "very_formal"] },
+      },
+      {
+        name: "mood",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "enum", enumValues: ["warm", "very_formal"] },
+        },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Tone GreetingGreetInputTone `json:"tone"`');
+    expect(typesGo).toContain("Mood *GreetingGreetInputMood");
+
+    const enumsGo = files.get("enums.go") ?? "";
+    expect(enumsGo).toContain("type GreetingGreetInputTone string");
+    expect(enumsGo).toContain(
+      'GreetingGreetInputToneVeryFormal GreetingGreetInputTone = "very_formal"',
+    );
+    expect(enumsGo).toContain("func (e GreetingGreetInputTone) IsValid() bool");
+    expect(enumsGo).toContain(
+      "func (e *GreetingGreetInputTone) UnmarshalJSON(data []byte) error",
+    );
+    expect(enumsGo).toContain(
+      "type GreetingGreetInputMood = GreetingGreetInputTone",
+    );
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain(
+      'if input.Tone != "" && !input.Tone.IsValid() {',
+    );
+    expect(validationGo).toContain("if !(*input.Mood).IsValid() {");
+
+    expect(generateFiles(createContract()).has("enums.go")).toBe(false);
+  });
+
+  it("generates time.Time and Date fields for timestamps and dates", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "sentAt",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+        validation: { datetime: true, past: true },
+      },
+      {
+        name: "replyBy",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "string" },
+        },
+        validation: { date: true, future: true },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('\t"time"\n)');
+    expect(typesGo).toContain('SentAt time.Time `json:"sentAt"`');
+    expect(typesGo).toContain('ReplyBy *Date `json:"replyBy,omitempty"`');
+
+    const datesGo = files.get("dates.go") ?? "";
+    expect(datesGo).toContain("type Date struct {\n\ttime.Time\n}");
+    expect(datesGo).toContain("func (d *Date) UnmarshalJSON(data []byte) error");
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.SentAt.IsZero() {");
+    expect(validationGo).toContain(
+      "if !input.SentAt.IsZero() && !input.SentAt.Before(time.Now()) {",
+    );
+    expect(validationGo).toContain("if !(*input.ReplyBy).After(time.Now()) {");
+
+    const plain = generateFiles(createContract());
+    expect(plain.has("dates.go")).toBe(false);
+    expect(plain.get("types.go")).not.toContain('"time"');
+  });
+
+  it("binds multipart file parts to File fields", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "avatar",
+        required: true,
+        type: { kind: "file" },
+        validation: { maxSize: 1000, mimeTypes: ["image/png"] },
+      },
+      {
+        name: "cover",
+        required: false,
+        type: { kind: "optional", baseType: { kind: "file" } },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Avatar File `json:"avatar"`');
+    expect(typesGo).toContain('Cover *File `json:"cover,omitempty"`');
+
+    const uploadsGo = files.get("uploads.go") ?? "";
+    expect(uploadsGo).toContain("type File struct {");
+    expect(uploadsGo).toContain(
+      "func decodeUploadRequest(req *http.Request, request interface{}) error {",
+    );
+    expect(files.get("router.go")).toContain(
+      "decodeUploadRequest(req, &request)",
+    );
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.Avatar.IsZero() {");
+    expect(validationGo).toContain("if input.Avatar.Size > 1000 {");
+    expect(validationGo).toContain(
+      'if !input.Avatar.IsZero() && !input.Avatar.HasContentType("image/png") {',
+    );
+
+    const plain = generateFiles(createContract());
+    expect(plain.has("uploads.go")).toBe(false);
+    expect(plain.get("router.go")).not.toContain("decodeUploadRequest");
+  });
+
+  it("generates []byte fields for bytes", () => {
+    const contract = createContract();
+    const input = contract.endpoints[0].input;
+    input.properties?.push(
+      {
+        name: "avatar",
+        required: true,
+        type: { kind: "primitive", baseType: "bytes" },
+        validation: { maxSize: 65536 },
+      },
+      {
+        name: "thumbnail",
+        required: false,
+        type: {
+          kind: "optional",
+          baseType: { kind: "primitive", baseType: "bytes" },
+        },
+        validation: { minSize: 1 },
+      },
+    );
+    contract.types[0].properties = input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Avatar []byte `json:"avatar"`');
+    expect(typesGo).toContain('Thumbnail []byte `json:"thumbnail,omitempty"`');
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.Avatar == nil {");
+    expect(validationGo).toContain("if len(input.Avatar) > 65536 {");
+    expect(validationGo).toContain(
+      "if input.Thumbnail != nil {\n\t\tif len(input.Thumbnail) < 1 {",
+    );
+  });
+
+  it("streams the items of an output array through a Stream", () => {
+    const contract = createContract();
+    const output = contract.endpoints[0].output;
+    output.properties?.push({
+      name: "lines",
+      required: true,
+      type: {
+        kind: "array",
+        elementType: { kind: "primitive", baseType: "string" },
+      },
+    });
+    contract.endpoints[0].stream = "lines";
+    const files = generateFiles(contract);
+
+    expect(files.get("types.go")).toContain(
+      "type GreetingGreetHandler func(ctx context.Context, info RequestInfo, input GreetingGreetInput, stream *Stream[string]) (GreetingGreetOutput, error)",
+    );
+
+    const streamsGo = files.get("streams.go") ?? "";
+    expect(streamsGo).toContain("type Stream[T any] struct {");
+    expect(streamsGo).toContain("func acceptsNDJSON(req *http.Request) bool {");
+
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(methodsGo).toContain('stream := newHTTPStream(w, req, "lines")');
+    expect(methodsGo).toContain(
+      "return loadHandler(r, &r.greetingGreet)(ctx, info, input.(GreetingGreetInput), streamTo[string](stream))",
+    );
+    expect(methodsGo).toContain(
+      "output, err := loadHandler(r, &r.greetingGreet)(ctx, info, input.(GreetingGreetInput), collectStream(&items))",
+    );
+    expect(files.get("mock.go")).toContain(
+      "return output, stream.SendAll(output.Lines)",
+    );
+
+    expect(generateFiles(createContract()).has("streams.go")).toBe(false);
+  });
+
+  it("generates cursor helpers for paginated queries", () => {
+    const contract = createContract();
+    contract.endpoints[0].paginated = true;
+    const files = generateFiles(contract);
+
+    const paginationGo = files.get("pagination.go") ?? "";
+    expect(paginationGo).toContain("var DefaultPageSize = 20");
+    expect(paginationGo).toContain(
+      "func EncodeCursor(position interface{}) (*string, error) {",
+    );
+    expect(paginationGo).toContain(
+      "func DecodeCursor(cursor *string, position interface{}) error {",
+    );
+
+    expect(generateFiles(createContract()).has("pagination.go")).toBe(false);
+  });
+
+  it("shares identical concurrent queries with SingleFlight", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "reset",
+      type: "mutation",
+      fullName: "greeting.reset",
+    });
+    const files = generateFiles(contract);
+    const singleFlightGo = files.get("singleflight.go") ?? "";
+
+    expect(singleFlightGo).toContain("func SingleFlight() InterceptorFunc {");
+    expect(singleFlightGo).toContain(
+      'return ok && m.kind == "query" && m.serve == nil',
+    );
+    // The shared call outlives the cancellation of the call starting it
+    expect(singleFlightGo).toContain(
+      "flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))",
+    );
+    expect(singleFlightGo).toContain(
+      "case <-ctx.Done():\n\t\tg.mu.Lock()\n\t\tcall.waiters--",
+    );
+  });
+
+  it("keeps the cache entries and flights of tenants apart", () => {
+    const files = generateFiles(createContract());
+
+    expect(files.get("cache.go")).toContain(
+      'tenant, _ := TenantFrom(ctx)\n\thash.Write([]byte(tenant.ID + "\\x00"))\n\tif !options.Shared {',
+    );
+    expect(files.get("singleflight.go")).toContain(
+      'return method + "\\x00" + tenant.ID + "\\x00" + userID + "\\x00" + hex.EncodeToString(sum[:]), nil',
+    );
+    expect(files.get("router_test.go")).toContain(
+      "func TestTenantKeys(t *testing.T) {",
+    );
+  });
+
+  it("caches query results in a pluggable store", () => {
+    const contract = createContract();
+    contract.endpoints.push({
+      ...contract.endpoints[0],
+      name: "reset",
+      type: "mutation",
+      fullName: "greeting.reset",
+    });
+    const files = generateFiles(contract);
+
+    const cacheGo = files.get("cache.go") ?? "";
+    expect(cacheGo).toContain(
+      "func Cache(store CacheStore, options CacheOptions) InterceptorFunc {",
+    );
+    expect(cacheGo).toContain(
+      "if ok && !entry.refreshEarly(time.Now(), options.Beta) {",
+    );
+    expect(cacheGo).toContain(
+      "return group.do(ctx, key, func(ctx context.Context) (interface{}, error) {",
+    );
+    expect(files.get("singleflight.go")).toContain(
+      "return group.do(ctx, key, next)",
+    );
+
+    const redisGo = files.get("redis.go") ?? "";
+    expect(redisGo).toContain(
+      "func NewRedisCacheStore(addr string, options RedisOptions) *RedisCacheStore {",
+    );
+    expect(redisGo).toContain(
+      '_, err = c.do(ctx, "SET", key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))',
+    );
+
+    // Only the query decodes cached results
+    const methodsGo = files.get("methods.go") ?? "";
+    expect(
+      methodsGo.match(/decodeResult: func\(data \[\]byte\)/g),
+    ).toHaveLength(1);
+    expect(methodsGo).toContain("var output GreetingGreetOutput");
+  });
+
+  it("keeps cookie sessions in a pluggable store", () => {
+    const files = generateFiles(createContract());
+
+    const sessionGo = files.get("session.go") ?? "";
+    expect(sessionGo).toContain(
+      "func Sessions(store SessionStore, options SessionOptions) MiddlewareFunc {",
+    );
+    expect(sessionGo).toContain(
+      "func SessionFrom(ctx context.Context) (*Session, bool) {",
+    );
+    expect(sessionGo).toContain(
+      "if cookie, err := info.Request.Cookie(options.CookieName); err == nil && validSessionID(cookie.Value) {",
+    );
+    expect(sessionGo).toContain("Secure:   !s.options.Insecure,");
+    expect(sessionGo).toContain("HttpOnly: true,");
+
+    const redisGo = files.get("redis.go") ?? "";
+    expect(redisGo).toContain(
+      "func NewRedisSessionStore(addr string, options RedisOptions) *RedisSessionStore {",
+    );
+    expect(redisGo).toContain(
+      'return s.setJSON(ctx, "xrpc:session:"+id, values, ttl)',
+    );
+  });
+
+  it("bounds concurrent calls with concurrency limits", () => {
+    const files = generateFiles(createContract());
+
+    const concurrencyGo = files.get("concurrency.go") ?? "";
+    expect(concurrencyGo).toContain(
+      "func (r *Router) SetConcurrencyLimitFor(pattern string, limit ConcurrencyLimit) *Router {",
+    );
+    expect(concurrencyGo).toContain("CodeResourceExhausted");
+    expect(files.get("router.go")).toContain(
+      "release, err := r.acquire(ctx, info.Method)",
+    );
+  });
+
+  it("sheds calls beyond the queue of a concurrency limit with a retry delay", () => {
+    const files = generateFiles(createContract());
+
+    const concurrencyGo = files.get("concurrency.go") ?? "";
+    expect(concurrencyGo).toContain("if p.limit.Wait > 0 && p.enqueue() {");
+    expect(concurrencyGo).toContain(
+      "WithDetails(RetryInfo{RetryAfterMs: p.limit.RetryAfter.Milliseconds()})",
+    );
+    expect(concurrencyGo).toContain(
+      "func (r *Router) ConcurrencyStats() []ConcurrencyStats {",
+    );
+    expect(files.get("errors.go")).toContain(
+      'w.Header().Set("Retry-After", strconv.FormatInt((info.RetryAfterMs+999)/1000, 10))',
+    );
+  });
+
+  it("encodes responses into pooled buffers without envelope maps", () => {
+    const files = generateFiles(createContract());
+
+    const buffersGo = files.get("buffers.go") ?? "";
+    expect(buffersGo).toContain("var encodeBuffers = sync.Pool{");
+    expect(buffersGo).toContain(
+      "func encodeJSON(value interface{}) (*encodeBuffer, error) {",
+    );
+    expect(buffersGo).toContain("if buf.Cap() > maxPooledBuffer {");
+
+    const routerGo = files.get("router.go") ?? "";
+    expect(routerGo).toContain("buf, err := r.marshal(result, true)");
+    expect(routerGo).toContain("defer buf.release()");
+    for (const file of ["router.go", "errors.go", "dispatch.go"]) {
+      expect(files.get(file)).not.toMatch(
+        /map\[string\]interface\{\}\{"(result|error)"/,
+      );
+    }
+    expect(files.get("router_test.go")).toContain(
+      "func BenchmarkServeHTTP(b *testing.B) {",
+    );
+  });
+
+  it("benchmarks decoding, validation and dispatch per method", () => {
+    const files = generateFiles(createContract());
+
+    const benchGo = files.get("router_bench_test.go") ?? "";
+    expect(benchGo).toContain(
+      '{"greeting.greet", "example", exampleGreetingGreetInput},',
+    );
+    // The name grows to its maxLength of 100
+    expect(benchGo).toContain(
+      `{"greeting.greet", "large", \`{"name":"example${"x".repeat(93)}"}\`},`,
+    );
+    for (const name of ["Decode", "Validate", "Dispatch"]) {
+      expect(benchGo).toContain(`func Benchmark${name}(b *testing.B) {`);
+    }
+    expect(benchGo).toContain("b.ReportAllocs()");
+    expect(benchGo).toContain(
+      "if _, err := r.Dispatch(ctx, method, params); err != nil {",
+    );
+  });
+
+  it("decodes params and encodes results through a pluggable Codec", () => {
+    const files = generateFiles(createContract());
+
+    const codecGo = files.get("codec.go") ?? "";
+    expect(codecGo).toContain("type Codec interface {");
+    expect(codecGo).toContain(
+      "func (r *Router) SetCodec(codec Codec) *Router {",
+    );
+    expect(codecGo).toContain("data, err = r.codec.Marshal(result)");
+    expect(files.get("methods.go")).toContain("r.unmarshal(params, &input)");
+    expect(files.has("jsoncodec.go")).toBe(false);
+    expect(files.has("json.go")).toBe(false);
+
+    const adapters = {
+      sonic: "sonic.ConfigStd.Marshal(v)",
+      jsonv2: "jsonv2.Marshal(v)",
+      easyjson: "easyjson.Marshal(marshaler)",
+    };
+    for (const [jsonCodec, call] of Object.entries(adapters)) {
+      const output = goTarget.generate({
+        contract: createContract(),
+        outputDir: "out",
+        options: { packageName: "server", jsonCodec },
+      });
+      const jsonCodecGo =
+        output.files.find((file) => file.path === "jsoncodec.go")?.content ??
+        "";
+      expect(jsonCodecGo).toContain(call);
+    }
+  });
+
+  it("generates static JSON methods only when requested", () => {
+    const output = goTarget.generate({
+      contract: createContract(),
+      outputDir: "out",
+      options: { packageName: "server", staticJSON: true },
+    });
+    const files = new Map(
+      output.files.map((file) => [file.path, file.content]),
+    );
+
+    const jsonGo = files.get("json.go") ?? "";
+    expect(jsonGo).toContain(
+      "func (v GreetingGreetInput) appendJSON(dst []byte) ([]byte, error) {",
+    );
+    expect(jsonGo).toContain(
+      "func (v *GreetingGreetInput) UnmarshalJSON(data []byte) error {",
+    );
+    expect(jsonGo).toContain('case "name":');
+    expect(jsonGo).toContain('if name := foldJSONKey(key, "name"); name != ""');
+    expect(files.get("codec.go")).toContain(
+      "data, err = appender.appendJSON(buf.AvailableBuffer())",
+    );
+
+    const easyjson = goTarget.generate({
+      contract: createContract(),
+      outputDir: "out",
+      options: {
+        packageName: "server",
+        staticJSON: true,
+        jsonCodec: "easyjson",
+      },
+    });
+    expect(
+      easyjson.diagnostics.some((issue) =>
+        issue.message.includes("staticJSON"),
+      ),
+    ).toBe(true);
+  });
+
+  it("caps validation errors and fails fast when requested", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties!.push({
+      name: "tags",
+      required: true,
+      type: {
+        kind: "array",
+        elementType: { kind: "primitive", baseType: "string" },
+      },
+      validation: { minItems: 1 },
+    });
+    contract.endpoints[0].input.properties![1].type.elementType!.validation = {
+      minLength: 2,
+    };
+    const generate = (options: Record<string, unknown>) => {
+      const output = goTarget.generate({
+        contract,
+        outputDir: "out",
+        options: { packageName: "server", format: false, ...options },
+      });
+      const files = new Map(
+        output.files.map((file) => [file.path, file.content]),
+      );
+      return { validationGo: files.get("validation.go") ?? "", output };
+    };
+
+    const { validationGo } = generate({ maxValidationErrors: 20 });
+    expect(validationGo).toContain("var MaxValidationErrors = 20");
+    expect(validationGo).toContain(
+      "return MaxValidationErrors > 0 && len(e) >= MaxValidationErrors",
+    );
+    // After name, tags and each tag of the input, and message of the output
+    expect(validationGo.match(/if errs\.full\(\) \{/g)?.length).toBe(4);
+    expect(validationGo).toContain("return errs[:MaxValidationErrors]");
+
+    expect(generate({ validationFailFast: true }).validationGo).toContain(
+      "var MaxValidationErrors = 1",
+    );
+
+    const invalid = generate({ maxValidationErrors: 0 });
+    expect(invalid.validationGo).not.toContain("MaxValidationErrors");
+    expect(
+      invalid.output.diagnostics?.some((issue) =>
+        issue.message.includes("maxValidationErrors"),
+      ),
+    ).toBe(true);
+    expect(generateFiles(contract).get("validation.go")).not.toContain(
+      "errs.full()",
+    );
+  });
+
+  it("applies initialisms and splits types by namespace when requested", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties!.push({
+      name: "userId",
+      required: false,
+      type: { kind: "primitive", baseType: "string" },
+    });
+    const output = goTarget.generate({
+      contract,
+      outputDir: "out",
+      options: {
+        packageName: "server",
+        initialisms: true,
+        splitNamespaces: true,
+      },
+    });
+    const files = new Map(
+      output.files.map((file) => [file.path, file.content]),
+    );
+
+    const greetingTypesGo = files.get("greeting_types.go") ?? "";
+    expect(greetingTypesGo).toContain("type GreetingGreetInput struct {");
+    expect(greetingTypesGo).toContain(
+      'UserID string `json:"userId,omitempty"`',
+    );
+    expect(greetingTypesGo).toContain("type GreetingGreetHandler func(");
+    expect(greetingTypesGo).toContain('import "context"');
+    expect(files.get("types.go")).toContain("type MiddlewareFunc func(");
+    expect(files.get("types.go")).not.toContain("GreetingGreetInput");
+
+    const flat = generateFiles(contract);
+    expect(flat.has("greeting_types.go")).toBe(false);
+    expect(flat.get("types.go")).toContain("UserId string");
+  });
+
+  it("generates discriminated unions with a variant interface", () => {
+    const variant = (kind: string, field: string): TypeReference => ({
+      kind: "object",
+      properties: [
+        {
+          name: "kind",
+          required: true,
+          type: { kind: "literal", literalValue: kind },
+        },
+        {
+          name: field,
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { minLength: 1 },
+        },
+      ],
+    });
+    const contract = createContract();
+    contract.endpoints[0].input.properties?.push({
+      name: "event",
+      required: true,
+      type: {
+        kind: "union",
+        discriminator: "kind",
+        unionTypes: [variant("created", "title"), variant("deleted", "id")],
+      },
+    });
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const files = generateFiles(contract);
+
+    const typesGo = files.get("types.go") ?? "";
+    expect(typesGo).toContain('Event GreetingGreetInputEvent `json:"event"`');
+    expect(typesGo).toContain("type GreetingGreetInputEventCreated struct {");
+    expect(typesGo).toContain("type GreetingGreetInputEventDeleted struct {");
+
+    const unionsGo = files.get("unions.go") ?? "";
+    expect(unionsGo).toContain(
+      "type GreetingGreetInputEvent struct {\n\tValue GreetingGreetInputEventVariant\n}",
+    );
+    expect(unionsGo).toContain(
+      "func (GreetingGreetInputEventCreated) isGreetingGreetInputEvent() {}",
+    );
+    expect(unionsGo).toContain('v.Kind = "deleted"');
+    expect(unionsGo).toContain('case "created":');
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if input.Event.Value == nil {");
+    expect(validationGo).toContain(
+      "return ValidateGreetingGreetInputEventDeleted(v)",
+    );
+    expect(validationGo).not.toContain("input.Kind");
+
+    expect(generateFiles(createContract()).has("unions.go")).toBe(false);
+  });
+
+  it("validates record keys, values and entry counts", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties?.push({
+      name: "labels",
+      required: true,
+      type: {
+        kind: "record",
+        keyType: {
+          kind: "primitive",
+          baseType: "string",
+          validation: { regex: "^[a-z]+$" },
+        },
+        valueType: {
+          kind: "primitive",
+          baseType: "string",
+          validation: { maxLength: 100 },
+        },
+      },
+      validation: { maxEntries: 20 },
+    });
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const files = generateFiles(contract);
+
+    expect(files.get("types.go")).toContain(
+      'Labels map[string]string `json:"labels"`',
+    );
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain(
+      'labelsKeyPattern = regexp.MustCompile("^[a-z]+$")',
+    );
+    expect(validationGo).toContain("if len(input.Labels) > 20 {");
+    expect(validationGo).toContain("for key, item := range input.Labels {");
+    expect(validationGo).toContain("if !labelsKeyPattern.MatchString(key) {");
+    expect(validationGo).toContain(
+      'Message: "key must match the required pattern",',
+    );
+    expect(validationGo).toContain("if utf8.RuneCountInString(item) > 100 {");
+    expect(validationGo).toContain('Field:   fmt.Sprintf("labels.%s", key),');
+  });
+
+  it("counts string lengths in runes unless the schema chooses another unit", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties?.push(
+      {
+        name: "title",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+        validation: { maxLength: 200, lengthUnit: "bytes" },
+      },
+      {
+        name: "label",
+        required: true,
+        type: { kind: "primitive", baseType: "string" },
+        validation: { maxLength: 20, lengthUnit: "graphemes" },
+      },
+    );
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const validationGo = generateFiles(contract).get("validation.go") ?? "";
+
+    expect(validationGo).toContain('"unicode/utf8"');
+    expect(validationGo).toContain(
+      "if utf8.RuneCountInString(input.Name) > 100 {",
+    );
+    expect(validationGo).toContain("if len(input.Title) > 200 {");
+    expect(validationGo).toContain("if graphemeCount(input.Label) > 20 {");
+    expect(validationGo).toContain("func graphemeCount(s string) int {");
+    // graphemeCount is documented as an approximation of UAX #29
+    expect(validationGo).toContain(
+      "// graphemeCount approximates the number of characters a user sees in s, the",
+    );
+  });
+
+  it("calls custom validators and checks they are registered", () => {
+    const contract = createContract();
+    contract.endpoints[0].input.properties?.push({
+      name: "dueDate",
+      required: true,
+      type: { kind: "primitive", baseType: "string" },
+      validation: { date: true, custom: "workingDay" },
+    });
+    contract.types[0].properties = contract.endpoints[0].input.properties;
+    const files = generateFiles(contract);
+
+    const validatorsGo = files.get("validators.go") ?? "";
+    expect(validatorsGo).toContain(
+      "func RegisterValidator(name string, fn ValidatorFunc) {",
+    );
+    expect(validatorsGo).toContain('"workingDay",');
+    expect(files.get("router.go")).toContain(
+      "func NewRouter() *Router {\n\tmustRegisteredValidators()",
+    );
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("if !input.DueDate.IsZero() {");
+    expect(validationGo).toContain(
+      'if err := validators["workingDay"](input.DueDate); err != nil {',
+    );
+    expect(validationGo).toContain("Message: err.Error(),");
+  });
+
+  it("does not generate validators.go without custom validators", () => {
+    const files = generateFiles(createContract());
+
+    expect(files.has("validators.go")).toBe(false);
+    expect(files.get("router.go")).not.toContain("mustRegisteredValidators");
+  });
+
+  it("limits the depth recursive types are validated to", () => {
+    const contract = createContract();
+    const comment: TypeReference = {
+      kind: "object",
+      name: "Comment",
+      properties: [
+        {
+          name: "body",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { minLength: 1 },
+        },
+        {
+          name: "replies",
+          required: true,
+          type: {
+            kind: "array",
+            elementType: { kind: "object", name: "Comment" },
+          },
+        },
+      ],
+    };
+    contract.types.push({
+      name: "Comment",
+      kind: "object",
+      properties: comment.properties,
+    });
+    contract.endpoints[0].output.properties?.push({
+      name: "comment",
+      required: true,
+      type: comment,
+    });
+    contract.types[1].properties = contract.endpoints[0].output.properties;
+    const files = generateFiles(contract);
+
+    expect(files.get("types.go")).toContain(
+      'Replies []Comment `json:"replies"`',
+    );
+
+    const validationGo = files.get("validation.go") ?? "";
+    expect(validationGo).toContain("var MaxValidationDepth = 32");
+    expect(validationGo).toContain(
+      "func ValidateComment(input Comment) error {\n\treturn validateComment(input, 0)\n}",
+    );
+    expect(validationGo).toContain(
+      "func validateComment(input Comment, depth int) error {",
+    );
+    expect(validationGo).toContain("if depth >= MaxValidationDepth {");
+    expect(validationGo).toContain(
+      "} else if err := validateComment(item, depth+1); err != nil {",
+    );
+    expect(validationGo).toContain(
+      "if err := ValidateComment(input.Comment); err != nil {",
+    );
+
+    expect(generateFiles(createContract()).get("validation.go")).not.toContain(
+      "MaxValidationDepth",
+    );
+  });
+});
@@ -1,5 +1,6 @@
 import {
   type ContractDefinition,
+  type GeneratedFile,
   type Property,
   TYPE_KINDS,
   type Target,
@@ -9,21 +10,191 @@ import {
   type TypeDefinition,
   type TypeReference,
   VALIDATION_KINDS,
-  toPascalCase,
   validateSupport,
 } from "@xrpckit/sdk";
-import { GoServerGenerator } from "./server-generator";
+import { GoAMQPGenerator } from "./amqp-generator";
+import { GoAuthGenerator } from "./auth-generator";
+import { GoBreakerGenerator } from "./breaker-generator";
+import { GoBufferGenerator } from "./buffer-generator";
+import { GoCacheGenerator } from "./cache-generator";
+import { GoCLIGenerator } from "./cli-generator";
+import { GoClientGenerator } from "./client-generator";
+import { GoCodecGenerator } from "./codec-generator";
+import { GoCoercionGenerator } from "./coercion-generator";
+import { GoCompatGenerator } from "./compat-generator";
+import { GoCompressionGenerator } from "./compression-generator";
+import { GoConcurrencyGenerator } from "./concurrency-generator";
+import { GoContextGenerator } from "./context-generator";
+import { GoCSRFGenerator } from "./csrf-generator";
+import { GoDateGenerator } from "./date-generator";
+import { GoDefaultsGenerator } from "./defaults-generator";
+import { GoDispatchGenerator } from "./dispatch-generator";
+import { GoEnumGenerator } from "./enum-generator";
+import { GoErrorsGenerator } from "./errors-generator";
+import { GoEventsGenerator } from "./events-generator";
+import { GoExamplesGenerator } from "./examples-generator";
+import { formatGoFiles } from "./format";
+import { GoGateGenerator } from "./gate-generator";
+import { GoGRPCGenerator } from "./grpc-generator";
+import { GoHealthGenerator } from "./health-generator";
+import { GoHedgingGenerator } from "./hedging-generator";
+import { GoInjectGenerator } from "./inject-generator";
+import { GoIntrospectGenerator } from "./introspect-generator";
+import { GoJSONGenerator } from "./json-generator";
+import {
+  GoJSONCodecGenerator,
+  type JSONCodecBackend,
+} from "./jsoncodec-generator";
+import { GoLoggingGenerator } from "./logging-generator";
+import { GoMethodsGenerator } from "./methods-generator";
+import { GoMetricsGenerator } from "./metrics-generator";
+import { GoMockGenerator } from "./mock-generator";
+import { GoMountGenerator } from "./mount-generator";
+import { COMMON_INITIALISMS, toPascalCase, withInitialisms } from "./naming";
+import { GoNATSGenerator } from "./nats-generator";
+import { GoNormalizeGenerator } from "./normalize-generator";
+import { GoOpenAPIGenerator } from "./openapi-generator";
+import { GoOperationsGenerator } from "./operations-generator";
+import { GoOutboxGenerator } from "./outbox-generator";
+import { GoPaginationGenerator } from "./pagination-generator";
+import { GoPlaygroundGenerator } from "./playground-generator";
+import { GoRedactGenerator } from "./redact-generator";
+import { GoRedisGenerator } from "./redis-generator";
+import { GoRESTGenerator } from "./rest-generator";
+import { GoRetryGenerator } from "./retry-generator";
+import { GoRouterBenchGenerator } from "./router-bench-generator";
+import { GoRouterTestGenerator } from "./router-test-generator";
+import { GoSchemasGenerator } from "./schemas-generator";
+import {
+  GoServerGenerator,
+  INTROSPECT_METHOD,
+  OPERATION_CANCEL_METHOD,
+  OPERATION_GET_METHOD,
+} from "./server-generator";
+import { GoServiceGenerator } from "./service-generator";
+import { GoSessionGenerator } from "./session-generator";
+import { GoSingleFlightGenerator } from "./singleflight-generator";
+import { GoSnippetsGenerator } from "./snippets-generator";
+import { GoStreamGenerator } from "./stream-generator";
+import { GoTenantGenerator } from "./tenant-generator";
+import { GoTestClientGenerator } from "./test-client-generator";
+import { GoTLSGenerator } from "./tls-generator";
+import { GoTransportGenerator } from "./transport-generator";
 import { GoTypeCollector } from "./type-collector";
 import { GoTypeGenerator } from "./type-generator";
+import { GoUnionGenerator } from "./union-generator";
+import { GoUploadGenerator } from "./upload-generator";
 import { GoValidationGenerator } from "./validation-generator";
+import {
+  GoValidatorsGenerator,
+  collectCustomValidators,
+} from "./validators-generator";
+import { GoWarningsGenerator } from "./warnings-generator";
 
 /**
  * Go server code generator that produces idiomatic Go HTTP handlers from xRPC contracts.
  *
- * Generates three files:
+ * Generates forty-three files:
  * - types.go: Struct definitions, handler types, middleware types
+ * - context.go: RequestInfo and typed context.Context accessors
+ * - auth.go: Bearer, JWT and API key authentication middleware
+ * - tls.go: ListenAndServeTLS with mutual TLS and client certificate identities
+ * - inject.go: Provide and MustGet injecting typed dependencies into calls
+ * - tenant.go: Tenant resolution middleware, TenantFrom and per-tenant rate limits
+ * - errors.go: Error type, error codes and their HTTP status mapping
  * - router.go: HTTP routing and JSON handling
+ * - methods.go: Method descriptor table the router dispatches calls through
+ * - services.go: An interface per namespace (TaskService) and its Register function
+ * - buffers.go: Pooled response buffers and envelope structs
+ * - dispatch.go: Router.Dispatch and ServeMessage for non-HTTP transports
+ * - mount.go: Router.Mount composing routers of other contracts under a prefix
+ * - logging.go: Logger interface receiving per-call log entries
+ * - csrf.go: Opt-in CSRF protection enabled with Router.SetCSRF
+ * - session.go: Opt-in Sessions middleware keeping cookie sessions in a SessionStore
+ * - compression.go: Opt-in gzip/deflate result compression (Router.SetCompression)
+ * - singleflight.go: Opt-in SingleFlight interceptor sharing identical queries
+ * - cache.go: Opt-in Cache interceptor keeping query results in a CacheStore
+ * - redis.go: RedisCacheStore and RedisSessionStore shared among replicas
+ * - concurrency.go: Opt-in global and per-method concurrency limits and queues
+ * - gates.go: Router.Disable, MethodGate, and maintenance and read-only modes
+ * - events.go: Events of mutations, EventPublisher and WebhookPublisher
+ * - outbox.go: Transactional outbox relaying the events of committed mutations
+ * - operations.go: Operations running async mutations in the background
+ * - codec.go: MessagePack and CBOR request and result encodings
+ * - coercion.go: Opt-in lenient decoding coercing numbers and strings of params
+ * - health.go: Liveness and readiness handlers with pluggable checks
+ * - openapi.go: Embedded OpenAPI 3.1 document served by Router.OpenAPISpec
+ * - introspect.go: Method table answering the built-in xrpc.introspect method
+ * - compat.go: CheckCompat finding breaking changes between contract schemas
+ * - playground.go: Router.Playground serving a page for trying out methods
+ * - snippets.go: curl and HTTPie commands calling each method with an example
+ * - schemas.go: JSON Schema documents of every input and output type
  * - validation.go: Input validation functions
+ * - testclient.go: TestClient calling the router in process with typed methods
+ * - client.go: Client calling a server of the contract over HTTP
+ * - retry.go: RetryPolicy the Client retries failed queries with
+ * - breaker.go: Per-method circuit breakers of the Client
+ * - hedging.go: Hedged requests the Client makes for slow queries
+ * - transport.go: TransportOptions tuning the Client's connection pool
+ * - cli.go: CLI with a command per method and a flag per input field
+ * - mock.go: MockServer answering with stubs instead of handlers
+ *
+ * String enums in the contract add enums.go with a typed string, constants
+ * and JSON methods per enum. Timestamp fields are generated as time.Time, and
+ * calendar date fields add dates.go with a Date type encoded as YYYY-MM-DD.
+ * Fields marked with .meta({ sensitive: true }) add redact.go with Redacted
+ * methods returning copies of the types holding them that are safe to log.
+ * Fields declared with .default() add defaults.go with ApplyDefaults
+ * methods, which the router calls on decoded inputs to fill in the fields
+ * that are absent. Strings normalized with .trim(), .toLowerCase(),
+ * .toUpperCase() or .meta({ normalize }) add normalize.go with Normalize
+ * methods, which the router calls on decoded inputs after that, before
+ * validating them. Soft limits declared with .meta({ warn }) add warnings.go
+ * with Warnings methods, whose results the router returns next to the
+ * results of the calls instead of rejecting them.
+ * File fields (z.file()) add uploads.go with a File type and the decoding of
+ * multipart/form-data requests binding file parts to them.
+ * Discriminated unions add unions.go with a wrapper, variant interface and
+ * JSON methods choosing the variant by its discriminator field. Endpoints
+ * declared with an http mapping add rest.go, whose Router.RESTHandler serves
+ * them as RESTful routes, and endpoints declared with a stream option add
+ * streams.go, whose Stream the handler sends the items of the output array
+ * through as they are written to the response. Paginated queries add
+ * pagination.go with the helpers reading their cursor and pageSize and
+ * encoding nextCursor. Fields marked
+ * with .meta({ custom: "name" }) add validators.go, where the server
+ * registers the validators they call. examples.go has an ExampleX()
+ * constructor per input and output type returning a value that passes
+ * validation, derived from the schemas.
+ * When the contract uses pattern validations, validation_test.go is also
+ * generated with benchmarks for the precompiled patterns. router_test.go
+ * tests the router over HTTP with the example payloads, and
+ * router_bench_test.go benchmarks decoding, validating and dispatching them,
+ * along with large payloads filling the length constraints. With the
+ * `metrics: "prometheus"` option, metrics.go adds a Logger recording
+ * per-method Prometheus metrics. With the `jsonCodec: "sonic" | "jsonv2" |
+ * "easyjson"` option, jsoncodec.go adds the Codec adapter of that library to
+ * install with Router.SetCodec. With the `grpc: true` option, grpc.go adds
+ * RegisterGRPC serving the methods over gRPC with the services and messages
+ * of the schema the proto target generates; `grpcPackage` names its proto
+ * package when the proto target's `package` option is set. With the
+ * `nats: true` option, nats.go adds ServeNATS answering the requests of a
+ * NATS subject with Router.ServeMessage, and with `amqp: true`, amqp.go adds
+ * ServeAMQP doing the same for an AMQP RPC queue. These five are the only
+ * files that need a dependency outside the standard library. With the
+ * `staticJSON: true` option, json.go adds MarshalJSON and UnmarshalJSON
+ * methods encoding and decoding the structs of types.go without reflection.
+ * The `initialisms: true` option (or a list of initialisms) names fields and
+ * types like Go linters expect, ID rather than Id, and `splitNamespaces: true`
+ * moves the types named after each endpoint namespace to a file of their own,
+ * such as task_types.go.
+ * `maxValidationErrors: 20` stops validating a value at its 20th error, and
+ * `validationFailFast: true` at its first, so garbage input such as arrays of
+ * hundreds of invalid items is rejected cheaply with a short error list.
+ *
+ * Endpoints and types are generated in name order, and the files are run
+ * through gofmt unless the `format: false` option is set, so regenerating an
+ * unchanged contract gives the same bytes.
  */
 const support: TargetSupport = {
   supportedTypes: [...TYPE_KINDS],
@@ -31,7 +202,7 @@ const support: TargetSupport = {
   notes: [
     "Generates idiomatic Go code using standard library only",
     "Uses net/http for HTTP handling",
-    "Uses encoding/json for JSON marshaling",
+    "Uses encoding/json for JSON marshaling, or a Codec set with Router.SetCodec",
     "Validation uses net/mail for email, net/url for URLs, regexp for patterns",
   ],
 };
@@ -47,6 +218,80 @@ function getPackageName(options?: Record<string, unknown>): string {
   return "server";
 }
 
+function getInitialisms(options?: Record<string, unknown>): string[] {
+  if (options?.initialisms === true) {
+    return COMMON_INITIALISMS;
+  }
+  if (Array.isArray(options?.initialisms)) {
+    return options.initialisms.filter(
+      (initialism): initialism is string => typeof initialism === "string",
+    );
+  }
+  return [];
+}
+
+function getMetricsBackend(
+  options?: Record<string, unknown>,
+): "prometheus" | undefined {
+  return options?.metrics === "prometheus" ? "prometheus" : undefined;
+}
+
+// The number of errors validation stops at: 1 with the validationFailFast
+// option, or the maxValidationErrors option
+function getMaxValidationErrors(
+  options?: Record<string, unknown>,
+): number | undefined {
+  if (options?.validationFailFast === true) {
+    return 1;
+  }
+  const max = options?.maxValidationErrors;
+  return typeof max === "number" && Number.isInteger(max) && max > 0
+    ? max
+    : undefined;
+}
+
+function getJSONCodec(
+  options?: Record<string, unknown>,
+): JSONCodecBackend | undefined {
+  switch (options?.jsonCodec) {
+    case "sonic":
+    case "jsonv2":
+    case "easyjson":
+      return options.jsonCodec;
+    default:
+      return undefined;
+  }
+}
+
+// The proto package of the schema grpc.go serves, which must be the package
+// option of the proto target generating the .proto clients are built from
+function getGRPCPackage(options?: Record<string, unknown>): string {
+  const value = options?.grpcPackage;
+  return typeof value === "string" && value ? value : "xrpc";
+}
+
+// Compares by code points, unlike localeCompare, so the order is the same
+// whatever the locale of the machine generating
+function compareNames(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
+
+/**
+ * Orders the endpoints and types of the contract by name, so the generated
+ * files list them in the same order however the contract declares them and
+ * moving an endpoint in the contract does not change them. Fields keep their
+ * declared order, which is the order of their JSON.
+ */
+export function sortContract(contract: ContractDefinition): ContractDefinition {
+  return {
+    ...contract,
+    endpoints: [...contract.endpoints].sort((a, b) =>
+      compareNames(a.fullName, b.fullName),
+    ),
+    types: [...contract.types].sort((a, b) => compareNames(a.name, b.name)),
+  };
+}
+
 function isNullableType(typeRef: TypeReference): boolean {
   if (typeRef.kind === "nullable") {
     return true;
@@ -154,7 +399,7 @@ function collectRequiredNullableFields(contract: ContractDefinition): string[] {
 }
 
 function generateGoServer(input: TargetInput): TargetOutput {
-  const { contract } = input;
+  const contract = sortContract(input.contract);
   const diagnostics = validateSupport(contract, support, "go-server");
   const requiredNullableFields = collectRequiredNullableFields(contract);
   for (const field of requiredNullableFields) {
@@ -163,42 +408,492 @@ function generateGoServer(input: TargetInput): TargetOutput {
       message: `Field "${field}" is required and nullable. Go cannot distinguish missing values from null, so validation only runs when the value is present.`,
     });
   }
+  if (
+    contract.endpoints.some(
+      (endpoint) => endpoint.fullName === INTROSPECT_METHOD,
+    )
+  ) {
+    diagnostics.push({
+      severity: "error",
+      message: `Method "${INTROSPECT_METHOD}" is reserved for the built-in introspection method.`,
+    });
+  }
+  if (contract.endpoints.some((endpoint) => endpoint.async)) {
+    for (const method of [OPERATION_GET_METHOD, OPERATION_CANCEL_METHOD]) {
+      if (contract.endpoints.some((endpoint) => endpoint.fullName === method)) {
+        diagnostics.push({
+          severity: "error",
+          message: `Method "${method}" is reserved for the built-in method of async mutations.`,
+        });
+      }
+    }
+  }
   const hasErrors = diagnostics.some((issue) => issue.severity === "error");
   if (hasErrors) {
     return { files: [], diagnostics };
   }
 
   const packageName = getPackageName(input.options);
+  const jsonCodec = getJSONCodec(input.options);
+  const staticJSON = input.options?.staticJSON === true;
+  if (staticJSON && jsonCodec === "easyjson") {
+    diagnostics.push({
+      severity: "warning",
+      message:
+        "The staticJSON option generates MarshalJSON and UnmarshalJSON methods that conflict with those easyjson generates; use one or the other.",
+    });
+  }
+  const maxValidationErrors = getMaxValidationErrors(input.options);
+  if (
+    input.options?.maxValidationErrors !== undefined &&
+    maxValidationErrors === undefined
+  ) {
+    diagnostics.push({
+      severity: "warning",
+      message:
+        "The maxValidationErrors option must be a positive integer; validation reports every error.",
+    });
+  }
   const typeCollector = new GoTypeCollector();
   const collectedTypes = typeCollector.collectTypes(contract);
 
-  const typeGenerator = new GoTypeGenerator(packageName);
+  const typeGenerator = new GoTypeGenerator(
+    packageName,
+    input.options?.splitNamespaces === true,
+  );
+  const contextGenerator = new GoContextGenerator(packageName);
+  const authGenerator = new GoAuthGenerator(packageName);
+  const tlsGenerator = new GoTLSGenerator(packageName);
+  const injectGenerator = new GoInjectGenerator(packageName);
+  const tenantGenerator = new GoTenantGenerator(packageName);
+  const errorsGenerator = new GoErrorsGenerator(packageName);
   const serverGenerator = new GoServerGenerator(packageName);
-  const validationGenerator = new GoValidationGenerator(packageName);
+  const methodsGenerator = new GoMethodsGenerator(packageName);
+  const serviceGenerator = new GoServiceGenerator(packageName);
+  const bufferGenerator = new GoBufferGenerator(packageName);
+  const dispatchGenerator = new GoDispatchGenerator(packageName);
+  const mountGenerator = new GoMountGenerator(packageName);
+  const gateGenerator = new GoGateGenerator(packageName);
+  const eventsGenerator = new GoEventsGenerator(packageName);
+  const outboxGenerator = new GoOutboxGenerator(packageName);
+  const operationsGenerator = new GoOperationsGenerator(packageName);
+  const loggingGenerator = new GoLoggingGenerator(packageName);
+  const csrfGenerator = new GoCSRFGenerator(packageName);
+  const sessionGenerator = new GoSessionGenerator(packageName);
+  const compressionGenerator = new GoCompressionGenerator(packageName);
+  const singleFlightGenerator = new GoSingleFlightGenerator(packageName);
+  const cacheGenerator = new GoCacheGenerator(packageName);
+  const redisGenerator = new GoRedisGenerator(packageName);
+  const concurrencyGenerator = new GoConcurrencyGenerator(packageName);
+  const codecGenerator = new GoCodecGenerator(packageName);
+  const healthGenerator = new GoHealthGenerator(packageName);
+  const openapiGenerator = new GoOpenAPIGenerator(packageName);
+  const introspectGenerator = new GoIntrospectGenerator(packageName);
+  const compatGenerator = new GoCompatGenerator(packageName);
+  const playgroundGenerator = new GoPlaygroundGenerator(packageName);
+  const snippetsGenerator = new GoSnippetsGenerator(packageName);
+  const schemasGenerator = new GoSchemasGenerator(packageName);
+  const validationGenerator = new GoValidationGenerator(
+    packageName,
+    maxValidationErrors,
+  );
+  const testClientGenerator = new GoTestClientGenerator(packageName);
+  const clientGenerator = new GoClientGenerator(packageName);
+  const retryGenerator = new GoRetryGenerator(packageName);
+  const breakerGenerator = new GoBreakerGenerator(packageName);
+  const hedgingGenerator = new GoHedgingGenerator(packageName);
+  const transportGenerator = new GoTransportGenerator(packageName);
+  const cliGenerator = new GoCLIGenerator(packageName);
+  const customValidators = collectCustomValidators(contract, collectedTypes);
 
-  return {
-    files: [
-      {
-        path: "types.go",
-        content: typeGenerator.generateTypes(contract, collectedTypes),
-      },
-      {
-        path: "router.go",
-        content: serverGenerator.generateServer(contract),
-      },
-      {
-        path: "validation.go",
-        content: validationGenerator.generateValidation(
-          contract,
-          collectedTypes,
-        ),
-      },
-    ],
-    diagnostics,
-  };
+  // Types come first: methods decode inputs with the ApplyDefaults and
+  // Normalize methods of their structs
+  const typesContent = typeGenerator.generateTypes(contract, collectedTypes);
+  const defaultsGenerator = new GoDefaultsGenerator(packageName);
+  const defaults = defaultsGenerator.generateDefaults(
+    typeGenerator.getStructs(),
+    typeGenerator.getStructAliases(),
+  );
+  const normalizeGenerator = new GoNormalizeGenerator(packageName);
+  const normalize = normalizeGenerator.generateNormalize(
+    typeGenerator.getStructs(),
+    typeGenerator.getStructAliases(),
+  );
+  const coercionGenerator = new GoCoercionGenerator(packageName);
+  const coercion = coercionGenerator.generateCoercion(
+    typeGenerator.getStructs(),
+    typeGenerator.getStructAliases(),
+  );
+  const warningsGenerator = new GoWarningsGenerator(packageName);
+  const warnings = warningsGenerator.generateWarnings(
+    typeGenerator.getStructs(),
+    typeGenerator.getStructAliases(),
+  );
+
+  const files: GeneratedFile[] = [
+    {
+      path: "types.go",
+      content: typesContent,
+    },
+    ...Array.from(typeGenerator.getNamespaceFiles(), ([path, content]) => ({
+      path,
+      content,
+    })),
+    {
+      path: "context.go",
+      content: contextGenerator.generateContext(),
+    },
+    {
+      path: "auth.go",
+      content: authGenerator.generateAuth(),
+    },
+    {
+      path: "tls.go",
+      content: tlsGenerator.generateTLS(),
+    },
+    {
+      path: "inject.go",
+      content: injectGenerator.generateInject(),
+    },
+    {
+      path: "tenant.go",
+      content: tenantGenerator.generateTenant(),
+    },
+    {
+      path: "errors.go",
+      content: errorsGenerator.generateErrors(),
+    },
+    {
+      path: "router.go",
+      content: serverGenerator.generateServer(
+        contract,
+        customValidators.length > 0,
+        warnings !== undefined,
+      ),
+    },
+    {
+      path: "methods.go",
+      content: methodsGenerator.generateMethods(
+        contract,
+        normalizeGenerator.getNormalizable(),
+        defaultsGenerator.getDefaulted(),
+        warningsGenerator.getWarned(),
+        coercionGenerator.getCoerceFunctions(),
+      ),
+    },
+    {
+      path: "services.go",
+      content: serviceGenerator.generateServices(contract),
+    },
+    {
+      path: "buffers.go",
+      content: bufferGenerator.generateBuffers(),
+    },
+    {
+      path: "dispatch.go",
+      content: dispatchGenerator.generateDispatch(warnings !== undefined),
+    },
+    {
+      path: "mount.go",
+      content: mountGenerator.generateMount(
+        contract.endpoints.some((endpoint) => endpoint.http),
+      ),
+    },
+    {
+      path: "logging.go",
+      content: loggingGenerator.generateLogging(),
+    },
+    {
+      path: "csrf.go",
+      content: csrfGenerator.generateCSRF(),
+    },
+    {
+      path: "session.go",
+      content: sessionGenerator.generateSession(),
+    },
+    {
+      path: "compression.go",
+      content: compressionGenerator.generateCompression(),
+    },
+    {
+      path: "singleflight.go",
+      content: singleFlightGenerator.generateSingleFlight(),
+    },
+    {
+      path: "cache.go",
+      content: cacheGenerator.generateCache(),
+    },
+    {
+      path: "redis.go",
+      content: redisGenerator.generateRedis(),
+    },
+    {
+      path: "concurrency.go",
+      content: concurrencyGenerator.generateConcurrency(),
+    },
+    {
+      path: "gates.go",
+      content: gateGenerator.generateGates(),
+    },
+    {
+      path: "events.go",
+      content: eventsGenerator.generateEvents(contract),
+    },
+    {
+      path: "outbox.go",
+      content: outboxGenerator.generateOutbox(),
+    },
+    {
+      path: "operations.go",
+      content: operationsGenerator.generateOperations(),
+    },
+    {
+      path: "codec.go",
+      content: codecGenerator.generateCodec(staticJSON),
+    },
+    {
+      path: "coercion.go",
+      content: coercion,
+    },
+    {
+      path: "health.go",
+      content: healthGenerator.generateHealth(),
+    },
+    {
+      path: "openapi.go",
+      content: openapiGenerator.generateOpenAPI(contract),
+    },
+    {
+      path: "introspect.go",
+      content: introspectGenerator.generateIntrospect(contract),
+    },
+    {
+      path: "compat.go",
+      content: compatGenerator.generateCompat(),
+    },
+    {
+      path: "playground.go",
+      content: playgroundGenerator.generatePlayground(),
+    },
+    {
+      path: "snippets.go",
+      content: snippetsGenerator.generateSnippets(contract),
+    },
+    {
+      path: "schemas.go",
+      content: schemasGenerator.generateSchemas(contract),
+    },
+    {
+      path: "validation.go",
+      content: validationGenerator.generateValidation(
+        contract,
+        collectedTypes,
+      ),
+    },
+    {
+      path: "testclient.go",
+      content: testClientGenerator.generateTestClient(contract),
+    },
+    {
+      path: "client.go",
+      content: clientGenerator.generateClient(contract),
+    },
+    {
+      path: "retry.go",
+      content: retryGenerator.generateRetry(),
+    },
+    {
+      path: "breaker.go",
+      content: breakerGenerator.generateBreaker(),
+    },
+    {
+      path: "hedging.go",
+      content: hedgingGenerator.generateHedging(),
+    },
+    {
+      path: "transport.go",
+      content: transportGenerator.generateTransport(),
+    },
+    {
+      path: "cli.go",
+      content: cliGenerator.generateCLI(contract),
+    },
+  ];
+
+  const unionGenerator = new GoUnionGenerator(packageName);
+  const unions = unionGenerator.generateUnions(contract, collectedTypes);
+  if (unions) {
+    files.splice(1, 0, { path: "unions.go", content: unions });
+  }
+
+  const enumGenerator = new GoEnumGenerator(packageName);
+  const enums = enumGenerator.generateEnums(contract, collectedTypes);
+  if (enums) {
+    files.splice(1, 0, { path: "enums.go", content: enums });
+  }
+
+  const dateGenerator = new GoDateGenerator(packageName);
+  const dates = dateGenerator.generateDates(contract, collectedTypes);
+  if (dates) {
+    files.splice(1, 0, { path: "dates.go", content: dates });
+  }
+
+  if (staticJSON) {
+    const jsonGenerator = new GoJSONGenerator(packageName);
+    const types = files.findIndex((file) => file.path === "types.go");
+    files.splice(types + 1, 0, {
+      path: "json.go",
+      content: jsonGenerator.generateJSON(
+        typeGenerator.getStructs(),
+        typeGenerator.getStructAliases(),
+      ),
+    });
+  }
+
+  const redactGenerator = new GoRedactGenerator(packageName);
+  const redact = redactGenerator.generateRedact(
+    typeGenerator.getStructs(),
+    typeGenerator.getStructAliases(),
+  );
+  if (redact) {
+    const types = files.findIndex((file) => file.path === "types.go");
+    files.splice(types + 1, 0, { path: "redact.go", content: redact });
+  }
+  if (normalize) {
+    const types = files.findIndex((file) => file.path === "types.go");
+    files.splice(types + 1, 0, { path: "normalize.go", content: normalize });
+  }
+  if (defaults) {
+    const types = files.findIndex((file) => file.path === "types.go");
+    files.splice(types + 1, 0, { path: "defaults.go", content: defaults });
+  }
+  if (warnings) {
+    const validation = files.findIndex(
+      (file) => file.path === "validation.go",
+    );
+    files.splice(validation + 1, 0, {
+      path: "warnings.go",
+      content: warnings,
+    });
+  }
+
+  const uploadGenerator = new GoUploadGenerator(packageName);
+  const uploads = uploadGenerator.generateUploads(contract);
+  if (uploads) {
+    const codec = files.findIndex((file) => file.path === "codec.go");
+    files.splice(codec + 1, 0, { path: "uploads.go", content: uploads });
+  }
+
+  const restGenerator = new GoRESTGenerator(packageName);
+  const rest = restGenerator.generateREST(contract.endpoints);
+  if (rest) {
+    const router = files.findIndex((file) => file.path === "router.go");
+    files.splice(router + 1, 0, { path: "rest.go", content: rest });
+  }
+
+  const streamGenerator = new GoStreamGenerator(packageName);
+  const streams = streamGenerator.generateStreams(contract);
+  if (streams) {
+    const dispatch = files.findIndex((file) => file.path === "dispatch.go");
+    files.splice(dispatch + 1, 0, { path: "streams.go", content: streams });
+  }
+
+  const paginationGenerator = new GoPaginationGenerator(packageName);
+  const pagination = paginationGenerator.generatePagination(contract);
+  if (pagination) {
+    const dispatch = files.findIndex((file) => file.path === "dispatch.go");
+    files.splice(dispatch + 1, 0, {
+      path: "pagination.go",
+      content: pagination,
+    });
+  }
+
+  const validatorsGenerator = new GoValidatorsGenerator(packageName);
+  const validators = validatorsGenerator.generateValidators(customValidators);
+  if (validators) {
+    files.push({ path: "validators.go", content: validators });
+  }
+
+  const examplesGenerator = new GoExamplesGenerator(packageName);
+  const examples = examplesGenerator.generateExamples(contract);
+  if (examples) {
+    files.push({ path: "examples.go", content: examples });
+  }
+
+  const mockGenerator = new GoMockGenerator(packageName);
+  files.push({
+    path: "mock.go",
+    content: mockGenerator.generateMock(contract, customValidators.length > 0),
+  });
+
+  if (getMetricsBackend(input.options) === "prometheus") {
+    const metricsGenerator = new GoMetricsGenerator(packageName);
+    files.push({
+      path: "metrics.go",
+      content: metricsGenerator.generateMetrics(),
+    });
+  }
+
+  if (jsonCodec) {
+    const jsonCodecGenerator = new GoJSONCodecGenerator(packageName);
+    files.push({
+      path: "jsoncodec.go",
+      content: jsonCodecGenerator.generateJSONCodec(jsonCodec),
+    });
+  }
+
+  if (input.options?.nats === true) {
+    const natsGenerator = new GoNATSGenerator(packageName);
+    files.push({ path: "nats.go", content: natsGenerator.generateNATS() });
+  }
+
+  if (input.options?.amqp === true) {
+    const amqpGenerator = new GoAMQPGenerator(packageName);
+    files.push({ path: "amqp.go", content: amqpGenerator.generateAMQP() });
+  }
+
+  if (input.options?.grpc === true) {
+    const grpcGenerator = new GoGRPCGenerator(packageName);
+    files.push({
+      path: "grpc.go",
+      content: grpcGenerator.generateGRPC(
+        contract,
+        getGRPCPackage(input.options),
+      ),
+    });
+  }
+
+  const validationTests = validationGenerator.generateValidationTests();
+  if (validationTests) {
+    files.push({ path: "validation_test.go", content: validationTests });
+  }
+
+  const routerTestGenerator = new GoRouterTestGenerator(packageName);
+  files.push({
+    path: "router_test.go",
+    content: routerTestGenerator.generateRouterTests(
+      contract,
+      customValidators.length > 0,
+    ),
+  });
+
+  const routerBenchmarks = new GoRouterBenchGenerator(
+    packageName,
+  ).generateRouterBenchmarks(contract);
+  if (routerBenchmarks) {
+    files.push({ path: "router_bench_test.go", content: routerBenchmarks });
+  }
+
+  if (input.options?.format === false) {
+    return { files, diagnostics };
+  }
+  return { files: formatGoFiles(files, diagnostics), diagnostics };
 }
 
 export const goTarget: Target = {
   name: "go-server",
-  generate: generateGoServer,
+  generate: (input) =>
+    withInitialisms(getInitialisms(input.options), () =>
+      generateGoServer(input),
+    ),
 };
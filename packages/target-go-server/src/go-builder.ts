@@ -1,9 +1,15 @@
 import { CodeWriter } from "@xrpckit/sdk";
 
 /**
- * Go-specific code builder with fluent DSL for common Go patterns
+ * Go-specific code builder with fluent DSL for common Go patterns. It indents
+ * with tabs like gofmt, which the generated files are run through to align
+ * them.
  */
 export class GoBuilder extends CodeWriter {
+  constructor() {
+    super("\t");
+  }
+
   // Short aliases
   l(text: string): this {
     return this.writeLine(text);
@@ -39,10 +45,12 @@ export class GoBuilder extends CodeWriter {
   }
 
   type(name: string, typeDef: string): this {
+    this.attachDocComment();
     return this.l(`type ${name} ${typeDef}`).n();
   }
 
   struct(name: string, fn: (b: this) => void): this {
+    this.attachDocComment();
     this.l(`type ${name} struct {`).i();
     fn(this);
     return this.u().l("}").n();
@@ -56,20 +64,30 @@ export class GoBuilder extends CodeWriter {
   }
 
   func(signature: string, fn?: (b: this) => void): this {
+    this.attachDocComment();
     if (fn) {
-      this.write(`func ${signature} {`);
-      this.indent();
+      this.l(`func ${signature} {`).i();
       fn(this);
-      this.unindent();
-      this.writeLine("}");
-      this.newLine();
+      this.u().l("}").n();
     } else {
-      this.l(`func ${signature}`);
-      this.n();
+      this.l(`func ${signature}`).n();
     }
     return this;
   }
 
+  // Drops the blank line n() leaves between a doc comment and the declaration
+  // it documents, which would detach the comment
+  private attachDocComment(): void {
+    const count = this.lines.length;
+    if (
+      count >= 2 &&
+      this.lines[count - 1] === "" &&
+      this.lines[count - 2].trimStart().startsWith("//")
+    ) {
+      this.lines.pop();
+    }
+  }
+
   method(
     receiver: string,
     name: string,
@@ -138,6 +156,30 @@ export class GoBuilder extends CodeWriter {
     return this.l(`// ${text}`);
   }
 
+  /**
+   * Write text, such as a description from the contract, as comment lines
+   * wrapped at 80 columns. Its blank lines separate paragraphs.
+   */
+  doc(text: string): this {
+    const paragraphs = text.trim().split(/\n\s*\n/);
+    paragraphs.forEach((paragraph, index) => {
+      if (index > 0) {
+        this.l("//");
+      }
+      let line = "";
+      for (const word of paragraph.split(/\s+/)) {
+        if (line && line.length + word.length >= 77) {
+          this.comment(line);
+          line = word;
+        } else {
+          line = line ? `${line} ${word}` : word;
+        }
+      }
+      this.comment(line);
+    });
+    return this;
+  }
+
   blockComment(lines: string[]): this {
     this.l("/*");
     for (const line of lines) {
@@ -146,3 +188,12 @@ export class GoBuilder extends CodeWriter {
     return this.l(" */");
   }
 }
+
+/**
+ * Quote a string as a Go literal, preferring a raw string so embedded JSON
+ * stays readable. Raw strings cannot contain backticks, so those fall back
+ * to an interpreted string.
+ */
+export function goStringLiteral(value: string): string {
+  return value.includes("`") ? JSON.stringify(value) : `\`${value}\``;
+}
@@ -0,0 +1,1368 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import {
+  type ProtoFieldModel,
+  ProtoGenerator,
+  type ProtoSchema,
+} from "@xrpckit/target-proto";
+import { GoBuilder } from "./go-builder";
+
+const SCALAR_TYPES: Record<string, string> = {
+  string: "grpcString",
+  int64: "grpcInt64",
+  double: "grpcDouble",
+  bool: "grpcBool",
+  bytes: "grpcBytes",
+};
+
+/**
+ * The name protoc gives the entry message of a map field: the field name in
+ * CamelCase followed by "Entry".
+ */
+function mapEntryName(fieldName: string): string {
+  const camel = fieldName.replace(/(^|_)([a-z0-9])/g, (_, __, c: string) =>
+    c.toUpperCase(),
+  );
+  return `${camel}Entry`;
+}
+
+/**
+ * Generates grpc.go: RegisterGRPC, serving the methods of the router over
+ * gRPC with the services and messages of the schema the proto target
+ * generates. Only emitted with the `grpc: true` option, since like metrics.go
+ * it depends on modules outside the standard library.
+ */
+export class GoGRPCGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateGRPC(contract: ContractDefinition, protoPackage = "xrpc"): string {
+    const w = this.w.reset();
+    const schema = new ProtoGenerator().build(contract, {
+      package: protoPackage,
+    });
+    const async = new Set(
+      contract.endpoints
+        .filter((endpoint) => endpoint.async)
+        .map((endpoint) => endpoint.fullName),
+    );
+
+    w.package(this.packageName).import(
+      "bytes",
+      "context",
+      "encoding/base64",
+      "encoding/json",
+      "fmt",
+      "net/http",
+      "strings",
+      "google.golang.org/grpc",
+      "google.golang.org/grpc/codes",
+      "google.golang.org/grpc/metadata",
+      "google.golang.org/grpc/peer",
+      "google.golang.org/grpc/status",
+      "google.golang.org/protobuf/encoding/protojson",
+      "google.golang.org/protobuf/proto",
+      "google.golang.org/protobuf/reflect/protodesc",
+      "google.golang.org/protobuf/reflect/protoreflect",
+      "google.golang.org/protobuf/reflect/protoregistry",
+      "google.golang.org/protobuf/types/descriptorpb",
+      "google.golang.org/protobuf/types/dynamicpb",
+      "google.golang.org/protobuf/types/known/structpb",
+      "google.golang.org/protobuf/types/known/timestamppb",
+    );
+
+    this.generateRegister(w);
+    this.generateCall(w);
+    this.generateWriters(w);
+    this.generateConversion(w);
+    this.generateServices(w, schema, async);
+    this.generateShapes(w, schema);
+    this.generateDescriptorHelpers(w);
+    this.generateFile(w, schema);
+
+    return w.toString();
+  }
+
+  private generateRegister(w: GoBuilder): void {
+    w.comment(
+      "grpcService is a service of the schema and the rpcs RegisterGRPC serves.",
+    )
+      .struct("grpcService", (b) => {
+        b.l("name string").l("rpcs []grpcRPC");
+      });
+
+    w.comment(
+      "grpcRPC is an rpc and the method it calls; stream is set for subscriptions,",
+    )
+      .comment("which are server-streaming rpcs.")
+      .struct("grpcRPC", (b) => {
+        b.l("name   string").l("method string").l("stream bool");
+      });
+
+    w.comment(
+      "grpcShape tells how a message stands for a value of the contract that is",
+    )
+      .comment(
+        'not an object: "value" messages wrap it in their single field, "tuple"',
+      )
+      .comment(
+        'messages have a field per element, and "union" messages a oneof with a',
+      )
+      .comment(
+        "field per variant, which discriminated unions choose by the variants map",
+      )
+      .comment("from discriminator values to field names.")
+      .struct("grpcShape", (b) => {
+        b.l("kind          string")
+          .l("discriminator string")
+          .l("variants      map[string]protoreflect.Name");
+      });
+
+    w.comment(
+      "grpcCallKey marks the requests RegisterGRPC serves gRPC calls with.",
+    )
+      .type("grpcCallKey", "struct{}");
+
+    w.comment(
+      "IsGRPCRequest reports whether req stands for a call that came over gRPC.",
+    )
+      .comment(
+        "gRPC clients are not browsers, so routers protected with SetCSRF can exempt",
+      )
+      .comment("the calls with CSRFOptions{Exempt: IsGRPCRequest}.")
+      .n()
+      .func("IsGRPCRequest(req *http.Request) bool", (b) => {
+        b.return("req.Context().Value(grpcCallKey{}) != nil");
+      });
+
+    w.comment(
+      "RegisterGRPC serves the methods of router on server as the services of the",
+    )
+      .comment(
+        "schema the proto target generates for the contract, so gRPC clients",
+      )
+      .comment(
+        "generated from it can call them. Calls go through router.ServeHTTP, so the",
+      )
+      .comment(
+        "middleware, interceptors, validation and Logger apply as for HTTP calls:",
+      )
+      .comment(
+        "the params are the JSON of the request message, the incoming metadata",
+      )
+      .comment(
+        "becomes the headers (authorization included) and the peer address the",
+      )
+      .comment(
+        "RemoteAddr. Subscriptions are server-streaming rpcs sending a message per",
+      )
+      .comment(
+        "event. Errors become the status whose code is closest to theirs, with the",
+      )
+      .comment("error's JSON in the xrpc-error-bin trailer.")
+      .n()
+      .func(
+        "RegisterGRPC(server grpc.ServiceRegistrar, router *Router) error",
+        (b) => {
+          b.l("var deps protoregistry.Files")
+            .l("for _, dep := range []protoreflect.FileDescriptor{")
+            .i()
+            .l("timestamppb.File_google_protobuf_timestamp_proto,")
+            .l("structpb.File_google_protobuf_struct_proto,")
+            .u()
+            .l("} {")
+            .i()
+            .if("err := deps.RegisterFile(dep); err != nil", (b) => {
+              b.return("err");
+            })
+            .u()
+            .l("}")
+            .decl("file, err", "protodesc.NewFile(grpcFile(), &deps)")
+            .ifErr((b) => {
+              b.return('fmt.Errorf("xrpc: building the gRPC schema: %w", err)');
+            })
+            .l("for _, service := range grpcServices {")
+            .i()
+            .decl(
+              "sd",
+              "file.Services().ByName(protoreflect.Name(service.name))",
+            )
+            .l("desc := &grpc.ServiceDesc{")
+            .i()
+            .l("ServiceName: string(sd.FullName()),")
+            .l("HandlerType: (*interface{})(nil),")
+            .l("Metadata:    file.Path(),")
+            .u()
+            .l("}")
+            .l("for _, rpc := range service.rpcs {")
+            .i()
+            .decl("md", "sd.Methods().ByName(protoreflect.Name(rpc.name))")
+            .l("call := &grpcCall{")
+            .i()
+            .l("router:     router,")
+            .l("method:     rpc.method,")
+            .l('fullMethod: fmt.Sprintf("/%s/%s", sd.FullName(), md.Name()),')
+            .l("input:      md.Input(),")
+            .l("output:     md.Output(),")
+            .u()
+            .l("}")
+            .l("if rpc.stream {")
+            .i()
+            .l("desc.Streams = append(desc.Streams, grpc.StreamDesc{")
+            .i()
+            .l("StreamName:    rpc.name,")
+            .l("Handler:       call.serveStream,")
+            .l("ServerStreams: true,")
+            .u()
+            .l("})")
+            .u()
+            .l("} else {")
+            .i()
+            .l("desc.Methods = append(desc.Methods, grpc.MethodDesc{")
+            .i()
+            .l("MethodName: rpc.name,")
+            .l("Handler:    call.serveUnary,")
+            .u()
+            .l("})")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .l("server.RegisterService(desc, router)")
+            .u()
+            .l("}")
+            .return("nil");
+        },
+      );
+  }
+
+  private generateCall(w: GoBuilder): void {
+    w.comment(
+      "grpcCall serves an rpc by calling the method it stands for on the router.",
+    )
+      .struct("grpcCall", (b) => {
+        b.l("router     *Router")
+          .l("method     string")
+          .l("fullMethod string")
+          .l("input      protoreflect.MessageDescriptor")
+          .l("output     protoreflect.MessageDescriptor");
+      });
+
+    w.comment(
+      "serveUnary is the handler of the rpc of a query or mutation, running the",
+    )
+      .comment("server's unary interceptors around the call.")
+      .n()
+      .method(
+        "c *grpcCall",
+        "serveUnary",
+        "srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor",
+        "(interface{}, error)",
+        (b) => {
+          b.decl("in", "dynamicpb.NewMessage(c.input)")
+            .if("err := dec(in); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .l(
+              "handler := func(ctx context.Context, in interface{}) (interface{}, error) {",
+            )
+            .i()
+            .decl("req, err", "c.request(ctx, in.(proto.Message))")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .decl("rec", "&grpcRecorder{header: http.Header{}}")
+            .l("c.router.ServeHTTP(rec, req)")
+            .decl("out, trailer, err", "c.result(rec.body.Bytes())")
+            .if("trailer != nil", (b) => {
+              b.l("grpc.SetTrailer(ctx, trailer)");
+            })
+            .return("out, err")
+            .u()
+            .l("}")
+            .if("interceptor == nil", (b) => {
+              b.return("handler(ctx, in)");
+            })
+            .return(
+              "interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: c.fullMethod}, handler)",
+            );
+        },
+      );
+
+    w.comment(
+      "serveStream is the handler of the server-streaming rpc of a subscription.",
+    )
+      .n()
+      .method(
+        "c *grpcCall",
+        "serveStream",
+        "srv interface{}, stream grpc.ServerStream",
+        "error",
+        (b) => {
+          b.decl("in", "dynamicpb.NewMessage(c.input)")
+            .if("err := stream.RecvMsg(in); err != nil", (b) => {
+              b.return("err");
+            })
+            .decl("req, err", "c.request(stream.Context(), in)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .decl(
+              "w",
+              "&grpcStreamWriter{call: c, stream: stream, header: http.Header{}}",
+            )
+            .l("c.router.ServeHTTP(w, req)")
+            .return("w.finish()");
+        },
+      );
+
+    w.comment(
+      'request builds the POST the router answers a call for: the {"method",',
+    )
+      .comment(
+        '"params"} envelope with in converted to the JSON of the contract, and the',
+      )
+      .comment("incoming metadata as headers.")
+      .n()
+      .method(
+        "c *grpcCall",
+        "request",
+        "ctx context.Context, in proto.Message",
+        "(*http.Request, error)",
+        (b) => {
+          b.decl("params, err", "json.Marshal(grpcEncode(in.ProtoReflect()))")
+            .ifErr((b) => {
+              b.return(
+                'nil, status.Errorf(codes.InvalidArgument, "converting the params of %s: %v", c.method, err)',
+              );
+            })
+            .l("body, err := json.Marshal(struct {")
+            .i()
+            .l('Method string          `json:"method"`')
+            .l('Params json.RawMessage `json:"params"`')
+            .u()
+            .l("}{c.method, params})")
+            .ifErr((b) => {
+              b.return("nil, status.Error(codes.Internal, err.Error())");
+            })
+            .decl(
+              "req, err",
+              'http.NewRequestWithContext(context.WithValue(ctx, grpcCallKey{}, c.fullMethod), http.MethodPost, "/", bytes.NewReader(body))',
+            )
+            .ifErr((b) => {
+              b.return("nil, status.Error(codes.Internal, err.Error())");
+            })
+            .decl("md, _", "metadata.FromIncomingContext(ctx)")
+            .l("for key, values := range md {")
+            .i()
+            .l("switch {")
+            .l('case key == ":authority":')
+            .i()
+            .l('req.Host = strings.Join(values, "")')
+            .u()
+            .l(
+              'case strings.HasPrefix(key, ":"), strings.HasPrefix(key, "grpc-"), strings.HasSuffix(key, "-bin"):',
+            )
+            .i()
+            .comment(
+              "Pseudo-headers, gRPC's own metadata and binary values are not",
+            )
+            .comment("headers of the call")
+            .u()
+            .l(
+              'case key == "content-type", key == "accept", key == "accept-encoding", key == "te":',
+            )
+            .i()
+            .comment(
+              "The router answers with uncompressed JSON, whatever the gRPC",
+            )
+            .comment("transport negotiated")
+            .u()
+            .l("default:")
+            .i()
+            .l("for _, value := range values {")
+            .i()
+            .l("req.Header.Add(key, value)")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .l('req.Header.Set("Content-Type", "application/json")')
+            .if("p, ok := peer.FromContext(ctx); ok && p.Addr != nil", (b) => {
+              b.l("req.RemoteAddr = p.Addr.String()");
+            })
+            .return("req, nil");
+        },
+      );
+
+    w.comment(
+      'result converts the {"result"} or {"error"} envelope the router answered',
+    )
+      .comment(
+        "with to the output message, or to the status and trailer of the error.",
+      )
+      .n()
+      .method(
+        "c *grpcCall",
+        "result",
+        "data []byte",
+        "(proto.Message, metadata.MD, error)",
+        (b) => {
+          b.l("var envelope struct {")
+            .i()
+            .l('Result json.RawMessage  `json:"result"`')
+            .l('Error  *json.RawMessage `json:"error"`')
+            .u()
+            .l("}")
+            .if("err := json.Unmarshal(data, &envelope); err != nil", (b) => {
+              b.return(
+                'nil, nil, status.Errorf(codes.Internal, "decoding the response of %s: %v", c.method, err)',
+              );
+            })
+            .if("envelope.Error != nil", (b) => {
+              b.l("var rpcErr Error")
+                .if(
+                  "err := json.Unmarshal(*envelope.Error, &rpcErr); err != nil",
+                  (b) => {
+                    b.return(
+                      'nil, nil, status.Errorf(codes.Internal, "decoding the error of %s: %v", c.method, err)',
+                    );
+                  },
+                )
+                .decl("code, ok", "grpcCodes[rpcErr.Code]")
+                .if("!ok", (b) => {
+                  b.l("code = codes.Unknown");
+                })
+                .return(
+                  'nil, metadata.Pairs("xrpc-error-bin", string(*envelope.Error)), status.Error(code, rpcErr.Message)',
+                );
+            })
+            .decl("out", "dynamicpb.NewMessage(c.output)")
+            .if("err := grpcDecode(out, envelope.Result); err != nil", (b) => {
+              b.return(
+                'nil, nil, status.Errorf(codes.Internal, "converting the result of %s: %v", c.method, err)',
+              );
+            })
+            .return("out, nil, nil");
+        },
+      );
+
+    w.comment(
+      "grpcCodes maps the codes of errors to the closest gRPC status codes.",
+    )
+      .l("var grpcCodes = map[ErrorCode]codes.Code{")
+      .i()
+      .l("CodeInvalidArgument:   codes.InvalidArgument,")
+      .l("CodeUnauthorized:      codes.Unauthenticated,")
+      .l("CodePermissionDenied:  codes.PermissionDenied,")
+      .l("CodeNotFound:          codes.NotFound,")
+      .l("CodeMethodNotFound:    codes.Unimplemented,")
+      .l("CodeMethodNotAllowed:  codes.Unimplemented,")
+      .l("CodeAlreadyExists:     codes.AlreadyExists,")
+      .l("CodeResourceExhausted: codes.ResourceExhausted,")
+      .l("CodeUnimplemented:     codes.Unimplemented,")
+      .l("CodeUnavailable:       codes.Unavailable,")
+      .l("CodeDeadlineExceeded:  codes.DeadlineExceeded,")
+      .l("CodeInternal:          codes.Internal,")
+      .u()
+      .l("}")
+      .n();
+  }
+
+  private generateWriters(w: GoBuilder): void {
+    w.comment(
+      "grpcRecorder buffers the response the router writes for a unary call.",
+    )
+      .struct("grpcRecorder", (b) => {
+        b.l("header http.Header").l("body   bytes.Buffer");
+      });
+
+    w.method("r *grpcRecorder", "Header", "", "http.Header", (b) => {
+      b.return("r.header");
+    });
+
+    w.method("r *grpcRecorder", "Write", "p []byte", "(int, error)", (b) => {
+      b.return("r.body.Write(p)");
+    });
+
+    w.comment(
+      "WriteHeader ignores the status, since the envelope tells errors apart.",
+    )
+      .l("func (r *grpcRecorder) WriteHeader(status int) {}")
+      .n();
+
+    w.comment(
+      "grpcStreamWriter sends the Server-Sent Events the router writes for a",
+    )
+      .comment(
+        "subscription as the messages of a server stream, each when it is flushed.",
+      )
+      .struct("grpcStreamWriter", (b) => {
+        b.l("call   *grpcCall")
+          .l("stream grpc.ServerStream")
+          .l("header http.Header")
+          .l("body   bytes.Buffer")
+          .comment(
+            "err is the error event or the failure to send that ended the stream",
+          )
+          .l("err error");
+      });
+
+    w.method("w *grpcStreamWriter", "Header", "", "http.Header", (b) => {
+      b.return("w.header");
+    });
+
+    w.method(
+      "w *grpcStreamWriter",
+      "Write",
+      "p []byte",
+      "(int, error)",
+      (b) => {
+        b.if("w.err != nil", (b) => {
+          b.return("0, w.err");
+        })
+          .return("w.body.Write(p)");
+      },
+    );
+
+    w.l("func (w *grpcStreamWriter) WriteHeader(status int) {}").n();
+
+    w.comment(
+      "streaming reports whether the router started the event stream, rather than",
+    )
+      .comment("rejecting the call with an error envelope.")
+      .n()
+      .method("w *grpcStreamWriter", "streaming", "", "bool", (b) => {
+        b.return(
+          'strings.HasPrefix(w.header.Get("Content-Type"), "text/event-stream")',
+        );
+      });
+
+    w.comment("Flush sends the events written since the last flush.")
+      .n()
+      .method("w *grpcStreamWriter", "Flush", "", "", (b) => {
+        b.if("!w.streaming()", (b) => {
+          b.return();
+        })
+          .l("for w.err == nil {")
+          .i()
+          .decl("end", 'bytes.Index(w.body.Bytes(), []byte("\\n\\n"))')
+          .if("end < 0", (b) => {
+            b.return();
+          })
+          .decl("event", "string(w.body.Next(end + 2))")
+          .l("w.err = w.send(event)")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "send sends the message of an event, or returns the error of an error event.",
+    )
+      .n()
+      .method("w *grpcStreamWriter", "send", "event string", "error", (b) => {
+        b.l("var name, data string")
+          .l('for _, line := range strings.Split(event, "\\n") {')
+          .i()
+          .l('if value, ok := strings.CutPrefix(line, "event: "); ok {')
+          .i()
+          .l("name = value")
+          .u()
+          .l('} else if value, ok := strings.CutPrefix(line, "data: "); ok {')
+          .i()
+          .l("data = value")
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .if('name == "error"', (b) => {
+            b.decl("_, trailer, err", "w.call.result([]byte(data))")
+              .l("w.stream.SetTrailer(trailer)")
+              .return("err");
+          })
+          .decl("out", "dynamicpb.NewMessage(w.call.output)")
+          .if(
+            "err := grpcDecode(out, json.RawMessage(data)); err != nil",
+            (b) => {
+              b.return(
+                'status.Errorf(codes.Internal, "converting an event of %s: %v", w.call.method, err)',
+              );
+            },
+          )
+          .return("w.stream.SendMsg(out)");
+      });
+
+    w.comment(
+      "finish returns the error the stream ended with, or the one the router",
+    )
+      .comment("rejected the call with before it started.")
+      .n()
+      .method("w *grpcStreamWriter", "finish", "", "error", (b) => {
+        b.if("w.err != nil || w.streaming()", (b) => {
+          b.return("w.err");
+        })
+          .decl("_, trailer, err", "w.call.result(w.body.Bytes())")
+          .l("w.stream.SetTrailer(trailer)")
+          .return("err");
+      });
+  }
+
+  private generateConversion(w: GoBuilder): void {
+    w.comment(
+      "grpcEncode converts m to the value it stands for in the JSON of the",
+    )
+      .comment("contract.")
+      .n()
+      .func("grpcEncode(m protoreflect.Message) interface{}", (b) => {
+        b.decl("desc", "m.Descriptor()")
+          .decl("fields", "desc.Fields()")
+          .l("switch desc.FullName() {")
+          .l('case "google.protobuf.Timestamp", "google.protobuf.Value":')
+          .i()
+          .decl("data, err", "protojson.Marshal(m.Interface())")
+          .ifErr((b) => {
+            b.return("nil");
+          })
+          .return("json.RawMessage(data)")
+          .u()
+          .l("}")
+          .decl("shape", "grpcShapes[desc.FullName()]")
+          .l("switch shape.kind {")
+          .l('case "value":')
+          .i()
+          .return("grpcEncodeField(m, fields.Get(0))")
+          .u()
+          .l('case "tuple":')
+          .i()
+          .decl("items", "make([]interface{}, fields.Len())")
+          .l("for i := range items {")
+          .i()
+          .l("items[i] = grpcEncodeField(m, fields.Get(i))")
+          .u()
+          .l("}")
+          .return("items")
+          .u()
+          .l('case "union":')
+          .i()
+          .decl("field", "m.WhichOneof(desc.Oneofs().Get(0))")
+          .if("field == nil", (b) => {
+            b.return("nil");
+          })
+          .decl("value", "grpcEncodeField(m, field)")
+          .comment(
+            "The oneof field set decides the variant, whatever the discriminator",
+          )
+          .comment("field of the message says")
+          .if(
+            'object, ok := value.(map[string]interface{}); ok && shape.discriminator != ""',
+            (b) => {
+              b.l("for tag, name := range shape.variants {")
+                .i()
+                .if("name == field.Name()", (b) => {
+                  b.l("object[shape.discriminator] = tag");
+                })
+                .u()
+                .l("}");
+            },
+          )
+          .return("value")
+          .u()
+          .l("}")
+          .decl("object", "make(map[string]interface{}, fields.Len())")
+          .l("for i := 0; i < fields.Len(); i++ {")
+          .i()
+          .decl("field", "fields.Get(i)")
+          .if("field.HasPresence() && !m.Has(field)", (b) => {
+            b.l("continue");
+          })
+          .l("object[field.JSONName()] = grpcEncodeField(m, field)")
+          .u()
+          .l("}")
+          .return("object");
+      });
+
+    w.comment(
+      "grpcEncodeField converts the value of field in m, nil when it is unset.",
+    )
+      .n()
+      .func(
+        "grpcEncodeField(m protoreflect.Message, field protoreflect.FieldDescriptor) interface{}",
+        (b) => {
+          b.if("field.HasPresence() && !m.Has(field)", (b) => {
+            b.return("nil");
+          })
+            .decl("value", "m.Get(field)")
+            .l("switch {")
+            .l("case field.IsList():")
+            .i()
+            .decl("list", "value.List()")
+            .decl("items", "make([]interface{}, list.Len())")
+            .l("for i := range items {")
+            .i()
+            .l("items[i] = grpcEncodeValue(field, list.Get(i))")
+            .u()
+            .l("}")
+            .return("items")
+            .u()
+            .l("case field.IsMap():")
+            .i()
+            .decl("object", "make(map[string]interface{}, value.Map().Len())")
+            .l(
+              "value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {",
+            )
+            .i()
+            .l(
+              "object[key.String()] = grpcEncodeValue(field.MapValue(), value)",
+            )
+            .return("true")
+            .u()
+            .l("})")
+            .return("object")
+            .u()
+            .l("}")
+            .return("grpcEncodeValue(field, value)");
+        },
+      );
+
+    w.comment(
+      "grpcEncodeValue converts a value of field, or an item or map value of it.",
+    )
+      .n()
+      .func(
+        "grpcEncodeValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{}",
+        (b) => {
+          b.l("switch field.Kind() {")
+            .l("case protoreflect.MessageKind:")
+            .i()
+            .return("grpcEncode(value.Message())")
+            .u()
+            .l("case protoreflect.EnumKind:")
+            .i()
+            .decl("values", "grpcEnumValues[field.Enum().FullName()]")
+            .if("n := int(value.Enum()); n > 0 && n <= len(values)", (b) => {
+              b.return("values[n-1]");
+            })
+            .comment("UNSPECIFIED stands for no value")
+            .return("nil")
+            .u()
+            .l("}")
+            .return("value.Interface()");
+        },
+      );
+
+    w.comment(
+      "grpcDecode sets the fields of m from data, JSON of the contract.",
+    )
+      .n()
+      .func(
+        "grpcDecode(m protoreflect.Message, data json.RawMessage) error",
+        (b) => {
+          b.decl("decoder", "json.NewDecoder(bytes.NewReader(data))")
+            .l("decoder.UseNumber()")
+            .l("var value interface{}")
+            .if("err := decoder.Decode(&value); err != nil", (b) => {
+              b.return("err");
+            })
+            .return("grpcSet(m, value)");
+        },
+      );
+
+    w.comment(
+      "grpcSet sets the fields of m from value, decoded JSON with its numbers as",
+    )
+      .comment("json.Number.")
+      .n()
+      .func("grpcSet(m protoreflect.Message, value interface{}) error", (b) => {
+        b.decl("desc", "m.Descriptor()")
+          .decl("fields", "desc.Fields()")
+          .l("switch desc.FullName() {")
+          .l('case "google.protobuf.Timestamp", "google.protobuf.Value":')
+          .i()
+          .decl("data, err", "json.Marshal(value)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .return("protojson.Unmarshal(data, m.Interface())")
+          .u()
+          .l("}")
+          .decl("shape", "grpcShapes[desc.FullName()]")
+          .l("switch shape.kind {")
+          .l('case "value":')
+          .i()
+          .return("grpcSetField(m, fields.Get(0), value)")
+          .u()
+          .l('case "tuple":')
+          .i()
+          .decl("items, ok", "value.([]interface{})")
+          .if("!ok", (b) => {
+            b.return('fmt.Errorf("expected an array, got %T", value)');
+          })
+          .l("for i, item := range items {")
+          .i()
+          .if("i >= fields.Len() || item == nil", (b) => {
+            b.l("continue");
+          })
+          .if(
+            "err := grpcSetField(m, fields.Get(i), item); err != nil",
+            (b) => {
+              b.return('fmt.Errorf("%d: %w", i, err)');
+            },
+          )
+          .u()
+          .l("}")
+          .return("nil")
+          .u()
+          .l('case "union":')
+          .i()
+          .if("value == nil", (b) => {
+            b.return("nil");
+          })
+          .decl("variants", "desc.Oneofs().Get(0).Fields()")
+          .if('shape.discriminator != ""', (b) => {
+            b.decl("object, _", "value.(map[string]interface{})")
+              .decl("tag", "fmt.Sprint(object[shape.discriminator])")
+              .decl("field", "variants.ByName(shape.variants[tag])")
+              .if("field == nil", (b) => {
+                b.return(
+                  'fmt.Errorf("unknown %s %q", shape.discriminator, tag)',
+                );
+              })
+              .return("grpcSetField(m, field, value)");
+          })
+          .comment(
+            "Undiscriminated unions take the first variant the value converts to",
+          )
+          .l("for i := 0; i < variants.Len(); i++ {")
+          .i()
+          .decl("field", "variants.Get(i)")
+          .if(
+            "v, err := grpcValue(field, m.NewField(field), value); err == nil",
+            (b) => {
+              b.l("m.Set(field, v)").return("nil");
+            },
+          )
+          .u()
+          .l("}")
+          .return('fmt.Errorf("no variant of %s fits %T", desc.Name(), value)')
+          .u()
+          .l("}")
+          .decl("object, ok", "value.(map[string]interface{})")
+          .if("!ok", (b) => {
+            b.return('fmt.Errorf("expected an object, got %T", value)');
+          })
+          .l("for i := 0; i < fields.Len(); i++ {")
+          .i()
+          .decl("field", "fields.Get(i)")
+          .decl("item, ok", "object[field.JSONName()]")
+          .if("!ok || item == nil", (b) => {
+            b.l("continue");
+          })
+          .if("err := grpcSetField(m, field, item); err != nil", (b) => {
+            b.return('fmt.Errorf("%s: %w", field.JSONName(), err)');
+          })
+          .u()
+          .l("}")
+          .return("nil");
+      });
+
+    w.comment(
+      "grpcSetField sets field in m from value, appending the items of lists and",
+    )
+      .comment("the entries of maps.")
+      .n()
+      .func(
+        "grpcSetField(m protoreflect.Message, field protoreflect.FieldDescriptor, value interface{}) error",
+        (b) => {
+          b.l("switch {")
+            .l("case field.IsList():")
+            .i()
+            .decl("items, ok", "value.([]interface{})")
+            .if("!ok", (b) => {
+              b.return('fmt.Errorf("expected an array, got %T", value)');
+            })
+            .decl("list", "m.Mutable(field).List()")
+            .l("for i, item := range items {")
+            .i()
+            .decl("v, err", "grpcValue(field, list.NewElement(), item)")
+            .ifErr((b) => {
+              b.return('fmt.Errorf("%d: %w", i, err)');
+            })
+            .l("list.Append(v)")
+            .u()
+            .l("}")
+            .return("nil")
+            .u()
+            .l("case field.IsMap():")
+            .i()
+            .decl("object, ok", "value.(map[string]interface{})")
+            .if("!ok", (b) => {
+              b.return('fmt.Errorf("expected an object, got %T", value)');
+            })
+            .decl("entries", "m.Mutable(field).Map()")
+            .l("for key, item := range object {")
+            .i()
+            .decl(
+              "v, err",
+              "grpcValue(field.MapValue(), entries.NewValue(), item)",
+            )
+            .ifErr((b) => {
+              b.return('fmt.Errorf("%s: %w", key, err)');
+            })
+            .l("entries.Set(protoreflect.ValueOfString(key).MapKey(), v)")
+            .u()
+            .l("}")
+            .return("nil")
+            .u()
+            .l("}")
+            .decl("v, err", "grpcValue(field, m.NewField(field), value)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("m.Set(field, v)")
+            .return("nil");
+        },
+      );
+
+    w.comment(
+      "grpcValue converts value to a value of the kind of field, filling in empty",
+    )
+      .comment("for messages.")
+      .n()
+      .func(
+        "grpcValue(field protoreflect.FieldDescriptor, empty protoreflect.Value, value interface{}) (protoreflect.Value, error)",
+        (b) => {
+          b.l("switch field.Kind() {")
+            .l("case protoreflect.MessageKind:")
+            .i()
+            .return("empty, grpcSet(empty.Message(), value)")
+            .u()
+            .l("case protoreflect.EnumKind:")
+            .i()
+            .if("s, ok := value.(string); ok", (b) => {
+              b.l("for i, v := range grpcEnumValues[field.Enum().FullName()] {")
+                .i()
+                .if("v == s", (b) => {
+                  b.return(
+                    "protoreflect.ValueOfEnum(protoreflect.EnumNumber(i + 1)), nil",
+                  );
+                })
+                .u()
+                .l("}")
+                .return('empty, fmt.Errorf("unknown value %q", s)');
+            })
+            .u()
+            .l("case protoreflect.StringKind:")
+            .i()
+            .if("s, ok := value.(string); ok", (b) => {
+              b.return("protoreflect.ValueOfString(s), nil");
+            })
+            .u()
+            .l("case protoreflect.BytesKind:")
+            .i()
+            .if("s, ok := value.(string); ok", (b) => {
+              b.decl("data, err", "base64.StdEncoding.DecodeString(s)")
+                .return("protoreflect.ValueOfBytes(data), err");
+            })
+            .u()
+            .l("case protoreflect.BoolKind:")
+            .i()
+            .if("b, ok := value.(bool); ok", (b) => {
+              b.return("protoreflect.ValueOfBool(b), nil");
+            })
+            .u()
+            .l("case protoreflect.Int64Kind:")
+            .i()
+            .if("n, ok := value.(json.Number); ok", (b) => {
+              b.decl("i, err", "n.Int64()")
+                .return("protoreflect.ValueOfInt64(i), err");
+            })
+            .u()
+            .l("case protoreflect.DoubleKind:")
+            .i()
+            .if("n, ok := value.(json.Number); ok", (b) => {
+              b.decl("f, err", "n.Float64()")
+                .return("protoreflect.ValueOfFloat64(f), err");
+            })
+            .u()
+            .l("}")
+            .return(
+              'empty, fmt.Errorf("cannot convert %T to %s", value, field.Kind())',
+            );
+        },
+      );
+  }
+
+  private generateDescriptorHelpers(w: GoBuilder): void {
+    w.comment("The types of the fields of the schema.")
+      .l("const (")
+      .i()
+      .l("grpcString      = descriptorpb.FieldDescriptorProto_TYPE_STRING")
+      .l("grpcInt64       = descriptorpb.FieldDescriptorProto_TYPE_INT64")
+      .l("grpcDouble      = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE")
+      .l("grpcBool        = descriptorpb.FieldDescriptorProto_TYPE_BOOL")
+      .l("grpcBytes       = descriptorpb.FieldDescriptorProto_TYPE_BYTES")
+      .l("grpcEnumType    = descriptorpb.FieldDescriptorProto_TYPE_ENUM")
+      .l("grpcMessageType = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE")
+      .u()
+      .l(")")
+      .n();
+
+    w.comment(
+      "grpcLabel is whether a field is singular, a proto3 optional or repeated.",
+    )
+      .type("grpcLabel", "int");
+
+    w.l("const (")
+      .i()
+      .l("grpcSingular grpcLabel = iota")
+      .l("grpcOptional")
+      .l("grpcRepeated")
+      .u()
+      .l(")")
+      .n();
+
+    w.comment("grpcServiceDescriptor describes a service with methods.")
+      .n()
+      .func(
+        "grpcServiceDescriptor(name string, methods ...*descriptorpb.MethodDescriptorProto) *descriptorpb.ServiceDescriptorProto",
+        (b) => {
+          b.return(
+            "&descriptorpb.ServiceDescriptorProto{Name: proto.String(name), Method: methods}",
+          );
+        },
+      );
+
+    w.comment(
+      "grpcMethodDescriptor describes an rpc by the full names of its messages;",
+    )
+      .comment("stream makes it server-streaming.")
+      .n()
+      .func(
+        "grpcMethodDescriptor(name, input, output string, stream bool) *descriptorpb.MethodDescriptorProto",
+        (b) => {
+          b.l("return &descriptorpb.MethodDescriptorProto{")
+            .i()
+            .l("Name:            proto.String(name),")
+            .l("InputType:       proto.String(input),")
+            .l("OutputType:      proto.String(output),")
+            .l("ServerStreaming: proto.Bool(stream),")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "grpcMessage describes a message with fields, which are the fields of a oneof",
+    )
+      .comment(
+        "named value for unions, and the map entry messages of its map fields.",
+      )
+      .comment(
+        "Optional fields get the synthetic oneof protoc declares for them.",
+      )
+      .n()
+      .func(
+        "grpcMessage(name string, union bool, fields []*descriptorpb.FieldDescriptorProto, entries ...*descriptorpb.DescriptorProto) *descriptorpb.DescriptorProto",
+        (b) => {
+          b.decl(
+            "message",
+            "&descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields, NestedType: entries}",
+          )
+            .if("union", (b) => {
+              b.l(
+                'message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("value")})',
+              )
+                .l("for _, field := range fields {")
+                .i()
+                .l("field.OneofIndex = proto.Int32(0)")
+                .u()
+                .l("}");
+            })
+            .l("for _, field := range fields {")
+            .i()
+            .if("field.GetProto3Optional()", (b) => {
+              b.l(
+                "field.OneofIndex = proto.Int32(int32(len(message.OneofDecl)))",
+              )
+                .l(
+                  'message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + field.GetName())})',
+                );
+            })
+            .u()
+            .l("}")
+            .return("message");
+        },
+      );
+
+    w.comment(
+      "grpcField describes a field whose type is kind, or the message or enum",
+    )
+      .comment("typeName names.")
+      .n()
+      .func(
+        "grpcField(name, jsonName string, number int32, label grpcLabel, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto",
+        (b) => {
+          b.l("field := &descriptorpb.FieldDescriptorProto{")
+            .i()
+            .l("Name:     proto.String(name),")
+            .l("JsonName: proto.String(jsonName),")
+            .l("Number:   proto.Int32(number),")
+            .l(
+              "Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),",
+            )
+            .l("Type:     kind.Enum(),")
+            .u()
+            .l("}")
+            .if('typeName != ""', (b) => {
+              b.l("field.TypeName = proto.String(typeName)");
+            })
+            .l("switch label {")
+            .l("case grpcOptional:")
+            .i()
+            .l("field.Proto3Optional = proto.Bool(true)")
+            .u()
+            .l("case grpcRepeated:")
+            .i()
+            .l(
+              "field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()",
+            )
+            .u()
+            .l("}")
+            .return("field");
+        },
+      );
+
+    w.comment(
+      "grpcMapEntry describes the entry message of a map field with string keys",
+    )
+      .comment("and values of the given type.")
+      .n()
+      .func(
+        "grpcMapEntry(name string, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.DescriptorProto",
+        (b) => {
+          b.l("return &descriptorpb.DescriptorProto{")
+            .i()
+            .l("Name: proto.String(name),")
+            .l("Field: []*descriptorpb.FieldDescriptorProto{")
+            .i()
+            .l('grpcField("key", "key", 1, grpcSingular, grpcString, ""),')
+            .l('grpcField("value", "value", 2, grpcSingular, kind, typeName),')
+            .u()
+            .l("},")
+            .l(
+              "Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},",
+            )
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "grpcEnum describes an enum whose values are numbered in order from 0.",
+    )
+      .n()
+      .func(
+        "grpcEnum(name string, values ...string) *descriptorpb.EnumDescriptorProto",
+        (b) => {
+          b.decl(
+            "protoEnum",
+            "&descriptorpb.EnumDescriptorProto{Name: proto.String(name)}",
+          )
+            .l("for i, value := range values {")
+            .i()
+            .l(
+              "protoEnum.Value = append(protoEnum.Value, &descriptorpb.EnumValueDescriptorProto{",
+            )
+            .i()
+            .l("Name:   proto.String(value),")
+            .l("Number: proto.Int32(int32(i)),")
+            .u()
+            .l("})")
+            .u()
+            .l("}")
+            .return("protoEnum");
+        },
+      );
+  }
+
+  private generateServices(
+    w: GoBuilder,
+    schema: ProtoSchema,
+    async: Set<string>,
+  ): void {
+    w.comment(
+      "grpcServices lists the services of the schema and the methods their rpcs",
+    )
+      .comment(
+        "call. Async mutations answer with an Operation, which their output message",
+      )
+      .comment("cannot carry, so their rpcs are left unimplemented.")
+      .l("var grpcServices = []grpcService{")
+      .i();
+    for (const service of schema.services) {
+      w.l(`{name: "${service.name}", rpcs: []grpcRPC{`).i();
+      for (const rpc of service.rpcs) {
+        if (async.has(rpc.method)) {
+          continue;
+        }
+        const stream = rpc.type === "subscription" ? ", stream: true" : "";
+        w.l(`{name: "${rpc.name}", method: "${rpc.method}"${stream}},`);
+      }
+      w.u().l("}},");
+    }
+    w.u().l("}").n();
+  }
+
+  private generateShapes(w: GoBuilder, schema: ProtoSchema): void {
+    const pkg = schema.package;
+    w.comment(
+      "grpcShapes holds the messages that are not objects in the JSON of the",
+    )
+      .comment("contract.")
+      .l("var grpcShapes = map[protoreflect.FullName]grpcShape{")
+      .i();
+    for (const message of schema.messages) {
+      if (message.kind === "object") {
+        continue;
+      }
+      if (message.kind !== "union" || !message.discriminator) {
+        w.l(`"${pkg}.${message.name}": {kind: "${message.kind}"},`);
+        continue;
+      }
+      w.l(
+        `"${pkg}.${message.name}": {kind: "union", discriminator: "${message.discriminator}", variants: map[string]protoreflect.Name{`,
+      ).i();
+      for (const [field, tag] of Object.entries(message.tags ?? {})) {
+        w.l(`${JSON.stringify(tag)}: "${field}",`);
+      }
+      w.u().l("}},");
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "grpcEnumValues holds the values of the contract each enum number stands for,",
+    )
+      .comment("from 1 on, since 0 is the UNSPECIFIED value proto3 requires.")
+      .l("var grpcEnumValues = map[protoreflect.FullName][]string{")
+      .i();
+    for (const protoEnum of schema.enums) {
+      const values = protoEnum.values
+        .map((value) => JSON.stringify(value.value))
+        .join(", ");
+      w.l(`"${pkg}.${protoEnum.name}": {${values}},`);
+    }
+    w.u().l("}").n();
+  }
+
+  private generateFile(w: GoBuilder, schema: ProtoSchema): void {
+    const pkg = schema.package;
+    const enums = new Set(schema.enums.map((protoEnum) => protoEnum.name));
+    // The type constant and type name of a field of the given proto type
+    const typeOf = (type: string): string => {
+      if (SCALAR_TYPES[type]) {
+        return `${SCALAR_TYPES[type]}, ""`;
+      }
+      if (type.startsWith("google.protobuf.")) {
+        return `grpcMessageType, ".${type}"`;
+      }
+      if (enums.has(type)) {
+        return `grpcEnumType, ".${pkg}.${type}"`;
+      }
+      return `grpcMessageType, ".${pkg}.${type}"`;
+    };
+    const label = (field: ProtoFieldModel): string => {
+      switch (field.label) {
+        case "optional":
+          return "grpcOptional";
+        case "repeated":
+          return "grpcRepeated";
+        default:
+          return "grpcSingular";
+      }
+    };
+
+    w.comment(
+      `grpcFile is the descriptor of the ${pkg}.proto schema the proto target`,
+    )
+      .comment("generates for the contract.")
+      .func("grpcFile() *descriptorpb.FileDescriptorProto", (b) => {
+        b.l("return &descriptorpb.FileDescriptorProto{")
+          .i()
+          .l(`Name:    proto.String("${pkg}.proto"),`)
+          .l(`Package: proto.String("${pkg}"),`)
+          .l('Syntax:  proto.String("proto3"),');
+        if (schema.imports.length > 0) {
+          b.l(
+            `Dependency: []string{${schema.imports.map((path) => `"${path}"`).join(", ")}},`,
+          );
+        }
+
+        b.l("Service: []*descriptorpb.ServiceDescriptorProto{").i();
+        for (const service of schema.services) {
+          b.l(`grpcServiceDescriptor("${service.name}",`).i();
+          for (const rpc of service.rpcs) {
+            const stream = rpc.type === "subscription";
+            b.l(
+              `grpcMethodDescriptor("${rpc.name}", ".${pkg}.${rpc.input}", ".${pkg}.${rpc.output}", ${stream}),`,
+            );
+          }
+          b.u().l("),");
+        }
+        b.u().l("},");
+
+        b.l("MessageType: []*descriptorpb.DescriptorProto{").i();
+        for (const message of schema.messages) {
+          const union = message.kind === "union";
+          const entries: string[] = [];
+          b.l(
+            `grpcMessage("${message.name}", ${union}, []*descriptorpb.FieldDescriptorProto{`,
+          ).i();
+          for (const field of message.fields) {
+            const map = /^map<string, (.+)>$/.exec(field.type);
+            const { name, jsonName, number } = field;
+            const args = `"${name}", "${jsonName}", ${number}`;
+            if (map) {
+              const entry = mapEntryName(field.name);
+              entries.push(`grpcMapEntry("${entry}", ${typeOf(map[1])}),`);
+              b.l(
+                `grpcField(${args}, grpcRepeated, grpcMessageType, ".${pkg}.${message.name}.${entry}"),`,
+              );
+            } else {
+              b.l(
+                `grpcField(${args}, ${label(field)}, ${typeOf(field.type)}),`,
+              );
+            }
+          }
+          if (entries.length === 0) {
+            b.u().l("}),");
+            continue;
+          }
+          b.u().l("},").i();
+          for (const entry of entries) {
+            b.l(entry);
+          }
+          b.u().l("),");
+        }
+        b.u().l("},");
+
+        if (schema.enums.length > 0) {
+          b.l("EnumType: []*descriptorpb.EnumDescriptorProto{").i();
+          for (const protoEnum of schema.enums) {
+            const values = [
+              `"${protoEnum.prefix}_UNSPECIFIED"`,
+              ...protoEnum.values.map((value) => `"${value.name}"`),
+            ].join(", ");
+            b.l(`grpcEnum("${protoEnum.name}", ${values}),`);
+          }
+          b.u().l("},");
+        }
+        b.u().l("}");
+      });
+  }
+}
@@ -0,0 +1,216 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates health.go: liveness and readiness handlers for orchestrators and
+ * load balancers. Router.HealthHandler always answers 200 while the process
+ * serves requests; Router.ReadinessHandler runs the checks registered with
+ * Router.AddReadinessCheck concurrently and answers 503 when one fails.
+ */
+export class GoHealthGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateHealth(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "fmt",
+      "net/http",
+      "sync",
+      "time",
+    );
+
+    this.generateChecks(w);
+    this.generateHandlers(w);
+
+    return w.toString();
+  }
+
+  private generateChecks(w: GoBuilder): void {
+    w.comment(
+      "ReadinessCheck reports whether a dependency the service needs is available,",
+    )
+      .comment("such as a database or downstream API.")
+      .type("ReadinessCheck", "func(ctx context.Context) error");
+
+    w.comment("readinessCheck is a check registered with AddReadinessCheck.")
+      .struct("readinessCheck", (b) => {
+        b.l("name  string").l("check ReadinessCheck");
+      });
+
+    w.comment(
+      "ReadinessTimeout bounds how long ReadinessHandler waits for its checks; a",
+    )
+      .comment("check still running after it is reported as failed.")
+      .l("var ReadinessTimeout = 5 * time.Second")
+      .n();
+
+    w.comment(
+      "AddReadinessCheck registers a check ReadinessHandler runs, reported under",
+    )
+      .comment("name. Register checks before serving requests.")
+      .n()
+      .method(
+        "r *Router",
+        "AddReadinessCheck",
+        "name string, check ReadinessCheck",
+        "*Router",
+        (b) => {
+          b.l(
+            "r.readinessChecks = append(r.readinessChecks, readinessCheck{name: name, check: check})",
+          ).return("r");
+        },
+      );
+
+    w.comment(
+      "PingCheck returns a ReadinessCheck pinging p, such as a *sql.DB.",
+    )
+      .n()
+      .func(
+        "PingCheck(p interface{ PingContext(ctx context.Context) error }) ReadinessCheck",
+        (b) => {
+          b.return("p.PingContext");
+        },
+      );
+  }
+
+  private generateHandlers(w: GoBuilder): void {
+    w.comment(
+      "HealthHandler answers liveness probes, such as GET /healthz: 200 with",
+    )
+      .comment(
+        '{"status": "ok"} for as long as the process serves requests. It runs no',
+      )
+      .comment(
+        "checks, so a failing dependency does not get the process restarted.",
+      )
+      .n()
+      .method("r *Router", "HealthHandler", "", "http.Handler", (b) => {
+        b.l(
+          "return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {",
+        )
+          .i()
+          .l(
+            'writeHealth(w, http.StatusOK, map[string]interface{}{"status": "ok"})',
+          )
+          .u()
+          .l("})");
+      });
+
+    w.comment(
+      "ReadinessHandler answers readiness probes, such as GET /readyz, by running the",
+    )
+      .comment(
+        'checks registered with AddReadinessCheck concurrently: 200 with {"status":',
+      )
+      .comment(
+        '"ok", "checks": {"db": "ok"}} when all pass, and 503 with "unavailable" and',
+      )
+      .comment(
+        "the error of each failed check otherwise, so traffic is routed elsewhere",
+      )
+      .comment("until the dependencies recover.")
+      .n()
+      .method("r *Router", "ReadinessHandler", "", "http.Handler", (b) => {
+        b.l(
+          "return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {",
+        )
+          .i()
+          .l(
+            "ctx, cancel := context.WithTimeout(req.Context(), ReadinessTimeout)",
+          )
+          .l("defer cancel()")
+          .n()
+          .decl("results", "make([]error, len(r.readinessChecks))")
+          .var("wg", "sync.WaitGroup")
+          .l("for i, entry := range r.readinessChecks {")
+          .i()
+          .l("wg.Add(1)")
+          .l("go func(i int, check ReadinessCheck) {")
+          .i()
+          .l("defer wg.Done()")
+          .l("results[i] = runReadinessCheck(ctx, check)")
+          .u()
+          .l("}(i, entry.check)")
+          .u()
+          .l("}")
+          .l("wg.Wait()")
+          .n()
+          .decl("status", "http.StatusOK")
+          .decl("checks", "make(map[string]string, len(results))")
+          .l("for i, err := range results {")
+          .i()
+          .decl("name", "r.readinessChecks[i].name")
+          .ifErr((b) => {
+            b.l("status = http.StatusServiceUnavailable")
+              .l("checks[name] = err.Error()")
+              .l("continue");
+          })
+          .l('checks[name] = "ok"')
+          .u()
+          .l("}")
+          .decl(
+            "body",
+            'map[string]interface{}{"status": "ok", "checks": checks}',
+          )
+          .if("status != http.StatusOK", (b) => {
+            b.l('body["status"] = "unavailable"');
+          })
+          .l("writeHealth(w, status, body)")
+          .u()
+          .l("})");
+      });
+
+    w.comment(
+      "runReadinessCheck runs check, failing it when it panics or outlasts ctx.",
+    )
+      .n()
+      .func(
+        "runReadinessCheck(ctx context.Context, check ReadinessCheck) error",
+        (b) => {
+          b.decl("done", "make(chan error, 1)")
+            .l("go func() {")
+            .i()
+            .l("defer func() {")
+            .i()
+            .if("rec := recover(); rec != nil", (b) => {
+              b.l('done <- fmt.Errorf("check panicked: %v", rec)');
+            })
+            .u()
+            .l("}()")
+            .l("done <- check(ctx)")
+            .u()
+            .l("}()")
+            .l("select {")
+            .l("case err := <-done:")
+            .i()
+            .return("err")
+            .u()
+            .l("case <-ctx.Done():")
+            .i()
+            .return("ctx.Err()")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment("writeHealth writes a health or readiness response body.")
+      .n()
+      .func(
+        "writeHealth(w http.ResponseWriter, status int, body map[string]interface{})",
+        (b) => {
+          b.l('w.Header().Set("Content-Type", "application/json")')
+            .l('w.Header().Set("Cache-Control", "no-store")')
+            .l("w.WriteHeader(status)")
+            .l("json.NewEncoder(w).Encode(body)");
+        },
+      );
+  }
+}
@@ -0,0 +1,122 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates hedging.go: hedged requests for latency-sensitive queries, which
+ * a Client sends a second time when the first attempt is slow to answer and
+ * takes whichever answer arrives first, trading some extra load for a
+ * shorter tail latency.
+ */
+export class GoHedgingGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateHedging(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "encoding/json", "time");
+
+    w.comment(
+      "WithHedging hedges calls of methods: when an attempt hasn't answered after",
+    )
+      .comment(
+        "delay, the Client sends a second one and returns the first successful",
+      )
+      .comment(
+        "answer, cancelling the other. Only queries can be hedged, as a hedged call",
+      )
+      .comment(
+        "runs twice on the server; WithHedging panics if a method is not one.",
+      )
+      .n()
+      .func(
+        "WithHedging(delay time.Duration, methods ...string) ClientOption",
+        (b) => {
+          b.l("return func(c *Client) {")
+            .i()
+            .l("c.hedgeDelay = delay")
+            .l("c.hedgedMethods = make(map[string]bool, len(methods))")
+            .l("for _, method := range methods {")
+            .i()
+            .if(
+              'm, ok := methodTable[method]; !ok || m.kind != "query"',
+              (b) => {
+                b.l('panic("hedged method " + method + " is not a query")');
+              },
+            )
+            .l("c.hedgedMethods[method] = true")
+            .u()
+            .l("}")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "hedgedAnswer is the result of an attempt at a hedged call, or its failure.",
+    ).struct("hedgedAnswer", (b) => {
+      b.l("result json.RawMessage")
+        .l("retryAfter time.Duration")
+        .l("err error");
+    });
+
+    w.comment(
+      "send makes an attempt at a call of method, hedged if the Client hedges it.",
+    )
+      .n()
+      .method(
+        "c *Client",
+        "send",
+        "ctx context.Context, method string, body []byte",
+        "(json.RawMessage, time.Duration, error)",
+        (b) => {
+          b.if("!c.hedgedMethods[method]", (b) => {
+            b.return("c.attempt(ctx, method, body)");
+          })
+            .l("ctx, cancel := context.WithCancel(ctx)")
+            .comment("Cancels the attempt that lost")
+            .l("defer cancel()")
+            .n()
+            .comment("Buffered so the attempt that lost doesn't block")
+            .decl("answers", "make(chan hedgedAnswer, 2)")
+            .decl("attempt", "func() {")
+            .i()
+            .l("result, retryAfter, err := c.attempt(ctx, method, body)")
+            .l("answers <- hedgedAnswer{result, retryAfter, err}")
+            .u()
+            .l("}")
+            .l("go attempt()")
+            .decl("timer", "time.NewTimer(c.hedgeDelay)")
+            .l("defer timer.Stop()")
+            .decl("pending", "1")
+            .l("for {")
+            .i()
+            .l("select {")
+            .l("case <-timer.C:")
+            .i()
+            .l("pending++")
+            .l("go attempt()")
+            .u()
+            .l("case answer := <-answers:")
+            .i()
+            .l("pending--")
+            .comment(
+              "A failure is only returned once no other attempt can still succeed",
+            )
+            .if("answer.err == nil || pending == 0", (b) => {
+              b.return("answer.result, answer.retryAfter, answer.err");
+            })
+            .u()
+            .l("}")
+            .u()
+            .l("}");
+        },
+      );
+
+    return w.toString();
+  }
+}
@@ -1,13 +1,64 @@
 export { goTarget } from "./generator";
-export { GoTypeGenerator } from "./type-generator";
+export { GoTypeGenerator, type GoStruct } from "./type-generator";
 export { GoServerGenerator } from "./server-generator";
+export { GoAuthGenerator } from "./auth-generator";
+export { GoBreakerGenerator } from "./breaker-generator";
+export { GoBufferGenerator } from "./buffer-generator";
+export { GoCLIGenerator } from "./cli-generator";
+export { GoClientGenerator } from "./client-generator";
+export { GoCodecGenerator } from "./codec-generator";
+export { GoCompatGenerator } from "./compat-generator";
+export { GoCompressionGenerator } from "./compression-generator";
+export { GoConcurrencyGenerator } from "./concurrency-generator";
+export { GoContextGenerator } from "./context-generator";
+export { GoCSRFGenerator } from "./csrf-generator";
+export { GoDispatchGenerator } from "./dispatch-generator";
+export { GoErrorsGenerator } from "./errors-generator";
+export { GoEnumGenerator } from "./enum-generator";
+export { GoDateGenerator } from "./date-generator";
+export { GoUnionGenerator } from "./union-generator";
+export { GoEventsGenerator } from "./events-generator";
+export { GoExamplesGenerator, collectExamples } from "./examples-generator";
+export { GoGateGenerator } from "./gate-generator";
+export { GoHealthGenerator } from "./health-generator";
+export { GoHedgingGenerator } from "./hedging-generator";
+export { GoJSONGenerator } from "./json-generator";
+export { GoUploadGenerator, usesFiles } from "./upload-generator";
+export { GoMethodsGenerator } from "./methods-generator";
+export { GoMockGenerator } from "./mock-generator";
+export { GoMountGenerator } from "./mount-generator";
+export { GoOpenAPIGenerator } from "./openapi-generator";
+export { GoOperationsGenerator } from "./operations-generator";
+export { GoOutboxGenerator } from "./outbox-generator";
+export {
+  GoPaginationGenerator,
+  usesPagination,
+} from "./pagination-generator";
+export { GoPlaygroundGenerator } from "./playground-generator";
+export { GoRedactGenerator } from "./redact-generator";
+export { GoRESTGenerator } from "./rest-generator";
+export { GoRetryGenerator } from "./retry-generator";
+export { GoRouterBenchGenerator } from "./router-bench-generator";
+export { GoRouterTestGenerator } from "./router-test-generator";
+export { GoSchemasGenerator } from "./schemas-generator";
+export { GoServiceGenerator } from "./service-generator";
+export { GoSingleFlightGenerator } from "./singleflight-generator";
+export { GoSnippetsGenerator } from "./snippets-generator";
+export { GoStreamGenerator, usesStreams } from "./stream-generator";
+export { GoTestClientGenerator } from "./test-client-generator";
+export { GoTransportGenerator } from "./transport-generator";
 export { GoValidationGenerator } from "./validation-generator";
+export {
+  GoValidatorsGenerator,
+  collectCustomValidators,
+} from "./validators-generator";
 export { GoTypeMapper } from "./type-mapper";
 export { GoValidationMapper, type GoValidationCode } from "./validation-mapper";
 export { GoTypeCollector, type CollectedType } from "./type-collector";
 export { GoBuilder } from "./go-builder";
 export {
   createGoEnumPattern,
+  goEnumConstNames,
   createGoBigIntPattern,
   createGoUnionPattern,
   createGoTuplePattern,
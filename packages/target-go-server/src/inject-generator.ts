@@ -0,0 +1,213 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates inject.go: typed dependency injection. Provide registers a
+ * provider per type on the router, the router puts a container of the
+ * router's providers in the context of every call, and MustGet and Get
+ * return the value its provider returned for the call, so handlers get their
+ * database, clients or loggers from ctx instead of package-level variables
+ * and tests swap them on the router or per call with WithDependency.
+ */
+export class GoInjectGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateInject(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "fmt", "sync");
+
+    this.generateContainer(w);
+    this.generateProvide(w);
+    this.generateGet(w);
+
+    return w.toString();
+  }
+
+  private generateContainer(w: GoBuilder): void {
+    w.comment(
+      "dependencyKey identifies the dependencies of type *T, among the providers of a",
+    )
+      .comment("router and in contexts.")
+      .l("type dependencyKey[T any] struct{}")
+      .n();
+
+    w.comment(
+      "dependenciesKey is the context key of the dependencies of the call.",
+    ).l("type dependenciesKey struct{}").n();
+
+    w.comment(
+      "dependencies are the providers of the router serving a call and the values",
+    )
+      .comment("they returned for it.")
+      .struct("dependencies", (b) => {
+        b.l("providers map[interface{}]func(ctx context.Context) interface{}")
+          .l("mu        sync.Mutex")
+          .l("values    map[interface{}]*dependency");
+      });
+
+    w.comment("dependency is the value of a provider for a call, once it was called.")
+      .struct("dependency", (b) => {
+        b.l("once  sync.Once").l("value interface{}");
+      });
+
+    w.comment(
+      "get returns the value of the provider of key for the call, calling it with ctx",
+    )
+      .comment(
+        "the first time the call asks for it; false if the router has no provider of",
+      )
+      .comment("key.")
+      .n()
+      .method(
+        "d *dependencies",
+        "get",
+        "ctx context.Context, key interface{}",
+        "(interface{}, bool)",
+        (b) => {
+          b.decl("provider, ok", "d.providers[key]")
+            .if("!ok", (b) => {
+              b.return("nil, false");
+            })
+            .l("d.mu.Lock()")
+            .decl("dep, ok", "d.values[key]")
+            .if("!ok", (b) => {
+              b.l("dep = &dependency{}").l("d.values[key] = dep");
+            })
+            .l("d.mu.Unlock()")
+            .comment(
+              "Called outside of the lock, as providers may get other dependencies",
+            )
+            .l("dep.once.Do(func() { dep.value = provider(ctx) })")
+            .return("dep.value, true");
+        },
+      );
+
+    w.comment(
+      "withDependencies returns a copy of ctx in which the call it serves gets the",
+    )
+      .comment("dependencies provided on r.")
+      .n()
+      .method(
+        "r *Router",
+        "withDependencies",
+        "ctx context.Context",
+        "context.Context",
+        (b) => {
+          b.if("len(r.providers) == 0", (b) => {
+            b.return("ctx");
+          }).return(
+            "context.WithValue(ctx, dependenciesKey{}, &dependencies{providers: r.providers, values: map[interface{}]*dependency{}})",
+          );
+        },
+      );
+  }
+
+  private generateProvide(w: GoBuilder): void {
+    w.comment(
+      "Provide registers provider as the source of the *T that handlers, interceptors",
+    )
+      .comment(
+        "and middleware of r get with MustGet or Get. It is called with the context of",
+      )
+      .comment(
+        "a call at most once per call, the first time the call asks for a *T, so it can",
+      )
+      .comment(
+        "return a value shared by every call, such as a connection pool, or one scoped",
+      )
+      .comment(
+        "to the call, such as a logger carrying its request ID. Providing a *T again",
+      )
+      .comment("replaces the provider, so tests can swap in fakes. Call it before")
+      .comment("serving requests:")
+      .comment("")
+      .comment("    db, err := NewDB(path)")
+      .comment("    ...")
+      .comment(
+        "    Provide[DB](router, func(ctx context.Context) *DB { return db })",
+      )
+      .n()
+      .func(
+        "Provide[T any](r *Router, provider func(ctx context.Context) *T) *Router",
+        (b) => {
+          b.if("r.providers == nil", (b) => {
+            b.l(
+              "r.providers = map[interface{}]func(ctx context.Context) interface{}{}",
+            );
+          })
+            .l(
+              "r.providers[dependencyKey[T]{}] = func(ctx context.Context) interface{} { return provider(ctx) }",
+            )
+            .return("r");
+        },
+      );
+
+    w.comment(
+      "WithDependency returns a copy of ctx in which MustGet and Get return value for",
+    )
+      .comment(
+        "*T instead of calling the router's provider, to swap a dependency for one",
+      )
+      .comment(
+        "call, such as in tests calling handlers or Dispatch directly, or in middleware",
+      )
+      .comment("binding a dependency to the caller.")
+      .n()
+      .func(
+        "WithDependency[T any](ctx context.Context, value *T) context.Context",
+        (b) => {
+          b.return("context.WithValue(ctx, dependencyKey[T]{}, value)");
+        },
+      );
+  }
+
+  private generateGet(w: GoBuilder): void {
+    w.comment(
+      "Get returns the *T of the call ctx belongs to: the one WithDependency put in",
+    )
+      .comment(
+        "ctx, or else the one the provider registered with Provide returns; false if",
+      )
+      .comment("there is neither.")
+      .n()
+      .func("Get[T any](ctx context.Context) (*T, bool)", (b) => {
+        b.if(
+          "value, ok := ctx.Value(dependencyKey[T]{}).(*T); ok",
+          (b) => {
+            b.return("value, true");
+          },
+        )
+          .decl("deps, ok", "ctx.Value(dependenciesKey{}).(*dependencies)")
+          .if("!ok", (b) => {
+            b.return("nil, false");
+          })
+          .decl("value, ok", "deps.get(ctx, dependencyKey[T]{})")
+          .if("!ok", (b) => {
+            b.return("nil, false");
+          })
+          .return("value.(*T), true");
+      });
+
+    w.comment(
+      "MustGet returns the *T of the call ctx belongs to, like Get. It panics when",
+    )
+      .comment(
+        "there is none, a mistake in setting up the server the router answers with",
+      )
+      .comment("INTERNAL.")
+      .n()
+      .func("MustGet[T any](ctx context.Context) *T", (b) => {
+        b.decl("value, ok", "Get[T](ctx)")
+          .if("!ok", (b) => {
+            b.l('panic(fmt.Sprintf("xrpc: no %T provided", value))');
+          })
+          .return("value");
+      });
+  }
+}
@@ -0,0 +1,269 @@
+import {
+  type ContractDefinition,
+  schemaHash,
+  typeToJsonSchema,
+} from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { INTROSPECT_METHOD } from "./server-generator";
+
+/**
+ * Generates introspect.go: a table describing every method with the JSON
+ * Schema of its input and output, and the Router methods that expose it.
+ * The router answers the built-in xrpc.introspect method from this table,
+ * along with the SchemaHash clients compare their own hash with, and suggests
+ * the closest of its methods to callers of an unknown one.
+ */
+export class GoIntrospectGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateIntrospect(contract: ContractDefinition): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "encoding/json",
+      "fmt",
+      "sort",
+      "strings",
+    );
+
+    w.comment(
+      "SchemaHash identifies the contract the router was generated from; clients",
+    )
+      .comment(
+        "generated from the same contract carry the same hash. Calls sending another",
+      )
+      .comment(
+        "in an X-Xrpc-Schema-Hash header are answered with an X-Xrpc-Schema-Mismatch",
+      )
+      .comment(
+        "header holding SchemaHash, warning the client it is stale. Servers called",
+      )
+      .comment(
+        "cross-origin have to allow X-Xrpc-Schema-Hash in Access-Control-Allow-Headers",
+      )
+      .comment(
+        "and expose X-Xrpc-Schema-Mismatch in Access-Control-Expose-Headers.",
+      )
+      .l(`const SchemaHash = "${schemaHash(contract)}"`)
+      .n();
+
+    w.comment(
+      "MethodInfo describes one method: its name, kind (query, mutation or",
+    )
+      .comment(
+        "subscription), the JSON Schemas of its input and output, the version of",
+      )
+      .comment(
+        "versioned methods such as task.list@v2, whether it is deprecated, and whether",
+      )
+      .comment(
+        "it is an async mutation, answering with an Operation whose result has the",
+      )
+      .comment("output schema.")
+      .struct("MethodInfo", (b) => {
+        b.l('Name       string          `json:"name"`')
+          .l('Kind       string          `json:"kind"`')
+          .l('Input      json.RawMessage `json:"input"`')
+          .l('Output     json.RawMessage `json:"output"`')
+          .l('Version    int             `json:"version,omitempty"`')
+          .l('Deprecated *Deprecation    `json:"deprecated,omitempty"`')
+          .l('Async      bool            `json:"async,omitempty"`');
+      });
+
+    w.comment(
+      "Deprecation describes a deprecated method: what to use instead, and the ISO",
+    )
+      .comment(
+        "8601 dates it was deprecated on and will stop being served on, when known.",
+      )
+      .struct("Deprecation", (b) => {
+        b.l('Message string `json:"message,omitempty"`')
+          .l('Since   string `json:"since,omitempty"`')
+          .l('Sunset  string `json:"sunset,omitempty"`');
+      });
+
+    w.comment("methodInfos describes every method in the contract.");
+    w.l("var methodInfos = []MethodInfo{").i();
+    for (const endpoint of contract.endpoints) {
+      const input = JSON.stringify(typeToJsonSchema(endpoint.input));
+      const output = JSON.stringify(typeToJsonSchema(endpoint.output));
+      w.l("{")
+        .i()
+        .l(`Name:   "${endpoint.fullName}",`)
+        .l(`Kind:   "${endpoint.type}",`)
+        .l(`Input:  json.RawMessage(${goStringLiteral(input)}),`)
+        .l(`Output: json.RawMessage(${goStringLiteral(output)}),`);
+      if (endpoint.version) {
+        w.l(`Version: ${endpoint.version},`);
+      }
+      if (endpoint.deprecated) {
+        const { message, since, sunset } = endpoint.deprecated;
+        const fields = [
+          message && `Message: ${goStringLiteral(message)}`,
+          since && `Since: "${since}"`,
+          sunset && `Sunset: "${sunset}"`,
+        ].filter(Boolean);
+        w.l(`Deprecated: &Deprecation{${fields.join(", ")}},`);
+      }
+      if (endpoint.async) {
+        w.l("Async: true,");
+      }
+      w.u().l("},");
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "Introspect returns the methods that have a handler registered, sorted by",
+    )
+      .comment("method name, followed by those of the routers added with Mount.")
+      .n()
+      .method("r *Router", "Introspect", "", "[]MethodInfo", (b) => {
+        b.decl("methods", "make([]MethodInfo, 0, len(methodInfos))")
+          .l("for _, info := range methodInfos {")
+          .i()
+          .if("methodTable[info.Name].registered(r)", (b) => {
+            b.l("methods = append(methods, info)");
+          })
+          .u()
+          .l("}")
+          .l("for _, entry := range r.mounts {")
+          .i()
+          .l("methods = append(methods, mountedMethods(entry)...)")
+          .u()
+          .l("}")
+          .return("methods");
+      });
+
+    w.comment(
+      `DisableIntrospection turns off the built-in ${INTROSPECT_METHOD} method, which then`,
+    )
+      .comment("reports CodeMethodNotFound like any unknown method.")
+      .n()
+      .method("r *Router", "DisableIntrospection", "", "*Router", (b) => {
+        b.l("r.introspectionDisabled = true").return("r");
+      });
+
+    this.generateMethodNotFound(w);
+
+    w.comment(
+      "EnableDeprecationHeaders makes calls of deprecated methods answer with a",
+    )
+      .comment(
+        'Deprecation header (RFC 9745) carrying the date they were deprecated on, or',
+      )
+      .comment(
+        '"true" when the contract gives none, and with a Sunset header (RFC 8594)',
+      )
+      .comment("when it gives the date they stop being served.")
+      .n()
+      .method("r *Router", "EnableDeprecationHeaders", "", "*Router", (b) => {
+        b.l("r.deprecationHeaders = true").return("r");
+      });
+
+    return w.toString();
+  }
+
+  private generateMethodNotFound(w: GoBuilder): void {
+    w.comment(
+      "MethodNotFound is the Details of CodeMethodNotFound errors: the method the",
+    )
+      .comment(
+        "caller most likely meant, when one is a few edits away from the method it",
+      )
+      .comment("called, and the namespaces of the methods the router serves.")
+      .struct("MethodNotFound", (b) => {
+        b.l('Suggestion string   `json:"suggestion,omitempty"`').l(
+          'Namespaces []string `json:"namespaces"`',
+        );
+      });
+
+    w.comment(
+      "methodNotFound returns the error answering a call of an unknown method, with",
+    )
+      .comment(
+        "a MethodNotFound suggesting the registered method closest to it by edit",
+      )
+      .comment(
+        "distance. Routers with introspection disabled do not reveal their methods.",
+      )
+      .n()
+      .method("r *Router", "methodNotFound", "method string", "*Error", (b) => {
+        b.decl("err", 'Errorf(CodeMethodNotFound, "Method not found: %s", method)')
+          .if("r.introspectionDisabled", (b) => {
+            b.return("err");
+          })
+          .decl("details", "MethodNotFound{Namespaces: []string{}}")
+          .comment(
+            "Suggestions are at most a third of the method's length plus one edit away",
+          )
+          .decl("best", "len(method)/3 + 2")
+          .decl("seen", "map[string]bool{}")
+          .l("for _, info := range r.Introspect() {")
+          .i()
+          .if("d := editDistance(method, info.Name); d < best", (b) => {
+            b.l("best = d").l("details.Suggestion = info.Name");
+          })
+          .decl("namespace", "info.Name")
+          .if('i := strings.LastIndex(namespace, "."); i >= 0', (b) => {
+            b.l("namespace = namespace[:i]");
+          })
+          .if("!seen[namespace]", (b) => {
+            b.l("seen[namespace] = true").l(
+              "details.Namespaces = append(details.Namespaces, namespace)",
+            );
+          })
+          .u()
+          .l("}")
+          .l("sort.Strings(details.Namespaces)")
+          .if('details.Suggestion != ""', (b) => {
+            b.l(
+              'err.Message += fmt.Sprintf(" (did you mean %s?)", details.Suggestion)',
+            );
+          })
+          .return("err.WithDetails(details)");
+      });
+
+    w.comment(
+      "editDistance returns the Levenshtein distance between a and b: the fewest",
+    )
+      .comment("bytes to insert, delete or replace to turn one into the other.")
+      .n()
+      .func("editDistance(a, b string) int", (b) => {
+        b.decl("prev", "make([]int, len(b)+1)")
+          .decl("curr", "make([]int, len(b)+1)")
+          .l("for j := range prev {")
+          .i()
+          .l("prev[j] = j")
+          .u()
+          .l("}")
+          .l("for i := 1; i <= len(a); i++ {")
+          .i()
+          .l("curr[0] = i")
+          .l("for j := 1; j <= len(b); j++ {")
+          .i()
+          .decl("replace", "prev[j-1]")
+          .if("a[i-1] != b[j-1]", (b) => {
+            b.l("replace++");
+          })
+          .l("curr[j] = replace")
+          .if("prev[j]+1 < curr[j]", (b) => {
+            b.l("curr[j] = prev[j] + 1");
+          })
+          .if("curr[j-1]+1 < curr[j]", (b) => {
+            b.l("curr[j] = curr[j-1] + 1");
+          })
+          .u()
+          .l("}")
+          .l("prev, curr = curr, prev")
+          .u()
+          .l("}")
+          .return("prev[len(b)]");
+      });
+  }
+}
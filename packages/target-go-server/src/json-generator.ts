@@ -0,0 +1,1084 @@
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+
+// How a Go type is encoded and decoded statically; undefined for types left
+// to encoding/json, such as enums, dates and unions
+type StaticType =
+  | { kind: "string" | "bool" | "float64" | "int" }
+  | { kind: "struct"; name: string }
+  | { kind: "pointer" | "slice" | "map"; type: string; elem: StaticType };
+
+// The error handling of a decoded value: the statement returning err
+type Fail = (b: GoBuilder) => void;
+
+/**
+ * Generates json.go: MarshalJSON and UnmarshalJSON methods for the structs of
+ * types.go that encode and decode their fields without reflection, and the
+ * JSON reader and writers they are built on. Strings, booleans, numbers,
+ * nested structs and pointers, slices and maps of them are handled directly;
+ * fields of other types, such as enums, dates and unions, still go through
+ * encoding/json. Output matches encoding/json's, and errors are of the same
+ * types.
+ */
+export class GoJSONGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private structs: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate json.go.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateJSON(structs: GoStruct[], aliases: Map<string, string>): string {
+    const w = this.w.reset();
+    this.aliases = aliases;
+    // Structs with an omitempty field whose emptiness can't be told from its
+    // type are left to encoding/json entirely
+    const known = new Set([...structs.map((struct) => struct.name), "time.Time"]);
+    const staticStructs = structs.filter((struct) =>
+      struct.fields.every(
+        (field) =>
+          !field.omitEmpty || this.isEmpty(field.type, "x", known) !== false,
+      ),
+    );
+    this.structs = new Set(staticStructs.map((struct) => struct.name));
+
+    w.package(this.packageName).import(
+      "bytes",
+      "encoding/json",
+      "fmt",
+      "math",
+      "reflect",
+      "sort",
+      "strconv",
+      "strings",
+      "unicode/utf8",
+    );
+
+    this.generateWriter(w);
+    this.generateReader(w);
+    this.generateDecoders(w);
+    for (const struct of staticStructs) {
+      this.generateStruct(w, struct);
+    }
+
+    return w.toString();
+  }
+
+  private generateWriter(w: GoBuilder): void {
+    w.l('const jsonHexDigits = "0123456789abcdef"').n();
+
+    w.comment(
+      "appendJSONString appends s as a JSON string, escaped like encoding/json escapes",
+    )
+      .comment(
+        "it: HTML characters, control characters and U+2028 and U+2029 as \\u escapes,",
+      )
+      .comment("and invalid UTF-8 as U+FFFD.")
+      .n()
+      .func("appendJSONString(dst []byte, s string) []byte", (b) => {
+        b.l("dst = append(dst, '\"')")
+          .decl("start", "0")
+          .l("for i := 0; i < len(s); {")
+          .i()
+          .if("c := s[i]; c < utf8.RuneSelf", (b) => {
+            b.if(
+              "c >= 0x20 && c != '\"' && c != '\\\\' && c != '<' && c != '>' && c != '&'",
+              (b) => {
+                b.l("i++").l("continue");
+              },
+            )
+              .l("dst = append(dst, s[start:i]...)")
+              .l("switch c {")
+              .l("case '\"', '\\\\':")
+              .i()
+              .l("dst = append(dst, '\\\\', c)")
+              .u()
+              .l("case '\\n':")
+              .i()
+              .l("dst = append(dst, '\\\\', 'n')")
+              .u()
+              .l("case '\\r':")
+              .i()
+              .l("dst = append(dst, '\\\\', 'r')")
+              .u()
+              .l("case '\\t':")
+              .i()
+              .l("dst = append(dst, '\\\\', 't')")
+              .u()
+              .l("case '\\b':")
+              .i()
+              .l("dst = append(dst, '\\\\', 'b')")
+              .u()
+              .l("case '\\f':")
+              .i()
+              .l("dst = append(dst, '\\\\', 'f')")
+              .u()
+              .l("default:")
+              .i()
+              .l(
+                "dst = append(dst, '\\\\', 'u', '0', '0', jsonHexDigits[c>>4], jsonHexDigits[c&0xF])",
+              )
+              .u()
+              .l("}")
+              .l("i++")
+              .l("start = i")
+              .l("continue");
+          })
+          .l("r, size := utf8.DecodeRuneInString(s[i:])")
+          .l("switch {")
+          .l("case r == utf8.RuneError && size == 1:")
+          .i()
+          .l("dst = append(dst, s[start:i]...)")
+          .l('dst = append(dst, "\\ufffd"...)')
+          .l("start = i + size")
+          .u()
+          .l("case r == '\\u2028' || r == '\\u2029':")
+          .i()
+          .l("dst = append(dst, s[start:i]...)")
+          .l("dst = append(dst, '\\\\', 'u', '2', '0', '2', jsonHexDigits[r&0xF])")
+          .l("start = i + size")
+          .u()
+          .l("}")
+          .l("i += size")
+          .u()
+          .l("}")
+          .l("dst = append(dst, s[start:]...)")
+          .return("append(dst, '\"')");
+      });
+
+    w.comment(
+      "appendJSONFloat appends f as a JSON number, formatted like encoding/json formats",
+    )
+      .comment("it. NaN and infinities have no JSON encoding.")
+      .n()
+      .func("appendJSONFloat(dst []byte, f float64) ([]byte, error)", (b) => {
+        b.if("math.IsInf(f, 0) || math.IsNaN(f)", (b) => {
+          b.return(
+            'nil, fmt.Errorf("json: unsupported value: %s", strconv.FormatFloat(f, \'g\', -1, 64))',
+          );
+        })
+          .decl("format", "byte('f')")
+          .if(
+            "abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21)",
+            (b) => {
+              b.l("format = 'e'");
+            },
+          )
+          .l("dst = strconv.AppendFloat(dst, f, format, -1, 64)")
+          .if("format == 'e'", (b) => {
+            b.comment("Shorten e-09 to e-9")
+              .decl("n", "len(dst)")
+              .if(
+                "n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0'",
+                (b) => {
+                  b.l("dst[n-2] = dst[n-1]").l("dst = dst[:n-1]");
+                },
+              );
+          })
+          .return("dst, nil");
+      });
+
+    w.comment(
+      "appendJSONValue appends the JSON of a value of a type json.go doesn't encode",
+    )
+      .comment("itself, encoded with encoding/json.")
+      .n()
+      .func(
+        "appendJSONValue(dst []byte, value interface{}) ([]byte, error)",
+        (b) => {
+          b.decl("data, err", "json.Marshal(value)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .return("append(dst, data...), nil");
+        },
+      );
+
+    w.comment(
+      "closeJSONObject turns the fields appended to dst from start, each preceded by a",
+    )
+      .comment("comma, into an object.")
+      .n()
+      .func("closeJSONObject(dst []byte, start int) []byte", (b) => {
+        b.if("len(dst) == start", (b) => {
+          b.return("append(dst, '{', '}')");
+        })
+          .l("dst[start] = '{'")
+          .return("append(dst, '}')");
+      });
+
+    w.comment(
+      "sortedJSONKeys returns the keys of m in the order encoding/json writes them.",
+    )
+      .n()
+      .func("sortedJSONKeys[T any](m map[string]T) []string", (b) => {
+        b.decl("keys", "make([]string, 0, len(m))")
+          .l("for key := range m {")
+          .i()
+          .l("keys = append(keys, key)")
+          .u()
+          .l("}")
+          .l("sort.Strings(keys)")
+          .return("keys");
+      });
+  }
+
+  private generateReader(w: GoBuilder): void {
+    w.comment(
+      "fieldError adds the struct field a type error occurred in to its path, as",
+    )
+      .comment(
+        "encoding/json reports it: Struct is the innermost struct and Field the path",
+      )
+      .comment("of fields from the outermost one.")
+      .n()
+      .func("fieldError(err error, structName, field string) error", (b) => {
+        b.if("typeErr, ok := err.(*json.UnmarshalTypeError); ok", (b) => {
+          b.if('typeErr.Struct == ""', (b) => {
+            b.l("typeErr.Struct = structName");
+          })
+            .if('typeErr.Field != ""', (b) => {
+              b.l('field += "." + typeErr.Field');
+            })
+            .l("typeErr.Field = field");
+        }).return("err");
+      });
+
+    w.comment(
+      "jsonDecoder reads a JSON document for the UnmarshalJSON methods of json.go.",
+    ).struct("jsonDecoder", (b) => {
+      b.l("data []byte").l("pos  int");
+    });
+
+    w.comment(
+      "finish completes the decoding of the document with the error of its value:",
+    )
+      .comment(
+        "nothing but whitespace may follow it, and like encoding/json, syntax errors",
+      )
+      .comment("are reported before type errors.")
+      .n()
+      .method("d *jsonDecoder", "finish", "err error", "error", (b) => {
+        b.if("err == nil && d.space() != 0", (b) => {
+          b.return("d.syntaxError()");
+        })
+          .if("_, ok := err.(*json.UnmarshalTypeError); ok && !json.Valid(d.data)", (b) => {
+            b.return("d.syntaxError()");
+          })
+          .return("err");
+      });
+
+    w.comment(
+      "syntaxError returns the error encoding/json reports for the document.",
+    )
+      .n()
+      .method("d *jsonDecoder", "syntaxError", "", "error", (b) => {
+        b.var("value", "interface{}")
+          .if("err := json.Unmarshal(d.data, &value); err != nil", (b) => {
+            b.return("err");
+          })
+          .return('fmt.Errorf("json: invalid character after top-level value")');
+      });
+
+    w.comment(
+      "space skips whitespace and returns the next byte, or 0 at the end of the data.",
+    )
+      .n()
+      .method("d *jsonDecoder", "space", "", "byte", (b) => {
+        b.l("for d.pos < len(d.data) {")
+          .i()
+          .l("switch c := d.data[d.pos]; c {")
+          .l("case ' ', '\\t', '\\n', '\\r':")
+          .i()
+          .l("d.pos++")
+          .u()
+          .l("default:")
+          .i()
+          .return("c")
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .return("0");
+      });
+
+    w.comment("null reads a null, reporting whether the next value is one.")
+      .n()
+      .method("d *jsonDecoder", "null", "", "bool", (b) => {
+        b.if(
+          "d.space() == 'n' && bytes.HasPrefix(d.data[d.pos:], []byte(\"null\"))",
+          (b) => {
+            b.l("d.pos += 4").return("true");
+          },
+        ).return("false");
+      });
+
+    w.comment(
+      "value reads a value of any type and returns its JSON, failing if it isn't valid.",
+    )
+      .n()
+      .method("d *jsonDecoder", "value", "", "([]byte, error)", (b) => {
+        b.l("d.space()")
+          .decl("start", "d.pos")
+          .decl("depth", "0")
+          .l("scan:")
+          .l("for ; d.pos < len(d.data); d.pos++ {")
+          .i()
+          .l("switch d.data[d.pos] {")
+          .l("case '\"':")
+          .i()
+          .l("for d.pos++; d.pos < len(d.data) && d.data[d.pos] != '\"'; d.pos++ {")
+          .i()
+          .if("d.data[d.pos] == '\\\\'", (b) => {
+            b.l("d.pos++");
+          })
+          .u()
+          .l("}")
+          .if("d.pos >= len(d.data)", (b) => {
+            b.l("d.pos = len(d.data)").l("break scan");
+          })
+          .u()
+          .l("case '{', '[':")
+          .i()
+          .l("depth++")
+          .u()
+          .l("case '}', ']':")
+          .i()
+          .if("depth == 0", (b) => {
+            b.l("break scan");
+          })
+          .l("depth--")
+          .if("depth == 0", (b) => {
+            b.l("d.pos++").l("break scan");
+          })
+          .u()
+          .l("case ',', ':', ' ', '\\t', '\\n', '\\r':")
+          .i()
+          .if("depth == 0", (b) => {
+            b.l("break scan");
+          })
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .decl("data", "d.data[start:d.pos]")
+          .if("!json.Valid(data)", (b) => {
+            b.return("nil, d.syntaxError()");
+          })
+          .return("data, nil");
+      });
+
+    w.comment(
+      "typeError returns the error of the next value not being of the type target",
+    )
+      .comment("points to.")
+      .n()
+      .method("d *jsonDecoder", "typeError", "target interface{}", "error", (b) => {
+        b.decl("data, err", "d.value()")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .comment(
+            "Like encoding/json, the offset is the end of the value, or just after the",
+          )
+          .comment("opening bracket of objects and arrays")
+          .decl("offset", "d.pos")
+          .decl("value", '"number"')
+          .l("switch data[0] {")
+          .l("case '\"':")
+          .i()
+          .l('value = "string"')
+          .u()
+          .l("case '{':")
+          .i()
+          .l('value = "object"')
+          .l("offset -= len(data) - 1")
+          .u()
+          .l("case '[':")
+          .i()
+          .l('value = "array"')
+          .l("offset -= len(data) - 1")
+          .u()
+          .l("case 't', 'f':")
+          .i()
+          .l('value = "bool"')
+          .u()
+          .l("}")
+          .return(
+            "&json.UnmarshalTypeError{Value: value, Type: reflect.TypeOf(target).Elem(), Offset: int64(offset)}",
+          );
+      });
+
+    w.comment(
+      "open reads the opening bracket of an object ('{') or array ('['), reporting",
+    )
+      .comment("whether it has members.")
+      .n()
+      .method(
+        "d *jsonDecoder",
+        "open",
+        "bracket byte, target interface{}",
+        "(bool, error)",
+        (b) => {
+          b.if("d.space() != bracket", (b) => {
+            b.return("false, d.typeError(target)");
+          })
+            .l("d.pos++")
+            .if("d.space() == closingBracket(bracket)", (b) => {
+              b.l("d.pos++").return("false, nil");
+            })
+            .return("true, nil");
+        },
+      );
+
+    w.comment(
+      "more reads the comma before the next member of an object or array opened with",
+    )
+      .comment("bracket, or its closing bracket, reporting whether there is one.")
+      .n()
+      .method("d *jsonDecoder", "more", "bracket byte", "(bool, error)", (b) => {
+        b.l("switch d.space() {")
+          .l("case ',':")
+          .i()
+          .l("d.pos++")
+          .return("true, nil")
+          .u()
+          .l("case closingBracket(bracket):")
+          .i()
+          .l("d.pos++")
+          .return("false, nil")
+          .u()
+          .l("}")
+          .return("false, d.syntaxError()");
+      });
+
+    w.func("closingBracket(bracket byte) byte", (b) => {
+      b.if("bracket == '['", (b) => {
+        b.return("']'");
+      }).return("'}'");
+    });
+
+    w.comment("key reads the name of an object member and its colon.")
+      .n()
+      .method("d *jsonDecoder", "key", "", "(string, error)", (b) => {
+        b.var("key", "string")
+          .if("d.space() != '\"'", (b) => {
+            b.return('"", d.syntaxError()');
+          })
+          .if("err := decodeJSONString(d, &key); err != nil", (b) => {
+            b.return('"", err');
+          })
+          .if("d.space() != ':'", (b) => {
+            b.return('"", d.syntaxError()');
+          })
+          .l("d.pos++")
+          .return("key, nil");
+      });
+
+    w.comment(
+      "plainString reads a string without escapes, the common case, reporting false",
+    )
+      .comment("to leave other strings to encoding/json.")
+      .n()
+      .method("d *jsonDecoder", "plainString", "", "(string, bool)", (b) => {
+        b.decl("ascii", "true")
+          .l("for i := d.pos + 1; i < len(d.data); i++ {")
+          .i()
+          .l("switch c := d.data[i]; {")
+          .l("case c == '\"':")
+          .i()
+          .decl("text", "d.data[d.pos+1 : i]")
+          .if("!ascii && !utf8.Valid(text)", (b) => {
+            b.return('"", false');
+          })
+          .l("d.pos = i + 1")
+          .return("string(text), true")
+          .u()
+          .l("case c == '\\\\' || c < 0x20:")
+          .i()
+          .return('"", false')
+          .u()
+          .l("case c >= utf8.RuneSelf:")
+          .i()
+          .l("ascii = false")
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .return('"", false');
+      });
+
+    w.comment(
+      "foldJSONKey returns the name a key matches case-insensitively, as encoding/json",
+    )
+      .comment("matches keys with no field of their exact name, or \"\".")
+      .n()
+      .func("foldJSONKey(key string, names ...string) string", (b) => {
+        b.l("for _, name := range names {")
+          .i()
+          .if("strings.EqualFold(key, name)", (b) => {
+            b.return("name");
+          })
+          .u()
+          .l("}")
+          .return('""');
+      });
+  }
+
+  private generateDecoders(w: GoBuilder): void {
+    w.comment("decodeJSONString decodes a string into s; null leaves it as is.")
+      .n()
+      .func("decodeJSONString(d *jsonDecoder, s *string) error", (b) => {
+        b.if("d.null()", (b) => {
+          b.return("nil");
+        })
+          .if("d.space() != '\"'", (b) => {
+            b.return("d.typeError(s)");
+          })
+          .if("text, ok := d.plainString(); ok", (b) => {
+            b.l("*s = text").return("nil");
+          })
+          .decl("data, err", "d.value()")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .return("json.Unmarshal(data, s)");
+      });
+
+    w.comment("decodeJSONBool decodes a boolean into v; null leaves it as is.")
+      .n()
+      .func("decodeJSONBool(d *jsonDecoder, v *bool) error", (b) => {
+        b.l("switch {")
+          .l("case d.null():")
+          .l('case bytes.HasPrefix(d.data[d.pos:], []byte("true")):')
+          .i()
+          .l("*v = true")
+          .l("d.pos += 4")
+          .u()
+          .l('case bytes.HasPrefix(d.data[d.pos:], []byte("false")):')
+          .i()
+          .l("*v = false")
+          .l("d.pos += 5")
+          .u()
+          .l("default:")
+          .i()
+          .return("d.typeError(v)")
+          .u()
+          .l("}")
+          .return("nil");
+      });
+
+    w.comment(
+      "number reads a number for target, or returns the type error of another value.",
+    )
+      .n()
+      .method(
+        "d *jsonDecoder",
+        "number",
+        "target interface{}",
+        "(string, error)",
+        (b) => {
+          b.if("c := d.space(); c != '-' && (c < '0' || c > '9')", (b) => {
+            b.return('"", d.typeError(target)');
+          })
+            .decl("data, err", "d.value()")
+            .return("string(data), err");
+        },
+      );
+
+    w.comment("decodeJSONFloat decodes a number into v; null leaves it as is.")
+      .n()
+      .func("decodeJSONFloat(d *jsonDecoder, v *float64) error", (b) => {
+        b.if("d.null()", (b) => {
+          b.return("nil");
+        })
+          .decl("number, err", "d.number(v)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .decl("f, err", "strconv.ParseFloat(number, 64)")
+          .ifErr((b) => {
+            b.return(
+              '&json.UnmarshalTypeError{Value: "number " + number, Type: reflect.TypeOf(v).Elem(), Offset: int64(d.pos)}',
+            );
+          })
+          .l("*v = f")
+          .return("nil");
+      });
+
+    w.comment(
+      "decodeJSONInt decodes an integer into v; null leaves it as is.",
+    )
+      .n()
+      .func("decodeJSONInt(d *jsonDecoder, v *int) error", (b) => {
+        b.if("d.null()", (b) => {
+          b.return("nil");
+        })
+          .decl("number, err", "d.number(v)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .decl("i, err", "strconv.ParseInt(number, 10, 0)")
+          .ifErr((b) => {
+            b.return(
+              '&json.UnmarshalTypeError{Value: "number " + number, Type: reflect.TypeOf(v).Elem(), Offset: int64(d.pos)}',
+            );
+          })
+          .l("*v = int(i)")
+          .return("nil");
+      });
+
+    w.comment(
+      "decodeJSONValue decodes a value of a type json.go doesn't decode itself into",
+    )
+      .comment("v with encoding/json.")
+      .n()
+      .func("decodeJSONValue(d *jsonDecoder, v interface{}) error", (b) => {
+        b.decl("data, err", "d.value()")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .return("json.Unmarshal(data, v)");
+      });
+  }
+
+  private generateStruct(w: GoBuilder, struct: GoStruct): void {
+    const { name, fields } = struct;
+    const known = new Set([...this.structs, "time.Time"]);
+
+    w.comment("MarshalJSON encodes v without reflection.")
+      .n()
+      .method(`v ${name}`, "MarshalJSON", "", "([]byte, error)", (b) => {
+        b.return("v.appendJSON(nil)");
+      });
+
+    w.comment("appendJSON appends the JSON of v to dst.")
+      .n()
+      .method(`v ${name}`, "appendJSON", "dst []byte", "([]byte, error)", (b) => {
+        if (
+          fields.some((field) => this.isFallible(this.staticType(field.type)))
+        ) {
+          b.var("err", "error");
+        }
+        b.decl("start", "len(dst)");
+        for (const field of fields) {
+          const type = this.staticType(field.type);
+          const key = `dst = append(dst, \`,${JSON.stringify(field.jsonName)}:\`...)`;
+          const expr = `v.${field.name}`;
+          const empty = field.omitEmpty
+            ? this.isEmpty(field.type, expr, known)
+            : undefined;
+          if (!empty) {
+            b.l(key);
+            this.appendValue(b, type, expr, 0);
+            continue;
+          }
+          b.if(empty, (b) => {
+            b.l(key);
+            // Set pointers are never null
+            if (type?.kind === "pointer") {
+              this.appendValue(b, type.elem, `*${expr}`, 0);
+            } else {
+              this.appendValue(b, type, expr, 0);
+            }
+          });
+        }
+        b.return("closeJSONObject(dst, start), nil");
+      });
+
+    w.comment("UnmarshalJSON decodes data into v without reflection.")
+      .n()
+      .method(`v *${name}`, "UnmarshalJSON", "data []byte", "error", (b) => {
+        b.decl("d", "jsonDecoder{data: data}").return(
+          "d.finish(v.decodeJSON(&d))",
+        );
+      });
+
+    w.comment("decodeJSON decodes the next value into v; null leaves it as is.")
+      .n()
+      .method(`v *${name}`, "decodeJSON", "d *jsonDecoder", "error", (b) => {
+        b.if("d.null()", (b) => {
+          b.return("nil");
+        })
+          .l(
+            "for ok, err := d.open('{', v); ok || err != nil; ok, err = d.more('{') {",
+          )
+          .i()
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .decl("key, err", "d.key()")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .if("err := v.decodeField(d, key); err != nil", (b) => {
+            b.return("err");
+          })
+          .u()
+          .l("}")
+          .return("nil");
+      });
+
+    w.comment("decodeField decodes the value of the member named key into v.")
+      .n()
+      .method(
+        `v *${name}`,
+        "decodeField",
+        "d *jsonDecoder, key string",
+        "error",
+        (b) => {
+          const fail: Fail = (b) => {
+            b.return(`fieldError(err, "${name}", key)`);
+          };
+          b.l("switch key {");
+          for (const field of fields) {
+            b.l(`case ${JSON.stringify(field.jsonName)}:`).i();
+            this.decodeValue(
+              b,
+              this.staticType(field.type),
+              field.type,
+              `v.${field.name}`,
+              fail,
+              0,
+            );
+            b.u();
+          }
+          const names = fields
+            .map((field) => `, ${JSON.stringify(field.jsonName)}`)
+            .join("");
+          b.l("default:")
+            .i()
+            .if(`name := foldJSONKey(key${names}); name != ""`, (b) => {
+              b.return("v.decodeField(d, name)");
+            })
+            .comment("Members of no field are skipped")
+            .decl("_, err", "d.value()")
+            .return("err")
+            .u()
+            .l("}")
+            .return("nil");
+        },
+      );
+  }
+
+  // Emits the statements appending the JSON of expr, of type, to dst
+  private appendValue(
+    b: GoBuilder,
+    type: StaticType | undefined,
+    expr: string,
+    depth: number,
+  ): void {
+    const fail = (b: GoBuilder) => {
+      b.return("nil, err");
+    };
+    if (!type) {
+      b.if(`dst, err = appendJSONValue(dst, ${expr}); err != nil`, fail);
+      return;
+    }
+    switch (type.kind) {
+      case "string":
+        b.l(`dst = appendJSONString(dst, ${expr})`);
+        return;
+      case "bool":
+        b.l(`dst = strconv.AppendBool(dst, ${expr})`);
+        return;
+      case "int":
+        b.l(`dst = strconv.AppendInt(dst, int64(${expr}), 10)`);
+        return;
+      case "float64":
+        b.if(`dst, err = appendJSONFloat(dst, ${expr}); err != nil`, fail);
+        return;
+      case "struct":
+        b.if(`dst, err = ${paren(expr)}.appendJSON(dst); err != nil`, fail);
+        return;
+    }
+
+    // Nil pointers, slices and maps are null
+    b.l(`if ${expr} == nil {`)
+      .i()
+      .l('dst = append(dst, "null"...)')
+      .u()
+      .l("} else {")
+      .i();
+    const suffix = depth === 0 ? "" : String(depth);
+    switch (type.kind) {
+      case "pointer":
+        this.appendValue(b, type.elem, `*${expr}`, depth);
+        break;
+      case "slice":
+        b.l("dst = append(dst, '[')")
+          .l(`for i${suffix}, item${suffix} := range ${expr} {`)
+          .i()
+          .if(`i${suffix} > 0`, (b) => {
+            b.l("dst = append(dst, ',')");
+          });
+        this.appendValue(b, type.elem, `item${suffix}`, depth + 1);
+        b.u().l("}").l("dst = append(dst, ']')");
+        break;
+      case "map":
+        b.l("dst = append(dst, '{')")
+          .l(`for i${suffix}, key${suffix} := range sortedJSONKeys(${expr}) {`)
+          .i()
+          .if(`i${suffix} > 0`, (b) => {
+            b.l("dst = append(dst, ',')");
+          })
+          .l(`dst = appendJSONString(dst, key${suffix})`)
+          .l("dst = append(dst, ':')");
+        this.appendValue(
+          b,
+          type.elem,
+          `${paren(expr)}[key${suffix}]`,
+          depth + 1,
+        );
+        b.u().l("}").l("dst = append(dst, '}')");
+        break;
+    }
+    b.u().l("}");
+  }
+
+  // Emits the statements decoding the next value into target, of type goType
+  private decodeValue(
+    b: GoBuilder,
+    type: StaticType | undefined,
+    goType: string,
+    target: string,
+    fail: Fail,
+    depth: number,
+  ): void {
+    const decode = (call: string) => {
+      b.if(`err := ${call}; err != nil`, fail);
+    };
+    if (!type) {
+      // Like encoding/json, the errors of the UnmarshalJSON methods of named
+      // types are returned as they are
+      b.if(
+        `err := decodeJSONValue(d, ${address(target)}); err != nil`,
+        isNamed(goType)
+          ? (b) => {
+              b.return("err");
+            }
+          : fail,
+      );
+      return;
+    }
+    switch (type.kind) {
+      case "string":
+        decode(`decodeJSONString(d, ${address(target)})`);
+        return;
+      case "bool":
+        decode(`decodeJSONBool(d, ${address(target)})`);
+        return;
+      case "float64":
+        decode(`decodeJSONFloat(d, ${address(target)})`);
+        return;
+      case "int":
+        decode(`decodeJSONInt(d, ${address(target)})`);
+        return;
+      case "struct":
+        decode(`${receiver(target)}.decodeJSON(d)`);
+        return;
+    }
+
+    b.l("if d.null() {")
+      .i()
+      .l(`${target} = nil`)
+      .u()
+      .l("} else {")
+      .i();
+    const suffix = depth === 0 ? "" : String(depth);
+    switch (type.kind) {
+      case "pointer":
+        b.if(`${target} == nil`, (b) => {
+          b.l(`${target} = new(${goType.slice(1)})`);
+        });
+        this.decodeValue(
+          b,
+          type.elem,
+          goType.slice(1),
+          `*${target}`,
+          fail,
+          depth,
+        );
+        break;
+      case "slice": {
+        const elemType = goType.slice(2);
+        b.decl(`items${suffix}`, `${paren(target)}[:0]`)
+          .l(
+            `for ok, err := d.open('[', ${address(target)}); ok || err != nil; ok, err = d.more('[') {`,
+          )
+          .i()
+          .ifErr(fail)
+          .var(`item${suffix}`, elemType);
+        this.decodeValue(
+          b,
+          type.elem,
+          elemType,
+          `item${suffix}`,
+          fail,
+          depth + 1,
+        );
+        b.l(`items${suffix} = append(items${suffix}, item${suffix})`)
+          .u()
+          .l("}")
+          .comment("Empty arrays decode to empty slices, not nil ones")
+          .if(`items${suffix} == nil`, (b) => {
+            b.l(`items${suffix} = ${goType}{}`);
+          })
+          .l(`${target} = items${suffix}`);
+        break;
+      }
+      case "map": {
+        const elemType = goType.slice("map[string]".length);
+        b.if(`${target} == nil`, (b) => {
+          b.l(`${target} = ${goType}{}`);
+        })
+          .l(
+            `for ok, err := d.open('{', ${address(target)}); ok || err != nil; ok, err = d.more('{') {`,
+          )
+          .i()
+          .ifErr(fail)
+          .decl(`name${suffix}, err`, "d.key()")
+          .ifErr(fail)
+          .var(`item${suffix}`, elemType);
+        this.decodeValue(
+          b,
+          type.elem,
+          elemType,
+          `item${suffix}`,
+          fail,
+          depth + 1,
+        );
+        b.l(`${paren(target)}[name${suffix}] = item${suffix}`)
+          .u()
+          .l("}");
+        break;
+      }
+    }
+    b.u().l("}");
+  }
+
+  // How goType is encoded, or undefined to leave it to encoding/json
+  private staticType(goType: string): StaticType | undefined {
+    switch (goType) {
+      case "string":
+      case "bool":
+      case "float64":
+      case "int":
+        return { kind: goType };
+      // Base64 strings
+      case "[]byte":
+        return undefined;
+    }
+    const struct = this.aliases.get(goType) ?? goType;
+    if (this.structs.has(struct)) {
+      return { kind: "struct", name: struct };
+    }
+    const composite = (
+      kind: "pointer" | "slice" | "map",
+      elem: string,
+    ): StaticType | undefined => {
+      // Composites of other types are left to encoding/json whole
+      const elemType = this.staticType(elem);
+      return elemType && { kind, type: goType, elem: elemType };
+    };
+    if (goType.startsWith("*")) {
+      return composite("pointer", goType.slice(1));
+    }
+    if (goType.startsWith("[]")) {
+      return composite("slice", goType.slice(2));
+    }
+    if (goType.startsWith("map[string]")) {
+      return composite("map", goType.slice("map[string]".length));
+    }
+    return undefined;
+  }
+
+  private isFallible(type: StaticType | undefined): boolean {
+    if (!type) {
+      return true;
+    }
+    switch (type.kind) {
+      case "float64":
+      case "struct":
+        return true;
+      case "pointer":
+      case "slice":
+      case "map":
+        return this.isFallible(type.elem);
+      default:
+        return false;
+    }
+  }
+
+  // The condition under which omitempty writes expr, of type goType: undefined
+  // when it always does, as for structs, and false when the type doesn't tell
+  private isEmpty(
+    goType: string,
+    expr: string,
+    structs: Set<string>,
+  ): string | undefined | false {
+    if (
+      goType.startsWith("*") ||
+      goType === "interface{}" ||
+      goType.startsWith("func(")
+    ) {
+      return `${expr} != nil`;
+    }
+    if (goType.startsWith("[]") || goType.startsWith("map[")) {
+      return `len(${expr}) > 0`;
+    }
+    switch (goType) {
+      case "string":
+        return `${expr} != ""`;
+      case "bool":
+        return expr;
+      case "int":
+      case "float64":
+        return `${expr} != 0`;
+    }
+    if (structs.has(this.aliases.get(goType) ?? goType)) {
+      return undefined;
+    }
+    return false;
+  }
+}
+
+// Whether goType, or the type it points to, is a named type other than a
+// builtin, such as an enum or Date
+function isNamed(goType: string): boolean {
+  const base = goType.replace(/^\*+/, "");
+  return /^[A-Za-z_][\w.]*$/.test(base) && !BUILTIN_TYPES.has(base);
+}
+
+const BUILTIN_TYPES = new Set([
+  "string",
+  "bool",
+  "int",
+  "int64",
+  "float64",
+  "byte",
+  "rune",
+]);
+
+// Parenthesizes a dereference for it to be indexed or called
+function paren(expr: string): string {
+  return expr.startsWith("*") ? `(${expr})` : expr;
+}
+
+// The address of an addressable expression
+function address(expr: string): string {
+  return expr.startsWith("*") ? expr.slice(1) : `&${expr}`;
+}
+
+// An expression whose pointer methods can be called
+function receiver(expr: string): string {
+  return expr.startsWith("*") ? expr.slice(1) : expr;
+}
@@ -0,0 +1,161 @@
+import { GoBuilder } from "./go-builder";
+
+export type JSONCodecBackend = "sonic" | "jsonv2" | "easyjson";
+
+/**
+ * Generates jsoncodec.go: a Codec adapter for a faster JSON implementation,
+ * to install with Router.SetCodec. Only emitted with the `jsonCodec` option,
+ * since like metrics.go it depends on a module outside the standard library.
+ */
+export class GoJSONCodecGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateJSONCodec(backend: JSONCodecBackend): string {
+    const w = this.w.reset();
+
+    switch (backend) {
+      case "sonic":
+        this.generateSonic(w);
+        break;
+      case "jsonv2":
+        this.generateJSONv2(w);
+        break;
+      case "easyjson":
+        this.generateEasyJSON(w);
+        break;
+    }
+
+    return w.toString();
+  }
+
+  private generateSonic(w: GoBuilder): void {
+    w.package(this.packageName).import("github.com/bytedance/sonic");
+
+    w.comment(
+      "SonicCodec is the Codec of github.com/bytedance/sonic, configured to behave",
+    )
+      .comment("like encoding/json. Install it with Router.SetCodec.")
+      .type("SonicCodec", "struct{}");
+
+    w.comment("Marshal encodes v with sonic.")
+      .n()
+      .method(
+        "SonicCodec",
+        "Marshal",
+        "v interface{}",
+        "([]byte, error)",
+        (b) => {
+          b.return("sonic.ConfigStd.Marshal(v)");
+        },
+      );
+
+    w.comment("Unmarshal decodes data into v with sonic.")
+      .n()
+      .method(
+        "SonicCodec",
+        "Unmarshal",
+        "data []byte, v interface{}",
+        "error",
+        (b) => {
+          b.return("sonic.ConfigStd.Unmarshal(data, v)");
+        },
+      );
+  }
+
+  private generateJSONv2(w: GoBuilder): void {
+    w.package(this.packageName)
+      .l('import jsonv2 "github.com/go-json-experiment/json"')
+      .n();
+
+    w.comment(
+      "JSONv2Codec is the Codec of github.com/go-json-experiment/json, the proposed",
+    )
+      .comment(
+        "encoding/json/v2. Its defaults differ from encoding/json: member names match",
+      )
+      .comment(
+        "fields exactly, nil slices and maps encode as [] and {}, and invalid UTF-8 is",
+      )
+      .comment("rejected. Install it with Router.SetCodec.")
+      .type("JSONv2Codec", "struct{}");
+
+    w.comment("Marshal encodes v with jsonv2.Marshal.")
+      .n()
+      .method(
+        "JSONv2Codec",
+        "Marshal",
+        "v interface{}",
+        "([]byte, error)",
+        (b) => {
+          b.return("jsonv2.Marshal(v)");
+        },
+      );
+
+    w.comment("Unmarshal decodes data into v with jsonv2.Unmarshal.")
+      .n()
+      .method(
+        "JSONv2Codec",
+        "Unmarshal",
+        "data []byte, v interface{}",
+        "error",
+        (b) => {
+          b.return("jsonv2.Unmarshal(data, v)");
+        },
+      );
+  }
+
+  private generateEasyJSON(w: GoBuilder): void {
+    w.package(this.packageName).import(
+      "encoding/json",
+      "github.com/mailru/easyjson",
+    );
+
+    w.comment(
+      "EasyJSONCodec encodes and decodes the types with github.com/mailru/easyjson",
+    )
+      .comment(
+        "methods without reflection, and other values with encoding/json. Generate",
+      )
+      .comment(
+        "the methods with `easyjson -all types.go` whenever the contract changes, then",
+      )
+      .comment("install it with Router.SetCodec.")
+      .type("EasyJSONCodec", "struct{}");
+
+    w.comment("Marshal encodes v with its easyjson methods if it has them.")
+      .n()
+      .method(
+        "EasyJSONCodec",
+        "Marshal",
+        "v interface{}",
+        "([]byte, error)",
+        (b) => {
+          b.if("marshaler, ok := v.(easyjson.Marshaler); ok", (b) => {
+            b.return("easyjson.Marshal(marshaler)");
+          }).return("json.Marshal(v)");
+        },
+      );
+
+    w.comment(
+      "Unmarshal decodes data into v with its easyjson methods if it has them.",
+    )
+      .n()
+      .method(
+        "EasyJSONCodec",
+        "Unmarshal",
+        "data []byte, v interface{}",
+        "error",
+        (b) => {
+          b.if("unmarshaler, ok := v.(easyjson.Unmarshaler); ok", (b) => {
+            b.return("easyjson.Unmarshal(data, unmarshaler)");
+          }).return("json.Unmarshal(data, v)");
+        },
+      );
+  }
+}
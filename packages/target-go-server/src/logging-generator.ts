@@ -0,0 +1,218 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates logging.go: the Logger interface the router reports every call
+ * to, the LogEntry it receives, and the response recorder that measures the
+ * status and size of each response and records the tenant of its call.
+ */
+export class GoLoggingGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateLogging(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("context", "net/http", "time");
+
+    this.generateOutcome(w);
+    this.generateLogEntry(w);
+    this.generateResponseRecorder(w);
+
+    return w.toString();
+  }
+
+  private generateOutcome(w: GoBuilder): void {
+    w.comment("Outcome classifies how a call ended.").type("Outcome", "string");
+
+    w.l("const (")
+      .i()
+      .comment("OutcomeSuccess means the handler returned a result.")
+      .l('OutcomeSuccess Outcome = "success"')
+      .comment(
+        "OutcomeValidationError means the params could not be decoded or failed validation.",
+      )
+      .l('OutcomeValidationError Outcome = "validation_error"')
+      .comment(
+        "OutcomeHandlerError means the handler or an interceptor failed or panicked.",
+      )
+      .l('OutcomeHandlerError Outcome = "handler_error"')
+      .comment(
+        "OutcomeRejected means the call never reached validation: the request was",
+      )
+      .comment(
+        "malformed, the method unknown or unregistered, or middleware refused it.",
+      )
+      .l('OutcomeRejected Outcome = "rejected"')
+      .u()
+      .l(")")
+      .n();
+  }
+
+  private generateLogEntry(w: GoBuilder): void {
+    w.comment("LogEntry describes one completed call.").struct(
+      "LogEntry",
+      (b) => {
+        b.l("Method   string")
+          .l("Duration time.Duration")
+          .l("Outcome  Outcome")
+          .comment("Code is the error code sent to the client; empty on success.")
+          .l("Code     ErrorCode")
+          .l("Status   int")
+          .comment("Size is the number of response body bytes written.")
+          .l("Size     int")
+          .comment(
+            "Tenant is the ID of the tenant middleware resolved for the call, if any.",
+          )
+          .l("Tenant   string");
+      },
+    );
+
+    w.comment(
+      "Logger receives an entry for every call once its response has been written.",
+    )
+      .l("type Logger interface {")
+      .i()
+      .l("LogRequest(ctx context.Context, entry LogEntry)")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("SetLogger sets the Logger that receives an entry for every call.")
+      .n()
+      .method("r *Router", "SetLogger", "logger Logger", "*Router", (b) => {
+        b.l("r.logger = logger").return("r");
+      });
+
+    w.comment(
+      "MultiLogger returns a Logger that passes every entry to each of loggers, so",
+    )
+      .comment("request logs and metrics can be recorded together.")
+      .n()
+      .func("MultiLogger(loggers ...Logger) Logger", (b) => {
+        b.return("multiLogger(loggers)");
+      });
+
+    w.type("multiLogger", "[]Logger");
+
+    w.method(
+      "m multiLogger",
+      "LogRequest",
+      "ctx context.Context, entry LogEntry",
+      "",
+      (b) => {
+        b.l("for _, logger := range m {")
+          .i()
+          .l("logger.LogRequest(ctx, entry)")
+          .u()
+          .l("}");
+      },
+    );
+  }
+
+  private generateResponseRecorder(w: GoBuilder): void {
+    w.comment(
+      "responseRecorder records the status, size and error code of a response, and",
+    )
+      .comment("the tenant of its call, for the Logger.")
+      .struct("responseRecorder", (b) => {
+        b.l("http.ResponseWriter")
+          .l("status int")
+          .l("size   int")
+          .l("code   ErrorCode")
+          .l("tenant string");
+      });
+
+    w.comment(
+      "errorCodeRecorder is implemented by the responseRecorder of every generated",
+    )
+      .comment(
+        "package, so the codes of errors written by mounted routers are logged too.",
+      )
+      .l("type errorCodeRecorder interface {")
+      .i()
+      .l("RecordErrorCode(code string)")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("RecordErrorCode records the code of the error response being written.")
+      .n()
+      .method(
+        "rec *responseRecorder",
+        "RecordErrorCode",
+        "code string",
+        "",
+        (b) => {
+          b.l("rec.code = ErrorCode(code)");
+        },
+      );
+
+    w.comment(
+      "tenantRecorder is implemented by the responseRecorder of every generated",
+    )
+      .comment(
+        "package, so the tenants of calls served by mounted routers are logged too.",
+      )
+      .l("type tenantRecorder interface {")
+      .i()
+      .l("RecordTenant(tenant string)")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("RecordTenant records the ID of the tenant of the call.")
+      .n()
+      .method("rec *responseRecorder", "RecordTenant", "tenant string", "", (b) => {
+        b.l("rec.tenant = tenant");
+      });
+
+    w.method(
+      "rec *responseRecorder",
+      "WriteHeader",
+      "status int",
+      "",
+      (b) => {
+        b.l("rec.status = status").l("rec.ResponseWriter.WriteHeader(status)");
+      },
+    );
+
+    w.method(
+      "rec *responseRecorder",
+      "Write",
+      "p []byte",
+      "(int, error)",
+      (b) => {
+        b.decl("n, err", "rec.ResponseWriter.Write(p)")
+          .l("rec.size += n")
+          .return("n, err");
+      },
+    );
+
+    w.comment("Flush lets subscriptions stream through the recorder.")
+      .n()
+      .method("rec *responseRecorder", "Flush", "", "", (b) => {
+        b.if("flusher, ok := rec.ResponseWriter.(http.Flusher); ok", (b) => {
+          b.l("flusher.Flush()");
+        });
+      });
+
+    w.comment(
+      "Unwrap returns the underlying ResponseWriter for http.ResponseController.",
+    )
+      .n()
+      .method(
+        "rec *responseRecorder",
+        "Unwrap",
+        "",
+        "http.ResponseWriter",
+        (b) => {
+          b.return("rec.ResponseWriter");
+        },
+      );
+  }
+}
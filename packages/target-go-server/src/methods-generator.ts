@@ -0,0 +1,572 @@
+import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
+import { eventConstName, eventPayloadType } from "./events-generator";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { toPascalCase } from "./naming";
+import {
+  INTROSPECT_METHOD,
+  OPERATION_GET_METHOD,
+  toFieldName,
+  toMethodName,
+} from "./server-generator";
+import { handlerFuncType } from "./type-generator";
+import { streamItemType } from "./type-mapper";
+
+/**
+ * Generates methods.go: the table describing every method of the contract,
+ * with the functions decoding and validating its params and calling its
+ * handler. The router looks methods up in the table instead of switching on
+ * their name, and GET routing, introspection, SingleFlight and metrics
+ * iterate it rather than keeping method lists of their own.
+ */
+export class GoMethodsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * @param normalizable - Input types with a Normalize method, called on
+   * every decoded input (GoNormalizeGenerator.getNormalizable)
+   * @param defaulted - Input types with an ApplyDefaults method, called on
+   * every decoded input before Normalize (GoDefaultsGenerator.getDefaulted)
+   * @param warned - Input types with a Warnings method, called on every
+   * validated input (GoWarningsGenerator.getWarned)
+   * @param coerceFunctions - The coerce functions of input types, called on
+   * params with lenient decoding (GoCoercionGenerator.getCoerceFunctions)
+   */
+  generateMethods(
+    contract: ContractDefinition,
+    normalizable: Set<string> = new Set(),
+    defaulted: Set<string> = new Set(),
+    warned: Set<string> = new Set(),
+    coerceFunctions: Map<string, string> = new Map(),
+  ): string {
+    const w = this.w.reset();
+    const hasSubscriptions = contract.endpoints.some(
+      (endpoint) => endpoint.type === "subscription",
+    );
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "fmt",
+      "net/http",
+    );
+
+    w.comment(
+      "methodDescriptor describes a method: its name, kind (query, mutation or",
+    )
+      .comment(
+        "subscription), the auth and tenant it requires, and the functions the router",
+      )
+      .comment(
+        "calls it through. Inputs are passed as interface{} holding the method's input",
+      )
+      .comment("type.")
+      .struct("methodDescriptor", (b) => {
+        b.l("name string")
+          .l("kind string")
+          .comment("Whether a middleware must have authenticated the caller")
+          .l("auth bool")
+          .comment("Whether a middleware must have resolved the caller's tenant")
+          .l("tenant bool")
+          .l("permissions []string")
+          .comment(
+            "Whether calls run in the background, answered with the Operation running",
+          )
+          .comment("them")
+          .l("async bool")
+          .comment(
+            "Deprecation and Sunset response headers of a deprecated method; empty",
+          )
+          .comment("otherwise")
+          .l("deprecation string")
+          .l("sunset string")
+          .comment("registered reports whether r has a handler for the method")
+          .l("registered func(r *Router) bool")
+          .comment(
+            "replace sets r's handler for the method, reporting whether handler has its",
+          )
+          .comment("type")
+          .l("replace func(r *Router, handler interface{}) bool")
+          .comment(
+            "coerce coerces the numbers and strings of decoded params with lenient",
+          )
+          .comment("decoding; nil for inputs without any")
+          .l(
+            "coerce func(v map[string]interface{}, path []PathSegment, errs *ValidationErrors)",
+          )
+          .comment("decode decodes params into the method's input")
+          .l("decode func(r *Router, params json.RawMessage) (interface{}, error)")
+          .l("validate func(input interface{}) error");
+        if (warned.size > 0) {
+          b.comment(
+            "warn reports the values of a validated input past their soft limits; nil",
+          )
+            .comment("for inputs without any")
+            .l("warn func(input interface{}) ValidationErrors");
+        }
+        b.comment(
+          "invoke calls the handler, collecting streamed items into the output; nil",
+        )
+          .comment("for subscriptions")
+          .l(
+            "invoke func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error)",
+          )
+          .comment(
+            "decodeResult decodes a result of the method encoded as JSON, for the Cache",
+          )
+          .comment("interceptor; nil for methods other than unstreamed queries")
+          .l("decodeResult func(data []byte) (interface{}, error)")
+          .comment(
+            "serve calls the handler of a subscription or streamed query over HTTP,",
+          )
+          .comment(
+            "writing what it produces as it goes, and returns the outcome; nil for",
+          )
+          .comment("methods answered with a single result")
+          .l(
+            "serve func(r *Router, ctx context.Context, info RequestInfo, input interface{}, w http.ResponseWriter, req *http.Request) Outcome",
+          )
+          .comment(
+            "event wraps the result of a successful call in the event the method",
+          )
+          .comment("publishes; nil for methods without one")
+          .l("event func(result interface{}) Event");
+      });
+
+    w.comment("methodDescriptors describes every method, sorted by method name.")
+      .l("var methodDescriptors = []*methodDescriptor{")
+      .i();
+    for (const endpoint of contract.endpoints) {
+      this.generateDescriptor(
+        endpoint,
+        w,
+        normalizable,
+        defaulted,
+        warned,
+        coerceFunctions,
+      );
+    }
+    w.u().l("}").n();
+
+    w.comment("methodTable indexes methodDescriptors by method name.")
+      .l("var methodTable = indexMethods(methodDescriptors)")
+      .n();
+
+    w.comment("indexMethods maps descriptors by their method name.")
+      .n()
+      .func(
+      "indexMethods(descriptors []*methodDescriptor) map[string]*methodDescriptor",
+      (b) => {
+        b.decl(
+          "table",
+          "make(map[string]*methodDescriptor, len(descriptors))",
+        )
+          .l("for _, m := range descriptors {")
+          .i()
+          .l("table[m.name] = m")
+          .u()
+          .l("}")
+          .return("table");
+      },
+    );
+
+    w.comment(
+      "getAllowed reports whether method may be called with GET: queries,",
+    )
+      .comment(
+        `subscriptions, ${INTROSPECT_METHOD} and ${OPERATION_GET_METHOD} can be, mutations must be`,
+      )
+      .comment("POSTed.")
+      .n()
+      .func("getAllowed(method string) bool", (b) => {
+        b.if(
+          `method == "${INTROSPECT_METHOD}" || method == "${OPERATION_GET_METHOD}"`,
+          (b) => {
+            b.return("true");
+          },
+        )
+          .decl("m, ok", "methodTable[method]")
+          .return('ok && m.kind != "mutation"');
+      });
+
+    w.comment(
+      "Replace swaps the handler of method while the router serves calls, such as",
+    )
+      .comment(
+        'Replace("task.get", h) to canary a new implementation or load one from a',
+      )
+      .comment(
+        "plugin. handler is the method's handler type or a func with its signature.",
+      )
+      .comment(
+        "Calls already running finish on the handler they started with; later calls",
+      )
+      .comment(
+        "get the new one. Replace also registers methods that had no handler yet.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "Replace",
+        "method string, handler interface{}",
+        "error",
+        (b) => {
+          b.decl("m, ok", "methodTable[method]")
+            .if("!ok", (b) => {
+              b.return('fmt.Errorf("replace %s: unknown method", method)');
+            })
+            .if("handler == nil || !m.replace(r, handler)", (b) => {
+              b.return(
+                'fmt.Errorf("replace %s: %T is not a handler of the method", method, handler)',
+              );
+            })
+            .return("nil");
+        },
+      );
+
+    w.comment(
+      "loadHandler reads the handler field of r under its handlers lock, so a call",
+    )
+      .comment(
+        "holds on to the handler it loaded while Replace swaps it for another.",
+      )
+      .n()
+      .func("loadHandler[H any](r *Router, field *H) H", (b) => {
+        b.l("r.handlersMu.RLock()")
+          .l("defer r.handlersMu.RUnlock()")
+          .return("*field");
+      });
+
+    w.comment(
+      "prepare runs the checks that come before a call of m: that it is not switched",
+    )
+      .comment(
+        "off, that its handler is registered, its auth and tenant requirements and",
+      )
+      .comment(
+        "permissions, and decoding and validating params. It returns the input, or",
+      )
+      .comment("how far the call got and why it failed.")
+      .n()
+      .method(
+        "r *Router",
+        "prepare",
+        "ctx context.Context, info RequestInfo, m *methodDescriptor, params json.RawMessage",
+        "(interface{}, Outcome, error)",
+        (b) => {
+          b.if("err := r.checkGate(ctx, info, m); err != nil", (b) => {
+            b.return("nil, OutcomeRejected, err");
+          }).if("!m.registered(r)", (b) => {
+            b.return(
+              'nil, OutcomeRejected, NewError(CodeUnimplemented, "Handler not registered")',
+            );
+          });
+          // Methods declared with auth: "required" need a middleware to have
+          // authenticated the caller
+          b.if("m.auth", (b) => {
+            b.if("_, ok := UserIDFrom(ctx); !ok", (b) => {
+              b.return(
+                'nil, OutcomeRejected, NewError(CodeUnauthorized, "Authentication required")',
+              );
+            });
+          })
+            .if("m.tenant", (b) => {
+              b.if("_, ok := TenantFrom(ctx); !ok", (b) => {
+                b.return(
+                  'nil, OutcomeRejected, NewError(CodeInvalidArgument, "Tenant required")',
+                );
+              });
+            })
+            .if("len(m.permissions) > 0", (b) => {
+              b.if(
+                "err := r.authorize(ctx, info, m.permissions); err != nil",
+                (b) => {
+                  b.return("nil, OutcomeRejected, err");
+                },
+              );
+            })
+            .n()
+            .if("r.lenientDecoding && m.coerce != nil", (b) => {
+              b.decl("coerced, err", "coerceParams(params, m.coerce)")
+                .ifErr((b) => {
+                  b.return("nil, OutcomeValidationError, err");
+                })
+                .l("params = coerced");
+            })
+            .decl("input, err", "m.decode(r, params)")
+            .ifErr((b) => {
+              b.return(
+                "nil, OutcomeValidationError, paramsError(params, err)",
+              );
+            })
+            .if("err := m.validate(input); err != nil", (b) => {
+              b.return("nil, OutcomeValidationError, err");
+            });
+          if (warned.size > 0) {
+            b.if("m.warn != nil", (b) => {
+              b.l("addWarnings(ctx, m.warn(input))");
+            });
+          }
+          b.return("input, OutcomeSuccess, nil");
+        },
+      );
+
+    if (hasSubscriptions) {
+      this.generateSubscriptionSupport(w);
+    }
+
+    return w.toString();
+  }
+
+  private generateDescriptor(
+    endpoint: Endpoint,
+    w: GoBuilder,
+    normalizable: Set<string>,
+    defaulted: Set<string>,
+    warned: Set<string>,
+    coerceFunctions: Map<string, string>,
+  ): void {
+    const fieldName = toFieldName(endpoint.fullName);
+    const methodName = toMethodName(endpoint.fullName);
+    const inputType = toPascalCase(endpoint.input.name!);
+    const itemType = endpoint.stream
+      ? streamItemType(endpoint).type
+      : undefined;
+
+    w.l("{")
+      .i()
+      .l(`name: "${endpoint.fullName}",`)
+      .l(`kind: "${endpoint.type}",`);
+    if (endpoint.auth === "required") {
+      w.l("auth: true,");
+    }
+    if (endpoint.tenant === "required") {
+      w.l("tenant: true,");
+    }
+    if (endpoint.permissions) {
+      const permissions = endpoint.permissions.map(goStringLiteral).join(", ");
+      w.l(`permissions: []string{${permissions}},`);
+    }
+    if (endpoint.async) {
+      w.l("async: true,");
+    }
+    if (endpoint.deprecated) {
+      const { since, sunset } = endpoint.deprecated;
+      // RFC 9745 dates are Unix times; deprecations without one use the
+      // "true" of the drafts before it
+      const deprecation = since
+        ? `@${Date.parse(`${since}T00:00:00Z`) / 1000}`
+        : "true";
+      w.l(`deprecation: "${deprecation}",`);
+      if (sunset) {
+        // RFC 8594 uses HTTP dates
+        w.l(
+          `sunset: "${new Date(`${sunset}T00:00:00Z`).toUTCString()}",`,
+        );
+      }
+    }
+    w.l("registered: func(r *Router) bool {")
+      .i()
+      .return(`loadHandler(r, &r.${fieldName}) != nil`)
+      .u()
+      .l("},")
+      .l("replace: func(r *Router, handler interface{}) bool {")
+      .i()
+      .l("switch h := handler.(type) {")
+      .l(`case ${methodName}Handler:`)
+      .i()
+      .l(`r.${methodName}(h)`)
+      .u()
+      // Func literals keep their unnamed type
+      .l(`case ${handlerFuncType(endpoint, itemType)}:`)
+      .i()
+      .l(`r.${methodName}(h)`)
+      .u()
+      .l("default:")
+      .i()
+      .return("false")
+      .u()
+      .l("}")
+      .return("true")
+      .u()
+      .l("},");
+    const coerce = coerceFunctions.get(inputType);
+    if (coerce) {
+      w.l(`coerce: ${coerce},`);
+    }
+    w.l("decode: func(r *Router, params json.RawMessage) (interface{}, error) {")
+      .i()
+      .var("input", inputType);
+    if (defaulted.has(inputType) || normalizable.has(inputType)) {
+      w.if("err := r.unmarshal(params, &input); err != nil", (b) => {
+        b.return("input, err");
+      });
+      if (defaulted.has(inputType)) {
+        w.l("input.ApplyDefaults()");
+      }
+      if (normalizable.has(inputType)) {
+        w.l("input.Normalize()");
+      }
+      w.return("input, nil");
+    } else {
+      w.decl("err", "r.unmarshal(params, &input)").return("input, err");
+    }
+    w.u()
+      .l("},")
+      .l("validate: func(input interface{}) error {")
+      .i()
+      .return(`Validate${inputType}(input.(${inputType}))`)
+      .u()
+      .l("},");
+    if (warned.has(inputType)) {
+      w.l("warn: func(input interface{}) ValidationErrors {")
+        .i()
+        .return(`input.(${inputType}).Warnings()`)
+        .u()
+        .l("},");
+    }
+
+    if (endpoint.type !== "subscription") {
+      w.l(
+        "invoke: func(r *Router, ctx context.Context, info RequestInfo, input interface{}) (interface{}, error) {",
+      ).i();
+      if (endpoint.stream) {
+        // Outside of HTTP streamed items are collected into the output
+        w.decl("items", `[]${streamItemType(endpoint).type}{}`)
+          .decl(
+            "output, err",
+            `loadHandler(r, &r.${fieldName})(ctx, info, input.(${inputType}), collectStream(&items))`,
+          )
+          .l(`output.${toPascalCase(endpoint.stream)} = items`)
+          .return("output, err");
+      } else {
+        w.return(
+          `loadHandler(r, &r.${fieldName})(ctx, info, input.(${inputType}))`,
+        );
+      }
+      w.u().l("},");
+    }
+
+    if (endpoint.type === "query" && !endpoint.stream) {
+      w.l("decodeResult: func(data []byte) (interface{}, error) {")
+        .i()
+        .var("output", toPascalCase(endpoint.output.name!))
+        .decl("err", "json.Unmarshal(data, &output)")
+        .return("output, err")
+        .u()
+        .l("},");
+    }
+
+    if (endpoint.event) {
+      const outputType = toPascalCase(endpoint.output.name!);
+      w.l("event: func(result interface{}) Event {")
+        .i()
+        .return(
+          `Event{Name: ${eventConstName(endpoint)}, Payload: ${eventPayloadType(endpoint)}(result.(${outputType}))}`,
+        )
+        .u()
+        .l("},");
+    }
+
+    if (endpoint.type === "subscription" || endpoint.stream) {
+      w.l(
+        "serve: func(r *Router, ctx context.Context, info RequestInfo, input interface{}, w http.ResponseWriter, req *http.Request) Outcome {",
+      ).i();
+      if (endpoint.stream) {
+        this.generateStreamServe(endpoint, w);
+      } else {
+        w.l(
+          "return r.serveSubscription(ctx, w, func(send func(event interface{}) error) error {",
+        )
+          .i()
+          .l(
+            `return loadHandler(r, &r.${fieldName})(ctx, info, input.(${inputType}), func(event ${toPascalCase(endpoint.output.name!)}) error {`,
+          )
+          .i()
+          .return("send(event)")
+          .u()
+          .l("})")
+          .u()
+          .l("})");
+      }
+      w.u().l("},");
+    }
+
+    w.u().l("},");
+  }
+
+  private generateStreamServe(endpoint: Endpoint, w: GoBuilder): void {
+    const fieldName = toFieldName(endpoint.fullName);
+    const inputType = toPascalCase(endpoint.input.name!);
+    const itemType = streamItemType(endpoint).type;
+
+    w.decl("stream", `newHTTPStream(w, req, "${endpoint.stream}")`)
+      .l(
+        "result, callErr := r.invokeStream(ctx, info, input, stream, func(ctx context.Context) (interface{}, error) {",
+      )
+      .i()
+      .return(
+        `loadHandler(r, &r.${fieldName})(ctx, info, input.(${inputType}), streamTo[${itemType}](stream))`,
+      )
+      .u()
+      .l("})")
+      .if("err := stream.finish(result, callErr); err != nil", (b) => {
+        b.l("r.writeError(w, AsError(err))").return("OutcomeHandlerError");
+      })
+      .if("callErr != nil", (b) => {
+        b.comment("finish wrote it into the stream already")
+          .l("r.logCause(AsError(callErr))")
+          .return("OutcomeHandlerError");
+      })
+      .return("OutcomeSuccess");
+  }
+
+  private generateSubscriptionSupport(w: GoBuilder): void {
+    w.comment(
+      "serveSubscription streams the events a subscription handler sends to w as",
+    )
+      .comment(
+        "Server-Sent Events. Handler errors after the stream started can only be",
+      )
+      .comment("reported in-band, as an error event.")
+      .n()
+      .method(
+        "r *Router",
+        "serveSubscription",
+        "ctx context.Context, w http.ResponseWriter, run func(send func(event interface{}) error) error",
+        "Outcome",
+        (b) => {
+          b.decl("flusher, ok", "w.(http.Flusher)")
+            .if("!ok", (b) => {
+              b.l(
+                'r.writeError(w, NewError(CodeInternal, "Streaming not supported"))',
+              ).return("OutcomeHandlerError");
+            })
+            .n()
+            .l('w.Header().Set("Content-Type", "text/event-stream")')
+            .l('w.Header().Set("Cache-Control", "no-cache")')
+            .l('w.Header().Set("Connection", "keep-alive")')
+            .l("w.WriteHeader(http.StatusOK)")
+            .l("flusher.Flush()")
+            .n()
+            .decl("send", "func(event interface{}) error {")
+            .i()
+            .return('writeEvent(w, flusher, "", event)')
+            .u()
+            .l("}")
+            .if("err := run(send); err != nil && ctx.Err() == nil", (b) => {
+              b.decl("rpcErr", "AsError(err)")
+                .l("r.logCause(rpcErr)")
+                .l('writeEvent(w, flusher, "error", errorEnvelope{Error: rpcErr})')
+                .return("OutcomeHandlerError");
+            })
+            .return("OutcomeSuccess");
+        },
+      );
+  }
+}
@@ -0,0 +1,206 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates metrics.go: a Logger that records per-method and per-tenant
+ * Prometheus metrics, and a collector exporting the load of the router's concurrency limits.
+ * Only emitted with the `metrics: "prometheus"` option, since it depends on a
+ * module outside the standard library.
+ */
+export class GoMetricsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateMetrics(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "github.com/prometheus/client_golang/prometheus",
+    );
+
+    w.comment(
+      "Metrics records a request counter by method and outcome, an error counter by",
+    )
+      .comment(
+        "method and code, a latency histogram by method, and a request counter by",
+      )
+      .comment(
+        "tenant and outcome for calls with a tenant. It implements Logger; install it",
+      )
+      .comment("with Router.SetLogger.")
+      .struct("Metrics", (b) => {
+        b.l("requests *prometheus.CounterVec")
+          .l("errors   *prometheus.CounterVec")
+          .l("duration *prometheus.HistogramVec")
+          .l("tenants  *prometheus.CounterVec");
+      })
+      .n();
+
+    w.comment(
+      "NewMetrics creates the xrpc_requests_total, xrpc_errors_total,",
+    )
+      .comment(
+        "xrpc_request_duration_seconds and xrpc_tenant_requests_total metrics and",
+      )
+      .comment("registers them with reg.")
+      .n()
+      .func("NewMetrics(reg prometheus.Registerer) *Metrics", (b) => {
+        b.decl("m", "&Metrics{")
+          .i()
+          .l("requests: prometheus.NewCounterVec(prometheus.CounterOpts{")
+          .i()
+          .l('Name: "xrpc_requests_total",')
+          .l('Help: "xRPC calls by method and outcome.",')
+          .u()
+          .l('}, []string{"method", "outcome"}),')
+          .l("errors: prometheus.NewCounterVec(prometheus.CounterOpts{")
+          .i()
+          .l('Name: "xrpc_errors_total",')
+          .l('Help: "Failed xRPC calls by method and error code.",')
+          .u()
+          .l('}, []string{"method", "code"}),')
+          .l("duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{")
+          .i()
+          .l('Name:    "xrpc_request_duration_seconds",')
+          .l('Help:    "xRPC call latency by method.",')
+          .l("Buckets: prometheus.DefBuckets,")
+          .u()
+          .l('}, []string{"method"}),')
+          .l("tenants: prometheus.NewCounterVec(prometheus.CounterOpts{")
+          .i()
+          .l('Name: "xrpc_tenant_requests_total",')
+          .l('Help: "xRPC calls by tenant and outcome.",')
+          .u()
+          .l('}, []string{"tenant", "outcome"}),')
+          .u()
+          .l("}")
+          .l("reg.MustRegister(m.requests, m.errors, m.duration, m.tenants)")
+          .return("m");
+      });
+
+    w.comment("LogRequest records entry in the metrics.")
+      .n()
+      .method(
+        "m *Metrics",
+        "LogRequest",
+        "ctx context.Context, entry LogEntry",
+        "",
+        (b) => {
+          b.decl("method", "entry.Method")
+            .comment(
+              "Calls to methods outside the contract are recorded as unknown so clients",
+            )
+            .comment("cannot grow the label set")
+            .if("_, ok := methodTable[method]; !ok", (b) => {
+              b.l('method = "unknown"');
+            })
+            .l(
+              "m.requests.WithLabelValues(method, string(entry.Outcome)).Inc()",
+            )
+            .if('entry.Code != ""', (b) => {
+              b.l("m.errors.WithLabelValues(method, string(entry.Code)).Inc()");
+            })
+            .l(
+              "m.duration.WithLabelValues(method).Observe(entry.Duration.Seconds())",
+            )
+            .comment(
+              "The verify function of ResolveTenant keeps tenants to known ones, so",
+            )
+            .comment("clients cannot grow this label set either")
+            .if('entry.Tenant != ""', (b) => {
+              b.l(
+                "m.tenants.WithLabelValues(entry.Tenant, string(entry.Outcome)).Inc()",
+              );
+            });
+        },
+      );
+
+    this.generateConcurrencyCollector(w);
+
+    return w.toString();
+  }
+
+  private generateConcurrencyCollector(w: GoBuilder): void {
+    w.comment(
+      "concurrencyCollector exports the ConcurrencyStats of a router as gauges.",
+    ).struct("concurrencyCollector", (b) => {
+      b.l("router    *Router")
+        .l("executing *prometheus.Desc")
+        .l("queued    *prometheus.Desc")
+        .l("limit     *prometheus.Desc");
+    });
+
+    w.comment(
+      "NewConcurrencyCollector returns a collector exporting the load of the",
+    )
+      .comment(
+        "concurrency limits of r as the xrpc_concurrency_executing,",
+      )
+      .comment(
+        "xrpc_concurrency_queued and xrpc_concurrency_limit gauges, labelled by the",
+      )
+      .comment(
+        "limit's method pattern. Register it with reg.MustRegister to watch the queues",
+      )
+      .comment("fill up before calls are shed.")
+      .n()
+      .func("NewConcurrencyCollector(r *Router) prometheus.Collector", (b) => {
+        b.decl("labels", '[]string{"pattern"}')
+          .l("return &concurrencyCollector{")
+          .i()
+          .l("router: r,")
+          .l(
+            'executing: prometheus.NewDesc("xrpc_concurrency_executing", "xRPC calls executing under a concurrency limit.", labels, nil),',
+          )
+          .l(
+            'queued: prometheus.NewDesc("xrpc_concurrency_queued", "xRPC calls queued for a slot of a concurrency limit.", labels, nil),',
+          )
+          .l(
+            'limit: prometheus.NewDesc("xrpc_concurrency_limit", "xRPC calls a concurrency limit lets execute at once.", labels, nil),',
+          )
+          .u()
+          .l("}");
+      });
+
+    w.comment("Describe sends the descriptors of the gauges.")
+      .n()
+      .method(
+        "c *concurrencyCollector",
+        "Describe",
+        "ch chan<- *prometheus.Desc",
+        "",
+        (b) => {
+          b.l("ch <- c.executing").l("ch <- c.queued").l("ch <- c.limit");
+        },
+      );
+
+    w.comment("Collect sends the current load of every concurrency limit.")
+      .n()
+      .method(
+        "c *concurrencyCollector",
+        "Collect",
+        "ch chan<- prometheus.Metric",
+        "",
+        (b) => {
+          b.l("for _, stats := range c.router.ConcurrencyStats() {")
+            .i()
+            .l(
+              "ch <- prometheus.MustNewConstMetric(c.executing, prometheus.GaugeValue, float64(stats.Executing), stats.Pattern)",
+            )
+            .l(
+              "ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(stats.Queued), stats.Pattern)",
+            )
+            .l(
+              "ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, float64(stats.Max), stats.Pattern)",
+            )
+            .u()
+            .l("}");
+        },
+      );
+  }
+}
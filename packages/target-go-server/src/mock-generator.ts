@@ -0,0 +1,339 @@
+import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
+import { collectExamples } from "./examples-generator";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { toMethodName } from "./server-generator";
+import { streamItemType } from "./type-mapper";
+
+/**
+ * Generates mock.go: a MockServer with a stub per method that answers with
+ * canned typed responses or programmable functions, and records the inputs
+ * it was called with. Until stubbed, methods answer with their example
+ * output from examples.go, or are unimplemented when they have none.
+ */
+export class GoMockGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate mock.go.
+   * @param contract - The contract definition
+   * @param hasValidators - Whether validators.go was generated, so the mock registers custom validators
+   */
+  generateMock(contract: ContractDefinition, hasValidators = false): string {
+    const w = this.w.reset();
+    const examples = collectExamples(contract);
+
+    w.package(this.packageName).import("context", "net/http", "sync");
+
+    w.comment(
+      "MockServer serves the API from stubs instead of handlers, for Go consumer tests",
+    )
+      .comment(
+        "(with httptest.NewServer) and frontend e2e environments that should not run",
+      )
+      .comment(
+        "the real backend. Stub a method with its field, e.g. mock.TaskGet.Returns(out, nil).",
+      )
+      .struct("MockServer", (b) => {
+        for (const endpoint of contract.endpoints) {
+          const name = toMethodName(endpoint.fullName);
+          b.l(`${name} *${name}Stub`);
+        }
+        b.l("router *Router");
+      });
+
+    w.comment(
+      "NewMockServer returns a MockServer whose methods answer with their example output",
+    )
+      .comment("until stubbed.")
+      .n()
+      .func("NewMockServer() *MockServer", (b) => {
+        if (hasValidators) {
+          b.l("registerMockValidators()");
+        }
+        b.l("m := &MockServer{").i();
+        for (const endpoint of contract.endpoints) {
+          this.generateDefaultStub(b, endpoint, examples);
+        }
+        b.u().l("}");
+        if (contract.endpoints.length === 0) {
+          b.l("m.router = NewRouter()");
+        } else {
+          b.l("m.router = NewRouter().").i();
+          contract.endpoints.forEach((endpoint, index) => {
+            const name = toMethodName(endpoint.fullName);
+            const last = index === contract.endpoints.length - 1;
+            b.l(`${name}(m.${name}.handle)${last ? "" : "."}`);
+          });
+          b.u();
+        }
+        b.return("m");
+      });
+
+    w.comment(
+      "Router returns the router serving the stubs, e.g. to add middleware.",
+    )
+      .n()
+      .method("m *MockServer", "Router", "", "*Router", (b) => {
+        b.return("m.router");
+      });
+
+    w.comment("ServeHTTP serves the API like Router.ServeHTTP.")
+      .n()
+      .method(
+        "m *MockServer",
+        "ServeHTTP",
+        "w http.ResponseWriter, req *http.Request",
+        "",
+        (b) => {
+          b.l("m.router.ServeHTTP(w, req)");
+        },
+      );
+
+    if (hasValidators) {
+      w.comment(
+        "registerMockValidators registers custom validators that accept every value",
+      )
+        .comment("for the ones the process has not registered.")
+        .n()
+        .func("registerMockValidators()", (b) => {
+          b.l("for _, name := range customValidators {")
+            .i()
+            .if("_, ok := validators[name]; !ok", (b) => {
+              b.l(
+                "RegisterValidator(name, func(interface{}) error { return nil })",
+              );
+            })
+            .u()
+            .l("}");
+        });
+    }
+
+    for (const endpoint of contract.endpoints) {
+      if (endpoint.type === "subscription") {
+        this.generateStreamStub(w, endpoint);
+      } else {
+        this.generateStub(w, endpoint);
+      }
+    }
+
+    return w.toString();
+  }
+
+  private generateDefaultStub(
+    b: GoBuilder,
+    endpoint: Endpoint,
+    examples: Map<string, string>,
+  ): void {
+    const name = toMethodName(endpoint.fullName);
+    const inputType = toPascalCase(endpoint.input.name!);
+    const outputType = toPascalCase(endpoint.output.name!);
+
+    if (!examples.has(outputType)) {
+      b.l(`${name}: &${name}Stub{},`);
+      return;
+    }
+    if (endpoint.type === "subscription") {
+      b.l(
+        `${name}: (&${name}Stub{}).Func(func(ctx context.Context, input ${inputType}, send func(${outputType}) error) error {`,
+      )
+        .i()
+        .return(`send(Example${outputType}())`)
+        .u()
+        .l("}),");
+      return;
+    }
+    b.l(
+      `${name}: (&${name}Stub{}).Func(func(ctx context.Context, input ${inputType}) (${outputType}, error) {`,
+    )
+      .i()
+      .return(`Example${outputType}(), nil`)
+      .u()
+      .l("}),");
+  }
+
+  private generateStub(w: GoBuilder, endpoint: Endpoint): void {
+    const name = toMethodName(endpoint.fullName);
+    const method = endpoint.fullName;
+    const stub = `${name}Stub`;
+    const inputType = toPascalCase(endpoint.input.name!);
+    const outputType = toPascalCase(endpoint.output.name!);
+    const fnType = `func(ctx context.Context, input ${inputType}) (${outputType}, error)`;
+
+    w.comment(
+      `${stub} stubs ${method}: it answers with a canned output and error, or a`,
+    )
+      .comment("function computing them, and records the inputs it was called with.")
+      .struct(stub, (b) => {
+        b.l("mu    sync.Mutex")
+          .l(`fn    ${fnType}`)
+          .l(`calls []${inputType}`);
+      });
+
+    w.comment(`Returns makes ${method} answer every call with output and err.`)
+      .n()
+      .method(
+        `s *${stub}`,
+        "Returns",
+        `output ${outputType}, err error`,
+        `*${stub}`,
+        (b) => {
+          b.l(
+            `return s.Func(func(context.Context, ${inputType}) (${outputType}, error) {`,
+          )
+            .i()
+            .return("output, err")
+            .u()
+            .l("})");
+        },
+      );
+
+    this.generateFuncAndCalls(w, method, stub, inputType, fnType);
+
+    if (endpoint.stream) {
+      // Streamed outputs are sent item by item like a handler would
+      const itemType = streamItemType(endpoint).type;
+      w.method(
+        `s *${stub}`,
+        "handle",
+        `ctx context.Context, info RequestInfo, input ${inputType}, stream *Stream[${itemType}]`,
+        `(${outputType}, error)`,
+        (b) => {
+          this.recordCall(b)
+            .if("fn == nil", (b) => {
+              b.var("output", outputType).return(
+                `output, NewError(CodeUnimplemented, "${method} is not stubbed")`,
+              );
+            })
+            .decl("output, err", "fn(ctx, input)")
+            .ifErr((b) => {
+              b.return("output, err");
+            })
+            .return(
+              `output, stream.SendAll(output.${toPascalCase(endpoint.stream)})`,
+            );
+        },
+      );
+      return;
+    }
+
+    w.method(
+      `s *${stub}`,
+      "handle",
+      `ctx context.Context, info RequestInfo, input ${inputType}`,
+      `(${outputType}, error)`,
+      (b) => {
+        this.recordCall(b)
+          .if("fn == nil", (b) => {
+            b.var("output", outputType).return(
+              `output, NewError(CodeUnimplemented, "${method} is not stubbed")`,
+            );
+          })
+          .return("fn(ctx, input)");
+      },
+    );
+  }
+
+  private generateStreamStub(w: GoBuilder, endpoint: Endpoint): void {
+    const name = toMethodName(endpoint.fullName);
+    const method = endpoint.fullName;
+    const stub = `${name}Stub`;
+    const inputType = toPascalCase(endpoint.input.name!);
+    const outputType = toPascalCase(endpoint.output.name!);
+    const fnType = `func(ctx context.Context, input ${inputType}, send func(${outputType}) error) error`;
+
+    w.comment(
+      `${stub} stubs the ${method} subscription: it sends canned events, or runs a`,
+    )
+      .comment(
+        "function sending them, and records the inputs it was called with.",
+      )
+      .struct(stub, (b) => {
+        b.l("mu    sync.Mutex")
+          .l(`fn    ${fnType}`)
+          .l(`calls []${inputType}`);
+      });
+
+    w.comment(
+      `Sends makes ${method} send events to every subscriber, then end the stream.`,
+    )
+      .n()
+      .method(
+        `s *${stub}`,
+        "Sends",
+        `events ...${outputType}`,
+        `*${stub}`,
+        (b) => {
+          b.l(
+            `return s.Func(func(ctx context.Context, input ${inputType}, send func(${outputType}) error) error {`,
+          )
+            .i()
+            .l("for _, event := range events {")
+            .i()
+            .if("err := send(event); err != nil", (b) => {
+              b.return("err");
+            })
+            .u()
+            .l("}")
+            .return("nil")
+            .u()
+            .l("})");
+        },
+      );
+
+    this.generateFuncAndCalls(w, method, stub, inputType, fnType);
+
+    w.method(
+      `s *${stub}`,
+      "handle",
+      `ctx context.Context, info RequestInfo, input ${inputType}, send func(${outputType}) error`,
+      "error",
+      (b) => {
+        this.recordCall(b)
+          .if("fn == nil", (b) => {
+            b.return(`NewError(CodeUnimplemented, "${method} is not stubbed")`);
+          })
+          .return("fn(ctx, input, send)");
+      },
+    );
+  }
+
+  private generateFuncAndCalls(
+    w: GoBuilder,
+    method: string,
+    stub: string,
+    inputType: string,
+    fnType: string,
+  ): void {
+    w.comment(`Func makes ${method} answer calls with fn.`)
+      .n()
+      .method(`s *${stub}`, "Func", `fn ${fnType}`, `*${stub}`, (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .l("s.fn = fn")
+          .return("s");
+      });
+
+    w.comment(`Calls returns the inputs ${method} was called with, in order.`)
+      .n()
+      .method(`s *${stub}`, "Calls", "", `[]${inputType}`, (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .return(`append([]${inputType}(nil), s.calls...)`);
+      });
+  }
+
+  private recordCall(b: GoBuilder): GoBuilder {
+    return b
+      .l("s.mu.Lock()")
+      .l("s.calls = append(s.calls, input)")
+      .decl("fn", "s.fn")
+      .l("s.mu.Unlock()");
+  }
+}
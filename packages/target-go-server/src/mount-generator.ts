@@ -0,0 +1,349 @@
+import { GoBuilder } from "./go-builder";
+import { INTROSPECT_METHOD } from "./server-generator";
+
+/**
+ * Generates mount.go: Router.Mount, which serves the methods of routers
+ * generated from other contracts under a method-name prefix, and the
+ * Mountable interface they are mounted through. Generated packages each have
+ * their own Router type, so the interface only uses standard library types.
+ * Router.Handler and Router.MountHTTP serve a router under a URL path prefix,
+ * so versions of an API generated into separate packages can be served side
+ * by side, such as under /api/v1 and /api/v2.
+ */
+export class GoMountGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate mount.go.
+   * @param hasREST - Whether rest.go is generated, so Handler serves its
+   * routes below the prefix
+   */
+  generateMount(hasREST: boolean): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "net/http",
+      "strings",
+    );
+
+    w.comment(
+      "Mountable is a router that can be mounted under another with Mount. Every",
+    )
+      .comment(
+        "generated Router implements it, so a schema split into contracts generated",
+      )
+      .comment(
+        "into separate packages can still be served from one endpoint. Outcomes are",
+      )
+      .comment("passed as strings since each package has its own Outcome type.")
+      .l("type Mountable interface {")
+      .i()
+      .comment(
+        "ServeCall answers a call whose envelope the mounting router decoded and",
+      )
+      .comment("returns its outcome.")
+      .l(
+        "ServeCall(w http.ResponseWriter, req *http.Request, method string, params json.RawMessage) string",
+      )
+      .comment(
+        "DispatchCall calls a method like Dispatch, without logging it, and returns",
+      )
+      .comment("its outcome.")
+      .l(
+        "DispatchCall(ctx context.Context, method string, params json.RawMessage) (interface{}, string, error)",
+      )
+      .u()
+      .l("}")
+      .n();
+
+    w.comment(
+      "mountEntry is a router added with Mount and the prefix of the methods it",
+    )
+      .comment("serves, including the trailing dot.")
+      .struct("mountEntry", (b) => {
+        b.l("prefix string").l("router Mountable");
+      });
+
+    w.comment(
+      "Mount serves the methods of router under prefix: mounted under \"billing\", its",
+    )
+      .comment(
+        '"invoice.get" method is called as "billing.invoice.get". A call first runs',
+      )
+      .comment(
+        'this router\'s middleware, with the prefixed name so UseFor("billing.*")',
+      )
+      .comment(
+        "matches it, then the mounted router's middleware, checks, interceptors and",
+      )
+      .comment(
+        "handler. This router's CSRF protection and Logger cover mounted calls.",
+      )
+      .comment(
+        "Context values pass through, but the keys behind WithUserID and",
+      )
+      .comment(
+        "WithPrincipal belong to each package, so a mounted router generated into",
+      )
+      .comment(
+        "another package must authenticate callers in its own middleware. Mount",
+      )
+      .comment(
+        "panics if prefix is not a method name, overlaps a prefix already mounted,",
+      )
+      .comment("or would hide one of this router's methods.")
+      .n()
+      .method(
+        "r *Router",
+        "Mount",
+        "prefix string, router Mountable",
+        "*Router",
+        (b) => {
+          b.if(
+            'prefix == "" || strings.HasPrefix(prefix, ".") || strings.HasSuffix(prefix, ".") || strings.ContainsAny(prefix, "*?[\\\\")',
+            (b) => {
+              b.l('panic("invalid mount prefix: " + prefix)');
+            },
+          )
+            .l('prefix += "."')
+            .l("for _, entry := range r.mounts {")
+            .i()
+            .if(
+              "strings.HasPrefix(prefix, entry.prefix) || strings.HasPrefix(entry.prefix, prefix)",
+              (b) => {
+                b.l(
+                  'panic("mount prefix " + strings.TrimSuffix(prefix, ".") + " overlaps " + strings.TrimSuffix(entry.prefix, "."))',
+                );
+              },
+            )
+            .u()
+            .l("}")
+            .l("for _, m := range methodDescriptors {")
+            .i()
+            .if("strings.HasPrefix(m.name, prefix)", (b) => {
+              b.l(
+                'panic("mount prefix " + strings.TrimSuffix(prefix, ".") + " hides method " + m.name)',
+              );
+            })
+            .u()
+            .l("}")
+            .l(
+              "r.mounts = append(r.mounts, mountEntry{prefix: prefix, router: router})",
+            )
+            .return("r");
+        },
+      );
+
+    w.comment(
+      "mountFor returns the router mounted under the prefix of method and the name",
+    )
+      .comment("that router knows the method by.")
+      .n()
+      .method(
+        "r *Router",
+        "mountFor",
+        "method string",
+        "(Mountable, string, bool)",
+        (b) => {
+          b.l("for _, entry := range r.mounts {")
+            .i()
+            .if("strings.HasPrefix(method, entry.prefix)", (b) => {
+              b.return("entry.router, method[len(entry.prefix):], true");
+            })
+            .u()
+            .l("}")
+            .return('nil, "", false');
+        },
+      );
+
+    w.comment(
+      "allowsGET reports whether method may be called with GET. Mounted routers",
+    )
+      .comment("decide for their own methods in ServeCall.")
+      .n()
+      .method("r *Router", "allowsGET", "method string", "bool", (b) => {
+        b.if("_, _, ok := r.mountFor(method); ok", (b) => {
+          b.return("true");
+        }).return("getAllowed(method)");
+      });
+
+    w.comment(
+      "ServeCall answers a call whose {\"method\", \"params\"} envelope a mounting router",
+    )
+      .comment(
+        "decoded, running this router's middleware, checks and handler as ServeHTTP",
+      )
+      .comment(
+        "does. It returns the outcome for the mounting router's Logger.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "ServeCall",
+        "w http.ResponseWriter, req *http.Request, method string, params json.RawMessage",
+        "string",
+        (b) => {
+          b.if("req.Method == http.MethodGet && !r.allowsGET(method)", (b) => {
+            b.l(
+              'r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))',
+            ).return("string(OutcomeRejected)");
+          }).return("string(r.serveCall(w, req, method, params))");
+        },
+      );
+
+    w.comment(
+      "DispatchCall calls a query or mutation like Dispatch for a mounting router,",
+    )
+      .comment(
+        "leaving panic recovery and logging to it, and returns the outcome.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "DispatchCall",
+        "ctx context.Context, method string, params json.RawMessage",
+        "(interface{}, string, error)",
+        (b) => {
+          b.decl("info", "RequestInfo{Method: method}")
+            .decl(
+              "result, outcome, err",
+              "r.dispatch(r.withDependencies(WithRequestInfo(ctx, info)), info, method, params)",
+            )
+            .return("result, string(outcome), err");
+        },
+      );
+
+    w.comment(
+      `mountedMethods lists the methods of a mounted router, named with their prefix,`,
+    )
+      .comment(
+        `through its ${INTROSPECT_METHOD} method. Its MethodInfo is a type of another`,
+      )
+      .comment(
+        "package, so the listing is converted through JSON. Routers that disabled",
+      )
+      .comment("introspection list none.")
+      .n()
+      .func("mountedMethods(entry mountEntry) []MethodInfo", (b) => {
+        b.decl(
+          "result, _, err",
+          `entry.router.DispatchCall(context.Background(), "${INTROSPECT_METHOD}", nil)`,
+        )
+          .ifErr((b) => {
+            b.return("nil");
+          })
+          .decl("data, err", "json.Marshal(result)")
+          .ifErr((b) => {
+            b.return("nil");
+          })
+          .var("listing", "struct {")
+          .i()
+          .l('Methods []MethodInfo `json:"methods"`')
+          .u()
+          .l("}")
+          .if("err := json.Unmarshal(data, &listing); err != nil", (b) => {
+            b.return("nil");
+          })
+          .l("for i := range listing.Methods {")
+          .i()
+          .l("listing.Methods[i].Name = entry.prefix + listing.Methods[i].Name")
+          .u()
+          .l("}")
+          .return("listing.Methods");
+      });
+
+    this.generateHTTPMount(w, hasREST);
+
+    return w.toString();
+  }
+
+  private generateHTTPMount(w: GoBuilder, hasREST: boolean): void {
+    const doc = hasREST
+      ? [
+          "slash, and the routes of RESTHandler below it, such as /api/v1/tasks/{id}.",
+          "The prefix is stripped from the URL path before the router sees it, and",
+          "middleware, such as CORS handling, is wrapped around the handler, the first",
+          "outermost.",
+        ]
+      : [
+          "slash, and other paths below it are NOT_FOUND. The prefix is stripped from",
+          "the URL path before the router sees it, and middleware, such as CORS",
+          "handling, is wrapped around the handler, the first outermost.",
+        ];
+    w.comment(
+      "Handler returns the router as an http.Handler served under prefix, such as",
+    ).comment(
+      '"/api/v1". Calls are answered at the prefix, with or without a trailing',
+    );
+    for (const line of doc) {
+      w.comment(line);
+    }
+    w.n().method(
+      "r *Router",
+      "Handler",
+      "prefix string, middleware ...func(http.Handler) http.Handler",
+      "http.Handler",
+      (b) => {
+        b.l('prefix = strings.TrimSuffix(prefix, "/")');
+        if (hasREST) {
+          b.decl("rest", "r.RESTHandler()");
+        }
+        b.l(
+          "handler := http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {",
+        )
+          .i()
+          .if('req.URL.Path == "" || req.URL.Path == "/"', (b) => {
+            b.l("r.ServeHTTP(w, req)").return();
+          });
+        if (hasREST) {
+          b.l("rest.ServeHTTP(w, req)");
+        } else {
+          b.l(
+            'r.writeError(w, Errorf(CodeNotFound, "Route not found: %s %s", req.Method, req.URL.Path))',
+          );
+        }
+        b.u()
+          .l("}))")
+          .l("for i := len(middleware) - 1; i >= 0; i-- {")
+          .i()
+          .l("handler = middleware[i](handler)")
+          .u()
+          .l("}")
+          .return("handler");
+      },
+    );
+
+    w.comment(
+      "MountHTTP registers the Handler of the router for prefix on mux, for the",
+    )
+      .comment(
+        "prefix itself and every path below it, so no redirect adds a trailing slash:",
+      )
+      .comment("")
+      .comment('    v1.MountHTTP(mux, "/api/v1", cors)')
+      .comment('    v2.MountHTTP(mux, "/api/v2", cors)')
+      .n()
+      .method(
+        "r *Router",
+        "MountHTTP",
+        "mux *http.ServeMux, prefix string, middleware ...func(http.Handler) http.Handler",
+        "",
+        (b) => {
+          b.decl("handler", "r.Handler(prefix, middleware...)")
+            .l('prefix = strings.TrimSuffix(prefix, "/")')
+            .if('prefix != ""', (b) => {
+              b.l("mux.Handle(prefix, handler)");
+            })
+            .l('mux.Handle(prefix+"/", handler)');
+        },
+      );
+  }
+}
@@ -0,0 +1,86 @@
+import { toPascalCase as pascalCase } from "@xrpckit/sdk";
+
+/**
+ * The initialisms Go linters expect in upper case, those of golint's
+ * commonInitialisms, applied with the `initialisms: true` option.
+ */
+export const COMMON_INITIALISMS = [
+  "ACL",
+  "API",
+  "ASCII",
+  "CPU",
+  "CSS",
+  "DNS",
+  "EOF",
+  "GUID",
+  "HTML",
+  "HTTP",
+  "HTTPS",
+  "ID",
+  "IP",
+  "JSON",
+  "LHS",
+  "QPS",
+  "RAM",
+  "RHS",
+  "RPC",
+  "SLA",
+  "SMTP",
+  "SQL",
+  "SSH",
+  "TCP",
+  "TLS",
+  "TTL",
+  "UDP",
+  "UI",
+  "UID",
+  "URI",
+  "URL",
+  "UTF8",
+  "UUID",
+  "VM",
+  "XML",
+  "XMPP",
+  "XSRF",
+  "XSS",
+];
+
+// Initialisms of the generation in progress, set with withInitialisms
+let initialisms: ReadonlySet<string> = new Set();
+
+/**
+ * Converts a contract name to a Go identifier in PascalCase, like the SDK's
+ * toPascalCase, with the words that are initialisms of the generation in
+ * progress in upper case: "userId" is "UserId", or "UserID" with "ID" among
+ * them. Every Go name derived from the contract goes through it, so the
+ * files referring to one type or field agree on its name.
+ */
+export function toPascalCase(name: string): string {
+  const pascal = pascalCase(name);
+  if (initialisms.size === 0) {
+    return pascal;
+  }
+  return pascal
+    .split(/(?=[A-Z])/)
+    .map((word) =>
+      initialisms.has(word.toUpperCase()) ? word.toUpperCase() : word,
+    )
+    .join("");
+}
+
+/**
+ * Runs generate with names converted by toPascalCase keeping the given
+ * initialisms in upper case.
+ */
+export function withInitialisms<T>(
+  list: readonly string[],
+  generate: () => T,
+): T {
+  const previous = initialisms;
+  initialisms = new Set(list.map((initialism) => initialism.toUpperCase()));
+  try {
+    return generate();
+  } finally {
+    initialisms = previous;
+  }
+}
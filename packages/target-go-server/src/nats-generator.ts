@@ -0,0 +1,106 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates nats.go: ServeNATS, which answers the RPC requests published on a
+ * NATS subject with Router.ServeMessage. Only emitted with the `nats: true`
+ * option, since it depends on the NATS client module.
+ */
+export class GoNATSGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateNATS(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "github.com/nats-io/nats.go",
+    );
+
+    w.comment("NATSOptions configures ServeNATS.")
+      .struct("NATSOptions", (b) => {
+        b.comment(
+          "Queue is the queue group of the subscription, so that each request is",
+        )
+          .comment(
+            "answered by one of the servers subscribed. Empty subscribes every server.",
+          )
+          .l("Queue string")
+          .comment(
+            "Context returns the context of a call from its message, such as with the",
+          )
+          .comment(
+            "caller taken from its headers (WithUserID). context.Background() by default.",
+          )
+          .l("Context func(msg *nats.Msg) context.Context")
+          .comment(
+            "MaxConcurrent is the number of messages answered at once, 1 by default,",
+          )
+          .comment("which answers them in order.")
+          .l("MaxConcurrent int");
+      });
+
+    w.comment(
+      "ServeNATS answers the requests published on subject with ServeMessage:",
+    )
+      .comment(
+        'requests carry a {"method", "params"} envelope and their replies the body',
+      )
+      .comment(
+        "ServeHTTP would send. Messages without a reply subject are still served.",
+      )
+      .comment("Unsubscribe or drain the returned subscription to stop.")
+      .n()
+      .func(
+        "ServeNATS(nc *nats.Conn, subject string, router *Router, options NATSOptions) (*nats.Subscription, error)",
+        (b) => {
+          b.decl("limit", "options.MaxConcurrent")
+            .if("limit < 1", (b) => {
+              b.l("limit = 1");
+            })
+            .decl("slots", "make(chan struct{}, limit)")
+            .l("handler := func(msg *nats.Msg) {")
+            .i()
+            .l("slots <- struct{}{}")
+            .l("go func() {")
+            .i()
+            .l("defer func() { <-slots }()")
+            .l("serveNATSMsg(msg, router, options)")
+            .u()
+            .l("}()")
+            .u()
+            .l("}")
+            .if('options.Queue != ""', (b) => {
+              b.return("nc.QueueSubscribe(subject, options.Queue, handler)");
+            })
+            .return("nc.Subscribe(subject, handler)");
+        },
+      );
+
+    w.comment("serveNATSMsg answers a message, if it has a reply subject.")
+      .n()
+      .func(
+        "serveNATSMsg(msg *nats.Msg, router *Router, options NATSOptions)",
+        (b) => {
+          b.decl("ctx", "context.Background()")
+            .if("options.Context != nil", (b) => {
+              b.l("ctx = options.Context(msg)");
+            })
+            .decl("reply", "router.ServeMessage(ctx, msg.Data)")
+            .if('msg.Reply == ""', (b) => {
+              b.return();
+            })
+            .if("err := msg.Respond(reply); err != nil", (b) => {
+              b.l('router.logf("xrpc: replying to %s: %v", msg.Subject, err)');
+            });
+        },
+      );
+
+    return w.toString();
+  }
+}
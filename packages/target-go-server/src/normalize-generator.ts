@@ -0,0 +1,225 @@
+import type { Normalization } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+
+/**
+ * Generates normalize.go for contracts with strings to normalize, declared
+ * with Zod's .trim(), .toLowerCase() and .toUpperCase() or with
+ * .meta({ normalize: ["collapseSpaces"] }): a Normalize method on every
+ * struct holding such a string, directly or in a nested struct, transforming
+ * them in place. The router normalizes decoded inputs before validating them
+ * and calling their handler, so handlers need not sanitize what they get.
+ */
+export class GoNormalizeGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private normalizable: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate normalize.go, or undefined when no string is normalized.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateNormalize(
+    structs: GoStruct[],
+    aliases: Map<string, string>,
+  ): string | undefined {
+    this.aliases = aliases;
+    // Structs with normalized strings, then those nesting them, until no
+    // more are found
+    this.normalizable = new Set();
+    let found = true;
+    while (found) {
+      found = false;
+      for (const struct of structs) {
+        if (this.normalizable.has(struct.name)) continue;
+        if (
+          struct.fields.some((field) =>
+            this.needsNormalization(field.type, field.normalize),
+          )
+        ) {
+          this.normalizable.add(struct.name);
+          found = true;
+        }
+      }
+    }
+    if (this.normalizable.size === 0) {
+      return undefined;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("strings");
+    for (const struct of structs) {
+      if (this.normalizable.has(struct.name)) {
+        this.generateNormalizeMethod(w, struct);
+      }
+    }
+    return w.toString();
+  }
+
+  /**
+   * The types with a Normalize method, aliases included, once
+   * generateNormalize has run.
+   */
+  getNormalizable(): Set<string> {
+    const types = new Set(this.normalizable);
+    for (const [alias, target] of this.aliases) {
+      if (this.normalizable.has(target)) {
+        types.add(alias);
+      }
+    }
+    return types;
+  }
+
+  private generateNormalizeMethod(w: GoBuilder, struct: GoStruct): void {
+    const fields = struct.fields.filter((field) =>
+      this.needsNormalization(field.type, field.normalize),
+    );
+    const strings = fields
+      .filter((field) => field.normalize.length > 0 && isText(field.type))
+      .map((field) => field.name);
+    const parts =
+      strings.length < fields.length
+        ? [...strings, "the strings of its nested values"]
+        : strings;
+    w.doc(`Normalize normalizes ${joinNames(parts)} in place.`);
+    w.n().method(`v *${struct.name}`, "Normalize", "", "", (b) => {
+      for (const field of fields) {
+        this.normalizeField(
+          b,
+          field.type,
+          `v.${field.name}`,
+          field.normalize,
+          0,
+        );
+      }
+    });
+  }
+
+  // Normalizes the strings expr holds, or the nested values it holds
+  private normalizeField(
+    b: GoBuilder,
+    goType: string,
+    expr: string,
+    normalize: Normalization[],
+    depth: number,
+  ): void {
+    if (goType === "string") {
+      b.l(`${expr} = ${normalizedString(expr, normalize)}`);
+      return;
+    }
+    if (this.normalizable.has(this.aliases.get(goType) ?? goType)) {
+      b.l(`${expr}.Normalize()`);
+      return;
+    }
+    const suffix = depth === 0 ? "" : `${depth}`;
+    const elem = elemType(goType);
+    if (elem === undefined) return;
+    if (goType.startsWith("*")) {
+      b.if(`${expr} != nil`, (b) => {
+        // Pointer receivers take the pointer itself
+        const target = this.normalizable.has(this.aliases.get(elem) ?? elem)
+          ? expr
+          : `*${expr}`;
+        this.normalizeField(b, elem, target, normalize, depth + 1);
+      });
+    } else if (goType.startsWith("[]")) {
+      const index = `i${suffix}`;
+      b.l(`for ${index} := range ${expr} {`).i();
+      this.normalizeField(
+        b,
+        elem,
+        `${parenthesize(expr)}[${index}]`,
+        normalize,
+        depth + 1,
+      );
+      b.u().l("}");
+    } else {
+      // Map values are copies, normalized and stored back
+      const key = `k${suffix}`;
+      const value = `e${suffix}`;
+      b.l(`for ${key}, ${value} := range ${expr} {`).i();
+      this.normalizeField(b, elem, value, normalize, depth + 1);
+      b.l(`${parenthesize(expr)}[${key}] = ${value}`).u().l("}");
+    }
+  }
+
+  // Whether a field of goType holds strings to normalize
+  private needsNormalization(
+    goType: string,
+    normalize: Normalization[],
+  ): boolean {
+    if (normalize.length > 0 && isText(goType)) {
+      return true;
+    }
+    if (this.normalizable.has(this.aliases.get(goType) ?? goType)) {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.needsNormalization(elem, []);
+  }
+}
+
+// The Go expression of value with the normalizations applied in order
+function normalizedString(value: string, normalize: Normalization[]): string {
+  let expr = value;
+  for (const normalization of normalize) {
+    switch (normalization) {
+      case "trim":
+        expr = `strings.TrimSpace(${expr})`;
+        break;
+      case "lowercase":
+        expr = `strings.ToLower(${expr})`;
+        break;
+      case "uppercase":
+        expr = `strings.ToUpper(${expr})`;
+        break;
+      case "collapseSpaces":
+        expr = `strings.Join(strings.Fields(${expr}), " ")`;
+        break;
+    }
+  }
+  return expr;
+}
+
+// Dereferenced pointers are indexed within parentheses
+function parenthesize(expr: string): string {
+  return expr.startsWith("*") ? `(${expr})` : expr;
+}
+
+// Whether goType is a string, or pointers, slices or maps of strings
+function isText(goType: string): boolean {
+  if (goType === "string") {
+    return true;
+  }
+  const elem = elemType(goType);
+  return elem !== undefined && isText(elem);
+}
+
+// The element type of pointer, slice and map types
+function elemType(goType: string): string | undefined {
+  if (goType.startsWith("*")) {
+    return goType.slice(1);
+  }
+  if (goType.startsWith("[]")) {
+    return goType.slice(2);
+  }
+  if (goType.startsWith("map[")) {
+    return goType.slice(goType.indexOf("]") + 1);
+  }
+  return undefined;
+}
+
+// Email, or Email and Name, or Email, Name and Title
+function joinNames(names: string[]): string {
+  if (names.length === 1) {
+    return names[0];
+  }
+  return `${names.slice(0, -1).join(", ")} and ${names[names.length - 1]}`;
+}
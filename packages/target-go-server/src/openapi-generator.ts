@@ -0,0 +1,37 @@
+import { type ContractDefinition, buildOpenAPIDocument } from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+
+/**
+ * Generates openapi.go: the contract's OpenAPI 3.1 document embedded as a
+ * constant and exposed through Router.OpenAPISpec.
+ */
+export class GoOpenAPIGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateOpenAPI(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const spec = JSON.stringify(buildOpenAPIDocument(contract), null, 2);
+
+    w.package(this.packageName);
+
+    w.comment("openAPISpec is the OpenAPI 3.1 document describing this API.");
+    w.l(`const openAPISpec = ${goStringLiteral(spec)}`).n();
+
+    w.comment(
+      "OpenAPISpec returns the OpenAPI 3.1 document (JSON) describing every method,",
+    )
+      .comment("its input and output schemas, and the error envelope.")
+      .n()
+      .method("r *Router", "OpenAPISpec", "", "[]byte", (b) => {
+        b.return("[]byte(openAPISpec)");
+      });
+
+    return w.toString();
+  }
+}
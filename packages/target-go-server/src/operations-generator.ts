@@ -0,0 +1,439 @@
+import { GoBuilder } from "./go-builder";
+import {
+  OPERATION_CANCEL_METHOD,
+  OPERATION_GET_METHOD,
+} from "./server-generator";
+
+/**
+ * Generates operations.go: the long-running operations of mutations declared
+ * with async: true. Their calls answer at once with an Operation and run in
+ * the background; clients poll it with the built-in operation.get method and
+ * stop it with operation.cancel. Operations are kept in an OperationStore, in
+ * memory by default or shared by every instance of the server through one
+ * backed by a database.
+ */
+export class GoOperationsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateOperations(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "errors",
+      "runtime/debug",
+      "sync",
+      "time",
+    );
+
+    this.generateOperation(w);
+    this.generateStore(w);
+    this.generateRun(w);
+    this.generateBuiltins(w);
+
+    return w.toString();
+  }
+
+  private generateOperation(w: GoBuilder): void {
+    w.comment("OperationStatus is how far an Operation got.").type(
+      "OperationStatus",
+      "string",
+    );
+
+    w.l("const (")
+      .i()
+      .l('OperationRunning OperationStatus = "running"')
+      .l('OperationSucceeded OperationStatus = "succeeded"')
+      .l('OperationFailed OperationStatus = "failed"')
+      .l('OperationCancelled OperationStatus = "cancelled"')
+      .u()
+      .l(")")
+      .n();
+
+    w.comment(
+      "Operation tracks a call of an async mutation, which answers with it at once",
+    )
+      .comment(
+        `and runs in the background. Clients poll it with ${OPERATION_GET_METHOD} until it is`,
+      )
+      .comment("done, when it holds the mutation's result or error.")
+      .struct("Operation", (b) => {
+        b.l('ID string `json:"operationId"`')
+          .l('Method string `json:"method"`')
+          .l('Status OperationStatus `json:"status"`')
+          .comment(
+            "Result is the result of a call that succeeded. Operations the Client",
+          )
+          .comment("fetches hold it as json.RawMessage.")
+          .l('Result interface{} `json:"result,omitempty"`')
+          .comment("Error is why a call failed.")
+          .l('Error *Error `json:"error,omitempty"`')
+          .comment(
+            "UserID is the caller that started the operation, the only one the",
+          )
+          .comment("built-in methods show it to.")
+          .l('UserID string `json:"userId,omitempty"`')
+          .l('CreatedAt time.Time `json:"createdAt"`')
+          .l('UpdatedAt time.Time `json:"updatedAt"`');
+      });
+
+    w.comment("Done reports whether the call op tracks has finished.")
+      .n()
+      .method("op Operation", "Done", "", "bool", (b) => {
+        b.return("op.Status != OperationRunning");
+      });
+  }
+
+  private generateStore(w: GoBuilder): void {
+    w.comment(
+      `OperationStore keeps operations for ${OPERATION_GET_METHOD} to answer from. Save is called`,
+    )
+      .comment(
+        "when an operation starts and when it is done. A store shared by several",
+      )
+      .comment(
+        "servers, such as a database table, lets any of them answer for the operations",
+      )
+      .comment("the others run.")
+      .l("type OperationStore interface {")
+      .i()
+      .l("Save(ctx context.Context, op Operation) error")
+      .comment(
+        "Load returns the operation with the given ID, or false when there is none.",
+      )
+      .l("Load(ctx context.Context, id string) (Operation, bool, error)")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment(
+      "MemoryOperationStore keeps operations in memory, dropping those done for",
+    )
+      .comment(
+        "longer than its retention. Routers use one with a retention of an hour",
+      )
+      .comment("until SetOperationStore sets another.")
+      .struct("MemoryOperationStore", (b) => {
+        b.l("retention time.Duration")
+          .l("mu sync.Mutex")
+          .l("operations map[string]Operation");
+      });
+
+    w.comment(
+      "NewMemoryOperationStore returns a MemoryOperationStore keeping operations for",
+    )
+      .comment("retention once they are done.")
+      .n()
+      .func(
+        "NewMemoryOperationStore(retention time.Duration) *MemoryOperationStore",
+        (b) => {
+          b.return(
+            "&MemoryOperationStore{retention: retention, operations: make(map[string]Operation)}",
+          );
+        },
+      );
+
+    w.comment("Save stores op, replacing the operation with its ID.")
+      .n()
+      .method(
+        "s *MemoryOperationStore",
+        "Save",
+        "ctx context.Context, op Operation",
+        "error",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .l("s.operations[op.ID] = op")
+            .if("op.Done()", (b) => {
+              b.l("time.AfterFunc(s.retention, func() { s.expire(op) })");
+            })
+            .return("nil");
+        },
+      );
+
+    w.comment("Load returns the operation with the given ID.")
+      .n()
+      .method(
+        "s *MemoryOperationStore",
+        "Load",
+        "ctx context.Context, id string",
+        "(Operation, bool, error)",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .decl("op, ok", "s.operations[id]")
+            .return("op, ok, nil");
+        },
+      );
+
+    w.comment("expire drops op unless it was saved again since.")
+      .n()
+      .method("s *MemoryOperationStore", "expire", "op Operation", "", (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .if(
+            "stored, ok := s.operations[op.ID]; ok && stored.UpdatedAt.Equal(op.UpdatedAt)",
+            (b) => {
+              b.l("delete(s.operations, op.ID)");
+            },
+          );
+      });
+
+    w.comment(
+      "SetOperationStore sets the store the operations of async mutations are kept",
+    )
+      .comment("in, such as one shared by every instance of the server.")
+      .n()
+      .method(
+        "r *Router",
+        "SetOperationStore",
+        "store OperationStore",
+        "*Router",
+        (b) => {
+          b.l("r.operationStore = store").return("r");
+        },
+      );
+  }
+
+  private generateRun(w: GoBuilder): void {
+    w.comment(
+      "startOperation saves the Operation of a call of the async method m and calls",
+    )
+      .comment(
+        "its handler in the background. The call keeps the values of ctx but not its",
+      )
+      .comment(
+        "deadline or cancellation, which end with the request; the timeouts set with",
+      )
+      .comment("SetTimeout and SetTimeoutFor still apply to it.")
+      .n()
+      .method(
+        "r *Router",
+        "startOperation",
+        "ctx context.Context, info RequestInfo, m *methodDescriptor, input interface{}",
+        "(Operation, error)",
+        (b) => {
+          b.decl("now", "time.Now().UTC()")
+            .decl(
+              "op",
+              "Operation{ID: newEventID(), Method: info.Method, Status: OperationRunning, CreatedAt: now, UpdatedAt: now}",
+            )
+            .l("op.UserID, _ = UserIDFrom(ctx)")
+            .if("err := r.operationStore.Save(ctx, op); err != nil", (b) => {
+              b.return("Operation{}, err");
+            })
+            .n()
+            .comment("The response is written before the handler runs")
+            .l("info.ResponseWriter = nil")
+            .l(
+              "ctx, cancel := context.WithCancel(WithRequestInfo(context.WithoutCancel(ctx), info))",
+            )
+            .l("r.operations.Store(op.ID, cancel)")
+            .l("go func() {")
+            .i()
+            .l("defer cancel()")
+            .l("defer r.operations.Delete(op.ID)")
+            .decl("result, err", "r.runOperation(ctx, info, m, input)")
+            .l("r.finishOperation(ctx, info, m, op, result, err)")
+            .u()
+            .l("}()")
+            .return("op, nil");
+        },
+      );
+
+    w.comment(
+      "runOperation calls the handler of m through the interceptors, recovering its",
+    )
+      .comment("panics, which no request is left to recover.")
+      .n()
+      .method(
+        "r *Router",
+        "runOperation",
+        "ctx context.Context, info RequestInfo, m *methodDescriptor, input interface{}",
+        "(result interface{}, err error)",
+        (b) => {
+          b.l("defer func() {")
+            .i()
+            .if("rec := recover(); rec != nil", (b) => {
+              b.l(
+                'r.logf("xrpc: panic serving %s: %v\\n%s", info.Method, rec, debug.Stack())',
+              ).l(
+                'result, err = nil, NewError(CodeInternal, "Internal server error")',
+              );
+            })
+            .u()
+            .l("}()")
+            .l(
+              "return r.invoke(ctx, info, input, func(ctx context.Context) (interface{}, error) {",
+            )
+            .i()
+            .return("m.invoke(r, ctx, info, input)")
+            .u()
+            .l("})");
+        },
+      );
+
+    w.comment(
+      "finishOperation saves op with the outcome of its call and publishes the event",
+    )
+      .comment(
+        "of a mutation that succeeded. Calls failing once their operation was",
+      )
+      .comment("cancelled end it as cancelled.")
+      .n()
+      .method(
+        "r *Router",
+        "finishOperation",
+        "ctx context.Context, info RequestInfo, m *methodDescriptor, op Operation, result interface{}, err error",
+        "",
+        (b) => {
+          b.l("cancelled := errors.Is(ctx.Err(), context.Canceled)")
+            .l("ctx = context.WithoutCancel(ctx)")
+            .l("op.UpdatedAt = time.Now().UTC()")
+            .l("switch {")
+            .l("case err == nil:")
+            .i()
+            .l("op.Status, op.Result = OperationSucceeded, result")
+            .u()
+            .l("case cancelled:")
+            .i()
+            .l("op.Status = OperationCancelled")
+            .u()
+            .l("default:")
+            .i()
+            .l("op.Status, op.Error = OperationFailed, AsError(err)")
+            .l("r.logCause(op.Error)")
+            .u()
+            .l("}")
+            .if("err := r.operationStore.Save(ctx, op); err != nil", (b) => {
+              b.l(
+                'r.logf("xrpc: saving operation %s of %s: %v", op.ID, op.Method, err)',
+              );
+            })
+            .if("op.Status == OperationSucceeded && m.event != nil", (b) => {
+              b.l("r.publish(ctx, info, m.event(result))");
+            });
+        },
+      );
+  }
+
+  private generateBuiltins(w: GoBuilder): void {
+    w.comment(
+      "GetOperation returns the operation with the given ID, or a CodeNotFound error.",
+    )
+      .n()
+      .method(
+        "r *Router",
+        "GetOperation",
+        "ctx context.Context, id string",
+        "(Operation, error)",
+        (b) => {
+          b.decl("op, ok, err", "r.operationStore.Load(ctx, id)")
+            .ifErr((b) => {
+              b.return("Operation{}, err");
+            })
+            .if("!ok", (b) => {
+              b.return(
+                'Operation{}, Errorf(CodeNotFound, "Operation not found: %s", id)',
+              );
+            })
+            .return("op, nil");
+        },
+      );
+
+    w.comment(
+      "CancelOperation cancels the context of the running operation with the given",
+    )
+      .comment(
+        "ID and returns it. Handlers returning an error once their context is",
+      )
+      .comment(
+        "cancelled end it as cancelled; those completing anyway end it as they would",
+      )
+      .comment(
+        "have. Operations no call of this router runs, such as those of a server that",
+      )
+      .comment(
+        "stopped, are marked cancelled at once; a server still running one saves its",
+      )
+      .comment("outcome when done.")
+      .n()
+      .method(
+        "r *Router",
+        "CancelOperation",
+        "ctx context.Context, id string",
+        "(Operation, error)",
+        (b) => {
+          b.decl("op, err", "r.GetOperation(ctx, id)")
+            .if("err != nil || op.Done()", (b) => {
+              b.return("op, err");
+            })
+            .if("cancel, ok := r.operations.Load(id); ok", (b) => {
+              b.l("cancel.(context.CancelFunc)()").return("op, nil");
+            })
+            .l("op.Status = OperationCancelled")
+            .l("op.UpdatedAt = time.Now().UTC()")
+            .return("op, r.operationStore.Save(ctx, op)");
+        },
+      );
+
+    w.comment(
+      `operationParams are the params of ${OPERATION_GET_METHOD} and ${OPERATION_CANCEL_METHOD}.`,
+    ).struct("operationParams", (b) => {
+      b.l('OperationID string `json:"operationId"`');
+    });
+
+    w.comment(
+      `serveOperation answers the built-in ${OPERATION_GET_METHOD} and ${OPERATION_CANCEL_METHOD} methods.`,
+    )
+      .comment(
+        "Operations started by another caller are answered as not found, as if the",
+      )
+      .comment("ID were wrong.")
+      .n()
+      .method(
+        "r *Router",
+        "serveOperation",
+        "ctx context.Context, method string, params json.RawMessage",
+        "(interface{}, Outcome, error)",
+        (b) => {
+          b.var("p", "operationParams")
+            .if("len(params) > 0", (b) => {
+              b.if("err := r.unmarshal(params, &p); err != nil", (b) => {
+                b.return(
+                  "nil, OutcomeValidationError, paramsError(params, err)",
+                );
+              });
+            })
+            .if('p.OperationID == ""', (b) => {
+              b.return(
+                'nil, OutcomeValidationError, NewError(CodeInvalidArgument, "operationId is required")',
+              );
+            })
+            .decl("op, err", "r.GetOperation(ctx, p.OperationID)")
+            .ifErr((b) => {
+              b.return("nil, OutcomeHandlerError, err");
+            })
+            .if("userID, _ := UserIDFrom(ctx); op.UserID != userID", (b) => {
+              b.return(
+                'nil, OutcomeHandlerError, Errorf(CodeNotFound, "Operation not found: %s", p.OperationID)',
+              );
+            })
+            .if(`method == "${OPERATION_CANCEL_METHOD}"`, (b) => {
+              b.l("op, err = r.CancelOperation(ctx, p.OperationID)");
+            })
+            .ifErr((b) => {
+              b.return("nil, OutcomeHandlerError, err");
+            })
+            .return("op, OutcomeSuccess, nil");
+        },
+      );
+  }
+}
@@ -0,0 +1,387 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates outbox.go: a transactional outbox for the events of events.go.
+ * Handlers add their event to the outbox table inside the database transaction
+ * writing their changes, and a relay publishes the committed events through
+ * an EventPublisher and marks them published, so an event goes out exactly
+ * when its changes were committed, even if the process crashes in between.
+ * Relayed events can be delivered more than once; consumers drop duplicates by
+ * their ID.
+ */
+export class GoOutboxGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateOutbox(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "database/sql",
+      "encoding/json",
+      "fmt",
+      "log",
+      "strings",
+      "time",
+    );
+
+    this.generateOptions(w);
+    this.generateConstructor(w);
+    this.generateAdd(w);
+    this.generateRelay(w);
+
+    return w.toString();
+  }
+
+  private generateOptions(w: GoBuilder): void {
+    w.comment(
+      "outboxSchema creates the outbox table. Times are Unix nanoseconds, which every",
+    )
+      .comment("database stores and orders the same way.")
+      .l("const outboxSchema = `CREATE TABLE IF NOT EXISTS %s (")
+      .l("\tid VARCHAR(32) PRIMARY KEY,")
+      .l("\tevent VARCHAR(255) NOT NULL,")
+      .l("\tmethod VARCHAR(255) NOT NULL,")
+      .l("\tcreated_at BIGINT NOT NULL,")
+      .l("\tpayload TEXT NOT NULL,")
+      .l("\tpublished_at BIGINT")
+      .l(")`")
+      .n();
+
+    w.comment("OutboxOptions configures an Outbox.").struct(
+      "OutboxOptions",
+      (b) => {
+        b.comment('Table is the outbox table, "xrpc_outbox" by default.')
+          .l("Table string")
+          .comment(
+            "DollarPlaceholders numbers query parameters $1, $2, ... as PostgreSQL",
+          )
+          .comment("expects, rather than the ? of MySQL and SQLite.")
+          .l("DollarPlaceholders bool")
+          .comment(
+            "BatchSize is the number of events a relay reads at once, 100 by default.",
+          )
+          .l("BatchSize int")
+          .comment(
+            "ErrorLog receives the errors of Relay. By default they are written to the",
+          )
+          .comment("standard logger.")
+          .l("ErrorLog *log.Logger");
+      },
+    );
+
+    w.comment(
+      "Outbox keeps events in a table of the database mutations write their changes",
+    )
+      .comment(
+        "to until a relay publishes them. Add stores an event in the transaction of",
+      )
+      .comment(
+        "the changes, so it is published if and only if they are committed, even when",
+      )
+      .comment(
+        "the process stops right after the commit. Events are published at least",
+      )
+      .comment(
+        "once: consumers drop the ones delivered twice by ID. Events stored in an",
+      )
+      .comment(
+        "outbox are published by its relay, so set no EventPublisher on the router.",
+      )
+      .struct("Outbox", (b) => {
+        b.l("db *sql.DB")
+          .l("options OutboxOptions")
+          .l("insert string")
+          .l("pending string")
+          .l("markPublished string")
+          .l("prune string");
+      });
+  }
+
+  private generateConstructor(w: GoBuilder): void {
+    w.comment(
+      "NewOutbox returns the Outbox kept in db. Create its table with CreateTable, or",
+    )
+      .comment("with the statement of Schema in a migration.")
+      .n()
+      .func(
+        "NewOutbox(db *sql.DB, options OutboxOptions) *Outbox",
+        (b) => {
+          b.if('options.Table == ""', (b) => {
+            b.l('options.Table = "xrpc_outbox"');
+          })
+            .if("options.BatchSize <= 0", (b) => {
+              b.l("options.BatchSize = 100");
+            })
+            .decl("o", "&Outbox{db: db, options: options}")
+            .l(
+              'o.insert = o.query("INSERT INTO %s (id, event, method, created_at, payload) VALUES (?, ?, ?, ?, ?)")',
+            )
+            .l(
+              'o.pending = o.query("SELECT id, event, method, created_at, payload FROM %s WHERE published_at IS NULL ORDER BY created_at, id LIMIT ?")',
+            )
+            .l(
+              'o.markPublished = o.query("UPDATE %s SET published_at = ? WHERE id = ?")',
+            )
+            .l(
+              'o.prune = o.query("DELETE FROM %s WHERE published_at IS NOT NULL AND published_at < ?")',
+            )
+            .return("o");
+        },
+      );
+
+    w.comment(
+      "query names the outbox table in statement and numbers its placeholders when",
+    )
+      .comment("the database expects $1, $2, ...")
+      .n()
+      .method("o *Outbox", "query", "statement string", "string", (b) => {
+        b.l("statement = fmt.Sprintf(statement, o.options.Table)")
+          .if("!o.options.DollarPlaceholders", (b) => {
+            b.return("statement");
+          })
+          .var("numbered", "strings.Builder")
+          .decl("n", "0")
+          .l("for _, c := range statement {")
+          .i()
+          .if("c == '?'", (b) => {
+            b.l("n++")
+              .l('fmt.Fprintf(&numbered, "$%d", n)')
+              .l("continue");
+          })
+          .l("numbered.WriteRune(c)")
+          .u()
+          .l("}")
+          .return("numbered.String()");
+      });
+
+    w.comment("Schema returns the CREATE TABLE statement of the outbox table.")
+      .n()
+      .method("o *Outbox", "Schema", "", "string", (b) => {
+        b.return("fmt.Sprintf(outboxSchema, o.options.Table)");
+      });
+
+    w.comment("CreateTable creates the outbox table if it does not exist.")
+      .n()
+      .method(
+        "o *Outbox",
+        "CreateTable",
+        "ctx context.Context",
+        "error",
+        (b) => {
+          b.decl("_, err", "o.db.ExecContext(ctx, o.Schema())").return("err");
+        },
+      );
+  }
+
+  private generateAdd(w: GoBuilder): void {
+    w.comment(
+      "Add stores event in the outbox within tx, the transaction the mutation writes",
+    )
+      .comment("its changes in, such as")
+      .comment("")
+      .comment(
+        "\toutbox.Add(ctx, tx, Event{Name: EventTaskCreated, Payload: TaskCreatedEvent(task)})",
+      )
+      .comment("")
+      .comment(
+        "Its ID and Time are set when empty, and its Method from the RequestInfo of",
+      )
+      .comment("ctx.")
+      .n()
+      .method(
+        "o *Outbox",
+        "Add",
+        "ctx context.Context, tx *sql.Tx, event Event",
+        "error",
+        (b) => {
+          b.if('event.ID == ""', (b) => {
+            b.l("event.ID = newEventID()");
+          })
+            .if("event.Time.IsZero()", (b) => {
+              b.l("event.Time = time.Now().UTC()");
+            })
+            .if('event.Method == ""', (b) => {
+              b.if("info, ok := RequestInfoFrom(ctx); ok", (b) => {
+                b.l("event.Method = info.Method");
+              });
+            })
+            .decl("payload, err", "json.Marshal(event.Payload)")
+            .ifErr((b) => {
+              b.return(
+                'fmt.Errorf("outbox: encoding %s event: %w", event.Name, err)',
+              );
+            })
+            .l(
+              "_, err = tx.ExecContext(ctx, o.insert, event.ID, event.Name, event.Method, event.Time.UnixNano(), string(payload))",
+            )
+            .return("err");
+        },
+      );
+  }
+
+  private generateRelay(w: GoBuilder): void {
+    w.comment(
+      "RelayOnce publishes the events not published yet through publisher, oldest",
+    )
+      .comment(
+        "first, with their payload as the json.RawMessage it was stored as, and marks",
+      )
+      .comment(
+        "them published. It stops at the first event publisher fails on, which the",
+      )
+      .comment("next call retries, and returns how many events it published.")
+      .n()
+      .method(
+        "o *Outbox",
+        "RelayOnce",
+        "ctx context.Context, publisher EventPublisher",
+        "(int, error)",
+        (b) => {
+          b.decl("published", "0")
+            .l("for {")
+            .i()
+            .decl("events, err", "o.readPending(ctx)")
+            .ifErr((b) => {
+              b.return("published, err");
+            })
+            .l("for _, event := range events {")
+            .i()
+            .if("err := publisher.Publish(ctx, event); err != nil", (b) => {
+              b.return(
+                'published, fmt.Errorf("outbox: publishing %s event %s: %w", event.Name, event.ID, err)',
+              );
+            })
+            .if(
+              "_, err := o.db.ExecContext(ctx, o.markPublished, time.Now().UnixNano(), event.ID); err != nil",
+              (b) => {
+                b.return("published, err");
+              },
+            )
+            .l("published++")
+            .u()
+            .l("}")
+            .if("len(events) < o.options.BatchSize", (b) => {
+              b.return("published, nil");
+            })
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "readPending reads a batch of the events not published yet, oldest first.",
+    )
+      .n()
+      .method(
+        "o *Outbox",
+        "readPending",
+        "ctx context.Context",
+        "([]Event, error)",
+        (b) => {
+          b.decl(
+            "rows, err",
+            "o.db.QueryContext(ctx, o.pending, o.options.BatchSize)",
+          )
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("defer rows.Close()")
+            .var("events", "[]Event")
+            .l("for rows.Next() {")
+            .i()
+            .var("event", "Event")
+            .var("created", "int64")
+            .var("payload", "string")
+            .if(
+              "err := rows.Scan(&event.ID, &event.Name, &event.Method, &created, &payload); err != nil",
+              (b) => {
+                b.return("nil, err");
+              },
+            )
+            .l("event.Time = time.Unix(0, created).UTC()")
+            .l("event.Payload = json.RawMessage(payload)")
+            .l("events = append(events, event)")
+            .u()
+            .l("}")
+            .return("events, rows.Err()");
+        },
+      );
+
+    w.comment(
+      "Relay runs RelayOnce every interval until ctx is done, writing its errors to",
+    )
+      .comment(
+        "the error log. Run one relay per outbox table: relays running at the same",
+      )
+      .comment("time publish events twice.")
+      .n()
+      .method(
+        "o *Outbox",
+        "Relay",
+        "ctx context.Context, publisher EventPublisher, interval time.Duration",
+        "",
+        (b) => {
+          b.decl("ticker", "time.NewTicker(interval)")
+            .l("defer ticker.Stop()")
+            .l("for {")
+            .i()
+            .if(
+              "_, err := o.RelayOnce(ctx, publisher); err != nil && ctx.Err() == nil",
+              (b) => {
+                b.l('o.logf("xrpc: relaying outbox: %v", err)');
+              },
+            )
+            .l("select {")
+            .l("case <-ctx.Done():")
+            .i()
+            .return()
+            .u()
+            .l("case <-ticker.C:")
+            .l("}")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.method(
+      "o *Outbox",
+      "logf",
+      "format string, args ...interface{}",
+      "",
+      (b) => {
+        b.if("o.options.ErrorLog != nil", (b) => {
+          b.l("o.options.ErrorLog.Printf(format, args...)").return();
+        }).l("log.Printf(format, args...)");
+      },
+    );
+
+    w.comment(
+      "Prune deletes the events published before cutoff and returns how many it",
+    )
+      .comment("deleted, to keep the outbox table small.")
+      .n()
+      .method(
+        "o *Outbox",
+        "Prune",
+        "ctx context.Context, cutoff time.Time",
+        "(int64, error)",
+        (b) => {
+          b.decl(
+            "result, err",
+            "o.db.ExecContext(ctx, o.prune, cutoff.UnixNano())",
+          )
+            .ifErr((b) => {
+              b.return("0, err");
+            })
+            .return("result.RowsAffected()");
+        },
+      );
+  }
+}
@@ -0,0 +1,107 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates pagination.go: the helpers handlers of paginated queries use to
+ * read the cursor and pageSize fields of their input and return nextCursor.
+ * Cursors are opaque to clients, which only pass back the nextCursor of the
+ * previous page.
+ */
+export class GoPaginationGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate pagination.go, or null when no endpoint of the contract is
+   * paginated.
+   * @param contract - The contract definition
+   */
+  generatePagination(contract: ContractDefinition): string | null {
+    if (!usesPagination(contract)) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("encoding/base64", "encoding/json");
+
+    w.comment(
+      "DefaultPageSize is the number of items a paginated query returns when called",
+    )
+      .comment("without a pageSize.")
+      .l("var DefaultPageSize = 20")
+      .n();
+
+    w.comment(
+      "PageSize returns the number of items to return for the pageSize a paginated",
+    )
+      .comment(
+        "query was called with: size, or DefaultPageSize when it has none. size is",
+      )
+      .comment("validated against the contract's bounds before handlers run.")
+      .n()
+      .func("PageSize(size *int) int", (b) => {
+        b.if("size == nil", (b) => {
+          b.return("DefaultPageSize");
+        }).return("*size");
+      });
+
+    w.comment(
+      "EncodeCursor returns the cursor of the page starting at position, such as an",
+    )
+      .comment(
+        "offset or the sort key of its first item, to set as nextCursor. The position",
+      )
+      .comment(
+        "is JSON-encoded and then base64url-encoded, so clients pass it back without",
+      )
+      .comment("depending on its content.")
+      .n()
+      .func("EncodeCursor(position interface{}) (*string, error)", (b) => {
+        b.decl("data, err", "json.Marshal(position)")
+          .ifErr((b) => {
+            b.return("nil, err");
+          })
+          .decl("cursor", "base64.RawURLEncoding.EncodeToString(data)")
+          .return("&cursor, nil");
+      });
+
+    w.comment(
+      "DecodeCursor decodes the cursor a paginated query was called with, made by",
+    )
+      .comment(
+        "EncodeCursor, into position. Without one, the first page, position is left",
+      )
+      .comment(
+        "unchanged; cursors clients altered or made up fail with INVALID_ARGUMENT.",
+      )
+      .n()
+      .func("DecodeCursor(cursor *string, position interface{}) error", (b) => {
+        b.if('cursor == nil || *cursor == ""', (b) => {
+          b.return("nil");
+        })
+          .decl("data, err", "base64.RawURLEncoding.DecodeString(*cursor)")
+          .if("err == nil", (b) => {
+            b.l("err = json.Unmarshal(data, position)");
+          })
+          .ifErr((b) => {
+            b.return('NewError(CodeInvalidArgument, "Invalid cursor")');
+          })
+          .return("nil");
+      });
+
+    return w.toString();
+  }
+}
+
+/**
+ * Reports whether any endpoint of the contract is declared with
+ * paginated: true.
+ */
+export function usesPagination(contract: ContractDefinition): boolean {
+  return contract.endpoints.some((endpoint) => endpoint.paginated);
+}
@@ -1,4 +1,27 @@
-import { type GeneratedUtility, toPascalCase } from "@xrpckit/sdk";
+import type { GeneratedUtility } from "@xrpckit/sdk";
+import { toPascalCase } from "./naming";
+
+/**
+ * Returns the Go constant name for each enum value, e.g. "in_progress" of
+ * TaskStatus becomes TaskStatusInProgress. Values that don't produce a
+ * usable or unique name fall back to their position.
+ *
+ * @param name - The enum type name (PascalCase)
+ * @param values - The enum values
+ * @returns One constant name per value, in order
+ */
+export function goEnumConstNames(name: string, values: string[]): string[] {
+  const used = new Set<string>();
+  return values.map((value, index) => {
+    const words = value.split(/[^A-Za-z0-9]+/).filter(Boolean);
+    let constName = name + words.map((word) => toPascalCase(word)).join("");
+    if (words.length === 0 || used.has(constName)) {
+      constName = `${name}Value${index}`;
+    }
+    used.add(constName);
+    return constName;
+  });
+}
 
 /**
  * Create a Go enum pattern with type, constants, IsValid, and Parse functions.
@@ -28,10 +51,11 @@ export function createGoEnumPattern(
   const stringValues = values.filter((v): v is string => typeof v === "string");
 
   // Generate constant names from values
-  const constants = stringValues.map((v) => {
-    const constName = `${name}${toPascalCase(v)}`;
-    return { constName, value: v };
-  });
+  const constNames = goEnumConstNames(name, stringValues);
+  const constants = stringValues.map((v, index) => ({
+    constName: constNames[index],
+    value: v,
+  }));
 
   const code = `// ${name} enum type
 type ${name} string
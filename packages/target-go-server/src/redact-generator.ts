@@ -0,0 +1,273 @@
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+
+/**
+ * Generates redact.go for contracts with fields marked with
+ * .meta({ sensitive: true }): a Redacted method on every struct holding such
+ * a field, directly or in a nested struct, returning a copy with the text of
+ * sensitive fields replaced by RedactedText and their other values cleared,
+ * and Redact, which interceptors, loggers and audit trails call on the inputs
+ * and results they record. Validation never quotes sensitive values either.
+ */
+export class GoRedactGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private redactable: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate redact.go, or undefined when no field is sensitive.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateRedact(
+    structs: GoStruct[],
+    aliases: Map<string, string>,
+  ): string | undefined {
+    this.aliases = aliases;
+    // Structs with sensitive fields, then those nesting them, until no more
+    // are found
+    this.redactable = new Set();
+    let found = true;
+    while (found) {
+      found = false;
+      for (const struct of structs) {
+        if (this.redactable.has(struct.name)) continue;
+        if (
+          struct.fields.some((field) =>
+            this.needsRedaction(field.type, field.sensitive),
+          )
+        ) {
+          this.redactable.add(struct.name);
+          found = true;
+        }
+      }
+    }
+    if (this.redactable.size === 0) {
+      return undefined;
+    }
+    const redactable = structs.filter((struct) =>
+      this.redactable.has(struct.name),
+    );
+
+    const w = this.w.reset();
+    w.package(this.packageName);
+    const cleared = redactable.flatMap((struct) =>
+      struct.fields.filter(
+        (field) => field.sensitive && !this.isText(field.type),
+      ),
+    );
+    if (
+      cleared.some(
+        (field) =>
+          zeroLiteral(field.type) === undefined &&
+          field.type.startsWith("time."),
+      )
+    ) {
+      w.import("time");
+    }
+
+    w.comment(
+      "RedactedText replaces the text of sensitive fields in the copies Redacted",
+    )
+      .comment("returns. Empty text is left empty.")
+      .l('const RedactedText = "[REDACTED]"')
+      .n();
+
+    for (const struct of redactable) {
+      this.generateRedacted(w, struct);
+    }
+    this.generateRedactFunc(w, redactable);
+
+    return w.toString();
+  }
+
+  private generateRedacted(w: GoBuilder, struct: GoStruct): void {
+    const sensitive = struct.fields
+      .filter((field) => field.sensitive)
+      .map((field) => field.name);
+    w.doc(
+      sensitive.length > 0
+        ? `Redacted returns a copy of v safe to log, with ${joinNames(sensitive)} redacted.`
+        : "Redacted returns a copy of v safe to log, with the sensitive fields of its nested values redacted.",
+    );
+    w.n().method(`v ${struct.name}`, "Redacted", "", struct.name, (b) => {
+      for (const field of struct.fields) {
+        if (!this.needsRedaction(field.type, field.sensitive)) continue;
+        this.redactField(b, field.type, `v.${field.name}`, field.sensitive, 0);
+      }
+      b.return("v");
+    });
+  }
+
+  // Replaces expr with a redacted copy. Slices, maps and pointers are copied
+  // before their elements are, so the original value is left untouched.
+  private redactField(
+    b: GoBuilder,
+    goType: string,
+    expr: string,
+    sensitive: boolean,
+    depth: number,
+  ): void {
+    if (sensitive && !this.isText(goType)) {
+      this.clearField(b, goType, expr);
+      return;
+    }
+    if (goType === "string") {
+      b.if(`${expr} != ""`, (b) => {
+        b.l(`${expr} = RedactedText`);
+      });
+      return;
+    }
+    if (this.redactable.has(this.aliases.get(goType) ?? goType)) {
+      b.l(`${expr} = ${expr}.Redacted()`);
+      return;
+    }
+    const suffix = depth === 0 ? "" : `${depth}`;
+    const elem = elemType(goType);
+    if (elem === undefined) return;
+    if (goType.startsWith("*")) {
+      const copy = `p${suffix}`;
+      b.if(`${expr} != nil`, (b) => {
+        if (this.redactable.has(this.aliases.get(elem) ?? elem)) {
+          b.decl(copy, `${expr}.Redacted()`);
+        } else {
+          b.decl(copy, `*${expr}`);
+          this.redactField(b, elem, copy, sensitive, depth + 1);
+        }
+        b.l(`${expr} = &${copy}`);
+      });
+    } else if (goType.startsWith("[]")) {
+      const copy = `s${suffix}`;
+      const index = `i${suffix}`;
+      b.if(`${expr} != nil`, (b) => {
+        b.decl(copy, `make(${goType}, len(${expr}))`)
+          .l(`copy(${copy}, ${expr})`)
+          .l(`for ${index} := range ${copy} {`)
+          .i();
+        this.redactField(b, elem, `${copy}[${index}]`, sensitive, depth + 1);
+        b.u().l("}").l(`${expr} = ${copy}`);
+      });
+    } else {
+      const copy = `m${suffix}`;
+      const key = `k${suffix}`;
+      const value = `e${suffix}`;
+      b.if(`${expr} != nil`, (b) => {
+        b.decl(copy, `make(${goType}, len(${expr}))`)
+          .l(`for ${key}, ${value} := range ${expr} {`)
+          .i();
+        this.redactField(b, elem, value, sensitive, depth + 1);
+        b.l(`${copy}[${key}] = ${value}`).u().l("}").l(`${expr} = ${copy}`);
+      });
+    }
+  }
+
+  // Sensitive values without text, such as numbers, are cleared
+  private clearField(b: GoBuilder, goType: string, expr: string): void {
+    const literal = zeroLiteral(goType);
+    if (literal !== undefined) {
+      b.l(`${expr} = ${literal}`);
+      return;
+    }
+    // Enums, dates, unions and other named types
+    const zero = `zero${expr.slice("v.".length)}`;
+    b.l(`var ${zero} ${goType}`).l(`${expr} = ${zero}`);
+  }
+
+  private generateRedactFunc(w: GoBuilder, structs: GoStruct[]): void {
+    w.comment(
+      "Redact returns a redacted copy of v when it is, or points to, a type with",
+    )
+      .comment(
+        "sensitive fields, and v otherwise. Interceptors, loggers and audit trails",
+      )
+      .comment("call it on the inputs and results they record.")
+      .n()
+      .func("Redact(v interface{}) interface{}", (b) => {
+        b.l("switch v := v.(type) {");
+        for (const struct of structs) {
+          b.l(`case ${struct.name}:`)
+            .i()
+            .return("v.Redacted()")
+            .u()
+            .l(`case *${struct.name}:`)
+            .i()
+            .if("v == nil", (b) => {
+              b.return("v");
+            })
+            .decl("redacted", "v.Redacted()")
+            .return("&redacted")
+            .u();
+        }
+        b.l("}").return("v");
+      });
+  }
+
+  // Whether a field of goType holds a value to redact
+  private needsRedaction(goType: string, sensitive: boolean): boolean {
+    if (sensitive) {
+      return true;
+    }
+    if (this.redactable.has(this.aliases.get(goType) ?? goType)) {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.needsRedaction(elem, false);
+  }
+
+  // Whether goType is a string, or pointers, slices or maps of strings
+  private isText(goType: string): boolean {
+    if (goType === "string") {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.isText(elem);
+  }
+}
+
+// The element type of pointer, slice and map types
+function elemType(goType: string): string | undefined {
+  if (goType.startsWith("*")) {
+    return goType.slice(1);
+  }
+  if (goType.startsWith("[]")) {
+    return goType.slice(2);
+  }
+  if (goType.startsWith("map[")) {
+    return goType.slice(goType.indexOf("]") + 1);
+  }
+  return undefined;
+}
+
+// The zero value of goType written as a literal, for the types that have one
+function zeroLiteral(goType: string): string | undefined {
+  if (
+    goType.startsWith("*") ||
+    goType.startsWith("[]") ||
+    goType.startsWith("map[") ||
+    goType === "interface{}"
+  ) {
+    return "nil";
+  }
+  switch (goType) {
+    case "bool":
+      return "false";
+    case "int":
+    case "float64":
+      return "0";
+  }
+  return undefined;
+}
+
+// Email, or Email and Token, or Email, Phone and Token
+function joinNames(names: string[]): string {
+  if (names.length === 1) {
+    return names[0];
+  }
+  return `${names.slice(0, -1).join(", ")} and ${names[names.length - 1]}`;
+}
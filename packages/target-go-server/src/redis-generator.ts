@@ -0,0 +1,490 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates redis.go: the Redis stores of the Cache interceptor and the
+ * Sessions middleware, RedisCacheStore and RedisSessionStore, so the replicas
+ * of a server share cached results and sessions. They speak the Redis
+ * protocol (RESP) over a pool of plain net connections, keeping the generated
+ * server free of a client library.
+ */
+export class GoRedisGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateRedis(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "bufio",
+      "bytes",
+      "context",
+      "encoding/json",
+      "errors",
+      "fmt",
+      "io",
+      "net",
+      "strconv",
+      "time",
+    );
+
+    this.generateRedisClient(w);
+    this.generateRedisCacheStore(w);
+    this.generateRedisSessionStore(w);
+    this.generateRedisConn(w);
+
+    return w.toString();
+  }
+
+  private generateRedisClient(w: GoBuilder): void {
+    w.comment(
+      "RedisOptions configures the connections of a RedisCacheStore or a",
+    )
+      .comment("RedisSessionStore.")
+      .struct("RedisOptions", (b) => {
+        b.comment(
+          "Username and Password authenticate connections with AUTH when Password is",
+        )
+          .comment("set; Username is for the ACL users of Redis 6 and later.")
+          .l("Username string")
+          .l("Password string")
+          .comment("DB is the database selected with SELECT, 0 by default.")
+          .l("DB int")
+          .comment(
+            "MaxIdleConns is the number of idle connections kept for reuse, 8 by",
+          )
+          .comment("default.")
+          .l("MaxIdleConns int")
+          .comment("DialTimeout bounds connecting, 5 seconds by default.")
+          .l("DialTimeout time.Duration");
+      });
+
+    w.comment(
+      "redisClient sends the commands of a Redis store over pooled connections.",
+    )
+      .comment(
+        "Commands are bounded by the deadline of their context, such as the timeout",
+      )
+      .comment("of the call.")
+      .struct("redisClient", (b) => {
+        b.l("addr    string").l("options RedisOptions").l("idle    chan *redisConn");
+      });
+
+    w.comment(
+      "newRedisClient returns a client of the Redis server at addr. Connections are",
+    )
+      .comment("made when they are first needed.")
+      .n()
+      .func(
+        "newRedisClient(addr string, options RedisOptions) *redisClient",
+        (b) => {
+          b.if("options.MaxIdleConns <= 0", (b) => {
+            b.l("options.MaxIdleConns = 8");
+          })
+            .if("options.DialTimeout <= 0", (b) => {
+              b.l("options.DialTimeout = 5 * time.Second");
+            })
+            .return(
+              "&redisClient{addr: addr, options: options, idle: make(chan *redisConn, options.MaxIdleConns)}",
+            );
+        },
+      );
+
+    w.comment("Close closes the idle connections of the store.")
+      .n()
+      .method("c *redisClient", "Close", "", "error", (b) => {
+        b.l("for {")
+          .i()
+          .l("select {")
+          .l("case conn := <-c.idle:")
+          .i()
+          .l("conn.Close()")
+          .u()
+          .l("default:")
+          .i()
+          .return("nil")
+          .u()
+          .l("}")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "do sends a command and returns its reply. Idle connections the server closed",
+    )
+      .comment(
+        "fail their first command, which is sent again on a new connection; every",
+      )
+      .comment("command of the stores is safe to repeat.")
+      .n()
+      .method(
+        "c *redisClient",
+        "do",
+        "ctx context.Context, args ...string",
+        "(interface{}, error)",
+        (b) => {
+          b.l("for {")
+            .i()
+            .decl("conn, reused, err", "c.conn(ctx)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .decl("reply, err", "conn.do(ctx, args...)")
+            .var("replyErr", "redisError")
+            .if("err == nil || errors.As(err, &replyErr)", (b) => {
+              b.l("c.release(conn)").return("reply, err");
+            })
+            .comment("The connection may be left in the middle of a reply")
+            .l("conn.Close()")
+            .if("!reused || ctx.Err() != nil", (b) => {
+              b.return("nil, err");
+            })
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "conn returns an idle connection, reporting that it was reused, or a new one",
+    )
+      .comment("authenticated and set to the database of the options.")
+      .n()
+      .method(
+        "c *redisClient",
+        "conn",
+        "ctx context.Context",
+        "(*redisConn, bool, error)",
+        (b) => {
+          b.l("select {")
+            .l("case conn := <-c.idle:")
+            .i()
+            .return("conn, true, nil")
+            .u()
+            .l("default:")
+            .l("}")
+            .decl("dialer", "net.Dialer{Timeout: c.options.DialTimeout}")
+            .decl("netConn, err", 'dialer.DialContext(ctx, "tcp", c.addr)')
+            .ifErr((b) => {
+              b.return("nil, false, err");
+            })
+            .decl(
+              "conn",
+              "&redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}",
+            )
+            .if('c.options.Password != ""', (b) => {
+              b.decl("args", '[]string{"AUTH", c.options.Password}')
+                .if('c.options.Username != ""', (b) => {
+                  b.l(
+                    'args = []string{"AUTH", c.options.Username, c.options.Password}',
+                  );
+                })
+                .if("_, err := conn.do(ctx, args...); err != nil", (b) => {
+                  b.l("conn.Close()").return("nil, false, err");
+                });
+            })
+            .if("c.options.DB != 0", (b) => {
+              b.if(
+                '_, err := conn.do(ctx, "SELECT", strconv.Itoa(c.options.DB)); err != nil',
+                (b) => {
+                  b.l("conn.Close()").return("nil, false, err");
+                },
+              );
+            })
+            .return("conn, false, nil");
+        },
+      );
+
+    w.comment(
+      "release keeps conn for reuse, or closes it when enough connections are idle.",
+    )
+      .n()
+      .method("c *redisClient", "release", "conn *redisConn", "", (b) => {
+        b.l("select {")
+          .l("case c.idle <- conn:")
+          .l("default:")
+          .i()
+          .l("conn.Close()")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "getJSON decodes the value of key, reporting false if there is none.",
+    )
+      .n()
+      .method(
+        "c *redisClient",
+        "getJSON",
+        "ctx context.Context, key string, value interface{}",
+        "(bool, error)",
+        (b) => {
+          b.decl("reply, err", 'c.do(ctx, "GET", key)')
+            .if("err != nil || reply == nil", (b) => {
+              b.return("false, err");
+            })
+            .decl("data, ok", "reply.([]byte)")
+            .if("!ok", (b) => {
+              b.return(
+                'false, fmt.Errorf("redis: unexpected GET reply %v", reply)',
+              );
+            })
+            .if("err := json.Unmarshal(data, value); err != nil", (b) => {
+              b.return("false, err");
+            })
+            .return("true, nil");
+        },
+      );
+
+    w.comment(
+      "setJSON sets key to the JSON of value with SET, for Redis to expire it after",
+    )
+      .comment("ttl.")
+      .n()
+      .method(
+        "c *redisClient",
+        "setJSON",
+        "ctx context.Context, key string, value interface{}, ttl time.Duration",
+        "error",
+        (b) => {
+          b.if("ttl.Milliseconds() <= 0", (b) => {
+            b.return("nil");
+          })
+            .decl("data, err", "json.Marshal(value)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l(
+              '_, err = c.do(ctx, "SET", key, string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))',
+            )
+            .return("err");
+        },
+      );
+  }
+
+  private generateRedisCacheStore(w: GoBuilder): void {
+    w.comment(
+      "RedisCacheStore keeps the entries of the Cache interceptor in Redis, shared by",
+    )
+      .comment("every replica of the server and expired by Redis.")
+      .struct("RedisCacheStore", (b) => {
+        b.l("*redisClient");
+      });
+
+    w.comment(
+      "NewRedisCacheStore returns a RedisCacheStore for the Redis server at addr, such",
+    )
+      .comment("as localhost:6379. Connections are made when they are first needed.")
+      .n()
+      .func(
+        "NewRedisCacheStore(addr string, options RedisOptions) *RedisCacheStore",
+        (b) => {
+          b.return("&RedisCacheStore{newRedisClient(addr, options)}");
+        },
+      );
+
+    w.comment("Load returns the entry stored under key with GET.")
+      .n()
+      .method(
+        "s *RedisCacheStore",
+        "Load",
+        "ctx context.Context, key string",
+        "(CacheEntry, bool, error)",
+        (b) => {
+          b.var("entry", "CacheEntry")
+            .decl("ok, err", "s.getJSON(ctx, key, &entry)")
+            .return("entry, ok, err");
+        },
+      );
+
+    w.comment(
+      "Save stores entry under key with SET, expiring it in Redis when it expires.",
+    )
+      .n()
+      .method(
+        "s *RedisCacheStore",
+        "Save",
+        "ctx context.Context, key string, entry CacheEntry",
+        "error",
+        (b) => {
+          b.return("s.setJSON(ctx, key, entry, time.Until(entry.Expires))");
+        },
+      );
+  }
+
+  private generateRedisSessionStore(w: GoBuilder): void {
+    w.comment(
+      "RedisSessionStore keeps the sessions of the Sessions middleware in Redis under",
+    )
+      .comment(
+        '"xrpc:session:" and their ID, shared by every replica of the server and',
+      )
+      .comment("expired by Redis.")
+      .struct("RedisSessionStore", (b) => {
+        b.l("*redisClient");
+      });
+
+    w.comment(
+      "NewRedisSessionStore returns a RedisSessionStore for the Redis server at addr,",
+    )
+      .comment(
+        "such as localhost:6379. Connections are made when they are first needed.",
+      )
+      .n()
+      .func(
+        "NewRedisSessionStore(addr string, options RedisOptions) *RedisSessionStore",
+        (b) => {
+          b.return("&RedisSessionStore{newRedisClient(addr, options)}");
+        },
+      );
+
+    w.comment("Load returns the values of the session id with GET.")
+      .n()
+      .method(
+        "s *RedisSessionStore",
+        "Load",
+        "ctx context.Context, id string",
+        "(map[string]json.RawMessage, bool, error)",
+        (b) => {
+          b.var("values", "map[string]json.RawMessage")
+            .decl("ok, err", 's.getJSON(ctx, "xrpc:session:"+id, &values)')
+            .return("values, ok, err");
+        },
+      );
+
+    w.comment(
+      "Save stores the values of the session id with SET, expiring them after ttl.",
+    )
+      .n()
+      .method(
+        "s *RedisSessionStore",
+        "Save",
+        "ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration",
+        "error",
+        (b) => {
+          b.return('s.setJSON(ctx, "xrpc:session:"+id, values, ttl)');
+        },
+      );
+
+    w.comment("Delete removes the session id with DEL.")
+      .n()
+      .method(
+        "s *RedisSessionStore",
+        "Delete",
+        "ctx context.Context, id string",
+        "error",
+        (b) => {
+          b.decl("_, err", 's.do(ctx, "DEL", "xrpc:session:"+id)').return(
+            "err",
+          );
+        },
+      );
+  }
+
+  private generateRedisConn(w: GoBuilder): void {
+    w.comment("redisConn is a connection to a Redis server.").struct(
+      "redisConn",
+      (b) => {
+        b.l("net.Conn").l("reader *bufio.Reader");
+      },
+    );
+
+    w.comment(
+      "redisError is an error reply of the server, after which the connection is",
+    )
+      .comment("still usable.")
+      .type("redisError", "string");
+
+    w.method("e redisError", "Error", "", "string", (b) => {
+      b.return('"redis: " + string(e)');
+    });
+
+    w.comment(
+      "do sends a command as an array of bulk strings and reads its reply, by the",
+    )
+      .comment("deadline of ctx if it has one.")
+      .n()
+      .method(
+        "c *redisConn",
+        "do",
+        "ctx context.Context, args ...string",
+        "(interface{}, error)",
+        (b) => {
+          b.comment("The zero time of contexts without a deadline clears it")
+            .decl("deadline, _", "ctx.Deadline()")
+            .if("err := c.SetDeadline(deadline); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .var("buf", "bytes.Buffer")
+            .l('fmt.Fprintf(&buf, "*%d\\r\\n", len(args))')
+            .l("for _, arg := range args {")
+            .i()
+            .l('fmt.Fprintf(&buf, "$%d\\r\\n%s\\r\\n", len(arg), arg)')
+            .u()
+            .l("}")
+            .if("_, err := c.Write(buf.Bytes()); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .return("c.readReply()");
+        },
+      );
+
+    w.comment(
+      "readReply reads a reply: a string for simple strings, an int64 for integers,",
+    )
+      .comment(
+        "a []byte for bulk strings, nil for the null bulk string of a missing key, or",
+      )
+      .comment("a redisError.")
+      .n()
+      .method(
+        "c *redisConn",
+        "readReply",
+        "",
+        "(interface{}, error)",
+        (b) => {
+          b.decl("line, err", "c.reader.ReadString('\\n')")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .if("len(line) < 3 || line[len(line)-2] != '\\r'", (b) => {
+              b.return('nil, fmt.Errorf("redis: malformed reply %q", line)');
+            })
+            .decl("text", "line[1 : len(line)-2]")
+            .l("switch line[0] {")
+            .l("case '+':")
+            .i()
+            .return("text, nil")
+            .u()
+            .l("case '-':")
+            .i()
+            .return("nil, redisError(text)")
+            .u()
+            .l("case ':':")
+            .i()
+            .return("strconv.ParseInt(text, 10, 64)")
+            .u()
+            .l("case '$':")
+            .i()
+            .decl("n, err", "strconv.Atoi(text)")
+            .ifErr((b) => {
+              b.return('nil, fmt.Errorf("redis: malformed reply %q", line)');
+            })
+            .if("n < 0", (b) => {
+              b.return("nil, nil");
+            })
+            .decl("data", "make([]byte, n+2)")
+            .if("_, err := io.ReadFull(c.reader, data); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .return("data[:n], nil")
+            .u()
+            .l("}")
+            .return('nil, fmt.Errorf("redis: unexpected reply %q", line)');
+        },
+      );
+  }
+}
@@ -0,0 +1,410 @@
+import type { Endpoint, TypeReference } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+
+/**
+ * How a query or path parameter is encoded as JSON for an input field:
+ * "string" values are quoted, "strings" and "array" fields collect repeated
+ * parameters (quoted or as JSON literals) and any other field is read as a
+ * JSON literal.
+ */
+export type ParamKind = "string" | "strings" | "array";
+
+export function unwrap(typeRef: TypeReference): TypeReference {
+  if (
+    (typeRef.kind === "optional" || typeRef.kind === "nullable") &&
+    typeof typeRef.baseType === "object"
+  ) {
+    return unwrap(typeRef.baseType);
+  }
+  return typeRef;
+}
+
+function isStringLike(typeRef: TypeReference): boolean {
+  const type = unwrap(typeRef);
+  switch (type.kind) {
+    case "primitive":
+      return ["string", "uuid", "email", "bytes"].includes(String(type.baseType));
+    case "date":
+      return true;
+    case "enum":
+      return (type.enumValues ?? []).every(
+        (value) => typeof value === "string",
+      );
+    case "literal":
+      return typeof type.literalValue === "string";
+    default:
+      return false;
+  }
+}
+
+export function paramKind(typeRef: TypeReference): ParamKind | undefined {
+  const type = unwrap(typeRef);
+  if (type.kind === "array") {
+    return type.elementType && isStringLike(type.elementType)
+      ? "strings"
+      : "array";
+  }
+  return isStringLike(type) ? "string" : undefined;
+}
+
+/** Splits a mapped path into its segments, without the leading slash. */
+export function pathSegments(path: string): string[] {
+  return path.replace(/^\/+|\/+$/g, "").split("/");
+}
+
+/**
+ * Orders routes so literal segments are tried before path parameters at the
+ * same position: "/tasks/search" matches before "/tasks/{id}".
+ */
+function compareRoutes(a: Endpoint, b: Endpoint): number {
+  const as = pathSegments(a.http!.path);
+  const bs = pathSegments(b.http!.path);
+  for (let i = 0; i < Math.min(as.length, bs.length); i++) {
+    const aParam = as[i].startsWith("{");
+    const bParam = bs[i].startsWith("{");
+    if (aParam !== bParam) {
+      return aParam ? 1 : -1;
+    }
+  }
+  return 0;
+}
+
+/**
+ * Generates rest.go for contracts with endpoints declaring an http mapping:
+ * Router.RESTHandler serves them as RESTful routes, binding path and query
+ * parameters and the JSON body into the method's params and calling the same
+ * typed handlers, middleware and validation as the RPC endpoint.
+ */
+export class GoRESTGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate rest.go, or undefined when no endpoint declares an http mapping.
+   */
+  generateREST(endpoints: Endpoint[]): string | undefined {
+    const routes = endpoints
+      .filter((endpoint) => endpoint.http)
+      .sort(compareRoutes);
+    if (routes.length === 0) {
+      return undefined;
+    }
+
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "encoding/json",
+      "errors",
+      "io",
+      "net/http",
+      "net/url",
+      "strings",
+    );
+
+    this.generateRoutes(w, routes);
+    this.generateHandler(w);
+    this.generateMatching(w);
+    this.generateBinding(w);
+
+    return w.toString();
+  }
+
+  private generateRoutes(w: GoBuilder, routes: Endpoint[]): void {
+    w.comment(
+      "restRoute maps an HTTP method and path to a method of the router. Segments",
+    )
+      .comment(
+        'in braces capture a path parameter. kinds tells how the "string" and array',
+      )
+      .comment(
+        "fields of the input are bound from query and path parameters; any other",
+      )
+      .comment("field is read as a JSON literal.")
+      .struct("restRoute", (b) => {
+        b.l("method   string")
+          .l("segments []string")
+          .l("call     string")
+          .l("kinds    map[string]string");
+      });
+
+    w.comment("restRoutes lists the routes, literal segments before parameters")
+      .l("var restRoutes = []restRoute{")
+      .i();
+    for (const endpoint of routes) {
+      const http = endpoint.http!;
+      const segments = pathSegments(http.path)
+        .map((segment) => JSON.stringify(segment))
+        .join(", ");
+      const kinds: string[] = [];
+      const input = unwrap(endpoint.input);
+      for (const prop of input.properties ?? []) {
+        const kind = paramKind(prop.type);
+        if (kind) {
+          kinds.push(`${JSON.stringify(prop.name)}: "${kind}"`);
+        }
+      }
+      const method = http.method[0] + http.method.slice(1).toLowerCase();
+      w.l("{")
+        .i()
+        .l(`method:   http.Method${method},`)
+        .l(`segments: []string{${segments}},`)
+        .l(`call:     "${endpoint.fullName}",`)
+        .l(`kinds:    map[string]string{${kinds.join(", ")}},`)
+        .u()
+        .l("},");
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "restCallKey is the context key of the restCall RESTHandler bound a request to.",
+    )
+      .type("restCallKey", "struct{}");
+
+    w.comment(
+      "restCall is the method and params of a request served by RESTHandler.",
+    ).struct("restCall", (b) => {
+      b.l("method string").l("params json.RawMessage");
+    });
+  }
+
+  private generateHandler(w: GoBuilder): void {
+    w.comment(
+      "RESTHandler serves the methods declared with an http mapping as RESTful routes",
+    )
+      .comment(
+        "for clients that cannot send RPC envelopes. Path parameters, query parameters",
+      )
+      .comment(
+        "and the JSON body of POST, PUT and PATCH requests are bound to the input",
+      )
+      .comment(
+        "fields of the same name (path parameters win), and the call goes through the",
+      )
+      .comment(
+        "same middleware, validation and handler as the RPC method. Results are written",
+      )
+      .comment(
+        'without the {"result": ...} envelope; errors keep their usual body and status.',
+      )
+      .comment("Mount it next to the RPC endpoint:")
+      .comment("")
+      .comment(
+        '    mux.Handle("/rest/", http.StripPrefix("/rest", router.RESTHandler()))',
+      )
+      .n()
+      .method("r *Router", "RESTHandler", "", "http.Handler", (b) => {
+        b.l(
+          "return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {",
+        )
+          .i()
+          .l("route, pathParams, allowed := matchRESTRoute(req.Method, req.URL)")
+          .if("route == nil && len(allowed) > 0", (b) => {
+            b.l('w.Header().Set("Allow", strings.Join(allowed, ", "))')
+              .l(
+                'r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))',
+              )
+              .return();
+          })
+          .if("route == nil", (b) => {
+            b.l(
+              'r.writeError(w, Errorf(CodeNotFound, "Route not found: %s %s", req.Method, req.URL.Path))',
+            ).return();
+          })
+          .decl("params, err", "bindRESTParams(req, route, pathParams)")
+          .ifErr((b) => {
+            b.l(
+              'r.writeError(w, Errorf(CodeInvalidArgument, "Invalid request: %v", err))',
+            ).return();
+          })
+          .decl(
+            "ctx",
+            "context.WithValue(req.Context(), restCallKey{}, restCall{method: route.call, params: params})",
+          )
+          .l("r.ServeHTTP(w, req.WithContext(ctx))")
+          .u()
+          .l("})");
+      });
+
+    w.comment(
+      "restCallFrom returns the method and params RESTHandler bound req to, if any.",
+    )
+      .n()
+      .func("restCallFrom(req *http.Request) (restCall, bool)", (b) => {
+        b.decl("call, ok", "req.Context().Value(restCallKey{}).(restCall)")
+          .return("call, ok");
+      });
+
+    w.comment(
+      'marshalResult encodes a result in the {"result": ...} envelope, or bare for',
+    )
+      .comment("requests served by RESTHandler, into a pooled buffer.")
+      .n()
+      .method(
+        "r *Router",
+        "marshalResult",
+        "req *http.Request, result interface{}",
+        "(*encodeBuffer, error)",
+        (b) => {
+          b.decl("_, ok", "restCallFrom(req)").return("r.marshal(result, !ok)");
+        },
+      );
+  }
+
+  private generateMatching(w: GoBuilder): void {
+    w.comment(
+      "matchRESTRoute returns the route matching method and the path of u with its",
+    )
+      .comment(
+        "path parameters. When only other methods match the path, it returns them as",
+      )
+      .comment("allowed instead.")
+      .n()
+      .func(
+        "matchRESTRoute(method string, u *url.URL) (route *restRoute, pathParams map[string]string, allowed []string)",
+        (b) => {
+          b.comment(
+            "Split the escaped path so an encoded slash stays inside its segment",
+          )
+            .decl(
+              "segments",
+              'strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")',
+            )
+            .l("for i := range restRoutes {")
+            .i()
+            .decl("params, ok", "matchRESTPath(restRoutes[i].segments, segments)")
+            .if("!ok", (b) => {
+              b.l("continue");
+            })
+            .if("restRoutes[i].method != method", (b) => {
+              b.l("allowed = append(allowed, restRoutes[i].method)").l(
+                "continue",
+              );
+            })
+            .return("&restRoutes[i], params, nil")
+            .u()
+            .l("}")
+            .return("nil, nil, allowed");
+        },
+      );
+
+    w.comment(
+      "matchRESTPath matches escaped path segments against a route's segments,",
+    )
+      .comment("returning the unescaped path parameters.")
+      .n()
+      .func(
+        "matchRESTPath(pattern, segments []string) (map[string]string, bool)",
+        (b) => {
+          b.if("len(pattern) != len(segments)", (b) => {
+            b.return("nil, false");
+          })
+            .decl("params", "map[string]string{}")
+            .l("for i, part := range pattern {")
+            .i()
+            .decl("value, err", "url.PathUnescape(segments[i])")
+            .if("err != nil", (b) => {
+              b.return("nil, false");
+            })
+            .if('strings.HasPrefix(part, "{")', (b) => {
+              b.if('value == ""', (b) => {
+                b.return("nil, false");
+              })
+                .l("params[part[1:len(part)-1]] = value")
+                .l("continue");
+            })
+            .if("value != part", (b) => {
+              b.return("nil, false");
+            })
+            .u()
+            .l("}")
+            .return("params, true");
+        },
+      );
+  }
+
+  private generateBinding(w: GoBuilder): void {
+    w.comment(
+      "bindRESTParams builds the params of a REST request from its JSON body, then",
+    )
+      .comment("its query parameters and finally its path parameters.")
+      .n()
+      .func(
+        "bindRESTParams(req *http.Request, route *restRoute, pathParams map[string]string) (json.RawMessage, error)",
+        (b) => {
+          b.decl("fields", "map[string]json.RawMessage{}")
+            .if(
+              "req.Method != http.MethodGet && req.Method != http.MethodDelete",
+              (b) => {
+                b.if(
+                  "err := decodeRequest(req, &fields); err != nil && !errors.Is(err, io.EOF)",
+                  (b) => {
+                    b.return("nil, err");
+                  },
+                ).if("fields == nil", (b) => {
+                  b.comment("The body was null")
+                    .l("fields = map[string]json.RawMessage{}");
+                });
+              },
+            )
+            .l("for name, values := range req.URL.Query() {")
+            .i()
+            .l("fields[name] = route.bind(name, values)")
+            .u()
+            .l("}")
+            .l("for name, value := range pathParams {")
+            .i()
+            .l("fields[name] = route.bind(name, []string{value})")
+            .u()
+            .l("}")
+            .return("json.Marshal(fields)");
+        },
+      );
+
+    w.comment(
+      "bind encodes the query or path parameter values of an input field as JSON.",
+    )
+      .comment("Single-valued fields take the last value.")
+      .n()
+      .method(
+        "route *restRoute",
+        "bind",
+        "name string, values []string",
+        "json.RawMessage",
+        (b) => {
+          b.decl("kind", "route.kinds[name]")
+            .if('kind == "strings" || kind == "array"', (b) => {
+              b.decl("items", "make([]json.RawMessage, len(values))")
+                .l("for i, value := range values {")
+                .i()
+                .l('items[i] = restValue(value, kind == "strings")')
+                .u()
+                .l("}")
+                .decl("data, _", "json.Marshal(items)")
+                .return("data");
+            })
+            .return('restValue(values[len(values)-1], kind == "string")');
+        },
+      );
+
+    w.comment(
+      "restValue encodes a parameter value as a JSON string when quoted is set or",
+    )
+      .comment(
+        "the value is not valid JSON, and as the JSON literal it spells otherwise.",
+      )
+      .n()
+      .func("restValue(value string, quoted bool) json.RawMessage", (b) => {
+        b.if("!quoted && json.Valid([]byte(value))", (b) => {
+          b.return("json.RawMessage(value)");
+        })
+          .decl("data, _", "json.Marshal(value)")
+          .return("data");
+      });
+  }
+}
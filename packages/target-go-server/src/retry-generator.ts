@@ -0,0 +1,184 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates retry.go: the RetryPolicy a Client retries failed calls with,
+ * backing off exponentially with jitter within the call's budget and
+ * deadline and honoring the Retry-After of the server. Only queries are
+ * retried by default, as mutations may not be idempotent.
+ */
+export class GoRetryGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateRetry(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "errors",
+      "math/rand",
+      "net/http",
+      "net/url",
+      "strconv",
+      "time",
+    );
+
+    w.comment(
+      "RetryPolicy decides which failed calls a Client retries and how long it waits",
+    )
+      .comment(
+        "before each retry: InitialBackoff, doubled after every retry up to",
+      )
+      .comment(
+        "MaxBackoff and jittered so clients that failed together don't retry",
+      )
+      .comment(
+        "together, or the Retry-After the server answered with. A retry that could",
+      )
+      .comment("not start within Budget or the call's deadline is not made.")
+      .struct("RetryPolicy", (b) => {
+        b.comment(
+          "MaxAttempts bounds the attempts of a call, the first included; 1 turns",
+        )
+          .comment("retrying off")
+          .l("MaxAttempts int")
+          .l("InitialBackoff time.Duration")
+          .comment("MaxBackoff caps the backoff; 0 leaves it uncapped")
+          .l("MaxBackoff time.Duration")
+          .comment(
+            "Budget bounds the time from the first attempt to the start of the last;",
+          )
+          .comment("0 leaves it unbounded")
+          .l("Budget time.Duration")
+          .comment(
+            "Retryable reports whether a call of method that failed with err may be",
+          )
+          .comment("retried; nil uses DefaultRetryable")
+          .l("Retryable func(method string, err error) bool");
+      });
+
+    w.comment(
+      "DefaultRetryPolicy is the RetryPolicy of Clients created without",
+    )
+      .comment(
+        "WithRetryPolicy: up to three attempts of the queries DefaultRetryable",
+      )
+      .comment("allows, 100ms apart and then 200ms, give or take the jitter.")
+      .l(
+        "var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}",
+      )
+      .n();
+
+    w.comment(
+      "DefaultRetryable retries queries, which don't change server state, when they",
+    )
+      .comment(
+        "failed to reach the server or were answered UNAVAILABLE, DEADLINE_EXCEEDED",
+      )
+      .comment(
+        "or RESOURCE_EXHAUSTED. Mutations are never retried, as a failed one may",
+      )
+      .comment("still have been applied.")
+      .n()
+      .func("DefaultRetryable(method string, err error) bool", (b) => {
+        b.if('m, ok := methodTable[method]; !ok || m.kind != "query"', (b) => {
+          b.return("false");
+        })
+          .if(
+            "errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)",
+            (b) => {
+              b.return("false");
+            },
+          )
+          .var("callErr", "*Error")
+          .if("errors.As(err, &callErr)", (b) => {
+            b.return(
+              "callErr.Code == CodeUnavailable || callErr.Code == CodeDeadlineExceeded || callErr.Code == CodeResourceExhausted",
+            );
+          })
+          .comment("http.Client.Do fails with a *url.Error")
+          .var("transportErr", "*url.Error")
+          .return("errors.As(err, &transportErr)");
+      });
+
+    w.comment(
+      "backoff returns how long to wait before retrying a call of method that failed",
+    )
+      .comment(
+        "with err on its attempt-th attempt, elapsed after the first one started, or",
+      )
+      .comment("false if it must not be retried.")
+      .n()
+      .method(
+        "p RetryPolicy",
+        "backoff",
+        "ctx context.Context, method string, err error, attempt int, retryAfter, elapsed time.Duration",
+        "(time.Duration, bool)",
+        (b) => {
+          b.if("attempt >= p.MaxAttempts", (b) => {
+            b.return("0, false");
+          })
+            .decl("retryable", "p.Retryable")
+            .if("retryable == nil", (b) => {
+              b.l("retryable = DefaultRetryable");
+            })
+            .if("!retryable(method, err)", (b) => {
+              b.return("0, false");
+            })
+            .n()
+            .decl("wait", "retryAfter")
+            .if("wait <= 0", (b) => {
+              b.l("wait = p.InitialBackoff")
+                .l(
+                  "for i := 1; i < attempt && (p.MaxBackoff == 0 || wait < p.MaxBackoff); i++ {",
+                )
+                .i()
+                .l("wait *= 2")
+                .u()
+                .l("}")
+                .if("p.MaxBackoff > 0 && wait > p.MaxBackoff", (b) => {
+                  b.l("wait = p.MaxBackoff");
+                })
+                .l(
+                  "wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))",
+                );
+            })
+            .if("p.Budget > 0 && elapsed+wait > p.Budget", (b) => {
+              b.return("0, false");
+            })
+            .if(
+              "deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline)",
+              (b) => {
+                b.return("0, false");
+              },
+            )
+            .return("wait, true");
+        },
+      );
+
+    w.comment(
+      "retryAfter parses a Retry-After header, in seconds or an HTTP date; 0 when it",
+    )
+      .comment("is absent or invalid.")
+      .n()
+      .func("retryAfter(value string) time.Duration", (b) => {
+        b.if('value == ""', (b) => {
+          b.return("0");
+        })
+          .if("seconds, err := strconv.Atoi(value); err == nil", (b) => {
+            b.return("time.Duration(seconds) * time.Second");
+          })
+          .if("date, err := http.ParseTime(value); err == nil", (b) => {
+            b.return("time.Until(date)");
+          })
+          .return("0");
+      });
+
+    return w.toString();
+  }
+}
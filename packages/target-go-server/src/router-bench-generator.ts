@@ -0,0 +1,288 @@
+import {
+  type ContractDefinition,
+  ExampleError,
+  type Property,
+  type TypeReference,
+  type ValidationRules,
+  typeToExample,
+} from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { type MethodExample, methodExample } from "./router-test-generator";
+
+// Caps on the lengths of large payloads, for fields without an upper bound or
+// with one too high to be representative
+const LARGE_STRING_LENGTH = 1024;
+const LARGE_COLLECTION_SIZE = 100;
+
+/**
+ * Generates router_bench_test.go: benchmarks decoding, validating and
+ * dispatching the params of every method, reporting allocations, with its
+ * example params and with large ones whose strings, arrays and records are as
+ * long as their constraints allow, so the cost of the generated code can be
+ * compared across releases with benchstat.
+ */
+export class GoRouterBenchGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  // Returns an empty string when no method has example params to benchmark
+  generateRouterBenchmarks(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    // Subscriptions have no single result to dispatch for
+    const methods = contract.endpoints
+      .filter((endpoint) => endpoint.type !== "subscription")
+      .map(methodExample)
+      .filter((method) => method.input !== undefined);
+
+    if (methods.length === 0) {
+      return "";
+    }
+    w.package(this.packageName).import("context", "encoding/json", "testing");
+
+    this.generatePayloads(w, methods);
+    this.generateBenchmarks(w, methods);
+
+    return w.toString();
+  }
+
+  private generatePayloads(w: GoBuilder, methods: MethodExample[]): void {
+    w.comment(
+      "benchmarkPayloads holds the params of every method in two sizes: its example,",
+    )
+      .comment(
+        "and a large one with strings, arrays and records as long as their constraints",
+      )
+      .comment(
+        `allow, up to ${LARGE_STRING_LENGTH} characters and ${LARGE_COLLECTION_SIZE} items.`,
+      )
+      .l("var benchmarkPayloads = []struct {")
+      .i()
+      .l("method string")
+      .l("size   string")
+      .l("params string")
+      .u()
+      .l("}{")
+      .i();
+    for (const method of methods) {
+      const name = method.endpoint.fullName;
+      w.l(`{"${name}", "example", example${method.inputType}},`);
+      const large = largeExample(method.endpoint.input);
+      if (large !== undefined && large !== method.input) {
+        w.l(`{"${name}", "large", ${goStringLiteral(large)}},`);
+      }
+    }
+    w.u().l("}").n();
+  }
+
+  private generateBenchmarks(w: GoBuilder, methods: MethodExample[]): void {
+    w.comment(
+      "BenchmarkDecode decodes the params of every method into its input type.",
+    )
+      .n()
+      .func("BenchmarkDecode(b *testing.B)", (b) => {
+        b.decl("r", "newTestRouter()")
+          .l("for _, payload := range benchmarkPayloads {")
+          .i()
+          .decl(
+            "m, params",
+            "methodTable[payload.method], json.RawMessage(payload.params)",
+          )
+          .l('b.Run(payload.method+"/"+payload.size, func(b *testing.B) {')
+          .i()
+          .l("b.ReportAllocs()")
+          .l("b.SetBytes(int64(len(params)))")
+          .l("for i := 0; i < b.N; i++ {")
+          .i()
+          .if("_, err := m.decode(r, params); err != nil", (b) => {
+            b.l("b.Fatal(err)");
+          })
+          .u()
+          .l("}")
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+
+    w.comment("BenchmarkValidate validates the decoded params of every method.")
+      .n()
+      .func("BenchmarkValidate(b *testing.B)", (b) => {
+        b.decl("r", "newTestRouter()")
+          .l("for _, payload := range benchmarkPayloads {")
+          .i()
+          .decl("m", "methodTable[payload.method]")
+          .decl("input, err", "m.decode(r, json.RawMessage(payload.params))")
+          .ifErr((b) => {
+            b.l("b.Fatal(err)");
+          })
+          .l('b.Run(payload.method+"/"+payload.size, func(b *testing.B) {')
+          .i()
+          .l("b.ReportAllocs()")
+          .l("for i := 0; i < b.N; i++ {")
+          .i()
+          .if("err := m.validate(input); err != nil", (b) => {
+            b.l("b.Fatal(err)");
+          })
+          .u()
+          .l("}")
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+
+    const async = methods.filter((method) => method.endpoint.async);
+    w.comment(
+      "BenchmarkDispatch calls every method through Dispatch: decoding and validating",
+    ).comment(
+      "its params and calling its handler through the interceptors, without HTTP.",
+    );
+    if (async.length > 0) {
+      w.comment(
+        "Async mutations are left out, as every call would start an operation.",
+      );
+    }
+    w.n().func("BenchmarkDispatch(b *testing.B)", (b) => {
+      b.decl("r", "newTestRouter()")
+        .comment("Methods requiring authentication accept any user")
+        .decl("ctx", 'WithUserID(context.Background(), "test-user")')
+        .l("for _, payload := range benchmarkPayloads {")
+        .i();
+      if (async.length > 0) {
+        b.if("methodTable[payload.method].async", (b) => {
+          b.l("continue");
+        });
+      }
+      b.decl(
+        "method, params",
+        "payload.method, json.RawMessage(payload.params)",
+      )
+        .l('b.Run(method+"/"+payload.size, func(b *testing.B) {')
+        .i()
+        .l("b.ReportAllocs()")
+        .l("b.SetBytes(int64(len(params)))")
+        .l("for i := 0; i < b.N; i++ {")
+        .i()
+        .if("_, err := r.Dispatch(ctx, method, params); err != nil", (b) => {
+          b.l("b.Fatal(err)");
+        })
+        .u()
+        .l("}")
+        .u()
+        .l("})")
+        .u()
+        .l("}");
+    });
+  }
+}
+
+// The JSON of an example of typeRef with its strings, arrays and records
+// grown to their upper bounds, or undefined when no such example exists
+function largeExample(typeRef: TypeReference): string | undefined {
+  try {
+    return JSON.stringify(typeToExample(largeType(typeRef)));
+  } catch (error) {
+    if (!(error instanceof ExampleError)) throw error;
+    return undefined;
+  }
+}
+
+// A copy of typeRef whose minimum lengths and sizes are raised to their
+// maximums, which typeToExample then fills. inherited are the rules of the
+// property or wrapper holding typeRef, which apply to it too.
+function largeType(
+  typeRef: TypeReference,
+  inherited?: ValidationRules,
+): TypeReference {
+  const rules = { ...inherited, ...typeRef.validation };
+  const large: TypeReference = { ...typeRef };
+  switch (typeRef.kind) {
+    case "optional":
+    case "nullable":
+      if (typeof typeRef.baseType === "object") {
+        large.baseType = largeType(typeRef.baseType, rules);
+      }
+      break;
+    case "primitive":
+      large.validation = largeString(typeRef, rules);
+      break;
+    case "array":
+      large.validation = {
+        ...rules,
+        minItems: grow(rules.minItems, rules.maxItems, LARGE_COLLECTION_SIZE),
+      };
+      break;
+    case "record":
+      large.validation = {
+        ...rules,
+        minEntries: grow(
+          rules.minEntries,
+          rules.maxEntries,
+          LARGE_COLLECTION_SIZE,
+        ),
+      };
+      break;
+  }
+  if (typeRef.properties) {
+    large.properties = typeRef.properties.map(
+      (prop): Property => ({
+        ...prop,
+        type: largeType(prop.type, prop.validation),
+      }),
+    );
+  }
+  if (typeRef.elementType) {
+    large.elementType = largeType(typeRef.elementType);
+  }
+  if (typeRef.valueType) {
+    large.valueType = largeType(typeRef.valueType);
+  }
+  if (typeRef.unionTypes) {
+    large.unionTypes = typeRef.unionTypes.map((type) => largeType(type));
+  }
+  if (typeRef.tupleElements) {
+    large.tupleElements = typeRef.tupleElements.map((type) => largeType(type));
+  }
+  return large;
+}
+
+// The rules of a string grown to its maximum length; strings with a format or
+// a pattern keep the length of their example
+function largeString(
+  typeRef: TypeReference,
+  rules: ValidationRules,
+): ValidationRules {
+  if (
+    typeRef.baseType !== "string" ||
+    rules.email ||
+    rules.url ||
+    rules.uuid ||
+    rules.datetime ||
+    rules.date ||
+    rules.hostname ||
+    rules.ipv4 ||
+    rules.ipv6 ||
+    rules.duration ||
+    rules.e164 ||
+    rules.regex
+  ) {
+    return rules;
+  }
+  return {
+    ...rules,
+    minLength: grow(rules.minLength, rules.maxLength, LARGE_STRING_LENGTH),
+  };
+}
+
+function grow(
+  min: number | undefined,
+  max: number | undefined,
+  cap: number,
+): number {
+  return Math.max(min ?? 0, Math.min(max ?? cap, cap));
+}
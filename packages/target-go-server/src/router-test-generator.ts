@@ -0,0 +1,717 @@
+import {
+  type ContractDefinition,
+  type Endpoint,
+  ExampleError,
+  type Property,
+  type TypeReference,
+  typeToExample,
+} from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { toMethodName } from "./server-generator";
+import {
+  isDiscriminatedUnion,
+  isStringEnum,
+  streamItemType,
+} from "./type-mapper";
+
+export interface MethodExample {
+  endpoint: Endpoint;
+  inputType: string;
+  outputType: string;
+  // Example params and result, or why none could be derived
+  input?: string;
+  output?: string;
+  missing?: string;
+}
+
+/**
+ * Generates router_test.go: table-driven httptest tests of the router itself
+ * covering the request envelope, unknown methods, malformed JSON, validation
+ * failures and a round trip per method with the example params and results
+ * of examples.go.
+ */
+export class GoRouterTestGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate router_test.go.
+   * @param contract - The contract definition
+   * @param hasValidators - Whether validators.go was generated, so the tests register custom validators
+   */
+  generateRouterTests(
+    contract: ContractDefinition,
+    hasValidators = false,
+  ): string {
+    const w = this.w.reset();
+    // Subscriptions stream events rather than answering with a result
+    const methods = contract.endpoints
+      .filter((endpoint) => endpoint.type !== "subscription")
+      .map(methodExample);
+    // Queries with examples are also called with GET
+    const hasGetCalls = methods.some(
+      (method) =>
+        method.endpoint.type === "query" && method.input !== undefined,
+    );
+
+    const imports = [
+      "context",
+      "encoding/json",
+      "net/http",
+      "net/http/httptest",
+      "strings",
+      "testing",
+    ];
+    if (hasGetCalls) {
+      imports.splice(imports.indexOf("strings"), 0, "net/url");
+    }
+    w.package(this.packageName).import(...imports);
+
+    this.generateHelpers(w, methods, hasValidators);
+    this.generateEnvelopeTests(w, contract, methods, hasValidators);
+    this.generateValidationTests(w, methods);
+    this.generateParamsErrorTests(w);
+    this.generateRoundTripTests(w, methods);
+    this.generateTenantKeyTests(w);
+    this.generateInBandErrorTests(w);
+    this.generateBenchmarks(w, methods);
+
+    return w.toString();
+  }
+
+  private generateHelpers(
+    w: GoBuilder,
+    methods: MethodExample[],
+    hasValidators: boolean,
+  ): void {
+    if (hasValidators) {
+      w.comment(
+        "registerTestValidators registers custom validators that accept every value",
+      )
+        .comment("for the ones the tests' process has not registered.")
+        .n()
+        .func("registerTestValidators()", (b) => {
+          b.l("for _, name := range customValidators {")
+            .i()
+            .if("_, ok := validators[name]; !ok", (b) => {
+              b.l(
+                "RegisterValidator(name, func(interface{}) error { return nil })",
+              );
+            })
+            .u()
+            .l("}");
+        });
+    }
+
+    w.comment(
+      "newTestRouter returns a router whose handlers return their method's example",
+    )
+      .comment("result.")
+      .n()
+      .func("newTestRouter() *Router", (b) => {
+        if (hasValidators) {
+          b.l("registerTestValidators()");
+        }
+        if (methods.length === 0) {
+          b.return("NewRouter()");
+          return;
+        }
+        b.l("return NewRouter().").i();
+        if (methods.some((method) => method.endpoint.auth === "required")) {
+          b.l("Use(func(ctx context.Context, info RequestInfo) *MiddlewareResult {")
+            .i()
+            .return('NewMiddlewareResult(WithUserID(ctx, "test-user"))')
+            .u()
+            .l("}).");
+        }
+        if (methods.some((method) => method.endpoint.tenant === "required")) {
+          b.l("Use(func(ctx context.Context, info RequestInfo) *MiddlewareResult {")
+            .i()
+            .return(
+              'NewMiddlewareResult(WithTenant(ctx, Tenant{ID: "test-tenant"}))',
+            )
+            .u()
+            .l("}).");
+        }
+        if (methods.some((method) => method.endpoint.permissions)) {
+          b.l(
+            "SetAuthorizer(AuthorizerFunc(func(context.Context, RequestInfo, []string) (bool, error) {",
+          )
+            .i()
+            .return("true, nil")
+            .u()
+            .l("})).");
+        }
+        methods.forEach((method, index) => {
+          const { endpoint, inputType, outputType } = method;
+          const name = toMethodName(endpoint.fullName);
+          if (endpoint.stream) {
+            // Streamed handlers send the example's items one at a time
+            const itemType = streamItemType(endpoint).type;
+            b.l(
+              `${name}(func(ctx context.Context, info RequestInfo, input ${inputType}, stream *Stream[${itemType}]) (${outputType}, error) {`,
+            ).i();
+            if (method.output !== undefined) {
+              b.decl("output", `Example${outputType}()`);
+            } else {
+              b.var("output", outputType);
+            }
+            b.return(
+              `output, stream.SendAll(output.${toPascalCase(endpoint.stream)})`,
+            );
+            b.u().l(index === methods.length - 1 ? "})" : "}).");
+            return;
+          }
+          b.l(
+            `${name}(func(ctx context.Context, info RequestInfo, input ${inputType}) (${outputType}, error) {`,
+          ).i();
+          if (method.output !== undefined) {
+            b.return(`Example${outputType}(), nil`);
+          } else {
+            b.var("output", outputType).return("output, nil");
+          }
+          b.u().l(index === methods.length - 1 ? "})" : "}).");
+        });
+        b.u();
+      });
+
+    w.comment("post returns a POST request carrying body to the API")
+      .n()
+      .func("post(body string) *http.Request", (b) => {
+        b.return(
+          'httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(body))',
+        );
+      });
+
+    w.comment(
+      "serve sends req to r and returns the response status and decoded JSON body",
+    )
+      .n()
+      .func(
+        "serve(t *testing.T, r *Router, req *http.Request) (int, map[string]json.RawMessage)",
+        (b) => {
+          b.l("t.Helper()")
+            .decl("rec", "httptest.NewRecorder()")
+            .l("r.ServeHTTP(rec, req)")
+            .var("body", "map[string]json.RawMessage")
+            .if(
+              "err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil",
+              (b) => {
+                b.l(
+                  't.Fatalf("response is not JSON: %v: %s", err, rec.Body.String())',
+                );
+              },
+            )
+            .return("rec.Code, body");
+        },
+      );
+
+    w.comment("errorCode returns the code of an error response body")
+      .n()
+      .func("errorCode(body map[string]json.RawMessage) ErrorCode", (b) => {
+        b.var("e", 'struct {\n\t\tCode ErrorCode `json:"code"`\n\t}')
+          .l('json.Unmarshal(body["error"], &e)')
+          .return("e.Code");
+      });
+  }
+
+  private generateEnvelopeTests(
+    w: GoBuilder,
+    contract: ContractDefinition,
+    methods: MethodExample[],
+    hasValidators: boolean,
+  ): void {
+    const cases: Array<[string, string, string]> = [
+      ["malformed JSON", 'post(`{"method":`)', "CodeInvalidArgument"],
+      [
+        "unknown method",
+        'post(`{"method":"xrpc.unknown","params":{}}`)',
+        "CodeMethodNotFound",
+      ],
+      ["missing method", 'post(`{"params":{}}`)', "CodeMethodNotFound"],
+      [
+        "unsupported HTTP method",
+        'httptest.NewRequest(http.MethodPut, "/api", nil)',
+        "CodeMethodNotAllowed",
+      ],
+    ];
+    if (methods.length > 0) {
+      const method = methods[0].endpoint.fullName;
+      cases.push([
+        "params of the wrong type",
+        `post(\`{"method":"${method}","params":42}\`)`,
+        "CodeInvalidArgument",
+      ]);
+    }
+    const mutation = contract.endpoints.find(
+      (endpoint) => endpoint.type === "mutation",
+    );
+    if (mutation) {
+      cases.push([
+        "mutation over GET",
+        `httptest.NewRequest(http.MethodGet, "/api?method=${mutation.fullName}", nil)`,
+        "CodeMethodNotAllowed",
+      ]);
+    }
+
+    w.comment(
+      "TestRouterEnvelope checks that malformed requests are rejected with the right",
+    )
+      .comment("error code and HTTP status.")
+      .n()
+      .func("TestRouterEnvelope(t *testing.T)", (b) => {
+        b.decl("tests", "[]struct {")
+          .i()
+          .l("name string")
+          .l("req  *http.Request")
+          .l("code ErrorCode")
+          .u()
+          .l("}{")
+          .i();
+        for (const [name, req, code] of cases) {
+          b.l(`{${JSON.stringify(name)}, ${req}, ${code}},`);
+        }
+        b.u()
+          .l("}")
+          .decl("r", "newTestRouter()")
+          .l("for _, tt := range tests {")
+          .i()
+          .l("t.Run(tt.name, func(t *testing.T) {")
+          .i()
+          .l("status, body := serve(t, r, tt.req)")
+          .if("code := errorCode(body); code != tt.code", (b) => {
+            b.l('t.Errorf("code = %q, want %q", code, tt.code)');
+          })
+          .if("status != tt.code.HTTPStatus()", (b) => {
+            b.l(
+              't.Errorf("status = %d, want %d", status, tt.code.HTTPStatus())',
+            );
+          })
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+
+    if (methods.length === 0) {
+      return;
+    }
+    const method = methods[0].endpoint.fullName;
+    w.comment(
+      "TestRouterUnimplemented checks that methods without a handler are reported as",
+    )
+      .comment("unimplemented.")
+      .n()
+      .func("TestRouterUnimplemented(t *testing.T)", (b) => {
+        if (hasValidators) {
+          b.l("registerTestValidators()");
+        }
+        b.l(
+          `status, body := serve(t, NewRouter(), post(\`{"method":"${method}","params":{}}\`))`,
+        )
+          .if("code := errorCode(body); code != CodeUnimplemented", (b) => {
+            b.l('t.Errorf("code = %q, want %q", code, CodeUnimplemented)');
+          })
+          .if("status != CodeUnimplemented.HTTPStatus()", (b) => {
+            b.l(
+              't.Errorf("status = %d, want %d", status, CodeUnimplemented.HTTPStatus())',
+            );
+          });
+      });
+  }
+
+  private generateValidationTests(
+    w: GoBuilder,
+    methods: MethodExample[],
+  ): void {
+    const cases: Array<[string, string, string]> = [];
+    for (const method of methods) {
+      if (method.input === undefined) continue;
+      const field = requiredField(method.endpoint.input);
+      if (!field) continue;
+      const params = JSON.parse(method.input);
+      delete params[field];
+      cases.push([method.endpoint.fullName, JSON.stringify(params), field]);
+    }
+    if (cases.length === 0) {
+      return;
+    }
+
+    w.comment(
+      "TestRouterValidation checks that params missing a required field fail",
+    )
+      .comment("validation and name the field.")
+      .n()
+      .func("TestRouterValidation(t *testing.T)", (b) => {
+        b.decl("tests", "[]struct {")
+          .i()
+          .l("method string")
+          .l("params string")
+          .l("field  string")
+          .u()
+          .l("}{")
+          .i();
+        for (const [method, params, field] of cases) {
+          b.l(`{"${method}", ${goStringLiteral(params)}, "${field}"},`);
+        }
+        b.u()
+          .l("}")
+          .decl("r", "newTestRouter()")
+          .l("for _, tt := range tests {")
+          .i()
+          .l("t.Run(tt.method, func(t *testing.T) {")
+          .i()
+          .decl(
+            "req",
+            'post(`{"method":"` + tt.method + `","params":` + tt.params + `}`)',
+          )
+          .l("status, body := serve(t, r, req)")
+          .if("status != CodeInvalidArgument.HTTPStatus()", (b) => {
+            b.l(
+              't.Errorf("status = %d, want %d", status, CodeInvalidArgument.HTTPStatus())',
+            );
+          })
+          .if(
+            '!strings.Contains(string(body["error"]), `"field":"`+tt.field+`"`)',
+            (b) => {
+              b.l(
+                't.Errorf("error %s does not name field %s", body["error"], tt.field)',
+              );
+            },
+          )
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateParamsErrorTests(w: GoBuilder): void {
+    w.comment(
+      "TestParamsError checks that params of the wrong JSON type are reported on the",
+    )
+      .comment(
+        "path of the value, and without a path when params themselves are not an",
+      )
+      .comment("object.")
+      .n()
+      .func("TestParamsError(t *testing.T)", (b) => {
+        b.l("var input struct {")
+          .i()
+          .l("Tasks []struct {")
+          .i()
+          .l('Title string `json:"title"`')
+          .u()
+          .l('} `json:"tasks"`')
+          .u()
+          .l("}")
+          .l("tests := []struct {")
+          .i()
+          .l("name    string")
+          .l("params  string")
+          .l("field   string")
+          .l("message string")
+          .u()
+          .l("}{")
+          .i()
+          .l(
+            '{"nested field", `{"tasks":[{"title":"a"},{"title":1}]}`, "tasks[1].title", "must be a string, got number"},',
+          )
+          .l('{"params", `[]`, "", "must be an object, got array"},')
+          .u()
+          .l("}")
+          .l("for _, tt := range tests {")
+          .i()
+          .l("t.Run(tt.name, func(t *testing.T) {")
+          .i()
+          .decl(
+            "err",
+            "paramsError([]byte(tt.params), json.Unmarshal([]byte(tt.params), &input))",
+          )
+          .decl("errs, ok", "err.(ValidationErrors)")
+          .if("!ok || len(errs) != 1", (b) => {
+            b.l('t.Fatalf("err = %v, want a ValidationError", err)');
+          })
+          .if(
+            "errs[0].Field != tt.field || errs[0].Message != tt.message",
+            (b) => {
+              b.l(
+                't.Errorf("error = %q: %q, want %q: %q", errs[0].Field, errs[0].Message, tt.field, tt.message)',
+              );
+            },
+          )
+          .if('len(errs[0].Path) == 0 && tt.field != ""', (b) => {
+            b.l('t.Errorf("error on %s has no path", tt.field)');
+          })
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateRoundTripTests(
+    w: GoBuilder,
+    methods: MethodExample[],
+  ): void {
+    const testable = methods.filter((method) => method.input !== undefined);
+    const missing = methods.filter((method) => method.missing);
+    if (testable.length === 0) {
+      return;
+    }
+
+    w.comment(
+      "TestRouterRoundTrip calls every method with its example params and checks",
+    )
+      .comment(
+        "the result decodes into its output type; queries are also called with GET.",
+      );
+    for (const method of missing) {
+      w.comment(
+        `${method.endpoint.fullName} has no example params: ${method.missing}`,
+      );
+    }
+    w.n().func("TestRouterRoundTrip(t *testing.T)", (b) => {
+      b.decl("tests", "[]struct {")
+        .i()
+        .l("name   string")
+        .l("req    *http.Request")
+        .l("decode func(data []byte) error")
+        .u()
+        .l("}{")
+        .i();
+      for (const method of testable) {
+        const { endpoint, inputType, outputType } = method;
+        const example = `example${inputType}`;
+        const requests: Array<[string, string]> = [
+          [
+            endpoint.fullName,
+            `post(\`{"method":"${endpoint.fullName}","params":\` + ${example} + \`}\`)`,
+          ],
+        ];
+        if (endpoint.type === "query") {
+          requests.push([
+            `GET ${endpoint.fullName}`,
+            `httptest.NewRequest(http.MethodGet, "/api?method=${endpoint.fullName}&params="+url.QueryEscape(${example}), nil)`,
+          ]);
+        }
+        for (const [name, req] of requests) {
+          b.l("{")
+            .i()
+            .l(`name:   "${name}",`)
+            .l(`req:    ${req},`)
+            .l("decode: func(data []byte) error {")
+            .i()
+            // Async mutations answer with the operation running them
+            .var("output", endpoint.async ? "Operation" : outputType)
+            .return("json.Unmarshal(data, &output)")
+            .u()
+            .l("},")
+            .u()
+            .l("},");
+        }
+      }
+      b.u()
+        .l("}")
+        .decl("r", "newTestRouter()")
+        .l("for _, tt := range tests {")
+        .i()
+        .l("t.Run(tt.name, func(t *testing.T) {")
+        .i()
+        .l("status, body := serve(t, r, tt.req)")
+        .if("status != http.StatusOK", (b) => {
+          b.l('t.Fatalf("status = %d: %s", status, body["error"])');
+        })
+        .if('err := tt.decode(body["result"]); err != nil', (b) => {
+          b.l('t.Errorf("result does not decode: %v", err)');
+        })
+        .u()
+        .l("})")
+        .u()
+        .l("}");
+    });
+  }
+
+  private generateTenantKeyTests(w: GoBuilder): void {
+    w.comment(
+      "TestTenantKeys checks that calls of two tenants with the same params get",
+    )
+      .comment(
+        "separate cache entries and flights, even when the cache is shared by users.",
+      )
+      .n()
+      .func("TestTenantKeys(t *testing.T)", (b) => {
+        b.decl("params", 'map[string]string{"id": "1"}')
+          .decl(
+            "keys",
+            "map[string]func(ctx context.Context) (string, error){",
+          )
+          .i()
+          .l('"cacheKey": func(ctx context.Context) (string, error) {')
+          .i()
+          .return('cacheKey(ctx, CacheOptions{Shared: true}, "test.method", params)')
+          .u()
+          .l("},")
+          .l('"flightKey": func(ctx context.Context) (string, error) {')
+          .i()
+          .return('flightKey(ctx, "test.method", params)')
+          .u()
+          .l("},")
+          .u()
+          .l("}")
+          .l("for name, key := range keys {")
+          .i()
+          .l("t.Run(name, func(t *testing.T) {")
+          .i()
+          .decl(
+            "acme, err",
+            'key(WithTenant(context.Background(), Tenant{ID: "acme"}))',
+          )
+          .ifErr((b) => {
+            b.l("t.Fatal(err)");
+          })
+          .decl(
+            "globex, err",
+            'key(WithTenant(context.Background(), Tenant{ID: "globex"}))',
+          )
+          .ifErr((b) => {
+            b.l("t.Fatal(err)");
+          })
+          .if("acme == globex", (b) => {
+            b.l('t.Errorf("tenants share the key %q", acme)');
+          })
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateInBandErrorTests(w: GoBuilder): void {
+    w.comment(
+      "TestInBandErrors checks that the clients return the error of an envelope the",
+    )
+      .comment("router sends with 200, as SetErrorStatus lets it.")
+      .n()
+      .func("TestInBandErrors(t *testing.T)", (b) => {
+        b.decl(
+          "r",
+          "newTestRouter().SetErrorStatus(func(err *Error) int { return http.StatusOK })",
+        )
+          .decl("server", "httptest.NewServer(r)")
+          .l("defer server.Close()")
+          .var("output", "json.RawMessage")
+          .decl("errs", "map[string]error{")
+          .i()
+          .l(
+            '"Client":     NewClient(server.URL).call(context.Background(), "no.such", struct{}{}, &output),',
+          )
+          .l(
+            '"TestClient": NewTestClient(r).call(context.Background(), "no.such", struct{}{}, &output),',
+          )
+          .u()
+          .l("}")
+          .l("for name, err := range errs {")
+          .i()
+          .if("code := AsError(err).Code; code != CodeMethodNotFound", (b) => {
+            b.l(
+              't.Errorf("%s: code = %q, want %q (err: %v)", name, code, CodeMethodNotFound, err)',
+            );
+          })
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateBenchmarks(w: GoBuilder, methods: MethodExample[]): void {
+    w.comment(
+      "BenchmarkServeHTTP serves a call of every method with its example params, and",
+    )
+      .comment(
+        "one failing with an unknown method, reporting the allocations per call.",
+      )
+      .n()
+      .func("BenchmarkServeHTTP(b *testing.B)", (b) => {
+        b.decl("benchmarks", "[]struct {")
+          .i()
+          .l("name string")
+          .l("body string")
+          .u()
+          .l("}{")
+          .i();
+        for (const method of methods) {
+          if (method.input === undefined) {
+            continue;
+          }
+          const name = method.endpoint.fullName;
+          b.l(
+            `{"${name}", \`{"method":"${name}","params":\` + example${method.inputType} + \`}\`},`,
+          );
+        }
+        b.l('{"unknown method", `{"method":"xrpc.unknown","params":{}}`},')
+          .u()
+          .l("}")
+          .decl("r", "newTestRouter()")
+          .l("for _, bm := range benchmarks {")
+          .i()
+          .l("b.Run(bm.name, func(b *testing.B) {")
+          .i()
+          .l("b.ReportAllocs()")
+          .l("for i := 0; i < b.N; i++ {")
+          .i()
+          .l("r.ServeHTTP(httptest.NewRecorder(), post(bm.body))")
+          .u()
+          .l("}")
+          .u()
+          .l("})")
+          .u()
+          .l("}");
+      });
+  }
+}
+
+export function methodExample(endpoint: Endpoint): MethodExample {
+  const method: MethodExample = {
+    endpoint,
+    inputType: toPascalCase(endpoint.input.name!),
+    outputType: toPascalCase(endpoint.output.name!),
+  };
+  try {
+    const input = JSON.stringify(typeToExample(endpoint.input));
+    method.output = JSON.stringify(typeToExample(endpoint.output));
+    method.input = input;
+  } catch (error) {
+    if (!(error instanceof ExampleError)) throw error;
+    method.missing = error.message;
+  }
+  return method;
+}
+
+// The first required input field whose absence fails validation with "is
+// required"; numbers and booleans have no such check
+function requiredField(input: TypeReference): string | undefined {
+  const isChecked = (prop: Property) => {
+    const type = prop.type;
+    const isOptional = type.kind === "optional" || type.kind === "nullable";
+    if (!prop.required || isOptional) {
+      return false;
+    }
+    return (
+      (type.kind === "primitive" && type.baseType === "string") ||
+      (type.kind === "primitive" && type.baseType === "bytes") ||
+      isStringEnum(type) ||
+      type.kind === "date" ||
+      type.kind === "file" ||
+      type.kind === "array" ||
+      type.kind === "record" ||
+      isDiscriminatedUnion(type)
+    );
+  };
+  return input.properties?.find(isChecked)?.name;
+}
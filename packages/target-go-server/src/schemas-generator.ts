@@ -0,0 +1,90 @@
+import { type ContractDefinition, typeToJsonSchema } from "@xrpckit/sdk";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { toPascalCase } from "./naming";
+
+const JSON_SCHEMA_DIALECT = "https://json-schema.org/draft/2020-12/schema";
+
+/**
+ * Generates schemas.go: a standalone JSON Schema document for every input and
+ * output type, with SchemaFor and TypeSchema to look them up at runtime, so
+ * frontends and contract tests can reuse the constraints the server checks.
+ */
+export class GoSchemasGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateSchemas(contract: ContractDefinition): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import("encoding/json");
+
+    // Types shared by several methods are documented once
+    const schemas = new Map<string, string>();
+    for (const endpoint of contract.endpoints) {
+      for (const typeRef of [endpoint.input, endpoint.output]) {
+        const name = toPascalCase(typeRef.name!);
+        if (!schemas.has(name)) {
+          const document = {
+            $schema: JSON_SCHEMA_DIALECT,
+            title: name,
+            ...typeToJsonSchema(typeRef),
+          };
+          schemas.set(name, JSON.stringify(document));
+        }
+      }
+    }
+
+    w.comment(
+      "typeSchemas holds the JSON Schema document of every input and output type,",
+    )
+      .comment("keyed by its Go type name.")
+      .l("var typeSchemas = map[string]json.RawMessage{")
+      .i();
+    for (const [name, schema] of schemas) {
+      w.l(`"${name}": json.RawMessage(${goStringLiteral(schema)}),`);
+    }
+    w.u().l("}").n();
+
+    w.comment("methodTypes names the input and output type of every method.")
+      .l("var methodTypes = map[string][2]string{")
+      .i();
+    for (const endpoint of contract.endpoints) {
+      const input = toPascalCase(endpoint.input.name!);
+      const output = toPascalCase(endpoint.output.name!);
+      w.l(`"${endpoint.fullName}": {"${input}", "${output}"},`);
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "SchemaFor returns the JSON Schema documents of method's input and output,",
+    )
+      .comment("and false if the contract has no such method.")
+      .n()
+      .func(
+        "SchemaFor(method string) (input, output json.RawMessage, ok bool)",
+        (b) => {
+          b.l("types, ok := methodTypes[method]")
+            .if("!ok", (b) => {
+              b.return("nil, nil, false");
+            })
+            .return("typeSchemas[types[0]], typeSchemas[types[1]], true");
+        },
+      );
+
+    w.comment(
+      "TypeSchema returns the JSON Schema document of the input or output type name,",
+    )
+      .comment('such as "TaskCreateInput", and false if there is no such type.')
+      .n()
+      .func("TypeSchema(name string) (json.RawMessage, bool)", (b) => {
+        b.l("schema, ok := typeSchemas[name]").return("schema, ok");
+      });
+
+    return w.toString();
+  }
+}
@@ -1,12 +1,22 @@
-import {
-  type ContractDefinition,
-  type Endpoint,
-  toPascalCase,
-} from "@xrpckit/sdk";
+import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
 import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { usesFiles } from "./upload-generator";
+
+/**
+ * Name of the built-in method that lists the router's methods.
+ */
+export const INTROSPECT_METHOD = "xrpc.introspect";
+
+/**
+ * Names of the built-in methods polling and cancelling the operations of
+ * async mutations.
+ */
+export const OPERATION_GET_METHOD = "operation.get";
+export const OPERATION_CANCEL_METHOD = "operation.cancel";
 
 // Helper to convert "greeting.greet" to "GreetingGreet"
-function toMethodName(fullName: string): string {
+export function toMethodName(fullName: string): string {
   return fullName
     .split(".")
     .map((part) => toPascalCase(part))
@@ -14,7 +24,7 @@ function toMethodName(fullName: string): string {
 }
 
 // Helper to convert "greeting.greet" to "greetingGreet"
-function toFieldName(fullName: string): string {
+export function toFieldName(fullName: string): string {
   return fullName
     .split(".")
     .map((part, i) => (i === 0 ? part : toPascalCase(part)))
@@ -30,14 +40,110 @@ export class GoServerGenerator {
     this.packageName = packageName;
   }
 
-  generateServer(contract: ContractDefinition): string {
+  /**
+   * Generate router.go.
+   * @param contract - The contract definition
+   * @param checkValidators - Whether validators.go was generated, so NewRouter checks its validators are registered
+   * @param hasWarnings - Whether warnings.go was generated, so results are returned with the warnings of their call
+   */
+  generateServer(
+    contract: ContractDefinition,
+    checkValidators = false,
+    hasWarnings = false,
+  ): string {
     const w = this.w.reset();
+    const hasSubscriptions = contract.endpoints.some(
+      (endpoint) => endpoint.type === "subscription",
+    );
 
-    w.package(this.packageName).import("encoding/json", "net/http", "fmt");
+    const hasQueries = contract.endpoints.some(
+      (endpoint) => endpoint.type === "query",
+    );
+
+    // fmt is only needed to write Server-Sent Events
+    const imports = [
+      "context",
+      "encoding/base64",
+      "encoding/json",
+      "log",
+      "net/http",
+      "path",
+      "runtime/debug",
+      "strconv",
+      "strings",
+      "sync",
+      "sync/atomic",
+      "time",
+    ];
+    if (hasSubscriptions) {
+      imports.splice(imports.indexOf("log"), 0, "fmt");
+    }
+    // crypto/sha256 is only needed for the ETags of query results
+    if (hasQueries) {
+      imports.unshift("crypto/sha256");
+    }
+    w.package(this.packageName).import(...imports);
+
+    // Middleware and interceptors are stored with the method pattern they
+    // are scoped to
+    w.comment(
+      "middlewareEntry is a registered middleware and the method pattern it applies",
+    )
+      .comment("to; an empty pattern applies to every method.")
+      .struct("middlewareEntry", (b) => {
+        b.l("pattern    string").l("middleware MiddlewareFunc");
+      });
+
+    w.comment(
+      "interceptorEntry is a registered interceptor and the method pattern it applies",
+    )
+      .comment("to; an empty pattern applies to every method.")
+      .struct("interceptorEntry", (b) => {
+        b.l("pattern     string").l("interceptor InterceptorFunc");
+      });
+
+    w.comment(
+      "timeoutEntry is a timeout set with SetTimeoutFor and the method pattern it",
+    )
+      .comment("applies to.")
+      .struct("timeoutEntry", (b) => {
+        b.l("pattern string").l("timeout time.Duration");
+      });
 
     // Generate Router struct with typed handler fields
     w.struct("Router", (b) => {
-      b.l("middleware []MiddlewareFunc");
+      b.l("middleware []middlewareEntry")
+        .l("interceptors []interceptorEntry")
+        .l("timeout time.Duration")
+        .l("timeouts []timeoutEntry")
+        .l("errorLog *log.Logger")
+        .l("errorStatus func(err *Error) int")
+        .l("logger Logger")
+        .l("authorizer Authorizer")
+        .l("csrf *CSRFOptions")
+        .l("compression bool")
+        .l("compressionMinSize int")
+        .l("codec Codec")
+        .l("lenientDecoding bool")
+        .l("concurrency *concurrencyPool")
+        .l("concurrencyPools []*concurrencyPool")
+        .l("readinessChecks []readinessCheck")
+        .l("introspectionDisabled bool")
+        .l("deprecationHeaders bool")
+        .l("mounts []mountEntry")
+        .l("methodGate MethodGate")
+        // Patterns switched off with Disable, which is called while serving
+        .l("disabled sync.Map")
+        .l("maintenance atomic.Bool")
+        .l("readOnly atomic.Bool")
+        .l("eventPublisher EventPublisher")
+        .l("operationStore OperationStore")
+        // Cancel funcs of the operations this router runs, by ID
+        .l("operations sync.Map")
+        // Dependency providers registered with Provide, by dependencyKey
+        .l("providers map[interface{}]func(ctx context.Context) interface{}")
+        // Guards the handler fields, which Replace swaps while serving
+        .l("handlersMu sync.RWMutex");
 
       // Generate typed handler field for each endpoint
       for (const endpoint of contract.endpoints) {
@@ -48,10 +154,21 @@ export class GoServerGenerator {
     });
 
     // Generate NewRouter
+    if (checkValidators) {
+      w.comment(
+        "NewRouter panics if a custom validator the contract uses is not registered",
+      )
+        .comment("with RegisterValidator.")
+        .n();
+    }
     w.func("NewRouter() *Router", (b) => {
+      if (checkValidators) {
+        b.l("mustRegisteredValidators()");
+      }
       b.l("return &Router{")
         .i()
-        .l("middleware: make([]MiddlewareFunc, 0),")
+        .l("middleware: make([]middlewareEntry, 0),")
+        .l("operationStore: NewMemoryOperationStore(time.Hour),")
         .u()
         .l("}");
     });
@@ -62,13 +179,20 @@ export class GoServerGenerator {
       const fieldName = toFieldName(endpoint.fullName);
       const handlerType = `${methodName}Handler`;
 
-      w.method(
+      w.comment(`${methodName} sets the handler of ${endpoint.fullName}.`);
+      if (endpoint.description) {
+        w.l("//").doc(endpoint.description);
+      }
+      w.n().method(
         "r *Router",
         methodName,
         `handler ${handlerType}`,
         "*Router",
         (b) => {
-          b.l(`r.${fieldName} = handler`).return("r");
+          b.l("r.handlersMu.Lock()")
+            .l(`r.${fieldName} = handler`)
+            .l("r.handlersMu.Unlock()")
+            .return("r");
         },
       );
     }
@@ -80,30 +204,289 @@ export class GoServerGenerator {
       "middleware MiddlewareFunc",
       "*Router",
       (b) => {
-        b.l("r.middleware = append(r.middleware, middleware)").return("r");
+        b.l(
+          "r.middleware = append(r.middleware, middlewareEntry{middleware: middleware})",
+        ).return("r");
+      },
+    );
+
+    // Generate scoped middleware registration method
+    w.comment(
+      "UseFor registers middleware that only runs for methods matching pattern, such",
+    )
+      .comment(
+        'as "task.*" or "*.create". Patterns use path.Match syntax; an exact method',
+      )
+      .comment(
+        "name matches only that method. Middleware runs in registration order,",
+      )
+      .comment("interleaved with middleware added through Use.")
+      .n()
+      .method(
+        "r *Router",
+        "UseFor",
+        "pattern string, middleware MiddlewareFunc",
+        "*Router",
+        (b) => {
+          b.l("mustValidPattern(pattern)")
+            .l(
+              "r.middleware = append(r.middleware, middlewareEntry{pattern: pattern, middleware: middleware})",
+            )
+            .return("r");
+        },
+      );
+
+    // Generate interceptor registration methods
+    w.comment(
+      "Intercept registers an interceptor that wraps every query and mutation call.",
+    )
+      .comment(
+        "Interceptors run after middleware and validation; the first registered is",
+      )
+      .comment(
+        "the outermost. Subscriptions stream events and are not intercepted.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "Intercept",
+        "interceptor InterceptorFunc",
+        "*Router",
+        (b) => {
+          b.l(
+            "r.interceptors = append(r.interceptors, interceptorEntry{interceptor: interceptor})",
+          ).return("r");
+        },
+      );
+
+    w.comment(
+      "InterceptFor registers an interceptor for methods matching pattern, using the",
+    )
+      .comment("same pattern syntax as UseFor.")
+      .n()
+      .method(
+        "r *Router",
+        "InterceptFor",
+        "pattern string, interceptor InterceptorFunc",
+        "*Router",
+        (b) => {
+          b.l("mustValidPattern(pattern)")
+            .l(
+              "r.interceptors = append(r.interceptors, interceptorEntry{pattern: pattern, interceptor: interceptor})",
+            )
+            .return("r");
+        },
+      );
+
+    // Generate timeout configuration
+    w.comment(
+      "SetTimeout sets how long queries and mutations may run. A call still running",
+    )
+      .comment(
+        "when its timeout expires has its context cancelled and fails with",
+      )
+      .comment(
+        "DEADLINE_EXCEEDED. Zero, the default, means no timeout. Subscriptions stream",
+      )
+      .comment(
+        "until the client disconnects and are not timed out. Calls are also bounded by",
+      )
+      .comment(
+        "the deadline their client sends in the X-Xrpc-Timeout header, as the Client",
+      )
+      .comment(
+        "of this package does for contexts with a deadline, whichever expires first.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "SetTimeout",
+        "timeout time.Duration",
+        "*Router",
+        (b) => {
+          b.l("r.timeout = timeout").return("r");
+        },
+      );
+
+    w.comment(
+      "SetTimeoutFor sets the timeout of methods matching pattern, using the same",
+    )
+      .comment(
+        "pattern syntax as UseFor. It overrides SetTimeout; when several patterns",
+      )
+      .comment("match a method, the last one set wins.")
+      .n()
+      .method(
+        "r *Router",
+        "SetTimeoutFor",
+        "pattern string, timeout time.Duration",
+        "*Router",
+        (b) => {
+          b.l("mustValidPattern(pattern)")
+            .l(
+              "r.timeouts = append(r.timeouts, timeoutEntry{pattern: pattern, timeout: timeout})",
+            )
+            .return("r");
+        },
+      );
+
+    // Generate authorization configuration
+    w.comment(
+      "SetAuthorizer sets the Authorizer checking the permissions methods declare.",
+    )
+      .comment(
+        "Without one, every call of a method that declares permissions is denied.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "SetAuthorizer",
+        "authorizer Authorizer",
+        "*Router",
+        (b) => {
+          b.l("r.authorizer = authorizer").return("r");
+        },
+      );
+
+    w.comment(
+      "authorize checks the caller holds permissions, or fails with PERMISSION_DENIED.",
+    )
+      .n()
+      .method(
+        "r *Router",
+        "authorize",
+        "ctx context.Context, info RequestInfo, permissions []string",
+        "error",
+        (b) => {
+          b.if("r.authorizer == nil", (b) => {
+            b.return('NewError(CodePermissionDenied, "Permission denied")');
+          })
+            .l(
+              "allowed, err := r.authorizer.Authorize(ctx, info, permissions)",
+            )
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .if("!allowed", (b) => {
+              b.return('NewError(CodePermissionDenied, "Permission denied")');
+            })
+            .return("nil");
+        },
+      );
+
+    // Generate error log configuration
+    w.comment(
+      "SetErrorLog sets the logger used to report recovered handler panics and events",
+    )
+      .comment(
+        "that failed to publish. By default they are written to the standard logger.",
+      )
+      .n()
+      .method("r *Router", "SetErrorLog", "logger *log.Logger", "*Router", (b) => {
+        b.l("r.errorLog = logger").return("r");
+      });
+
+    w.method(
+      "r *Router",
+      "logf",
+      "format string, args ...interface{}",
+      "",
+      (b) => {
+        b.if("r.errorLog != nil", (b) => {
+          b.l("r.errorLog.Printf(format, args...)").return();
+        }).l("log.Printf(format, args...)");
       },
     );
 
+    // Generate error status configuration
+    w.comment(
+      "SetErrorStatus overrides the HTTP status sent with error responses. By default",
+    )
+      .comment(
+        "it is err.Code.HTTPStatus(); return http.StatusOK to keep errors in-band for",
+      )
+      .comment("clients that only read the error envelope.")
+      .n()
+      .method(
+        "r *Router",
+        "SetErrorStatus",
+        "status func(err *Error) int",
+        "*Router",
+        (b) => {
+          b.l("r.errorStatus = status").return("r");
+        },
+      );
+
+    w.comment(
+      "writeError writes err with the status of its code, or the one SetErrorStatus",
+    )
+      .comment("chose, logging the error it keeps from the client.")
+      .n()
+      .method(
+        "r *Router",
+        "writeError",
+        "w http.ResponseWriter, err *Error",
+        "",
+        (b) => {
+          b.l("r.logCause(err)")
+            .decl("status", "err.Code.HTTPStatus()")
+            .if("r.errorStatus != nil", (b) => {
+              b.l("status = r.errorStatus(err)");
+            })
+            .l("writeError(w, status, err)");
+        },
+      );
+
+    w.comment(
+      "logCause logs the error an INTERNAL error made by AsError stands for, which",
+    )
+      .comment("the client is not told.")
+      .n()
+      .method("r *Router", "logCause", "err *Error", "", (b) => {
+        b.if("err.cause != nil", (b) => {
+          b.l('r.logf("xrpc: internal error: %v", err.cause)');
+        });
+      });
+
+    this.generatePatternHelpers(w);
+    this.generateInvoke(w);
+
     // Generate ServeHTTP
-    this.generateServeHTTP(contract.endpoints, w);
+    this.generateServeHTTP(
+      contract.endpoints,
+      usesFiles(contract),
+      hasWarnings,
+      w,
+    );
+    this.generateDispatch(w);
+
+    // Generate GET request support for queries and subscriptions
+    w.n();
+    this.generateDecodeQueryParams(w);
+    if (hasQueries) {
+      this.generateNotModified(w);
+    }
+
+    // Generate Server-Sent Events support for subscriptions
+    if (hasSubscriptions) {
+      this.generateWriteEvent(w);
+    }
 
     return w.toString();
   }
 
-  private generateServeHTTP(endpoints: Endpoint[], w: GoBuilder): void {
+  private generateServeHTTP(
+    endpoints: Endpoint[],
+    hasUploads: boolean,
+    hasWarnings: boolean,
+    w: GoBuilder,
+  ): void {
     w.method(
       "r *Router",
       "ServeHTTP",
       "w http.ResponseWriter, req *http.Request",
       "",
       (b) => {
-        // Only accept POST
-        b.if("req.Method != http.MethodPost", (b) => {
-          b.l(
-            'http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)',
-          ).return();
-        }).n();
-
         // Parse JSON-RPC request
         b.var("request", "struct {")
           .i()
@@ -111,123 +494,757 @@ export class GoServerGenerator {
           .l('Params json.RawMessage `json:"params"`')
           .u()
           .l("}")
+          .decl("outcome", "OutcomeRejected")
           .n();
 
-        b.if(
-          "err := json.NewDecoder(req.Body).Decode(&request); err != nil",
-          (b) => {
-            b.l(
-              'http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)',
-            ).return();
-          },
-        ).n();
-
-        // Initialize context for middleware and handlers
-        b.decl("ctx", "&Context{")
-          .i()
-          .l("Request:        req,")
-          .l("ResponseWriter: w,")
-          .l("Data:           make(map[string]interface{}),")
-          .u()
-          .l("}")
-          .n();
+        this.generateRequestLogging(b);
 
-        // Execute middleware chain
-        b.comment("Execute middleware chain")
-          .l("for _, middleware := range r.middleware {")
-          .i()
-          .decl("result", "middleware(ctx)")
-          .if("result.Error != nil", (b) => {
-            b.l(
-              'http.Error(w, fmt.Sprintf("Middleware error: %v", result.Error), http.StatusInternalServerError)',
-            ).return();
-          })
-          .if("result.Response != nil", (b) => {
-            b.comment("Middleware short-circuited with response").return();
-          })
-          .l("ctx = result.Context")
-          .u()
-          .l("}")
-          .n();
+        b.if("err := r.checkCSRF(req); err != nil", (b) => {
+          b.l("r.writeError(w, err)").return();
+        }).n();
 
-        // Route to handler based on method name
-        const cases = endpoints.map((endpoint) => ({
-          value: `"${endpoint.fullName}"`,
-          fn: (b: GoBuilder) => {
-            const fieldName = toFieldName(endpoint.fullName);
+        this.generateRequestDecoding(
+          b,
+          endpoints.some((endpoint) => endpoint.http),
+          hasUploads,
+        );
 
-            // Check if handler is registered
-            b.if(`r.${fieldName} == nil`, (b) => {
-              b.l(
-                'http.Error(w, "Handler not registered", http.StatusNotFound)',
-              ).return();
-            }).n();
+        b.l("outcome = r.serveCall(w, req, request.Method, request.Params)");
+      },
+    );
 
-            // Parse input
-            const inputTypeName = toPascalCase(endpoint.input.name!);
-            b.var("input", inputTypeName);
+    w.comment(
+      "serveCall runs the middleware for a call whose envelope ServeHTTP decoded and",
+    )
+      .comment(
+        "answers it, or hands it to the router mounted under its prefix. It returns",
+      )
+      .comment("how far the call got, for logging.")
+      .n()
+      .method(
+        "r *Router",
+        "serveCall",
+        "w http.ResponseWriter, req *http.Request, method string, params json.RawMessage",
+        "(outcome Outcome)",
+        (b) => {
+          b.l("outcome = OutcomeRejected").n();
+
+          // Recover handler panics so one bad request is logged and answered
+          // with an error instead of an empty response
+          b.l("defer func() {")
+            .i()
+            .l("rec := recover()")
+            .if("rec == nil", (b) => {
+              b.return();
+            })
+            .if("rec == http.ErrAbortHandler", (b) => {
+              b.l("panic(rec)");
+            })
+            .l(
+              'r.logf("xrpc: panic serving %s: %v\\n%s", method, rec, debug.Stack())',
+            )
+            .l('r.writeError(w, NewError(CodeInternal, "Internal server error"))')
+            .u()
+            .l("}()")
+            .n();
+
+          // Derive the handler context from the request context so
+          // cancellation and deadlines propagate to handlers
+          b.decl("info", "RequestInfo{")
+            .i()
+            .l("Method:         method,")
+            .l("Request:        req,")
+            .l("ResponseWriter: w,")
+            .u()
+            .l("}")
+            .if("r.timeoutFor(method) > 0", (b) => {
+              b.comment(
+                "A handler still running after its timeout must not write to the",
+              )
+                .comment("response the router answered with DEADLINE_EXCEEDED")
+                .l("info.ResponseWriter = &timeoutWriter{ResponseWriter: w}");
+            })
+            .decl(
+              "ctx",
+              "r.withDependencies(WithRequestInfo(req.Context(), info))",
+            );
+          if (hasWarnings) {
+            b.l("ctx = withWarnings(ctx)");
+          }
+          b.comment(
+            "The client's deadline bounds the middleware too, and the calls the handler",
+          )
+            .comment("makes to other services with ctx")
+            .if("timeout, ok := clientTimeout(req); ok", (b) => {
+              b.var("cancel", "context.CancelFunc")
+                .l("ctx, cancel = context.WithTimeout(ctx, timeout)")
+                .l("defer cancel()");
+            })
+            .n();
+
+          // Sent before middleware so rejected calls are warned too
+          b.if(
+            'hash := req.Header.Get("X-Xrpc-Schema-Hash"); hash != "" && hash != SchemaHash',
+            (b) => {
+              b.l('w.Header().Set("X-Xrpc-Schema-Mismatch", SchemaHash)');
+            },
+          );
+          b.if("r.deprecationHeaders", (b) => {
+            b.if(
+              'm, ok := methodTable[method]; ok && m.deprecation != ""',
+              (b) => {
+                b.l('w.Header().Set("Deprecation", m.deprecation)').if(
+                  'm.sunset != ""',
+                  (b) => {
+                    b.l('w.Header().Set("Sunset", m.sunset)');
+                  },
+                );
+              },
+            );
+          }).n();
+
+          // Calls rejected by middleware after the tenant was resolved, such
+          // as by TenantRateLimit, are logged with their tenant too
+          b.comment(
+            "The Logger gets the tenant middleware resolved, even for calls it rejected",
+          )
+            .l("defer func() {")
+            .i()
+            .if("tenant, ok := TenantFrom(ctx); ok", (b) => {
+              b.if("rec, ok := w.(tenantRecorder); ok", (b) => {
+                b.l("rec.RecordTenant(tenant.ID)");
+              });
+            })
+            .u()
+            .l("}()")
+            .n();
+
+          // Execute middleware chain
+          b.comment("Execute middleware chain")
+            .l("for _, entry := range r.middleware {")
+            .i()
+            .if("!matchMethod(entry.pattern, method)", (b) => {
+              b.l("continue");
+            })
+            .decl("result", "entry.middleware(ctx, info)")
+            .if("result.Error != nil", (b) => {
+              b.l("r.writeError(w, AsError(result.Error))").return();
+            })
+            .if("result.Response != nil", (b) => {
+              b.comment("Middleware short-circuited with response").return();
+            })
+            .if("result.Context != nil", (b) => {
+              b.l("ctx = result.Context");
+            })
+            .u()
+            .l("}")
+            .n();
+
+          // Mounted routers run their own middleware and answer the call
+          // themselves, under the method name they know it by
+          b.if("sub, name, ok := r.mountFor(method); ok", (b) => {
+            b.return("Outcome(sub.ServeCall(w, req.WithContext(ctx), name, params))");
+          }).n();
+
+          // Subscriptions and streamed results are written to the response as
+          // they are produced, so they are served here; every other method is
+          // answered by dispatch
+          b.if(
+            "m, ok := methodTable[method]; ok && m.serve != nil",
+            (b) => {
+              b.decl("input, stage, err", "r.prepare(ctx, info, m, params)")
+                .ifErr((b) => {
+                  b.l("outcome = stage")
+                    .l("r.writeError(w, AsError(err))")
+                    .return();
+                })
+                .l("outcome = m.serve(r, ctx, info, input, w, req)")
+                .return();
+            },
+          ).n();
+
+          b.decl(
+            "result, stage, err",
+            "r.dispatch(ctx, info, method, params)",
+          )
+            .l("outcome = stage")
+            .ifErr((b) => {
+              b.l("r.writeError(w, AsError(err))").return();
+            })
+            .n();
+
+          // RESTHandler results are written without the envelope
+          b.comment(
+            "Encode before writing so results that fail to encode (such as invalid",
+          )
+            .comment("enum values) are still reported as errors")
+            .decl(
+              "buf, err",
+              endpoints.some((endpoint) => endpoint.http)
+                ? "r.marshalResult(req, result)"
+                : "r.marshal(result, true)",
+            )
+            .ifErr((b) => {
+              b.l("outcome = OutcomeHandlerError")
+                .l("r.writeError(w, AsError(err))")
+                .return();
+            })
+            .l("defer buf.release()")
+            .n();
+
+          if (hasWarnings) {
+            const appendWarnings = (b: GoBuilder) => {
+              b.if("warnings := WarningsFrom(ctx); len(warnings) > 0", (b) => {
+                b.l("buf.appendWarnings(warnings)");
+              });
+            };
+            if (endpoints.some((endpoint) => endpoint.http)) {
+              // Bare RESTHandler results have no envelope to carry warnings
+              b.if("_, ok := restCallFrom(req); !ok", appendWarnings);
+            } else {
+              appendWarnings(b);
+            }
+            b.n();
+          }
+
+          // Only queries can be called with GET, so only their results get
+          // ETags
+          if (endpoints.some((endpoint) => endpoint.type === "query")) {
             b.if(
-              "err := json.Unmarshal(request.Params, &input); err != nil",
+              "req.Method == http.MethodGet && notModified(w, req, buf.Bytes())",
               (b) => {
-                b.l(
-                  'http.Error(w, fmt.Sprintf("Invalid params: %v", err), http.StatusBadRequest)',
-                ).return();
+                b.return();
               },
-            ).n();
+            );
+          }
+          b.l("r.writeResult(w, req, buf.Bytes())").return();
+        },
+      );
+  }
 
-            // Validate input
-            const validationFuncName = `Validate${inputTypeName}`;
-            b.if(`err := ${validationFuncName}(input); err != nil`, (b) => {
-              b.l('w.Header().Set("Content-Type", "application/json")').l(
-                "w.WriteHeader(http.StatusBadRequest)",
+  /**
+   * Generates dispatch, the transport-agnostic core of ServeHTTP and
+   * Dispatch: it looks the method up in methodTable, checks, decodes and
+   * validates its params and calls its handler through the interceptors.
+   */
+  private generateDispatch(w: GoBuilder): void {
+    w.comment(
+      "dispatch checks, decodes and validates the params of a query or mutation and",
+    )
+      .comment(
+        "calls its handler through the interceptors, then publishes the event of a",
+      )
+      .comment(
+        "mutation that succeeded. Async mutations answer with the Operation running",
+      )
+      .comment(
+        "them in the background instead. It returns the handler's result and how far",
+      )
+      .comment(
+        "the call got, for logging. Methods under a mount prefix are dispatched by the",
+      )
+      .comment("mounted router.")
+      .n()
+      .method(
+        "r *Router",
+        "dispatch",
+        "ctx context.Context, info RequestInfo, method string, params json.RawMessage",
+        "(interface{}, Outcome, error)",
+        (b) => {
+          b.decl("m, ok", "methodTable[method]")
+            .if("!ok", (b) => {
+              // Built-in method listing the registered methods
+              b.if(
+                `method == "${INTROSPECT_METHOD}" && !r.introspectionDisabled`,
+                (b) => {
+                  b.return(
+                    'map[string]interface{}{"methods": r.Introspect(), "schemaHash": SchemaHash}, OutcomeSuccess, nil',
+                  );
+                },
+              );
+              // Built-in methods polling and cancelling operations
+              b.if(
+                `method == "${OPERATION_GET_METHOD}" || method == "${OPERATION_CANCEL_METHOD}"`,
+                (b) => {
+                  b.return("r.serveOperation(ctx, method, params)");
+                },
+              );
+              b.if("sub, name, ok := r.mountFor(method); ok", (b) => {
+                b.decl("result, stage, err", "sub.DispatchCall(ctx, name, params)")
+                  .return("result, Outcome(stage), err");
+              }).return(
+                "nil, OutcomeRejected, r.methodNotFound(method)",
+              );
+            })
+            .if("m.invoke == nil", (b) => {
+              b.return(
+                'nil, OutcomeRejected, Errorf(CodeMethodNotAllowed, "Method %s is a subscription and needs a streaming transport", method)',
               );
-              b.l("if validationErrs, ok := err.(ValidationErrors); ok {")
-                .i()
-                .l("json.NewEncoder(w).Encode(map[string]interface{}{")
-                .i()
-                .l('"error": "Validation failed",')
-                .l('"errors": validationErrs,')
-                .u()
-                .l("})")
-                .u()
-                .l("} else {")
-                .i()
-                .l("json.NewEncoder(w).Encode(map[string]interface{}{")
-                .i()
-                .l('"error": err.Error(),')
-                .u()
-                .l("})")
-                .u()
-                .l("}");
+            })
+            .n()
+            .decl("input, stage, err", "r.prepare(ctx, info, m, params)")
+            .ifErr((b) => {
+              b.return("nil, stage, err");
+            })
+            .if("m.async", (b) => {
+              b.decl("op, err", "r.startOperation(ctx, info, m, input)")
+                .ifErr((b) => {
+                  b.return("nil, OutcomeHandlerError, err");
+                })
+                .return("op, OutcomeSuccess, nil");
+            })
+            .n()
+            .decl(
+              "result, err",
+              "r.invoke(ctx, info, input, func(ctx context.Context) (interface{}, error) {",
+            )
+            .i()
+            .return("m.invoke(r, ctx, info, input)")
+            .u()
+            .l("})")
+            .ifErr((b) => {
+              b.return("nil, OutcomeHandlerError, err");
+            })
+            .if("m.event != nil", (b) => {
+              b.l("r.publish(ctx, info, m.event(result))");
+            })
+            .return("result, OutcomeSuccess, nil");
+        },
+      );
+  }
+
+  private generateRequestLogging(b: GoBuilder): void {
+    // Registered before panic recovery so recovered panics are logged too
+    b.if("r.logger != nil", (b) => {
+      b.decl(
+        "rec",
+        "&responseRecorder{ResponseWriter: w, status: http.StatusOK}",
+      )
+        .l("w = rec")
+        .decl("start", "time.Now()")
+        .l("defer func() {")
+        .i()
+        .l("r.logger.LogRequest(req.Context(), LogEntry{")
+        .i()
+        .l("Method:   request.Method,")
+        .l("Duration: time.Since(start),")
+        .l("Outcome:  outcome,")
+        .l("Code:     rec.code,")
+        .l("Status:   rec.status,")
+        .l("Size:     rec.size,")
+        .l("Tenant:   rec.tenant,")
+        .u()
+        .l("})")
+        .u()
+        .l("}()");
+    }).n();
+  }
+
+  private generatePatternHelpers(w: GoBuilder): void {
+    w.comment(
+      "mustValidPattern panics if pattern is not valid path.Match syntax, so typos",
+    )
+      .comment(
+        "surface when the router is built rather than as silently skipped methods.",
+      )
+      .n()
+      .func("mustValidPattern(pattern string)", (b) => {
+        b.if('_, err := path.Match(pattern, ""); err != nil', (b) => {
+          b.l('panic("invalid method pattern: " + pattern)');
+        });
+      });
+
+    w.comment(
+      "matchMethod reports whether method matches pattern; an empty pattern matches",
+    )
+      .comment("every method.")
+      .n()
+      .func("matchMethod(pattern, method string) bool", (b) => {
+        b.if('pattern == ""', (b) => {
+          b.return("true");
+        })
+          .decl("matched, _", "path.Match(pattern, method)")
+          .return("matched");
+      });
+  }
+
+  private generateInvoke(w: GoBuilder): void {
+    w.comment(
+      "intercept wraps handler in the interceptors registered for info.Method",
+    )
+      .n()
+      .method(
+        "r *Router",
+        "intercept",
+        "info RequestInfo, input interface{}, handler NextFunc",
+        "NextFunc",
+        (b) => {
+          b.decl("next", "handler")
+            .l("for i := len(r.interceptors) - 1; i >= 0; i-- {")
+            .i()
+            .decl("entry", "r.interceptors[i]")
+            .if("!matchMethod(entry.pattern, info.Method)", (b) => {
+              b.l("continue");
+            })
+            .decl("inner", "next")
+            .l("next = func(ctx context.Context) (interface{}, error) {")
+            .i()
+            .return("entry.interceptor(ctx, info, input, inner)")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .return("next");
+        },
+      );
+
+    w.comment(
+      "invoke calls handler through the interceptors registered for info.Method,",
+    )
+      .comment("within its concurrency limits and timeout")
+      .n()
+      .method(
+        "r *Router",
+        "invoke",
+        "ctx context.Context, info RequestInfo, input interface{}, handler NextFunc",
+        "(interface{}, error)",
+        (b) => {
+          b.decl("release, err", "r.acquire(ctx, info.Method)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .comment(
+              "The slot is held until the handler returns, even past a timeout",
+            )
+            .decl("intercepted", "r.intercept(info, input, handler)")
+            .l("next := func(ctx context.Context) (interface{}, error) {")
+            .i()
+            .l("defer release()")
+            .return("intercepted(ctx)")
+            .u()
+            .l("}")
+            .decl("timeout", "r.timeoutFor(info.Method)")
+            .if("timeout <= 0", (b) => {
+              b.return("next(ctx)");
+            })
+            .return("r.invokeWithTimeout(ctx, info, timeout, next)");
+        },
+      );
+
+    w.comment(
+      "timeoutFor returns the timeout of method, or zero if it has none",
+    )
+      .n()
+      .method(
+        "r *Router",
+        "timeoutFor",
+        "method string",
+        "time.Duration",
+        (b) => {
+          b.decl("timeout", "r.timeout")
+            .l("for _, entry := range r.timeouts {")
+            .i()
+            .if("matchMethod(entry.pattern, method)", (b) => {
+              b.l("timeout = entry.timeout");
+            })
+            .u()
+            .l("}")
+            .return("timeout");
+        },
+      );
+
+    w.comment(
+      "clientTimeout returns the time left until the deadline the client of req sent",
+    )
+      .comment(
+        "in the X-Xrpc-Timeout header, in milliseconds. Values that are not a number",
+      )
+      .comment("of milliseconds up to 2^32-1 are ignored.")
+      .n()
+      .func("clientTimeout(req *http.Request) (time.Duration, bool)", (b) => {
+        b.decl("value", 'req.Header.Get("X-Xrpc-Timeout")')
+          .if('value == ""', (b) => {
+            b.return("0, false");
+          })
+          .decl("ms, err", "strconv.ParseUint(value, 10, 32)")
+          .ifErr((b) => {
+            b.return("0, false");
+          })
+          .return("time.Duration(ms) * time.Millisecond, true");
+      });
+
+    w.comment(
+      "invokeWithTimeout calls next with a context that expires after timeout. If it",
+    )
+      .comment(
+        "expires first, the call fails with DEADLINE_EXCEEDED and whatever next",
+      )
+      .comment(
+        "returns or writes to info.ResponseWriter afterwards is dropped.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "invokeWithTimeout",
+        "ctx context.Context, info RequestInfo, timeout time.Duration, next NextFunc",
+        "(interface{}, error)",
+        (b) => {
+          b.l("ctx, cancel := context.WithTimeout(ctx, timeout)")
+            .l("defer cancel()")
+            .n()
+            .var(
+              "done",
+              "",
+              "make(chan func() (interface{}, error), 1)",
+            )
+            .l("go func() {")
+            .i()
+            .comment(
+              "Panics are recovered here, in the handler's goroutine, so a late one",
+            )
+            .comment("doesn't crash the server")
+            .l("defer func() {")
+            .i()
+            .decl("rec", "recover()")
+            .if("rec == nil", (b) => {
               b.return();
-            }).n();
+            })
+            .if("rec == http.ErrAbortHandler", (b) => {
+              b.l(
+                "done <- func() (interface{}, error) { panic(rec) }",
+              ).return();
+            })
+            .l(
+              'r.logf("xrpc: panic serving %s: %v\\n%s", info.Method, rec, debug.Stack())',
+            )
+            .l(
+              'done <- func() (interface{}, error) { return nil, NewError(CodeInternal, "Internal server error") }',
+            )
+            .u()
+            .l("}()")
+            .l("result, err := next(ctx)")
+            .l("done <- func() (interface{}, error) { return result, err }")
+            .u()
+            .l("}()")
+            .n()
+            .l("select {")
+            .l("case outcome := <-done:")
+            .i()
+            .return("outcome()")
+            .u()
+            .l("case <-ctx.Done():")
+            .i()
+            .if(
+              "writer, ok := info.ResponseWriter.(*timeoutWriter); ok",
+              (b) => {
+                b.l("writer.expire()");
+              },
+            )
+            .if("ctx.Err() == context.DeadlineExceeded", (b) => {
+              b.return(
+                'nil, NewError(CodeDeadlineExceeded, "Deadline exceeded")',
+              );
+            })
+            .return("nil, ctx.Err()")
+            .u()
+            .l("}");
+        },
+      );
 
-            // Call typed handler directly
-            b.decl("result, err", `r.${fieldName}(ctx, input)`);
+    w.comment(
+      "timeoutWriter passes a handler's writes through to the response until its call",
+    )
+      .comment(
+        "times out, then drops them, so a slow handler can't write to a response the",
+      )
+      .comment("router already answered.")
+      .struct("timeoutWriter", (b) => {
+        b.l("http.ResponseWriter").l("mu      sync.Mutex").l("expired bool");
+      });
 
-            b.ifErr((b) => {
-              b.l('w.Header().Set("Content-Type", "application/json")')
-                .l(
-                  'json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error()})',
-                )
-                .return();
-            }).n();
+    w.method("tw *timeoutWriter", "expire", "", "", (b) => {
+      b.l("tw.mu.Lock()").l("defer tw.mu.Unlock()").l("tw.expired = true");
+    });
 
-            // Write response wrapped in JSON-RPC format
-            b.l('w.Header().Set("Content-Type", "application/json")')
-              .l(
-                'json.NewEncoder(w).Encode(map[string]interface{}{"result": result})',
-              )
-              .return();
-          },
-        }));
+    w.method("tw *timeoutWriter", "Header", "", "http.Header", (b) => {
+      b.l("tw.mu.Lock()")
+        .l("defer tw.mu.Unlock()")
+        .if("tw.expired", (b) => {
+          b.return("http.Header{}");
+        })
+        .return("tw.ResponseWriter.Header()");
+    });
 
-        w.switch("request.Method", cases, (b) => {
+    w.method("tw *timeoutWriter", "Write", "p []byte", "(int, error)", (b) => {
+      b.l("tw.mu.Lock()")
+        .l("defer tw.mu.Unlock()")
+        .if("tw.expired", (b) => {
+          b.return("0, http.ErrHandlerTimeout");
+        })
+        .return("tw.ResponseWriter.Write(p)");
+    });
+
+    w.method("tw *timeoutWriter", "WriteHeader", "status int", "", (b) => {
+      b.l("tw.mu.Lock()")
+        .l("defer tw.mu.Unlock()")
+        .if("!tw.expired", (b) => {
+          b.l("tw.ResponseWriter.WriteHeader(status)");
+        });
+    });
+  }
+
+  private generateRequestDecoding(
+    b: GoBuilder,
+    hasRest: boolean,
+    hasUploads: boolean,
+  ): void {
+    if (hasRest) {
+      b.l("if call, ok := restCallFrom(req); ok {")
+        .i()
+        .comment("RESTHandler already bound the route to a method and params")
+        .l("request.Method = call.method")
+        .l("request.Params = call.params")
+        .u()
+        .l("} else {")
+        .i();
+    }
+    b.l("switch req.Method {")
+      .l("case http.MethodPost:")
+      .i()
+      .if(
+        hasUploads
+          ? "err := decodeUploadRequest(req, &request); err != nil"
+          : "err := decodeRequest(req, &request); err != nil",
+        (b) => {
           b.l(
-            'http.Error(w, "Method not found", http.StatusNotFound)',
+            'r.writeError(w, Errorf(CodeInvalidArgument, "Invalid request: %v", err))',
           ).return();
-        });
-      },
-    );
+        },
+      )
+      .u()
+      .l("case http.MethodGet:")
+      .i()
+      .comment(
+        "Queries can be cached by HTTP intermediaries and EventSource clients",
+      )
+      .comment(
+        "can only issue GET requests, so both accept the envelope as query",
+      )
+      .comment("parameters. Mutations must still be POSTed.")
+      .decl("query", "req.URL.Query()")
+      .l('request.Method = query.Get("method")')
+      .l('request.Params = decodeQueryParams(query.Get("params"))')
+      .if("!r.allowsGET(request.Method)", (b) => {
+        b.l(
+          'r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))',
+        ).return();
+      })
+      .u()
+      .l("default:")
+      .i()
+      .l('r.writeError(w, NewError(CodeMethodNotAllowed, "Method not allowed"))')
+      .return()
+      .u()
+      .l("}");
+    if (hasRest) {
+      b.u().l("}");
+    }
+    b.n();
+  }
+
+  private generateDecodeQueryParams(w: GoBuilder): void {
+    w.comment(
+      "decodeQueryParams reads the params query parameter, which holds the input",
+    )
+      .comment(
+        "either as URL-encoded JSON or as base64url-encoded JSON. A missing value",
+      )
+      .comment("is an empty object.")
+      .n()
+      .func("decodeQueryParams(value string) json.RawMessage", (b) => {
+        b.if('value == ""', (b) => {
+          b.return('json.RawMessage("{}")');
+        })
+          .if("json.Valid([]byte(value))", (b) => {
+            b.return("json.RawMessage(value)");
+          })
+          .decl(
+            "decoded, err",
+            'base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))',
+          )
+          .if("err == nil && json.Valid(decoded)", (b) => {
+            b.return("json.RawMessage(decoded)");
+          })
+          .comment("Left as is so decoding reports it as invalid params")
+          .return("json.RawMessage(value)");
+      });
+  }
+
+  private generateNotModified(w: GoBuilder): void {
+    w.comment(
+      "notModified sets the ETag of a query result served over GET, a hash of its",
+    )
+      .comment(
+        "encoded body, and answers 304 Not Modified when the request's If-None-Match",
+      )
+      .comment("already names it, so polling clients skip unchanged results.")
+      .n()
+      .func(
+        "notModified(w http.ResponseWriter, req *http.Request, body []byte) bool",
+        (b) => {
+          b.decl("sum", "sha256.Sum256(body)")
+            .decl(
+              "etag",
+              '`"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`',
+            )
+            .l('w.Header().Set("ETag", etag)')
+            .l(
+              'for _, candidate := range strings.Split(req.Header.Get("If-None-Match"), ",") {',
+            )
+            .i()
+            .comment("Weak comparison, as RFC 9110 requires for If-None-Match")
+            .l(
+              'candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")',
+            )
+            .if('candidate == etag || candidate == "*"', (b) => {
+              b.l("w.WriteHeader(http.StatusNotModified)").return("true");
+            })
+            .u()
+            .l("}")
+            .return("false");
+        },
+      );
+  }
+
+  private generateWriteEvent(w: GoBuilder): void {
+    w.comment(
+      "writeEvent writes a single Server-Sent Event and flushes it to the client",
+    )
+      .n()
+      .func(
+        "writeEvent(w http.ResponseWriter, flusher http.Flusher, event string, v interface{}) error",
+        (b) => {
+          b.decl("data, err", "json.Marshal(v)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .if('event != ""', (b) => {
+              b.if(
+                '_, err := fmt.Fprintf(w, "event: %s\\n", event); err != nil',
+                (b) => {
+                  b.return("err");
+                },
+              );
+            })
+            .if(
+              '_, err := fmt.Fprintf(w, "data: %s\\n\\n", data); err != nil',
+              (b) => {
+                b.return("err");
+              },
+            )
+            .l("flusher.Flush()")
+            .return("nil");
+        },
+      );
   }
 }
@@ -0,0 +1,195 @@
+import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toMethodName } from "./server-generator";
+import { endpointNamespace, handlerFuncType } from "./type-generator";
+import { streamItemType } from "./type-mapper";
+
+/**
+ * Generates services.go: an interface per endpoint namespace with a method
+ * per endpoint, TaskService for the task.* methods, and RegisterTaskService
+ * setting the methods of an implementation as the handlers of a router. A
+ * team implements a namespace with one struct, and the compiler reports the
+ * methods it is missing. NewTaskHandlers builds an implementation from
+ * handler functions taking the namespace's dependencies as an argument, so
+ * handlers are wired to a dependency struct instead of globals.
+ */
+export class GoServiceGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateServices(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const namespaces = new Map<string, Endpoint[]>();
+    for (const endpoint of contract.endpoints) {
+      const namespace = endpointNamespace(endpoint);
+      namespaces.set(namespace, [
+        ...(namespaces.get(namespace) ?? []),
+        endpoint,
+      ]);
+    }
+
+    w.package(this.packageName);
+    if (namespaces.size === 0) {
+      return w.toString();
+    }
+    w.import("context");
+
+    for (const [namespace, endpoints] of namespaces) {
+      this.generateService(w, namespace, endpoints);
+    }
+
+    return w.toString();
+  }
+
+  private generateService(
+    w: GoBuilder,
+    namespace: string,
+    endpoints: Endpoint[],
+  ): void {
+    const prefix = toMethodName(namespace);
+    const service = `${prefix}Service`;
+    // Methods are named after the endpoint without its namespace, List for
+    // task.list
+    const methodName = (endpoint: Endpoint) =>
+      toMethodName(endpoint.fullName).slice(prefix.length);
+
+    w.comment(
+      `${service} serves the ${namespace}.* methods, each with the signature of`,
+    )
+      .comment(
+        `its handler. Register an implementation with Register${service}.`,
+      )
+      .l(`type ${service} interface {`)
+      .i();
+    for (const endpoint of endpoints) {
+      const itemType = endpoint.stream
+        ? streamItemType(endpoint).type
+        : undefined;
+      w.comment(`${methodName(endpoint)} serves ${endpoint.fullName}.`);
+      if (endpoint.description) {
+        w.l("//").doc(endpoint.description);
+      }
+      w.l(
+        `${methodName(endpoint)}${handlerFuncType(endpoint, itemType).slice("func".length)}`,
+      );
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      `Register${service} sets the methods of impl as the handlers of the`,
+    )
+      .comment(`${namespace}.* methods of r.`)
+      .func(`Register${service}(r *Router, impl ${service})`, (b) => {
+        for (const endpoint of endpoints) {
+          b.l(
+            `r.${toMethodName(endpoint.fullName)}(impl.${methodName(endpoint)})`,
+          );
+        }
+      });
+
+    this.generateHandlerFuncs(w, namespace, endpoints, prefix, methodName);
+  }
+
+  // TaskHandlerFuncs, the handlers of a namespace as functions of their
+  // dependencies, and NewTaskHandlers binding them into a TaskService
+  private generateHandlerFuncs(
+    w: GoBuilder,
+    namespace: string,
+    endpoints: Endpoint[],
+    prefix: string,
+    methodName: (endpoint: Endpoint) => string,
+  ): void {
+    const service = `${prefix}Service`;
+    const funcs = `${prefix}HandlerFuncs`;
+    const bound = `bound${prefix}Handlers`;
+    const signature = (endpoint: Endpoint) =>
+      handlerFuncType(
+        endpoint,
+        endpoint.stream ? streamItemType(endpoint).type : undefined,
+      );
+
+    w.comment(
+      `${funcs} holds the handlers of the ${namespace}.* methods as functions of`,
+    )
+      .comment(
+        "their dependencies deps, such as a struct of the database and clients they",
+      )
+      .comment("use, instead of package-level variables.")
+      .struct(`${funcs}[D any]`, (b) => {
+        for (const endpoint of endpoints) {
+          b.l(
+            `${methodName(endpoint)} ${signature(endpoint).replace("(ctx context.Context, ", "(ctx context.Context, deps D, ")}`,
+          );
+        }
+      });
+
+    const example = (endpoint: Endpoint) => {
+      const name = methodName(endpoint);
+      return `${name}: ${name.charAt(0).toLowerCase()}${name.slice(1)}${prefix},`;
+    };
+    w.comment(
+      `New${prefix}Handlers returns the ${service} calling funcs with deps, for`,
+    )
+      .comment(
+        `Register${service}; tests pass fake dependencies. It panics if a function`,
+      )
+      .comment("is missing.")
+      .comment("")
+      .comment(`    type ${prefix}Deps struct{ DB *DB }`)
+      .comment("")
+      .comment(
+        `    handlers := New${prefix}Handlers(${prefix}Deps{DB: db}, ${funcs}[${prefix}Deps]{`,
+      )
+      .comment(`        ${example(endpoints[0])}`);
+    if (endpoints.length > 1) {
+      w.comment("        ...");
+    }
+    w.comment("    })")
+      .comment(`    Register${service}(router, handlers)`)
+      .n()
+      .func(
+        `New${prefix}Handlers[D any](deps D, funcs ${funcs}[D]) ${service}`,
+        (b) => {
+          for (const endpoint of endpoints) {
+            const name = methodName(endpoint);
+            b.if(`funcs.${name} == nil`, (b) => {
+              b.l(
+                `panic("xrpc: New${prefix}Handlers: no ${name} function for ${endpoint.fullName}")`,
+              );
+            });
+          }
+          b.return(`${bound}[D]{deps: deps, funcs: funcs}`);
+        },
+      );
+
+    w.comment(`${bound} is the ${service} of New${prefix}Handlers.`).struct(
+      `${bound}[D any]`,
+      (b) => {
+        b.l("deps  D").l(`funcs ${funcs}[D]`);
+      },
+    );
+
+    for (const endpoint of endpoints) {
+      const name = methodName(endpoint);
+      const args =
+        endpoint.type === "subscription"
+          ? "ctx, h.deps, info, input, send"
+          : endpoint.stream
+            ? "ctx, h.deps, info, input, stream"
+            : "ctx, h.deps, info, input";
+      w.l(
+        `func (h ${bound}[D]) ${name}${signature(endpoint).slice("func".length)} {`,
+      )
+        .i()
+        .return(`h.funcs.${name}(${args})`)
+        .u()
+        .l("}")
+        .n();
+    }
+  }
+}
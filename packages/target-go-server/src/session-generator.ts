@@ -0,0 +1,468 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates session.go: the Sessions middleware loading the cookie session of
+ * each call from a pluggable SessionStore, in memory or in Redis (redis.go),
+ * and SessionFrom returning it to handlers. Session IDs are random, carried
+ * in a Secure, HttpOnly cookie, and only taken from a client when the store
+ * knows them.
+ */
+export class GoSessionGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateSession(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "crypto/rand",
+      "encoding/base64",
+      "encoding/json",
+      "net/http",
+      "sync",
+      "time",
+    );
+
+    this.generateStore(w);
+    this.generateMemoryStore(w);
+    this.generateSessionType(w);
+    this.generateMiddleware(w);
+
+    return w.toString();
+  }
+
+  private generateStore(w: GoBuilder): void {
+    w.comment(
+      "SessionStore keeps the values of sessions by ID for the Sessions middleware,",
+    )
+      .comment(
+        "in memory with MemorySessionStore or in Redis with RedisSessionStore.",
+      )
+      .l("type SessionStore interface {")
+      .i()
+      .comment(
+        "Load returns the values saved for the session id, false if there are none",
+      )
+      .comment("or they expired.")
+      .l(
+        "Load(ctx context.Context, id string) (map[string]json.RawMessage, bool, error)",
+      )
+      .comment("Save stores the values of the session id, to expire after ttl.")
+      .l(
+        "Save(ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration) error",
+      )
+      .comment("Delete removes the session id.")
+      .l("Delete(ctx context.Context, id string) error")
+      .u()
+      .l("}")
+      .n();
+  }
+
+  private generateMemoryStore(w: GoBuilder): void {
+    w.comment(
+      "MemorySessionStore keeps sessions in memory, for a single server or tests.",
+    ).struct("MemorySessionStore", (b) => {
+      b.l("mu       sync.Mutex")
+        .l("sessions map[string]memorySession")
+        .comment("The number of sessions after the last sweep of expired ones")
+        .l("swept int");
+    });
+
+    w.comment("memorySession is a session of a MemorySessionStore.").struct(
+      "memorySession",
+      (b) => {
+        b.l("values  map[string]json.RawMessage").l("expires time.Time");
+      },
+    );
+
+    w.comment("NewMemorySessionStore returns an empty MemorySessionStore.")
+      .n()
+      .func("NewMemorySessionStore() *MemorySessionStore", (b) => {
+        b.return(
+          "&MemorySessionStore{sessions: make(map[string]memorySession)}",
+        );
+      });
+
+    w.comment(
+      "Load returns a copy of the values of the session id, dropping it if it expired.",
+    )
+      .n()
+      .method(
+        "s *MemorySessionStore",
+        "Load",
+        "ctx context.Context, id string",
+        "(map[string]json.RawMessage, bool, error)",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .decl("session, ok", "s.sessions[id]")
+            .if("!ok", (b) => {
+              b.return("nil, false, nil");
+            })
+            .if("!time.Now().Before(session.expires)", (b) => {
+              b.l("delete(s.sessions, id)").return("nil, false, nil");
+            })
+            .return("copySessionValues(session.values), true, nil");
+        },
+      );
+
+    w.comment(
+      "Save stores a copy of the values of the session id. Expired sessions are swept",
+    )
+      .comment(
+        "whenever the number of sessions doubled since the last sweep.",
+      )
+      .n()
+      .method(
+        "s *MemorySessionStore",
+        "Save",
+        "ctx context.Context, id string, values map[string]json.RawMessage, ttl time.Duration",
+        "error",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .decl("now", "time.Now()")
+            .l(
+              "s.sessions[id] = memorySession{values: copySessionValues(values), expires: now.Add(ttl)}",
+            )
+            .if("len(s.sessions) > 2*s.swept", (b) => {
+              b.l("for id, session := range s.sessions {")
+                .i()
+                .if("!now.Before(session.expires)", (b) => {
+                  b.l("delete(s.sessions, id)");
+                })
+                .u()
+                .l("}")
+                .l("s.swept = len(s.sessions)");
+            })
+            .return("nil");
+        },
+      );
+
+    w.comment("Delete removes the session id.")
+      .n()
+      .method(
+        "s *MemorySessionStore",
+        "Delete",
+        "ctx context.Context, id string",
+        "error",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .l("delete(s.sessions, id)")
+            .return("nil");
+        },
+      );
+
+    w.comment(
+      "copySessionValues copies values, so sessions of concurrent calls do not share",
+    )
+      .comment("a map.")
+      .n()
+      .func(
+        "copySessionValues(values map[string]json.RawMessage) map[string]json.RawMessage",
+        (b) => {
+          b.decl("copied", "make(map[string]json.RawMessage, len(values))")
+            .l("for key, value := range values {")
+            .i()
+            .l("copied[key] = value")
+            .u()
+            .l("}")
+            .return("copied");
+        },
+      );
+  }
+
+  private generateSessionType(w: GoBuilder): void {
+    w.comment("SessionOptions configures the Sessions middleware.").struct(
+      "SessionOptions",
+      (b) => {
+        b.comment(
+          'CookieName is the name of the session cookie, "xrpc_session" by default.',
+        )
+          .l("CookieName string")
+          .comment(
+            "TTL is how long a session lasts after it was last saved, 24 hours by",
+          )
+          .comment("default.")
+          .l("TTL time.Duration")
+          .comment('Path and Domain scope the cookie; Path is "/" by default.')
+          .l("Path   string")
+          .l("Domain string")
+          .comment(
+            "SameSite restricts sending the cookie with cross-site requests, Lax by",
+          )
+          .comment("default.")
+          .l("SameSite http.SameSite")
+          .comment(
+            "Insecure sends the cookie over plain HTTP too. Otherwise it is Secure,",
+          )
+          .comment("which browsers also accept from http://localhost.")
+          .l("Insecure bool");
+      },
+    );
+
+    w.comment(
+      "Session is the session of the caller of a call: the one its cookie names, or a",
+    )
+      .comment(
+        "new, empty one. Changes are kept until Save. It is safe for concurrent use.",
+      )
+      .struct("Session", (b) => {
+        b.l("store   SessionStore")
+          .l("options SessionOptions")
+          .l("w       http.ResponseWriter")
+          .l("mu      sync.Mutex")
+          .l("id      string")
+          .l("values  map[string]json.RawMessage");
+      });
+
+    w.comment("sessionKey is the context key of the session of a call.")
+      .l("type sessionKey struct{}")
+      .n();
+
+    w.comment(
+      "ID returns the ID of the session, empty for a new session until it is saved.",
+    )
+      .n()
+      .method("s *Session", "ID", "", "string", (b) => {
+        b.l("s.mu.Lock()").l("defer s.mu.Unlock()").return("s.id");
+      });
+
+    w.comment(
+      "Get decodes the value stored under key into target, like json.Unmarshal, and",
+    )
+      .comment("reports false if there is none.")
+      .n()
+      .method(
+        "s *Session",
+        "Get",
+        "key string, target interface{}",
+        "(bool, error)",
+        (b) => {
+          b.l("s.mu.Lock()")
+            .decl("value, ok", "s.values[key]")
+            .l("s.mu.Unlock()")
+            .if("!ok", (b) => {
+              b.return("false, nil");
+            })
+            .return("true, json.Unmarshal(value, target)");
+        },
+      );
+
+    w.comment("Set stores the JSON of value under key.")
+      .n()
+      .method(
+        "s *Session",
+        "Set",
+        "key string, value interface{}",
+        "error",
+        (b) => {
+          b.decl("data, err", "json.Marshal(value)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("s.mu.Lock()")
+            .l("defer s.mu.Unlock()")
+            .l("s.values[key] = data")
+            .return("nil");
+        },
+      );
+
+    w.comment("Delete removes the value stored under key.")
+      .n()
+      .method("s *Session", "Delete", "key string", "", (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .l("delete(s.values, key)");
+      });
+
+    w.comment(
+      "Save stores the session, extending its expiry, and sets its cookie, choosing a",
+    )
+      .comment(
+        "random ID for a new session. Handlers save before returning, as the cookie",
+      )
+      .comment("cannot be set once a subscription or stream started writing.")
+      .n()
+      .method("s *Session", "Save", "ctx context.Context", "error", (b) => {
+        b.l("s.mu.Lock()").l("defer s.mu.Unlock()").return("s.save(ctx)");
+      });
+
+    w.comment(
+      "Renew moves the session to a new ID, keeping its values, and saves it. Call it",
+    )
+      .comment(
+        "when the caller signs in, so an ID an attacker planted before does not become",
+      )
+      .comment("a signed-in session (session fixation).")
+      .n()
+      .method("s *Session", "Renew", "ctx context.Context", "error", (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .if('s.id != ""', (b) => {
+            b.if("err := s.store.Delete(ctx, s.id); err != nil", (b) => {
+              b.return("err");
+            }).l('s.id = ""');
+          })
+          .return("s.save(ctx)");
+      });
+
+    w.comment(
+      "Destroy deletes the session and its cookie, such as when the caller signs out.",
+    )
+      .comment("The session is new and empty afterwards.")
+      .n()
+      .method("s *Session", "Destroy", "ctx context.Context", "error", (b) => {
+        b.l("s.mu.Lock()")
+          .l("defer s.mu.Unlock()")
+          .if('s.id != ""', (b) => {
+            b.if("err := s.store.Delete(ctx, s.id); err != nil", (b) => {
+              b.return("err");
+            });
+          })
+          .l('s.id = ""')
+          .l("s.values = map[string]json.RawMessage{}")
+          .l('s.setCookie("", -1)')
+          .return("nil");
+      });
+
+    w.comment("save is Save, with s.mu held.")
+      .n()
+      .method("s *Session", "save", "ctx context.Context", "error", (b) => {
+        b.if('s.id == ""', (b) => {
+          b.decl("id", "make([]byte, 32)")
+            .if("_, err := rand.Read(id); err != nil", (b) => {
+              b.return("err");
+            })
+            .l("s.id = base64.RawURLEncoding.EncodeToString(id)");
+        })
+          .if(
+            "err := s.store.Save(ctx, s.id, s.values, s.options.TTL); err != nil",
+            (b) => {
+              b.return("err");
+            },
+          )
+          .l("s.setCookie(s.id, int(s.options.TTL/time.Second))")
+          .return("nil");
+      });
+
+    w.comment(
+      "setCookie sets the session cookie of the response, deleting it when maxAge is",
+    )
+      .comment("negative. Calls without a response, such as Dispatch, have no cookie.")
+      .n()
+      .method("s *Session", "setCookie", "value string, maxAge int", "", (b) => {
+        b.if("s.w == nil", (b) => {
+          b.return();
+        }).l("http.SetCookie(s.w, &http.Cookie{")
+          .i()
+          .l("Name:     s.options.CookieName,")
+          .l("Value:    value,")
+          .l("Path:     s.options.Path,")
+          .l("Domain:   s.options.Domain,")
+          .l("MaxAge:   maxAge,")
+          .l("Secure:   !s.options.Insecure,")
+          .l("HttpOnly: true,")
+          .l("SameSite: s.options.SameSite,")
+          .u()
+          .l("})");
+      });
+  }
+
+  private generateMiddleware(w: GoBuilder): void {
+    w.comment(
+      "Sessions returns middleware loading the session whose ID the cookie of a call",
+    )
+      .comment(
+        "carries from store, or starting a new one, for handlers to get with",
+      )
+      .comment("SessionFrom:")
+      .comment("")
+      .comment("    router.Use(Sessions(NewMemorySessionStore(), SessionOptions{}))")
+      .comment("")
+      .comment("    session, _ := SessionFrom(ctx)")
+      .comment('    session.Set("cart", cart)')
+      .comment("    err := session.Save(ctx)")
+      .comment("")
+      .comment(
+        "Cookies with IDs the store does not know start a new session with a new ID,",
+      )
+      .comment("so clients cannot choose their session ID.")
+      .n()
+      .func(
+        "Sessions(store SessionStore, options SessionOptions) MiddlewareFunc",
+        (b) => {
+          b.if('options.CookieName == ""', (b) => {
+            b.l('options.CookieName = "xrpc_session"');
+          })
+            .if("options.TTL <= 0", (b) => {
+              b.l("options.TTL = 24 * time.Hour");
+            })
+            .if('options.Path == ""', (b) => {
+              b.l('options.Path = "/"');
+            })
+            .if("options.SameSite == 0", (b) => {
+              b.l("options.SameSite = http.SameSiteLaxMode");
+            })
+            .l(
+              "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+            )
+            .i()
+            .decl(
+              "session",
+              "&Session{store: store, options: options, w: info.ResponseWriter, values: map[string]json.RawMessage{}}",
+            )
+            .if("info.Request != nil", (b) => {
+              b.if(
+                "cookie, err := info.Request.Cookie(options.CookieName); err == nil && validSessionID(cookie.Value)",
+                (b) => {
+                  b.decl("values, ok, err", "store.Load(ctx, cookie.Value)")
+                    .ifErr((b) => {
+                      b.return("NewMiddlewareError(err)");
+                    })
+                    .if("ok && values != nil", (b) => {
+                      b.l("session.id = cookie.Value").l(
+                        "session.values = values",
+                      );
+                    });
+                },
+              );
+            })
+            .return(
+              "NewMiddlewareResult(context.WithValue(ctx, sessionKey{}, session))",
+            )
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "validSessionID reports whether id has the form of the IDs Save chooses, so",
+    )
+      .comment("other cookie values are not looked up.")
+      .n()
+      .func("validSessionID(id string) bool", (b) => {
+        b.decl("decoded, err", "base64.RawURLEncoding.DecodeString(id)").return(
+          "err == nil && len(decoded) == 32",
+        );
+      });
+
+    w.comment(
+      "SessionFrom returns the session the Sessions middleware loaded for the call",
+    )
+      .comment("ctx belongs to, if it runs.")
+      .n()
+      .func("SessionFrom(ctx context.Context) (*Session, bool)", (b) => {
+        b.decl("session, ok", "ctx.Value(sessionKey{}).(*Session)").return(
+          "session, ok",
+        );
+      });
+  }
+}
@@ -0,0 +1,236 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates singleflight.go: the opt-in SingleFlight interceptor, which
+ * shares one handler execution among identical queries running at the same
+ * time, keyed by method, params and caller.
+ */
+export class GoSingleFlightGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateSingleFlight(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "crypto/sha256",
+      "encoding/hex",
+      "encoding/json",
+      "sync",
+    );
+
+    w.comment(
+      "singleFlightAllowed reports whether SingleFlight shares calls of method: only",
+    )
+      .comment(
+        "queries answered with a single result. Mutations change state and streamed",
+      )
+      .comment(
+        "results are written by the call producing them, so their calls always run.",
+      )
+      .n()
+      .func("singleFlightAllowed(method string) bool", (b) => {
+        b.decl("m, ok", "methodTable[method]")
+          .return('ok && m.kind == "query" && m.serve == nil');
+      });
+
+    w.comment("flightCall is a handler execution shared by identical calls.")
+      .struct("flightCall", (b) => {
+        b.l("done   chan struct{}")
+          .l("result interface{}")
+          .l("err    error")
+          .comment("What fn panicked with, re-raised in one of the waiting calls")
+          .l("panicked interface{}")
+          .comment(
+            "The number of calls waiting for the result, and the cancellation of fn",
+          )
+          .comment("once none is left")
+          .l("waiters int")
+          .l("cancel  context.CancelFunc");
+      });
+
+    w.comment(
+      "flightGroup shares one execution of a function among the calls with the same",
+    )
+      .comment("key made while it runs. The zero value is ready to use.")
+      .struct("flightGroup", (b) => {
+        b.l("mu    sync.Mutex").l("calls map[string]*flightCall");
+      });
+
+    w.comment(
+      "do returns the result of fn, or waits for the call with key already running",
+    )
+      .comment(
+        "and returns its result instead. fn runs with the values of the ctx of the",
+      )
+      .comment(
+        "call starting it but not its cancellation, so the others are not failed by",
+      )
+      .comment(
+        "it leaving early; each call stops waiting when its own ctx is done, and fn",
+      )
+      .comment("is cancelled once no call is left waiting for it.")
+      .n()
+      .method(
+        "g *flightGroup",
+        "do",
+        "ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)",
+        "(interface{}, error)",
+        (b) => {
+          b.l("g.mu.Lock()")
+            .decl("call, ok", "g.calls[key]")
+            .if("!ok", (b) => {
+              b.if("g.calls == nil", (b) => {
+                b.l("g.calls = map[string]*flightCall{}");
+              })
+                .decl(
+                  "flightCtx, cancel",
+                  "context.WithCancel(context.WithoutCancel(ctx))",
+                )
+                .comment(
+                  "Waiting calls get INTERNAL if fn panics before returning",
+                )
+                .l(
+                  'call = &flightCall{done: make(chan struct{}), err: NewError(CodeInternal, "Internal server error"), cancel: cancel}',
+                )
+                .l("g.calls[key] = call")
+                .l("go g.run(flightCtx, key, call, fn)");
+            })
+            .l("call.waiters++")
+            .l("g.mu.Unlock()")
+            .n()
+            .l("select {")
+            .l("case <-call.done:")
+            .i()
+            .l("g.mu.Lock()")
+            .decl("rec", "call.panicked")
+            .l("call.panicked = nil")
+            .l("g.mu.Unlock()")
+            .if("rec != nil", (b) => {
+              b.l("panic(rec)");
+            })
+            .return("call.result, call.err")
+            .u()
+            .l("case <-ctx.Done():")
+            .i()
+            .l("g.mu.Lock()")
+            .l("call.waiters--")
+            .if("call.waiters == 0", (b) => {
+              b.comment(
+                "Nobody waits for the result any more; later calls start afresh",
+              )
+                .l("call.cancel()")
+                .if("g.calls[key] == call", (b) => {
+                  b.l("delete(g.calls, key)");
+                });
+            })
+            .l("g.mu.Unlock()")
+            .return("nil, ctx.Err()")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "run calls fn for call, recovering a panic to re-raise it in a waiting call",
+    )
+      .comment("rather than crash the server from this goroutine.")
+      .n()
+      .method(
+        "g *flightGroup",
+        "run",
+        "ctx context.Context, key string, call *flightCall, fn func(ctx context.Context) (interface{}, error)",
+        "",
+        (b) => {
+          b.l("defer func() {")
+            .i()
+            .l("call.panicked = recover()")
+            .l("call.cancel()")
+            .l("g.mu.Lock()")
+            .if("g.calls[key] == call", (b) => {
+              b.l("delete(g.calls, key)");
+            })
+            .l("g.mu.Unlock()")
+            .l("close(call.done)")
+            .u()
+            .l("}()")
+            .l("call.result, call.err = fn(ctx)");
+        },
+      );
+
+    w.comment(
+      "SingleFlight returns an interceptor sharing one handler execution among",
+    )
+      .comment(
+        "identical queries: calls of a method with the same params by the same user",
+      )
+      .comment(
+        "(UserIDFrom) of the same tenant (TenantFrom) arriving while one is running wait",
+      )
+      .comment(
+        "for it and get its result, so bursts of the same query, such as from dashboards",
+      )
+      .comment(
+        "fanning it out, run the handler once. Results are not kept past the call, and a",
+      )
+      .comment(
+        "waiting call whose context is done stops waiting. Register it with Intercept, or",
+      )
+      .comment(
+        'InterceptFor to limit it to some queries, e.g. InterceptFor("task.list",',
+      )
+      .comment(
+        "SingleFlight()). Callers share the result value, so interceptors and handlers",
+      )
+      .comment("must not modify it afterwards.")
+      .n()
+      .func("SingleFlight() InterceptorFunc", (b) => {
+        b.var("group", "flightGroup")
+          .l(
+            "return func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error) {",
+          )
+          .i()
+          .if("!singleFlightAllowed(info.Method)", (b) => {
+            b.return("next(ctx)");
+          })
+          .decl("key, err", "flightKey(ctx, info.Method, input)")
+          .ifErr((b) => {
+            b.return("next(ctx)");
+          })
+          .l(
+            "return group.do(ctx, key, next)",
+          )
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "flightKey identifies a call by its method, the tenant and user making it and a",
+    )
+      .comment("hash of its params.")
+      .n()
+      .func(
+        "flightKey(ctx context.Context, method string, input interface{}) (string, error)",
+        (b) => {
+          b.decl("params, err", "json.Marshal(input)")
+            .ifErr((b) => {
+              b.return('"", err');
+            })
+            .decl("tenant, _", "TenantFrom(ctx)")
+            .decl("userID, _", "UserIDFrom(ctx)")
+            .decl("sum", "sha256.Sum256(params)")
+            .return(
+              'method + "\\x00" + tenant.ID + "\\x00" + userID + "\\x00" + hex.EncodeToString(sum[:]), nil',
+            );
+        },
+      );
+
+    return w.toString();
+  }
+}
@@ -0,0 +1,161 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import { collectExamples } from "./examples-generator";
+import { GoBuilder, goStringLiteral } from "./go-builder";
+import { toPascalCase } from "./naming";
+
+/**
+ * Generates snippets.go: ready-to-run curl and HTTPie commands calling every
+ * method with an example input derived from its schema, for runbooks and bug
+ * reports, and NewSnippet building them for any params.
+ */
+export class GoSnippetsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateSnippets(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const examples = collectExamples(contract);
+
+    w.package(this.packageName).import(
+      "encoding/json",
+      "net/http",
+      "sort",
+      "strings",
+    );
+
+    w.comment(
+      "Snippet holds commands calling a method of a server of the contract, to paste",
+    )
+      .comment("into a shell.")
+      .struct("Snippet", (b) => {
+        b.l("Method string").l("Curl   string").l("HTTPie string");
+      });
+
+    w.comment(
+      "snippetInputs holds the example input of every method whose input has one,",
+    )
+      .comment("as JSON, sorted by method name.")
+      .l("var snippetInputs = [][2]string{")
+      .i();
+    for (const endpoint of contract.endpoints) {
+      const example = examples.get(toPascalCase(endpoint.input.name!));
+      if (example !== undefined) {
+        w.l(
+          `{${JSON.stringify(endpoint.fullName)}, ${goStringLiteral(example)}},`,
+        );
+      }
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "Snippets returns the snippets calling every method with an example input",
+    )
+      .comment(
+        "that passes validation, derived from its schema. url is where the router is",
+      )
+      .comment(
+        'served, such as "https://api.example.com/api", and header is sent with every',
+      )
+      .comment(
+        "call, e.g. to authenticate. Methods whose input has no example are left out.",
+      )
+      .n()
+      .func("Snippets(url string, header http.Header) []Snippet", (b) => {
+        b.decl("snippets", "make([]Snippet, 0, len(snippetInputs))")
+          .l("for _, input := range snippetInputs {")
+          .i()
+          .l(
+            "snippets = append(snippets, NewSnippet(url, input[0], json.RawMessage(input[1]), header))",
+          )
+          .u()
+          .l("}")
+          .return("snippets");
+      });
+
+    w.comment(
+      "NewSnippet returns the snippet calling method with params at url, sending",
+    )
+      .comment(
+        "header. Subscriptions are called with output buffering off, so events show",
+      )
+      .comment("as they arrive.")
+      .n()
+      .func(
+        "NewSnippet(url, method string, params json.RawMessage, header http.Header) Snippet",
+        (b) => {
+          b.if("len(params) == 0", (b) => {
+            b.l('params = json.RawMessage("{}")');
+          })
+            .l("body, _ := json.Marshal(struct {")
+            .i()
+            .l('Method string          `json:"method"`')
+            .l('Params json.RawMessage `json:"params"`')
+            .u()
+            .l("}{method, params})")
+            .decl("m, ok", "methodTable[method]")
+            .decl("stream", 'ok && m.kind == "subscription"')
+            .decl("keys", "make([]string, 0, len(header))")
+            .l("for key := range header {")
+            .i()
+            .l("keys = append(keys, key)")
+            .u()
+            .l("}")
+            .l("sort.Strings(keys)")
+            .n()
+            .var("curl", "strings.Builder")
+            .l('curl.WriteString("curl")')
+            .if("stream", (b) => {
+              b.l('curl.WriteString(" -N")');
+            })
+            .l(
+              'curl.WriteString(" " + shellQuote(url) + " \\\\\\n  -H \'Content-Type: application/json\'")',
+            )
+            .var("httpie", "strings.Builder")
+            .l('httpie.WriteString("http")')
+            .if("stream", (b) => {
+              b.l('httpie.WriteString(" --stream")');
+            })
+            .l('httpie.WriteString(" POST " + shellQuote(url))')
+            .l("for _, key := range keys {")
+            .i()
+            .l("for _, value := range header[key] {")
+            .i()
+            .l(
+              'curl.WriteString(" \\\\\\n  -H " + shellQuote(key+": "+value))',
+            )
+            .l('httpie.WriteString(" " + shellQuote(key+":"+value))')
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .l(
+              'curl.WriteString(" \\\\\\n  -d " + shellQuote(string(body)))',
+            )
+            .l(
+              'httpie.WriteString(" method=" + shellQuote(method) + " params:=" + shellQuote(string(params)))',
+            )
+            .return(
+              "Snippet{Method: method, Curl: curl.String(), HTTPie: httpie.String()}",
+            );
+        },
+      );
+
+    w.comment(
+      "shellQuote quotes s for a POSIX shell, in single quotes that keep everything",
+    )
+      .comment("but a single quote, which is closed, escaped and reopened.")
+      .n()
+      .func("shellQuote(s string) string", (b) => {
+        b.return(
+          `"'" + strings.Replace(s, "'", \`'\\''\`, -1) + "'"`,
+        );
+      });
+
+    return w.toString();
+  }
+}
@@ -0,0 +1,384 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates streams.go: the Stream sink handlers of endpoints declared with
+ * a stream option send the items of their output's array field to, and the
+ * writing of those items to the response as they are sent, as a chunked JSON
+ * result or as NDJSON, so large results such as exports are never buffered
+ * whole.
+ */
+export class GoStreamGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate streams.go, or null when no endpoint of the contract streams.
+   * @param contract - The contract definition
+   */
+  generateStreams(contract: ContractDefinition): string | null {
+    if (!usesStreams(contract)) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import(
+      "bytes",
+      "context",
+      "encoding/json",
+      "io",
+      "mime",
+      "net/http",
+      "sort",
+      "strings",
+    );
+
+    this.generateStream(w);
+    this.generateHTTPStream(
+      w,
+      contract.endpoints.some((endpoint) => endpoint.http),
+    );
+    this.generateInvokeStream(w);
+
+    return w.toString();
+  }
+
+  private generateStream(w: GoBuilder): void {
+    w.comment(
+      "Stream sends the items of a streamed result, such as the tasks of a listing,",
+    )
+      .comment(
+        "as the handler produces them instead of buffering the whole array. Over HTTP",
+      )
+      .comment(
+        "each item is written to the response as it is sent; Dispatch collects them",
+      )
+      .comment("into the result.")
+      .struct("Stream[T any]", (b) => {
+        b.l("send func(item T) error");
+      });
+
+    w.comment(
+      "Send writes item to the client. It fails once the client went away or the",
+    )
+      .comment("call timed out, and the handler should then return.")
+      .n()
+      .method("s *Stream[T]", "Send", "item T", "error", (b) => {
+        b.return("s.send(item)");
+      });
+
+    w.comment("SendAll sends items in order, stopping at the first error.")
+      .n()
+      .method("s *Stream[T]", "SendAll", "items []T", "error", (b) => {
+        b.l("for _, item := range items {")
+          .i()
+          .if("err := s.send(item); err != nil", (b) => {
+            b.return("err");
+          })
+          .u()
+          .l("}")
+          .return("nil");
+      });
+
+    w.comment(
+      "collectStream returns a Stream appending the items sent to items.",
+    )
+      .n()
+      .func("collectStream[T any](items *[]T) *Stream[T]", (b) => {
+        b.l("return &Stream[T]{send: func(item T) error {")
+          .i()
+          .l("*items = append(*items, item)")
+          .return("nil")
+          .u()
+          .l("}}");
+      });
+
+    w.comment("streamTo returns a Stream writing the items sent to s.")
+      .n()
+      .func("streamTo[T any](s *httpStream) *Stream[T]", (b) => {
+        b.l("return &Stream[T]{send: func(item T) error {")
+          .i()
+          .return("s.write(item)")
+          .u()
+          .l("}}");
+      });
+  }
+
+  private generateHTTPStream(w: GoBuilder, hasRest: boolean): void {
+    w.comment(
+      "httpStream writes a streamed result to the response as its items are sent: as",
+    )
+      .comment(
+        "the JSON result, its array written one item at a time with chunked encoding,",
+      )
+      .comment(
+        "or as NDJSON, one item per line, when the client accepts application/x-ndjson.",
+      )
+      .comment(
+        "NDJSON carries the items only, so the other fields of the output are dropped.",
+      )
+      .comment(
+        "Streams are always JSON; codecs, compression and ETags don't apply to them.",
+      )
+      .struct("httpStream", (b) => {
+        b.l("w        http.ResponseWriter")
+          .l("ctx      context.Context")
+          .l("field    string")
+          .l("ndjson   bool")
+          .l("envelope bool")
+          .l("started  bool");
+      });
+
+    w.comment(
+      "newHTTPStream returns the stream of the response to req, whose items are the",
+    )
+      .comment("output field named field.")
+      .n()
+      .func(
+        "newHTTPStream(w http.ResponseWriter, req *http.Request, field string) *httpStream",
+        (b) => {
+          b.decl("stream", "&httpStream{")
+            .i()
+            .l("w:        w,")
+            .l("ctx:      req.Context(),")
+            .l("field:    field,")
+            .l("ndjson:   acceptsNDJSON(req),")
+            .l("envelope: true,")
+            .u()
+            .l("}");
+          if (hasRest) {
+            b.comment("RESTHandler results are written without the envelope")
+              .if("_, ok := restCallFrom(req); ok", (b) => {
+                b.l("stream.envelope = false");
+              });
+          }
+          b.return("stream");
+        },
+      );
+
+    w.comment(
+      "acceptsNDJSON reports whether the Accept header of req lists application/x-ndjson.",
+    )
+      .n()
+      .func("acceptsNDJSON(req *http.Request) bool", (b) => {
+        b.l(
+          'for _, value := range strings.Split(req.Header.Get("Accept"), ",") {',
+        )
+          .i()
+          .l("mediaType, _, err := mime.ParseMediaType(value)")
+          .if('err == nil && mediaType == "application/x-ndjson"', (b) => {
+            b.return("true");
+          })
+          .u()
+          .l("}")
+          .return("false");
+      });
+
+    w.comment(
+      "start writes the headers and, for JSON, the result up to the opening of its",
+    )
+      .comment("array.")
+      .n()
+      .method("s *httpStream", "start", "", "error", (b) => {
+        b.l("s.started = true")
+          .if("s.ndjson", (b) => {
+            b.l('s.w.Header().Set("Content-Type", "application/x-ndjson")')
+              .l("s.w.WriteHeader(http.StatusOK)")
+              .return("nil");
+          })
+          .l('s.w.Header().Set("Content-Type", "application/json")')
+          .l("s.w.WriteHeader(http.StatusOK)")
+          .decl("key, _", "json.Marshal(s.field)")
+          .decl("prefix", '"{" + string(key) + ":["')
+          .if("s.envelope", (b) => {
+            b.l('prefix = `{"result":` + prefix');
+          })
+          .decl("_, err", "io.WriteString(s.w, prefix)")
+          .return("err");
+      });
+
+    w.comment(
+      "write writes an item, starting the response with the first. The response",
+    )
+      .comment(
+        "writer buffers the writes, sending a chunk to the client whenever it fills.",
+      )
+      .n()
+      .method("s *httpStream", "write", "item interface{}", "error", (b) => {
+        b.if("err := s.ctx.Err(); err != nil", (b) => {
+          b.return("err");
+        })
+          .decl("data, err", "json.Marshal(item)")
+          .ifErr((b) => {
+            b.return("err");
+          })
+          .l("switch {")
+          .l("case !s.started:")
+          .i()
+          .if("err := s.start(); err != nil", (b) => {
+            b.return("err");
+          })
+          .u()
+          .l("case !s.ndjson:")
+          .i()
+          .l("data = append([]byte{','}, data...)")
+          .u()
+          .l("}")
+          .if("s.ndjson", (b) => {
+            b.l("data = append(data, '\\n')");
+          })
+          .l("_, err = s.w.Write(data)")
+          .return("err");
+      });
+
+    w.comment(
+      "finish ends the stream with the handler's output, whose other fields follow",
+    )
+      .comment(
+        "the items in a JSON result, or with its error, reported in-band once items",
+      )
+      .comment(
+        "were written. If none were, it returns err for the call to be answered like",
+      )
+      .comment("any failed one.")
+      .n()
+      .method(
+        "s *httpStream",
+        "finish",
+        "output interface{}, err error",
+        "error",
+        (b) => {
+          b.if("!s.started", (b) => {
+            b.ifErr((b) => {
+              b.return("err");
+            }).if("err := s.start(); err != nil", (b) => {
+              b.return("err");
+            });
+          })
+            .n()
+            .var("tail", "bytes.Buffer")
+            .if("!s.ndjson", (b) => {
+              b.l("tail.WriteByte(']')")
+                .if("err == nil", (b) => {
+                  b.decl("fields, tailErr", "streamTail(output, s.field)")
+                    .l("tail.Write(fields)")
+                    .l("err = tailErr");
+                })
+                .if("s.envelope", (b) => {
+                  b.l("tail.WriteByte('}')");
+                });
+            })
+            .ifErr((b) => {
+              b.decl("data, _", "json.Marshal(AsError(err))")
+                .decl("separator", "byte(',')")
+                .if("s.ndjson", (b) => {
+                  b.l("separator = '{'");
+                })
+                .l("tail.WriteByte(separator)")
+                .l('tail.WriteString(`"error":`)')
+                .l("tail.Write(data)");
+            })
+            .if("!s.ndjson || err != nil", (b) => {
+              b.l('tail.WriteString("}\\n")');
+            })
+            .decl("_, writeErr", "s.w.Write(tail.Bytes())")
+            .return("writeErr");
+        },
+      );
+
+    w.comment(
+      "streamTail encodes the fields of output other than field, each preceded by a",
+    )
+      .comment("comma, for them to follow the streamed array in order of name.")
+      .n()
+      .func(
+        "streamTail(output interface{}, field string) ([]byte, error)",
+        (b) => {
+          b.decl("data, err", "json.Marshal(output)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .var("fields", "map[string]json.RawMessage")
+            .if("err := json.Unmarshal(data, &fields); err != nil", (b) => {
+              b.return("nil, err");
+            })
+            .l("delete(fields, field)")
+            .decl("keys", "make([]string, 0, len(fields))")
+            .l("for key := range fields {")
+            .i()
+            .l("keys = append(keys, key)")
+            .u()
+            .l("}")
+            .l("sort.Strings(keys)")
+            .n()
+            .var("tail", "bytes.Buffer")
+            .l("for _, key := range keys {")
+            .i()
+            .decl("name, _", "json.Marshal(key)")
+            .l("tail.WriteByte(',')")
+            .l("tail.Write(name)")
+            .l("tail.WriteByte(':')")
+            .l("tail.Write(fields[key])")
+            .u()
+            .l("}")
+            .return("tail.Bytes(), nil");
+        },
+      );
+  }
+
+  private generateInvokeStream(w: GoBuilder): void {
+    w.comment(
+      "invokeStream calls the handler of a streamed method through the interceptors,",
+    )
+      .comment(
+        "within its concurrency limits. Its items are written as they are sent, so",
+      )
+      .comment(
+        "unlike invoke it runs the handler in the request's goroutine: a timeout",
+      )
+      .comment(
+        "cancels ctx, failing the sends after it, and the call with DEADLINE_EXCEEDED.",
+      )
+      .n()
+      .method(
+        "r *Router",
+        "invokeStream",
+        "ctx context.Context, info RequestInfo, input interface{}, stream *httpStream, handler NextFunc",
+        "(interface{}, error)",
+        (b) => {
+          b.decl("release, err", "r.acquire(ctx, info.Method)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("defer release()")
+            .if("timeout := r.timeoutFor(info.Method); timeout > 0", (b) => {
+            b.var("cancel", "context.CancelFunc")
+              .l("ctx, cancel = context.WithTimeout(ctx, timeout)")
+              .l("defer cancel()");
+          })
+            .l("stream.ctx = ctx")
+            .n()
+            .decl("result, err", "r.intercept(info, input, handler)(ctx)")
+            .if("ctx.Err() == context.DeadlineExceeded", (b) => {
+              b.return(
+                'nil, NewError(CodeDeadlineExceeded, "Deadline exceeded")',
+              );
+            })
+            .return("result, err");
+        },
+      );
+  }
+}
+
+/**
+ * Reports whether any endpoint of the contract is declared with a stream
+ * option.
+ */
+export function usesStreams(contract: ContractDefinition): boolean {
+  return contract.endpoints.some((endpoint) => !!endpoint.stream);
+}
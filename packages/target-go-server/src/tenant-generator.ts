@@ -0,0 +1,313 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates tenant.go: the tenant of a call as a typed context value,
+ * ResolveTenant middleware finding it in a header, the subdomain or a claim
+ * of the caller's token, and TenantRateLimit limiting the calls of each
+ * tenant. Methods declared with tenant: "required" reject calls without a
+ * tenant in the router, and the Logger and Prometheus metrics record it.
+ */
+export class GoTenantGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateTenant(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "errors",
+      "math",
+      "net",
+      "strings",
+      "sync",
+      "time",
+    );
+
+    this.generateTenantContext(w);
+    this.generateResolvers(w);
+    this.generateResolveTenant(w);
+    this.generateRateLimit(w);
+
+    return w.toString();
+  }
+
+  private generateTenantContext(w: GoBuilder): void {
+    w.comment(
+      "Tenant is the tenant, such as a customer organization, whose data a call works",
+    )
+      .comment("on.")
+      .struct("Tenant", (b) => {
+        b.comment("ID identifies the tenant.").l("ID string");
+      });
+
+    w.comment("tenantKey is the context key of the tenant of a call.")
+      .l("type tenantKey struct{}")
+      .n();
+
+    w.comment("WithTenant returns a copy of ctx carrying tenant.")
+      .n()
+      .func(
+        "WithTenant(ctx context.Context, tenant Tenant) context.Context",
+        (b) => {
+          b.return("context.WithValue(ctx, tenantKey{}, tenant)");
+        },
+      );
+
+    w.comment("TenantFrom returns the tenant stored in ctx by WithTenant, if any.")
+      .n()
+      .func("TenantFrom(ctx context.Context) (Tenant, bool)", (b) => {
+        b.decl("tenant, ok", "ctx.Value(tenantKey{}).(Tenant)").return(
+          "tenant, ok",
+        );
+      });
+  }
+
+  private generateResolvers(w: GoBuilder): void {
+    w.comment(
+      'TenantResolver returns the ID of the tenant a request names, or "" if it names',
+    )
+      .comment("none.")
+      .type(
+        "TenantResolver",
+        "func(ctx context.Context, info RequestInfo) string",
+      )
+      .n();
+
+    w.comment(
+      "TenantHeader resolves the tenant from the request header name, such as",
+    )
+      .comment("X-Tenant-ID.")
+      .n()
+      .func("TenantHeader(name string) TenantResolver", (b) => {
+        b.l("return func(ctx context.Context, info RequestInfo) string {")
+          .i()
+          .if("info.Request == nil", (b) => {
+            b.return('""');
+          })
+          .return("strings.TrimSpace(info.Request.Header.Get(name))")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "TenantSubdomain resolves the tenant from the subdomain of domain the request",
+    )
+      .comment(
+        'was sent to: "acme" for acme.example.com with domain "example.com". Hosts',
+      )
+      .comment("outside domain or with several labels before it name no tenant.")
+      .n()
+      .func("TenantSubdomain(domain string) TenantResolver", (b) => {
+        b.decl("suffix", '"." + strings.ToLower(domain)')
+          .l("return func(ctx context.Context, info RequestInfo) string {")
+          .i()
+          .if("info.Request == nil", (b) => {
+            b.return('""');
+          })
+          .decl("host", "info.Request.Host")
+          .if("name, _, err := net.SplitHostPort(host); err == nil", (b) => {
+            b.l("host = name");
+          })
+          .decl("host, ok", "strings.CutSuffix(strings.ToLower(host), suffix)")
+          .if('!ok || host == "" || strings.Contains(host, ".")', (b) => {
+            b.return('""');
+          })
+          .return("host")
+          .u()
+          .l("}");
+      });
+
+    w.comment(
+      "TenantClaim resolves the tenant from the string claim of the Principal an",
+    )
+      .comment(
+        "authentication middleware such as JWTAuth accepted, so it must come after",
+      )
+      .comment("that middleware.")
+      .n()
+      .func("TenantClaim(claim string) TenantResolver", (b) => {
+        b.l("return func(ctx context.Context, info RequestInfo) string {")
+          .i()
+          .decl("principal, _", "PrincipalFrom(ctx)")
+          .decl("tenant, _", "principal.Claims[claim].(string)")
+          .return("tenant")
+          .u()
+          .l("}");
+      });
+  }
+
+  private generateResolveTenant(w: GoBuilder): void {
+    w.comment(
+      "ResolveTenant returns middleware setting the tenant of each call, for handlers",
+    )
+      .comment(
+        "to get with TenantFrom, to the first tenant resolvers find. Headers and",
+      )
+      .comment(
+        "subdomains are chosen by the client, so verify checks that the caller belongs",
+      )
+      .comment(
+        "to the tenant, such as against its principal, and that the tenant exists; a",
+      )
+      .comment(
+        "*Error it returns is sent as is, other errors as PERMISSION_DENIED. Calls",
+      )
+      .comment(
+        "naming no tenant continue without one, which methods declared with",
+      )
+      .comment('tenant: "required" reject:')
+      .comment("")
+      .comment("    router.Use(JWTAuth(keys, nil))")
+      .comment("    router.Use(ResolveTenant(verifyMember,")
+      .comment('        TenantClaim("tenant"), TenantHeader("X-Tenant-ID")))')
+      .n()
+      .func(
+        "ResolveTenant(verify func(ctx context.Context, tenant Tenant) error, resolvers ...TenantResolver) MiddlewareFunc",
+        (b) => {
+          b.l(
+            "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+          )
+            .i()
+            .l("for _, resolve := range resolvers {")
+            .i()
+            .decl("tenant", "Tenant{ID: resolve(ctx, info)}")
+            .if('tenant.ID == ""', (b) => {
+              b.l("continue");
+            })
+            .if("verify != nil", (b) => {
+              b.if("err := verify(ctx, tenant); err != nil", (b) => {
+                b.return("NewMiddlewareError(tenantError(err))");
+              });
+            })
+            .return("NewMiddlewareResult(WithTenant(ctx, tenant))")
+            .u()
+            .l("}")
+            .return("NewMiddlewareResult(ctx)")
+            .u()
+            .l("}");
+        },
+      );
+
+    w.comment(
+      "tenantError is the error sent for a tenant verify rejected: err if it is an",
+    )
+      .comment("*Error, PERMISSION_DENIED otherwise.")
+      .n()
+      .func("tenantError(err error) *Error", (b) => {
+        b.var("e", "*Error")
+          .if("errors.As(err, &e)", (b) => {
+            b.return("e");
+          })
+          .return('NewError(CodePermissionDenied, "Tenant not allowed")');
+      });
+  }
+
+  private generateRateLimit(w: GoBuilder): void {
+    w.comment(
+      "TenantLimit is the rate the calls of a tenant are limited to: PerSecond calls",
+    )
+      .comment(
+        "a second on average, in bursts of up to Burst calls. A zero PerSecond leaves",
+      )
+      .comment("the tenant unlimited.")
+      .struct("TenantLimit", (b) => {
+        b.l("PerSecond float64").l("Burst     int");
+      });
+
+    w.comment(
+      "tenantBucket holds the tokens of a tenant, refilled at its rate, and when it",
+    )
+      .comment("is full again.")
+      .struct("tenantBucket", (b) => {
+        b.l("tokens  float64").l("updated time.Time").l("full    time.Time");
+      });
+
+    w.comment(
+      "TenantRateLimit returns middleware limiting the calls of each tenant to the",
+    )
+      .comment(
+        "TenantLimit that limit returns for it, such as by its plan, with a token",
+      )
+      .comment(
+        "bucket per tenant. Calls over it fail with RESOURCE_EXHAUSTED and a",
+      )
+      .comment(
+        "Retry-After for when the next one is allowed. It must come after",
+      )
+      .comment("ResolveTenant; calls without a tenant are not limited:")
+      .comment("")
+      .comment("    router.Use(TenantRateLimit(func(tenant Tenant) TenantLimit {")
+      .comment("        return TenantLimit{PerSecond: 50, Burst: 100}")
+      .comment("    }))")
+      .n()
+      .func(
+        "TenantRateLimit(limit func(tenant Tenant) TenantLimit) MiddlewareFunc",
+        (b) => {
+          b.var("mu", "sync.Mutex")
+            .decl("buckets", "map[string]*tenantBucket{}")
+            .comment(
+              "The number of buckets after the last sweep of full ones, which are",
+            )
+            .comment("the same as new ones")
+            .decl("swept", "0")
+            .l(
+              "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+            )
+            .i()
+            .decl("tenant, ok", "TenantFrom(ctx)")
+            .if("!ok", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .decl("rate", "limit(tenant)")
+            .if("rate.PerSecond <= 0", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .decl("burst", "math.Max(float64(rate.Burst), 1)")
+            .decl("now", "time.Now()")
+            .l("mu.Lock()")
+            .l("defer mu.Unlock()")
+            .decl("bucket, ok", "buckets[tenant.ID]")
+            .if("!ok", (b) => {
+              b.l("bucket = &tenantBucket{tokens: burst, updated: now}").l(
+                "buckets[tenant.ID] = bucket",
+              );
+            })
+            .l(
+              "bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate.PerSecond)",
+            )
+            .l("bucket.updated = now")
+            .if("bucket.tokens < 1", (b) => {
+              b.decl(
+                "wait",
+                "time.Duration((1 - bucket.tokens) / rate.PerSecond * float64(time.Second))",
+              ).return(
+                'NewMiddlewareError(NewError(CodeResourceExhausted, "Tenant rate limit exceeded").WithDetails(RetryInfo{RetryAfterMs: wait.Milliseconds() + 1}))',
+              );
+            })
+            .l("bucket.tokens--")
+            .l(
+              "bucket.full = now.Add(time.Duration((burst - bucket.tokens) / rate.PerSecond * float64(time.Second)))",
+            )
+            .if("len(buckets) > 2*swept", (b) => {
+              b.l("for id, bucket := range buckets {")
+                .i()
+                .if("!now.Before(bucket.full)", (b) => {
+                  b.l("delete(buckets, id)");
+                })
+                .u()
+                .l("}")
+                .l("swept = len(buckets)");
+            })
+            .return("NewMiddlewareResult(ctx)")
+            .u()
+            .l("}");
+        },
+      );
+  }
+}
@@ -0,0 +1,317 @@
+import type { ContractDefinition } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import { toMethodName } from "./server-generator";
+
+/**
+ * Generates testclient.go: a TestClient calling a Router in process through
+ * httptest, with a typed method per endpoint, so handler integration tests
+ * don't hand-craft JSON envelopes.
+ */
+export class GoTestClientGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateTestClient(contract: ContractDefinition): string {
+    const w = this.w.reset();
+    const hasSubscriptions = contract.endpoints.some(
+      (endpoint) => endpoint.type === "subscription",
+    );
+
+    const imports = [
+      "bytes",
+      "context",
+      "encoding/json",
+      "fmt",
+      "net/http",
+      "net/http/httptest",
+    ];
+    if (hasSubscriptions) {
+      imports.unshift("bufio");
+      imports.push("strings");
+    }
+    w.package(this.packageName).import(...imports);
+
+    w.comment(
+      "TestClient calls a Router in process through httptest, with a typed method per",
+    )
+      .comment(
+        "endpoint. Errors the router answers with are returned as *Error, so tests can",
+      )
+      .comment("check their code.")
+      .struct("TestClient", (b) => {
+        b.l("router *Router")
+          .comment("Header is sent with every call, e.g. to authenticate.")
+          .l("Header http.Header");
+      });
+
+    w.comment("NewTestClient returns a TestClient calling router.")
+      .n()
+      .func("NewTestClient(router *Router) *TestClient", (b) => {
+        b.return("&TestClient{router: router, Header: http.Header{}}");
+      });
+
+    for (const endpoint of contract.endpoints) {
+      const name = toMethodName(endpoint.fullName);
+      const inputType = toPascalCase(endpoint.input.name!);
+      const outputType = toPascalCase(endpoint.output.name!);
+      const method = JSON.stringify(endpoint.fullName);
+
+      if (endpoint.type === "subscription") {
+        w.comment(
+          `${name} calls ${endpoint.fullName} and returns the events the handler sent before`,
+        )
+          .comment(
+            "it returned; cancel ctx to end subscriptions that stream until cancelled.",
+          )
+          .n()
+          .method(
+            "c *TestClient",
+            name,
+            `ctx context.Context, input ${inputType}`,
+            `([]${outputType}, error)`,
+            (b) => {
+              b.var("events", `[]${outputType}`)
+                .l(
+                  `err := c.subscribe(ctx, ${method}, input, func(data []byte) error {`,
+                )
+                .i()
+                .var("event", outputType)
+                .if("err := json.Unmarshal(data, &event); err != nil", (b) => {
+                  b.return("err");
+                })
+                .l("events = append(events, event)")
+                .return("nil")
+                .u()
+                .l("})")
+                .return("events, err");
+            },
+          );
+        continue;
+      }
+
+      if (endpoint.async) {
+        w.comment(
+          `${name} calls ${endpoint.fullName}, which answers with the Operation running it;`,
+        )
+          .comment("the router's GetOperation returns it with its result once done.")
+          .n()
+          .method(
+            "c *TestClient",
+            name,
+            `ctx context.Context, input ${inputType}`,
+            "(Operation, error)",
+            (b) => {
+              b.var("op", "Operation")
+                .l(`err := c.call(ctx, ${method}, input, &op)`)
+                .return("op, err");
+            },
+          );
+        continue;
+      }
+
+      w.comment(`${name} calls ${endpoint.fullName}.`)
+        .n()
+        .method(
+          "c *TestClient",
+          name,
+          `ctx context.Context, input ${inputType}`,
+          `(${outputType}, error)`,
+          (b) => {
+            b.var("output", outputType)
+              .l(`err := c.call(ctx, ${method}, input, &output)`)
+              .return("output, err");
+          },
+        );
+    }
+
+    w.comment(
+      "do POSTs a call of method with input to the router and returns the response.",
+    )
+      .n()
+      .method(
+        "c *TestClient",
+        "do",
+        "ctx context.Context, method string, input interface{}",
+        "(*httptest.ResponseRecorder, error)",
+        (b) => {
+          b.l("body, err := json.Marshal(struct {")
+            .i()
+            .l('Method string      `json:"method"`')
+            .l('Params interface{} `json:"params"`')
+            .u()
+            .l("}{method, input})")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .decl(
+              "req",
+              'httptest.NewRequest(http.MethodPost, "/api", bytes.NewReader(body)).WithContext(ctx)',
+            )
+            .l('req.Header.Set("Content-Type", "application/json")')
+            .l("for key, values := range c.Header {")
+            .i()
+            .l("req.Header[key] = values")
+            .u()
+            .l("}")
+            .decl("rec", "httptest.NewRecorder()")
+            .l("c.router.ServeHTTP(rec, req)")
+            .return("rec, nil");
+        },
+      );
+
+    w.comment(
+      "call calls method with input and decodes its result into output.",
+    )
+      .n()
+      .method(
+        "c *TestClient",
+        "call",
+        "ctx context.Context, method string, input, output interface{}",
+        "error",
+        (b) => {
+          b.l("rec, err := c.do(ctx, method, input)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .if("rec.Code != http.StatusOK", (b) => {
+              b.return("responseError(method, rec.Body.Bytes())");
+            })
+            .var(
+              "response",
+              'struct {\n\t\tResult json.RawMessage  `json:"result"`\n\t\tError  *json.RawMessage `json:"error"`\n\t}',
+            )
+            .if(
+              "err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil",
+              (b) => {
+                b.return(
+                  'fmt.Errorf("decoding %s response: %w", method, err)',
+                );
+              },
+            )
+            .comment("Errors kept in-band with SetErrorStatus come with 200")
+            .if("response.Error != nil", (b) => {
+              b.return("responseError(method, rec.Body.Bytes())");
+            })
+            .return("json.Unmarshal(response.Result, output)");
+        },
+      );
+
+    if (hasSubscriptions) {
+      w.comment(
+        "subscribe calls method with input and passes the data of every event the",
+      )
+        .comment("handler sent to onEvent, stopping at an error event.")
+        .n()
+        .method(
+          "c *TestClient",
+          "subscribe",
+          "ctx context.Context, method string, input interface{}, onEvent func(data []byte) error",
+          "error",
+          (b) => {
+            b.l("rec, err := c.do(ctx, method, input)")
+              .ifErr((b) => {
+                b.return("err");
+              })
+              .if(
+                'rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream")',
+                (b) => {
+                  b.return("responseError(method, rec.Body.Bytes())");
+                },
+              )
+              .var("event", "string")
+              .decl("scanner", "bufio.NewScanner(rec.Body)")
+              .l("scanner.Buffer(nil, 16<<20)")
+              .l("for scanner.Scan() {")
+              .i()
+              .decl("line", "scanner.Text()")
+              .l("switch {")
+              .l('case strings.HasPrefix(line, "event: "):')
+              .i()
+              .l('event = strings.TrimPrefix(line, "event: ")')
+              .u()
+              .l('case strings.HasPrefix(line, "data: "):')
+              .i()
+              .decl("data", '[]byte(strings.TrimPrefix(line, "data: "))')
+              .if('event == "error"', (b) => {
+                b.return("responseError(method, data)");
+              })
+              .if("err := onEvent(data); err != nil", (b) => {
+                b.return("err");
+              })
+              .l('event = ""')
+              .u()
+              .l("}")
+              .u()
+              .l("}")
+              .return("scanner.Err()");
+          },
+        );
+    }
+
+    w.comment(
+      "responseError returns the *Error of an error envelope. The details of a",
+    )
+      .comment(
+        "CodeInvalidArgument listing field errors are decoded into ValidationErrors,",
+      )
+      .comment("which errors.As finds through the *Error.")
+      .n()
+      .func("responseError(method string, body []byte) error", (b) => {
+        b.var(
+          "response",
+          'struct {\n\t\tError *struct {\n\t\t\tCode    ErrorCode       `json:"code"`\n\t\t\tMessage string          `json:"message"`\n\t\t\tDetails json.RawMessage `json:"details"`\n\t\t} `json:"error"`\n\t}',
+        )
+          .if(
+            "err := json.Unmarshal(body, &response); err != nil || response.Error == nil",
+            (b) => {
+              b.return('fmt.Errorf("unexpected %s response: %s", method, body)');
+            },
+          )
+          .decl(
+            "callErr",
+            "&Error{Code: response.Error.Code, Message: response.Error.Message}",
+          )
+          .if("len(response.Error.Details) == 0", (b) => {
+            b.return("callErr");
+          })
+          .if("callErr.Code == CodeInvalidArgument", (b) => {
+            b.var("validationErrs", "ValidationErrors")
+              .if(
+                "json.Unmarshal(response.Error.Details, &validationErrs) == nil && fieldErrors(validationErrs)",
+                (b) => {
+                  b.l("callErr.Details = validationErrs").return("callErr");
+                },
+              );
+          })
+          .l("json.Unmarshal(response.Error.Details, &callErr.Details)")
+          .return("callErr");
+      });
+
+    w.comment(
+      "fieldErrors reports whether errs decoded from details are field errors,",
+    )
+      .comment("rather than details of another shape.")
+      .n()
+      .func("fieldErrors(errs ValidationErrors) bool", (b) => {
+        b.if("len(errs) == 0", (b) => {
+          b.return("false");
+        })
+          .l("for _, err := range errs {")
+          .i()
+          .if('err == nil || err.Field == "" || err.Message == ""', (b) => {
+            b.return("false");
+          })
+          .u()
+          .l("}")
+          .return("true");
+      });
+
+    return w.toString();
+  }
+}
@@ -0,0 +1,275 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates tls.go: ListenAndServeTLS serving a router over HTTPS, with
+ * mutual TLS when client CAs are configured, and the identity of a verified
+ * client certificate as a typed context accessor and as the Principal of the
+ * ClientCertAuth middleware, for service-to-service authentication.
+ */
+export class GoTLSGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateTLS(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "context",
+      "crypto/tls",
+      "crypto/x509",
+      "fmt",
+      "net/http",
+      "os",
+    );
+
+    this.generateOptions(w);
+    this.generateListenAndServe(w);
+    this.generateClientIdentity(w);
+    this.generateClientCertAuth(w);
+
+    return w.toString();
+  }
+
+  private generateOptions(w: GoBuilder): void {
+    w.comment(
+      "TLSOptions configures ListenAndServeTLS. Setting ClientCAFile or ClientCAs",
+    )
+      .comment(
+        "turns on mutual TLS: clients must present a certificate signed by one of the",
+      )
+      .comment("CAs, unless ClientCertOptional is set.")
+      .struct("TLSOptions", (b) => {
+        b.comment(
+          "CertFile and KeyFile hold the server's PEM certificate chain and key.",
+        )
+          .l("CertFile string")
+          .l("KeyFile  string")
+          .comment(
+            "ClientCAFile holds the PEM certificates of the CAs client certificates",
+          )
+          .comment("are verified against.")
+          .l("ClientCAFile string")
+          .comment("ClientCAs is used instead of ClientCAFile when set.")
+          .l("ClientCAs *x509.CertPool")
+          .comment(
+            "ClientCertOptional accepts connections without a client certificate,",
+          )
+          .comment(
+            "still verifying those that present one; ClientCertAuth leaves their",
+          )
+          .comment("calls unauthenticated.")
+          .l("ClientCertOptional bool")
+          .comment("MinVersion is the lowest TLS version accepted, TLS 1.2 if zero.")
+          .l("MinVersion uint16");
+      });
+
+    w.comment(
+      "TLSConfig returns the tls.Config of options, for servers configured by the",
+    )
+      .comment("application rather than by ListenAndServeTLS.")
+      .n()
+      .method(
+        "options TLSOptions",
+        "TLSConfig",
+        "",
+        "(*tls.Config, error)",
+        (b) => {
+          b.decl("config", "&tls.Config{MinVersion: tls.VersionTLS12}")
+            .if("options.MinVersion != 0", (b) => {
+              b.l("config.MinVersion = options.MinVersion");
+            })
+            .decl("pool", "options.ClientCAs")
+            .if('pool == nil && options.ClientCAFile != ""', (b) => {
+              b.decl("data, err", "os.ReadFile(options.ClientCAFile)")
+                .ifErr((b) => {
+                  b.return('nil, fmt.Errorf("read client CAs: %w", err)');
+                })
+                .l("pool = x509.NewCertPool()")
+                .if("!pool.AppendCertsFromPEM(data)", (b) => {
+                  b.return(
+                    'nil, fmt.Errorf("no certificates in %s", options.ClientCAFile)',
+                  );
+                });
+            })
+            .if("pool == nil", (b) => {
+              b.return("config, nil");
+            })
+            .l("config.ClientCAs = pool")
+            .l("config.ClientAuth = tls.RequireAndVerifyClientCert")
+            .if("options.ClientCertOptional", (b) => {
+              b.l("config.ClientAuth = tls.VerifyClientCertIfGiven");
+            })
+            .return("config, nil");
+        },
+      );
+  }
+
+  private generateListenAndServe(w: GoBuilder): void {
+    w.comment(
+      "ListenAndServeTLS serves handler, such as a Router, over HTTPS on addr with",
+    )
+      .comment(
+        "the TLS configuration of options, like http.ListenAndServeTLS. Handlers",
+      )
+      .comment(
+        "read the identity of a verified client certificate with ClientIdentityFrom,",
+      )
+      .comment("or as the Principal of ClientCertAuth:")
+      .comment("")
+      .comment("    router.Use(ClientCertAuth(nil))")
+      .comment('    log.Fatal(ListenAndServeTLS(":8443", router, TLSOptions{')
+      .comment('        CertFile:     "server.pem",')
+      .comment('        KeyFile:      "server-key.pem",')
+      .comment('        ClientCAFile: "services-ca.pem",')
+      .comment("    }))")
+      .n()
+      .func(
+        "ListenAndServeTLS(addr string, handler http.Handler, options TLSOptions) error",
+        (b) => {
+          b.decl("config, err", "options.TLSConfig()")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .decl(
+              "server",
+              "&http.Server{Addr: addr, Handler: handler, TLSConfig: config}",
+            )
+            .return("server.ListenAndServeTLS(options.CertFile, options.KeyFile)");
+        },
+      );
+  }
+
+  private generateClientIdentity(w: GoBuilder): void {
+    w.comment(
+      "ClientIdentity is the identity of the client certificate verified for a",
+    )
+      .comment("mutual TLS connection.")
+      .struct("ClientIdentity", (b) => {
+        b.comment("CommonName and Organizations are from the certificate's subject.")
+          .l("CommonName    string")
+          .l("Organizations []string")
+          .comment(
+            "DNSNames, URIs and EmailAddresses are its subject alternative names; URIs",
+          )
+          .comment("hold SPIFFE IDs such as spiffe://example.org/ns/prod/sa/billing.")
+          .l("DNSNames       []string")
+          .l("URIs           []string")
+          .l("EmailAddresses []string")
+          .comment("Certificate is the verified certificate.")
+          .l("Certificate *x509.Certificate");
+      });
+
+    w.comment(
+      "ID identifies the client: its first URI, such as a SPIFFE ID, or else its",
+    )
+      .comment("common name.")
+      .n()
+      .method("c ClientIdentity", "ID", "", "string", (b) => {
+        b.if("len(c.URIs) > 0", (b) => {
+          b.return("c.URIs[0]");
+        }).return("c.CommonName");
+      });
+
+    w.comment(
+      "ClientIdentityFrom returns the identity of the client certificate verified for",
+    )
+      .comment(
+        "the connection of the call ctx belongs to, if the client presented one.",
+      )
+      .n()
+      .func(
+        "ClientIdentityFrom(ctx context.Context) (ClientIdentity, bool)",
+        (b) => {
+          b.decl("info, ok", "RequestInfoFrom(ctx)")
+            .if("!ok", (b) => {
+              b.return("ClientIdentity{}, false");
+            })
+            .return("clientIdentity(info.Request)");
+        },
+      );
+
+    w.comment(
+      "clientIdentity returns the identity of the leaf of the first verified chain",
+    )
+      .comment(
+        "of req; certificates the server did not verify carry no identity.",
+      )
+      .n()
+      .func(
+        "clientIdentity(req *http.Request) (ClientIdentity, bool)",
+        (b) => {
+          b.if(
+            "req == nil || req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0",
+            (b) => {
+              b.return("ClientIdentity{}, false");
+            },
+          )
+            .decl("cert", "req.TLS.VerifiedChains[0][0]")
+            .decl("identity", "ClientIdentity{")
+            .i()
+            .l("CommonName:     cert.Subject.CommonName,")
+            .l("Organizations:  cert.Subject.Organization,")
+            .l("DNSNames:       cert.DNSNames,")
+            .l("EmailAddresses: cert.EmailAddresses,")
+            .l("Certificate:    cert,")
+            .u()
+            .l("}")
+            .l("for _, uri := range cert.URIs {")
+            .i()
+            .l("identity.URIs = append(identity.URIs, uri.String())")
+            .u()
+            .l("}")
+            .return("identity, true");
+        },
+      );
+  }
+
+  private generateClientCertAuth(w: GoBuilder): void {
+    w.comment(
+      "ClientCertAuth returns middleware authenticating requests with the client",
+    )
+      .comment(
+        "certificate verified by mutual TLS. identify maps its identity to the",
+      )
+      .comment(
+        "principal and may reject it, e.g. for a service not allowed to call this one;",
+      )
+      .comment(
+        "if it is nil, the principal's ID is the identity's ID. Requests without a",
+      )
+      .comment("verified certificate continue unauthenticated.")
+      .n()
+      .func(
+        "ClientCertAuth(identify func(ctx context.Context, identity ClientIdentity) (Principal, error)) MiddlewareFunc",
+        (b) => {
+          b.l(
+            "return func(ctx context.Context, info RequestInfo) *MiddlewareResult {",
+          )
+            .i()
+            .l("identity, ok := clientIdentity(info.Request)")
+            .if("!ok", (b) => {
+              b.return("NewMiddlewareResult(ctx)");
+            })
+            .decl("principal", "Principal{ID: identity.ID()}")
+            .if("identify != nil", (b) => {
+              b.var("err", "error")
+                .l("principal, err = identify(ctx, identity)")
+                .ifErr((b) => {
+                  b.return("NewMiddlewareError(authError(err))");
+                });
+            })
+            .if('principal.Scheme == ""', (b) => {
+              b.l('principal.Scheme = "mtls"');
+            })
+            .return("NewMiddlewareResult(WithPrincipal(ctx, principal))")
+            .u()
+            .l("}");
+        },
+      );
+  }
+}
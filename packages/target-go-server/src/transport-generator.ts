@@ -0,0 +1,140 @@
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates transport.go: the TransportOptions tuning the connection pool,
+ * TLS, proxy and HTTP/2 use of a Client's transport, which is a transport of
+ * its own rather than http.DefaultTransport, whose two idle connections per
+ * host make concurrent calls to one server redial.
+ */
+export class GoTransportGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  generateTransport(): string {
+    const w = this.w.reset();
+
+    w.package(this.packageName).import(
+      "crypto/tls",
+      "net",
+      "net/http",
+      "net/url",
+      "time",
+    );
+
+    w.comment(
+      "TransportOptions configures the HTTP transport of a Client, set with",
+    )
+      .comment(
+        "WithTransport. Zero fields keep the defaults, those of http.DefaultTransport",
+      )
+      .comment(
+        "but for MaxIdleConnsPerHost: 100 rather than 2, as a Client makes all its",
+      )
+      .comment("calls to the one server.")
+      .struct("TransportOptions", (b) => {
+        b.comment(
+          "MaxIdleConns bounds the idle keep-alive connections across hosts; 100",
+        )
+          .comment("by default")
+          .l("MaxIdleConns int")
+          .comment(
+            "MaxIdleConnsPerHost bounds the idle keep-alive connections to the",
+          )
+          .comment("server; 100 by default")
+          .l("MaxIdleConnsPerHost int")
+          .comment(
+            "MaxConnsPerHost bounds the connections to the server, in use or idle;",
+          )
+          .comment("calls beyond it wait. 0 leaves it unbounded")
+          .l("MaxConnsPerHost int")
+          .comment(
+            "IdleConnTimeout closes connections idle for longer; 90s by default",
+          )
+          .l("IdleConnTimeout time.Duration")
+          .comment(
+            "TLSConfig configures TLS, e.g. with RootCAs, or Certificates for mTLS",
+          )
+          .l("TLSConfig *tls.Config")
+          .comment(
+            "Proxy returns the proxy of a request; http.ProxyFromEnvironment by",
+          )
+          .comment("default")
+          .l("Proxy func(req *http.Request) (*url.URL, error)")
+          .comment(
+            "DisableHTTP2 keeps to HTTP/1.1. Otherwise HTTP/2 is negotiated with",
+          )
+          .comment("servers supporting it over TLS, a custom TLSConfig included")
+          .l("DisableHTTP2 bool");
+      });
+
+    w.comment(
+      "WithTransport sends the Client's requests through a transport configured by",
+    )
+      .comment("options.")
+      .n()
+      .func("WithTransport(options TransportOptions) ClientOption", (b) => {
+        b.l("return func(c *Client) {")
+          .i()
+          .l("c.httpClient = &http.Client{Transport: newTransport(options)}")
+          .u()
+          .l("}");
+      });
+
+    w.comment("newTransport returns a transport configured by options.")
+      .n()
+      .func("newTransport(options TransportOptions) *http.Transport", (b) => {
+        b.l("transport := &http.Transport{")
+          .i()
+          .l("Proxy: http.ProxyFromEnvironment,")
+          .l(
+            "DialContext: (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,",
+          )
+          .l("ForceAttemptHTTP2: !options.DisableHTTP2,")
+          .l("MaxIdleConns: 100,")
+          .l("MaxIdleConnsPerHost: 100,")
+          .l("MaxConnsPerHost: options.MaxConnsPerHost,")
+          .l("IdleConnTimeout: 90 * time.Second,")
+          .l("TLSClientConfig: options.TLSConfig,")
+          .l("TLSHandshakeTimeout: 10 * time.Second,")
+          .l("ExpectContinueTimeout: time.Second,")
+          .u()
+          .l("}")
+          .if("options.MaxIdleConns > 0", (b) => {
+            b.l("transport.MaxIdleConns = options.MaxIdleConns");
+          })
+          .if("options.MaxIdleConnsPerHost > 0", (b) => {
+            b.l("transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost");
+          })
+          .if("options.IdleConnTimeout > 0", (b) => {
+            b.l("transport.IdleConnTimeout = options.IdleConnTimeout");
+          })
+          .if("options.Proxy != nil", (b) => {
+            b.l("transport.Proxy = options.Proxy");
+          })
+          .if("options.DisableHTTP2", (b) => {
+            b.comment("A non-nil empty map turns HTTP/2 off")
+              .l(
+                "transport.TLSNextProto = make(map[string]func(authority string, conn *tls.Conn) http.RoundTripper)",
+              )
+              .if("options.TLSConfig != nil", (b) => {
+                b.comment(
+                  "Nor may the TLS handshake offer it, as a TLSConfig shared with a",
+                )
+                  .comment("server may")
+                  .l("transport.TLSClientConfig = options.TLSConfig.Clone()")
+                  .l(
+                    'transport.TLSClientConfig.NextProtos = []string{"http/1.1"}',
+                  );
+              });
+          })
+          .return("transport");
+      });
+
+    return w.toString();
+  }
+}
@@ -1,10 +1,16 @@
-import {
-  type ContractDefinition,
-  type Property,
-  type TypeDefinition,
-  type TypeReference,
-  toPascalCase,
+import type {
+  ContractDefinition,
+  Property,
+  TypeDefinition,
+  TypeReference,
 } from "@xrpckit/sdk";
+import { toPascalCase } from "./naming";
+import { goEnumConstNames } from "./patterns";
+import {
+  discriminatorValue,
+  isDiscriminatedUnion,
+  isStringEnum,
+} from "./type-mapper";
 
 export interface CollectedType {
   name: string;
@@ -25,6 +31,7 @@ export interface CollectedType {
 export class GoTypeCollector {
   private collectedTypes: Map<string, CollectedType> = new Map();
   private usedNames: Set<string> = new Set();
+  private namedUnions: Set<string> = new Set();
 
   /**
    * Collect all types from a contract that need Go struct generation.
@@ -37,6 +44,7 @@ export class GoTypeCollector {
   collectTypes(contract: ContractDefinition): CollectedType[] {
     this.collectedTypes.clear();
     this.usedNames.clear();
+    this.namedUnions.clear();
 
     // First pass: collect all explicitly named types to avoid collisions
     for (const type of contract.types) {
@@ -73,6 +81,44 @@ export class GoTypeCollector {
         `${contextName}.elementType`,
       );
     }
+
+    if (isDiscriminatedUnion(type)) {
+      this.processUnionVariants(
+        type as TypeReference,
+        toPascalCase(type.name),
+        contextName,
+      );
+    }
+  }
+
+  /**
+   * Name the inline variants of a discriminated union after the union and
+   * their discriminator value, e.g. NotificationTaskAssigned
+   */
+  private processUnionVariants(
+    typeRef: TypeReference,
+    unionName: string,
+    source: string,
+  ): void {
+    // A named union referenced from several fields only needs its variants
+    // named once
+    if (this.namedUnions.has(unionName)) {
+      return;
+    }
+    this.namedUnions.add(unionName);
+
+    const variants = typeRef.unionTypes ?? [];
+    const values = variants.map(
+      (variant) => discriminatorValue(variant, typeRef.discriminator!) ?? "",
+    );
+    const names = goEnumConstNames(unionName, values);
+    variants.forEach((variant, index) => {
+      this.processTypeReference(
+        variant,
+        names[index],
+        `${source}.union[${values[index]}]`,
+      );
+    });
   }
 
   /**
@@ -155,6 +201,19 @@ export class GoTypeCollector {
       return;
     }
 
+    // Handle unnamed string enums - these get a typed string named after
+    // the field, like inline objects
+    if (typeRef.kind === "enum" && !typeRef.name && isStringEnum(typeRef)) {
+      const assignedName = this.assignUniqueName(suggestedName);
+      typeRef.name = assignedName;
+      this.collectedTypes.set(assignedName, {
+        name: assignedName,
+        typeRef,
+        source,
+      });
+      return;
+    }
+
     // Handle records - process value type
     if (typeRef.kind === "record" && typeRef.valueType) {
       this.processTypeReference(
@@ -177,6 +236,22 @@ export class GoTypeCollector {
       return;
     }
 
+    // Handle discriminated unions - named after the field like inline
+    // objects, since they are generated as a wrapper type
+    if (isDiscriminatedUnion(typeRef)) {
+      if (!typeRef.name) {
+        const assignedName = this.assignUniqueName(suggestedName);
+        typeRef.name = assignedName;
+        this.collectedTypes.set(assignedName, {
+          name: assignedName,
+          typeRef,
+          source,
+        });
+      }
+      this.processUnionVariants(typeRef, toPascalCase(typeRef.name!), source);
+      return;
+    }
+
     // Handle unions - process each variant type
     if (typeRef.kind === "union" && typeRef.unionTypes) {
       typeRef.unionTypes.forEach((variant, index) => {
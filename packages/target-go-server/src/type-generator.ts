@@ -1,13 +1,24 @@
 import {
   type ContractDefinition,
+  type Endpoint,
+  type Normalization,
   type Property,
   type TypeDefinition,
   type TypeReference,
-  toPascalCase,
+  type TypeResult,
+  toSnakeCase,
+  type ValidationRules,
+  type WarningRules,
 } from "@xrpckit/sdk";
 import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
 import type { CollectedType } from "./type-collector";
-import { GoTypeMapper } from "./type-mapper";
+import {
+  GoTypeMapper,
+  isDiscriminatedUnion,
+  isStringEnum,
+  streamItemType,
+} from "./type-mapper";
 
 // Helper to convert "greeting.greet" to "GreetingGreet"
 function toMethodName(fullName: string): string {
@@ -17,16 +28,138 @@ function toMethodName(fullName: string): string {
     .join("");
 }
 
+// The namespace of an endpoint, "task" for task.get
+export function endpointNamespace(endpoint: Endpoint): string {
+  return endpoint.fullName.slice(0, endpoint.fullName.lastIndexOf("."));
+}
+
+// Canonical form of an object's shape. Object and enum names are dropped so
+// inline types collected under different names still compare equal, and so
+// are descriptions, which document a shape without changing it.
+export function shapeKey(properties: Property[]): string {
+  return JSON.stringify(properties, function (key, value) {
+    if (key === "name" && (this.kind === "object" || this.kind === "enum")) {
+      return undefined;
+    }
+    if (key === "description") {
+      return undefined;
+    }
+    return value;
+  });
+}
+
+/**
+ * A struct generated in types.go, described for the generators of code
+ * handling its fields, such as json.go.
+ */
+export interface GoStruct {
+  name: string;
+  fields: Array<{
+    name: string;
+    jsonName: string;
+    type: string;
+    omitEmpty: boolean;
+    // Marked with .meta({ sensitive: true }), see redact.go
+    sensitive: boolean;
+    // Transforms of the field's strings, or of the strings its arrays and
+    // records hold, see normalize.go
+    normalize: Normalization[];
+    // Given to the field when it is absent, see defaults.go
+    default?: string | number | boolean;
+    // Soft limits reported without rejecting the value, see warnings.go
+    warn?: WarningRules;
+    lengthUnit?: ValidationRules["lengthUnit"];
+  }>;
+}
+
+// The normalizations of a property: its own, or those of the items of its
+// arrays and the values of its records
+function propertyNormalizations(prop: Property): Normalization[] {
+  if (prop.validation?.normalize) {
+    return prop.validation.normalize;
+  }
+  let type: TypeReference | undefined = prop.type;
+  while (type) {
+    if (type.validation?.normalize) {
+      return type.validation.normalize;
+    }
+    type =
+      type.kind === "array"
+        ? type.elementType
+        : type.kind === "record"
+          ? type.valueType
+          : typeof type.baseType === "object"
+            ? type.baseType
+            : undefined;
+  }
+  return [];
+}
+
+// The default of a property whose Go type takes it as a literal: a pointer
+// to a string, number, boolean or string enum, but not to a time or date
+function goDefault(
+  prop: Property,
+  goType: string,
+): { default?: string | number | boolean } {
+  const value = prop.default;
+  let type: TypeReference = prop.type;
+  while (
+    (type.kind === "optional" || type.kind === "nullable") &&
+    typeof type.baseType === "object"
+  ) {
+    type = type.baseType;
+  }
+  const literalType: Record<string, string> = {
+    "*string": "string",
+    "*int": "number",
+    "*float64": "number",
+    "*bool": "boolean",
+  };
+  if (
+    value === undefined ||
+    !goType.startsWith("*") ||
+    (goType === "*int" && !Number.isInteger(value))
+  ) {
+    return {};
+  }
+  if (
+    literalType[goType] === typeof value ||
+    (isStringEnum(type) && typeof value === "string")
+  ) {
+    return { default: value };
+  }
+  return {};
+}
+
 export class GoTypeGenerator {
+  // The builder of the file the type being generated goes to
   private w: GoBuilder;
+  private main: GoBuilder;
   private typeMapper: GoTypeMapper;
   private packageName: string;
+  private splitNamespaces: boolean;
+  // Namespace -> builder of its file, with splitNamespaces
+  private namespaces: Map<string, GoBuilder> = new Map();
   private generatedTypes: Set<string> = new Set();
+  // Shape key -> first struct generated with that shape
+  private structShapes: Map<string, string> = new Map();
+  // Packages referred to by generated types, e.g. "time" for time.Time
+  private imports: Set<string> = new Set();
+  private structs: GoStruct[] = [];
+  // Alias name -> struct it aliases
+  private aliases: Map<string, string> = new Map();
 
-  constructor(packageName = "server") {
-    this.w = new GoBuilder();
+  /**
+   * @param splitNamespaces - Generate the types named after a namespace of
+   *   the contract, such as TaskGetInput of task.get, in a file of their own
+   *   (see getNamespaceFiles) rather than in types.go
+   */
+  constructor(packageName = "server", splitNamespaces = false) {
+    this.main = new GoBuilder();
+    this.w = this.main;
     this.typeMapper = new GoTypeMapper();
     this.packageName = packageName;
+    this.splitNamespaces = splitNamespaces;
   }
 
   /**
@@ -38,20 +171,30 @@ export class GoTypeGenerator {
     contract: ContractDefinition,
     collectedTypes?: CollectedType[],
   ): string {
-    const w = this.w.reset();
+    const w = this.main.reset();
+    this.w = w;
+    this.namespaces.clear();
+    if (this.splitNamespaces) {
+      for (const endpoint of contract.endpoints) {
+        const namespace = endpointNamespace(endpoint);
+        if (!this.namespaces.has(namespace)) {
+          this.namespaces.set(namespace, new GoBuilder());
+        }
+      }
+    }
     this.typeMapper.reset();
     this.generatedTypes.clear();
-
-    w.package(this.packageName).import("net/http");
-
-    // Generate Context type for middleware support
-    this.generateContextType();
+    this.structShapes.clear();
+    this.imports = new Set(["context", "net/http"]);
+    this.structs = [];
+    this.aliases.clear();
 
     // Always generate middleware types (router uses them)
     this.generateMiddlewareTypes();
 
     // Generate input/output types from contract
     for (const type of contract.types) {
+      this.w = this.builderFor(toPascalCase(type.name));
       this.generateType(type);
     }
 
@@ -59,6 +202,7 @@ export class GoTypeGenerator {
     if (collectedTypes) {
       for (const collected of collectedTypes) {
         if (!this.generatedTypes.has(collected.name)) {
+          this.w = this.builderFor(collected.name);
           this.generateTypeFromReference(collected.name, collected.typeRef);
         }
       }
@@ -72,19 +216,92 @@ export class GoTypeGenerator {
 
     // Generate typed handler types for each endpoint
     this.generateTypedHandlers(contract);
+    this.w = w;
 
-    return w.toString();
+    return this.file(w, this.splitNamespaces);
   }
 
-  private generateContextType(): void {
-    // Generate Context struct for middleware and handlers
-    this.w
-      .struct("Context", (b) => {
-        b.l("Request        *http.Request")
-          .l("ResponseWriter http.ResponseWriter")
-          .l("Data           map[string]interface{}");
-      })
-      .n();
+  /**
+   * The files of the types split out of types.go by namespace in the last
+   * generateTypes call, keyed by path, e.g. "task_types.go". Empty unless the
+   * generator splits namespaces.
+   */
+  getNamespaceFiles(): Map<string, string> {
+    const files = new Map<string, string>();
+    for (const [namespace, builder] of this.namespaces) {
+      const body = builder.toString();
+      if (body.trim() !== "") {
+        files.set(
+          `${toSnakeCase(namespace).replace(/\W/g, "_")}_types.go`,
+          this.file(builder, true),
+        );
+      }
+    }
+    return files;
+  }
+
+  // The builder of the file a type goes to: that of the namespace whose Go
+  // name its name starts with, the longest if several do, or types.go's
+  private builderFor(typeName: string): GoBuilder {
+    let builder = this.main;
+    let longest = 0;
+    for (const [namespace, namespaceBuilder] of this.namespaces) {
+      const prefix = toMethodName(namespace);
+      if (
+        prefix.length > longest &&
+        typeName.startsWith(prefix) &&
+        !/^[a-z]/.test(typeName.slice(prefix.length))
+      ) {
+        builder = namespaceBuilder;
+        longest = prefix.length;
+      }
+    }
+    return builder;
+  }
+
+  // A file of generated types. Imports are only known once the types are
+  // generated; when types are split across files, each imports the packages
+  // its own code refers to.
+  private file(builder: GoBuilder, ownImports: boolean): string {
+    const body = builder.toString();
+    let imports = Array.from(this.imports);
+    if (ownImports) {
+      const code = body
+        .split("\n")
+        .filter((line) => !line.trim().startsWith("//"))
+        .join("\n");
+      imports = imports.filter((pkg) =>
+        new RegExp(`\\b${pkg.split("/").pop()}\\.[A-Z]`).test(code),
+      );
+    }
+    const header = new GoBuilder();
+    header.package(this.packageName).import(...imports.sort());
+    return `${header.toString()}\n${body}`;
+  }
+
+  /**
+   * The structs of the last generateTypes call, in the order generated.
+   */
+  getStructs(): GoStruct[] {
+    return this.structs;
+  }
+
+  /**
+   * The aliases of structs with the same shape as an earlier one generated by
+   * the last generateTypes call, mapped to the struct they alias.
+   */
+  getStructAliases(): Map<string, string> {
+    return this.aliases;
+  }
+
+  // Returns the Go type of a mapping, recording the packages it refers to
+  private goType(result: TypeResult<string>): string {
+    for (const pkg of result.imports ?? []) {
+      if (result.type.includes(`${pkg.split("/").pop()}.`)) {
+        this.imports.add(pkg);
+      }
+    }
+    return result.type;
   }
 
   private generateMiddlewareTypes(): void {
@@ -94,13 +311,16 @@ export class GoTypeGenerator {
         "MiddlewareFunc is a function that processes a request and extends context",
       )
       .n()
-      .type("MiddlewareFunc", "func(ctx *Context) *MiddlewareResult")
+      .type(
+        "MiddlewareFunc",
+        "func(ctx context.Context, info RequestInfo) *MiddlewareResult",
+      )
       .n();
 
     // Generate middleware result type
     this.w
       .struct("MiddlewareResult", (b) => {
-        b.l("Context  *Context")
+        b.l("Context  context.Context")
           .l("Error    error")
           .l("Response *http.Response");
       })
@@ -109,9 +329,12 @@ export class GoTypeGenerator {
     this.w
       .comment("NewMiddlewareResult creates a successful middleware result")
       .n()
-      .func("NewMiddlewareResult(ctx *Context) *MiddlewareResult", (b) => {
-        b.return("&MiddlewareResult{Context: ctx}");
-      })
+      .func(
+        "NewMiddlewareResult(ctx context.Context) *MiddlewareResult",
+        (b) => {
+          b.return("&MiddlewareResult{Context: ctx}");
+        },
+      )
       .n();
 
     this.w
@@ -134,6 +357,30 @@ export class GoTypeGenerator {
         },
       )
       .n();
+
+    // Generate interceptor types
+    this.w
+      .comment(
+        "NextFunc runs the rest of an interceptor chain and finally the handler",
+      )
+      .n()
+      .type("NextFunc", "func(ctx context.Context) (interface{}, error)")
+      .n();
+
+    this.w
+      .comment(
+        "InterceptorFunc wraps a query or mutation call. It receives the validated input",
+      )
+      .comment(
+        "and may change the context passed to next, replace the result, translate",
+      )
+      .comment("errors, time the call or recover from panics.")
+      .n()
+      .type(
+        "InterceptorFunc",
+        "func(ctx context.Context, info RequestInfo, input interface{}, next NextFunc) (interface{}, error)",
+      )
+      .n();
   }
 
   private generateType(type: TypeDefinition): void {
@@ -160,7 +407,9 @@ export class GoTypeGenerator {
         this.w.type(typeName, `[]${elementTypeName}`);
       } else {
         // For primitive element types, use the type mapper directly
-        const elementGoType = this.typeMapper.mapType(type.elementType).type;
+        const elementGoType = this.goType(
+          this.typeMapper.mapType(type.elementType),
+        );
         this.generatedTypes.add(typeName);
         this.w.type(typeName, `[]${elementGoType}`);
       }
@@ -169,16 +418,13 @@ export class GoTypeGenerator {
 
     // Handle object types - generate struct
     if (type.kind === "object") {
-      this.generatedTypes.add(typeName);
-      this.w.struct(typeName, (b) => {
-        if (type.properties) {
-          for (const prop of type.properties) {
-            const goType = this.typeMapper.mapType(prop.type).type;
-            const jsonTag = this.generateJSONTag(prop);
-            b.l(`${toPascalCase(prop.name)} ${goType} \`${jsonTag}\``);
-          }
-        }
-      });
+      this.generateStruct(typeName, type.properties ?? [], type.description);
+      return;
+    }
+
+    // String enums and discriminated unions are generated in enums.go and
+    // unions.go
+    if (isStringEnum(type) || isDiscriminatedUnion(type)) {
       return;
     }
 
@@ -189,7 +435,7 @@ export class GoTypeGenerator {
     }
 
     // For all other kinds, generate a type alias
-    const goType = this.typeMapper.mapType(type).type;
+    const goType = this.goType(this.typeMapper.mapType(type));
     this.generatedTypes.add(typeName);
     this.w.type(typeName, goType);
   }
@@ -208,14 +454,7 @@ export class GoTypeGenerator {
 
     // Handle object types - generate struct
     if (typeRef.kind === "object" && typeRef.properties) {
-      this.generatedTypes.add(typeName);
-      this.w.struct(typeName, (b) => {
-        for (const prop of typeRef.properties!) {
-          const goType = this.typeMapper.mapType(prop.type).type;
-          const jsonTag = this.generateJSONTag(prop);
-          b.l(`${toPascalCase(prop.name)} ${goType} \`${jsonTag}\``);
-        }
-      });
+      this.generateStruct(typeName, typeRef.properties, typeRef.description);
       return;
     }
 
@@ -235,24 +474,88 @@ export class GoTypeGenerator {
         this.generatedTypes.add(typeName);
         this.w.type(typeName, `[]${elementTypeName}`);
       } else {
-        const elementGoType = this.typeMapper.mapType(typeRef.elementType).type;
+        const elementGoType = this.goType(
+          this.typeMapper.mapType(typeRef.elementType),
+        );
         this.generatedTypes.add(typeName);
         this.w.type(typeName, `[]${elementGoType}`);
       }
       return;
     }
 
+    if (isStringEnum(typeRef) || isDiscriminatedUnion(typeRef)) {
+      return;
+    }
+
     if (typeRef.kind === "union" || typeRef.kind === "tuple") {
       this.typeMapper.mapType(typeRef, { name: typeName });
       return;
     }
 
     // For other types, generate a type alias
-    const goType = this.typeMapper.mapType(typeRef).type;
+    const goType = this.goType(this.typeMapper.mapType(typeRef));
     this.generatedTypes.add(typeName);
     this.w.type(typeName, goType);
   }
 
+  /**
+   * Generate a struct, or an alias of an earlier struct with the same shape so
+   * handlers can return one value for several methods without copying fields.
+   * Nested objects are aliased the same way, which keeps the field types of
+   * both names identical. Descriptions from the contract become the doc
+   * comments of the struct and its fields.
+   */
+  private generateStruct(
+    typeName: string,
+    properties: Property[],
+    description?: string,
+  ): void {
+    this.generatedTypes.add(typeName);
+
+    const key = shapeKey(properties);
+    const existing = this.structShapes.get(key);
+    if (existing) {
+      this.aliases.set(typeName, existing);
+      if (description) {
+        this.w.doc(`${typeName} ${description}`).l("//");
+      }
+      this.w
+        .comment(`${typeName} has the same shape as ${existing}.`)
+        .type(typeName, `= ${existing}`);
+      return;
+    }
+    this.structShapes.set(key, typeName);
+
+    const struct: GoStruct = { name: typeName, fields: [] };
+    if (description) {
+      this.w.doc(`${typeName} ${description}`);
+    }
+    this.w.struct(typeName, (b) => {
+      for (const prop of properties) {
+        const goType = this.goType(this.typeMapper.mapPropertyType(prop));
+        const tag = this.generateStructTag(prop);
+        if (prop.description) {
+          b.doc(prop.description);
+        }
+        b.l(`${toPascalCase(prop.name)} ${goType} \`${tag}\``);
+        struct.fields.push({
+          name: toPascalCase(prop.name),
+          jsonName: prop.name,
+          type: goType,
+          omitEmpty: !prop.required,
+          sensitive: prop.sensitive === true,
+          normalize: propertyNormalizations(prop),
+          ...goDefault(prop, goType),
+          ...(prop.validation?.warn && {
+            warn: prop.validation.warn,
+            lengthUnit: prop.validation.lengthUnit,
+          }),
+        });
+      }
+    });
+    this.structs.push(struct);
+  }
+
   /**
    * Generate struct types for tuples
    */
@@ -261,11 +564,12 @@ export class GoTypeGenerator {
     for (const [name, typeRef] of tupleTypes) {
       if (this.generatedTypes.has(name)) continue;
       this.generatedTypes.add(name);
+      this.w = this.builderFor(name);
 
       if (typeRef.tupleElements && typeRef.tupleElements.length > 0) {
         this.w.struct(name, (b) => {
           typeRef.tupleElements?.forEach((elem, index) => {
-            const goType = this.typeMapper.mapType(elem).type;
+            const goType = this.goType(this.typeMapper.mapType(elem));
             b.l(`V${index} ${goType} \`json:"v${index}"\``);
           });
         });
@@ -281,6 +585,7 @@ export class GoTypeGenerator {
     for (const [name, typeRef] of unionTypes) {
       if (this.generatedTypes.has(name)) continue;
       this.generatedTypes.add(name);
+      this.w = this.builderFor(name);
 
       if (typeRef.unionTypes && typeRef.unionTypes.length > 0) {
         // Generate a wrapper struct that can hold any of the union variants
@@ -291,7 +596,7 @@ export class GoTypeGenerator {
           // Add a field for each unique type in the union
           const seenTypes = new Set<string>();
           typeRef.unionTypes?.forEach((variant, index) => {
-            const goType = this.typeMapper.mapType(variant).type;
+            const goType = this.goType(this.typeMapper.mapType(variant));
             // Skip if we've already added a field for this Go type
             if (seenTypes.has(goType)) return;
             seenTypes.add(goType);
@@ -326,28 +631,55 @@ export class GoTypeGenerator {
     return `Variant${index}`;
   }
 
-  private generateJSONTag(prop: Property): string {
-    if (prop.required) {
-      return `json:"${prop.name}"`;
-    }
-    return `json:"${prop.name},omitempty"`;
+  private generateStructTag(prop: Property): string {
+    const json = prop.required
+      ? `json:"${prop.name}"`
+      : `json:"${prop.name},omitempty"`;
+    // Extra tags declared with .meta({ goTags }) follow in declaration order
+    const extra = Object.entries(prop.goTags ?? {}).map(
+      ([key, value]) => `${key}:${JSON.stringify(value)}`,
+    );
+    return [json, ...extra].join(" ");
   }
 
   private generateTypedHandlers(contract: ContractDefinition): void {
-    this.w.comment("Typed handler types for each endpoint").n();
+    if (!this.splitNamespaces) {
+      this.main.comment("Typed handler types for each endpoint").n();
+    }
 
     for (const endpoint of contract.endpoints) {
       const handlerName = `${toMethodName(endpoint.fullName)}Handler`;
-      const inputType = toPascalCase(endpoint.input.name!);
-      const outputType = toPascalCase(endpoint.output.name!);
-
-      this.w
-        .comment(`Handler type for ${endpoint.fullName}`)
-        .type(
-          handlerName,
-          `func(ctx *Context, input ${inputType}) (${outputType}, error)`,
-        )
-        .n();
+      // Streamed items are sent through a stream; the returned output
+      // supplies the other fields
+      const itemType = endpoint.stream
+        ? this.goType(streamItemType(endpoint))
+        : undefined;
+      this.w = this.builderFor(handlerName);
+      this.w.comment(`Handler type for ${endpoint.fullName}`);
+      if (endpoint.description) {
+        this.w.l("//").doc(endpoint.description);
+      }
+      this.w.type(handlerName, handlerFuncType(endpoint, itemType)).n();
     }
   }
 }
+
+/**
+ * The func type of an endpoint's handler. Subscriptions push events through
+ * send until they return or ctx is done; streamed queries send items of
+ * itemType through stream.
+ */
+export function handlerFuncType(
+  endpoint: Endpoint,
+  itemType?: string,
+): string {
+  const inputType = toPascalCase(endpoint.input.name!);
+  const outputType = toPascalCase(endpoint.output.name!);
+  if (endpoint.type === "subscription") {
+    return `func(ctx context.Context, info RequestInfo, input ${inputType}, send func(${outputType}) error) error`;
+  }
+  if (endpoint.stream) {
+    return `func(ctx context.Context, info RequestInfo, input ${inputType}, stream *Stream[${itemType}]) (${outputType}, error)`;
+  }
+  return `func(ctx context.Context, info RequestInfo, input ${inputType}) (${outputType}, error)`;
+}
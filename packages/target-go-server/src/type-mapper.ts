@@ -1,12 +1,19 @@
 import {
+  type Endpoint,
+  type Property,
   type TypeContext,
+  type TypeDefinition,
   TypeMapperBase,
   type TypeMapping,
   type TypeReference,
   type TypeResult,
-  toPascalCase,
 } from "@xrpckit/sdk";
-import { createGoTuplePattern, createGoUnionPattern } from "./patterns";
+import { toPascalCase } from "./naming";
+import {
+  createGoEnumPattern,
+  createGoTuplePattern,
+  createGoUnionPattern,
+} from "./patterns";
 
 /**
  * Go type mapper that converts xRPC types to Go types.
@@ -34,6 +41,7 @@ export class GoTypeMapper extends TypeMapperBase<string> {
     record: (ctx) => this.handleRecord(ctx),
     tuple: (ctx) => this.handleTuple(ctx),
     date: () => this.handleDate(),
+    file: () => this.handleFile(),
   };
 
   /**
@@ -45,7 +53,9 @@ export class GoTypeMapper extends TypeMapperBase<string> {
       number: "float64",
       integer: "int",
       boolean: "bool",
+      bytes: "[]byte",
       date: "time.Time",
+      calendarDate: "Date",
       uuid: "string",
       email: "string",
       any: "interface{}",
@@ -60,6 +70,26 @@ export class GoTypeMapper extends TypeMapperBase<string> {
     return result;
   }
 
+  /**
+   * Map a struct field's type. Numbers the field validates as integers become
+   * `int`, so handlers don't convert from float64. Strings validated as
+   * timestamps become `time.Time` and calendar dates `Date` (see dates.go),
+   * so handlers get parsed values and malformed ones fail to decode.
+   */
+  mapPropertyType(prop: Property): TypeResult<string> {
+    const rules = prop.validation;
+    if (rules?.int) {
+      return this.mapType(withBaseType(prop.type, "number", "integer"));
+    }
+    if (rules?.datetime) {
+      return this.mapType(withBaseType(prop.type, "string", "date"));
+    }
+    if (rules?.date) {
+      return this.mapType(withBaseType(prop.type, "string", "calendarDate"));
+    }
+    return this.mapType(prop.type);
+  }
+
   /**
    * Get all registered tuple types that need struct generation
    */
@@ -116,7 +146,10 @@ export class GoTypeMapper extends TypeMapperBase<string> {
     const { typeRef } = ctx;
     const baseType =
       typeof typeRef.baseType === "string" ? typeRef.baseType : "unknown";
-    const goType = this.mapPrimitive(baseType);
+    const goType =
+      baseType === "number" && typeRef.validation?.int
+        ? this.mapPrimitive("integer")
+        : this.mapPrimitive(baseType);
 
     // time.Time requires import
     if (goType === "time.Time") {
@@ -128,14 +161,23 @@ export class GoTypeMapper extends TypeMapperBase<string> {
   private handleOptional(ctx: TypeContext): TypeResult<string> {
     const { typeRef } = ctx;
 
-    // In Go, optional is handled by validation - we use the base type
+    // Optional values are pointers so an absent field (nil) can be told
+    // apart from a zero value
     if (typeof typeRef.baseType === "object") {
-      return this.mapType(typeRef.baseType);
+      const base = this.mapType(typeRef.baseType);
+      return isOptionalPointer(typeRef.baseType)
+        ? { ...base, type: `*${base.type}` }
+        : base;
     }
 
     // String baseType - map to primitive
     if (typeof typeRef.baseType === "string") {
-      return { type: this.mapPrimitive(typeRef.baseType) };
+      const goType = this.mapPrimitive(typeRef.baseType);
+      return {
+        type: isOptionalPointer({ kind: "primitive", baseType: typeRef.baseType })
+          ? `*${goType}`
+          : goType,
+      };
     }
 
     console.warn("[GoTypeMapper] Optional with unknown baseType:", typeRef);
@@ -145,17 +187,23 @@ export class GoTypeMapper extends TypeMapperBase<string> {
   private handleNullable(ctx: TypeContext): TypeResult<string> {
     const { typeRef } = ctx;
 
-    // In Go, nullable maps to pointer type
-    if (typeof typeRef.baseType === "object") {
-      const base = this.mapType(typeRef.baseType);
+    // In Go, nullable maps to pointer type. An optional inside it is
+    // already covered by the same pointer.
+    const inner =
+      typeof typeRef.baseType === "object" &&
+      typeRef.baseType.kind === "optional"
+        ? typeRef.baseType.baseType
+        : typeRef.baseType;
+    if (typeof inner === "object") {
+      const base = this.mapType(inner);
       return {
         type: `*${base.type}`,
         imports: base.imports,
       };
     }
 
-    if (typeof typeRef.baseType === "string") {
-      return { type: `*${this.mapPrimitive(typeRef.baseType)}` };
+    if (typeof inner === "string") {
+      return { type: `*${this.mapPrimitive(inner)}` };
     }
 
     console.warn("[GoTypeMapper] Nullable with unknown baseType:", typeRef);
@@ -165,8 +213,13 @@ export class GoTypeMapper extends TypeMapperBase<string> {
   private handleUnion(ctx: TypeContext): TypeResult<string> {
     const { typeRef, name } = ctx;
 
-    // If the union has a name, use a wrapper struct
+    // Discriminated unions get a wrapper holding one variant (see unions.go)
     const unionName = name || typeRef.name;
+    if (unionName && isDiscriminatedUnion(typeRef)) {
+      return { type: toPascalCase(unionName) };
+    }
+
+    // If the union has a name, use a wrapper struct
     if (unionName) {
       const goName = toPascalCase(unionName);
       this.unionTypes.set(goName, typeRef);
@@ -214,9 +267,20 @@ export class GoTypeMapper extends TypeMapperBase<string> {
     return { type: "interface{}" };
   }
 
-  private handleEnum(_ctx: TypeContext): TypeResult<string> {
-    // Enums in Go are typically represented as strings
-    // The validation layer handles the enum value checking
+  private handleEnum(ctx: TypeContext): TypeResult<string> {
+    const { typeRef, name } = ctx;
+
+    // Named string enums get a typed string with constants (see enums.go);
+    // anything else stays a plain string checked by the validation layer
+    if ((name || typeRef.name) && isStringEnum(typeRef)) {
+      const goName = toPascalCase(name || typeRef.name!);
+      const utility = createGoEnumPattern(goName, typeRef.enumValues ?? []);
+      return {
+        type: goName,
+        utilities: [utility],
+        imports: utility.imports,
+      };
+    }
     return { type: "string" };
   }
 
@@ -302,4 +366,161 @@ export class GoTypeMapper extends TypeMapperBase<string> {
       imports: ["time"],
     };
   }
+
+  private handleFile(): TypeResult<string> {
+    // Declared in uploads.go
+    return { type: "File" };
+  }
+}
+
+// Rewrites a primitive type, possibly wrapped in optional/nullable, as
+// another primitive, e.g. a number as an integer
+function withBaseType(
+  typeRef: TypeReference,
+  from: string,
+  to: string,
+): TypeReference {
+  if (typeRef.kind === "primitive" && typeRef.baseType === from) {
+    return { ...typeRef, baseType: to };
+  }
+  if (
+    (typeRef.kind === "optional" || typeRef.kind === "nullable") &&
+    typeof typeRef.baseType === "object"
+  ) {
+    return { ...typeRef, baseType: withBaseType(typeRef.baseType, from, to) };
+  }
+  return typeRef;
+}
+
+/**
+ * Reports whether every value of an enum is a string, which is what typed
+ * Go enums support.
+ */
+export function isStringEnum(typeRef: TypeReference | TypeDefinition): boolean {
+  return (
+    typeRef.kind === "enum" &&
+    !!typeRef.enumValues &&
+    typeRef.enumValues.length > 0 &&
+    typeRef.enumValues.every((value) => typeof value === "string")
+  );
+}
+
+/**
+ * Reports whether a union is discriminated: every variant is an object whose
+ * discriminator field is a string literal, so the variant can be chosen by
+ * that field when decoding.
+ */
+export function isDiscriminatedUnion(
+  typeRef: TypeReference | TypeDefinition,
+): boolean {
+  if (typeRef.kind !== "union" || !typeRef.discriminator) {
+    return false;
+  }
+  const variants = typeRef.unionTypes ?? [];
+  return (
+    variants.length > 0 &&
+    variants.every(
+      (variant) =>
+        discriminatorValue(variant, typeRef.discriminator!) !== undefined,
+    )
+  );
+}
+
+/**
+ * Returns the value of a discriminated union variant's discriminator field,
+ * or undefined when the variant is not an object with a string literal there.
+ */
+export function discriminatorValue(
+  variant: TypeReference,
+  discriminator: string,
+): string | undefined {
+  if (variant.kind !== "object") {
+    return undefined;
+  }
+  const field = variant.properties?.find((prop) => prop.name === discriminator);
+  const value = field?.type.literalValue;
+  return field?.type.kind === "literal" && typeof value === "string"
+    ? value
+    : undefined;
+}
+
+/**
+ * Reports whether an optional value of the given type is generated as a
+ * pointer. Slices, maps, interfaces and types that are already pointers are
+ * left as is, since nil already marks them absent.
+ */
+export function isOptionalPointer(typeRef: TypeReference): boolean {
+  switch (typeRef.kind) {
+    case "primitive":
+      return isPointerPrimitive(typeRef.baseType);
+    case "enum":
+    case "date":
+    case "file":
+      return true;
+    case "literal":
+      return typeRef.literalValue !== null;
+    case "object":
+      return !!typeRef.name;
+    case "tuple":
+      return !!typeRef.name;
+    case "optional":
+      return typeof typeRef.baseType === "object"
+        ? isOptionalPointer(typeRef.baseType)
+        : isPointerPrimitive(typeRef.baseType);
+    case "union": {
+      if (typeRef.name) {
+        return true;
+      }
+      const variants = typeRef.unionTypes ?? [];
+      const nonNullVariants = variants.filter(
+        (variant) =>
+          !(variant.kind === "literal" && variant.literalValue === null),
+      );
+      if (nonNullVariants.length !== variants.length) {
+        // Nullable union, already a pointer
+        return false;
+      }
+      // Anonymous unions are only a concrete type when every variant maps
+      // to the same Go type; otherwise they become interface{}
+      const keys = new Set(variants.map(variantKey));
+      return keys.size === 1 && isOptionalPointer(variants[0]);
+    }
+    default:
+      // array, record and nullable
+      return false;
+  }
+}
+
+// Bytes are []byte slices, and interface{} values need no pointer either
+function isPointerPrimitive(
+  baseType: string | TypeReference | undefined,
+): boolean {
+  return baseType !== "any" && baseType !== "unknown" && baseType !== "bytes";
+}
+
+// Approximates the Go type a union variant maps to, for comparing variants
+function variantKey(typeRef: TypeReference): string {
+  switch (typeRef.kind) {
+    case "literal":
+      return typeof typeRef.literalValue === "number"
+        ? "number"
+        : typeof typeRef.literalValue;
+    case "enum":
+      return "string";
+    case "primitive":
+      return String(typeRef.baseType);
+    default:
+      return `${typeRef.kind}:${typeRef.name ?? ""}`;
+  }
+}
+
+/**
+ * Returns the Go type of the items a streamed endpoint sends through its
+ * Stream: the element type of the output field named by its stream option.
+ */
+export function streamItemType(endpoint: Endpoint): TypeResult<string> {
+  const field = endpoint.output.properties?.find(
+    (prop) => prop.name === endpoint.stream,
+  );
+  return new GoTypeMapper().mapType(field!.type.elementType!);
 }
@@ -0,0 +1,155 @@
+import type { ContractDefinition, TypeReference } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
+import type { CollectedType } from "./type-collector";
+import { discriminatorValue, isDiscriminatedUnion } from "./type-mapper";
+
+interface UnionVariant {
+  typeName: string;
+  value: string;
+}
+
+/**
+ * Generates unions.go: for every discriminated union in the contract, a
+ * wrapper struct whose Value holds one variant, an interface the variants
+ * implement, and JSON methods that pick the variant by its discriminator
+ * field.
+ */
+export class GoUnionGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate unions.go, or null when the contract has no discriminated unions.
+   * @param contract - The contract definition
+   * @param collectedTypes - Nested types from GoTypeCollector, including inline unions it named
+   */
+  generateUnions(
+    contract: ContractDefinition,
+    collectedTypes: CollectedType[] = [],
+  ): string | null {
+    const unions = new Map<string, TypeReference>();
+    const addUnion = (name: string, typeRef: TypeReference) => {
+      const goName = toPascalCase(name);
+      if (isDiscriminatedUnion(typeRef) && !unions.has(goName)) {
+        unions.set(goName, typeRef);
+      }
+    };
+    for (const type of contract.types) {
+      addUnion(type.name, type as TypeReference);
+    }
+    for (const collected of collectedTypes) {
+      addUnion(collected.name, collected.typeRef);
+    }
+    if (unions.size === 0) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("encoding/json", "fmt");
+
+    for (const [name, typeRef] of unions) {
+      const discriminator = typeRef.discriminator!;
+      const variants = (typeRef.unionTypes ?? []).map((variant) => ({
+        typeName: toPascalCase(variant.name!),
+        value: discriminatorValue(variant, discriminator)!,
+      }));
+      this.generateUnion(w, name, discriminator, variants);
+    }
+
+    return w.toString();
+  }
+
+  private generateUnion(
+    w: GoBuilder,
+    name: string,
+    discriminator: string,
+    variants: UnionVariant[],
+  ): void {
+    const field = toPascalCase(discriminator);
+    const variantNames = variants.map((variant) => variant.typeName);
+    const allowed = variants
+      .map((variant) => variant.value)
+      .join(", ")
+      .replace(/%/g, "%%");
+    const message = JSON.stringify(
+      `invalid ${name} ${discriminator} %q, must be one of: ${allowed}`,
+    );
+
+    w.comment(
+      `${name} is one of ${variantNames.join(", ")}, told apart by their`,
+    )
+      .comment(`"${discriminator}" field. Value holds the variant.`)
+      .struct(name, (b) => {
+        b.l(`Value ${name}Variant`);
+      });
+
+    w.comment(`${name}Variant is implemented by the variants of ${name}.`)
+      .l(`type ${name}Variant interface {`)
+      .i()
+      .l(`is${name}()`)
+      .u()
+      .l("}")
+      .n();
+
+    for (const variantName of variantNames) {
+      w.l(`func (${variantName}) is${name}() {}`).n();
+    }
+
+    w.comment(
+      `MarshalJSON encodes the variant in u.Value with its "${discriminator}" field set`,
+    )
+      .n()
+      .method(`u ${name}`, "MarshalJSON", "", "([]byte, error)", (b) => {
+        b.l("switch v := u.Value.(type) {").l("case nil:").i();
+        b.return('[]byte("null"), nil').u();
+        for (const variant of variants) {
+          b.l(`case ${variant.typeName}:`)
+            .i()
+            .l(`v.${field} = ${JSON.stringify(variant.value)}`)
+            .return("json.Marshal(v)")
+            .u();
+        }
+        b.l("}").return(
+          `nil, fmt.Errorf("invalid ${name} variant %T", u.Value)`,
+        );
+      });
+
+    w.comment(
+      `UnmarshalJSON decodes the variant named by the "${discriminator}" field into u.Value`,
+    )
+      .n()
+      .method(`u *${name}`, "UnmarshalJSON", "data []byte", "error", (b) => {
+        b.l("var tag struct {")
+          .i()
+          .l(`${field} string \`json:"${discriminator}"\``)
+          .u()
+          .l("}")
+          .if("err := json.Unmarshal(data, &tag); err != nil", (b) => {
+            b.return("err");
+          })
+          .l(`switch tag.${field} {`);
+        for (const variant of variants) {
+          b.l(`case ${JSON.stringify(variant.value)}:`)
+            .i()
+            .var("v", variant.typeName)
+            .if("err := json.Unmarshal(data, &v); err != nil", (b) => {
+              b.return("err");
+            })
+            .l("u.Value = v")
+            .u();
+        }
+        b.l("default:")
+          .i()
+          .return(`fmt.Errorf(${message}, tag.${field})`)
+          .u()
+          .l("}")
+          .return("nil");
+      });
+  }
+}
@@ -0,0 +1,349 @@
+import { type ContractDefinition, collectContractUsage } from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+
+/**
+ * Generates uploads.go: the File type fields declared with z.file() are
+ * generated with, and the decoding of multipart/form-data requests, whose
+ * file parts are bound to those fields. Files are transcoded through the
+ * JSON params like the other wire encodings, so the generated types and
+ * validation apply unchanged.
+ */
+export class GoUploadGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate uploads.go, or null when no field in the contract is a file.
+   * @param contract - The contract definition
+   */
+  generateUploads(contract: ContractDefinition): string | null {
+    if (!usesFiles(contract)) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import(
+      "bytes",
+      "encoding/json",
+      "fmt",
+      "io",
+      "mime",
+      "net/http",
+      "strconv",
+      "strings",
+    );
+
+    this.generateFile(w);
+    this.generateMultipart(w);
+
+    return w.toString();
+  }
+
+  private generateFile(w: GoBuilder): void {
+    w.comment(
+      "MaxUploadSize bounds the body of a multipart request, files included. Larger",
+    )
+      .comment("requests fail with INVALID_ARGUMENT.")
+      .l("var MaxUploadSize int64 = 32 << 20")
+      .n();
+
+    w.comment(
+      "File is an uploaded file, the value of fields declared with z.file(). Multipart",
+    )
+      .comment(
+        "requests send it as a file part, JSON bodies as an object holding the",
+      )
+      .comment(
+        'base64-encoded content, {"filename", "contentType", "data"}, or as a bare',
+      )
+      .comment(
+        "base64 string. ContentType is the type the client declared, or the one",
+      )
+      .comment("sniffed from the content when it declared none.")
+      .struct("File", (b) => {
+        b.l("Filename    string")
+          .l("ContentType string")
+          .l("Size        int64")
+          .l("data        []byte");
+      });
+
+    w.comment("fileJSON is the JSON form of a File.").struct(
+      "fileJSON",
+      (b) => {
+        b.l('Filename    string `json:"filename,omitempty"`')
+          .l('ContentType string `json:"contentType,omitempty"`')
+          .l('Size        int64  `json:"size"`')
+          .l('Data        []byte `json:"data"`');
+      },
+    );
+
+    w.comment(
+      "NewFile returns a File holding data, such as for a result or a test call.",
+    )
+      .n()
+      .func(
+        "NewFile(filename, contentType string, data []byte) File",
+        (b) => {
+          b.return(
+            "File{Filename: filename, ContentType: contentType, Size: int64(len(data)), data: data}",
+          );
+        },
+      );
+
+    w.comment("Open returns a reader of the file's content.")
+      .n()
+      .method("f File", "Open", "", "io.ReadSeeker", (b) => {
+        b.return("bytes.NewReader(f.data)");
+      });
+
+    w.comment("Bytes returns the file's content.")
+      .n()
+      .method("f File", "Bytes", "", "[]byte", (b) => {
+        b.return("f.data");
+      });
+
+    w.comment("IsZero reports whether no file was sent.")
+      .n()
+      .method("f File", "IsZero", "", "bool", (b) => {
+        b.return('f.Filename == "" && f.ContentType == "" && len(f.data) == 0');
+      });
+
+    w.comment(
+      'HasContentType reports whether the file\'s media type is one of types, where',
+    )
+      .comment(
+        '"image/*" matches any image. Parameters such as charset are ignored.',
+      )
+      .n()
+      .method("f File", "HasContentType", "types ...string", "bool", (b) => {
+        b.l("mediaType, _, err := mime.ParseMediaType(f.ContentType)")
+          .ifErr((b) => {
+            b.return("false");
+          })
+          .l("for _, t := range types {")
+          .i()
+          .decl("t", "strings.ToLower(t)")
+          .if(
+            't == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")))',
+            (b) => {
+              b.return("true");
+            },
+          )
+          .u()
+          .l("}")
+          .return("false");
+      });
+
+    w.comment("MarshalJSON encodes f with its content base64-encoded.")
+      .n()
+      .method("f File", "MarshalJSON", "", "([]byte, error)", (b) => {
+        b.return(
+          "json.Marshal(fileJSON{Filename: f.Filename, ContentType: f.ContentType, Size: f.Size, Data: f.data})",
+        );
+      });
+
+    w.comment(
+      "UnmarshalJSON decodes f from its JSON form or a bare base64 string. The size",
+    )
+      .comment("is the length of the content, whatever the JSON claims.")
+      .n()
+      .method("f *File", "UnmarshalJSON", "data []byte", "error", (b) => {
+        b.if('string(data) == "null"', (b) => {
+          b.return("nil");
+        })
+          .var("value", "fileJSON")
+          .decl("target", "interface{}(&value)")
+          .if("len(data) > 0 && data[0] == '\"'", (b) => {
+            b.l("target = &value.Data");
+          })
+          .if("err := json.Unmarshal(data, target); err != nil", (b) => {
+            b.return("err");
+          })
+          .decl("contentType", "value.ContentType")
+          .if(
+            'len(value.Data) > 0 && (contentType == "" || contentType == "application/octet-stream")',
+            (b) => {
+              b.l("contentType = http.DetectContentType(value.Data)");
+            },
+          )
+          .l("*f = NewFile(value.Filename, contentType, value.Data)")
+          .return("nil");
+      });
+  }
+
+  private generateMultipart(w: GoBuilder): void {
+    w.comment(
+      "uploadedFile is a file part of a multipart request, with the path of the field",
+    )
+      .comment("it is bound to.")
+      .struct("uploadedFile", (b) => {
+        b.l("path  string").l("value fileJSON");
+      });
+
+    w.comment(
+      "decodeUploadRequest decodes the envelope of a POST request like decodeRequest,",
+    )
+      .comment(
+        'and from multipart/form-data bodies: a "method" part, a "params" part holding',
+      )
+      .comment(
+        "the JSON params, and a file part per file, named after the path of its field",
+      )
+      .comment(
+        'in the params, such as "attachment", "photos.0" or "profile.avatar". Files',
+      )
+      .comment(
+        "of an array are sent in order. Bodies larger than MaxUploadSize fail.",
+      )
+      .n()
+      .func(
+        "decodeUploadRequest(req *http.Request, request interface{}) error",
+        (b) => {
+          b.l(
+            'mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))',
+          )
+            .if('mediaType != "multipart/form-data"', (b) => {
+              b.return("decodeRequest(req, request)");
+            })
+            .l("req.Body = http.MaxBytesReader(nil, req.Body, MaxUploadSize)")
+            .l("reader, err := req.MultipartReader()")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .n()
+            .var("method", "string")
+            .var("params", "map[string]interface{}")
+            .var("files", "[]uploadedFile")
+            .l("for {")
+            .i()
+            .l("part, err := reader.NextPart()")
+            .if("err == io.EOF", (b) => {
+              b.l("break");
+            })
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("data, err := io.ReadAll(part)")
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .l("switch {")
+            .l('case part.FileName() != "":')
+            .i()
+            .l("files = append(files, uploadedFile{")
+            .i()
+            .l("path: part.FormName(),")
+            .l("value: fileJSON{")
+            .i()
+            .l("Filename:    part.FileName(),")
+            .l('ContentType: part.Header.Get("Content-Type"),')
+            .l("Data:        data,")
+            .u()
+            .l("},")
+            .u()
+            .l("})")
+            .u()
+            .l('case part.FormName() == "method":')
+            .i()
+            .l("method = string(data)")
+            .u()
+            .l('case part.FormName() == "params":')
+            .i()
+            .decl("decoder", "json.NewDecoder(bytes.NewReader(data))")
+            .l("decoder.UseNumber()")
+            .if("err := decoder.Decode(&params); err != nil", (b) => {
+              b.return('fmt.Errorf("params: %w", err)');
+            })
+            .u()
+            .l("default:")
+            .i()
+            .return('fmt.Errorf("unexpected form field %q", part.FormName())')
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .n()
+            .decl("root", "interface{}(params)")
+            .if("params == nil", (b) => {
+              b.l("root = map[string]interface{}{}");
+            })
+            .l("for _, file := range files {")
+            .i()
+            .l('root, err = setUploadPath(root, strings.Split(file.path, "."), file.value)')
+            .ifErr((b) => {
+              b.return('fmt.Errorf("file %q: %w", file.path, err)');
+            })
+            .u()
+            .l("}")
+            .decl(
+              "body, err",
+              'json.Marshal(map[string]interface{}{"method": method, "params": root})',
+            )
+            .ifErr((b) => {
+              b.return("err");
+            })
+            .return("json.Unmarshal(body, request)");
+        },
+      );
+
+    w.comment(
+      "setUploadPath returns node with value set at path, creating the objects and",
+    )
+      .comment(
+        "arrays along it. Arrays grow one item at a time, so an index may not skip",
+      )
+      .comment("items.")
+      .n()
+      .func(
+        "setUploadPath(node interface{}, path []string, value interface{}) (interface{}, error)",
+        (b) => {
+          b.if("len(path) == 0", (b) => {
+            b.return("value, nil");
+          })
+            .decl("key", "path[0]")
+            .l("index, err := strconv.Atoi(key)")
+            .l("fields, isObject := node.(map[string]interface{})")
+            .if("isObject || (node == nil && err != nil)", (b) => {
+              b.if("fields == nil", (b) => {
+                b.l("fields = map[string]interface{}{}");
+              })
+                .l("child, err := setUploadPath(fields[key], path[1:], value)")
+                .ifErr((b) => {
+                  b.return("nil, err");
+                })
+                .l("fields[key] = child")
+                .return("fields, nil");
+            })
+            .l("items, isArray := node.([]interface{})")
+            .if("!isArray && node != nil", (b) => {
+              b.return('nil, fmt.Errorf("cannot set %s, not an object or array", key)');
+            })
+            .if("err != nil || index < 0 || index > len(items)", (b) => {
+              b.return('nil, fmt.Errorf("invalid index %s", key)');
+            })
+            .if("index == len(items)", (b) => {
+              b.l("items = append(items, nil)");
+            })
+            .l("child, err := setUploadPath(items[index], path[1:], value)")
+            .ifErr((b) => {
+              b.return("nil, err");
+            })
+            .l("items[index] = child")
+            .return("items, nil");
+        },
+      );
+  }
+}
+
+/**
+ * Reports whether any input, output or shared type of the contract has a
+ * field declared with z.file().
+ */
+export function usesFiles(contract: ContractDefinition): boolean {
+  return collectContractUsage(contract).types.has("file");
+}
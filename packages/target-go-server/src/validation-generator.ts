@@ -1,22 +1,67 @@
-import {
-  type ContractDefinition,
-  type Property,
-  type TypeDefinition,
-  type TypeReference,
-  type ValidationRules,
-  toPascalCase,
+import type {
+  ContractDefinition,
+  Property,
+  TypeDefinition,
+  TypeReference,
+  ValidationRules,
+  WarningRules,
 } from "@xrpckit/sdk";
 import { GoBuilder } from "./go-builder";
+import { toPascalCase } from "./naming";
 import type { CollectedType } from "./type-collector";
+import { shapeKey } from "./type-generator";
+import {
+  discriminatorValue,
+  isDiscriminatedUnion,
+  isOptionalPointer,
+  isStringEnum,
+} from "./type-mapper";
+import {
+  conditionMessage,
+  countsRunes,
+  goFieldCondition,
+  goStringLength,
+} from "./validation-mapper";
 
 export class GoValidationGenerator {
   private w: GoBuilder;
   private packageName: string;
   private generatedValidations: Set<string> = new Set();
+  // Shape key -> first validator generated for that shape
+  private shapeValidators: Map<string, string> = new Map();
+  // Compiled regex variables by pattern, in declaration order
+  private patterns: Map<string, string> = new Map();
+  // Union variant name -> discriminator field, which MarshalJSON sets
+  private discriminators: Map<string, string> = new Map();
+  // Types that can contain themselves, whose validators track the depth
+  private recursiveTypes: Set<string> = new Set();
+  // Whether the validator being generated has a depth parameter
+  private depthLimited = false;
+  // Whether the property being validated is sensitive, whose value custom
+  // validators' errors could echo
+  private sensitive = false;
+  // Number of errors validators stop at, if any
+  private maxErrors?: number;
+  // Whether the property being validated can append an error
+  private appendsErrors = false;
+  // Whether a validator checks uniqueItems, and compares items by their JSON
+  private checksUniqueItems = false;
+  private comparesJSON = false;
+  // Whether a validator checks a multiple that is not a whole number
+  private checksMultiples = false;
+  // Whether a validator compares a value with the time of the request
+  private comparesTimes = false;
+  // String formats validators check, whose helpers and imports are generated
+  private checkedFormats: Set<StringFormat> = new Set();
 
-  constructor(packageName = "server") {
+  /**
+   * @param maxErrors - Stop validating a value once it has this many errors,
+   * 1 to fail fast, rather than reporting all of them
+   */
+  constructor(packageName = "server", maxErrors?: number) {
     this.w = new GoBuilder();
     this.packageName = packageName;
+    this.maxErrors = maxErrors;
   }
 
   /**
@@ -29,18 +74,27 @@ export class GoValidationGenerator {
     collectedTypes?: CollectedType[],
   ): string {
     const w = this.w.reset();
+    const body = new GoBuilder();
     this.generatedValidations.clear();
+    this.shapeValidators.clear();
+    this.patterns.clear();
+    this.checksUniqueItems = false;
+    this.comparesJSON = false;
+    this.checksMultiples = false;
+    this.comparesTimes = false;
+    this.checkedFormats.clear();
+    this.collectDiscriminators(contract, collectedTypes ?? []);
+    this.recursiveTypes = findRecursiveTypes(contract, collectedTypes ?? []);
 
     // Determine which imports are needed based on validation rules in contract
-    const imports = new Set<string>(["fmt", "strings"]);
+    const imports = new Set<string>([
+      "encoding/json",
+      "fmt",
+      "strconv",
+      "strings",
+    ]);
 
     // Check if any validation rules require these imports
-    // Note: email uses mail.ParseAddress, not regex, so we check separately
-    const needsRegex = this.hasValidationRule(
-      contract,
-      collectedTypes,
-      (rules) => !!(rules.uuid || (rules.regex && !rules.email)), // regex but not email (email uses mail)
-    );
     const needsMail = this.hasValidationRule(
       contract,
       collectedTypes,
@@ -51,23 +105,27 @@ export class GoValidationGenerator {
       collectedTypes,
       (rules) => !!rules.url,
     );
+    const countsLength = (rules: ValidationRules | WarningRules) =>
+      rules.minLength !== undefined || rules.maxLength !== undefined;
+    const needsRunes = this.hasValidationRule(
+      contract,
+      collectedTypes,
+      (rules) => countsRunes(rules.lengthUnit) && countsLength(rules),
+    );
+    // warnings.go counts the graphemes of soft limits with graphemeCount
+    const needsGraphemes = this.hasValidationRule(
+      contract,
+      collectedTypes,
+      (rules) =>
+        rules.lengthUnit === "graphemes" &&
+        (countsLength(rules) || (!!rules.warn && countsLength(rules.warn))),
+    );
 
-    if (needsRegex) imports.add("regexp");
-    if (needsMail) imports.add("net/mail");
-    if (needsURL) imports.add("net/url");
-
-    w.package(this.packageName);
-    if (imports.size > 0) {
-      w.import(...Array.from(imports));
-    }
-
-    // Generate error types
-    this.generateErrorTypes(w);
 
     // Generate validation functions for each type from contract
     for (const type of contract.types) {
       if (type.kind === "object" && type.properties) {
-        this.generateTypeValidation(type, w);
+        this.generateTypeValidation(type, body);
       } else if (
         type.kind === "array" &&
         type.elementType?.kind === "object" &&
@@ -82,7 +140,13 @@ export class GoValidationGenerator {
           kind: "object",
           properties: type.elementType.properties,
         };
-        this.generateTypeValidation(elementType, w);
+        this.generateTypeValidation(elementType, body);
+      } else if (isDiscriminatedUnion(type)) {
+        this.generateUnionValidation(
+          toPascalCase(type.name),
+          type as TypeReference,
+          body,
+        );
       }
     }
 
@@ -98,20 +162,138 @@ export class GoValidationGenerator {
             kind: "object",
             properties: collected.typeRef.properties,
           };
-          this.generateTypeValidation(typeDefinition, w);
+          this.generateTypeValidation(typeDefinition, body);
+        } else if (isDiscriminatedUnion(collected.typeRef)) {
+          this.generateUnionValidation(
+            collected.name,
+            collected.typeRef,
+            body,
+          );
         }
       }
     }
 
     // Generate helper functions
-    this.generateHelperFunctions(w);
+    this.generateHelperFunctions(body, needsGraphemes);
+
+    // Patterns are only known once the validation functions are generated
+    if (this.patterns.size > 0) imports.add("regexp");
+    if (this.checksMultiples) imports.add("math");
+    if (this.checkedFormats.has("ipv4") || this.checkedFormats.has("ipv6")) {
+      imports.add("net/netip");
+    }
+    if (needsMail) imports.add("net/mail");
+    if (needsURL) imports.add("net/url");
+    if (this.comparesTimes) imports.add("time");
+    if (needsGraphemes) imports.add("unicode");
+    if (needsRunes) imports.add("unicode/utf8");
+
+    w.package(this.packageName);
+    if (imports.size > 0) {
+      w.import(...Array.from(imports));
+    }
+
+    // Generate error types
+    this.generateErrorTypes(w);
+
+    // Generate compiled regex patterns
+    this.generatePatternVars(w);
+
+    if (this.recursiveTypes.size > 0) {
+      w.comment(
+        "MaxValidationDepth is how deeply recursive types are validated; values",
+      )
+        .comment("nested deeper fail validation.")
+        .l("var MaxValidationDepth = 32")
+        .n();
+    }
+
+    if (this.maxErrors !== undefined) {
+      w.comment(
+        "MaxValidationErrors is how many errors validation reports before it stops, so",
+      )
+        .comment(
+          "garbage input costs little to reject and yields a small response. 0 reports",
+        )
+        .comment("every error.")
+        .l(`var MaxValidationErrors = ${this.maxErrors}`)
+        .n();
+    }
+
+    return `${w.toString()}\n${body.toString()}`;
+  }
+
+  /**
+   * Generate a benchmark file comparing the precompiled patterns against
+   * compiling them on every call. Must be called after generateValidation;
+   * returns null when the contract has no pattern validations.
+   */
+  generateValidationTests(): string | null {
+    if (this.patterns.size === 0) {
+      return null;
+    }
+
+    const w = new GoBuilder();
+    w.package(this.packageName).import("regexp", "testing");
+    w.comment(
+      "Each pattern is benchmarked against regexp.MatchString, which compiles the",
+    )
+      .comment("pattern on every call.")
+      .n();
+
+    for (const [pattern, varName] of this.patterns) {
+      const isUUID = varName === "uuidPattern";
+      const name = isUUID ? "UUIDPattern" : toPascalCase(varName);
+      const sample = isUUID ? "3fa85f64-5717-4562-b3fc-2c963f66afa6" : "xrpc";
+
+      w.func(`Benchmark${name}(b *testing.B)`, (b) => {
+        b.l("for i := 0; i < b.N; i++ {")
+          .i()
+          .l(`${varName}.MatchString("${sample}")`)
+          .u()
+          .l("}");
+      });
+
+      w.func(`Benchmark${name}Uncompiled(b *testing.B)`, (b) => {
+        b.l("for i := 0; i < b.N; i++ {")
+          .i()
+          .l(`regexp.MatchString("${pattern}", "${sample}")`)
+          .u()
+          .l("}");
+      });
+    }
 
     return w.toString();
   }
 
   private generateErrorTypes(w: GoBuilder): void {
     w.struct("ValidationError", (b) => {
-      b.l('Field   string `json:"field"`').l('Message string `json:"message"`');
+      b.l('Field   string        `json:"field"`')
+        .l('Path    []PathSegment `json:"path,omitempty"`')
+        .l('Message string        `json:"message"`');
+    }).n();
+
+    w.doc(
+      "PathSegment is a step of the path to an invalid value: the Key of an object field or record entry, or the Index of an array item. Paths encode as JSON arrays of keys and indices, such as [\"tasks\", 1, \"title\"].",
+    )
+      .struct("PathSegment", (b) => {
+        b.l("Key     string").l("Index   int").l("IsIndex bool");
+      })
+      .n();
+
+    w.method("s PathSegment", "MarshalJSON", "", "([]byte, error)", (b) => {
+      b.if("s.IsIndex", (b) => {
+        b.return("[]byte(strconv.Itoa(s.Index)), nil");
+      }).return("json.Marshal(s.Key)");
+    }).n();
+
+    w.method("s *PathSegment", "UnmarshalJSON", "data []byte", "error", (b) => {
+      b.var("index", "int")
+        .if("err := json.Unmarshal(data, &index); err == nil", (b) => {
+          b.l("*s = PathSegment{Index: index, IsIndex: true}").return("nil");
+        })
+        .l("*s = PathSegment{}")
+        .return("json.Unmarshal(data, &s.Key)");
     }).n();
 
     w.type("ValidationErrors", "[]*ValidationError").n();
@@ -120,6 +302,30 @@ export class GoValidationGenerator {
       b.return('fmt.Sprintf("%s: %s", e.Field, e.Message)');
     }).n();
 
+    w.doc(
+      'JSONPointer renders the path of e as a JSON Pointer (RFC 6901), such as "/tasks/1/title", locating the invalid value in the params.',
+    )
+      .method("e *ValidationError", "JSONPointer", "", "string", (b) => {
+        b.var("pointer", "strings.Builder")
+          .l("for _, segment := range e.Path {")
+          .i()
+          .l('pointer.WriteString("/")')
+          .if("segment.IsIndex", (b) => {
+            b.l("pointer.WriteString(strconv.Itoa(segment.Index))").l(
+              "continue",
+            );
+          })
+          .l("pointer.WriteString(pointerEscaper.Replace(segment.Key))")
+          .u()
+          .l("}")
+          .return("pointer.String()");
+      })
+      .n();
+
+    w.comment("pointerEscaper escapes the keys of JSON Pointers")
+      .l('var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")')
+      .n();
+
     w.method("e ValidationErrors", "Error", "", "string", (b) => {
       b.var("msgs", "[]string");
       b.l("for _, err := range e {")
@@ -129,6 +335,105 @@ export class GoValidationGenerator {
         .l("}");
       b.return('strings.Join(msgs, "; ")');
     }).n();
+
+    if (this.maxErrors !== undefined) {
+      w.comment("full reports whether e holds MaxValidationErrors errors.")
+        .n()
+        .method("e ValidationErrors", "full", "", "bool", (b) => {
+          b.return("MaxValidationErrors > 0 && len(e) >= MaxValidationErrors");
+        })
+        .n();
+    }
+  }
+
+  private generatePatternVars(w: GoBuilder): void {
+    if (this.patterns.size === 0) {
+      return;
+    }
+
+    w.comment("Patterns are compiled once at startup rather than on every request")
+      .l("var (")
+      .i();
+    const width = Math.max(
+      ...Array.from(this.patterns.values(), (varName) => varName.length),
+    );
+    for (const [pattern, varName] of this.patterns) {
+      w.l(`${varName.padEnd(width)} = regexp.MustCompile("${pattern}")`);
+    }
+    w.u().l(")").n();
+  }
+
+  /**
+   * Returns the package-level variable holding the compiled pattern,
+   * registering it on first use. The pattern must already be escaped for a
+   * Go string literal.
+   */
+  /**
+   * The checks of the string formats in rules that are parsed rather than
+   * matched against Zod's patterns, as conditions on value that hold when it
+   * is invalid and descriptions of what it must be.
+   */
+  private formatChecks(
+    rules: ValidationRules,
+    value: string,
+  ): Array<[string, string]> {
+    const checks: Array<[string, string]> = [];
+    for (const format of STRING_FORMATS) {
+      if (!rules[format]) continue;
+      this.checkedFormats.add(format);
+      switch (format) {
+        case "hostname":
+          checks.push([`!isHostname(${value})`, "a valid hostname"]);
+          break;
+        case "ipv4":
+          checks.push([
+            `addr, err := netip.ParseAddr(${value}); err != nil || !addr.Is4()`,
+            "a valid IPv4 address",
+          ]);
+          break;
+        case "ipv6":
+          checks.push([
+            `addr, err := netip.ParseAddr(${value}); err != nil || !addr.Is6() || addr.Zone() != ""`,
+            "a valid IPv6 address",
+          ]);
+          break;
+        case "duration":
+          checks.push([
+            `!isDuration(${value})`,
+            "a valid ISO 8601 duration, such as PT1H30M",
+          ]);
+          break;
+        case "e164": {
+          const pattern = this.patternVar("^[+][0-9]{7,15}$", "e164");
+          checks.push([
+            `!${pattern}.MatchString(${value})`,
+            "a valid E.164 phone number, such as +14155550100",
+          ]);
+          break;
+        }
+      }
+    }
+    return checks;
+  }
+
+  private patternVar(pattern: string, fieldPathStr: string): string {
+    const existing = this.patterns.get(pattern);
+    if (existing) {
+      return existing;
+    }
+
+    const words = fieldPathStr.split(/[^a-zA-Z0-9]+/).filter(Boolean);
+    const base = words
+      .map((word, i) => (i === 0 ? word : toPascalCase(word)))
+      .join("");
+    const taken = new Set(this.patterns.values());
+    let varName = `${base}Pattern`;
+    for (let n = 2; taken.has(varName); n++) {
+      varName = `${base}Pattern${n}`;
+    }
+
+    this.patterns.set(pattern, varName);
+    return varName;
   }
 
   private generateTypeValidation(type: TypeDefinition, w: GoBuilder): void {
@@ -141,13 +446,42 @@ export class GoValidationGenerator {
     }
     this.generatedValidations.add(funcName);
 
+    const discriminator = this.discriminators.get(typeName);
+    const properties = (type.properties ?? []).filter(
+      (prop) => prop.name !== discriminator,
+    );
+
+    if (this.recursiveTypes.has(typeName)) {
+      this.generateDepthLimitedValidation(typeName, w, (b) => {
+        b.var("errs", "ValidationErrors");
+        for (const prop of properties) {
+          this.generatePropertyValidation(prop, "input", b, properties);
+        }
+        b.if("len(errs) > 0", (b) => {
+          b.return("errs");
+        });
+        b.return("nil");
+      });
+      return;
+    }
+
+    // Types with the same shape are aliases of one struct (see
+    // GoTypeGenerator), so they share its validator
+    const key = shapeKey(properties);
+    const existing = this.shapeValidators.get(key);
+    if (existing) {
+      w.func(`${funcName}(input ${typeName}) error`, (b) => {
+        b.return(`${existing}(input)`);
+      }).n();
+      return;
+    }
+    this.shapeValidators.set(key, funcName);
+
     w.func(`${funcName}(input ${typeName}) error`, (b) => {
       b.var("errs", "ValidationErrors");
 
-      if (type.properties) {
-        for (const prop of type.properties) {
-          this.generatePropertyValidation(prop, "input", b);
-        }
+      for (const prop of properties) {
+        this.generatePropertyValidation(prop, "input", b, properties);
       }
 
       b.if("len(errs) > 0", (b) => {
@@ -157,18 +491,223 @@ export class GoValidationGenerator {
     }).n();
   }
 
+  /**
+   * Generate the validator of a recursive type: Validate<Type> starts at depth
+   * 0 and validate<Type> takes the depth, which grows with every nested value
+   * of a recursive type, so deeply nested input fails instead of exhausting
+   * the stack.
+   */
+  private generateDepthLimitedValidation(
+    typeName: string,
+    w: GoBuilder,
+    body: (b: GoBuilder) => void,
+  ): void {
+    w.func(`Validate${typeName}(input ${typeName}) error`, (b) => {
+      b.return(`validate${typeName}(input, 0)`);
+    }).n();
+
+    this.depthLimited = true;
+    w.func(`validate${typeName}(input ${typeName}, depth int) error`, body).n();
+    this.depthLimited = false;
+  }
+
+  /**
+   * Open the if statement that validates a nested value of the given type.
+   * Inside a recursive validator, recursive types are validated one level
+   * deeper, and not at all past MaxValidationDepth.
+   */
+  private openValidatorCall(
+    typeName: string,
+    value: string,
+    field: string,
+    w: GoBuilder,
+  ): void {
+    if (!this.depthLimited || !this.recursiveTypes.has(typeName)) {
+      w.l(`if err := Validate${typeName}(${value}); err != nil {`);
+      return;
+    }
+    w.l("if depth >= MaxValidationDepth {")
+      .i()
+      .l(this.appendError())
+      .i()
+      .l(`Field:   ${field},`)
+      .l(`Path:    ${goPath(field)},`)
+      .l('Message: "is nested too deeply",')
+      .u()
+      .l("})")
+      .u()
+      .l(
+        `} else if err := validate${typeName}(${value}, depth+1); err != nil {`,
+      );
+  }
+
+  // Variants don't validate their discriminator: it is set when the union is
+  // encoded and checked when it is decoded
+  private collectDiscriminators(
+    contract: ContractDefinition,
+    collectedTypes: CollectedType[],
+  ): void {
+    this.discriminators.clear();
+    const unions = [
+      ...contract.types.map((type) => type as TypeReference),
+      ...collectedTypes.map((collected) => collected.typeRef),
+    ].filter(isDiscriminatedUnion);
+    for (const union of unions) {
+      for (const variant of union.unionTypes ?? []) {
+        if (variant.name) {
+          this.discriminators.set(
+            toPascalCase(variant.name),
+            union.discriminator!,
+          );
+        }
+      }
+    }
+  }
+
+  /**
+   * Validate a discriminated union by validating the variant it holds.
+   */
+  private generateUnionValidation(
+    typeName: string,
+    typeRef: TypeReference,
+    w: GoBuilder,
+  ): void {
+    const funcName = `Validate${typeName}`;
+    if (this.generatedValidations.has(funcName)) {
+      return;
+    }
+    this.generatedValidations.add(funcName);
+
+    const body = (b: GoBuilder) => {
+      b.l("switch v := input.Value.(type) {");
+      for (const variant of typeRef.unionTypes ?? []) {
+        if (!discriminatorValue(variant, typeRef.discriminator!)) continue;
+        const variantName = toPascalCase(variant.name!);
+        // The variant is the union's value, at the same depth
+        const call =
+          this.depthLimited && this.recursiveTypes.has(variantName)
+            ? `validate${variantName}(v, depth)`
+            : `Validate${variantName}(v)`;
+        b.l(`case ${variantName}:`).i().return(call).u();
+      }
+      b.l("}").return("nil");
+    };
+
+    if (this.recursiveTypes.has(typeName)) {
+      this.generateDepthLimitedValidation(typeName, w, body);
+      return;
+    }
+    w.func(`${funcName}(input ${typeName}) error`, body).n();
+  }
+
   private generatePropertyValidation(
     prop: Property,
     prefix: string,
     w: GoBuilder,
+    // The properties of its object, which its conditions refer to
+    siblings: Property[],
   ): void {
     const fieldName = toPascalCase(prop.name);
     const fieldPath = `${prefix}.${fieldName}`;
     const fieldPathStr = prop.name;
     const unwrappedType = this.unwrapOptionalNullable(prop.type);
     const isPointerType = this.isPointerType(prop.type);
+    this.sensitive = prop.sensitive === true;
+    const outer = this.appendsErrors;
+    this.appendsErrors = false;
+    this.generateConditionalValidation(prop, prefix, w, siblings);
+    this.generatePropertyValidationChecks(
+      prop,
+      fieldPath,
+      fieldPathStr,
+      unwrappedType,
+      isPointerType,
+      w,
+    );
+    this.sensitive = false;
+    const appends = this.appendsErrors;
+    this.appendsErrors = outer || appends;
+    if (appends) {
+      this.generateErrorLimit(w);
+    }
+  }
 
+  /**
+   * Check the requiredIf and forbiddenIf rules of an optional property: it
+   * must be set, or left out, when the condition on another field of its
+   * object holds. The error names both fields.
+   */
+  private generateConditionalValidation(
+    prop: Property,
+    prefix: string,
+    w: GoBuilder,
+    siblings: Property[],
+  ): void {
+    if (prop.required && prop.type.kind !== "nullable") {
+      return;
+    }
+    for (const rule of ["requiredIf", "forbiddenIf"] as const) {
+      const condition = prop.validation?.[rule];
+      const other = siblings.find(
+        (sibling) => sibling.name === condition?.field,
+      );
+      if (!condition || !other) continue;
+      const holds = goFieldCondition(
+        `${prefix}.${toPascalCase(other.name)}`,
+        this.isPointerType(other.type),
+        condition,
+      );
+      const field = `${prefix}.${toPascalCase(prop.name)}`;
+      const set = rule === "requiredIf" ? "==" : "!=";
+      const guard = holds.includes("||") ? `(${holds})` : holds;
+      const message = conditionMessage(rule, condition);
+      w.comment(`${prop.name} ${message}`);
+      w.if(`${field} ${set} nil && ${guard}`, (b) => {
+        b.l(this.appendError())
+          .i()
+          .l(`Field:   "${prop.name}",`)
+          .l(`Path:    ${keyPath(prop.name)},`)
+          .l(`Message: ${JSON.stringify(message)},`)
+          .u()
+          .l("})");
+      });
+    }
+  }
+
+  /**
+   * Start appending a ValidationError to errs, recording that the property
+   * being validated can add one.
+   */
+  private appendError(): string {
+    this.appendsErrors = true;
+    return "errs = append(errs, &ValidationError{";
+  }
+
+  /**
+   * Return the errors found so far once there are MaxValidationErrors of
+   * them, dropping those past it that a nested validator added.
+   */
+  private generateErrorLimit(w: GoBuilder): void {
+    if (this.maxErrors === undefined) {
+      return;
+    }
+    w.if("errs.full()", (b) => {
+      b.return("errs[:MaxValidationErrors]");
+    });
+  }
+
+  private generatePropertyValidationChecks(
+    prop: Property,
+    fieldPath: string,
+    fieldPathStr: string,
+    unwrappedType: TypeReference,
+    isPointerType: boolean,
+    w: GoBuilder,
+  ): void {
     if (isPointerType) {
+      if (!this.hasValueValidation(prop, unwrappedType)) {
+        return;
+      }
       w.comment(`Validate ${prop.name} when present`);
       w.if(`${fieldPath} != nil`, (b) => {
         this.generatePropertyValidationForValue(
@@ -178,6 +717,7 @@ export class GoValidationGenerator {
           unwrappedType,
           b,
           false,
+          true,
         );
       });
       return;
@@ -200,22 +740,70 @@ export class GoValidationGenerator {
     typeRef: TypeReference,
     w: GoBuilder,
     isRequired: boolean,
+    // The value is known to be set, so empty strings are validated too
+    isPresent = false,
   ): void {
     const actualType = this.getActualType(typeRef);
     const isString = actualType === "string";
     const isNumber = actualType === "number";
     const isArray = typeRef.kind === "array";
+    const isRecord = typeRef.kind === "record";
 
     const enumValues = this.getEnumValues(typeRef);
     const isEnum = enumValues !== null;
+    const validationRules = checkedRules(
+      prop.validation || prop.type.validation,
+    );
+
+    // Timestamps and dates are decoded into time.Time and Date values
+    if (actualType === "date" || (isString && isTimeFormat(validationRules))) {
+      this.generateTimeValidation(
+        prop,
+        validationRules ?? {},
+        valuePath,
+        fieldPathStr,
+        w,
+        isRequired,
+        isPresent,
+      );
+      return;
+    }
+
+    // Files are decoded into File values (see uploads.go)
+    if (typeRef.kind === "file") {
+      this.generateFileValidation(
+        prop,
+        validationRules ?? {},
+        valuePath,
+        fieldPathStr,
+        typeRef,
+        w,
+        isRequired,
+      );
+      return;
+    }
+
+    // Bytes are decoded from base64 into []byte values
+    if (actualType === "bytes") {
+      this.generateBytesValidation(
+        prop,
+        validationRules ?? {},
+        valuePath,
+        fieldPathStr,
+        w,
+        isRequired,
+      );
+      return;
+    }
 
     if (isRequired) {
       w.comment(`Validate ${prop.name}`);
       if (isEnum || isString) {
         w.if(`${valuePath} == ""`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+          b.l(this.appendError())
             .i()
             .l(`Field:   "${fieldPathStr}",`)
+            .l(`Path:    ${keyPath(fieldPathStr)},`)
             .l(`Message: "is required",`)
             .u()
             .l("})");
@@ -223,11 +811,22 @@ export class GoValidationGenerator {
       } else if (isNumber) {
         // For numbers, we can't easily check if zero is valid, so we skip required check
         // The validation rules (min/max) will handle it
-      } else if (isArray) {
+      } else if (isArray || isRecord) {
         w.if(`${valuePath} == nil`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Path:    ${keyPath(fieldPathStr)},`)
+            .l(`Message: "is required",`)
+            .u()
+            .l("})");
+        });
+      } else if (isDiscriminatedUnion(typeRef)) {
+        w.if(`${valuePath}.Value == nil`, (b) => {
+          b.l(this.appendError())
             .i()
             .l(`Field:   "${fieldPathStr}",`)
+            .l(`Path:    ${keyPath(fieldPathStr)},`)
             .l(`Message: "is required",`)
             .u()
             .l("})");
@@ -237,24 +836,39 @@ export class GoValidationGenerator {
 
     if (isEnum && enumValues) {
       const enumValuesStr = enumValues.join(", ");
-      const enumConditions = enumValues
-        .map((v) => `${valuePath} != "${v}"`)
-        .join(" && ");
-      w.if(`${valuePath} != "" && ${enumConditions}`, (b) => {
-        b.l("errs = append(errs, &ValidationError{")
+      const enumType = this.unwrapOptionalNullable(typeRef);
+      const receiver = valuePath.startsWith("*") ? `(${valuePath})` : valuePath;
+      // Typed enums (see enums.go) check their own constants
+      const enumConditions =
+        enumType.name && isStringEnum(enumType)
+          ? `!${receiver}.IsValid()`
+          : enumValues.map((v) => `${valuePath} != "${v}"`).join(" && ");
+      const enumCondition = isPresent
+        ? enumConditions
+        : `${valuePath} != "" && ${enumConditions}`;
+      w.if(enumCondition, (b) => {
+        b.l(this.appendError())
           .i()
           .l(`Field:   "${fieldPathStr}",`)
+          .l(`Path:    ${keyPath(fieldPathStr)},`)
           .l(`Message: "must be one of: ${enumValuesStr}",`)
           .u()
           .l("})");
       });
     }
 
-    const validationRules = prop.validation || prop.type.validation;
-
     if (validationRules) {
       if (!isRequired) {
-        if (isString) {
+        if (isString && isPresent) {
+          this.generateValidationRules(
+            validationRules,
+            valuePath,
+            fieldPathStr,
+            { kind: "primitive", baseType: actualType },
+            w,
+            false,
+          );
+        } else if (isString) {
           w.if(`${valuePath} != ""`, (b) => {
             const validationTypeRef: TypeReference = {
               kind: "primitive",
@@ -282,7 +896,7 @@ export class GoValidationGenerator {
             w,
             false,
           );
-        } else if (isArray) {
+        } else if (isArray || isRecord) {
           w.if(`${valuePath} != nil`, (b) => {
             this.generateValidationRules(
               validationRules,
@@ -303,7 +917,7 @@ export class GoValidationGenerator {
             false,
           );
         }
-      } else if (isArray) {
+      } else if (isArray || isRecord) {
         this.generateValidationRules(
           validationRules,
           valuePath,
@@ -328,20 +942,22 @@ export class GoValidationGenerator {
       }
     }
 
-    if (typeRef.kind === "object" && typeRef.name) {
-      const nestedTypeName = toPascalCase(typeRef.name);
-      const nestedFuncName = `Validate${nestedTypeName}`;
-      w.l(`if err := ${nestedFuncName}(${valuePath}); err != nil {`)
-        .i()
-        .l("if nestedErrs, ok := err.(ValidationErrors); ok {")
-        .i()
-        .l("errs = append(errs, nestedErrs...)")
-        .u()
+    if (hasValidator(typeRef)) {
+      this.openValidatorCall(
+        toPascalCase(typeRef.name!),
+        valuePath,
+        `"${fieldPathStr}"`,
+        w,
+      );
+      w.i().l("if nestedErrs, ok := err.(ValidationErrors); ok {").i();
+      this.generateNestedErrors(fieldPathStr, [], w);
+      w.u()
         .l("} else {")
         .i()
-        .l("errs = append(errs, &ValidationError{")
+        .l(this.appendError())
         .i()
         .l(`Field:   "${fieldPathStr}",`)
+        .l(`Path:    ${keyPath(fieldPathStr)},`)
         .l("Message: err.Error(),")
         .u()
         .l("})")
@@ -351,44 +967,390 @@ export class GoValidationGenerator {
         .l("}");
     }
 
-    if (typeRef.kind === "array" && typeRef.elementType) {
-      const elementTypeRef = this.unwrapOptionalNullable(typeRef.elementType);
-      if (elementTypeRef.kind === "object" && elementTypeRef.name) {
-        const elementTypeName = toPascalCase(elementTypeRef.name);
-        const elementFuncName = `Validate${elementTypeName}`;
-        const isElementPointer = this.isPointerType(typeRef.elementType);
-
-        w.l(`for i, item := range ${valuePath} {`).i();
-        if (isElementPointer) {
-          w.l("if item == nil { continue }");
-          w.l(`if err := ${elementFuncName}(*item); err != nil {`);
-        } else {
-          w.l(`if err := ${elementFuncName}(item); err != nil {`);
-        }
-        w.i()
-          .l("if nestedErrs, ok := err.(ValidationErrors); ok {")
-          .i()
-          .l("for _, nestedErr := range nestedErrs {")
-          .i()
-          .l("errs = append(errs, &ValidationError{")
+    if ((isArray || isRecord) && this.hasItemValidation(typeRef)) {
+      this.generateContainerValidation(
+        valuePath,
+        typeRef,
+        fieldPathStr,
+        [],
+        w,
+        0,
+      );
+    }
+  }
+
+  /**
+   * Validate a time.Time or Date value: a required value must not be the zero
+   * time, and future/past compare it with the time of the request.
+   */
+  private generateTimeValidation(
+    prop: Property,
+    rules: ValidationRules,
+    valuePath: string,
+    fieldPathStr: string,
+    w: GoBuilder,
+    isRequired: boolean,
+    isPresent: boolean,
+  ): void {
+    const receiver = valuePath.startsWith("*") ? `(${valuePath})` : valuePath;
+
+    if (isRequired) {
+      w.comment(`Validate ${prop.name}`);
+      w.if(`${receiver}.IsZero()`, (b) => {
+        b.l(this.appendError())
           .i()
-          .l(
-            `Field:   fmt.Sprintf("${fieldPathStr}[%%d].%%s", i, nestedErr.Field),`,
-          )
-          .l("Message: nestedErr.Message,")
-          .u()
-          .l("})")
+          .l(`Field:   "${fieldPathStr}",`)
+          .l(`Path:    ${keyPath(fieldPathStr)},`)
+          .l(`Message: "is required",`)
           .u()
-          .l("}")
+          .l("})");
+      });
+    }
+
+    const ranges: Array<[boolean | undefined, string, string]> = [
+      [rules.future, "After", "must be in the future"],
+      [rules.past, "Before", "must be in the past"],
+    ];
+    for (const [enabled, method, message] of ranges) {
+      if (!enabled) continue;
+      this.comparesTimes = true;
+      const check = `!${receiver}.${method}(time.Now())`;
+      w.if(isPresent ? check : `!${receiver}.IsZero() && ${check}`, (b) => {
+        b.l(this.appendError())
+          .i()
+          .l(`Field:   "${fieldPathStr}",`)
+          .l(`Path:    ${keyPath(fieldPathStr)},`)
+          .l(`Message: "${message}",`)
           .u()
-          .l("}")
+          .l("})");
+      });
+    }
+
+    if (rules.custom) {
+      this.generateCustomValidation(
+        rules.custom,
+        valuePath,
+        `"${fieldPathStr}"`,
+        w,
+        isPresent ? undefined : `!${receiver}.IsZero()`,
+      );
+    }
+  }
+
+  /**
+   * Validate a File value: a required file must be set, and its size and
+   * content type must match the rules.
+   */
+  private generateFileValidation(
+    prop: Property,
+    rules: ValidationRules,
+    valuePath: string,
+    fieldPathStr: string,
+    typeRef: TypeReference,
+    w: GoBuilder,
+    isRequired: boolean,
+  ): void {
+    const receiver = valuePath.startsWith("*") ? `(${valuePath})` : valuePath;
+
+    if (isRequired) {
+      w.comment(`Validate ${prop.name}`);
+      w.if(`${receiver}.IsZero()`, (b) => {
+        b.l(this.appendError())
+          .i()
+          .l(`Field:   "${fieldPathStr}",`)
+          .l(`Path:    ${keyPath(fieldPathStr)},`)
+          .l(`Message: "is required",`)
           .u()
-          .l("}")
+          .l("})");
+      });
+    }
+
+    this.generateValidationRules(
+      rules,
+      valuePath,
+      fieldPathStr,
+      typeRef,
+      w,
+      isRequired,
+    );
+  }
+
+  /**
+   * Validate a bytes field. Missing and null values decode to a nil slice,
+   * so only nil is reported as required; an empty base64 string is set.
+   */
+  private generateBytesValidation(
+    prop: Property,
+    rules: ValidationRules,
+    valuePath: string,
+    fieldPathStr: string,
+    w: GoBuilder,
+    isRequired: boolean,
+  ): void {
+    const typeRef: TypeReference = { kind: "primitive", baseType: "bytes" };
+
+    if (isRequired) {
+      w.comment(`Validate ${prop.name}`);
+      w.if(`${valuePath} == nil`, (b) => {
+        b.l(this.appendError())
+          .i()
+          .l(`Field:   "${fieldPathStr}",`)
+          .l(`Path:    ${keyPath(fieldPathStr)},`)
+          .l(`Message: "is required",`)
           .u()
-          .l("}");
-        w.u().l("}");
+          .l("})");
+      });
+      this.generateValidationRules(
+        rules,
+        valuePath,
+        fieldPathStr,
+        typeRef,
+        w,
+        true,
+      );
+      return;
+    }
+
+    if (
+      rules.minSize !== undefined ||
+      rules.maxSize !== undefined ||
+      rules.custom
+    ) {
+      w.if(`${valuePath} != nil`, (b) => {
+        this.generateValidationRules(
+          rules,
+          valuePath,
+          fieldPathStr,
+          typeRef,
+          b,
+          false,
+        );
+      });
+    }
+  }
+
+  /**
+   * Loop over an array or record and validate every item it contains: the
+   * rules on record keys and on items, and the objects it contains,
+   * descending into nested arrays and records. Errors are reported with the
+   * index or key path, e.g. "grid[1][0].x" or "byName.alice.count".
+   */
+  private generateContainerValidation(
+    valuePath: string,
+    typeRef: TypeReference,
+    pathFormat: string,
+    pathArgs: string[],
+    w: GoBuilder,
+    depth: number,
+  ): void {
+    const item = depth === 0 ? "item" : `item${depth}`;
+
+    if (typeRef.kind === "array" && typeRef.elementType) {
+      const index = ["i", "j", "k"][depth] ?? `i${depth}`;
+      w.l(`for ${index}, ${item} := range ${valuePath} {`).i();
+      this.generateItemValidation(
+        item,
+        typeRef.elementType,
+        `${pathFormat}[%d]`,
+        [...pathArgs, index],
+        w,
+        depth,
+      );
+      this.generateErrorLimit(w);
+      w.u().l("}");
+    } else if (typeRef.kind === "record" && typeRef.valueType) {
+      const key = depth === 0 ? "key" : `key${depth}`;
+      const value = this.hasItemValidation(typeRef.valueType, true)
+        ? item
+        : "_";
+      w.l(`for ${key}, ${value} := range ${valuePath} {`).i();
+      const keyRules = checkedRules(typeRef.keyType?.validation);
+      if (keyRules) {
+        this.generateKeyValidation(
+          key,
+          keyRules,
+          `${pathFormat}.%s`,
+          [...pathArgs, key],
+          w,
+        );
+      }
+      if (value !== "_") {
+        this.generateItemValidation(
+          item,
+          typeRef.valueType,
+          `${pathFormat}.%s`,
+          [...pathArgs, key],
+          w,
+          depth,
+        );
       }
+      this.generateErrorLimit(w);
+      w.u().l("}");
+    }
+  }
+
+  /**
+   * Check a record key against the rules of the record's key schema, e.g. a
+   * pattern. Errors are reported at the entry and name the key.
+   */
+  private generateKeyValidation(
+    key: string,
+    rules: ValidationRules,
+    pathFormat: string,
+    pathArgs: string[],
+    w: GoBuilder,
+  ): void {
+    const checks: Array<[string, string]> = [];
+    if (rules.minLength !== undefined) {
+      checks.push([
+        `${goStringLength(key, rules.lengthUnit)} < ${rules.minLength}`,
+        `fmt.Sprintf("key must be at least %d character(s)", ${rules.minLength})`,
+      ]);
+    }
+    if (rules.maxLength !== undefined) {
+      checks.push([
+        `${goStringLength(key, rules.lengthUnit)} > ${rules.maxLength}`,
+        `fmt.Sprintf("key must be at most %d character(s)", ${rules.maxLength})`,
+      ]);
+    }
+    if (rules.regex) {
+      const escapedRegex = rules.regex
+        .replace(/\\/g, "\\\\")
+        .replace(/"/g, '\\"');
+      const pattern = this.patternVar(
+        escapedRegex,
+        `${containerName(pathFormat)}Key`,
+      );
+      checks.push([
+        `!${pattern}.MatchString(${key})`,
+        '"key must match the required pattern"',
+      ]);
+    }
+    for (const [condition, description] of this.formatChecks(rules, key)) {
+      checks.push([condition, `"key must be ${description}"`]);
+    }
+    if (rules.custom) {
+      // The condition declares the validator's error, which is the message
+      checks.push([
+        `err := validators[${JSON.stringify(rules.custom)}](${key}); err != nil`,
+        this.sensitive ? '"key is invalid"' : '"key " + err.Error()',
+      ]);
+    }
+
+    const field = sprintfField(pathFormat, pathArgs);
+    for (const [condition, message] of checks) {
+      w.if(condition, (b) => {
+        b.l(this.appendError())
+          .i()
+          .l(`Field:   ${field},`)
+          .l(`Path:    ${goPath(field)},`)
+          .l(`Message: ${message},`)
+          .u()
+          .l("})");
+      });
+    }
+  }
+
+  private generateItemValidation(
+    item: string,
+    typeRef: TypeReference,
+    pathFormat: string,
+    pathArgs: string[],
+    w: GoBuilder,
+    depth: number,
+  ): void {
+    const unwrapped = this.unwrapOptionalNullable(typeRef);
+    let value = item;
+    if (this.isPointerType(typeRef)) {
+      w.l(`if ${item} == nil { continue }`);
+      value = `*${item}`;
+    }
+
+    const rules = itemRules(typeRef, unwrapped);
+    if (rules) {
+      this.generateValidationRules(
+        rules,
+        value,
+        `${containerName(pathFormat)}Value`,
+        unwrapped.kind === "primitive"
+          ? { kind: "primitive", baseType: this.getActualType(unwrapped) }
+          : unwrapped,
+        w,
+        false,
+        sprintfField(pathFormat, pathArgs),
+      );
+    }
+
+    if (hasValidator(unwrapped)) {
+      this.openValidatorCall(
+        toPascalCase(unwrapped.name!),
+        value,
+        sprintfField(pathFormat, pathArgs),
+        w,
+      );
+      w.i().l("if nestedErrs, ok := err.(ValidationErrors); ok {").i();
+      this.generateNestedErrors(pathFormat, pathArgs, w);
+      w.u().l("}").u().l("}");
+      return;
+    }
+
+    if (this.hasItemValidation(unwrapped)) {
+      this.generateContainerValidation(
+        value,
+        unwrapped,
+        pathFormat,
+        pathArgs,
+        w,
+        depth + 1,
+      );
+    }
+  }
+
+  /**
+   * Add the errors of a nested validator, nestedErrs, prefixing their fields
+   * and paths with the value's, e.g. "tasks[1].title" for the "title" of the
+   * second task.
+   */
+  private generateNestedErrors(
+    pathFormat: string,
+    pathArgs: string[],
+    w: GoBuilder,
+  ): void {
+    const args = [...pathArgs, "nestedErr.Field"].join(", ");
+    w.l("for _, nestedErr := range nestedErrs {")
+      .i()
+      .l(this.appendError())
+      .i()
+      .l(`Field:   fmt.Sprintf("${pathFormat}.%s", ${args}),`)
+      .l(`Path:    append(${pathOf(pathFormat, pathArgs)}, nestedErr.Path...),`)
+      .l("Message: nestedErr.Message,")
+      .u()
+      .l("})")
+      .u()
+      .l("}");
+  }
+
+  // Whether the items of an array or record are validated: objects that have
+  // validators, and rules on record keys and on items. With isItem, the rules
+  // of the value itself count too.
+  private hasItemValidation(typeRef: TypeReference, isItem = false): boolean {
+    const unwrapped = this.unwrapOptionalNullable(typeRef);
+    if (isItem && itemRules(typeRef, unwrapped)) {
+      return true;
+    }
+    if (unwrapped.kind === "object" || unwrapped.kind === "union") {
+      return hasValidator(unwrapped);
     }
+    if (unwrapped.kind === "array" && unwrapped.elementType) {
+      return this.hasItemValidation(unwrapped.elementType, true);
+    }
+    if (unwrapped.kind === "record" && unwrapped.valueType) {
+      return (
+        !!checkedRules(unwrapped.keyType?.validation) ||
+        this.hasItemValidation(unwrapped.valueType, true)
+      );
+    }
+    return false;
   }
 
   private generateValidationRules(
@@ -398,19 +1360,23 @@ export class GoValidationGenerator {
     typeRef: TypeReference,
     w: GoBuilder,
     isRequired = false,
+    // Go expression for the error's field, for paths built at runtime
+    field = `"${fieldPathStr}"`,
   ): void {
     if (typeRef.baseType === "string") {
+      const length = goStringLength(fieldPath, rules.lengthUnit);
       // For required fields, skip length checks if empty (already handled by required check)
       // For optional fields, we only get here if field is not empty
       if (rules.minLength !== undefined) {
         // Only check length if string is not empty (for required fields)
         const minLengthCondition = isRequired
-          ? `${fieldPath} != "" && len(${fieldPath}) < ${rules.minLength}`
-          : `len(${fieldPath}) < ${rules.minLength}`;
+          ? `${fieldPath} != "" && ${length} < ${rules.minLength}`
+          : `${length} < ${rules.minLength}`;
         w.if(minLengthCondition, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(
               `Message: fmt.Sprintf("must be at least %d character(s)", ${rules.minLength}),`,
             )
@@ -419,10 +1385,11 @@ export class GoValidationGenerator {
         });
       }
       if (rules.maxLength !== undefined) {
-        w.if(`len(${fieldPath}) > ${rules.maxLength}`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+        w.if(`${length} > ${rules.maxLength}`, (b) => {
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(
               `Message: fmt.Sprintf("must be at most %d character(s)", ${rules.maxLength}),`,
             )
@@ -439,9 +1406,10 @@ export class GoValidationGenerator {
           w.if(`${fieldPath} != ""`, (b) => {
             b.l(`if _, err := mail.ParseAddress(${fieldPath}); err != nil {`)
               .i()
-              .l("errs = append(errs, &ValidationError{")
+              .l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(`Message: "must be a valid email address",`)
               .u()
               .l("})")
@@ -452,9 +1420,10 @@ export class GoValidationGenerator {
           // Already in "if fieldPath != "" block", so validate directly
           w.l(`if _, err := mail.ParseAddress(${fieldPath}); err != nil {`)
             .i()
-            .l("errs = append(errs, &ValidationError{")
+            .l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must be a valid email address",`)
             .u()
             .l("})")
@@ -470,9 +1439,10 @@ export class GoValidationGenerator {
               `if u, err := url.Parse(${fieldPath}); err != nil || u.Scheme == "" || u.Host == "" {`,
             )
               .i()
-              .l("errs = append(errs, &ValidationError{")
+              .l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(`Message: "must be a valid URL",`)
               .u()
               .l("})")
@@ -484,9 +1454,10 @@ export class GoValidationGenerator {
             `if u, err := url.Parse(${fieldPath}); err != nil || u.Scheme == "" || u.Host == "" {`,
           )
             .i()
-            .l("errs = append(errs, &ValidationError{")
+            .l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must be a valid URL",`)
             .u()
             .l("})")
@@ -496,17 +1467,20 @@ export class GoValidationGenerator {
       }
       // UUID validation
       if (rules.uuid) {
+        const uuidPattern = this.patternVar(
+          "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$",
+          "uuid",
+        );
         if (isRequired) {
           w.if(`${fieldPath} != ""`, (b) => {
-            b.l(
-              `matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", ${fieldPath})`,
-            )
+            b.l(`matched := ${uuidPattern}.MatchString(${fieldPath})`)
               .n()
               .l("if !matched {")
               .i()
-              .l("errs = append(errs, &ValidationError{")
+              .l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(`Message: "must be a valid UUID",`)
               .u()
               .l("})")
@@ -514,15 +1488,14 @@ export class GoValidationGenerator {
               .l("}");
           });
         } else {
-          w.l(
-            `matched, _ := regexp.MatchString("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$", ${fieldPath})`,
-          )
+          w.l(`matched := ${uuidPattern}.MatchString(${fieldPath})`)
             .n()
             .l("if !matched {")
             .i()
-            .l("errs = append(errs, &ValidationError{")
+            .l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must be a valid UUID",`)
             .u()
             .l("})")
@@ -530,23 +1503,43 @@ export class GoValidationGenerator {
             .l("}");
         }
       }
+      for (const [condition, description] of this.formatChecks(
+        rules,
+        fieldPath,
+      )) {
+        const check = (b: GoBuilder) =>
+          b.if(condition, (b) => {
+            b.l(this.appendError())
+              .i()
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
+              .l(`Message: "must be ${description}",`)
+              .u()
+              .l("})");
+          });
+        if (isRequired) {
+          w.if(`${fieldPath} != ""`, check);
+        } else {
+          check(w);
+        }
+      }
       // Custom regex validation (only if not email/url/uuid which have dedicated validators)
       if (rules.regex && !rules.email && !rules.url && !rules.uuid) {
+        // Escape the regex pattern for Go
+        const escapedRegex = rules.regex
+          .replace(/\\/g, "\\\\")
+          .replace(/"/g, '\\"');
+        const pattern = this.patternVar(escapedRegex, fieldPathStr);
         if (isRequired) {
           w.if(`${fieldPath} != ""`, (b) => {
-            // Escape the regex pattern for Go
-            const escapedRegex = rules.regex
-              ?.replace(/\\/g, "\\\\")
-              .replace(/"/g, '\\"');
-            b.l(
-              `matched, _ := regexp.MatchString("${escapedRegex}", ${fieldPath})`,
-            )
+            b.l(`matched := ${pattern}.MatchString(${fieldPath})`)
               .n()
               .l("if !matched {")
               .i()
-              .l("errs = append(errs, &ValidationError{")
+              .l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(`Message: "must match the required pattern",`)
               .u()
               .l("})")
@@ -554,19 +1547,14 @@ export class GoValidationGenerator {
               .l("}");
           });
         } else {
-          // Escape the regex pattern for Go
-          const escapedRegex = rules.regex
-            ?.replace(/\\/g, "\\\\")
-            .replace(/"/g, '\\"');
-          w.l(
-            `matched, _ := regexp.MatchString("${escapedRegex}", ${fieldPath})`,
-          )
+          w.l(`matched := ${pattern}.MatchString(${fieldPath})`)
             .n()
             .l("if !matched {")
             .i()
-            .l("errs = append(errs, &ValidationError{")
+            .l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must match the required pattern",`)
             .u()
             .l("})")
@@ -575,41 +1563,42 @@ export class GoValidationGenerator {
         }
       }
     } else if (typeRef.baseType === "number") {
-      if (rules.min !== undefined) {
-        w.if(`${fieldPath} < ${rules.min}`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
-            .i()
-            .l(`Field:   "${fieldPathStr}",`)
-            .l(`Message: fmt.Sprintf("must be at least %v", ${rules.min}),`)
-            .u()
-            .l("})");
-        });
-      }
-      if (rules.max !== undefined) {
-        w.if(`${fieldPath} > ${rules.max}`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+      // Integer fields are generated as int, so the JSON decoder already
+      // rejects fractions and bounds are rounded to whole numbers
+      const min =
+        rules.int && rules.min !== undefined ? Math.ceil(rules.min) : rules.min;
+      const max =
+        rules.int && rules.max !== undefined
+          ? Math.floor(rules.max)
+          : rules.max;
+      if (min !== undefined) {
+        w.if(`${fieldPath} < ${min}`, (b) => {
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
-            .l(`Message: fmt.Sprintf("must be at most %v", ${rules.max}),`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(`Message: fmt.Sprintf("must be at least %v", ${min}),`)
             .u()
             .l("})");
         });
       }
-      if (rules.int) {
-        w.if(`float64(${fieldPath}) != float64(int64(${fieldPath}))`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+      if (max !== undefined) {
+        w.if(`${fieldPath} > ${max}`, (b) => {
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
-            .l(`Message: "must be an integer",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(`Message: fmt.Sprintf("must be at most %v", ${max}),`)
             .u()
             .l("})");
         });
       }
       if (rules.positive) {
         w.if(`${fieldPath} <= 0`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must be positive",`)
             .u()
             .l("})");
@@ -617,14 +1606,71 @@ export class GoValidationGenerator {
       }
       if (rules.negative) {
         w.if(`${fieldPath} >= 0`, (b) => {
-          b.l("errs = append(errs, &ValidationError{")
+          b.l(this.appendError())
             .i()
-            .l(`Field:   "${fieldPathStr}",`)
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
             .l(`Message: "must be negative",`)
             .u()
             .l("})");
         });
       }
+      // Exclusive bounds of integers are compared with the whole numbers
+      // next to them: greater than 1.5 is greater than 1
+      if (rules.exclusiveMin !== undefined) {
+        const bound = rules.int
+          ? Math.floor(rules.exclusiveMin)
+          : rules.exclusiveMin;
+        w.if(`${fieldPath} <= ${bound}`, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must be greater than %v", ${rules.exclusiveMin}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+      if (rules.exclusiveMax !== undefined) {
+        const bound = rules.int
+          ? Math.ceil(rules.exclusiveMax)
+          : rules.exclusiveMax;
+        w.if(`${fieldPath} >= ${bound}`, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must be less than %v", ${rules.exclusiveMax}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+      if (rules.multipleOf !== undefined) {
+        // Fractional multiples, and multiples of floats, allow for the
+        // rounding of floating point
+        const wholeMultiple = rules.int && Number.isInteger(rules.multipleOf);
+        if (!wholeMultiple) {
+          this.checksMultiples = true;
+        }
+        const condition = wholeMultiple
+          ? `${fieldPath}%${rules.multipleOf} != 0`
+          : `!isMultipleOf(float64(${fieldPath}), ${rules.multipleOf})`;
+        w.if(condition, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must be a multiple of %v", ${rules.multipleOf}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
     } else if (typeRef.kind === "array") {
       // Only validate array length if array is not nil
       const arrayCheckCondition = isRequired
@@ -634,9 +1680,10 @@ export class GoValidationGenerator {
         w.if(
           `${arrayCheckCondition} && len(${fieldPath}) < ${rules.minItems}`,
           (b) => {
-            b.l("errs = append(errs, &ValidationError{")
+            b.l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(
                 `Message: fmt.Sprintf("must have at least %d item(s)", ${rules.minItems}),`,
               )
@@ -649,9 +1696,10 @@ export class GoValidationGenerator {
         w.if(
           `${arrayCheckCondition} && len(${fieldPath}) > ${rules.maxItems}`,
           (b) => {
-            b.l("errs = append(errs, &ValidationError{")
+            b.l(this.appendError())
               .i()
-              .l(`Field:   "${fieldPathStr}",`)
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
               .l(
                 `Message: fmt.Sprintf("must have at most %d item(s)", ${rules.maxItems}),`,
               )
@@ -660,6 +1708,176 @@ export class GoValidationGenerator {
           },
         );
       }
+      if (rules.uniqueItems && typeRef.elementType) {
+        const items = fieldPath.startsWith("*") ? `(${fieldPath})` : fieldPath;
+        // Named so as not to shadow the indexes of enclosing item loops, which
+        // the field can refer to
+        let key = `${items}[at]`;
+        if (!this.isComparableItem(typeRef.elementType)) {
+          key = `jsonKey(${key})`;
+          this.comparesJSON = true;
+        }
+        this.checksUniqueItems = true;
+        w.if(
+          `dup, first := duplicateItem(len(${fieldPath}), func(at int) interface{} { return ${key} }); dup >= 0`,
+          (b) => {
+            b.l(this.appendError())
+              .i()
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
+              .l(
+                'Message: fmt.Sprintf("must have unique items: item %d equals item %d", dup, first),',
+              )
+              .u()
+              .l("})");
+          },
+        );
+      }
+    } else if (typeRef.kind === "record") {
+      // A missing required record is reported as required, not as empty
+      const minEntriesCondition = isRequired
+        ? `${fieldPath} != nil && len(${fieldPath}) < ${rules.minEntries}`
+        : `len(${fieldPath}) < ${rules.minEntries}`;
+      if (rules.minEntries !== undefined) {
+        w.if(minEntriesCondition, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must have at least %d entries", ${rules.minEntries}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+      if (rules.maxEntries !== undefined) {
+        w.if(`len(${fieldPath}) > ${rules.maxEntries}`, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must have at most %d entries", ${rules.maxEntries}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+    } else if (typeRef.kind === "file" || typeRef.baseType === "bytes") {
+      const receiver = fieldPath.startsWith("*")
+        ? `(${fieldPath})`
+        : fieldPath;
+      // Files carry their size, bytes are []byte slices
+      const isFile = typeRef.kind === "file";
+      const size = isFile ? `${receiver}.Size` : `len(${fieldPath})`;
+      // A missing required value is reported as required, not as too small
+      let present = "";
+      if (isRequired) {
+        present = isFile
+          ? `!${receiver}.IsZero() && `
+          : `${fieldPath} != nil && `;
+      }
+      if (rules.minSize !== undefined) {
+        w.if(`${present}${size} < ${rules.minSize}`, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must be at least %d byte(s)", ${rules.minSize}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+      if (rules.maxSize !== undefined) {
+        w.if(`${size} > ${rules.maxSize}`, (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              `Message: fmt.Sprintf("must be at most %d byte(s)", ${rules.maxSize}),`,
+            )
+            .u()
+            .l("})");
+        });
+      }
+      if (rules.mimeTypes && rules.mimeTypes.length > 0) {
+        const message = `must be of type: ${rules.mimeTypes.join(", ")}`;
+        const types = rules.mimeTypes.map((type) => JSON.stringify(type));
+        w.if(
+          `${present}!${receiver}.HasContentType(${types.join(", ")})`,
+          (b) => {
+            b.l(this.appendError())
+              .i()
+              .l(`Field:   ${field},`)
+              .l(`Path:    ${goPath(field)},`)
+              .l(`Message: ${JSON.stringify(message)},`)
+              .u()
+              .l("})");
+          },
+        );
+      }
+    }
+
+    if (rules.custom) {
+      // Empty required values are already reported as required
+      let guard: string | undefined;
+      if (isRequired && typeRef.baseType === "string") {
+        guard = `${fieldPath} != ""`;
+      } else if (isRequired && typeRef.kind === "file") {
+        const receiver = fieldPath.startsWith("*")
+          ? `(${fieldPath})`
+          : fieldPath;
+        guard = `!${receiver}.IsZero()`;
+      } else if (
+        isRequired &&
+        (typeRef.kind === "array" ||
+          typeRef.kind === "record" ||
+          typeRef.baseType === "bytes")
+      ) {
+        guard = `${fieldPath} != nil`;
+      }
+      this.generateCustomValidation(rules.custom, fieldPath, field, w, guard);
+    }
+  }
+
+  /**
+   * Call the custom validator registered under name (see validators.go) and
+   * report the error it returns as the field's message, or "is invalid" for
+   * sensitive fields, as the error may quote the value.
+   */
+  private generateCustomValidation(
+    name: string,
+    value: string,
+    field: string,
+    w: GoBuilder,
+    guard?: string,
+  ): void {
+    const check = (b: GoBuilder) => {
+      b.if(
+        `err := validators[${JSON.stringify(name)}](${value}); err != nil`,
+        (b) => {
+          b.l(this.appendError())
+            .i()
+            .l(`Field:   ${field},`)
+            .l(`Path:    ${goPath(field)},`)
+            .l(
+              this.sensitive
+                ? 'Message: "is invalid",'
+                : "Message: err.Error(),",
+            )
+            .u()
+            .l("})");
+        },
+      );
+    };
+    if (guard) {
+      w.if(guard, check);
+    } else {
+      check(w);
     }
   }
 
@@ -751,6 +1969,13 @@ export class GoValidationGenerator {
       }
     }
 
+    // Check record keys and values
+    for (const entryType of [typeRef.keyType, typeRef.valueType]) {
+      if (entryType && this.checkTypeRefForValidationRule(entryType, check)) {
+        return true;
+      }
+    }
+
     // Unwrap optional/nullable
     if (
       (typeRef.kind === "optional" || typeRef.kind === "nullable") &&
@@ -764,9 +1989,283 @@ export class GoValidationGenerator {
     return false;
   }
 
-  private generateHelperFunctions(w: GoBuilder): void {
-    // Helper functions can be added here if needed
-    // For now, we use standard library functions directly
+  private generateHelperFunctions(
+    w: GoBuilder,
+    needsGraphemes: boolean,
+  ): void {
+    if (needsGraphemes) {
+      this.generateGraphemeCount(w);
+    }
+    if (this.checksUniqueItems) {
+      this.generateDuplicateItem(w);
+    }
+    if (this.checksMultiples) {
+      this.generateIsMultipleOf(w);
+    }
+    if (this.checkedFormats.has("hostname")) {
+      this.generateIsHostname(w);
+    }
+    if (this.checkedFormats.has("duration")) {
+      this.generateIsDuration(w);
+    }
+  }
+
+  private generateIsHostname(w: GoBuilder): void {
+    w.comment(
+      "isHostname reports whether s is a DNS hostname: dot-separated labels of up to",
+    )
+      .comment(
+        "63 letters, digits and hyphens that neither start nor end with a hyphen, and",
+      )
+      .comment("at most 253 characters in all.")
+      .n()
+      .func("isHostname(s string) bool", (b) => {
+        b.if('s == "" || len(s) > 253', (b) => {
+          b.return("false");
+        })
+          .l('for _, label := range strings.Split(s, ".") {')
+          .i()
+          .if(
+            'label == "" || len(label) > 63 || label[0] == \'-\' || label[len(label)-1] == \'-\'',
+            (b) => {
+              b.return("false");
+            },
+          )
+          .l("for _, c := range label {")
+          .i()
+          .if(
+            "!(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-')",
+            (b) => {
+              b.return("false");
+            },
+          )
+          .u()
+          .l("}")
+          .u()
+          .l("}")
+          .return("true");
+      });
+  }
+
+  private generateIsDuration(w: GoBuilder): void {
+    w.comment(
+      "isDuration reports whether s is an ISO 8601 duration, such as P3Y6M4DT12H30M5S",
+    )
+      .comment(
+        "or P2W: units in order, each after a number, with a fraction only on seconds.",
+      )
+      .n()
+      .func("isDuration(s string) bool", (b) => {
+        b.decl("rest, ok", 'strings.CutPrefix(s, "P")')
+          .if('!ok || rest == ""', (b) => {
+            b.return("false");
+          })
+          .decl("date, clock, timed", 'strings.Cut(rest, "T")')
+          .if('timed && clock == ""', (b) => {
+            b.return("false");
+          })
+          .if('!timed && strings.HasSuffix(date, "W")', (b) => {
+            b.return('durationUnits(date, "W")');
+          })
+          .return('durationUnits(date, "YMD") && durationUnits(clock, "HMS")');
+      });
+
+    w.comment(
+      "durationUnits reports whether s is a sequence of numbers, each followed by one",
+    )
+      .comment("of units in their order, where only seconds may have a fraction.")
+      .n()
+      .func("durationUnits(s, units string) bool", (b) => {
+        b.l('for s != "" {')
+          .i()
+          .decl("i", "0")
+          .l("for i < len(s) && s[i] >= '0' && s[i] <= '9' {")
+          .i()
+          .l("i++")
+          .u()
+          .l("}")
+          .decl("digits", "i")
+          .if("i < len(s) && (s[i] == '.' || s[i] == ',')", (b) => {
+            b.l("i++")
+              .l("for i < len(s) && s[i] >= '0' && s[i] <= '9' {")
+              .i()
+              .l("i++")
+              .u()
+              .l("}")
+              .if("i == digits+1 || i == len(s) || s[i] != 'S'", (b) => {
+                b.return("false");
+              });
+          })
+          .if("digits == 0 || i == len(s)", (b) => {
+            b.return("false");
+          })
+          .decl("unit", "strings.IndexByte(units, s[i])")
+          .if("unit < 0", (b) => {
+            b.return("false");
+          })
+          .l("units, s = units[unit+1:], s[i+1:]")
+          .u()
+          .l("}")
+          .return("true");
+      });
+  }
+
+  private generateIsMultipleOf(w: GoBuilder): void {
+    w.comment(
+      "isMultipleOf reports whether value is a whole number of steps, allowing for",
+    )
+      .comment(
+        "the rounding of steps such as 0.1 that floating point cannot represent.",
+      )
+      .n()
+      .func("isMultipleOf(value, step float64) bool", (b) => {
+        b.decl("steps", "value / step").return(
+          "math.Abs(steps-math.Round(steps)) <= 1e-9*math.Max(1, math.Abs(steps))",
+        );
+      });
+  }
+
+  private generateDuplicateItem(w: GoBuilder): void {
+    w.comment(
+      "duplicateItem returns the index of the first of n items whose key equals the key",
+    )
+      .comment("of an earlier item, and the index of that item, or -1, -1.")
+      .n()
+      .func(
+        "duplicateItem(n int, key func(i int) interface{}) (int, int)",
+        (b) => {
+          b.decl("seen", "make(map[interface{}]int, n)")
+            .l("for i := 0; i < n; i++ {")
+            .i()
+            .decl("k", "key(i)")
+            .if("first, ok := seen[k]; ok", (b) => {
+              b.return("i, first");
+            })
+            .l("seen[k] = i")
+            .u()
+            .l("}")
+            .return("-1, -1");
+        },
+      );
+
+    if (this.comparesJSON) {
+      w.comment(
+        "jsonKey is the key of an item that is not comparable, such as a struct: its",
+      )
+        .comment(
+          "JSON, in which struct fields keep their order and map keys are sorted.",
+        )
+        .n()
+        .func("jsonKey(item interface{}) interface{}", (b) => {
+          b.decl("data, _", "json.Marshal(item)").return("string(data)");
+        });
+    }
+  }
+
+  // graphemeCount approximates extended grapheme clusters with the standard
+  // library, which has no segmentation of its own
+  private generateGraphemeCount(w: GoBuilder): void {
+    w.comment(
+      "graphemeCount approximates the number of characters a user sees in s, the",
+    )
+      .comment(
+        "extended grapheme clusters of Unicode (UAX #29), with the standard library,",
+      )
+      .comment(
+        "which has no segmentation: combining marks, variation selectors, skin tone",
+      )
+      .comment(
+        "modifiers, tags and characters joined with a zero-width joiner extend the",
+      )
+      .comment(
+        "character before them, and regional indicators pair up into flags. Other",
+      )
+      .comment(
+        "rules, such as those of Hangul syllables, prepended marks and CRLF, are not",
+      )
+      .comment(
+        "applied, so s can count more characters than a full segmenter finds.",
+      )
+      .n()
+      .func("graphemeCount(s string) int", (b) => {
+        b.decl("count", "0")
+          .decl("joined", "false")
+          .decl("regional", "false")
+          .l("for _, r := range s {")
+          .i()
+          .l("switch {")
+          .l("case joined:")
+          .i()
+          .l("joined = false")
+          .u()
+          .l("case r == '\\u200d':")
+          .i()
+          .l("joined = true")
+          .u()
+          .l("case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):")
+          .l(
+            "case r >= 0xfe00 && r <= 0xfe0f, r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:",
+          )
+          .l("case r >= 0x1f1e6 && r <= 0x1f1ff:")
+          .i()
+          .if("!regional", (b) => {
+            b.l("count++");
+          })
+          .l("regional = !regional")
+          .l("continue")
+          .u()
+          .l("default:")
+          .i()
+          .l("count++")
+          .u()
+          .l("}")
+          .l("regional = false")
+          .u()
+          .l("}")
+          .return("count");
+      });
+  }
+
+  // Whether items of this type can be map keys, equal when their values are:
+  // strings, numbers, booleans and enums. Pointers, timestamps, structs and
+  // others are compared by their JSON.
+  private isComparableItem(typeRef: TypeReference): boolean {
+    if (this.isPointerType(typeRef)) {
+      return false;
+    }
+    const unwrapped = this.unwrapOptionalNullable(typeRef);
+    if (unwrapped.kind === "enum") {
+      return true;
+    }
+    return (
+      unwrapped.kind === "primitive" &&
+      ["string", "number", "integer", "boolean", "uuid", "email"].includes(
+        this.getActualType(unwrapped),
+      ) &&
+      !isTimeFormat(typeRef.validation ?? unwrapped.validation)
+    );
+  }
+
+  // Whether a set value of this property has anything to validate
+  private hasValueValidation(prop: Property, typeRef: TypeReference): boolean {
+    const rules = checkedRules(prop.validation || prop.type.validation);
+    // Timestamp and date formats are checked when the JSON is decoded
+    if (
+      rules &&
+      (rules.future || rules.past || rules.custom || !isTimeFormat(rules))
+    ) {
+      return true;
+    }
+    if (this.getEnumValues(typeRef) !== null) {
+      return true;
+    }
+    if (hasValidator(typeRef)) {
+      return true;
+    }
+    if (typeRef.kind === "array" || typeRef.kind === "record") {
+      return this.hasItemValidation(typeRef);
+    }
+    return false;
   }
 
   private unwrapOptionalNullable(typeRef: TypeReference): TypeReference {
@@ -830,8 +2329,11 @@ export class GoValidationGenerator {
       return true;
     }
 
-    if (typeRef.kind === "optional") {
-      if (typeRef.baseType && typeof typeRef.baseType !== "string") {
+    if (typeRef.kind === "optional" && typeRef.baseType) {
+      if (isOptionalPointer(typeRef)) {
+        return true;
+      }
+      if (typeof typeRef.baseType !== "string") {
         return this.isPointerType(typeRef.baseType);
       }
     }
@@ -849,3 +2351,164 @@ export class GoValidationGenerator {
     return false;
   }
 }
+
+// Whether a string is validated as a timestamp or date, and so is generated
+// as time.Time or Date (see GoTypeMapper.mapPropertyType)
+// String formats parsed by the generated code rather than matched against
+// Zod's patterns
+const STRING_FORMATS = [
+  "hostname",
+  "ipv4",
+  "ipv6",
+  "duration",
+  "e164",
+] as const;
+
+type StringFormat = (typeof STRING_FORMATS)[number];
+
+function isTimeFormat(rules: ValidationRules | undefined): boolean {
+  return !!rules?.datetime || !!rules?.date;
+}
+
+// The rules, unless they only say how strings are counted and normalized,
+// when the value must be set (see generateConditionalValidation) or its soft
+// limits (see warnings.go)
+function checkedRules(
+  rules: ValidationRules | undefined,
+): ValidationRules | undefined {
+  if (!rules) {
+    return undefined;
+  }
+  const { lengthUnit, normalize, requiredIf, forbiddenIf, warn, ...checks } =
+    rules;
+  return Object.values(checks).some((value) => value !== undefined)
+    ? rules
+    : undefined;
+}
+
+// Rules checked on an array item or record value; timestamps and dates are
+// checked when they are decoded
+function itemRules(
+  typeRef: TypeReference,
+  unwrapped: TypeReference,
+): ValidationRules | undefined {
+  const rules = checkedRules(typeRef.validation ?? unwrapped.validation);
+  return rules && !isTimeFormat(rules) ? rules : undefined;
+}
+
+// The field of an item's errors, e.g. fmt.Sprintf("labels.%s", key)
+function sprintfField(pathFormat: string, pathArgs: string[]): string {
+  return `fmt.Sprintf(${JSON.stringify(pathFormat)}, ${pathArgs.join(", ")})`;
+}
+
+// The path of an error's field, given as its Go expression: "tags", or
+// fmt.Sprintf("tags[%d].%s", i, key) for the items and entries it is in
+function goPath(field: string): string {
+  const sprintf = /^fmt\.Sprintf\((".*"), (.*)\)$/.exec(field);
+  if (!sprintf) {
+    return keyPath(JSON.parse(field));
+  }
+  return pathOf(JSON.parse(sprintf[1]), sprintf[2].split(", "));
+}
+
+function keyPath(name: string): string {
+  return pathOf(name, []);
+}
+
+// The path of the field the format of fmt.Sprintf and its args build: a
+// property followed by the indices and keys of items and entries, e.g.
+// []PathSegment{{Key: "tags"}, {Index: i, IsIndex: true}}
+function pathOf(pathFormat: string, pathArgs: string[]): string {
+  const args = [...pathArgs];
+  const segments: string[] = [];
+  let rest = pathFormat;
+  for (;;) {
+    if (rest.endsWith("[%d]")) {
+      segments.unshift(`{Index: ${args.pop()}, IsIndex: true}`);
+      rest = rest.slice(0, -"[%d]".length);
+    } else if (rest.endsWith(".%s")) {
+      segments.unshift(`{Key: ${args.pop()}}`);
+      rest = rest.slice(0, -".%s".length);
+    } else {
+      break;
+    }
+  }
+  segments.unshift(`{Key: ${JSON.stringify(rest)}}`);
+  return `[]PathSegment{${segments.join(", ")}}`;
+}
+
+// The container a path format refers to, e.g. "labels" for "labels.%s", used
+// to name its patterns
+function containerName(pathFormat: string): string {
+  return pathFormat.replace(/\[%d\]|\.%s/g, "");
+}
+
+// Whether a value is checked by a generated Validate function: named objects
+// and discriminated unions
+function hasValidator(typeRef: TypeReference): boolean {
+  return (
+    !!typeRef.name &&
+    (typeRef.kind === "object" || isDiscriminatedUnion(typeRef))
+  );
+}
+
+// Names of the objects and unions that can contain themselves, directly or
+// through other types
+function findRecursiveTypes(
+  contract: ContractDefinition,
+  collectedTypes: CollectedType[],
+): Set<string> {
+  const types = new Map<string, TypeReference>();
+  for (const type of contract.types) {
+    types.set(toPascalCase(type.name), type as TypeReference);
+  }
+  for (const collected of collectedTypes) {
+    if (!types.has(collected.name)) {
+      types.set(collected.name, collected.typeRef);
+    }
+  }
+
+  // The named types each type refers to
+  const references = new Map<string, Set<string>>();
+  for (const [name, typeRef] of types) {
+    const found = new Set<string>();
+    const visit = (ref: TypeReference, isRoot: boolean) => {
+      const isNamed =
+        !!ref.name && (ref.kind === "object" || ref.kind === "union");
+      if (isNamed && !isRoot) {
+        found.add(toPascalCase(ref.name!));
+        return;
+      }
+      const children = [
+        ...(ref.properties ?? []).map((prop) => prop.type),
+        typeof ref.baseType === "object" ? ref.baseType : undefined,
+        ref.elementType,
+        ref.valueType,
+        ...(ref.unionTypes ?? []),
+        ...(ref.tupleElements ?? []),
+      ];
+      for (const child of children) {
+        if (child) visit(child, false);
+      }
+    };
+    visit(typeRef, true);
+    references.set(name, found);
+  }
+
+  const recursive = new Set<string>();
+  for (const name of types.keys()) {
+    const seen = new Set<string>();
+    const pending = [...(references.get(name) ?? [])];
+    while (pending.length > 0) {
+      const next = pending.pop()!;
+      if (next === name) {
+        recursive.add(name);
+        break;
+      }
+      if (seen.has(next)) continue;
+      seen.add(next);
+      pending.push(...(references.get(next) ?? []));
+    }
+  }
+  return recursive;
+}
@@ -1,9 +1,13 @@
 import {
+  type FieldCondition,
   type ValidationContext,
   ValidationMapperBase,
   type ValidationMapping,
   type ValidationResult,
+  type ValidationRules,
+  type WarningRules,
 } from "@xrpckit/sdk";
+import { toPascalCase } from "./naming";
 
 /**
  * Represents a Go validation check with its code and required imports.
@@ -19,6 +23,133 @@ export interface GoValidationCode {
   skipIfEmpty?: boolean;
 }
 
+/**
+ * The Go expression for the length of a string in the given unit:
+ * utf8.RuneCountInString counts runes, the default, len() bytes, and
+ * graphemeCount (generated in validation.go) approximates the characters a
+ * user sees.
+ */
+export function goStringLength(
+  value: string,
+  unit: ValidationRules["lengthUnit"],
+): string {
+  switch (unit) {
+    case "bytes":
+      return `len(${value})`;
+    case "graphemes":
+      return `graphemeCount(${value})`;
+    default:
+      return `utf8.RuneCountInString(${value})`;
+  }
+}
+
+/**
+ * Whether lengths in unit are counted with utf8.RuneCountInString: runes are
+ * counted unless the schema asks for bytes or graphemes.
+ */
+export function countsRunes(unit: ValidationRules["lengthUnit"]): boolean {
+  return unit !== "bytes" && unit !== "graphemes";
+}
+
+/**
+ * The Go expression reporting whether the condition of a requiredIf or
+ * forbiddenIf rule holds for the field at path, a pointer when isPointer:
+ * whether it is set, or its value equals the one compared or differs from
+ * it, an absent field differing from every value.
+ */
+export function goFieldCondition(
+  path: string,
+  isPointer: boolean,
+  condition: FieldCondition,
+): string {
+  const compared = condition.equals ?? condition.notEquals;
+  if (compared === undefined) {
+    return `${path} != nil`;
+  }
+  const negate = condition.notEquals !== undefined;
+  const value = isPointer ? `*${path}` : path;
+  const check =
+    typeof compared === "boolean"
+      ? compared !== negate
+        ? value
+        : `!${value}`
+      : `${value} ${negate ? "!=" : "=="} ${JSON.stringify(compared)}`;
+  if (!isPointer) {
+    return check;
+  }
+  return negate ? `${path} == nil || ${check}` : `${path} != nil && ${check}`;
+}
+
+/**
+ * The message of a field failing its requiredIf or forbiddenIf rule, naming
+ * the field of the condition, e.g. "is required when priority is urgent" or
+ * "must not be set unless status is completed".
+ */
+export function conditionMessage(
+  rule: "requiredIf" | "forbiddenIf",
+  condition: FieldCondition,
+): string {
+  const { field, equals, notEquals } = condition;
+  if (rule === "forbiddenIf" && notEquals !== undefined) {
+    return `must not be set unless ${field} is ${notEquals}`;
+  }
+  const holds =
+    equals !== undefined
+      ? `${field} is ${equals}`
+      : notEquals !== undefined
+        ? `${field} is not ${notEquals}`
+        : `${field} is set`;
+  return rule === "requiredIf"
+    ? `is required when ${holds}`
+    : `must not be set when ${holds}`;
+}
+
+// Imports the length expression of a unit needs
+/**
+ * The checks of the soft limits of a value: the conditions under which a
+ * warning is reported, with its message. Strings have lengths counted in
+ * unit, numbers bounds and arrays counts of items.
+ */
+export function goWarningChecks(
+  value: string,
+  rules: WarningRules,
+  kind: "string" | "number" | "array",
+  unit?: ValidationRules["lengthUnit"],
+): Array<[string, string]> {
+  const [measure, low, high, noun] =
+    kind === "string"
+      ? [
+          goStringLength(value, unit),
+          rules.minLength,
+          rules.maxLength,
+          " character(s)",
+        ]
+      : kind === "number"
+        ? [value, rules.min, rules.max, ""]
+        : [`len(${value})`, rules.minItems, rules.maxItems, " item(s)"];
+  const message = (bound: string) =>
+    JSON.stringify(rules.message ?? `should be ${bound}${noun}`);
+  const checks: Array<[string, string]> = [];
+  if (low !== undefined) {
+    checks.push([`${measure} < ${low}`, message(`at least ${low}`)]);
+  }
+  if (high !== undefined) {
+    checks.push([`${measure} > ${high}`, message(`at most ${high}`)]);
+  }
+  return checks;
+}
+
+function lengthImports(unit: ValidationRules["lengthUnit"]): string[] {
+  return countsRunes(unit) ? ["unicode/utf8"] : [];
+}
+
+// The size in bytes of a File (see uploads.go) or of a []byte
+function byteSize(ctx: ValidationContext): string {
+  return ctx.baseType === "bytes"
+    ? `len(${ctx.fieldPath})`
+    : `${ctx.fieldPath}.Size`;
+}
+
 /**
  * Go validation mapper that converts xRPC validation rules to Go validation code.
  * Extends ValidationMapperBase to ensure all validation kinds are handled.
@@ -36,6 +167,13 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
     url: (ctx) => this.handleUrl(ctx),
     uuid: (ctx) => this.handleUuid(ctx),
     regex: (ctx) => this.handleRegex(ctx),
+    datetime: (ctx) => this.handleTimeFormat(ctx),
+    date: (ctx) => this.handleTimeFormat(ctx),
+    hostname: (ctx) => this.handleHostname(ctx),
+    ipv4: (ctx) => this.handleIP(ctx, "Is4", "IPv4"),
+    ipv6: (ctx) => this.handleIP(ctx, "Is6", "IPv6"),
+    duration: (ctx) => this.handleDuration(ctx),
+    e164: (ctx) => this.handleE164(ctx),
 
     // Number validations
     min: (ctx) => this.handleMin(ctx),
@@ -43,10 +181,37 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
     int: (ctx) => this.handleInt(ctx),
     positive: (ctx) => this.handlePositive(ctx),
     negative: (ctx) => this.handleNegative(ctx),
+    exclusiveMin: (ctx) => this.handleExclusiveMin(ctx),
+    exclusiveMax: (ctx) => this.handleExclusiveMax(ctx),
+    multipleOf: (ctx) => this.handleMultipleOf(ctx),
 
     // Array validations
     minItems: (ctx) => this.handleMinItems(ctx),
     maxItems: (ctx) => this.handleMaxItems(ctx),
+    uniqueItems: (ctx) => this.handleUniqueItems(ctx),
+
+    // Date validations
+    future: (ctx) => this.handleFuture(ctx),
+    past: (ctx) => this.handlePast(ctx),
+
+    // Record validations
+    minEntries: (ctx) => this.handleMinEntries(ctx),
+    maxEntries: (ctx) => this.handleMaxEntries(ctx),
+
+    // File validations
+    minSize: (ctx) => this.handleMinSize(ctx),
+    maxSize: (ctx) => this.handleMaxSize(ctx),
+    mimeTypes: (ctx) => this.handleMimeTypes(ctx),
+
+    // Conditional validations
+    requiredIf: (ctx) => this.handleCondition(ctx, "requiredIf"),
+    forbiddenIf: (ctx) => this.handleCondition(ctx, "forbiddenIf"),
+
+    // Warnings
+    warn: (ctx) => this.handleWarn(ctx),
+
+    // Custom validations
+    custom: (ctx) => this.handleCustom(ctx),
   };
 
   // --- String validation handlers ---
@@ -54,11 +219,12 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
   private handleMinLength(
     ctx: ValidationContext,
   ): ValidationResult<GoValidationCode> {
-    const { fieldPath, value, isRequired } = ctx;
+    const { fieldPath, value, isRequired, allRules } = ctx;
+    const length = goStringLength(fieldPath, allRules.lengthUnit);
     // For required fields, skip check if empty (already caught by required check)
     const condition = isRequired
-      ? `${fieldPath} != "" && len(${fieldPath}) < ${value}`
-      : `len(${fieldPath}) < ${value}`;
+      ? `${fieldPath} != "" && ${length} < ${value}`
+      : `${length} < ${value}`;
 
     return {
       validation: {
@@ -66,20 +232,21 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
         message: `fmt.Sprintf("must be at least %d character(s)", ${value})`,
         skipIfEmpty: !isRequired,
       },
-      imports: ["fmt"],
+      imports: ["fmt", ...lengthImports(allRules.lengthUnit)],
     };
   }
 
   private handleMaxLength(
     ctx: ValidationContext,
   ): ValidationResult<GoValidationCode> {
-    const { fieldPath, value } = ctx;
+    const { fieldPath, value, allRules } = ctx;
+    const length = goStringLength(fieldPath, allRules.lengthUnit);
     return {
       validation: {
-        condition: `len(${fieldPath}) > ${value}`,
+        condition: `${length} > ${value}`,
         message: `fmt.Sprintf("must be at most %d character(s)", ${value})`,
       },
-      imports: ["fmt"],
+      imports: ["fmt", ...lengthImports(allRules.lengthUnit)],
     };
   }
 
@@ -140,8 +307,14 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
   ): ValidationResult<GoValidationCode> {
     const { fieldPath, value, isRequired, allRules } = ctx;
 
-    // Skip if email/url/uuid is set (they have dedicated validators)
-    if (allRules.email || allRules.url || allRules.uuid) {
+    // Skip if email/url/uuid/datetime/date is set (they have dedicated validators)
+    if (
+      allRules.email ||
+      allRules.url ||
+      allRules.uuid ||
+      allRules.datetime ||
+      allRules.date
+    ) {
       return {
         validation: {
           condition: "false", // Never triggers
@@ -166,6 +339,75 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
     };
   }
 
+  private handleTimeFormat(
+    _ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    // Timestamps and dates are generated as time.Time and Date, so the JSON
+    // decoder already rejects values in the wrong format
+    return {
+      validation: {
+        condition: "false", // Never triggers
+        message: `""`, // No message
+      },
+    };
+  }
+
+  private handleHostname(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, isRequired } = ctx;
+    return {
+      validation: {
+        condition: `!isHostname(${fieldPath})`,
+        message: `"must be a valid hostname"`,
+        skipIfEmpty: !isRequired,
+      },
+    };
+  }
+
+  private handleIP(
+    ctx: ValidationContext,
+    is: "Is4" | "Is6",
+    version: string,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, isRequired } = ctx;
+    return {
+      validation: {
+        condition: `func() bool { addr, err := netip.ParseAddr(${fieldPath}); return err != nil || !addr.${is}() || addr.Zone() != "" }()`,
+        message: `"must be a valid ${version} address"`,
+        skipIfEmpty: !isRequired,
+      },
+      imports: ["net/netip"],
+    };
+  }
+
+  private handleDuration(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, isRequired } = ctx;
+    return {
+      validation: {
+        condition: `!isDuration(${fieldPath})`,
+        message: `"must be a valid ISO 8601 duration, such as PT1H30M"`,
+        skipIfEmpty: !isRequired,
+      },
+    };
+  }
+
+  private handleE164(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, isRequired } = ctx;
+    return {
+      validation: {
+        condition: `func() bool { matched, _ := regexp.MatchString("^[+][0-9]{7,15}$", ${fieldPath}); return !matched }()`,
+        message: `"must be a valid E.164 phone number, such as +14155550100"`,
+        skipIfEmpty: !isRequired,
+      },
+      imports: ["regexp"],
+    };
+  }
+
   // --- Number validation handlers ---
 
   private handleMin(
@@ -230,6 +472,45 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
     };
   }
 
+  private handleExclusiveMin(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `${fieldPath} <= ${value}`,
+        message: `fmt.Sprintf("must be greater than %v", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  private handleExclusiveMax(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `${fieldPath} >= ${value}`,
+        message: `fmt.Sprintf("must be less than %v", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  private handleMultipleOf(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `!isMultipleOf(float64(${fieldPath}), ${value})`,
+        message: `fmt.Sprintf("must be a multiple of %v", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
   // --- Array validation handlers ---
 
   private handleMinItems(
@@ -257,4 +538,186 @@ export class GoValidationMapper extends ValidationMapperBase<GoValidationCode> {
       imports: ["fmt"],
     };
   }
+
+  private handleUniqueItems(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath } = ctx;
+    // duplicateItem is declared in validation.go; items that are not
+    // comparable, such as structs, need jsonKey(fieldPath[at]) as their key
+    return {
+      validation: {
+        condition: `dup, first := duplicateItem(len(${fieldPath}), func(at int) interface{} { return ${fieldPath}[at] }); dup >= 0`,
+        message:
+          'fmt.Sprintf("must have unique items: item %d equals item %d", dup, first)',
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  // --- Date validation handlers ---
+
+  private handleFuture(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath } = ctx;
+    return {
+      validation: {
+        condition: `!${fieldPath}.After(time.Now())`,
+        message: `"must be in the future"`,
+      },
+      imports: ["time"],
+    };
+  }
+
+  private handlePast(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath } = ctx;
+    return {
+      validation: {
+        condition: `!${fieldPath}.Before(time.Now())`,
+        message: `"must be in the past"`,
+      },
+      imports: ["time"],
+    };
+  }
+
+  // --- Record validation handlers ---
+
+  private handleMinEntries(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `${fieldPath} != nil && len(${fieldPath}) < ${value}`,
+        message: `fmt.Sprintf("must have at least %d entries", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  private handleMaxEntries(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `len(${fieldPath}) > ${value}`,
+        message: `fmt.Sprintf("must have at most %d entries", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  // --- File validation handlers ---
+
+  private handleMinSize(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `${byteSize(ctx)} < ${value}`,
+        message: `fmt.Sprintf("must be at least %d byte(s)", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  private handleMaxSize(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    return {
+      validation: {
+        condition: `${byteSize(ctx)} > ${value}`,
+        message: `fmt.Sprintf("must be at most %d byte(s)", ${value})`,
+      },
+      imports: ["fmt"],
+    };
+  }
+
+  private handleMimeTypes(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    const types = value as string[];
+    const quoted = types.map((type) => JSON.stringify(type));
+    // HasContentType is declared on File in uploads.go
+    return {
+      validation: {
+        condition: `!${fieldPath}.HasContentType(${quoted.join(", ")})`,
+        message: JSON.stringify(`must be of type: ${types.join(", ")}`),
+      },
+    };
+  }
+
+  // --- Conditional validation handlers ---
+
+  private handleCondition(
+    ctx: ValidationContext,
+    rule: "requiredIf" | "forbiddenIf",
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    const condition = value as FieldCondition;
+    // The field of the condition is a sibling of this one, compared as a
+    // value; validation.go knows which fields are pointers
+    const sibling =
+      fieldPath.slice(0, fieldPath.lastIndexOf(".") + 1) +
+      toPascalCase(condition.field);
+    const holds = goFieldCondition(sibling, false, condition);
+    const set = rule === "requiredIf" ? "==" : "!=";
+    return {
+      validation: {
+        condition: `${fieldPath} ${set} nil && ${holds}`,
+        message: JSON.stringify(conditionMessage(rule, condition)),
+      },
+    };
+  }
+
+  // --- Warning handlers ---
+
+  private handleWarn(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value, baseType, allRules } = ctx;
+    const kind =
+      baseType === "number" || baseType === "integer"
+        ? "number"
+        : baseType === "array"
+          ? "array"
+          : "string";
+    const checks = goWarningChecks(
+      fieldPath,
+      value as WarningRules,
+      kind,
+      allRules.lengthUnit,
+    );
+    // Values past a soft limit are accepted; warnings.go reports them
+    return {
+      validation: {
+        condition:
+          checks.map(([condition]) => condition).join(" || ") || "false",
+        message: checks[0]?.[1] ?? '""',
+      },
+      imports: lengthImports(allRules.lengthUnit),
+    };
+  }
+
+  // --- Custom validation handlers ---
+
+  private handleCustom(
+    ctx: ValidationContext,
+  ): ValidationResult<GoValidationCode> {
+    const { fieldPath, value } = ctx;
+    // The condition declares the validator's error, which is the message
+    return {
+      validation: {
+        condition: `err := validators[${JSON.stringify(value)}](${fieldPath}); err != nil`,
+        message: "err.Error()",
+      },
+    };
+  }
 }
@@ -0,0 +1,143 @@
+import type {
+  ContractDefinition,
+  Property,
+  TypeReference,
+} from "@xrpckit/sdk";
+import { GoBuilder } from "./go-builder";
+import type { CollectedType } from "./type-collector";
+
+/**
+ * Generates validators.go: the registry of the custom validators fields name
+ * with .meta({ custom: "name" }), for business rules the built-in validations
+ * can't express. NewRouter panics if one the contract uses is not registered.
+ */
+export class GoValidatorsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate validators.go, or null when no field uses a custom validator.
+   * @param names - The custom validators the contract uses, from collectCustomValidators
+   */
+  generateValidators(names: string[]): string | null {
+    if (names.length === 0) {
+      return null;
+    }
+
+    const w = this.w.reset();
+    w.package(this.packageName).import("fmt", "strings");
+
+    w.comment(
+      "ValidatorFunc checks a field's value against a business rule. The error it",
+    )
+      .comment("returns is reported as the field's validation message.")
+      .type("ValidatorFunc", "func(value interface{}) error");
+
+    w.comment(
+      "validators holds the validators registered with RegisterValidator.",
+    )
+      .l("var validators = map[string]ValidatorFunc{}")
+      .n();
+
+    w.comment("customValidators are the validators the contract's fields use.")
+      .l("var customValidators = []string{")
+      .i();
+    for (const name of names) {
+      w.l(`${JSON.stringify(name)},`);
+    }
+    w.u().l("}").n();
+
+    w.comment(
+      "RegisterValidator registers fn as the custom validator called name. Every",
+    )
+      .comment(
+        "validator the contract uses must be registered before NewRouter is called.",
+      )
+      .n()
+      .func("RegisterValidator(name string, fn ValidatorFunc)", (b) => {
+        b.l("validators[name] = fn");
+      });
+
+    w.comment(
+      "mustRegisteredValidators panics if a validator the contract uses is not",
+    )
+      .comment(
+        "registered, so it fails at startup rather than when a request is validated.",
+      )
+      .n()
+      .func("mustRegisteredValidators()", (b) => {
+        b.var("missing", "[]string")
+          .l("for _, name := range customValidators {")
+          .i()
+          .if("_, ok := validators[name]; !ok", (b) => {
+            b.l("missing = append(missing, name)");
+          })
+          .u()
+          .l("}")
+          .if("len(missing) > 0", (b) => {
+            b.l(
+              'panic(fmt.Sprintf("custom validators not registered: %s", strings.Join(missing, ", ")))',
+            );
+          });
+      });
+
+    return w.toString();
+  }
+}
+
+/**
+ * The names of the custom validators the contract's fields use, in the order
+ * they first appear.
+ */
+export function collectCustomValidators(
+  contract: ContractDefinition,
+  collectedTypes: CollectedType[] = [],
+): string[] {
+  const names = new Set<string>();
+  const visited = new Set<TypeReference>();
+
+  const fromProperties = (properties: Property[] | undefined): void => {
+    for (const prop of properties ?? []) {
+      if (prop.validation?.custom) {
+        names.add(prop.validation.custom);
+      }
+      fromTypeRef(prop.type);
+    }
+  };
+
+  const fromTypeRef = (typeRef: TypeReference): void => {
+    if (visited.has(typeRef)) return;
+    visited.add(typeRef);
+
+    if (typeRef.validation?.custom) {
+      names.add(typeRef.validation.custom);
+    }
+    fromProperties(typeRef.properties);
+    if (typeof typeRef.baseType === "object") {
+      fromTypeRef(typeRef.baseType);
+    }
+    const children = [
+      typeRef.elementType,
+      typeRef.keyType,
+      typeRef.valueType,
+      ...(typeRef.unionTypes ?? []),
+      ...(typeRef.tupleElements ?? []),
+    ];
+    for (const child of children) {
+      if (child) fromTypeRef(child);
+    }
+  };
+
+  for (const type of contract.types) {
+    fromTypeRef(type as TypeReference);
+  }
+  for (const collected of collectedTypes) {
+    fromTypeRef(collected.typeRef);
+  }
+  return Array.from(names);
+}
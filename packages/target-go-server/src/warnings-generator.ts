@@ -0,0 +1,423 @@
+import { GoBuilder } from "./go-builder";
+import type { GoStruct } from "./type-generator";
+import { countsRunes, goWarningChecks } from "./validation-mapper";
+
+type GoField = GoStruct["fields"][number];
+
+/**
+ * Generates warnings.go for contracts with soft limits, declared with
+ * .meta({ warn: { maxLength: 120, message: "..." } }): a Warnings method on
+ * every struct holding such a field, directly or in a nested struct,
+ * reporting the values past their soft limits as ValidationErrors. The router
+ * collects the warnings of validated inputs without rejecting the call,
+ * hands them to handlers through WarningsFrom and returns them next to the
+ * result, in the "warnings" of the envelope.
+ */
+export class GoWarningsGenerator {
+  private w: GoBuilder;
+  private packageName: string;
+  private warned: Set<string> = new Set();
+  private aliases: Map<string, string> = new Map();
+  // Whether a Warnings method formats the fields of array items or record
+  // values, or nests the warnings of another struct
+  private formatsFields = false;
+  private nests = false;
+  private countsRunes = false;
+
+  constructor(packageName = "server") {
+    this.w = new GoBuilder();
+    this.packageName = packageName;
+  }
+
+  /**
+   * Generate warnings.go, or undefined when no field has soft limits.
+   * @param structs - The structs of types.go (GoTypeGenerator.getStructs)
+   * @param aliases - The aliases of structs (GoTypeGenerator.getStructAliases)
+   */
+  generateWarnings(
+    structs: GoStruct[],
+    aliases: Map<string, string>,
+  ): string | undefined {
+    this.aliases = aliases;
+    this.formatsFields = false;
+    this.nests = false;
+    this.countsRunes = false;
+    // Structs with soft limits, then those nesting them, until no more are
+    // found
+    this.warned = new Set();
+    let found = true;
+    while (found) {
+      found = false;
+      for (const struct of structs) {
+        if (this.warned.has(struct.name)) continue;
+        if (
+          struct.fields.some(
+            (field) =>
+              warningKind(field) !== undefined ||
+              this.holdsWarnings(field.type),
+          )
+        ) {
+          this.warned.add(struct.name);
+          found = true;
+        }
+      }
+    }
+    if (this.warned.size === 0) {
+      return undefined;
+    }
+
+    const body = new GoBuilder();
+    this.generateCollector(body);
+    for (const struct of structs) {
+      if (this.warned.has(struct.name)) {
+        this.generateWarningsMethod(body, struct);
+      }
+    }
+    if (this.nests) {
+      this.generateNestWarnings(body);
+    }
+
+    const imports = ["context", "encoding/json"];
+    if (this.formatsFields) imports.push("fmt");
+    imports.push("sync");
+    if (this.countsRunes) imports.push("unicode/utf8");
+    const w = this.w.reset();
+    w.package(this.packageName).import(...imports);
+    return `${w.toString()}\n${body.toString()}`;
+  }
+
+  /**
+   * The types with a Warnings method, aliases included, once
+   * generateWarnings has run.
+   */
+  getWarned(): Set<string> {
+    const types = new Set(this.warned);
+    for (const [alias, target] of this.aliases) {
+      if (this.warned.has(target)) {
+        types.add(alias);
+      }
+    }
+    return types;
+  }
+
+  // The collector of the warnings of a call, carried by its context
+  private generateCollector(w: GoBuilder): void {
+    w.comment("warningsKey is the context key of the warnings of a call.")
+      .type("warningsKey", "struct{}")
+      .n();
+
+    w.comment(
+      "warningCollector gathers the warnings of a call and of the calls dispatched",
+    )
+      .comment("with its context.")
+      .struct("warningCollector", (b) => {
+        b.l("mu       sync.Mutex").l("warnings ValidationErrors");
+      });
+
+    w.comment(
+      "WarningsFrom returns the warnings of the call ctx belongs to: the values of",
+    )
+      .comment(
+        "its input past the soft limits of the contract. They do not reject the",
+      )
+      .comment(
+        'call; the router returns them next to the result, in "warnings".',
+      )
+      .n()
+      .func("WarningsFrom(ctx context.Context) ValidationErrors", (b) => {
+        b.decl("c, ok", "ctx.Value(warningsKey{}).(*warningCollector)")
+          .if("!ok", (b) => {
+            b.return("nil");
+          })
+          .l("c.mu.Lock()")
+          .l("defer c.mu.Unlock()")
+          .return("append(ValidationErrors(nil), c.warnings...)");
+      })
+      .n();
+
+    w.comment(
+      "withWarnings returns ctx with a collector for the warnings of a call, unless",
+    )
+      .comment("it has one already.")
+      .n()
+      .func("withWarnings(ctx context.Context) context.Context", (b) => {
+        b.if(
+          "_, ok := ctx.Value(warningsKey{}).(*warningCollector); ok",
+          (b) => {
+            b.return("ctx");
+          },
+        ).return("context.WithValue(ctx, warningsKey{}, &warningCollector{})");
+      })
+      .n();
+
+    w.comment("addWarnings adds warnings to the collector of ctx, if any.")
+      .n()
+      .func(
+        "addWarnings(ctx context.Context, warnings ValidationErrors)",
+        (b) => {
+          b.decl("c, ok", "ctx.Value(warningsKey{}).(*warningCollector)")
+            .if("!ok || len(warnings) == 0", (b) => {
+              b.return();
+            })
+            .l("c.mu.Lock()")
+            .l("c.warnings = append(c.warnings, warnings...)")
+            .l("c.mu.Unlock()");
+        },
+      )
+      .n();
+
+    w.comment(
+      'appendWarnings adds warnings to the {"result": ...} envelope in buf, after',
+    )
+      .comment("the result.")
+      .n()
+      .method(
+        "buf *encodeBuffer",
+        "appendWarnings",
+        "warnings ValidationErrors",
+        "",
+        (b) => {
+          b.decl("data, _", "json.Marshal(warnings)")
+            .comment("Written over the closing brace and newline of the envelope")
+            .l("buf.Truncate(buf.Len() - 2)")
+            .l('buf.WriteString(`,"warnings":`)')
+            .l("buf.Write(data)")
+            .l('buf.WriteString("}\\n")');
+        },
+      );
+  }
+
+  private generateWarningsMethod(w: GoBuilder, struct: GoStruct): void {
+    const fields = struct.fields.filter(
+      (field) =>
+        warningKind(field) !== undefined || this.holdsWarnings(field.type),
+    );
+    const limited = fields
+      .filter((field) => warningKind(field) !== undefined)
+      .map((field) => field.name);
+    const parts =
+      limited.length < fields.length
+        ? [...limited, "its nested values"]
+        : limited;
+    w.doc(
+      `Warnings reports the values of ${joinNames(parts)} past their soft limits.`,
+    );
+    w.n().method(
+      `v ${struct.name}`,
+      "Warnings",
+      "",
+      "ValidationErrors",
+      (b) => {
+        b.var("warnings", "ValidationErrors");
+        for (const field of fields) {
+          if (warningKind(field) !== undefined) {
+            this.warnField(b, field);
+          } else {
+            this.nestedWarnings(
+              b,
+              field.type,
+              `v.${field.name}`,
+              field.jsonName,
+              [],
+              0,
+            );
+          }
+        }
+        b.return("warnings");
+      },
+    );
+  }
+
+  // Checks the soft limits of a field's own value
+  private warnField(b: GoBuilder, field: GoField): void {
+    const kind = warningKind(field)!;
+    const pointer = field.type.startsWith("*");
+    const value = pointer ? `*v.${field.name}` : `v.${field.name}`;
+    if (countsRunes(field.lengthUnit) && kind === "string") {
+      this.countsRunes = true;
+    }
+    const checks = goWarningChecks(
+      value,
+      field.warn!,
+      kind,
+      field.lengthUnit,
+    );
+    // Absent values are past no limit
+    const present = pointer
+      ? `v.${field.name} != nil`
+      : field.omitEmpty && kind !== "number"
+        ? `len(v.${field.name}) > 0`
+        : undefined;
+    for (const [check, message] of checks) {
+      const condition = present ? `${present} && ${check}` : check;
+      b.if(condition, (b) => {
+        b.l("warnings = append(warnings, &ValidationError{")
+          .i()
+          .l(`Field:   "${field.jsonName}",`)
+          .l(`Path:    []PathSegment{{Key: "${field.jsonName}"}},`)
+          .l(`Message: ${message},`)
+          .u()
+          .l("})");
+      });
+    }
+  }
+
+  // Adds the warnings of the structs expr holds, prefixed with their field
+  // and path
+  private nestedWarnings(
+    b: GoBuilder,
+    goType: string,
+    expr: string,
+    fieldFormat: string,
+    args: string[],
+    depth: number,
+  ): void {
+    if (this.warned.has(this.aliases.get(goType) ?? goType)) {
+      this.nests = true;
+      const field =
+        args.length > 0
+          ? `fmt.Sprintf("${fieldFormat}", ${args.join(", ")})`
+          : `"${fieldFormat}"`;
+      const path = pathSegments(fieldFormat, args);
+      b.l(
+        `warnings = append(warnings, nestWarnings(${expr}.Warnings(), ${field}, ${path})...)`,
+      );
+      return;
+    }
+    const suffix = depth === 0 ? "" : `${depth}`;
+    const elem = elemType(goType);
+    if (elem === undefined) return;
+    if (goType.startsWith("*")) {
+      // Warnings has a value receiver, called on the pointer once it is set
+      b.if(`${expr} != nil`, (b) => {
+        this.nestedWarnings(b, elem, expr, fieldFormat, args, depth + 1);
+      });
+    } else if (goType.startsWith("[]")) {
+      this.formatsFields = true;
+      const index = `i${suffix}`;
+      const item = `e${suffix}`;
+      b.l(`for ${index}, ${item} := range ${expr} {`).i();
+      this.nestedWarnings(
+        b,
+        elem,
+        item,
+        `${fieldFormat}[%d]`,
+        [...args, index],
+        depth + 1,
+      );
+      b.u().l("}");
+    } else {
+      this.formatsFields = true;
+      const key = `k${suffix}`;
+      const value = `e${suffix}`;
+      b.l(`for ${key}, ${value} := range ${expr} {`).i();
+      this.nestedWarnings(
+        b,
+        elem,
+        value,
+        `${fieldFormat}.%s`,
+        [...args, key],
+        depth + 1,
+      );
+      b.u().l("}");
+    }
+  }
+
+  private generateNestWarnings(w: GoBuilder): void {
+    w.comment(
+      "nestWarnings prefixes the fields and paths of the warnings of a nested value",
+    )
+      .comment("with its own.")
+      .n()
+      .func(
+        "nestWarnings(warnings ValidationErrors, field string, path ...PathSegment) ValidationErrors",
+        (b) => {
+          b.l("for i, warning := range warnings {")
+            .i()
+            .l("warnings[i] = &ValidationError{")
+            .i()
+            .l(`Field:   field + "." + warning.Field,`)
+            .l(
+              "Path:    append(append([]PathSegment(nil), path...), warning.Path...),",
+            )
+            .l("Message: warning.Message,")
+            .u()
+            .l("}")
+            .u()
+            .l("}")
+            .return("warnings");
+        },
+      );
+  }
+
+  // Whether a field of goType holds structs with soft limits
+  private holdsWarnings(goType: string): boolean {
+    if (this.warned.has(this.aliases.get(goType) ?? goType)) {
+      return true;
+    }
+    const elem = elemType(goType);
+    return elem !== undefined && this.holdsWarnings(elem);
+  }
+}
+
+// What a field's soft limits measure: the length of a string, a number or
+// the items of an array; undefined without soft limits
+function warningKind(
+  field: GoField,
+): "string" | "number" | "array" | undefined {
+  if (!field.warn) {
+    return undefined;
+  }
+  const goType = field.type.replace(/^\*/, "");
+  if (goType === "string") {
+    return "string";
+  }
+  if (goType.startsWith("[]")) {
+    return "array";
+  }
+  return /^(u?int\d*|float\d+)$/.test(goType) ? "number" : undefined;
+}
+
+// The path segments of a field format and its arguments, e.g.
+// PathSegment{Key: "tasks"}, PathSegment{Index: i, IsIndex: true} for
+// "tasks[%d]"
+function pathSegments(fieldFormat: string, args: string[]): string {
+  const rest = [...args];
+  const segments: string[] = [];
+  let format = fieldFormat;
+  for (;;) {
+    if (format.endsWith("[%d]")) {
+      segments.unshift(`PathSegment{Index: ${rest.pop()}, IsIndex: true}`);
+      format = format.slice(0, -"[%d]".length);
+    } else if (format.endsWith(".%s")) {
+      segments.unshift(`PathSegment{Key: ${rest.pop()}}`);
+      format = format.slice(0, -".%s".length);
+    } else {
+      break;
+    }
+  }
+  segments.unshift(`PathSegment{Key: "${format}"}`);
+  return segments.join(", ");
+}
+
+// The element type of pointer, slice and map types
+function elemType(goType: string): string | undefined {
+  if (goType.startsWith("*")) {
+    return goType.slice(1);
+  }
+  if (goType.startsWith("[]")) {
+    return goType.slice(2);
+  }
+  if (goType.startsWith("map[")) {
+    return goType.slice(goType.indexOf("]") + 1);
+  }
+  return undefined;
+}
+
+// Title, or Title and Tags, or Title, Tags and its nested values
+function joinNames(names: string[]): string {
+  if (names.length === 1) {
+    return names[0];
+  }
+  return `${names.slice(0, -1).join(", ")} and ${names[names.length - 1]}`;
+}
@@ -0,0 +1,148 @@
+import { describe, expect, it } from "bun:test";
+import type { ContractDefinition, TypeReference } from "@xrpckit/sdk";
+import { openapiTarget } from "./generator";
+
+describe("openapi target", () => {
+  it("describes methods, schemas and validation constraints", () => {
+    const createInput: TypeReference = {
+      kind: "object",
+      name: "UserCreateInput",
+      properties: [
+        {
+          name: "email",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { email: true },
+        },
+        {
+          name: "age",
+          required: false,
+          type: {
+            kind: "optional",
+            baseType: { kind: "primitive", baseType: "number" },
+          },
+          validation: { int: true, min: 18 },
+        },
+      ],
+    };
+
+    const createOutput: TypeReference = {
+      kind: "object",
+      name: "UserCreateOutput",
+      properties: [
+        {
+          name: "id",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+          validation: { uuid: true },
+        },
+      ],
+    };
+
+    const contract: ContractDefinition = {
+      routers: [],
+      types: [],
+      endpoints: [
+        {
+          name: "create",
+          type: "mutation",
+          input: createInput,
+          output: createOutput,
+          fullName: "user.create",
+        },
+      ],
+    };
+
+    const output = openapiTarget.generate({
+      contract,
+      outputDir: "out",
+      options: { title: "Users", path: "/rpc" },
+    });
+
+    expect(output.files.map((file) => file.path)).toEqual(["openapi.json"]);
+    const document = JSON.parse(output.files[0].content);
+
+    expect(document.openapi).toBe("3.1.0");
+    expect(document.info.title).toBe("Users");
+
+    const post = document.paths["/rpc"].post;
+    expect(post.requestBody.content["application/json"].schema).toEqual({
+      oneOf: [{ $ref: "#/components/schemas/UserCreateRequest" }],
+      discriminator: {
+        propertyName: "method",
+        mapping: {
+          "user.create": "#/components/schemas/UserCreateRequest",
+        },
+      },
+    });
+    expect(post.responses.default.content["application/json"].schema).toEqual(
+      { $ref: "#/components/schemas/ErrorResponse" },
+    );
+
+    const schemas = document.components.schemas;
+    expect(schemas.UserCreateRequest.properties.method).toEqual({
+      const: "user.create",
+    });
+    expect(schemas.UserCreateInput).toEqual({
+      type: "object",
+      properties: {
+        email: { type: "string", format: "email" },
+        age: { type: "integer", minimum: 18 },
+      },
+      required: ["email"],
+    });
+    expect(schemas.UserCreateOutput.properties.id.format).toBe("uuid");
+    expect(schemas.Error.required).toEqual(["code", "message"]);
+  });
+
+  it("defines recursive types once and refers to them", () => {
+    const comment: TypeReference = {
+      kind: "object",
+      name: "Comment",
+      properties: [
+        {
+          name: "body",
+          required: true,
+          type: { kind: "primitive", baseType: "string" },
+        },
+        {
+          name: "replies",
+          required: true,
+          type: {
+            kind: "array",
+            elementType: { kind: "object", name: "Comment" },
+          },
+        },
+      ],
+    };
+
+    const contract: ContractDefinition = {
+      routers: [],
+      types: [],
+      endpoints: [
+        {
+          name: "get",
+          type: "query",
+          input: { kind: "object", name: "CommentGetInput", properties: [] },
+          output: {
+            kind: "object",
+            name: "CommentGetOutput",
+            properties: [{ name: "comment", required: true, type: comment }],
+          },
+          fullName: "comment.get",
+        },
+      ],
+    };
+
+    const output = openapiTarget.generate({ contract, outputDir: "out" });
+    const schemas = JSON.parse(output.files[0].content).components.schemas;
+
+    expect(schemas.CommentGetOutput.properties.comment).toEqual({
+      $ref: "#/components/schemas/Comment",
+    });
+    expect(schemas.Comment.properties.replies.items).toEqual({
+      $ref: "#/components/schemas/Comment",
+    });
+    expect(schemas.CommentGetOutput.$defs).toBeUndefined();
+  });
+});
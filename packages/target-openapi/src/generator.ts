@@ -0,0 +1,72 @@
+import {
+  type OpenAPIOptions,
+  TYPE_KINDS,
+  type Target,
+  type TargetInput,
+  type TargetOutput,
+  type TargetSupport,
+  VALIDATION_KINDS,
+  buildOpenAPIDocument,
+  validateSupport,
+} from "@xrpckit/sdk";
+
+/**
+ * OpenAPI generator that describes an xRPC contract as an OpenAPI 3.1 document.
+ *
+ * Generates one file:
+ * - openapi.json: Methods, input/output schemas with validation constraints,
+ *   the RPC envelope and the error shape
+ *
+ * Options: `title`, `version` and `path` (where the router is mounted,
+ * default "/api").
+ */
+const support: TargetSupport = {
+  supportedTypes: [...TYPE_KINDS],
+  supportedValidations: [...VALIDATION_KINDS],
+  notes: [
+    "Schemas use JSON Schema draft 2020-12 as required by OpenAPI 3.1",
+    "All methods share one path; the request body is discriminated by method",
+    "Subscriptions are described as a GET returning text/event-stream",
+  ],
+};
+
+function getOpenAPIOptions(options?: Record<string, unknown>): OpenAPIOptions {
+  const result: OpenAPIOptions = {};
+  for (const key of ["title", "version", "path"] as const) {
+    const value = options?.[key];
+    if (typeof value === "string" && value) {
+      result[key] = value;
+    }
+  }
+  return result;
+}
+
+function generateOpenAPI(input: TargetInput): TargetOutput {
+  const { contract } = input;
+  const diagnostics = validateSupport(contract, support, "openapi");
+
+  const hasErrors = diagnostics.some((issue) => issue.severity === "error");
+  if (hasErrors) {
+    return { files: [], diagnostics };
+  }
+
+  const document = buildOpenAPIDocument(
+    contract,
+    getOpenAPIOptions(input.options),
+  );
+
+  return {
+    files: [
+      {
+        path: "openapi.json",
+        content: `${JSON.stringify(document, null, 2)}\n`,
+      },
+    ],
+    diagnostics,
+  };
+}
+
+export const openapiTarget: Target = {
+  name: "openapi",
+  generate: generateOpenAPI,
+};
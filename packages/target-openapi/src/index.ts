@@ -0,0 +1 @@
+export { openapiTarget } from "./generator";
@@ -0,0 +1,196 @@
+import { describe, expect, it } from "bun:test";
+import type { ContractDefinition, TypeReference } from "@xrpckit/sdk";
+import { protoTarget } from "./generator";
+
+describe("proto target", () => {
+  it("mirrors methods and types as a proto3 schema", () => {
+    const listInput: TypeReference = {
+      kind: "object",
+      name: "TaskListInput",
+      properties: [
+        {
+          name: "status",
+          required: false,
+          type: {
+            kind: "optional",
+            baseType: {
+              kind: "enum",
+              name: "TaskStatus",
+              enumValues: ["pending", "in_progress"],
+            },
+          },
+        },
+        {
+          name: "limit",
+          required: false,
+          type: {
+            kind: "optional",
+            baseType: { kind: "primitive", baseType: "number" },
+          },
+          validation: { int: true, min: 1 },
+        },
+      ],
+    };
+
+    const listOutput: TypeReference = {
+      kind: "object",
+      name: "TaskListOutput",
+      properties: [
+        {
+          name: "tasks",
+          required: true,
+          type: {
+            kind: "array",
+            elementType: {
+              kind: "object",
+              properties: [
+                {
+                  name: "ownerID",
+                  required: true,
+                  type: { kind: "primitive", baseType: "string" },
+                },
+                {
+                  name: "createdAt",
+                  required: true,
+                  type: { kind: "date" },
+                },
+              ],
+            },
+          },
+        },
+        {
+          name: "grid",
+          required: true,
+          type: {
+            kind: "array",
+            elementType: {
+              kind: "array",
+              elementType: { kind: "primitive", baseType: "number" },
+            },
+          },
+        },
+        {
+          name: "labels",
+          required: true,
+          type: {
+            kind: "record",
+            keyType: { kind: "primitive", baseType: "string" },
+            valueType: { kind: "primitive", baseType: "string" },
+          },
+        },
+      ],
+    };
+
+    const event: TypeReference = {
+      kind: "union",
+      name: "TaskEvent",
+      discriminator: "type",
+      unionTypes: [
+        {
+          kind: "object",
+          properties: [
+            {
+              name: "type",
+              required: true,
+              type: { kind: "literal", literalValue: "created" },
+            },
+          ],
+        },
+        {
+          kind: "object",
+          properties: [
+            {
+              name: "type",
+              required: true,
+              type: { kind: "literal", literalValue: "deleted" },
+            },
+          ],
+        },
+      ],
+    };
+
+    const contract: ContractDefinition = {
+      routers: [],
+      types: [],
+      endpoints: [
+        {
+          name: "list",
+          type: "query",
+          input: listInput,
+          output: listOutput,
+          fullName: "task.list",
+        },
+        {
+          name: "watch",
+          type: "subscription",
+          input: listInput,
+          output: event,
+          fullName: "task.watch",
+        },
+      ],
+    };
+
+    const output = protoTarget.generate({
+      contract,
+      outputDir: "out",
+      options: { package: "todo.v1", goPackage: "example.com/todo/v1" },
+    });
+
+    expect(output.files.map((file) => file.path)).toEqual(["todo.v1.proto"]);
+    const proto = output.files[0].content;
+
+    expect(proto).toContain('syntax = "proto3";');
+    expect(proto).toContain("package todo.v1;");
+    expect(proto).toContain('option go_package = "example.com/todo/v1";');
+    expect(proto).toContain('import "google/protobuf/timestamp.proto";');
+
+    expect(proto).toContain("service TaskService {");
+    expect(proto).toContain("rpc List(TaskListInput) returns (TaskListOutput);");
+    expect(proto).toContain(
+      "rpc Watch(TaskListInput) returns (stream TaskEvent);",
+    );
+
+    expect(proto).toContain("optional TaskStatus status = 1;");
+    expect(proto).toContain("optional int64 limit = 2;");
+    expect(proto).toContain("TASK_STATUS_UNSPECIFIED = 0;");
+    expect(proto).toContain("TASK_STATUS_IN_PROGRESS = 2;");
+
+    expect(proto).toContain("repeated TaskListOutputTasksItem tasks = 1;");
+    expect(proto).toContain('string owner_id = 1 [json_name = "ownerID"];');
+    expect(proto).toContain("google.protobuf.Timestamp created_at = 2;");
+    expect(proto).toContain("repeated TaskListOutputGridItem grid = 2;");
+    expect(proto).toContain(
+      "message TaskListOutputGridItem {\n    repeated double values = 1;\n}",
+    );
+    expect(proto).toContain("map<string, string> labels = 3;");
+
+    expect(proto).toContain("message TaskEvent {\n    oneof value {");
+    expect(proto).toContain("TaskEventCreated created = 1;");
+    expect(proto).toContain("TaskEventDeleted deleted = 2;");
+  });
+
+  it("names services after endpoint groups", () => {
+    const empty: TypeReference = { kind: "object", properties: [] };
+    const contract: ContractDefinition = {
+      routers: [],
+      types: [],
+      endpoints: [
+        {
+          name: "greet",
+          type: "query",
+          input: empty,
+          output: empty,
+          fullName: "greeting.greet",
+        },
+      ],
+    };
+
+    const output = protoTarget.generate({ contract, outputDir: "out" });
+
+    expect(output.files[0].path).toBe("xrpc.proto");
+    expect(output.files[0].content).toContain("package xrpc;");
+    expect(output.files[0].content).toContain(
+      "rpc Greet(GreetingGreetInput) returns (GreetingGreetOutput);",
+    );
+  });
+});
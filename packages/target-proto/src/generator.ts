@@ -0,0 +1,67 @@
+import {
+  TYPE_KINDS,
+  type Target,
+  type TargetInput,
+  type TargetOutput,
+  type TargetSupport,
+  VALIDATION_KINDS,
+  validateSupport,
+} from "@xrpckit/sdk";
+import { type ProtoOptions, ProtoGenerator } from "./proto-generator";
+
+/**
+ * Protocol Buffers generator that mirrors an xRPC contract as a proto3 schema,
+ * so gRPC tooling and services in other languages can share the contract.
+ *
+ * Generates one file:
+ * - <package>.proto: a service per endpoint group with an rpc per method
+ *   (subscriptions are server-streaming), a message per object type and an
+ *   enum per string enum
+ *
+ * Options: `package` (the proto package, default "xrpc") and `goPackage`
+ * (the go_package option for protoc-gen-go).
+ */
+const support: TargetSupport = {
+  supportedTypes: [...TYPE_KINDS],
+  supportedValidations: [...VALIDATION_KINDS],
+  notes: [
+    "Validation rules have no proto3 equivalent and are not carried over",
+    "Optional and nullable both map to field presence; proto3 has no null",
+    "Unions become a message with a oneof of their variants",
+    "Enum values are prefixed with the enum name, so their protobuf JSON names differ from the xRPC values",
+  ],
+};
+
+function getProtoOptions(options?: Record<string, unknown>): ProtoOptions {
+  const result: ProtoOptions = {};
+  for (const key of ["package", "goPackage"] as const) {
+    const value = options?.[key];
+    if (typeof value === "string" && value) {
+      result[key] = value;
+    }
+  }
+  return result;
+}
+
+function generateProto(input: TargetInput): TargetOutput {
+  const { contract } = input;
+  const diagnostics = validateSupport(contract, support, "proto");
+
+  const hasErrors = diagnostics.some((issue) => issue.severity === "error");
+  if (hasErrors) {
+    return { files: [], diagnostics };
+  }
+
+  const options = getProtoOptions(input.options);
+  const content = new ProtoGenerator().generate(contract, options);
+
+  return {
+    files: [{ path: `${options.package ?? "xrpc"}.proto`, content }],
+    diagnostics,
+  };
+}
+
+export const protoTarget: Target = {
+  name: "proto",
+  generate: generateProto,
+};
@@ -0,0 +1,12 @@
+export { protoTarget } from "./generator";
+export {
+  ProtoGenerator,
+  type ProtoEnumModel,
+  type ProtoFieldModel,
+  type ProtoMessageModel,
+  type ProtoOptions,
+  type ProtoRpcModel,
+  type ProtoSchema,
+  type ProtoServiceModel,
+  renderProto,
+} from "./proto-generator";
@@ -0,0 +1,529 @@
+import {
+  CodeWriter,
+  type ContractDefinition,
+  type Endpoint,
+  type Property,
+  type TypeReference,
+  toPascalCase,
+} from "@xrpckit/sdk";
+
+export interface ProtoOptions {
+  /** The proto package, default "xrpc" */
+  package?: string;
+  /** The go_package option, for protoc-gen-go */
+  goPackage?: string;
+}
+
+/** How a field is declared: its type and label. */
+interface ProtoField {
+  type: string;
+  label: "" | "optional" | "repeated";
+}
+
+/**
+ * A field of a message: a scalar, message or enum name, a
+ * "google.protobuf." well-known type or a "map<string, T>".
+ */
+export interface ProtoFieldModel {
+  /** The snake_case field name */
+  name: string;
+  type: string;
+  label: ProtoField["label"];
+  /** The contract's name for the field, which is its JSON name */
+  jsonName: string;
+  number: number;
+}
+
+/**
+ * A message of the schema. Objects have a field per property, tuples one per
+ * element and unions a oneof with one per variant; values wrap a type that is
+ * not a message in a single field.
+ */
+export interface ProtoMessageModel {
+  name: string;
+  kind: "object" | "tuple" | "union" | "value";
+  fields: ProtoFieldModel[];
+  /** The discriminator property of a discriminated union */
+  discriminator?: string;
+  /** The discriminator value of each variant field of a discriminated union */
+  tags?: Record<string, string>;
+}
+
+/**
+ * A string enum, whose values are numbered from 1 in order after the
+ * <prefix>_UNSPECIFIED value 0 proto3 requires.
+ */
+export interface ProtoEnumModel {
+  name: string;
+  /** The prefix of the value names, the enum name in SCREAMING_SNAKE_CASE */
+  prefix: string;
+  values: Array<{ name: string; value: string }>;
+}
+
+export interface ProtoRpcModel {
+  name: string;
+  /** The full name of the xRPC method the rpc mirrors */
+  method: string;
+  type: Endpoint["type"];
+  input: string;
+  output: string;
+}
+
+export interface ProtoServiceModel {
+  name: string;
+  rpcs: ProtoRpcModel[];
+}
+
+/**
+ * The proto3 schema of a contract, for targets that bridge to gRPC as well
+ * as for rendering the .proto file.
+ */
+export interface ProtoSchema {
+  package: string;
+  goPackage?: string;
+  imports: string[];
+  services: ProtoServiceModel[];
+  messages: ProtoMessageModel[];
+  enums: ProtoEnumModel[];
+}
+
+/**
+ * Converts a camelCase field name to the snake_case protobuf style, keeping
+ * runs of capitals together ("userID" becomes "user_id").
+ */
+export function toProtoFieldName(name: string): string {
+  return name
+    .replace(/([a-z0-9])([A-Z])/g, "$1_$2")
+    .replace(/([A-Z]+)([A-Z][a-z])/g, "$1_$2")
+    .replace(/[^A-Za-z0-9_]/g, "_")
+    .toLowerCase();
+}
+
+/**
+ * The JSON name protoc derives from a snake_case field name: lowerCamelCase
+ * with the underscores removed.
+ */
+function defaultJsonName(fieldName: string): string {
+  return fieldName.replace(/_([a-z0-9])/g, (_, c: string) => c.toUpperCase());
+}
+
+function toEnumValueName(value: string): string {
+  return toProtoFieldName(value).toUpperCase().replace(/^_+|_+$/g, "");
+}
+
+/**
+ * Builds a proto3 schema mirroring an xRPC contract: a message per object
+ * type, an enum per string enum and a service per endpoint group with an rpc
+ * per method. Inline types are named after where they appear, like the Go
+ * target's structs.
+ */
+export class ProtoGenerator {
+  private messages = new Map<string, ProtoMessageModel>();
+  private enums = new Map<string, ProtoEnumModel>();
+  private imports = new Set<string>();
+
+  generate(contract: ContractDefinition, options: ProtoOptions = {}): string {
+    return renderProto(this.build(contract, options));
+  }
+
+  /** Builds the schema model of contract without rendering it. */
+  build(contract: ContractDefinition, options: ProtoOptions = {}): ProtoSchema {
+    this.messages.clear();
+    this.enums.clear();
+    this.imports.clear();
+
+    const services = new Map<string, ProtoRpcModel[]>();
+    for (const endpoint of contract.endpoints) {
+      const group = endpoint.fullName.split(".").slice(0, -1).join("_");
+      const service = `${toPascalCase(group || "api")}Service`;
+      const rpc: ProtoRpcModel = {
+        name: toPascalCase(endpoint.name),
+        method: endpoint.fullName,
+        type: endpoint.type,
+        input: this.messageFor(
+          endpoint.input,
+          `${toMethodName(endpoint)}Input`,
+        ),
+        output: this.messageFor(
+          endpoint.output,
+          `${toMethodName(endpoint)}Output`,
+        ),
+      };
+      services.set(service, [...(services.get(service) ?? []), rpc]);
+    }
+
+    return {
+      package: options.package ?? "xrpc",
+      goPackage: options.goPackage,
+      imports: [...this.imports].sort(),
+      services: [...services].map(([name, rpcs]) => ({ name, rpcs })),
+      messages: [...this.messages.values()],
+      enums: [...this.enums.values()],
+    };
+  }
+
+  /**
+   * Returns the message an rpc takes or returns for typeRef, wrapping types
+   * that are not messages in one with a single value field.
+   */
+  private messageFor(typeRef: TypeReference, contextName: string): string {
+    switch (typeRef.kind) {
+      case "object":
+        return this.objectMessage(typeRef, contextName);
+      case "union":
+        return this.unionMessage(typeRef, contextName);
+      case "tuple":
+        return this.tupleMessage(typeRef, contextName);
+    }
+    const name = contextName;
+    if (!this.messages.has(name)) {
+      const message: ProtoMessageModel = { name, kind: "value", fields: [] };
+      this.messages.set(name, message);
+      const field = this.fieldFor(typeRef, `${name}Value`);
+      message.fields.push(modelField(field, "value", 1));
+    }
+    return name;
+  }
+
+  private objectMessage(typeRef: TypeReference, contextName: string): string {
+    const name = typeRef.name ? toPascalCase(typeRef.name) : contextName;
+    if (this.messages.has(name)) {
+      return name;
+    }
+    // Registered before its fields so recursive types terminate
+    const message: ProtoMessageModel = { name, kind: "object", fields: [] };
+    this.messages.set(name, message);
+    (typeRef.properties ?? []).forEach((prop, index) => {
+      message.fields.push(this.propertyField(prop, name, index + 1));
+    });
+    return name;
+  }
+
+  private propertyField(
+    prop: Property,
+    parent: string,
+    number: number,
+  ): ProtoFieldModel {
+    const fieldName = toProtoFieldName(prop.name);
+    const field = this.fieldFor(
+      prop.type,
+      `${parent}${toPascalCase(prop.name)}`,
+      prop.validation?.int,
+    );
+    if (!prop.required && this.canBeOptional(field)) {
+      field.label = "optional";
+    }
+    return { ...field, name: fieldName, jsonName: prop.name, number };
+  }
+
+  private fieldFor(
+    typeRef: TypeReference,
+    contextName: string,
+    int = false,
+  ): ProtoField {
+    switch (typeRef.kind) {
+      case "optional":
+      case "nullable": {
+        const base = typeRef.baseType;
+        const field =
+          typeof base === "object"
+            ? this.fieldFor(base, contextName, int)
+            : {
+                type: this.scalar(base ?? "unknown", int),
+                label: "" as const,
+              };
+        // proto3 has no null; absence stands in for both
+        return this.canBeOptional(field)
+          ? { ...field, label: "optional" }
+          : field;
+      }
+      case "array": {
+        const element = typeRef.elementType
+          ? this.fieldFor(
+              typeRef.elementType,
+              `${contextName}Item`,
+              typeRef.elementType.validation?.int,
+            )
+          : { type: this.scalar("unknown"), label: "" as const };
+        return {
+          type: this.unlabeled(element, `${contextName}Item`),
+          label: "repeated",
+        };
+      }
+      case "record": {
+        const value = typeRef.valueType
+          ? this.fieldFor(
+              typeRef.valueType,
+              `${contextName}Value`,
+              typeRef.valueType.validation?.int,
+            )
+          : { type: this.scalar("unknown"), label: "" as const };
+        const valueType = this.unlabeled(value, `${contextName}Value`);
+        return { type: `map<string, ${valueType}>`, label: "" };
+      }
+      case "object":
+        return { type: this.objectMessage(typeRef, contextName), label: "" };
+      case "enum":
+        return { type: this.enumType(typeRef, contextName, int), label: "" };
+      case "literal":
+        return {
+          type: this.scalar(
+            typeof typeRef.literalValue === "boolean"
+              ? "boolean"
+              : typeof typeRef.literalValue === "number"
+                ? "number"
+                : "string",
+            int || Number.isInteger(typeRef.literalValue),
+          ),
+          label: "",
+        };
+      case "date":
+        this.imports.add("google/protobuf/timestamp.proto");
+        return { type: "google.protobuf.Timestamp", label: "" };
+      case "file":
+        return { type: "bytes", label: "" };
+      case "tuple":
+        return { type: this.tupleMessage(typeRef, contextName), label: "" };
+      case "union":
+        return { type: this.unionMessage(typeRef, contextName), label: "" };
+      default: {
+        const base =
+          typeof typeRef.baseType === "string" ? typeRef.baseType : "unknown";
+        return {
+          type: this.scalar(base, int || typeRef.validation?.int),
+          label: "",
+        };
+      }
+    }
+  }
+
+  /**
+   * Returns the type of a field that cannot carry a label, such as an array
+   * element or map value, wrapping repeated and map fields in a message.
+   */
+  private unlabeled(field: ProtoField, wrapperName: string): string {
+    if (field.label !== "repeated" && !field.type.startsWith("map<")) {
+      return field.type;
+    }
+    if (!this.messages.has(wrapperName)) {
+      this.messages.set(wrapperName, {
+        name: wrapperName,
+        kind: "value",
+        fields: [modelField(field, "values", 1)],
+      });
+    }
+    return wrapperName;
+  }
+
+  private enumType(
+    typeRef: TypeReference,
+    contextName: string,
+    int: boolean,
+  ): string {
+    const values = typeRef.enumValues ?? [];
+    if (values.some((value) => typeof value === "number")) {
+      return this.scalar("number", int || values.every(Number.isInteger));
+    }
+    const name = typeRef.name ? toPascalCase(typeRef.name) : contextName;
+    if (!this.enums.has(name)) {
+      const prefix = toEnumValueName(name);
+      this.enums.set(name, {
+        name,
+        prefix,
+        values: values.map((value) => ({
+          name: `${prefix}_${toEnumValueName(String(value))}`,
+          value: String(value),
+        })),
+      });
+    }
+    return name;
+  }
+
+  private tupleMessage(typeRef: TypeReference, contextName: string): string {
+    const name = typeRef.name ? toPascalCase(typeRef.name) : contextName;
+    if (!this.messages.has(name)) {
+      const message: ProtoMessageModel = { name, kind: "tuple", fields: [] };
+      this.messages.set(name, message);
+      (typeRef.tupleElements ?? []).forEach((element, index) => {
+        const field = this.fieldFor(
+          element,
+          `${name}Item${index}`,
+          element.validation?.int,
+        );
+        message.fields.push(modelField(field, `item_${index}`, index + 1));
+      });
+    }
+    return name;
+  }
+
+  /**
+   * Unions become a message with a oneof holding one field per variant,
+   * named by the discriminator value for discriminated unions and by the
+   * variant's type otherwise. Null variants are left out: the oneof being
+   * unset stands in for them.
+   */
+  private unionMessage(typeRef: TypeReference, contextName: string): string {
+    const name = typeRef.name ? toPascalCase(typeRef.name) : contextName;
+    if (this.messages.has(name)) {
+      return name;
+    }
+    const message: ProtoMessageModel = { name, kind: "union", fields: [] };
+    if (typeRef.discriminator) {
+      message.discriminator = typeRef.discriminator;
+      message.tags = {};
+    }
+    this.messages.set(name, message);
+    const used = new Set<string>();
+    let number = 1;
+    for (const variant of typeRef.unionTypes ?? []) {
+      if (variant.kind === "primitive" && variant.baseType === "null") {
+        continue;
+      }
+      const tag = this.variantTag(variant, typeRef.discriminator);
+      let fieldName = toProtoFieldName(tag);
+      while (used.has(fieldName)) {
+        fieldName = `${fieldName}_${number}`;
+      }
+      used.add(fieldName);
+      const field = this.fieldFor(
+        variant,
+        `${name}${toPascalCase(fieldName)}`,
+        variant.validation?.int,
+      );
+      const type = this.unlabeled(field, `${name}${toPascalCase(fieldName)}`);
+      message.fields.push(modelField({ type, label: "" }, fieldName, number));
+      if (message.tags && typeRef.discriminator) {
+        message.tags[fieldName] = tag;
+      }
+      number++;
+    }
+    return name;
+  }
+
+  private variantTag(variant: TypeReference, discriminator?: string): string {
+    if (discriminator) {
+      const value = variant.properties?.find(
+        (prop) => prop.name === discriminator,
+      )?.type.literalValue;
+      if (value !== undefined) {
+        return String(value);
+      }
+    }
+    if (variant.name) {
+      return variant.name;
+    }
+    if (variant.kind === "primitive" && typeof variant.baseType === "string") {
+      return `${variant.baseType}_value`;
+    }
+    return `${variant.kind}_value`;
+  }
+
+  private scalar(baseType: string, int = false): string {
+    switch (baseType) {
+      case "string":
+      case "uuid":
+      case "email":
+        return "string";
+      case "number":
+        return int ? "int64" : "double";
+      case "integer":
+        return "int64";
+      case "boolean":
+        return "bool";
+      case "bytes":
+        return "bytes";
+      case "date":
+        this.imports.add("google/protobuf/timestamp.proto");
+        return "google.protobuf.Timestamp";
+      default:
+        this.imports.add("google/protobuf/struct.proto");
+        return "google.protobuf.Value";
+    }
+  }
+
+  /**
+   * Whether a field can be declared optional: only singular scalars and
+   * enums, since messages already track presence and maps cannot.
+   */
+  private canBeOptional(field: ProtoField): boolean {
+    return (
+      field.label === "" &&
+      !field.type.startsWith("map<") &&
+      !field.type.startsWith("google.protobuf.") &&
+      !this.messages.has(field.type)
+    );
+  }
+}
+
+function toMethodName(endpoint: Endpoint): string {
+  return endpoint.fullName.split(".").map(toPascalCase).join("");
+}
+
+function modelField(
+  field: ProtoField,
+  name: string,
+  number: number,
+): ProtoFieldModel {
+  return { ...field, name, jsonName: defaultJsonName(name), number };
+}
+
+/** Renders a schema model as the text of a .proto file. */
+export function renderProto(schema: ProtoSchema): string {
+  const w = new CodeWriter();
+  w.l("// Code generated by xRPC. DO NOT EDIT.")
+    .n()
+    .l('syntax = "proto3";')
+    .n()
+    .l(`package ${schema.package};`)
+    .n();
+  if (schema.goPackage) {
+    w.l(`option go_package = "${schema.goPackage}";`).n();
+  }
+  if (schema.imports.length > 0) {
+    for (const path of schema.imports) {
+      w.l(`import "${path}";`);
+    }
+    w.n();
+  }
+
+  for (const service of schema.services) {
+    w.l(`service ${service.name} {`).i();
+    for (const rpc of service.rpcs) {
+      const stream = rpc.type === "subscription" ? "stream " : "";
+      w.l(`// ${rpc.method} (${rpc.type})`).l(
+        `rpc ${rpc.name}(${rpc.input}) returns (${stream}${rpc.output});`,
+      );
+    }
+    w.u().l("}").n();
+  }
+
+  for (const message of schema.messages) {
+    w.l(`message ${message.name} {`).i();
+    if (message.kind === "union") {
+      w.l("oneof value {").i();
+    }
+    for (const field of message.fields) {
+      const label = field.label ? `${field.label} ` : "";
+      const jsonName =
+        defaultJsonName(field.name) === field.jsonName
+          ? ""
+          : ` [json_name = "${field.jsonName}"]`;
+      w.l(`${label}${field.type} ${field.name} = ${field.number}${jsonName};`);
+    }
+    if (message.kind === "union") {
+      w.u().l("}");
+    }
+    w.u().l("}").n();
+  }
+
+  for (const protoEnum of schema.enums) {
+    w.l(`enum ${protoEnum.name} {`).i();
+    w.l(`${protoEnum.prefix}_UNSPECIFIED = 0;`);
+    protoEnum.values.forEach((value, index) => {
+      w.l(`${value.name} = ${index + 1};`);
+    });
+    w.u().l("}").n();
+  }
+
+  return w.toString();
+}
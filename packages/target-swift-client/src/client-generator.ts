@@ -120,18 +120,22 @@ export class SwiftClientGenerator {
     w.mark("Error Types");
 
     w.struct("XRPCErrorObject", ["Decodable", "Equatable"], (b) => {
-      b.l("public let message: String?")
+      b.l("public let code: String?")
+        .l("public let message: String?")
         .l("public let error: String?")
         .l("public let errors: XRPCAny?")
+        .l("public let details: XRPCAny?")
         .l("public let data: XRPCAny?")
         .n()
         .l(
-          "public init(message: String? = nil, error: String? = nil, errors: XRPCAny? = nil, data: XRPCAny? = nil) {",
+          "public init(code: String? = nil, message: String? = nil, error: String? = nil, errors: XRPCAny? = nil, details: XRPCAny? = nil, data: XRPCAny? = nil) {",
         )
         .i()
+        .l("self.code = code")
         .l("self.message = message")
         .l("self.error = error")
         .l("self.errors = errors")
+        .l("self.details = details")
         .l("self.data = data")
         .u()
         .l("}");
@@ -140,11 +144,15 @@ export class SwiftClientGenerator {
     w.n();
 
     w.struct("XRPCErrorPayload", ["Error", "Decodable", "Equatable"], (b) => {
-      b.l("public let message: String")
+      b.l("public let code: String?")
+        .l("public let message: String")
         .l("public let details: XRPCAny?")
         .n()
-        .l("public init(message: String, details: XRPCAny? = nil) {")
+        .l(
+          "public init(code: String? = nil, message: String, details: XRPCAny? = nil) {",
+        )
         .i()
+        .l("self.code = code")
         .l("self.message = message")
         .l("self.details = details")
         .u()
@@ -155,6 +163,7 @@ export class SwiftClientGenerator {
         .l("let container = try decoder.singleValueContainer()")
         .l("if let value = try? container.decode(String.self) {")
         .i()
+        .l("self.code = nil")
         .l("self.message = value")
         .l("self.details = nil")
         .l("return")
@@ -162,10 +171,11 @@ export class SwiftClientGenerator {
         .l("}")
         .l("if let object = try? container.decode(XRPCErrorObject.self) {")
         .i()
+        .l("self.code = object.code")
         .l(
           "self.message = object.message ?? object.error ?? \"Unknown error\"",
         )
-        .l("self.details = object.errors ?? object.data")
+        .l("self.details = object.details ?? object.errors ?? object.data")
         .l("return")
         .u()
         .l("}")
@@ -15,7 +15,15 @@ import { SwiftClientGenerator } from "./client-generator";
 import { SwiftTypeGenerator } from "./type-generator";
 
 const support: TargetSupport = {
-  supportedTypes: [...TYPE_KINDS],
+  supportedTypes: TYPE_KINDS.filter((kind) => kind !== "file"),
+  unsupportedTypes: [
+    {
+      kind: "file",
+      reason:
+        "inputs are sent as bare base64 content, without a filename or content type, and file outputs cannot be decoded",
+      fallback: "Data",
+    },
+  ],
   supportedValidations: [],
   unsupportedValidations: VALIDATION_KINDS.map((kind) => ({
     kind,
@@ -136,8 +144,24 @@ function collectRequiredNullableFields(
 }
 
 function generateSwiftClient(input: TargetInput): TargetOutput {
-  const { contract } = input;
-  const diagnostics = validateSupport(contract, support, "swift-client");
+  const { contract: fullContract } = input;
+  const diagnostics = validateSupport(fullContract, support, "swift-client");
+
+  // Subscriptions are streamed over SSE, which this target does not support yet
+  const contract = {
+    ...fullContract,
+    endpoints: fullContract.endpoints.filter(
+      (endpoint) => endpoint.type !== "subscription",
+    ),
+  };
+  for (const endpoint of fullContract.endpoints) {
+    if (endpoint.type === "subscription") {
+      diagnostics.push({
+        severity: "warning",
+        message: `Subscription "${endpoint.fullName}" is not supported by swift-client and was skipped.`,
+      });
+    }
+  }
 
   const requiredNullableFields = collectRequiredNullableFields(contract);
   for (const field of requiredNullableFields) {
@@ -153,6 +153,7 @@ export class SwiftTypeGenerator {
       case "nullable":
       case "literal":
       case "date":
+      case "file":
         this.generateAliasType(typeName, this.mapType(typeRef));
         return;
       default:
@@ -20,6 +20,7 @@ export class SwiftTypeMapper extends TypeMapperBase<string> {
     record: (ctx) => this.handleRecord(ctx),
     tuple: (ctx) => this.handleTuple(ctx),
     date: () => this.handleDate(),
+    file: () => this.handleFile(),
   };
 
   mapPrimitive(type: string): string {
@@ -28,6 +29,7 @@ export class SwiftTypeMapper extends TypeMapperBase<string> {
       number: "Double",
       integer: "Int",
       boolean: "Bool",
+      bytes: "Data",
       date: "Date",
       uuid: "String",
       email: "String",
@@ -185,6 +187,10 @@ export class SwiftTypeMapper extends TypeMapperBase<string> {
     return { type: "Date" };
   }
 
+  private handleFile(): TypeResult<string> {
+    return { type: "Data" };
+  }
+
   private wrapOptional(type: string): string {
     return type.endsWith("?") ? type : `${type}?`;
   }
@@ -1,4 +1,9 @@
-import type { ContractDefinition, Endpoint } from "@xrpckit/sdk";
+import {
+  type ContractDefinition,
+  collectContractUsage,
+  type Endpoint,
+  schemaHash,
+} from "@xrpckit/sdk";
 import { TsBuilder } from "./ts-builder";
 
 export class TsClientGenerator {
@@ -35,14 +40,32 @@ export class TsClientGenerator {
     this.generateClientConfig(w);
     w.n();
 
-    // Generate base RPC call function
-    this.generateCallRpcFunction(w);
+    // Generate the schema hash check warning about a stale client
+    this.generateSchemaHash(contract, w);
+
+    // Generate error type thrown for failed calls
+    this.generateClientError(w);
+
+    // Generate base RPC call function, sending files as multipart form data
+    const hasFiles = collectContractUsage(contract).types.has("file");
+    if (hasFiles) {
+      this.generateExtractFilesFunction(w);
+    }
+    this.generateCallRpcFunction(w, hasFiles);
+
+    if (contract.endpoints.some((ep) => ep.type === "subscription")) {
+      this.generateSubscribeRpcFunction(w);
+    }
 
     // Generate type-safe wrapper functions for each endpoint
     w.comment("=== Individual Functions (backward compatible) ===");
     w.n();
     for (const endpoint of contract.endpoints) {
-      this.generateEndpointFunction(endpoint, w);
+      if (endpoint.type === "subscription") {
+        this.generateSubscriptionFunction(endpoint, w);
+      } else {
+        this.generateEndpointFunction(endpoint, w);
+      }
       w.n();
     }
 
@@ -100,6 +123,19 @@ export class TsClientGenerator {
         const inputType = this.getTypeName(endpoint, "Input");
         const isLastEndpoint = j === endpoints.length - 1;
 
+        if (endpoint.type === "subscription") {
+          const outputType = this.getTypeName(endpoint, "Output");
+          w.l(
+            `${methodName}: (input: ${inputType}, onData: (data: ${outputType}) => void, options?: { onError?: (error: Error) => void }) =>`,
+          );
+          w.i();
+          w.l(
+            `${functionName}(config, input, onData, options)${isLastEndpoint ? "" : ","}`,
+          );
+          w.u();
+          continue;
+        }
+
         w.l(
           `${methodName}: (input: ${inputType}, options?: { signal?: AbortSignal }) =>`,
         );
@@ -129,11 +165,134 @@ export class TsClientGenerator {
       b.l("baseUrl: string;")
         .l("validateInputs?: boolean;")
         .l("validateOutputs?: boolean;")
-        .l("headers?: Record<string, string>;");
+        .l("headers?: Record<string, string>;")
+        .l("sendSchemaHash?: boolean;")
+        .l("onSchemaMismatch?: (serverHash: string) => void;");
     });
   }
 
-  private generateCallRpcFunction(w: TsBuilder): void {
+  private generateSchemaHash(contract: ContractDefinition, w: TsBuilder): void {
+    w.comment(
+      "Hash of the contract this client was generated from; the server answers",
+    );
+    w.comment(
+      "calls sending a different one with an X-Xrpc-Schema-Mismatch header",
+    );
+    w.l(`export const SCHEMA_HASH = '${schemaHash(contract)}';`).n();
+
+    w.l("let schemaMismatchWarned = false;").n();
+
+    w.comment(
+      "Reports a server generated from another contract to onSchemaMismatch, or",
+    );
+    w.comment("warns about it once on the console");
+    w.n();
+    w.function(
+      "checkSchemaHash(config: XRpcClientConfig, response: Response): void",
+      (b) => {
+        b.l(
+          "const serverHash = response.headers.get('X-Xrpc-Schema-Mismatch');",
+        );
+        b.l("if (!serverHash) {");
+        b.i().l("return;");
+        b.u().l("}");
+        b.l("if (config.onSchemaMismatch) {");
+        b.i().l("config.onSchemaMismatch(serverHash);").l("return;");
+        b.u().l("}");
+        b.l("if (!schemaMismatchWarned) {");
+        b.i()
+          .l("schemaMismatchWarned = true;")
+          .l(
+            "console.warn(`xRPC client generated from schema ${SCHEMA_HASH}, but the server serves ${serverHash}; regenerate the client`);",
+          );
+        b.u().l("}");
+      },
+    );
+    w.n();
+  }
+
+  private generateClientError(w: TsBuilder): void {
+    w.comment(
+      "Error thrown when an RPC call fails; code mirrors the server's error code",
+    );
+    w.l("export class XRpcError extends Error {")
+      .i()
+      .l("readonly code: string;")
+      .l("readonly details?: unknown;")
+      .l("readonly status?: number;")
+      .n()
+      .l(
+        "constructor(code: string, message: string, details?: unknown, status?: number) {",
+      )
+      .i()
+      .l("super(message);")
+      .l("this.name = 'XRpcError';")
+      .l("this.code = code;")
+      .l("this.details = details;")
+      .l("this.status = status;")
+      .u()
+      .l("}")
+      .u()
+      .l("}")
+      .n();
+
+    w.comment("Normalizes an error payload from the server into an XRpcError");
+    w.l(
+      "function toXRpcError(payload: any, fallback: string, status?: number): XRpcError {",
+    )
+      .i()
+      .l("if (typeof payload === 'string') {")
+      .i()
+      .l("return new XRpcError('INTERNAL', payload, undefined, status);")
+      .u()
+      .l("}")
+      .l(
+        "return new XRpcError(payload?.code ?? 'INTERNAL', payload?.message || fallback, payload?.details ?? payload?.data, status);",
+      )
+      .u()
+      .l("}")
+      .n();
+  }
+
+  private generateExtractFilesFunction(w: TsBuilder): void {
+    w.comment(
+      "Moves the Blobs (such as Files) out of params, collecting them with the",
+    );
+    w.comment('dotted path of their field, such as "photos.0"');
+    w.n();
+    w.function(
+      "extractFiles(value: unknown, path: string, files: Array<[string, Blob]>): unknown",
+      (b) => {
+        b.l("if (value instanceof Blob) {");
+        b.i().l("files.push([path, value]);").l("return null;");
+        b.u().l("}");
+        b.l(
+          "const child = (key: string | number) => (path ? `${path}.${key}` : `${key}`);",
+        );
+        b.l("if (Array.isArray(value)) {");
+        b.i().l(
+          "return value.map((item, i) => extractFiles(item, child(i), files));",
+        );
+        b.u().l("}");
+        b.l(
+          "if (value !== null && Object.getPrototypeOf(value) === Object.prototype) {",
+        );
+        b.i()
+          .l("return Object.fromEntries(")
+          .i()
+          .l(
+            "Object.entries(value as object).map(([key, item]) => [key, extractFiles(item, child(key), files)]),",
+          )
+          .u()
+          .l(");");
+        b.u().l("}");
+        b.l("return value;");
+      },
+    );
+    w.n();
+  }
+
+  private generateCallRpcFunction(w: TsBuilder, hasFiles: boolean): void {
     w.comment("Base RPC call function");
     w.n();
     w.asyncFunction(
@@ -145,15 +304,26 @@ export class TsClientGenerator {
         b.i().l("validatedParams = options.inputSchema.parse(params);");
         b.u().l("}").n();
 
-        b.comment("Make HTTP request");
-        b.l("const response = await fetch(config.baseUrl, {");
-        b.i().l("method: 'POST',").l("headers: {");
-        b.i().l("'Content-Type': 'application/json',").l("...config.headers,");
-        b.u()
-          .l("},")
-          .l("body: JSON.stringify({ method, params: validatedParams }),")
-          .l("signal: options?.signal,");
-        b.u().l("});").n();
+        if (hasFiles) {
+          this.generateMultipartRequest(b);
+        } else {
+          b.comment("Make HTTP request");
+          b.l("const response = await fetch(config.baseUrl, {");
+          b.i().l("method: 'POST',").l("headers: {");
+          b.i()
+            .l("'Content-Type': 'application/json',")
+            .l(
+              "...(config.sendSchemaHash === false ? {} : { 'X-Xrpc-Schema-Hash': SCHEMA_HASH }),",
+            )
+            .l("...config.headers,");
+          b.u()
+            .l("},")
+            .l("body: JSON.stringify({ method, params: validatedParams }),")
+            .l("signal: options?.signal,");
+          b.u().l("});").n();
+        }
+
+        b.l("checkSchemaHash(config, response);").n();
 
         b.comment("Handle errors");
         b.l("if (!response.ok) {");
@@ -162,7 +332,7 @@ export class TsClientGenerator {
             "const error = await response.json().catch(() => ({ error: { message: response.statusText } }));",
           )
           .l(
-            "throw new Error(error.error?.message || `RPC call failed: ${response.statusText}`);",
+            "throw toXRpcError(error.error, `RPC call failed: ${response.statusText}`, response.status);",
           );
         b.u().l("}").n();
 
@@ -170,7 +340,9 @@ export class TsClientGenerator {
         b.l("const result = await response.json();");
         b.comment("Handle JSON-RPC response format");
         b.l("if (result.error) {");
-        b.i().l("throw new Error(result.error.message || result.error);");
+        b.i().l(
+          "throw toXRpcError(result.error, 'RPC call failed', response.status);",
+        );
         b.u().l("}");
         b.l("const data = result.result;").n();
 
@@ -184,6 +356,129 @@ export class TsClientGenerator {
     );
   }
 
+  private generateMultipartRequest(b: TsBuilder): void {
+    b.comment(
+      "Params holding files are sent as multipart form data: the method, the JSON",
+    );
+    b.comment("params with null in place of each file, and a part per file");
+    b.l("const files: Array<[string, Blob]> = [];");
+    b.l("const jsonParams = extractFiles(validatedParams, '', files);");
+    b.l("const headers: Record<string, string> = {");
+    b.i()
+      .l("'Content-Type': 'application/json',")
+      .l(
+        "...(config.sendSchemaHash === false ? {} : { 'X-Xrpc-Schema-Hash': SCHEMA_HASH }),",
+      )
+      .l("...config.headers,");
+    b.u().l("};");
+    b.l(
+      "let body: BodyInit = JSON.stringify({ method, params: jsonParams });",
+    );
+    b.l("if (files.length > 0) {");
+    b.i()
+      .l("const form = new FormData();")
+      .l("form.append('method', method);")
+      .l("form.append('params', JSON.stringify(jsonParams));")
+      .l("for (const [path, file] of files) {");
+    b.i().l("form.append(path, file);");
+    b.u().l("}");
+    b.comment("fetch sets the multipart Content-Type with its boundary");
+    b.l("delete headers['Content-Type'];").l("body = form;");
+    b.u().l("}").n();
+
+    b.comment("Make HTTP request");
+    b.l("const response = await fetch(config.baseUrl, {");
+    b.i()
+      .l("method: 'POST',")
+      .l("headers,")
+      .l("body,")
+      .l("signal: options?.signal,");
+    b.u().l("});").n();
+  }
+
+  private generateSubscribeRpcFunction(w: TsBuilder): void {
+    w.comment("Base subscription function (Server-Sent Events)");
+    w.comment(
+      "EventSource cannot send custom headers, so config.headers is not applied",
+    );
+    w.n();
+    w.function(
+      "subscribeRpc<T>(config: XRpcClientConfig, method: string, params: unknown, onData: (data: T) => void, options?: { inputSchema?: z.ZodType; outputSchema?: z.ZodType; onError?: (error: Error) => void }): () => void",
+      (b) => {
+        b.comment("Validate input if enabled");
+        b.l("let validatedParams = params;");
+        b.l("if (config.validateInputs !== false && options?.inputSchema) {");
+        b.i().l("validatedParams = options.inputSchema.parse(params);");
+        b.u().l("}").n();
+
+        b.l("const url = new URL(config.baseUrl, globalThis.location?.href);")
+          .l("url.searchParams.set('method', method);")
+          .l(
+            "url.searchParams.set('params', JSON.stringify(validatedParams));",
+          )
+          .l("const source = new EventSource(url);")
+          .n();
+
+        b.l("source.onmessage = (event) => {");
+        b.i().l("const data = JSON.parse(event.data);");
+        b.l("if (config.validateOutputs && options?.outputSchema) {");
+        b.i().l("onData(options.outputSchema.parse(data));");
+        b.u().l("} else {");
+        b.i().l("onData(data);");
+        b.u().l("}");
+        b.u().l("};").n();
+
+        b.comment("Handler errors arrive as 'error' events with a JSON payload");
+        b.l("source.addEventListener('error', (event) => {");
+        b.i().l("if (event instanceof MessageEvent) {");
+        b.i().l(
+          "options?.onError?.(toXRpcError(JSON.parse(event.data).error, 'Subscription failed'));",
+        );
+        b.u().l("} else {");
+        b.i().l(
+          "options?.onError?.(new Error('Subscription connection error'));",
+        );
+        b.u().l("}");
+        b.u().l("});").n();
+
+        b.l("return () => source.close();");
+      },
+    );
+  }
+
+  private generateSubscriptionFunction(endpoint: Endpoint, w: TsBuilder): void {
+    const functionName = this.getFunctionName(endpoint);
+    const inputType = this.getTypeName(endpoint, "Input");
+    const outputType = this.getTypeName(endpoint, "Output");
+    const inputSchema = this.getSchemaName(endpoint, "input");
+    const outputSchema = this.getSchemaName(endpoint, "output");
+
+    w.comment(
+      `Type-safe subscription for ${endpoint.fullName}; returns an unsubscribe function`,
+    );
+    w.n();
+    w.function(
+      `${functionName}(config: XRpcClientConfig, input: ${inputType}, onData: (data: ${outputType}) => void, options?: { onError?: (error: Error) => void })`,
+      (b) => {
+        b.l(`return subscribeRpc<${outputType}>(`);
+        b.i()
+          .l("config,")
+          .l(`'${endpoint.fullName}',`)
+          .l("input,")
+          .l("onData,")
+          .l("{")
+          .i()
+          .l(`inputSchema: ${inputSchema},`)
+          .l(`outputSchema: ${outputSchema},`)
+          .l("onError: options?.onError,")
+          .u()
+          .l("}")
+          .u()
+          .l(");");
+      },
+    );
+  }
+
   private generateEndpointFunction(endpoint: Endpoint, w: TsBuilder): void {
     const functionName = this.getFunctionName(endpoint);
     const inputType = this.getTypeName(endpoint, "Input");
@@ -240,7 +535,7 @@ export class TsClientGenerator {
 
   private toPascalCase(str: string): string {
     return str
-      .split(/[-_]/)
+      .split(/[-_@]/)
       .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
       .join("");
   }
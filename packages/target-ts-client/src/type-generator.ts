@@ -44,7 +44,10 @@ export class TsTypeGenerator {
     // Extract group and endpoint names from fullName (e.g., "greeting.greet")
     const [groupName, endpointName] = endpoint.fullName.split(".");
 
-    const routerPath = `router.${groupName}.${endpointName}`;
+    // Versioned names such as "list@v2" are not identifiers
+    const routerPath = /^[A-Za-z_$][\w$]*$/.test(endpointName)
+      ? `router.${groupName}.${endpointName}`
+      : `router.${groupName}[${JSON.stringify(endpointName)}]`;
 
     // Export schemas and types for this endpoint (grouped together)
     w.const(inputSchemaName, `${routerPath}.input`, true);
@@ -97,7 +100,7 @@ export class TsTypeGenerator {
 
   private toPascalCase(str: string): string {
     return str
-      .split(/[-_]/)
+      .split(/[-_@]/)
       .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
       .join("");
   }
@@ -32,6 +32,7 @@ export class TsTypeMapper extends TypeMapperBase<string> {
     record: (ctx) => this.handleRecord(ctx),
     tuple: (ctx) => this.handleTuple(ctx),
     date: () => this.handleDate(),
+    file: () => this.handleFile(),
   };
 
   /**
@@ -43,6 +44,7 @@ export class TsTypeMapper extends TypeMapperBase<string> {
       number: "number",
       integer: "number",
       boolean: "boolean",
+      bytes: "string",
       date: "Date",
       uuid: "string",
       email: "string",
@@ -230,4 +232,8 @@ export class TsTypeMapper extends TypeMapperBase<string> {
   private handleDate(): TypeResult<string> {
     return { type: "Date" };
   }
+
+  private handleFile(): TypeResult<string> {
+    return { type: "File" };
+  }
 }
@@ -29,6 +29,13 @@ export class TsValidationMapper extends ValidationMapperBase<null> {
     url: createNoOpValidationHandler(),
     uuid: createNoOpValidationHandler(),
     regex: createNoOpValidationHandler(),
+    datetime: createNoOpValidationHandler(),
+    date: createNoOpValidationHandler(),
+    hostname: createNoOpValidationHandler(),
+    ipv4: createNoOpValidationHandler(),
+    ipv6: createNoOpValidationHandler(),
+    duration: createNoOpValidationHandler(),
+    e164: createNoOpValidationHandler(),
 
     // Number validations - handled by Zod z.number().min(), .max(), .int(), etc.
     min: createNoOpValidationHandler(),
@@ -36,9 +43,37 @@ export class TsValidationMapper extends ValidationMapperBase<null> {
     int: createNoOpValidationHandler(),
     positive: createNoOpValidationHandler(),
     negative: createNoOpValidationHandler(),
+    exclusiveMin: createNoOpValidationHandler(),
+    exclusiveMax: createNoOpValidationHandler(),
+    multipleOf: createNoOpValidationHandler(),
 
-    // Array validations - handled by Zod z.array().min(), .max()
+    // Array validations - handled by Zod z.array().min(), .max(); uniqueItems
+    // is contract metadata that servers check
     minItems: createNoOpValidationHandler(),
     maxItems: createNoOpValidationHandler(),
+    uniqueItems: createNoOpValidationHandler(),
+
+    // Date validations - contract metadata that servers check, not Zod checks
+    future: createNoOpValidationHandler(),
+    past: createNoOpValidationHandler(),
+
+    // Record validations - contract metadata that servers check, not Zod checks
+    minEntries: createNoOpValidationHandler(),
+    maxEntries: createNoOpValidationHandler(),
+
+    // File validations - handled by Zod z.file().min(), .max(), .mime()
+    minSize: createNoOpValidationHandler(),
+    maxSize: createNoOpValidationHandler(),
+    mimeTypes: createNoOpValidationHandler(),
+
+    // Conditional validations - contract metadata that servers check
+    requiredIf: createNoOpValidationHandler(),
+    forbiddenIf: createNoOpValidationHandler(),
+
+    // Warnings - soft limits servers report without rejecting the call
+    warn: createNoOpValidationHandler(),
+
+    // Custom validations - named validators registered with the server
+    custom: createNoOpValidationHandler(),
   };
 }
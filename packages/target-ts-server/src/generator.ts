@@ -20,7 +20,13 @@ import { TsTypeGenerator } from "./type-generator";
  * Note: This target relies on Zod for runtime validation and schema inference.
  */
 const support: TargetSupport = {
-  supportedTypes: [...TYPE_KINDS],
+  supportedTypes: TYPE_KINDS.filter((kind) => kind !== "file"),
+  unsupportedTypes: [
+    {
+      kind: "file",
+      reason: "the handler only decodes JSON bodies, not multipart uploads",
+    },
+  ],
   supportedValidations: [...VALIDATION_KINDS],
   notes: [
     "Uses Zod for type inference and runtime validation",
@@ -41,8 +47,24 @@ function getContractPath(options?: Record<string, unknown>): string | undefined
 }
 
 function generateTsServer(input: TargetInput): TargetOutput {
-  const { contract, outputDir } = input;
-  const diagnostics = validateSupport(contract, support, "ts-server");
+  const { contract: fullContract, outputDir } = input;
+  const diagnostics = validateSupport(fullContract, support, "ts-server");
+
+  // Subscriptions are streamed over SSE, which this target does not support yet
+  const contract = {
+    ...fullContract,
+    endpoints: fullContract.endpoints.filter(
+      (endpoint) => endpoint.type !== "subscription",
+    ),
+  };
+  for (const endpoint of fullContract.endpoints) {
+    if (endpoint.type === "subscription") {
+      diagnostics.push({
+        severity: "warning",
+        message: `Subscription "${endpoint.fullName}" is not supported by ts-server and was skipped.`,
+      });
+    }
+  }
 
   const contractPath = getContractPath(input.options);
   if (!contractPath) {
@@ -356,7 +356,7 @@ export class TsServerGenerator {
 
   private toPascalCase(str: string): string {
     return str
-      .split(/[-_]/)
+      .split(/[-_@]/)
       .map((word) => word.charAt(0).toUpperCase() + word.slice(1))
       .join("");
   }
@@ -1,12 +1,141 @@
-import type { z } from "zod";
+import { z } from "zod";
 
 export interface EndpointDefinition<
   TInputSchema extends z.ZodTypeAny = z.ZodTypeAny,
   TOutputSchema extends z.ZodTypeAny = z.ZodTypeAny,
 > {
-  type: "query" | "mutation";
+  type: "query" | "mutation" | "subscription";
   input: TInputSchema;
   output: TOutputSchema;
+  /**
+   * "required" makes generated servers reject calls that no authentication
+   * middleware authenticated.
+   */
+  auth?: "required";
+  /**
+   * "required" makes generated servers reject calls that no tenant resolution
+   * middleware resolved a tenant for, for methods serving tenant-scoped data.
+   */
+  tenant?: "required";
+  /**
+   * Permissions the caller must hold, checked by the generated server's
+   * authorizer before the handler runs.
+   */
+  permissions?: string[];
+  /**
+   * HTTP mapping for the generated REST layer, e.g. "GET /tasks/{id}". Path
+   * parameters are bound to the input fields of the same name.
+   */
+  http?: string;
+  /**
+   * Output field holding an array the generated servers stream item by item,
+   * e.g. "tasks" for output z.object({ tasks: z.array(Task) }), instead of
+   * buffering the whole result.
+   */
+  stream?: string;
+  /**
+   * Whether the query is paginated: its input has the cursor and pageSize
+   * fields and its output the nextCursor field.
+   */
+  paginated?: boolean;
+  /**
+   * Marks the endpoint as deprecated: true, a notice telling callers what to
+   * use instead, or a Deprecation with the dates it was deprecated on and
+   * will be removed on. Generated servers report it through introspection
+   * and can send Deprecation and Sunset response headers.
+   */
+  deprecated?: boolean | string | Deprecation;
+  /**
+   * Event a mutation publishes after it succeeds, e.g. "task.created". Its
+   * payload is the mutation's output, and generated servers publish it
+   * through the event publisher they are given, such as a webhook.
+   */
+  event?: string;
+  /**
+   * Runs a slow mutation in the background: calls answer at once with an
+   * operation (operationId and status) that clients poll with the built-in
+   * operation.get method and can stop with operation.cancel, instead of
+   * holding the HTTP request open until the mutation is done.
+   */
+  async?: boolean;
+  /**
+   * What the endpoint does, carried into the documentation of generated
+   * code, such as the doc comments of the Go handler type and router method.
+   */
+  description?: string;
+}
+
+/**
+ * Details of a deprecated endpoint. Dates are ISO 8601 calendar dates, e.g.
+ * "2025-06-30".
+ */
+export interface Deprecation {
+  /** What callers should use instead, e.g. "Use task.list@v2." */
+  message?: string;
+  /** When the endpoint was deprecated */
+  since?: string;
+  /** When the endpoint will stop being served */
+  sunset?: string;
+}
+
+/**
+ * Largest pageSize a paginated query accepts.
+ */
+export const MAX_PAGE_SIZE = 100;
+
+/**
+ * Input fields of paginated queries: the cursor of the page to return, from
+ * the nextCursor of the previous one, and the number of items per page.
+ */
+const pageInput = {
+  cursor: z.string().optional(),
+  pageSize: z.number().int().min(1).max(MAX_PAGE_SIZE).optional(),
+};
+
+/**
+ * Output fields of paginated queries: the cursor of the next page, absent on
+ * the last one.
+ */
+const pageOutput = {
+  nextCursor: z.string().optional(),
+};
+
+/**
+ * An object schema extended with the pagination fields.
+ */
+export type Paginated<
+  TSchema extends z.ZodTypeAny,
+  TFields extends z.core.$ZodLooseShape,
+> = TSchema extends z.ZodObject<infer Shape, infer Config>
+  ? z.ZodObject<z.core.util.Extend<Shape, TFields>, Config>
+  : TSchema;
+
+/**
+ * The definition query() returns, whose schemas have the pagination fields
+ * when it is paginated.
+ */
+type QueryDefinition<
+  TInputSchema extends z.ZodTypeAny,
+  TOutputSchema extends z.ZodTypeAny,
+  TPaginated extends boolean,
+> = EndpointDefinition<
+  TPaginated extends true
+    ? Paginated<TInputSchema, typeof pageInput>
+    : TInputSchema,
+  TPaginated extends true
+    ? Paginated<TOutputSchema, typeof pageOutput>
+    : TOutputSchema
+>;
+
+function paginate(
+  schema: z.ZodTypeAny,
+  fields: z.core.$ZodLooseShape,
+  name: "input" | "output",
+): z.ZodTypeAny {
+  if (!(schema instanceof z.ZodObject)) {
+    throw new Error(`Paginated queries need an object ${name} schema.`);
+  }
+  return schema.extend(fields);
 }
 
 /**
@@ -16,6 +145,15 @@ export interface EndpointDefinition<
  * @param config - Configuration object containing input and output Zod schemas
  * @param config.input - Zod schema for validating the input parameters
  * @param config.output - Zod schema for validating the output response
+ * @param config.auth - "required" to reject unauthenticated calls
+ * @param config.tenant - "required" to reject calls without a tenant
+ * @param config.permissions - Permissions the caller must hold, e.g. ["task:write"]
+ * @param config.http - REST mapping, e.g. "GET /tasks/{id}"
+ * @param config.stream - Output array field to stream, e.g. "tasks"
+ * @param config.paginated - true to add the cursor and pageSize input fields
+ *   and the nextCursor output field
+ * @param config.deprecated - true, a notice, or a Deprecation with dates
+ * @param config.description - What the endpoint does, for generated docs
  * @returns An endpoint definition with type 'query'
  *
  * @example
@@ -29,15 +167,41 @@ export interface EndpointDefinition<
 export function query<
   TInputSchema extends z.ZodTypeAny,
   TOutputSchema extends z.ZodTypeAny,
+  TPaginated extends boolean = false,
 >(config: {
   input: TInputSchema;
   output: TOutputSchema;
-}): EndpointDefinition<TInputSchema, TOutputSchema> {
-  return {
+  auth?: "required";
+  tenant?: "required";
+  permissions?: string[];
+  http?: string;
+  stream?: string;
+  paginated?: TPaginated;
+  deprecated?: boolean | string | Deprecation;
+  description?: string;
+}): QueryDefinition<TInputSchema, TOutputSchema, TPaginated> {
+  const definition: EndpointDefinition = {
     type: "query",
     input: config.input,
     output: config.output,
+    auth: config.auth,
+    tenant: config.tenant,
+    permissions: config.permissions,
+    http: config.http,
+    stream: config.stream,
+    deprecated: config.deprecated,
+    description: config.description,
   };
+  if (config.paginated) {
+    definition.input = paginate(config.input, pageInput, "input");
+    definition.output = paginate(config.output, pageOutput, "output");
+    definition.paginated = true;
+  }
+  return definition as QueryDefinition<
+    TInputSchema,
+    TOutputSchema,
+    TPaginated
+  >;
 }
 
 /**
@@ -47,6 +211,17 @@ export function query<
  * @param config - Configuration object containing input and output Zod schemas
  * @param config.input - Zod schema for validating the input parameters
  * @param config.output - Zod schema for validating the output response
+ * @param config.auth - "required" to reject unauthenticated calls
+ * @param config.tenant - "required" to reject calls without a tenant
+ * @param config.permissions - Permissions the caller must hold, e.g. ["task:write"]
+ * @param config.http - REST mapping, e.g. "GET /tasks/{id}"
+ * @param config.stream - Output array field to stream, e.g. "tasks"
+ * @param config.deprecated - true, a notice, or a Deprecation with dates
+ * @param config.event - Event published after the mutation succeeds, e.g.
+ *   "task.created"
+ * @param config.async - true to run the mutation in the background and answer
+ *   with an operation to poll
+ * @param config.description - What the endpoint does, for generated docs
  * @returns An endpoint definition with type 'mutation'
  *
  * @example
@@ -63,10 +238,75 @@ export function mutation<
 >(config: {
   input: TInputSchema;
   output: TOutputSchema;
+  auth?: "required";
+  tenant?: "required";
+  permissions?: string[];
+  http?: string;
+  stream?: string;
+  deprecated?: boolean | string | Deprecation;
+  event?: string;
+  async?: boolean;
+  description?: string;
 }): EndpointDefinition<TInputSchema, TOutputSchema> {
   return {
     type: "mutation",
     input: config.input,
     output: config.output,
+    auth: config.auth,
+    tenant: config.tenant,
+    permissions: config.permissions,
+    http: config.http,
+    stream: config.stream,
+    deprecated: config.deprecated,
+    event: config.event,
+    async: config.async,
+    description: config.description,
+  };
+}
+
+/**
+ * Creates a subscription endpoint definition.
+ * Subscriptions push a stream of output values to the client (served over
+ * Server-Sent Events) until either side closes the connection.
+ *
+ * @param config - Configuration object containing input and output Zod schemas
+ * @param config.input - Zod schema for validating the subscription parameters
+ * @param config.output - Zod schema for each event pushed to the client
+ * @param config.auth - "required" to reject unauthenticated calls
+ * @param config.tenant - "required" to reject calls without a tenant
+ * @param config.permissions - Permissions the caller must hold, e.g. ["task:write"]
+ * @param config.deprecated - true, a notice, or a Deprecation with dates
+ * @param config.description - What the endpoint does, for generated docs
+ * @returns An endpoint definition with type 'subscription'
+ *
+ * @example
+ * ```typescript
+ * const watchTasks = subscription({
+ *   input: z.object({ taskId: z.string().optional() }),
+ *   output: z.object({ type: z.enum(['created', 'updated']), taskId: z.string() }),
+ * });
+ * ```
+ */
+export function subscription<
+  TInputSchema extends z.ZodTypeAny,
+  TOutputSchema extends z.ZodTypeAny,
+>(config: {
+  input: TInputSchema;
+  output: TOutputSchema;
+  auth?: "required";
+  tenant?: "required";
+  permissions?: string[];
+  deprecated?: boolean | string | Deprecation;
+  description?: string;
+}): EndpointDefinition<TInputSchema, TOutputSchema> {
+  return {
+    type: "subscription",
+    input: config.input,
+    output: config.output,
+    auth: config.auth,
+    tenant: config.tenant,
+    permissions: config.permissions,
+    deprecated: config.deprecated,
+    description: config.description,
   };
 }
@@ -7,5 +7,13 @@ export {
   type Middleware,
   type RouterConfig,
 } from "./router";
-export { query, mutation, type EndpointDefinition } from "./endpoint";
+export {
+  query,
+  mutation,
+  subscription,
+  MAX_PAGE_SIZE,
+  type EndpointDefinition,
+  type Deprecation,
+  type Paginated,
+} from "./endpoint";
 export type { InferInput, InferOutput } from "./types";
@@ -61,6 +61,9 @@ function isRouterConfig(
  * @param endpoints - An object mapping endpoint names to their definitions (query or mutation)
  * @returns The endpoint group with preserved types
  *
+ * Names may end in a version, as in "list@v2", so a new version of a method
+ * can be served next to the one it replaces until callers have moved over.
+ *
  * @example
  * ```typescript
  * const greeting = createEndpoint({
@@ -69,6 +72,11 @@ function isRouterConfig(
  *     output: z.object({ message: z.string() }),
  *   }),
  * });
+ *
+ * const task = createEndpoint({
+ *   list: query({ ..., deprecated: "Use task.list@v2." }),
+ *   "list@v2": query({ ... }),
+ * });
  * ```
  */
 export function createEndpoint<T extends EndpointGroup>(endpoints: T): T {
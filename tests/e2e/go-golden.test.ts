@@ -0,0 +1,55 @@
+import { describe, test, expect } from 'bun:test';
+import { mkdirSync, readdirSync, readFileSync, rmSync, writeFileSync } from 'node:fs';
+import { join } from 'node:path';
+import { parseContract } from '../../packages/sdk/src/parser/index.js';
+import type { ContractDefinition } from '../../packages/sdk/src/parser/index.js';
+import { goTarget } from '../../packages/target-go-server/src/index.js';
+
+// The generated files of the fixture, committed so a generator change shows
+// up as a diff of them. After an intended change, update them with
+//   UPDATE_GOLDEN=1 bun test tests/e2e/go-golden.test.ts
+const goldenDir = join(process.cwd(), 'tests', 'e2e', 'golden', 'go-server');
+const inputPath = join(process.cwd(), 'tests', 'fixtures', 'api-with-validation.ts');
+
+function generate(contract: ContractDefinition): Map<string, string> {
+  const result = goTarget.generate({
+    contract,
+    outputDir: 'generated',
+    options: { packageName: 'server' },
+  });
+  expect(result.diagnostics ?? []).toEqual([]);
+  return new Map(result.files.map((file) => [file.path, file.content]));
+}
+
+describe('Go Server golden files', () => {
+  test('generates the golden files', async () => {
+    const files = generate(await parseContract(inputPath));
+
+    if (process.env.UPDATE_GOLDEN) {
+      rmSync(goldenDir, { recursive: true, force: true });
+      mkdirSync(goldenDir, { recursive: true });
+      for (const [path, content] of files) {
+        writeFileSync(join(goldenDir, `${path}.golden`), content);
+      }
+    }
+
+    const golden = readdirSync(goldenDir)
+      .map((name) => name.replace(/\.golden$/, ''))
+      .sort();
+    expect([...files.keys()].sort()).toEqual(golden);
+    for (const [path, content] of files) {
+      expect(content).toBe(readFileSync(join(goldenDir, `${path}.golden`), 'utf8'));
+    }
+  });
+
+  test('generates the same files whatever the declaration order', async () => {
+    const contract = await parseContract(inputPath);
+    const reordered = {
+      ...contract,
+      endpoints: [...contract.endpoints].reverse(),
+      types: [...contract.types].reverse(),
+    };
+
+    expect(generate(reordered)).toEqual(generate(contract));
+  });
+});
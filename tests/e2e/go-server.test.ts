@@ -166,6 +166,7 @@ go 1.25
       'package main',
       '',
       'import (',
+      '	"context"',
       '	"fmt"',
       '	"log"',
       '	"net/http"',
@@ -174,13 +175,13 @@ go 1.25
       '	"github.com/test/e2e-server/generated/go/server"',
       ')',
       '',
-      'func greetHandler(ctx *server.Context, input server.GreetingGreetInput) (server.GreetingGreetOutput, error) {',
+      'func greetHandler(ctx context.Context, info server.RequestInfo, input server.GreetingGreetInput) (server.GreetingGreetOutput, error) {',
       '	return server.GreetingGreetOutput{',
-      '		Message: fmt.Sprintf("Hello, %s!", input.Name),',
+      '		Message: fmt.Sprintf("%s, %s!", *input.Salutation, input.Name),',
       '	}, nil',
       '}',
       '',
-      'func createUserHandler(ctx *server.Context, input server.GreetingCreateUserInput) (server.GreetingCreateUserOutput, error) {',
+      'func createUserHandler(ctx context.Context, info server.RequestInfo, input server.GreetingCreateUserInput) (server.GreetingCreateUserOutput, error) {',
       '	return server.GreetingCreateUserOutput{',
       '		Id:   fmt.Sprintf("user-%d", len(input.Name)),',
       '		Name: input.Name,',
@@ -333,6 +334,20 @@ go 1.25
     expect(validQueryData.result).toHaveProperty('message');
     expect(validQueryData.result.message).toBe('Hello, World!');
 
+    // Test 1b: Absent fields take their defaults, set ones are kept
+    const salutationResponse = await fetch(`${serverUrl}/api`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        method: 'greeting.greet',
+        params: { name: 'World', salutation: 'Hi' },
+      }),
+    });
+
+    expect(salutationResponse.status).toBe(200);
+    const salutationData = await salutationResponse.json();
+    expect(salutationData.result.message).toBe('Hi, World!');
+
     // Test 2: Valid mutation request
     const validMutationResponse = await fetch(`${serverUrl}/api`, {
       method: 'POST',
@@ -355,6 +370,25 @@ go 1.25
     expect(validMutationData.result).toHaveProperty('name');
     expect(validMutationData.result.name).toBe('John');
 
+    // Test 2b: Inputs are normalized before validation and the handler
+    const paddedNameResponse = await fetch(`${serverUrl}/api`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        method: 'greeting.createUser',
+        params: {
+          name: '  John  ',
+          email: 'john@example.com',
+          age: 25,
+          tags: ['tag1'],
+        },
+      }),
+    });
+
+    expect(paddedNameResponse.status).toBe(200);
+    const paddedNameData = await paddedNameResponse.json();
+    expect(paddedNameData.result.name).toBe('John');
+
     // Test 3: Validation error - missing required field
     const missingFieldResponse = await fetch(`${serverUrl}/api`, {
       method: 'POST',
@@ -368,10 +402,10 @@ go 1.25
     expect(missingFieldResponse.status).toBe(400);
     const missingFieldData = await missingFieldResponse.json();
     expect(missingFieldData).toHaveProperty('error');
-    expect(missingFieldData).toHaveProperty('errors');
-    expect(Array.isArray(missingFieldData.errors)).toBe(true);
-    expect(missingFieldData.errors.length).toBeGreaterThan(0);
-    const nameError = missingFieldData.errors.find((e: any) => e.field === 'name');
+    expect(missingFieldData.error.code).toBe('INVALID_ARGUMENT');
+    expect(Array.isArray(missingFieldData.error.details)).toBe(true);
+    expect(missingFieldData.error.details.length).toBeGreaterThan(0);
+    const nameError = missingFieldData.error.details.find((e: any) => e.field === 'name');
     expect(nameError).toBeDefined();
 
     // Test 4: Validation error - invalid email
@@ -392,8 +426,8 @@ go 1.25
     expect(invalidEmailResponse.status).toBe(400);
     const invalidEmailData = await invalidEmailResponse.json();
     expect(invalidEmailData).toHaveProperty('error');
-    expect(invalidEmailData).toHaveProperty('errors');
-    const emailError = invalidEmailData.errors.find((e: any) => e.field === 'email');
+    expect(invalidEmailData.error.code).toBe('INVALID_ARGUMENT');
+    const emailError = invalidEmailData.error.details.find((e: any) => e.field === 'email');
     expect(emailError).toBeDefined();
 
     // Test 5: Validation error - array minItems
@@ -414,10 +448,53 @@ go 1.25
     expect(arrayMinItemsResponse.status).toBe(400);
     const arrayMinItemsData = await arrayMinItemsResponse.json();
     expect(arrayMinItemsData).toHaveProperty('error');
-    expect(arrayMinItemsData).toHaveProperty('errors');
-    const tagsError = arrayMinItemsData.errors.find((e: any) => e.field === 'tags');
+    expect(arrayMinItemsData.error.code).toBe('INVALID_ARGUMENT');
+    const tagsError = arrayMinItemsData.error.details.find((e: any) => e.field === 'tags');
     expect(tagsError).toBeDefined();
 
+    // Test 5b: Validation error - duplicate and invalid array items
+    const arrayItemsResponse = await fetch(`${serverUrl}/api`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        method: 'greeting.createUser',
+        params: {
+          name: 'John',
+          email: 'john@example.com',
+          age: 25,
+          tags: ['tag1', 'tag1', 'Not A Tag'],
+        },
+      }),
+    });
+
+    expect(arrayItemsResponse.status).toBe(400);
+    const arrayItemsData = await arrayItemsResponse.json();
+    const fields = arrayItemsData.error.details.map((e: any) => e.field);
+    expect(fields).toContain('tags');
+    expect(fields).toContain('tags[2]');
+    const itemError = arrayItemsData.error.details.find((e: any) => e.field === 'tags[2]');
+    expect(itemError.path).toEqual(['tags', 2]);
+
+    // Test 5c: Validation error - integer bounds
+    const ageResponse = await fetch(`${serverUrl}/api`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({
+        method: 'greeting.createUser',
+        params: {
+          name: 'John',
+          email: 'john@example.com',
+          age: 12,
+          tags: ['tag1'],
+        },
+      }),
+    });
+
+    expect(ageResponse.status).toBe(400);
+    const ageData = await ageResponse.json();
+    const ageError = ageData.error.details.find((e: any) => e.field === 'age');
+    expect(ageError?.message).toBe('must be at least 18');
+
     // Test 6: Missing method
     const missingMethodResponse = await fetch(`${serverUrl}/api`, {
       method: 'POST',
@@ -429,5 +506,24 @@ go 1.25
     });
 
     expect(missingMethodResponse.status).toBe(404);
+    const missingMethodData = await missingMethodResponse.json();
+    expect(missingMethodData.error.code).toBe('METHOD_NOT_FOUND');
+
+    // Test 7: Queries can be called with GET, mutations cannot
+    const getParams = encodeURIComponent(
+      JSON.stringify({ name: 'World', email: 'test@example.com' }),
+    );
+    const getQueryResponse = await fetch(
+      `${serverUrl}/api?method=greeting.greet&params=${getParams}`,
+    );
+
+    expect(getQueryResponse.status).toBe(200);
+    const getQueryData = await getQueryResponse.json();
+    expect(getQueryData.result.message).toBe('Hello, World!');
+
+    const getMutationResponse = await fetch(
+      `${serverUrl}/api?method=greeting.createUser&params=%7B%7D`,
+    );
+    expect(getMutationResponse.status).toBe(405);
   }, 60000); // 60 second timeout
 });
@@ -2,6 +2,7 @@ import { describe, test, expect } from 'bun:test';
 import { join } from 'node:path';
 import { parseContract } from '../../packages/sdk/src/parser/index.js';
 import { GoTypeGenerator } from '../../packages/target-go-server/src/type-generator.js';
+import { GoEnumGenerator } from '../../packages/target-go-server/src/enum-generator.js';
 
 describe('Go Type Generator', () => {
   test('generates named union, tuple, and enum types', async () => {
@@ -24,6 +25,10 @@ describe('Go Type Generator', () => {
     expect(typesGo).toContain('V0 string');
     expect(typesGo).toContain('V1 float64');
 
-    expect(typesGo).toContain('type DemoEnumOutputOutput string');
+    const enumsGo = new GoEnumGenerator('server').generateEnums(contract);
+    expect(enumsGo).toContain('type DemoEnumOutputOutput string');
+    expect(enumsGo).toContain(
+      'DemoEnumOutputOutputActive   DemoEnumOutputOutput = "active"',
+    );
   });
 });
@@ -6,15 +6,16 @@ const greeting = createEndpoint({
     input: z.object({ 
       name: z.string().min(1).max(100),
       email: z.string().email().optional(),
+      salutation: z.string().min(1).max(20).default('Hello'),
     }),
     output: z.object({ message: z.string() }),
   }),
   createUser: mutation({
     input: z.object({
-      name: z.string().min(3).max(50),
+      name: z.string().trim().min(3).max(50),
       email: z.string().email(),
       age: z.number().min(18).max(120).int(),
-      tags: z.array(z.string()).min(1).max(10),
+      tags: z.array(z.string().min(1).max(30).regex(/^[a-z0-9-]+$/)).min(1).max(10).meta({ uniqueItems: true }),
     }),
     output: z.object({ id: z.string(), name: z.string() }),
   }),